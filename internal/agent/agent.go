@@ -17,6 +17,7 @@ import (
 	"log/slog"
 	"os"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -35,8 +36,11 @@ import (
 	"github.com/charmbracelet/crush/internal/agent/notify"
 	"github.com/charmbracelet/crush/internal/agent/tools"
 	"github.com/charmbracelet/crush/internal/agent/tools/mcp"
+	"github.com/charmbracelet/crush/internal/checkpoint"
 	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/hooks"
 	"github.com/charmbracelet/crush/internal/message"
 	"github.com/charmbracelet/crush/internal/permission"
 	"github.com/charmbracelet/crush/internal/pubsub"
@@ -78,6 +82,19 @@ type SessionAgentCall struct {
 	FrequencyPenalty *float64
 	PresencePenalty  *float64
 	NonInteractive   bool
+	// WorkingDir is the directory the tools work in, when it's not the
+	// project's, like for sessions isolated in a git worktree.
+	WorkingDir string
+	// PlanMode restricts the agent to read-only tools, until it submits a
+	// plan the user approves.
+	PlanMode bool
+	// Budget limits the run. The run stops with a [*BudgetExceededError]
+	// once it's used up.
+	Budget config.Budget
+
+	// hooked is set once the UserPromptSubmit hooks ran on the call, so they
+	// don't run again when it's dequeued or continued after a summary.
+	hooked bool
 }
 
 type SessionAgent interface {
@@ -100,6 +117,12 @@ type Model struct {
 	Model      fantasy.LanguageModel
 	CatwalkCfg catwalk.Model
 	ModelCfg   config.SelectedModel
+	// Fallbacks are the models to fail over to, in order.
+	Fallbacks []Model
+
+	// callOptions, set on fallbacks, replace the options of the calls made
+	// for the model they're a fallback of.
+	callOptions *callOptions
 }
 
 type sessionAgent struct {
@@ -115,6 +138,11 @@ type sessionAgent struct {
 	disableAutoSummarize bool
 	isYolo               bool
 	notify               pubsub.Publisher[notify.Notification]
+	hooks                *hooks.Runner
+	loopDetection        config.LoopDetection
+	checkpoints          *checkpoint.Service
+	workingDir           string
+	buildSystemPrompt    func(ctx context.Context, workingDir string) (string, error)
 
 	messageQueue   *csync.Map[string, []SessionAgentCall]
 	activeRequests *csync.Map[string, context.CancelFunc]
@@ -132,6 +160,17 @@ type SessionAgentOptions struct {
 	Messages             message.Service
 	Tools                []fantasy.AgentTool
 	Notify               pubsub.Publisher[notify.Notification]
+	Hooks                *hooks.Runner
+	LoopDetection        config.LoopDetection
+	// Checkpoints snapshots the working tree at the start of each turn. It's
+	// nil when checkpoints are disabled.
+	Checkpoints *checkpoint.Service
+	// WorkingDir is the project directory, checkpointed unless the call works
+	// elsewhere.
+	WorkingDir string
+	// BuildSystemPrompt builds the system prompt of calls working in another
+	// directory than the project's, like in a git worktree.
+	BuildSystemPrompt func(ctx context.Context, workingDir string) (string, error)
 }
 
 func NewSessionAgent(
@@ -149,6 +188,11 @@ func NewSessionAgent(
 		tools:                csync.NewSliceFrom(opts.Tools),
 		isYolo:               opts.IsYolo,
 		notify:               opts.Notify,
+		hooks:                opts.Hooks,
+		loopDetection:        opts.LoopDetection,
+		checkpoints:          opts.Checkpoints,
+		workingDir:           opts.WorkingDir,
+		buildSystemPrompt:    opts.BuildSystemPrompt,
 		messageQueue:         csync.NewMap[string, []SessionAgentCall](),
 		activeRequests:       csync.NewMap[string, context.CancelFunc](),
 	}
@@ -162,6 +206,16 @@ func (a *sessionAgent) Run(ctx context.Context, call SessionAgentCall) (*fantasy
 		return nil, ErrSessionMissing
 	}
 
+	// Let the hooks deny or extend the prompt before it's queued or sent.
+	if a.hooks != nil && !a.isSubAgent && !call.hooked {
+		var err error
+		call, err = a.runPromptHooks(ctx, hooks.UserPromptSubmit, call)
+		if err != nil {
+			return nil, err
+		}
+		call.hooked = true
+	}
+
 	// Queue the message if busy
 	if a.IsSessionBusy(call.SessionID) {
 		existing, ok := a.messageQueue.Get(call.SessionID)
@@ -174,9 +228,17 @@ func (a *sessionAgent) Run(ctx context.Context, call SessionAgentCall) (*fantasy
 	}
 
 	// Copy mutable fields under lock to avoid races with SetTools/SetModels.
-	agentTools := a.tools.Copy()
+	agentTools := filterPlanModeTools(a.tools.Copy(), call.PlanMode)
 	largeModel := a.largeModel.Get()
 	systemPrompt := a.systemPrompt.Get()
+	if call.WorkingDir != "" && a.buildSystemPrompt != nil {
+		// The environment described in the prompt is the one of the call.
+		if built, err := a.buildSystemPrompt(ctx, call.WorkingDir); err != nil {
+			slog.Warn("Failed to build system prompt", "working_dir", call.WorkingDir, "error", err)
+		} else {
+			systemPrompt = built
+		}
+	}
 	promptPrefix := a.systemPromptPrefix.Get()
 	var instructions strings.Builder
 
@@ -194,13 +256,27 @@ func (a *sessionAgent) Run(ctx context.Context, call SessionAgentCall) (*fantasy
 		systemPrompt += "\n\n<mcp-instructions>\n" + s + "\n</mcp-instructions>"
 	}
 
+	if call.WorkingDir != "" {
+		systemPrompt += "\n\n<worktree>\nThe working directory is an isolated git worktree of the project at " + call.WorkingDir +
+			". Its changes stay on its own branch until the user merges them.\n</worktree>"
+	}
+
+	if call.PlanMode {
+		systemPrompt += "\n\n" + planModePrompt
+	}
+
 	if len(agentTools) > 0 {
 		// Add Anthropic caching to the last tool.
 		agentTools[len(agentTools)-1].SetProviderOptions(a.getCacheControlOptions())
 	}
 
+	// The assistant message being generated, which records the model used.
+	var currentAssistant *message.Message
+	models := newFailoverModel(largeModel, func(from, to Model, err error) {
+		a.failedOver(ctx, call, currentAssistant, from, to, err)
+	})
 	agent := fantasy.NewAgent(
-		largeModel.Model,
+		models,
 		fantasy.WithSystemPrompt(systemPrompt),
 		fantasy.WithTools(agentTools...),
 		fantasy.WithUserAgent(userAgent),
@@ -217,6 +293,13 @@ func (a *sessionAgent) Run(ctx context.Context, call SessionAgentCall) (*fantasy
 		return nil, fmt.Errorf("failed to get session messages: %w", err)
 	}
 
+	if len(msgs) == 0 && a.hooks != nil && !a.isSubAgent {
+		call, err = a.runPromptHooks(ctx, hooks.SessionStart, call)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	var wg sync.WaitGroup
 	// Generate title if first message.
 	if len(msgs) == 0 {
@@ -228,13 +311,25 @@ func (a *sessionAgent) Run(ctx context.Context, call SessionAgentCall) (*fantasy
 	defer wg.Wait()
 
 	// Add the user message to the session.
-	_, err = a.createUserMessage(ctx, call)
+	userMsg, err := a.createUserMessage(ctx, call)
 	if err != nil {
 		return nil, err
 	}
 
+	// Snapshot the working tree, so whatever the turn changes can be undone.
+	if a.checkpoints != nil {
+		dir := cmp.Or(call.WorkingDir, a.workingDir)
+		if _, err := a.checkpoints.Create(ctx, dir, call.SessionID, userMsg.ID, call.Prompt); err != nil {
+			slog.Warn("Failed to create checkpoint", "session_id", call.SessionID, "error", err)
+		}
+	}
+
 	// Add the session to the context.
 	ctx = context.WithValue(ctx, tools.SessionIDContextKey, call.SessionID)
+	if call.WorkingDir != "" {
+		ctx = context.WithValue(ctx, tools.WorkingDirContextKey, call.WorkingDir)
+		ctx = permission.WithWorkingDir(ctx, call.WorkingDir)
+	}
 
 	genCtx, cancel := context.WithCancel(ctx)
 	a.activeRequests.Set(call.SessionID, cancel)
@@ -242,17 +337,18 @@ func (a *sessionAgent) Run(ctx context.Context, call SessionAgentCall) (*fantasy
 	defer cancel()
 	defer a.activeRequests.Del(call.SessionID)
 
-	history, files := a.preparePrompt(msgs, call.Attachments...)
+	promptMsgs, files := a.preparePrompt(msgs, call.Attachments...)
 
 	startTime := time.Now()
+	startCost := currentSession.Cost
 	a.eventPromptSent(call.SessionID)
 
-	var currentAssistant *message.Message
 	var shouldSummarize bool
+	var budgetErr *BudgetExceededError
 	result, err := agent.Stream(genCtx, fantasy.AgentStreamCall{
 		Prompt:           message.PromptWithTextAttachments(call.Prompt, call.Attachments),
 		Files:            files,
-		Messages:         history,
+		Messages:         promptMsgs,
 		ProviderOptions:  call.ProviderOptions,
 		MaxOutputTokens:  &call.MaxOutputTokens,
 		TopP:             call.TopP,
@@ -276,7 +372,8 @@ func (a *sessionAgent) Run(ctx context.Context, call SessionAgentCall) (*fantasy
 				prepared.Messages = append(prepared.Messages, userMessage.ToAIMessage()...)
 			}
 
-			prepared.Messages = a.workaroundProviderMediaLimitations(prepared.Messages, largeModel)
+			model := models.Current()
+			prepared.Messages = a.workaroundProviderMediaLimitations(prepared.Messages, model)
 
 			lastSystemRoleInx := 0
 			systemMessageUpdated := false
@@ -302,15 +399,17 @@ func (a *sessionAgent) Run(ctx context.Context, call SessionAgentCall) (*fantasy
 			assistantMsg, err = a.messages.Create(callContext, call.SessionID, message.CreateMessageParams{
 				Role:     message.Assistant,
 				Parts:    []message.ContentPart{},
-				Model:    largeModel.ModelCfg.Model,
-				Provider: largeModel.ModelCfg.Provider,
+				Model:    model.ModelCfg.Model,
+				Provider: model.ModelCfg.Provider,
 			})
 			if err != nil {
 				return callContext, prepared, err
 			}
 			callContext = context.WithValue(callContext, tools.MessageIDContextKey, assistantMsg.ID)
-			callContext = context.WithValue(callContext, tools.SupportsImagesContextKey, largeModel.CatwalkCfg.SupportsImages)
-			callContext = context.WithValue(callContext, tools.ModelNameContextKey, largeModel.CatwalkCfg.Name)
+			callContext = history.WithMessageID(callContext, assistantMsg.ID)
+			callContext = context.WithValue(callContext, tools.SupportsImagesContextKey, model.CatwalkCfg.SupportsImages)
+			callContext = context.WithValue(callContext, tools.SupportsPDFContextKey, model.CatwalkCfg.SupportsImages && providerAcceptsPDFs(model))
+			callContext = context.WithValue(callContext, tools.ModelNameContextKey, model.CatwalkCfg.Name)
 			currentAssistant = &assistantMsg
 			return callContext, prepared, err
 		},
@@ -405,7 +504,7 @@ func (a *sessionAgent) Run(ctx context.Context, call SessionAgentCall) (*fantasy
 			if getSessionErr != nil {
 				return getSessionErr
 			}
-			a.updateSessionUsage(largeModel, &updatedSession, stepResult.Usage, a.openrouterCost(stepResult.ProviderMetadata))
+			a.updateSessionUsage(models.Current(), &updatedSession, stepResult.Usage, a.openrouterCost(stepResult.ProviderMetadata))
 			_, sessionErr := a.sessions.Save(ctx, updatedSession)
 			if sessionErr != nil {
 				return sessionErr
@@ -415,7 +514,7 @@ func (a *sessionAgent) Run(ctx context.Context, call SessionAgentCall) (*fantasy
 		},
 		StopWhen: []fantasy.StopCondition{
 			func(_ []fantasy.StepResult) bool {
-				cw := int64(largeModel.CatwalkCfg.ContextWindow)
+				cw := int64(models.Current().CatwalkCfg.ContextWindow)
 				tokens := currentSession.CompletionTokens + currentSession.PromptTokens
 				remaining := cw - tokens
 				var threshold int64
@@ -431,12 +530,25 @@ func (a *sessionAgent) Run(ctx context.Context, call SessionAgentCall) (*fantasy
 				return false
 			},
 			func(steps []fantasy.StepResult) bool {
-				return hasRepeatedToolCalls(steps, loopDetectionWindowSize, loopDetectionMaxRepeats)
+				budgetErr = checkBudget(call.Budget, steps, currentSession.Cost-startCost, time.Since(startTime))
+				return budgetErr != nil
 			},
+			func(steps []fantasy.StepResult) bool {
+				if a.loopDetection.Disabled {
+					return false
+				}
+				return hasRepeatedToolCalls(
+					steps,
+					cmp.Or(a.loopDetection.WindowSize, loopDetectionWindowSize),
+					cmp.Or(a.loopDetection.MaxRepeats, loopDetectionMaxRepeats),
+				)
+			},
+			isPlanApproved,
 		},
 	})
 
 	a.eventPromptResponded(call.SessionID, time.Since(startTime).Truncate(time.Second))
+	a.runStopHooks(ctx, call, err)
 
 	if err != nil {
 		isCancelErr := errors.Is(err, context.Canceled)
@@ -521,7 +633,7 @@ func (a *sessionAgent) Run(ctx context.Context, call SessionAgentCall) (*fantasy
 				currentAssistant.AddFinish(
 					message.FinishReasonError,
 					"Copilot model not enabled",
-					fmt.Sprintf("%q is not enabled in Copilot. Go to the following page to enable it. Then, wait 5 minutes before trying again. %s", largeModel.CatwalkCfg.Name, link),
+					fmt.Sprintf("%q is not enabled in Copilot. Go to the following page to enable it. Then, wait 5 minutes before trying again. %s", models.Current().CatwalkCfg.Name, link),
 				)
 			} else {
 				currentAssistant.AddFinish(message.FinishReasonError, cmp.Or(stringext.Capitalize(providerErr.Title), defaultTitle), providerErr.Message)
@@ -540,6 +652,26 @@ func (a *sessionAgent) Run(ctx context.Context, call SessionAgentCall) (*fantasy
 		return nil, err
 	}
 
+	if budgetErr != nil {
+		currentAssistant.AddFinish(
+			message.FinishReasonBudgetExceeded,
+			"Budget exceeded",
+			fmt.Sprintf("Stopped after reaching the budget of %s (%s).", budgetErr.Description, budgetErr.Limit),
+		)
+		if updateErr := a.messages.Update(ctx, *currentAssistant); updateErr != nil {
+			return nil, updateErr
+		}
+		if !call.NonInteractive && a.notify != nil {
+			a.notify.Publish(pubsub.CreatedEvent, notify.Notification{
+				SessionID:    call.SessionID,
+				SessionTitle: currentSession.Title,
+				Type:         notify.TypeBudgetExceeded,
+				Message:      budgetErr.Description,
+			})
+		}
+		return result, budgetErr
+	}
+
 	// Send notification that agent has finished its turn (skip for
 	// nested/non-interactive sessions).
 	if !call.NonInteractive && a.notify != nil {
@@ -610,7 +742,8 @@ func (a *sessionAgent) Summarize(ctx context.Context, sessionID string, opts fan
 	defer a.activeRequests.Del(sessionID)
 	defer cancel()
 
-	agent := fantasy.NewAgent(largeModel.Model,
+	models := newFailoverModel(largeModel, nil)
+	agent := fantasy.NewAgent(models,
 		fantasy.WithSystemPrompt(string(summaryPrompt)),
 		fantasy.WithUserAgent(userAgent),
 	)
@@ -623,6 +756,11 @@ func (a *sessionAgent) Summarize(ctx context.Context, sessionID string, opts fan
 	if err != nil {
 		return err
 	}
+	// The summary is saved as it streams, along with the model used.
+	models.onFailover = func(_, to Model, _ error) {
+		summaryMessage.Model = to.Model.Model()
+		summaryMessage.Provider = to.Model.Provider()
+	}
 
 	summaryPromptText := buildSummaryPrompt(currentSession.Todos)
 
@@ -684,7 +822,7 @@ func (a *sessionAgent) Summarize(ctx context.Context, sessionID string, opts fan
 		}
 	}
 
-	a.updateSessionUsage(largeModel, &currentSession, resp.TotalUsage, openrouterCost)
+	a.updateSessionUsage(models.Current(), &currentSession, resp.TotalUsage, openrouterCost)
 
 	// Just in case, get just the last usage info.
 	usage := resp.Response.Usage
@@ -712,6 +850,52 @@ func (a *sessionAgent) getCacheControlOptions() fantasy.ProviderOptions {
 	}
 }
 
+// runPromptHooks runs the hooks of a prompt event. Context returned by the
+// hooks is attached to the prompt.
+func (a *sessionAgent) runPromptHooks(ctx context.Context, event hooks.Event, call SessionAgentCall) (SessionAgentCall, error) {
+	if !a.hooks.Has(event) {
+		return call, nil
+	}
+	result := a.hooks.Run(ctx, hooks.Payload{
+		Event:      event,
+		SessionID:  call.SessionID,
+		WorkingDir: call.WorkingDir,
+		Prompt:     call.Prompt,
+	})
+	if result.Denied() {
+		return call, fmt.Errorf("%w: %s", ErrPromptDenied, result.Reason)
+	}
+	if result.Context != "" {
+		call.Attachments = append(slices.Clone(call.Attachments), message.Attachment{
+			FilePath: string(event) + " hook",
+			FileName: string(event) + " hook",
+			MimeType: "text/plain",
+			Content:  []byte(hooks.FormatContext(event, result.Context)),
+		})
+	}
+	return call, nil
+}
+
+// runStopHooks runs the Stop hooks once the agent is done with a prompt.
+func (a *sessionAgent) runStopHooks(ctx context.Context, call SessionAgentCall, err error) {
+	if a.hooks == nil || a.isSubAgent || !a.hooks.Has(hooks.Stop) {
+		return
+	}
+	reason := "end_turn"
+	switch {
+	case errors.Is(err, context.Canceled):
+		reason = "canceled"
+	case err != nil:
+		reason = "error"
+	}
+	a.hooks.Run(ctx, hooks.Payload{
+		Event:      hooks.Stop,
+		SessionID:  call.SessionID,
+		WorkingDir: call.WorkingDir,
+		StopReason: reason,
+	})
+}
+
 func (a *sessionAgent) createUserMessage(ctx context.Context, call SessionAgentCall) (message.Message, error) {
 	parts := []message.ContentPart{message.TextContent{Text: call.Prompt}}
 	var attachmentParts []message.ContentPart
@@ -1092,6 +1276,9 @@ func (a *sessionAgent) convertToToolResult(result fantasy.ToolResultContent) mes
 // Anthropic and Bedrock support images natively in tool results, so we skip
 // this workaround for them.
 //
+// PDFs are replaced with a text placeholder for providers that don't accept
+// them, as they may have been read with another model of the session.
+//
 // Example transformation:
 //
 //	BEFORE: [tool result: image data]
@@ -1099,10 +1286,7 @@ func (a *sessionAgent) convertToToolResult(result fantasy.ToolResultContent) mes
 func (a *sessionAgent) workaroundProviderMediaLimitations(messages []fantasy.Message, largeModel Model) []fantasy.Message {
 	providerSupportsMedia := largeModel.ModelCfg.Provider == string(catwalk.InferenceProviderAnthropic) ||
 		largeModel.ModelCfg.Provider == string(catwalk.InferenceProviderBedrock)
-
-	if providerSupportsMedia {
-		return messages
-	}
+	acceptsPDFs := providerAcceptsPDFs(largeModel)
 
 	convertedMessages := make([]fantasy.Message, 0, len(messages))
 
@@ -1123,6 +1307,21 @@ func (a *sessionAgent) workaroundProviderMediaLimitations(messages []fantasy.Mes
 			}
 
 			if media, ok := fantasy.AsToolResultOutputType[fantasy.ToolResultOutputContentMedia](toolResult.Output); ok {
+				if !acceptsPDFs && !strings.HasPrefix(media.MediaType, "image/") {
+					textParts = append(textParts, fantasy.ToolResultPart{
+						ToolCallID: toolResult.ToolCallID,
+						Output: fantasy.ToolResultOutputContentText{
+							Text: "[PDF content can't be sent to this model - view the file again to read its text]",
+						},
+						ProviderOptions: toolResult.ProviderOptions,
+					})
+					continue
+				}
+				if providerSupportsMedia {
+					textParts = append(textParts, part)
+					continue
+				}
+
 				decoded, err := base64.StdEncoding.DecodeString(media.Data)
 				if err != nil {
 					slog.Warn("Failed to decode media data", "error", err)
@@ -1149,8 +1348,9 @@ func (a *sessionAgent) workaroundProviderMediaLimitations(messages []fantasy.Mes
 		}
 
 		convertedMessages = append(convertedMessages, fantasy.Message{
-			Role:    fantasy.MessageRoleTool,
-			Content: textParts,
+			Role:            fantasy.MessageRoleTool,
+			Content:         textParts,
+			ProviderOptions: msg.ProviderOptions,
 		})
 
 		if len(mediaFiles) > 0 {
@@ -1164,6 +1364,20 @@ func (a *sessionAgent) workaroundProviderMediaLimitations(messages []fantasy.Mes
 	return convertedMessages
 }
 
+// providerAcceptsPDFs reports whether the provider of the model accepts PDFs.
+// The Anthropic provider, which Bedrock and Claude models on Vertex use too,
+// only sends images, so the API would reject a PDF.
+func providerAcceptsPDFs(model Model) bool {
+	if model.Model == nil {
+		return false
+	}
+	switch model.Model.Provider() {
+	case anthropic.Name, bedrock.Name:
+		return false
+	}
+	return true
+}
+
 // buildSummaryPrompt constructs the prompt text for session summarization.
 func buildSummaryPrompt(todos []session.Todo) string {
 	var sb strings.Builder
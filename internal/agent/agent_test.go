@@ -1,7 +1,11 @@
 package agent
 
 import (
+	"encoding/base64"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -9,8 +13,11 @@ import (
 	"testing"
 
 	"charm.land/fantasy"
+	"charm.land/fantasy/providers/anthropic"
+	"charm.land/fantasy/providers/openai"
 	"charm.land/x/vcr"
 	"github.com/charmbracelet/crush/internal/agent/tools"
+	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/message"
 	"github.com/charmbracelet/crush/internal/session"
 	"github.com/stretchr/testify/assert"
@@ -618,6 +625,87 @@ func TestCoderAgent(t *testing.T) {
 	}
 }
 
+func TestWorkaroundProviderMediaLimitationsPDF(t *testing.T) {
+	t.Parallel()
+
+	pdf := base64.StdEncoding.EncodeToString([]byte("%PDF-1.4 test"))
+	messages := []fantasy.Message{
+		fantasy.NewUserMessage("Read doc.pdf"),
+		{
+			Role: fantasy.MessageRoleAssistant,
+			Content: []fantasy.MessagePart{fantasy.ToolCallPart{
+				ToolCallID: "call_1",
+				ToolName:   tools.ViewToolName,
+				Input:      `{"file_path":"doc.pdf"}`,
+			}},
+		},
+		{
+			Role: fantasy.MessageRoleTool,
+			Content: []fantasy.MessagePart{fantasy.ToolResultPart{
+				ToolCallID: "call_1",
+				Output:     fantasy.ToolResultOutputContentMedia{Data: pdf, MediaType: "application/pdf"},
+			}},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		provider string
+		response string
+		build    func(url string) (fantasy.Provider, error)
+		wantPDF  bool
+	}{
+		{
+			name:     "anthropic",
+			provider: "anthropic",
+			response: `{"id":"msg_1","type":"message","role":"assistant","model":"m","content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn","usage":{"input_tokens":1,"output_tokens":1}}`,
+			build: func(url string) (fantasy.Provider, error) {
+				return anthropic.New(anthropic.WithBaseURL(url), anthropic.WithAPIKey("key"))
+			},
+		},
+		{
+			name:     "openai",
+			provider: "openai",
+			response: `{"id":"chat_1","object":"chat.completion","created":1,"model":"m","choices":[{"index":0,"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}],"usage":{"prompt_tokens":1,"completion_tokens":1,"total_tokens":2}}`,
+			build: func(url string) (fantasy.Provider, error) {
+				return openai.New(openai.WithBaseURL(url), openai.WithAPIKey("key"))
+			},
+			wantPDF: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var body []byte
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ = io.ReadAll(r.Body)
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = io.WriteString(w, tt.response)
+			}))
+			t.Cleanup(srv.Close)
+
+			provider, err := tt.build(srv.URL)
+			require.NoError(t, err)
+			lm, err := provider.LanguageModel(t.Context(), "m")
+			require.NoError(t, err)
+			model := Model{Model: lm, ModelCfg: config.SelectedModel{Provider: tt.provider, Model: "m"}}
+
+			a := &sessionAgent{}
+			_, err = lm.Generate(t.Context(), fantasy.Call{Prompt: a.workaroundProviderMediaLimitations(messages, model)})
+			require.NoError(t, err)
+
+			if tt.wantPDF {
+				require.Contains(t, string(body), "application/pdf")
+				require.Contains(t, string(body), pdf)
+			} else {
+				require.NotContains(t, string(body), "application/pdf")
+				require.Contains(t, string(body), "view the file again")
+			}
+		})
+	}
+}
+
 func makeTestTodos(n int) []session.Todo {
 	todos := make([]session.Todo, n)
 	for i := range n {
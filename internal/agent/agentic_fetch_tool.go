@@ -13,6 +13,7 @@ import (
 
 	"github.com/charmbracelet/crush/internal/agent/prompt"
 	"github.com/charmbracelet/crush/internal/agent/tools"
+	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/permission"
 )
 
@@ -146,7 +147,7 @@ func (c *coordinator) agenticFetchTool(_ context.Context, client *http.Client) (
 				return fantasy.ToolResponse{}, fmt.Errorf("error creating prompt: %s", err)
 			}
 
-			_, small, err := c.buildAgentModels(ctx, true)
+			_, small, err := c.buildAgentModels(ctx, config.SelectedModelTypeLarge, true)
 			if err != nil {
 				return fantasy.ToolResponse{}, fmt.Errorf("error building models: %s", err)
 			}
@@ -162,7 +163,13 @@ func (c *coordinator) agenticFetchTool(_ context.Context, client *http.Client) (
 			}
 
 			webFetchTool := tools.NewWebFetchTool(tmpDir, client)
-			webSearchTool := tools.NewWebSearchTool(client)
+			// A bad web search setting is the user's to fix, so report it
+			// rather than failing the whole run.
+			searchProvider, err := tools.NewSearchProvider(c.cfg.Config().Tools.WebSearch, client)
+			if err != nil {
+				return fantasy.NewTextErrorResponse(fmt.Sprintf("invalid tools.web_search configuration: %s", err)), nil
+			}
+			webSearchTool := tools.NewWebSearchTool(searchProvider)
 			fetchTools := []fantasy.AgentTool{
 				webFetchTool,
 				webSearchTool,
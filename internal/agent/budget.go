@@ -0,0 +1,63 @@
+package agent
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"time"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/config"
+)
+
+// ErrBudgetExceeded is returned, wrapped in a [*BudgetExceededError], when a
+// run is stopped because it hit one of its budgets.
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// BudgetContinuePrompt is sent to continue a run stopped by its budget, once
+// the user extended it.
+const BudgetContinuePrompt = "You were stopped because you hit your budget. The user extended it: continue where you left off."
+
+// BudgetExceededError describes the budget a run hit.
+type BudgetExceededError struct {
+	// Limit is the name of the budget, as in the configuration.
+	Limit string
+	// Description describes the budget, like "50 steps".
+	Description string
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("budget exceeded: %s (%s)", e.Description, e.Limit)
+}
+
+func (e *BudgetExceededError) Unwrap() error {
+	return ErrBudgetExceeded
+}
+
+// checkBudget returns a [*BudgetExceededError] if the run used up one of its
+// budgets and the agent is about to keep going. cost and elapsed are the
+// cost of the run and the time it's been running for.
+func checkBudget(budget config.Budget, steps []fantasy.StepResult, cost float64, elapsed time.Duration) *BudgetExceededError {
+	// A run whose last step has no tool calls is over anyway.
+	if len(steps) == 0 || len(steps[len(steps)-1].Content.ToolCalls()) == 0 {
+		return nil
+	}
+
+	var tokens int64
+	for _, step := range steps {
+		tokens += cmp.Or(step.Usage.TotalTokens, step.Usage.InputTokens+step.Usage.OutputTokens)
+	}
+	maxDuration := time.Duration(budget.MaxDuration) * time.Second
+
+	switch {
+	case budget.MaxSteps > 0 && len(steps) >= budget.MaxSteps:
+		return &BudgetExceededError{Limit: "max_steps", Description: fmt.Sprintf("%d steps", budget.MaxSteps)}
+	case budget.MaxTokens > 0 && tokens >= budget.MaxTokens:
+		return &BudgetExceededError{Limit: "max_tokens", Description: fmt.Sprintf("%d tokens", budget.MaxTokens)}
+	case budget.MaxCost > 0 && cost >= budget.MaxCost:
+		return &BudgetExceededError{Limit: "max_cost", Description: fmt.Sprintf("$%.2f", budget.MaxCost)}
+	case maxDuration > 0 && elapsed >= maxDuration:
+		return &BudgetExceededError{Limit: "max_duration", Description: maxDuration.String()}
+	}
+	return nil
+}
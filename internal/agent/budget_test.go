@@ -0,0 +1,54 @@
+package agent
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func makeUsageStep(tokens int64) fantasy.StepResult {
+	step := makeToolStep("bash", `{"command":"ls"}`, "file.go")
+	step.Usage = fantasy.Usage{InputTokens: tokens / 2, OutputTokens: tokens - tokens/2}
+	return step
+}
+
+func TestCheckBudget(t *testing.T) {
+	t.Parallel()
+
+	steps := []fantasy.StepResult{makeUsageStep(1000), makeUsageStep(1000), makeUsageStep(1000)}
+
+	tests := []struct {
+		name    string
+		budget  config.Budget
+		steps   []fantasy.StepResult
+		cost    float64
+		elapsed time.Duration
+		limit   string
+	}{
+		{name: "no budget", steps: steps, cost: 100, elapsed: time.Hour},
+		{name: "under budget", budget: config.Budget{MaxSteps: 4, MaxTokens: 4000, MaxCost: 1, MaxDuration: 60}, steps: steps, cost: 0.5, elapsed: time.Second},
+		{name: "max steps", budget: config.Budget{MaxSteps: 3}, steps: steps, limit: "max_steps"},
+		{name: "max tokens", budget: config.Budget{MaxTokens: 3000}, steps: steps, limit: "max_tokens"},
+		{name: "max cost", budget: config.Budget{MaxCost: 1}, steps: steps, cost: 1.5, limit: "max_cost"},
+		{name: "max duration", budget: config.Budget{MaxDuration: 60}, steps: steps, elapsed: 2 * time.Minute, limit: "max_duration"},
+		{name: "steps come first", budget: config.Budget{MaxSteps: 1, MaxCost: 1}, steps: steps, cost: 2, limit: "max_steps"},
+		{name: "run is over anyway", budget: config.Budget{MaxSteps: 1}, steps: append(steps, makeEmptyStep())},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := checkBudget(tt.budget, tt.steps, tt.cost, tt.elapsed)
+			if tt.limit == "" {
+				require.Nil(t, err)
+				return
+			}
+			require.NotNil(t, err)
+			require.Equal(t, tt.limit, err.Limit)
+			require.True(t, errors.Is(err, ErrBudgetExceeded))
+		})
+	}
+}
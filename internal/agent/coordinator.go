@@ -21,9 +21,12 @@ import (
 	"github.com/charmbracelet/crush/internal/agent/notify"
 	"github.com/charmbracelet/crush/internal/agent/prompt"
 	"github.com/charmbracelet/crush/internal/agent/tools"
+	"github.com/charmbracelet/crush/internal/checkpoint"
 	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/csync"
 	"github.com/charmbracelet/crush/internal/filetracker"
 	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/hooks"
 	"github.com/charmbracelet/crush/internal/log"
 	"github.com/charmbracelet/crush/internal/lsp"
 	"github.com/charmbracelet/crush/internal/message"
@@ -31,6 +34,7 @@ import (
 	"github.com/charmbracelet/crush/internal/permission"
 	"github.com/charmbracelet/crush/internal/pubsub"
 	"github.com/charmbracelet/crush/internal/session"
+	"github.com/charmbracelet/crush/internal/worktree"
 	"golang.org/x/sync/errgroup"
 
 	"charm.land/fantasy/providers/anthropic"
@@ -58,17 +62,31 @@ var (
 )
 
 type Coordinator interface {
-	// INFO: (kujtim) this is not used yet we will use this when we have multiple agents
-	// SetMainAgent(string)
+	// SetMainAgent sets the agent used by sessions that didn't select one.
+	SetMainAgent(agentID string) error
+	// SetSessionAgent sets the agent used by the given session.
+	SetSessionAgent(sessionID, agentID string) error
+	// SessionAgentID returns the ID of the agent used by the given session.
+	SessionAgentID(sessionID string) string
 	Run(ctx context.Context, sessionID, prompt string, attachments ...message.Attachment) (*fantasy.AgentResult, error)
 	Cancel(sessionID string)
 	CancelAll()
 	IsSessionBusy(sessionID string) bool
 	IsBusy() bool
+	// SetPlanMode turns plan mode on or off for the given session.
+	SetPlanMode(sessionID string, enabled bool)
+	// PlanMode reports whether the given session is in plan mode.
+	PlanMode(sessionID string) bool
+	// SetBudget sets the budget of the runs of the given session, overriding
+	// the budget of its agent where set.
+	SetBudget(sessionID string, budget config.Budget)
 	QueuedPrompts(sessionID string) int
 	QueuedPromptsList(sessionID string) []string
 	ClearQueue(sessionID string)
 	Summarize(context.Context, string) error
+	// Sample completes the messages of a sampling request of an MCP server
+	// with the small model.
+	Sample(ctx context.Context, call fantasy.Call) (*fantasy.Response, error)
 	Model() Model
 	UpdateModels(ctx context.Context) error
 }
@@ -82,9 +100,18 @@ type coordinator struct {
 	filetracker filetracker.Service
 	lspManager  *lsp.Manager
 	notify      pubsub.Publisher[notify.Notification]
-
-	currentAgent SessionAgent
-	agents       map[string]SessionAgent
+	hooks       *hooks.Runner
+
+	// agents holds every enabled primary agent, keyed by agent ID. It's only
+	// written to when the coordinator is created.
+	agents        map[string]SessionAgent
+	mainAgentID   *csync.Value[string]
+	sessionAgents *csync.Map[string, string]
+	// planSessions holds the sessions in plan mode, and approvedPlans the
+	// ones whose plan was just approved and needs implementing.
+	planSessions  *csync.Map[string, bool]
+	approvedPlans *csync.Map[string, bool]
+	budgets       *csync.Map[string, config.Budget]
 
 	readyWg errgroup.Group
 }
@@ -101,35 +128,124 @@ func NewCoordinator(
 	notify pubsub.Publisher[notify.Notification],
 ) (Coordinator, error) {
 	c := &coordinator{
-		cfg:         cfg,
-		sessions:    sessions,
-		messages:    messages,
-		permissions: permissions,
-		history:     history,
-		filetracker: filetracker,
-		lspManager:  lspManager,
-		notify:      notify,
-		agents:      make(map[string]SessionAgent),
-	}
-
-	agentCfg, ok := cfg.Config().Agents[config.AgentCoder]
-	if !ok {
+		cfg:           cfg,
+		sessions:      sessions,
+		messages:      messages,
+		permissions:   permissions,
+		history:       history,
+		filetracker:   filetracker,
+		lspManager:    lspManager,
+		notify:        notify,
+		hooks:         hooks.NewRunner(cfg),
+		agents:        make(map[string]SessionAgent),
+		mainAgentID:   csync.NewValue(config.AgentCoder),
+		sessionAgents: csync.NewMap[string, string](),
+		planSessions:  csync.NewMap[string, bool](),
+		approvedPlans: csync.NewMap[string, bool](),
+		budgets:       csync.NewMap[string, config.Budget](),
+	}
+
+	if _, ok := cfg.Config().Agents[config.AgentCoder]; !ok {
 		return nil, errCoderAgentNotConfigured
 	}
 
-	// TODO: make this dynamic when we support multiple agents
-	prompt, err := coderPrompt(prompt.WithWorkingDir(c.cfg.WorkingDir()))
-	if err != nil {
-		return nil, err
+	for _, agentCfg := range cfg.Config().PrimaryAgents() {
+		agent, err := c.buildPrimaryAgent(ctx, agentCfg)
+		if err != nil {
+			if agentCfg.ID == config.AgentCoder {
+				return nil, err
+			}
+			// A broken user-defined agent should not prevent Crush from
+			// starting.
+			slog.Error("Failed to build agent", "agent", agentCfg.ID, "error", err)
+			continue
+		}
+		c.agents[agentCfg.ID] = agent
 	}
+	return c, nil
+}
 
-	agent, err := c.buildAgent(ctx, prompt, agentCfg, false)
+func (c *coordinator) buildPrimaryAgent(ctx context.Context, agentCfg config.Agent) (SessionAgent, error) {
+	prompt, err := agentPrompt(
+		agentCfg,
+		c.cfg.WorkingDir(),
+		prompt.WithWorkingDir(c.cfg.WorkingDir()),
+		prompt.WithContextPaths(agentCfg.ContextPaths),
+	)
 	if err != nil {
 		return nil, err
 	}
-	c.currentAgent = agent
-	c.agents[config.AgentCoder] = agent
-	return c, nil
+	return c.buildAgent(ctx, prompt, agentCfg, false)
+}
+
+// SetMainAgent implements Coordinator.
+func (c *coordinator) SetMainAgent(agentID string) error {
+	if _, ok := c.agents[agentID]; !ok {
+		return fmt.Errorf("%w: %s", ErrAgentNotFound, agentID)
+	}
+	c.mainAgentID.Set(agentID)
+	return nil
+}
+
+// SetSessionAgent implements Coordinator.
+func (c *coordinator) SetSessionAgent(sessionID, agentID string) error {
+	if _, ok := c.agents[agentID]; !ok {
+		return fmt.Errorf("%w: %s", ErrAgentNotFound, agentID)
+	}
+	if c.IsSessionBusy(sessionID) {
+		return ErrSessionBusy
+	}
+	return c.saveSessionAgent(context.Background(), sessionID, agentID)
+}
+
+// saveSessionAgent saves the agent of a session with the session, so it's
+// kept across restarts.
+func (c *coordinator) saveSessionAgent(ctx context.Context, sessionID, agentID string) error {
+	if err := c.sessions.SetAgent(ctx, sessionID, agentID); err != nil {
+		return fmt.Errorf("saving session agent: %w", err)
+	}
+	c.sessionAgents.Set(sessionID, agentID)
+	return nil
+}
+
+// SessionAgentID implements Coordinator.
+func (c *coordinator) SessionAgentID(sessionID string) string {
+	agentID, ok := c.sessionAgents.Get(sessionID)
+	if !ok && sessionID != "" {
+		if sess, err := c.sessions.Get(context.Background(), sessionID); err == nil {
+			agentID = sess.AgentID
+			c.sessionAgents.Set(sessionID, agentID)
+		}
+	}
+	if _, ok := c.agents[agentID]; ok {
+		return agentID
+	}
+	if _, ok := c.agents[c.mainAgentID.Get()]; ok {
+		return c.mainAgentID.Get()
+	}
+	return config.AgentCoder
+}
+
+func (c *coordinator) SetPlanMode(sessionID string, enabled bool) {
+	if enabled {
+		c.planSessions.Set(sessionID, true)
+		return
+	}
+	c.planSessions.Del(sessionID)
+}
+
+func (c *coordinator) PlanMode(sessionID string) bool {
+	_, ok := c.planSessions.Get(sessionID)
+	return ok
+}
+
+func (c *coordinator) SetBudget(sessionID string, budget config.Budget) {
+	c.budgets.Set(sessionID, budget)
+}
+
+// sessionAgent returns the agent used by the given session.
+func (c *coordinator) sessionAgent(sessionID string) SessionAgent {
+	return c.agents[c.SessionAgentID(sessionID)]
 }
 
 // Run implements Coordinator.
@@ -138,12 +254,22 @@ func (c *coordinator) Run(ctx context.Context, sessionID string, prompt string,
 		return nil, err
 	}
 
+	agentID := c.SessionAgentID(sessionID)
+	agent := c.agents[agentID]
+	// Sessions keep the agent they started with, even if the main agent
+	// changes later on.
+	if saved, _ := c.sessionAgents.Get(sessionID); saved != agentID {
+		if err := c.saveSessionAgent(ctx, sessionID, agentID); err != nil {
+			slog.Error("Failed to save session agent", "session_id", sessionID, "error", err)
+		}
+	}
+
 	// refresh models before each run
-	if err := c.UpdateModels(ctx); err != nil {
+	if err := c.updateAgentModels(ctx, agentID, agent); err != nil {
 		return nil, fmt.Errorf("failed to update models: %w", err)
 	}
 
-	model := c.currentAgent.Model()
+	model := agent.Model()
 	maxTokens := model.CatwalkCfg.DefaultMaxTokens
 	if model.ModelCfg.MaxTokens != 0 {
 		maxTokens = model.ModelCfg.MaxTokens
@@ -174,8 +300,18 @@ func (c *coordinator) Run(ctx context.Context, sessionID string, prompt string,
 		}
 	}
 
+	// Sessions isolated in a git worktree work in it rather than in the
+	// project directory.
+	var workingDir string
+	if wt, err := worktree.Find(ctx, c.cfg.WorkingDir(), sessionID); err == nil {
+		workingDir = wt.Path
+	}
+
+	budget, _ := c.budgets.Get(sessionID)
+	budget = c.cfg.Config().Agents[agentID].Budget.Override(budget)
+
 	run := func() (*fantasy.AgentResult, error) {
-		return c.currentAgent.Run(ctx, SessionAgentCall{
+		return agent.Run(ctx, SessionAgentCall{
 			SessionID:        sessionID,
 			Prompt:           prompt,
 			Attachments:      attachments,
@@ -186,9 +322,17 @@ func (c *coordinator) Run(ctx context.Context, sessionID string, prompt string,
 			TopK:             topK,
 			FrequencyPenalty: freqPenalty,
 			PresencePenalty:  presPenalty,
+			WorkingDir:       workingDir,
+			PlanMode:         c.PlanMode(sessionID),
+			Budget:           budget,
 		})
 	}
 	result, originalErr := run()
+	if originalErr == nil {
+		if _, ok := c.approvedPlans.Take(sessionID); ok {
+			return c.Run(ctx, sessionID, executePlanPrompt)
+		}
+	}
 
 	if c.isUnauthorized(originalErr) {
 		switch {
@@ -378,12 +522,18 @@ func mergeCallOptions(model Model, cfg config.ProviderConfig) (fantasy.ProviderO
 }
 
 func (c *coordinator) buildAgent(ctx context.Context, prompt *prompt.Prompt, agent config.Agent, isSubAgent bool) (SessionAgent, error) {
-	large, small, err := c.buildAgentModels(ctx, isSubAgent)
+	large, small, err := c.buildAgentModels(ctx, agent.Model, isSubAgent)
 	if err != nil {
 		return nil, err
 	}
 
 	largeProviderCfg, _ := c.cfg.Config().Providers.Get(large.ModelCfg.Provider)
+	// Sub-agents work within the turn of their parent, which is already
+	// checkpointed.
+	var checkpoints *checkpoint.Service
+	if !isSubAgent && !c.cfg.Config().Options.DisableCheckpoints {
+		checkpoints = checkpoint.NewService(c.cfg.Config().Options.DataDirectory)
+	}
 	result := NewSessionAgent(SessionAgentOptions{
 		LargeModel:           large,
 		SmallModel:           small,
@@ -396,6 +546,13 @@ func (c *coordinator) buildAgent(ctx context.Context, prompt *prompt.Prompt, age
 		Messages:             c.messages,
 		Tools:                nil,
 		Notify:               c.notify,
+		Hooks:                c.hooks,
+		LoopDetection:        c.cfg.Config().Options.LoopDetection,
+		Checkpoints:          checkpoints,
+		WorkingDir:           c.cfg.WorkingDir(),
+		BuildSystemPrompt: func(ctx context.Context, workingDir string) (string, error) {
+			return prompt.ForWorkingDir(workingDir).Build(ctx, large.Model.Provider(), large.Model.Model(), c.cfg)
+		},
 	})
 
 	c.readyWg.Go(func() error {
@@ -452,19 +609,35 @@ func (c *coordinator) buildTools(ctx context.Context, agent config.Agent) ([]fan
 		tools.NewDownloadTool(c.permissions, c.cfg.WorkingDir(), nil),
 		tools.NewEditTool(c.lspManager, c.permissions, c.history, c.filetracker, c.cfg.WorkingDir()),
 		tools.NewMultiEditTool(c.lspManager, c.permissions, c.history, c.filetracker, c.cfg.WorkingDir()),
+		tools.NewNotebookEditTool(c.permissions, c.history, c.filetracker, c.cfg.WorkingDir()),
 		tools.NewFetchTool(c.permissions, c.cfg.WorkingDir(), nil),
 		tools.NewGlobTool(c.cfg.WorkingDir()),
 		tools.NewGrepTool(c.cfg.WorkingDir(), c.cfg.Config().Tools.Grep),
 		tools.NewLsTool(c.permissions, c.cfg.WorkingDir(), c.cfg.Config().Tools.Ls),
 		tools.NewSourcegraphTool(nil),
 		tools.NewTodosTool(c.sessions),
+		tools.NewPlanTool(c.sessions, c.permissions, c.cfg.WorkingDir(), func(_ context.Context, sessionID string) {
+			c.planSessions.Del(sessionID)
+			c.approvedPlans.Set(sessionID, true)
+		}),
 		tools.NewViewTool(c.lspManager, c.permissions, c.filetracker, c.cfg.WorkingDir(), c.cfg.Config().Options.SkillsPaths...),
 		tools.NewWriteTool(c.lspManager, c.permissions, c.history, c.filetracker, c.cfg.WorkingDir()),
 	)
 
 	// Add LSP tools if user has configured LSPs or auto_lsp is enabled (nil or true).
 	if len(c.cfg.Config().LSP) > 0 || c.cfg.Config().Options.AutoLSP == nil || *c.cfg.Config().Options.AutoLSP {
-		allTools = append(allTools, tools.NewDiagnosticsTool(c.lspManager), tools.NewReferencesTool(c.lspManager), tools.NewLSPRestartTool(c.lspManager))
+		allTools = append(
+			allTools,
+			tools.NewDiagnosticsTool(c.lspManager),
+			tools.NewReferencesTool(c.lspManager),
+			tools.NewDefinitionTool(c.lspManager),
+			tools.NewImplementationTool(c.lspManager),
+			tools.NewHoverTool(c.lspManager),
+			tools.NewSymbolsTool(c.lspManager),
+			tools.NewRenameTool(c.lspManager, c.permissions, c.history, c.filetracker, c.cfg.WorkingDir()),
+			tools.NewCodeActionTool(c.lspManager, c.permissions, c.history, c.filetracker, c.cfg.WorkingDir()),
+			tools.NewLSPRestartTool(c.lspManager),
+		)
 	}
 
 	if len(c.cfg.Config().MCP) > 0 {
@@ -477,7 +650,9 @@ func (c *coordinator) buildTools(ctx context.Context, agent config.Agent) ([]fan
 
 	var filteredTools []fantasy.AgentTool
 	for _, tool := range allTools {
-		if slices.Contains(agent.AllowedTools, tool.Info().Name) {
+		// The plan tool is the only way out of plan mode, so every agent gets
+		// it. It's only offered while in plan mode.
+		if name := tool.Info().Name; slices.Contains(agent.AllowedTools, name) || name == tools.PlanToolName {
 			filteredTools = append(filteredTools, tool)
 		}
 	}
@@ -508,13 +683,27 @@ func (c *coordinator) buildTools(ctx context.Context, agent config.Agent) ([]fan
 	slices.SortFunc(filteredTools, func(a, b fantasy.AgentTool) int {
 		return strings.Compare(a.Info().Name, b.Info().Name)
 	})
+
+	for i, tool := range filteredTools {
+		filteredTools[i] = permission.WrapTool(tool)
+	}
+	if c.hooks != nil {
+		for i, tool := range filteredTools {
+			filteredTools[i] = c.hooks.WrapTool(tool)
+		}
+	}
 	return filteredTools, nil
 }
 
-// TODO: when we support multiple agents we need to change this so that we pass in the agent specific model config
-func (c *coordinator) buildAgentModels(ctx context.Context, isSubAgent bool) (Model, Model, error) {
-	largeModelCfg, ok := c.cfg.Config().Models[config.SelectedModelTypeLarge]
+// buildAgentModels builds the models used by an agent. The first model is the
+// one selected by modelType, which the agent uses to respond. The second one is
+// always the small model.
+func (c *coordinator) buildAgentModels(ctx context.Context, modelType config.SelectedModelType, isSubAgent bool) (Model, Model, error) {
+	largeModelCfg, ok := c.cfg.Config().Models[cmp.Or(modelType, config.SelectedModelTypeLarge)]
 	if !ok {
+		if modelType == config.SelectedModelTypeSmall {
+			return Model{}, Model{}, errSmallModelNotSelected
+		}
 		return Model{}, Model{}, errLargeModelNotSelected
 	}
 	smallModelCfg, ok := c.cfg.Config().Models[config.SelectedModelTypeSmall]
@@ -588,6 +777,7 @@ func (c *coordinator) buildAgentModels(ctx context.Context, isSubAgent bool) (Mo
 			Model:      largeModel,
 			CatwalkCfg: *largeCatwalkModel,
 			ModelCfg:   largeModelCfg,
+			Fallbacks:  c.buildFallbacks(ctx, largeModelCfg, isSubAgent),
 		}, Model{
 			Model:      smallModel,
 			CatwalkCfg: *smallCatwalkModel,
@@ -595,6 +785,57 @@ func (c *coordinator) buildAgentModels(ctx context.Context, isSubAgent bool) (Mo
 		}, nil
 }
 
+// buildFallbacks builds the models to fail over to from the given one.
+// Fallbacks that can't be built are skipped, as they shouldn't keep the
+// primary model from working.
+func (c *coordinator) buildFallbacks(ctx context.Context, modelCfg config.SelectedModel, isSubAgent bool) []Model {
+	var fallbacks []Model
+	for _, fallbackCfg := range modelCfg.Fallbacks {
+		providerCfg, ok := c.cfg.Config().Providers.Get(fallbackCfg.Provider)
+		if !ok {
+			slog.Warn("Skipping fallback model, its provider is not configured", "provider", fallbackCfg.Provider, "model", fallbackCfg.Model)
+			continue
+		}
+		var catwalkModel *catwalk.Model
+		for _, m := range providerCfg.Models {
+			if m.ID == fallbackCfg.Model {
+				catwalkModel = &m
+			}
+		}
+		if catwalkModel == nil {
+			slog.Warn("Skipping fallback model, it was not found", "provider", fallbackCfg.Provider, "model", fallbackCfg.Model)
+			continue
+		}
+		provider, err := c.buildProvider(providerCfg, fallbackCfg, isSubAgent)
+		if err != nil {
+			slog.Warn("Skipping fallback model", "provider", fallbackCfg.Provider, "model", fallbackCfg.Model, "error", err)
+			continue
+		}
+		modelID := fallbackCfg.Model
+		if fallbackCfg.Provider == openrouter.Name && isExactoSupported(modelID) {
+			modelID += ":exacto"
+		}
+		languageModel, err := provider.LanguageModel(ctx, modelID)
+		if err != nil {
+			slog.Warn("Skipping fallback model", "provider", fallbackCfg.Provider, "model", fallbackCfg.Model, "error", err)
+			continue
+		}
+
+		model := Model{
+			Model:      languageModel,
+			CatwalkCfg: *catwalkModel,
+			ModelCfg:   fallbackCfg,
+		}
+		opts := &callOptions{
+			maxOutputTokens: cmp.Or(fallbackCfg.MaxTokens, catwalkModel.DefaultMaxTokens),
+		}
+		opts.providerOptions, opts.temperature, opts.topP, opts.topK, opts.frequencyPenalty, opts.presencePenalty = mergeCallOptions(model, providerCfg)
+		model.callOptions = opts
+		fallbacks = append(fallbacks, model)
+	}
+	return fallbacks
+}
+
 func (c *coordinator) buildAnthropicProvider(baseURL, apiKey string, headers map[string]string, providerID string) (fantasy.Provider, error) {
 	var opts []anthropic.Option
 
@@ -856,64 +1097,119 @@ func isExactoSupported(modelID string) bool {
 }
 
 func (c *coordinator) Cancel(sessionID string) {
-	c.currentAgent.Cancel(sessionID)
+	// The session may still be running on an agent it switched away from.
+	for _, agent := range c.agents {
+		agent.Cancel(sessionID)
+	}
 }
 
 func (c *coordinator) CancelAll() {
-	c.currentAgent.CancelAll()
+	for _, agent := range c.agents {
+		agent.CancelAll()
+	}
 }
 
 func (c *coordinator) ClearQueue(sessionID string) {
-	c.currentAgent.ClearQueue(sessionID)
+	for _, agent := range c.agents {
+		agent.ClearQueue(sessionID)
+	}
 }
 
 func (c *coordinator) IsBusy() bool {
-	return c.currentAgent.IsBusy()
+	for _, agent := range c.agents {
+		if agent.IsBusy() {
+			return true
+		}
+	}
+	return false
 }
 
 func (c *coordinator) IsSessionBusy(sessionID string) bool {
-	return c.currentAgent.IsSessionBusy(sessionID)
+	for _, agent := range c.agents {
+		if agent.IsSessionBusy(sessionID) {
+			return true
+		}
+	}
+	return false
 }
 
 func (c *coordinator) Model() Model {
-	return c.currentAgent.Model()
+	return c.agents[c.SessionAgentID("")].Model()
 }
 
 func (c *coordinator) UpdateModels(ctx context.Context) error {
-	// build the models again so we make sure we get the latest config
-	large, small, err := c.buildAgentModels(ctx, false)
-	if err != nil {
-		return err
+	for agentID, agent := range c.agents {
+		if err := c.updateAgentModels(ctx, agentID, agent); err != nil {
+			return err
+		}
 	}
-	c.currentAgent.SetModels(large, small)
+	return nil
+}
 
-	agentCfg, ok := c.cfg.Config().Agents[config.AgentCoder]
+func (c *coordinator) updateAgentModels(ctx context.Context, agentID string, agent SessionAgent) error {
+	agentCfg, ok := c.cfg.Config().Agents[agentID]
 	if !ok {
-		return errCoderAgentNotConfigured
+		if agentID == config.AgentCoder {
+			return errCoderAgentNotConfigured
+		}
+		return fmt.Errorf("%w: %s", ErrAgentNotFound, agentID)
+	}
+
+	// build the models again so we make sure we get the latest config
+	large, small, err := c.buildAgentModels(ctx, agentCfg.Model, false)
+	if err != nil {
+		return err
 	}
+	agent.SetModels(large, small)
 
 	tools, err := c.buildTools(ctx, agentCfg)
 	if err != nil {
 		return err
 	}
-	c.currentAgent.SetTools(tools)
+	agent.SetTools(tools)
 	return nil
 }
 
 func (c *coordinator) QueuedPrompts(sessionID string) int {
-	return c.currentAgent.QueuedPrompts(sessionID)
+	var count int
+	for _, agent := range c.agents {
+		count += agent.QueuedPrompts(sessionID)
+	}
+	return count
 }
 
 func (c *coordinator) QueuedPromptsList(sessionID string) []string {
-	return c.currentAgent.QueuedPromptsList(sessionID)
+	var prompts []string
+	for _, agent := range c.agents {
+		prompts = append(prompts, agent.QueuedPromptsList(sessionID)...)
+	}
+	return prompts
 }
 
 func (c *coordinator) Summarize(ctx context.Context, sessionID string) error {
-	providerCfg, ok := c.cfg.Config().Providers.Get(c.currentAgent.Model().ModelCfg.Provider)
+	agent := c.sessionAgent(sessionID)
+	providerCfg, ok := c.cfg.Config().Providers.Get(agent.Model().ModelCfg.Provider)
 	if !ok {
 		return errModelProviderNotConfigured
 	}
-	return c.currentAgent.Summarize(ctx, sessionID, getProviderOptions(c.currentAgent.Model(), providerCfg))
+	return agent.Summarize(ctx, sessionID, getProviderOptions(agent.Model(), providerCfg))
+}
+
+func (c *coordinator) Sample(ctx context.Context, call fantasy.Call) (*fantasy.Response, error) {
+	small, _, err := c.buildAgentModels(ctx, config.SelectedModelTypeSmall, true)
+	if err != nil {
+		return nil, err
+	}
+	providerCfg, ok := c.cfg.Config().Providers.Get(small.ModelCfg.Provider)
+	if !ok {
+		return nil, errSmallModelProviderNotConfigured
+	}
+	if providerCfg.SystemPromptPrefix != "" {
+		call.Prompt = append(fantasy.Prompt{fantasy.NewSystemMessage(providerCfg.SystemPromptPrefix)}, call.Prompt...)
+	}
+	call.ProviderOptions = getProviderOptions(small, providerCfg)
+	call.UserAgent = userAgent
+	return small.Model.Generate(ctx, call)
 }
 
 func (c *coordinator) isUnauthorized(err error) bool {
@@ -1001,6 +1297,7 @@ func (c *coordinator) runSubAgent(ctx context.Context, params subAgentParams) (f
 		FrequencyPenalty: model.ModelCfg.FrequencyPenalty,
 		PresencePenalty:  model.ModelCfg.PresencePenalty,
 		NonInteractive:   true,
+		WorkingDir:       tools.GetWorkingDirFromContext(ctx, ""),
 	})
 	if err != nil {
 		return fantasy.NewTextErrorResponse("error generating response"), nil
@@ -8,6 +8,7 @@ import (
 	"charm.land/catwalk/pkg/catwalk"
 	"charm.land/fantasy"
 	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/csync"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -383,3 +384,52 @@ func TestUpdateParentSessionCost(t *testing.T) {
 		assert.InDelta(t, 0.0, updated.Cost, 1e-9)
 	})
 }
+
+func TestCoordinatorSessionAgents(t *testing.T) {
+	t.Parallel()
+
+	env := testEnv(t)
+	coder := &mockSessionAgent{}
+	reviewer := &mockSessionAgent{}
+	newCoordinator := func() *coordinator {
+		return &coordinator{
+			sessions: env.sessions,
+			agents: map[string]SessionAgent{
+				config.AgentCoder: coder,
+				"reviewer":        reviewer,
+			},
+			mainAgentID:   csync.NewValue(config.AgentCoder),
+			sessionAgents: csync.NewMap[string, string](),
+		}
+	}
+	coord := newCoordinator()
+
+	session1, err := env.sessions.Create(t.Context(), "Session 1")
+	require.NoError(t, err)
+	session2, err := env.sessions.Create(t.Context(), "Session 2")
+	require.NoError(t, err)
+
+	assert.Equal(t, config.AgentCoder, coord.SessionAgentID(session1.ID))
+
+	require.NoError(t, coord.SetSessionAgent(session1.ID, "reviewer"))
+	assert.Equal(t, "reviewer", coord.SessionAgentID(session1.ID))
+	assert.Equal(t, config.AgentCoder, coord.SessionAgentID(session2.ID))
+
+	require.NoError(t, coord.SetMainAgent("reviewer"))
+	assert.Equal(t, "reviewer", coord.SessionAgentID(session2.ID))
+
+	err = coord.SetSessionAgent(session1.ID, "missing")
+	require.ErrorIs(t, err, ErrAgentNotFound)
+	err = coord.SetMainAgent("missing")
+	require.ErrorIs(t, err, ErrAgentNotFound)
+
+	// The agent of a session is saved with it.
+	assert.Equal(t, "reviewer", newCoordinator().SessionAgentID(session1.ID))
+	assert.Equal(t, config.AgentCoder, newCoordinator().SessionAgentID(session2.ID))
+
+	// Cancelling must reach every agent, as the session may have switched
+	// agents while a request was running.
+	coord.Cancel(session1.ID)
+	assert.Equal(t, []string{session1.ID}, coder.cancelled)
+	assert.Equal(t, []string{session1.ID}, reviewer.cancelled)
+}
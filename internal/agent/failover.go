@@ -0,0 +1,273 @@
+package agent
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/agent/hyper"
+	"github.com/charmbracelet/crush/internal/agent/notify"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/pubsub"
+)
+
+// failoverRetries is the number of times a model is retried before failing
+// over, matching the retries of fantasy agents.
+const failoverRetries = 2
+
+// statusOverloaded is the status code of Anthropic's overloaded errors.
+const statusOverloaded = 529
+
+// callOptions are the options of the calls to a model. Fallbacks carry their
+// own, as the options of a call depend on the model it's made for.
+type callOptions struct {
+	providerOptions  fantasy.ProviderOptions
+	maxOutputTokens  int64
+	temperature      *float64
+	topP             *float64
+	topK             *int64
+	frequencyPenalty *float64
+	presencePenalty  *float64
+}
+
+func (o *callOptions) apply(call *fantasy.Call) {
+	if o == nil {
+		return
+	}
+	call.ProviderOptions = o.providerOptions
+	call.MaxOutputTokens = &o.maxOutputTokens
+	call.Temperature = o.temperature
+	call.TopP = o.topP
+	call.TopK = o.topK
+	call.FrequencyPenalty = o.frequencyPenalty
+	call.PresencePenalty = o.presencePenalty
+}
+
+// failoverModel is a language model going through a model and its
+// fallbacks. It fails over to the next model right away on the error classes
+// of the model's config, and once the retries of other retryable errors run
+// out. It then sticks to the fallback, so it's meant to last a single run.
+type failoverModel struct {
+	models  []Model
+	classes []config.FailoverClass
+	// onFailover is called when failing over, before calling the fallback.
+	onFailover func(from, to Model, err error)
+
+	mu       sync.Mutex
+	current  int
+	failures int
+}
+
+var _ fantasy.LanguageModel = (*failoverModel)(nil)
+
+func newFailoverModel(model Model, onFailover func(from, to Model, err error)) *failoverModel {
+	return &failoverModel{
+		models:     append([]Model{model}, model.Fallbacks...),
+		classes:    model.ModelCfg.FailoverClasses(),
+		onFailover: onFailover,
+	}
+}
+
+// Current returns the model calls are currently made to.
+func (m *failoverModel) Current() Model {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.models[m.current]
+}
+
+// Generate implements [fantasy.LanguageModel].
+func (m *failoverModel) Generate(ctx context.Context, call fantasy.Call) (*fantasy.Response, error) {
+	return failover(m, call, func(model fantasy.LanguageModel, call fantasy.Call) (*fantasy.Response, error) {
+		return model.Generate(ctx, call)
+	})
+}
+
+// Stream implements [fantasy.LanguageModel]. Errors streamed before any
+// output are failed over too, as most providers report them this way.
+func (m *failoverModel) Stream(ctx context.Context, call fantasy.Call) (fantasy.StreamResponse, error) {
+	return failover(m, call, func(model fantasy.LanguageModel, call fantasy.Call) (fantasy.StreamResponse, error) {
+		stream, err := model.Stream(ctx, call)
+		if err != nil || len(m.models) == 1 {
+			return stream, err
+		}
+		return peekStream(stream)
+	})
+}
+
+// GenerateObject implements [fantasy.LanguageModel]. Objects aren't failed
+// over.
+func (m *failoverModel) GenerateObject(ctx context.Context, call fantasy.ObjectCall) (*fantasy.ObjectResponse, error) {
+	return m.Current().Model.GenerateObject(ctx, call)
+}
+
+// StreamObject implements [fantasy.LanguageModel]. Objects aren't failed
+// over.
+func (m *failoverModel) StreamObject(ctx context.Context, call fantasy.ObjectCall) (fantasy.ObjectStreamResponse, error) {
+	return m.Current().Model.StreamObject(ctx, call)
+}
+
+// Provider implements [fantasy.LanguageModel].
+func (m *failoverModel) Provider() string {
+	return m.Current().Model.Provider()
+}
+
+// Model implements [fantasy.LanguageModel].
+func (m *failoverModel) Model() string {
+	return m.Current().Model.Model()
+}
+
+func failover[T any](m *failoverModel, call fantasy.Call, fn func(fantasy.LanguageModel, fantasy.Call) (T, error)) (T, error) {
+	for {
+		m.mu.Lock()
+		i := m.current
+		m.mu.Unlock()
+
+		model := m.models[i]
+		modelCall := call
+		model.callOptions.apply(&modelCall)
+		result, err := fn(model.Model, modelCall)
+		next, ok := m.next(i, err)
+		if !ok {
+			return result, err
+		}
+		if m.onFailover != nil {
+			m.onFailover(model, next, err)
+		}
+	}
+}
+
+// next records the outcome of a call to the i-th model, returning the model
+// to fail over to, if any.
+func (m *failoverModel) next(i int, err error) (Model, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err == nil {
+		m.failures = 0
+		return Model{}, false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return Model{}, false
+	}
+	if i != m.current {
+		// A concurrent call failed over already.
+		return m.models[m.current], true
+	}
+	if m.current == len(m.models)-1 {
+		return Model{}, false
+	}
+
+	m.failures++
+	if !slices.Contains(m.classes, failoverClass(err)) {
+		var providerErr *fantasy.ProviderError
+		if !errors.As(err, &providerErr) || !providerErr.IsRetryable() || m.failures <= failoverRetries {
+			return Model{}, false
+		}
+	}
+	m.current++
+	m.failures = 0
+	return m.models[m.current], true
+}
+
+// failedOver records the model a run failed over to on the message being
+// generated, and lets the user know.
+func (a *sessionAgent) failedOver(ctx context.Context, call SessionAgentCall, msg *message.Message, from, to Model, err error) {
+	slog.Warn("Failing over to fallback model",
+		"session_id", call.SessionID,
+		"from", from.ModelCfg.Provider+"/"+from.ModelCfg.Model,
+		"to", to.ModelCfg.Provider+"/"+to.ModelCfg.Model,
+		"error", err,
+	)
+	if msg != nil {
+		msg.Model = to.ModelCfg.Model
+		msg.Provider = to.ModelCfg.Provider
+		if updateErr := a.messages.Update(ctx, *msg); updateErr != nil {
+			slog.Error("Failed to record fallback model", "error", updateErr)
+		}
+	}
+	if !call.NonInteractive && a.notify != nil {
+		a.notify.Publish(pubsub.CreatedEvent, notify.Notification{
+			SessionID: call.SessionID,
+			Type:      notify.TypeFailover,
+			Message:   fmt.Sprintf("%s failed, switched to %s", modelLabel(from), modelLabel(to)),
+		})
+	}
+}
+
+// modelLabel describes a model to the user, like "Claude Sonnet 4 via
+// bedrock".
+func modelLabel(m Model) string {
+	return cmp.Or(m.CatwalkCfg.Name, m.ModelCfg.Model) + " via " + m.ModelCfg.Provider
+}
+
+// failoverClass returns the class of a provider error, or an empty class if
+// it's not a provider error.
+func failoverClass(err error) config.FailoverClass {
+	if errors.Is(err, hyper.ErrNoCredits) {
+		return config.FailoverQuota
+	}
+	var providerErr *fantasy.ProviderError
+	if !errors.As(err, &providerErr) {
+		return ""
+	}
+	message := strings.ToLower(providerErr.Title + " " + providerErr.Message)
+	switch {
+	case providerErr.StatusCode == statusOverloaded || strings.Contains(message, "overloaded"):
+		return config.FailoverOverloaded
+	case providerErr.StatusCode == http.StatusPaymentRequired ||
+		strings.Contains(message, "quota") || strings.Contains(message, "credits"):
+		return config.FailoverQuota
+	case providerErr.StatusCode == http.StatusTooManyRequests:
+		return config.FailoverRateLimit
+	case providerErr.StatusCode == http.StatusUnauthorized || providerErr.StatusCode == http.StatusForbidden:
+		return config.FailoverAuth
+	case providerErr.StatusCode >= http.StatusInternalServerError:
+		return config.FailoverServer
+	default:
+		return ""
+	}
+}
+
+// peekStream reads a stream up to its first output, returning the error
+// streamed before it, if any.
+func peekStream(stream fantasy.StreamResponse) (fantasy.StreamResponse, error) {
+	next, stop := iter.Pull(iter.Seq[fantasy.StreamPart](stream))
+	var peeked []fantasy.StreamPart
+	for {
+		part, ok := next()
+		if !ok {
+			break
+		}
+		if part.Type == fantasy.StreamPartTypeError {
+			stop()
+			return nil, part.Error
+		}
+		peeked = append(peeked, part)
+		if part.Type != fantasy.StreamPartTypeWarnings {
+			break
+		}
+	}
+	return func(yield func(fantasy.StreamPart) bool) {
+		defer stop()
+		for _, part := range peeked {
+			if !yield(part) {
+				return
+			}
+		}
+		for {
+			part, ok := next()
+			if !ok || !yield(part) {
+				return
+			}
+		}
+	}, nil
+}
@@ -0,0 +1,257 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+
+	"charm.land/catwalk/pkg/catwalk"
+	"charm.land/fantasy"
+	"charm.land/fantasy/providers/anthropic"
+	"charm.land/x/vcr"
+	"github.com/charmbracelet/crush/internal/agent/hyper"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeModel is a language model answering calls with the given errors, in
+// order, and with "ok" once they run out.
+type fakeModel struct {
+	fantasy.LanguageModel
+	name  string
+	errs  []error
+	calls int
+}
+
+func (m *fakeModel) Generate(context.Context, fantasy.Call) (*fantasy.Response, error) {
+	if err := m.nextErr(); err != nil {
+		return nil, err
+	}
+	return &fantasy.Response{Content: fantasy.ResponseContent{fantasy.TextContent{Text: "ok"}}}, nil
+}
+
+func (m *fakeModel) Stream(context.Context, fantasy.Call) (fantasy.StreamResponse, error) {
+	err := m.nextErr()
+	return func(yield func(fantasy.StreamPart) bool) {
+		if !yield(fantasy.StreamPart{Type: fantasy.StreamPartTypeWarnings}) {
+			return
+		}
+		if err != nil {
+			yield(fantasy.StreamPart{Type: fantasy.StreamPartTypeError, Error: err})
+			return
+		}
+		if !yield(fantasy.StreamPart{Type: fantasy.StreamPartTypeTextDelta, Delta: "ok"}) {
+			return
+		}
+		yield(fantasy.StreamPart{Type: fantasy.StreamPartTypeFinish})
+	}, nil
+}
+
+func (m *fakeModel) nextErr() error {
+	m.calls++
+	if len(m.errs) == 0 {
+		return nil
+	}
+	err := m.errs[0]
+	m.errs = m.errs[1:]
+	return err
+}
+
+func (m *fakeModel) Provider() string { return m.name }
+func (m *fakeModel) Model() string    { return m.name }
+
+func fakeModels(failoverOn []config.FailoverClass, models ...*fakeModel) Model {
+	primary := Model{
+		Model:    models[0],
+		ModelCfg: config.SelectedModel{Provider: models[0].name, Model: models[0].name, FailoverOn: failoverOn},
+	}
+	for _, m := range models[1:] {
+		primary.Fallbacks = append(primary.Fallbacks, Model{
+			Model:    m,
+			ModelCfg: config.SelectedModel{Provider: m.name, Model: m.name},
+		})
+	}
+	return primary
+}
+
+func providerError(status int, message string) error {
+	return &fantasy.ProviderError{StatusCode: status, Message: message}
+}
+
+func TestFailoverModel(t *testing.T) {
+	t.Parallel()
+
+	overloaded := providerError(statusOverloaded, "Overloaded")
+	unavailable := providerError(http.StatusServiceUnavailable, "unavailable")
+	badRequest := providerError(http.StatusBadRequest, "bad request")
+
+	tests := []struct {
+		name       string
+		failoverOn []config.FailoverClass
+		primary    []error
+		fallback   []error
+		err        error
+		current    string
+		calls      [2]int
+	}{
+		{name: "no errors", current: "primary", calls: [2]int{1, 0}},
+		{name: "failover class", primary: []error{overloaded}, current: "fallback", calls: [2]int{1, 1}},
+		{name: "retries first", primary: []error{unavailable, unavailable}, current: "primary", calls: [2]int{3, 0}},
+		{name: "retries run out", primary: []error{unavailable, unavailable, unavailable}, current: "fallback", calls: [2]int{3, 1}},
+		{name: "configured classes", failoverOn: []config.FailoverClass{config.FailoverServer}, primary: []error{unavailable}, current: "fallback", calls: [2]int{1, 1}},
+		{name: "not retryable", primary: []error{badRequest}, err: badRequest, current: "primary", calls: [2]int{1, 0}},
+		{name: "last model fails", primary: []error{overloaded}, fallback: []error{overloaded, overloaded, overloaded}, err: overloaded, current: "fallback", calls: [2]int{1, 3}},
+	}
+	for _, stream := range []bool{false, true} {
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("%s stream=%v", tt.name, stream), func(t *testing.T) {
+				t.Parallel()
+				primary := &fakeModel{name: "primary", errs: tt.primary}
+				fallback := &fakeModel{name: "fallback", errs: tt.fallback}
+				var failedOver []string
+				m := newFailoverModel(fakeModels(tt.failoverOn, primary, fallback), func(from, to Model, _ error) {
+					failedOver = append(failedOver, from.ModelCfg.Model+" -> "+to.ModelCfg.Model)
+				})
+
+				// Retries are up to the caller, like fantasy agents.
+				var err error
+				for range failoverRetries + 1 {
+					if stream {
+						_, err = m.Stream(t.Context(), fantasy.Call{})
+					} else {
+						_, err = m.Generate(t.Context(), fantasy.Call{})
+					}
+					var providerErr *fantasy.ProviderError
+					if err == nil || !errors.As(err, &providerErr) || !providerErr.IsRetryable() {
+						break
+					}
+				}
+
+				require.Equal(t, tt.err, err)
+				require.Equal(t, tt.current, m.Current().ModelCfg.Model)
+				require.Equal(t, tt.calls, [2]int{primary.calls, fallback.calls})
+				if tt.calls[1] > 0 {
+					require.Equal(t, []string{"primary -> fallback"}, failedOver)
+				} else {
+					require.Empty(t, failedOver)
+				}
+			})
+		}
+	}
+
+	t.Run("sticks to the fallback", func(t *testing.T) {
+		t.Parallel()
+		primary := &fakeModel{name: "primary", errs: []error{overloaded}}
+		fallback := &fakeModel{name: "fallback"}
+		m := newFailoverModel(fakeModels(nil, primary, fallback), nil)
+		for range 3 {
+			_, err := m.Generate(t.Context(), fantasy.Call{})
+			require.NoError(t, err)
+		}
+		require.Equal(t, 1, primary.calls)
+		require.Equal(t, 3, fallback.calls)
+		require.Equal(t, "fallback", m.Model())
+	})
+
+	t.Run("applies call options", func(t *testing.T) {
+		t.Parallel()
+		var got []int64
+		record := func(_ fantasy.LanguageModel, call fantasy.Call) (*fantasy.Response, error) {
+			got = append(got, *call.MaxOutputTokens)
+			if len(got) == 1 {
+				return nil, overloaded
+			}
+			return &fantasy.Response{}, nil
+		}
+		model := fakeModels(nil, &fakeModel{name: "primary"}, &fakeModel{name: "fallback"})
+		model.Fallbacks[0].callOptions = &callOptions{maxOutputTokens: 20}
+		maxTokens := int64(10)
+		_, err := failover(newFailoverModel(model, nil), fantasy.Call{MaxOutputTokens: &maxTokens}, record)
+		require.NoError(t, err)
+		require.Equal(t, []int64{10, 20}, got)
+	})
+}
+
+func TestFailoverClass(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		err   error
+		class config.FailoverClass
+	}{
+		{providerError(statusOverloaded, "Overloaded"), config.FailoverOverloaded},
+		{providerError(http.StatusServiceUnavailable, "The model is overloaded"), config.FailoverOverloaded},
+		{providerError(http.StatusPaymentRequired, "Payment required"), config.FailoverQuota},
+		{providerError(http.StatusForbidden, "Key limit exceeded, insufficient credits"), config.FailoverQuota},
+		{providerError(http.StatusTooManyRequests, "Rate limit exceeded"), config.FailoverRateLimit},
+		{providerError(http.StatusUnauthorized, "Invalid API key"), config.FailoverAuth},
+		{providerError(http.StatusBadGateway, "Bad gateway"), config.FailoverServer},
+		{providerError(http.StatusBadRequest, "Bad request"), ""},
+		{fmt.Errorf("hyper: %w", hyper.ErrNoCredits), config.FailoverQuota},
+		{errors.New("connection reset"), ""},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.class, failoverClass(tt.err), tt.err.Error())
+	}
+}
+
+// TestFailover replays the simple test cassette of Anthropic's Sonnet behind
+// a primary model that's always overloaded.
+func TestFailover(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skipping on windows for now")
+	}
+
+	overloaded := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(statusOverloaded)
+		fmt.Fprint(w, `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`)
+	}))
+	t.Cleanup(overloaded.Close)
+
+	provider, err := anthropic.New(anthropic.WithAPIKey("test"), anthropic.WithBaseURL(overloaded.URL))
+	require.NoError(t, err)
+	primary, err := provider.LanguageModel(t.Context(), "claude-sonnet-4-6")
+	require.NoError(t, err)
+
+	r := vcr.NewRecorder(t)
+	large, small := getModels(t, r, modelPairs[0])
+	env := testEnv(t)
+	createSimpleGoProject(t, env.workingDir)
+	agent, err := coderAgent(r, env, large, small)
+	require.NoError(t, err)
+
+	catwalkCfg := catwalk.Model{ContextWindow: 200000, DefaultMaxTokens: 10000}
+	agent.SetModels(Model{
+		Model:      primary,
+		CatwalkCfg: catwalkCfg,
+		ModelCfg:   config.SelectedModel{Provider: "anthropic", Model: "claude-sonnet-4-6"},
+		Fallbacks: []Model{{
+			Model:      large,
+			CatwalkCfg: catwalkCfg,
+			ModelCfg:   config.SelectedModel{Provider: "anthropic-vcr", Model: "claude-sonnet-4-6"},
+		}},
+	}, Model{Model: small, CatwalkCfg: catwalkCfg})
+
+	session, err := env.sessions.Create(t.Context(), "New Session")
+	require.NoError(t, err)
+	_, err = agent.Run(t.Context(), SessionAgentCall{
+		Prompt:          "Hello",
+		SessionID:       session.ID,
+		MaxOutputTokens: 10000,
+	})
+	require.NoError(t, err)
+
+	msgs, err := env.messages.List(t.Context(), session.ID)
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+	require.Equal(t, message.Assistant, msgs[1].Role)
+	require.Equal(t, "anthropic-vcr", msgs[1].Provider)
+	require.Equal(t, "claude-sonnet-4-6", msgs[1].Model)
+	require.NotEmpty(t, msgs[1].Content().Text)
+}
@@ -0,0 +1,100 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"charm.land/catwalk/pkg/catwalk"
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/hooks"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/stretchr/testify/require"
+)
+
+// gatedModel is a fake model whose first stream waits for the gate to open,
+// keeping the session busy.
+type gatedModel struct {
+	fakeModel
+	started chan struct{}
+	gate    chan struct{}
+}
+
+func (m *gatedModel) Stream(ctx context.Context, call fantasy.Call) (fantasy.StreamResponse, error) {
+	select {
+	case m.started <- struct{}{}:
+		<-m.gate
+	default:
+	}
+	return m.fakeModel.Stream(ctx, call)
+}
+
+func TestUserPromptSubmitHooksRunOnce(t *testing.T) {
+	env := testEnv(t)
+	count := filepath.Join(t.TempDir(), "count")
+
+	cfg, err := config.Init(t.TempDir(), "", false)
+	require.NoError(t, err)
+	cfg.Config().Hooks = config.Hooks{
+		UserPromptSubmit: []config.Hook{{Command: "echo x >> " + count + "; echo context"}},
+	}
+
+	large := &gatedModel{
+		fakeModel: fakeModel{name: "large"},
+		started:   make(chan struct{}),
+		gate:      make(chan struct{}),
+	}
+	catwalkCfg := catwalk.Model{ContextWindow: 200000, DefaultMaxTokens: 10000}
+	agent := NewSessionAgent(SessionAgentOptions{
+		LargeModel: Model{Model: large, CatwalkCfg: catwalkCfg},
+		SmallModel: Model{Model: &fakeModel{name: "small"}, CatwalkCfg: catwalkCfg},
+		IsYolo:     true,
+		Sessions:   env.sessions,
+		Messages:   env.messages,
+		Hooks:      hooks.NewRunner(cfg),
+	})
+
+	sess, err := env.sessions.Create(t.Context(), "New Session")
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := agent.Run(t.Context(), SessionAgentCall{Prompt: "first", SessionID: sess.ID})
+		done <- err
+	}()
+	<-large.started
+
+	// The session is busy, so the prompt is queued, and run once the first
+	// one is done.
+	result, err := agent.Run(t.Context(), SessionAgentCall{Prompt: "second", SessionID: sess.ID})
+	require.NoError(t, err)
+	require.Nil(t, result)
+	require.Equal(t, 1, agent.QueuedPrompts(sess.ID))
+	close(large.gate)
+	require.NoError(t, <-done)
+
+	data, err := os.ReadFile(count)
+	require.NoError(t, err)
+	require.Equal(t, 2, strings.Count(string(data), "x"))
+
+	msgs, err := env.messages.List(t.Context(), sess.ID)
+	require.NoError(t, err)
+	var prompts []string
+	for _, msg := range msgs {
+		if msg.Role != message.User {
+			continue
+		}
+		prompts = append(prompts, msg.Content().Text)
+		var hookFiles int
+		for _, bin := range msg.BinaryContent() {
+			if bin.Path == string(hooks.UserPromptSubmit)+" hook" {
+				hookFiles++
+			}
+		}
+		require.Equal(t, 1, hookFiles, msg.Content().Text)
+	}
+	require.Equal(t, []string{"first", "second"}, prompts)
+}
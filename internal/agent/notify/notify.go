@@ -9,6 +9,11 @@ type Type string
 const (
 	// TypeAgentFinished indicates the agent has completed its turn.
 	TypeAgentFinished Type = "agent_finished"
+	// TypeBudgetExceeded indicates the agent was stopped because it hit one
+	// of its budgets.
+	TypeBudgetExceeded Type = "budget_exceeded"
+	// TypeFailover indicates the agent failed over to a fallback model.
+	TypeFailover Type = "failover"
 )
 
 // Notification represents a domain event published by the agent.
@@ -16,4 +21,6 @@ type Notification struct {
 	SessionID    string
 	SessionTitle string
 	Type         Type
+	// Message describes the event, when its type isn't enough.
+	Message string
 }
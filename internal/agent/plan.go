@@ -0,0 +1,58 @@
+package agent
+
+import (
+	_ "embed"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/agent/tools"
+	"github.com/charmbracelet/crush/internal/config"
+)
+
+//go:embed templates/plan_mode.md
+var planModePrompt string
+
+// executePlanPrompt is sent once a plan is approved, to start implementing it.
+const executePlanPrompt = "The plan was approved. Implement it now, keeping the todo list up to date as you go."
+
+// filterPlanModeTools returns the tools available in plan mode when planMode
+// is true. Otherwise, it returns every tool but the plan tool, which is only
+// useful in plan mode.
+func filterPlanModeTools(agentTools []fantasy.AgentTool, planMode bool) []fantasy.AgentTool {
+	names := make([]string, len(agentTools))
+	for i, tool := range agentTools {
+		names[i] = tool.Info().Name
+	}
+	allowed := make(map[string]bool)
+	if planMode {
+		for _, name := range config.PlanModeTools(names) {
+			allowed[name] = true
+		}
+	}
+
+	filtered := make([]fantasy.AgentTool, 0, len(agentTools))
+	for _, tool := range agentTools {
+		name := tool.Info().Name
+		if allowed[name] || (!planMode && name != tools.PlanToolName) {
+			filtered = append(filtered, tool)
+		}
+	}
+	return filtered
+}
+
+// isPlanApproved reports whether the plan was approved in the last step, in
+// which case the agent must stop so the plan is implemented in a new turn
+// with the full toolset.
+func isPlanApproved(steps []fantasy.StepResult) bool {
+	if len(steps) == 0 {
+		return false
+	}
+	for _, result := range steps[len(steps)-1].Content.ToolResults() {
+		if result.ToolName != tools.PlanToolName {
+			continue
+		}
+		if _, ok := fantasy.AsToolResultOutputType[fantasy.ToolResultOutputContentError](result.Result); !ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterPlanModeTools(t *testing.T) {
+	noop := func(context.Context, struct{}, fantasy.ToolCall) (fantasy.ToolResponse, error) {
+		return fantasy.ToolResponse{}, nil
+	}
+	var agentTools []fantasy.AgentTool
+	for _, name := range []string{"agent", "bash", "edit", "grep", "plan", "view", "mcp_github_create_issue"} {
+		agentTools = append(agentTools, fantasy.NewAgentTool(name, "", noop))
+	}
+	names := func(agentTools []fantasy.AgentTool) []string {
+		var names []string
+		for _, tool := range agentTools {
+			names = append(names, tool.Info().Name)
+		}
+		return names
+	}
+
+	require.Equal(t, []string{"agent", "grep", "plan", "view"}, names(filterPlanModeTools(agentTools, true)))
+	require.Equal(t, []string{"agent", "bash", "edit", "grep", "view", "mcp_github_create_issue"}, names(filterPlanModeTools(agentTools, false)))
+}
+
+func TestIsPlanApproved(t *testing.T) {
+	t.Run("no steps", func(t *testing.T) {
+		require.False(t, isPlanApproved(nil))
+	})
+
+	t.Run("approved plan", func(t *testing.T) {
+		steps := []fantasy.StepResult{makeToolStep("plan", `{"plan":"..."}`, "approved")}
+		require.True(t, isPlanApproved(steps))
+	})
+
+	t.Run("rejected plan", func(t *testing.T) {
+		steps := []fantasy.StepResult{makeStep(
+			[]fantasy.ToolCallContent{{ToolCallID: "call_1", ToolName: "plan"}},
+			[]fantasy.ToolResultContent{{ToolCallID: "call_1", ToolName: "plan", Result: fantasy.ToolResultOutputContentError{}}},
+		)}
+		require.False(t, isPlanApproved(steps))
+	})
+
+	t.Run("plan approved in an earlier step", func(t *testing.T) {
+		steps := []fantasy.StepResult{
+			makeToolStep("plan", `{"plan":"..."}`, "approved"),
+			makeToolStep("view", `{"file_path":"main.go"}`, "package main"),
+		}
+		require.False(t, isPlanApproved(steps))
+	})
+}
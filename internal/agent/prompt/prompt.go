@@ -24,6 +24,8 @@ type Prompt struct {
 	now        func() time.Time
 	platform   string
 	workingDir string
+	// contextPaths overrides the context paths from the config when set.
+	contextPaths []string
 }
 
 type PromptDat struct {
@@ -64,6 +66,12 @@ func WithWorkingDir(workingDir string) Option {
 	}
 }
 
+func WithContextPaths(contextPaths []string) Option {
+	return func(p *Prompt) {
+		p.contextPaths = contextPaths
+	}
+}
+
 func NewPrompt(name, promptTemplate string, opts ...Option) (*Prompt, error) {
 	p := &Prompt{
 		name:     name,
@@ -76,6 +84,14 @@ func NewPrompt(name, promptTemplate string, opts ...Option) (*Prompt, error) {
 	return p, nil
 }
 
+// ForWorkingDir returns a copy of the prompt describing another working
+// directory, like the git worktree of a session.
+func (p *Prompt) ForWorkingDir(workingDir string) *Prompt {
+	clone := *p
+	clone.workingDir = workingDir
+	return &clone
+}
+
 func (p *Prompt) Build(ctx context.Context, provider, model string, store *config.ConfigStore) (string, error) {
 	t, err := template.New(p.name).Parse(p.template)
 	if err != nil {
@@ -104,11 +120,11 @@ func processFile(filePath string) *ContextFile {
 	}
 }
 
-func processContextPath(p string, store *config.ConfigStore) []ContextFile {
+func processContextPath(p, workingDir string) []ContextFile {
 	var contexts []ContextFile
 	fullPath := p
 	if !filepath.IsAbs(p) {
-		fullPath = filepath.Join(store.WorkingDir(), p)
+		fullPath = filepath.Join(workingDir, p)
 	}
 	info, err := os.Stat(fullPath)
 	if err != nil {
@@ -155,13 +171,17 @@ func (p *Prompt) promptData(ctx context.Context, provider, model string, store *
 	files := map[string][]ContextFile{}
 
 	cfg := store.Config()
-	for _, pth := range cfg.Options.ContextPaths {
+	contextPaths := cfg.Options.ContextPaths
+	if p.contextPaths != nil {
+		contextPaths = p.contextPaths
+	}
+	for _, pth := range contextPaths {
 		expanded := expandPath(pth, store)
 		pathKey := strings.ToLower(expanded)
 		if _, ok := files[pathKey]; ok {
 			continue
 		}
-		content := processContextPath(expanded, store)
+		content := processContextPath(expanded, workingDir)
 		files[pathKey] = content
 	}
 
@@ -177,7 +197,7 @@ func (p *Prompt) promptData(ctx context.Context, provider, model string, store *
 		}
 	}
 
-	isGit := isGitRepo(store.WorkingDir())
+	isGit := isGitRepo(workingDir)
 	data := PromptDat{
 		Provider:      provider,
 		Model:         model,
@@ -190,7 +210,7 @@ func (p *Prompt) promptData(ctx context.Context, provider, model string, store *
 	}
 	if isGit {
 		var err error
-		data.GitStatus, err = getGitStatus(ctx, store.WorkingDir())
+		data.GitStatus, err = getGitStatus(ctx, workingDir)
 		if err != nil {
 			return PromptDat{}, err
 		}
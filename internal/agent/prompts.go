@@ -3,9 +3,13 @@ package agent
 import (
 	"context"
 	_ "embed"
+	"fmt"
+	"os"
+	"path/filepath"
 
 	"github.com/charmbracelet/crush/internal/agent/prompt"
 	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/home"
 )
 
 //go:embed templates/coder.md.tpl
@@ -33,6 +37,30 @@ func taskPrompt(opts ...prompt.Option) (*prompt.Prompt, error) {
 	return systemPrompt, nil
 }
 
+// agentPrompt returns the system prompt of the given agent. Agents without a
+// prompt of their own use the built-in one matching their ID, falling back to
+// the coder prompt.
+func agentPrompt(agent config.Agent, workingDir string, opts ...prompt.Option) (*prompt.Prompt, error) {
+	switch {
+	case agent.SystemPrompt != "":
+		return prompt.NewPrompt(agent.ID, agent.SystemPrompt, opts...)
+	case agent.SystemPromptFile != "":
+		path := home.Long(agent.SystemPromptFile)
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(workingDir, path)
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read system prompt of agent %q: %w", agent.ID, err)
+		}
+		return prompt.NewPrompt(agent.ID, string(content), opts...)
+	case agent.ID == config.AgentTask:
+		return taskPrompt(opts...)
+	default:
+		return coderPrompt(opts...)
+	}
+}
+
 func InitializePrompt(cfg *config.ConfigStore) (string, error) {
 	systemPrompt, err := prompt.NewPrompt("initialize", string(initializePromptTmpl))
 	if err != nil {
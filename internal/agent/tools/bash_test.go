@@ -21,6 +21,10 @@ func (m *mockBashPermissionService) Request(ctx context.Context, req permission.
 	return true, nil
 }
 
+func (m *mockBashPermissionService) Approve(ctx context.Context, req permission.CreatePermissionRequest) (bool, error) {
+	return true, nil
+}
+
 func (m *mockBashPermissionService) Grant(req permission.PermissionRequest) {}
 
 func (m *mockBashPermissionService) Deny(req permission.PermissionRequest) {}
@@ -39,6 +43,14 @@ func (m *mockBashPermissionService) SubscribeNotifications(ctx context.Context)
 	return make(<-chan pubsub.Event[permission.PermissionNotification])
 }
 
+func (m *mockBashPermissionService) SessionGrants(ctx context.Context, sessionID string) ([]permission.Grant, error) {
+	return nil, nil
+}
+
+func (m *mockBashPermissionService) RevokeGrant(ctx context.Context, id string) error {
+	return nil
+}
+
 func TestBashTool_DefaultAutoBackgroundThreshold(t *testing.T) {
 	workingDir := t.TempDir()
 	tool := newBashToolForTest(workingDir)
@@ -0,0 +1,181 @@
+package tools
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"os"
+	"strings"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/filepathext"
+	"github.com/charmbracelet/crush/internal/filetracker"
+	"github.com/charmbracelet/crush/internal/fsext"
+	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/lsp"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+)
+
+type CodeActionParams struct {
+	FilePath string `json:"file_path" description:"The path to the file to get the code actions of"`
+	Line     int    `json:"line,omitempty" description:"The first line to get the code actions of (1-based). Defaults to the whole file."`
+	EndLine  int    `json:"end_line,omitempty" description:"The last line to get the code actions of (1-based). Defaults to line."`
+	Apply    string `json:"apply,omitempty" description:"The title of the code action to apply, as listed by a previous call. Leave empty to list the available code actions."`
+}
+
+type CodeActionPermissionsParams struct {
+	Title    string `json:"title"`
+	FilePath string `json:"file_path"`
+	Diff     string `json:"diff"`
+}
+
+type CodeActionResponseMetadata struct {
+	Files     []string `json:"files"`
+	Additions int      `json:"additions"`
+	Removals  int      `json:"removals"`
+}
+
+const CodeActionToolName = "lsp_code_action"
+
+//go:embed code_action.md
+var codeActionDescription []byte
+
+func NewCodeActionTool(
+	lspManager *lsp.Manager,
+	permissions permission.Service,
+	files history.Service,
+	filetracker filetracker.Service,
+	workingDir string,
+) fantasy.AgentTool {
+	return fantasy.NewAgentTool(
+		CodeActionToolName,
+		string(codeActionDescription),
+		func(ctx context.Context, params CodeActionParams, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			workingDir := GetWorkingDirFromContext(ctx, workingDir)
+			lspManager := lspManager.ForRoot(workingDir)
+			if params.FilePath == "" {
+				return fantasy.NewTextErrorResponse("file_path is required"), nil
+			}
+			filePath := filepathext.SmartJoin(workingDir, params.FilePath)
+
+			client := clientForFile(lspManager, filePath)
+			if client == nil {
+				return fantasy.NewTextErrorResponse(fmt.Sprintf("no LSP client handles %s", filePath)), nil
+			}
+
+			startLine, endLine := params.Line, max(params.EndLine, params.Line)
+			if startLine <= 0 {
+				content, err := os.ReadFile(filePath)
+				if err != nil {
+					return fantasy.NewTextErrorResponse(fmt.Sprintf("failed to read file: %s", err)), nil
+				}
+				startLine, endLine = 1, strings.Count(string(content), "\n")+1
+			}
+
+			actions, err := client.CodeActions(ctx, filePath, startLine, endLine)
+			if err != nil {
+				return fantasy.NewTextErrorResponse(err.Error()), nil
+			}
+			if params.Apply == "" {
+				return fantasy.NewTextResponse(formatCodeActions(filePath, startLine, endLine, actions)), nil
+			}
+
+			var action protocol.CodeAction
+			for _, a := range actions {
+				if a.Title == params.Apply {
+					action = a
+					break
+				}
+			}
+			switch {
+			case action.Title == "":
+				return fantasy.NewTextErrorResponse(fmt.Sprintf("no code action titled %q for these lines, list them first", params.Apply)), nil
+			case action.Disabled != nil:
+				return fantasy.NewTextErrorResponse(fmt.Sprintf("code action %q can't be applied: %s", action.Title, action.Disabled.Reason)), nil
+			case action.Edit == nil && action.Data != nil:
+				if action, err = client.ResolveCodeAction(ctx, action); err != nil {
+					return fantasy.NewTextErrorResponse(err.Error()), nil
+				}
+			}
+			// Commands run on the server, which applies their changes
+			// without asking, so they aren't run.
+			if action.Edit == nil {
+				return fantasy.NewTextErrorResponse(fmt.Sprintf("code action %q runs a command on the LSP server, which isn't supported", action.Title)), nil
+			}
+
+			sessionID := GetSessionFromContext(ctx)
+			if sessionID == "" {
+				return fantasy.ToolResponse{}, fmt.Errorf("session ID is required for applying a code action")
+			}
+
+			editor := lspEditor{lspManager, permissions, files, filetracker}
+			result, refusal, err := editor.apply(ctx, client, *action.Edit, sessionID, workingDir, func(path, diff string) permission.CreatePermissionRequest {
+				return permission.CreatePermissionRequest{
+					SessionID:   sessionID,
+					Path:        fsext.PathOrPrefix(path, workingDir),
+					ToolCallID:  call.ID,
+					ToolName:    CodeActionToolName,
+					Action:      "write",
+					Description: fmt.Sprintf("Apply %q to %s", action.Title, path),
+					Params: CodeActionPermissionsParams{
+						Title:    action.Title,
+						FilePath: path,
+						Diff:     diff,
+					},
+				}
+			})
+			if err != nil {
+				return fantasy.ToolResponse{}, err
+			}
+			if refusal != "" {
+				return fantasy.NewTextErrorResponse(refusal), nil
+			}
+
+			var output strings.Builder
+			fmt.Fprintf(&output, "Applied %q to %d file(s):\n", action.Title, len(result.paths))
+			for _, path := range result.paths {
+				fmt.Fprintf(&output, "  %s\n", path)
+			}
+			if action.Command != nil {
+				fmt.Fprintf(&output, "The command of the code action, %q, wasn't run.\n", action.Command.Title)
+			}
+			for _, path := range result.paths {
+				output.WriteString(getDiagnostics(path, lspManager))
+			}
+
+			return fantasy.WithResponseMetadata(
+				fantasy.NewTextResponse(output.String()),
+				CodeActionResponseMetadata{
+					Files:     result.paths,
+					Additions: result.additions,
+					Removals:  result.removals,
+				},
+			), nil
+		})
+}
+
+func formatCodeActions(path string, startLine, endLine int, actions []protocol.CodeAction) string {
+	if len(actions) == 0 {
+		return fmt.Sprintf("No code actions for lines %d-%d of %s", startLine, endLine, path)
+	}
+
+	var output strings.Builder
+	fmt.Fprintf(&output, "Found %d code action(s) for lines %d-%d of %s:\n", len(actions), startLine, endLine, path)
+	for _, action := range actions {
+		fmt.Fprintf(&output, "- %s", action.Title)
+		if action.Kind != "" {
+			fmt.Fprintf(&output, " (%s)", action.Kind)
+		}
+		switch {
+		case action.Disabled != nil:
+			fmt.Fprintf(&output, " [disabled: %s]", action.Disabled.Reason)
+		case action.Edit == nil && action.Data == nil:
+			output.WriteString(" [runs a command, can't be applied]")
+		case action.IsPreferred:
+			output.WriteString(" [preferred]")
+		}
+		output.WriteString("\n")
+	}
+	return output.String()
+}
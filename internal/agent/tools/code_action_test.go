@@ -0,0 +1,121 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/lsp/lsptest"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodeActionTool(t *testing.T) {
+	t.Parallel()
+
+	const source = "package main\n\nimport \"os\"\n\nfunc main() {}\n"
+	removeImport := protocol.CodeAction{
+		Title:       "Remove unused import",
+		Kind:        "quickfix",
+		IsPreferred: true,
+	}
+
+	setup := func(t *testing.T, rules ...permission.Rule) (fantasy.AgentTool, string, fakeFileTracker) {
+		dir := t.TempDir()
+		file := filepath.Join(dir, "main.go")
+		require.NoError(t, os.WriteFile(file, []byte(source), 0o644))
+
+		action := removeImport
+		action.Edit = &protocol.WorkspaceEdit{Changes: map[protocol.DocumentURI][]protocol.TextEdit{
+			protocol.URIFromPath(file): {{
+				Range: protocol.Range{Start: protocol.Position{Line: 2}, End: protocol.Position{Line: 4}},
+			}},
+		}}
+		manager := newFakeLSPManager(t, dir, map[string]lsptest.Handler{
+			"textDocument/codeAction": func(json.RawMessage) (any, error) {
+				return []any{action, protocol.Command{Title: "Run go generate", Command: "generate"}}, nil
+			},
+		})
+		tracker := fakeFileTracker{csync.NewMap[string, time.Time]()}
+		// Requests are skipped like with --yolo, rules still apply.
+		permissions := permission.NewPermissionService(dir, true, nil, rules, nil)
+		files := &mockHistoryService{Broker: pubsub.NewBroker[history.File]()}
+		return NewCodeActionTool(manager, permissions, files, tracker, dir), dir, tracker
+	}
+	run := func(t *testing.T, tool fantasy.AgentTool, dir string, params CodeActionParams) (fantasy.ToolResponse, error) {
+		input, err := json.Marshal(params)
+		require.NoError(t, err)
+		ctx := context.WithValue(t.Context(), WorkingDirContextKey, dir)
+		ctx = context.WithValue(ctx, SessionIDContextKey, "session")
+		return tool.Run(ctx, fantasy.ToolCall{ID: "test-call", Name: CodeActionToolName, Input: string(input)})
+	}
+
+	t.Run("list", func(t *testing.T) {
+		t.Parallel()
+
+		tool, dir, _ := setup(t)
+		resp, err := run(t, tool, dir, CodeActionParams{FilePath: "main.go", Line: 3})
+		require.NoError(t, err)
+		require.False(t, resp.IsError, resp.Content)
+		require.Equal(t, "Found 2 code action(s) for lines 3-3 of "+filepath.Join(dir, "main.go")+":\n"+
+			"- Remove unused import (quickfix) [preferred]\n"+
+			"- Run go generate [runs a command, can't be applied]\n", resp.Content)
+	})
+
+	t.Run("apply", func(t *testing.T) {
+		t.Parallel()
+
+		tool, dir, tracker := setup(t)
+		file := filepath.Join(dir, "main.go")
+
+		resp, err := run(t, tool, dir, CodeActionParams{FilePath: file, Line: 3, Apply: removeImport.Title})
+		require.NoError(t, err)
+		require.True(t, resp.IsError)
+		require.Contains(t, resp.Content, "you must read")
+
+		tracker.RecordRead(t.Context(), "session", file)
+		resp, err = run(t, tool, dir, CodeActionParams{FilePath: file, Line: 3, Apply: removeImport.Title})
+		require.NoError(t, err)
+		require.False(t, resp.IsError, resp.Content)
+		require.Contains(t, resp.Content, `Applied "Remove unused import" to 1 file(s)`)
+
+		content, err := os.ReadFile(file)
+		require.NoError(t, err)
+		require.Equal(t, "package main\n\nfunc main() {}\n", string(content))
+	})
+
+	t.Run("write rules apply", func(t *testing.T) {
+		t.Parallel()
+
+		deny, err := permission.ParseRule(permission.DecisionDeny, "write(main.go)")
+		require.NoError(t, err)
+		tool, dir, tracker := setup(t, deny)
+		file := filepath.Join(dir, "main.go")
+		tracker.RecordRead(t.Context(), "session", file)
+
+		_, err = run(t, tool, dir, CodeActionParams{FilePath: file, Line: 3, Apply: removeImport.Title})
+		require.ErrorIs(t, err, permission.ErrorPermissionDenied)
+
+		content, err := os.ReadFile(file)
+		require.NoError(t, err)
+		require.Equal(t, source, string(content))
+	})
+
+	t.Run("command", func(t *testing.T) {
+		t.Parallel()
+
+		tool, dir, _ := setup(t)
+		resp, err := run(t, tool, dir, CodeActionParams{FilePath: "main.go", Apply: "Run go generate"})
+		require.NoError(t, err)
+		require.True(t, resp.IsError)
+		require.Contains(t, resp.Content, "runs a command")
+	})
+}
@@ -0,0 +1,105 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/lsp"
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+)
+
+type DefinitionParams struct {
+	Symbol string `json:"symbol" description:"The symbol name to find the definition of (e.g., function name, variable name, type name)"`
+	Path   string `json:"path,omitempty" description:"The directory or file where the symbol is used. Use a directory/file to narrow down the symbol search. Defaults to the current working directory."`
+}
+
+const DefinitionToolName = "lsp_definition"
+
+// maxDefinitions is the number of definitions described in full, with their
+// signature and documentation.
+const maxDefinitions = 5
+
+//go:embed definition.md
+var definitionDescription []byte
+
+func NewDefinitionTool(lspManager *lsp.Manager) fantasy.AgentTool {
+	return fantasy.NewAgentTool(
+		DefinitionToolName,
+		string(definitionDescription),
+		func(ctx context.Context, params DefinitionParams, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			lspManager := lspManager.ForRoot(GetWorkingDirFromContext(ctx, ""))
+			if params.Symbol == "" {
+				return fantasy.NewTextErrorResponse("symbol is required"), nil
+			}
+
+			if lspManager.Clients().Len() == 0 {
+				return fantasy.NewTextErrorResponse("no LSP clients available"), nil
+			}
+
+			path := cmp.Or(params.Path, GetWorkingDirFromContext(ctx, "."))
+			locations, err := lookupSymbol(ctx, lspManager, params.Symbol, path, func(client *lsp.Client, path string, line, character int) ([]protocol.Location, bool, error) {
+				locations, err := client.FindDefinitions(ctx, path, line, character)
+				return locations, len(locations) > 0, err
+			})
+			if errors.Is(err, errSymbolNotFound) {
+				return fantasy.NewTextResponse(fmt.Sprintf("Symbol '%s' not found", params.Symbol)), nil
+			}
+			if err != nil {
+				return fantasy.NewTextErrorResponse(err.Error()), nil
+			}
+			if len(locations) == 0 {
+				return fantasy.NewTextResponse(fmt.Sprintf("No definition found for symbol '%s'", params.Symbol)), nil
+			}
+
+			return fantasy.NewTextResponse(formatDefinitions(ctx, lspManager, cleanupLocations(locations))), nil
+		})
+}
+
+func formatDefinitions(ctx context.Context, lspManager *lsp.Manager, locations []protocol.Location) string {
+	var output strings.Builder
+	fmt.Fprintf(&output, "Found %d definition(s):\n", len(locations))
+
+	for i, loc := range locations {
+		path, err := loc.URI.Path()
+		if err != nil {
+			continue
+		}
+		line := int(loc.Range.Start.Line) + 1
+		char := int(loc.Range.Start.Character) + 1
+		fmt.Fprintf(&output, "\n%s:%d:%d\n", path, line, char)
+		if i >= maxDefinitions {
+			continue
+		}
+
+		if client := clientForFile(lspManager, path); client != nil {
+			if hover, err := client.Hover(ctx, path, line, char); err == nil && strings.TrimSpace(hover.Contents.Value) != "" {
+				fmt.Fprintf(&output, "%s\n", strings.TrimSpace(hover.Contents.Value))
+				continue
+			}
+		}
+		if text, ok := sourceLine(path, line); ok {
+			fmt.Fprintf(&output, "%s\n", text)
+		}
+	}
+
+	return output.String()
+}
+
+// sourceLine returns the given 1-based line of a file, trimmed.
+func sourceLine(path string, line int) (string, bool) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	lines := strings.Split(string(content), "\n")
+	if line < 1 || line > len(lines) {
+		return "", false
+	}
+	return strings.TrimSpace(lines[line-1]), true
+}
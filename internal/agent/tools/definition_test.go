@@ -0,0 +1,101 @@
+package tools
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/lsp/lsptest"
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+const helperSource = "package main\n\nfunc helper() {}\n\nfunc main() {\n\thelper()\n}\n"
+
+// writeHelperSource writes helperSource to main.go in a new directory,
+// returning both.
+func writeHelperSource(t *testing.T) (dir, file string) {
+	t.Helper()
+
+	dir = t.TempDir()
+	file = filepath.Join(dir, "main.go")
+	require.NoError(t, os.WriteFile(file, []byte(helperSource), 0o644))
+	return dir, file
+}
+
+func location(file string, line, character uint32) protocol.Location {
+	start := protocol.Position{Line: line, Character: character}
+	return protocol.Location{URI: protocol.URIFromPath(file), Range: protocol.Range{Start: start, End: start}}
+}
+
+func withCapabilities(capabilities map[string]any) lsptest.Handler {
+	return func(json.RawMessage) (any, error) {
+		return map[string]any{"capabilities": capabilities}, nil
+	}
+}
+
+func TestDefinitionTool(t *testing.T) {
+	t.Parallel()
+
+	hover := func(json.RawMessage) (any, error) {
+		return protocol.Hover{Contents: protocol.MarkupContent{Kind: protocol.Markdown, Value: "func helper()"}}, nil
+	}
+
+	t.Run("definition request", func(t *testing.T) {
+		t.Parallel()
+
+		dir, file := writeHelperSource(t)
+		var asked protocol.DefinitionParams
+		manager := newFakeLSPManager(t, dir, map[string]lsptest.Handler{
+			"initialize": withCapabilities(map[string]any{"definitionProvider": true}),
+			"textDocument/definition": func(params json.RawMessage) (any, error) {
+				if err := json.Unmarshal(params, &asked); err != nil {
+					return nil, err
+				}
+				return location(file, 2, 5), nil
+			},
+			"textDocument/hover": hover,
+		})
+
+		resp := runLSPTool(t, NewDefinitionTool(manager), dir, DefinitionParams{Symbol: "helper"})
+		require.False(t, resp.IsError, resp.Content)
+		require.Equal(t, "Found 1 definition(s):\n\n"+file+":3:6\nfunc helper()\n", resp.Content)
+		// The first occurrence of the symbol is looked up.
+		require.Equal(t, protocol.Position{Line: 2, Character: 5}, asked.Position)
+	})
+
+	t.Run("references fallback", func(t *testing.T) {
+		t.Parallel()
+
+		dir, file := writeHelperSource(t)
+		manager := newFakeLSPManager(t, dir, map[string]lsptest.Handler{
+			"textDocument/references": func(params json.RawMessage) (any, error) {
+				var p protocol.ReferenceParams
+				if err := json.Unmarshal(params, &p); err != nil {
+					return nil, err
+				}
+				if p.Context.IncludeDeclaration {
+					return []protocol.Location{location(file, 2, 5), location(file, 5, 1)}, nil
+				}
+				return []protocol.Location{location(file, 5, 1)}, nil
+			},
+		})
+
+		resp := runLSPTool(t, NewDefinitionTool(manager), dir, DefinitionParams{Symbol: "helper"})
+		require.False(t, resp.IsError, resp.Content)
+		// Without hover information, the source line is shown.
+		require.Equal(t, "Found 1 definition(s):\n\n"+file+":3:6\nfunc helper() {}\n", resp.Content)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		t.Parallel()
+
+		dir, _ := writeHelperSource(t)
+		manager := newFakeLSPManager(t, dir, nil)
+
+		resp := runLSPTool(t, NewDefinitionTool(manager), dir, DefinitionParams{Symbol: "missing"})
+		require.False(t, resp.IsError, resp.Content)
+		require.Equal(t, "Symbol 'missing' not found", resp.Content)
+	})
+}
@@ -29,6 +29,7 @@ func NewDiagnosticsTool(lspManager *lsp.Manager) fantasy.AgentTool {
 		DiagnosticsToolName,
 		string(diagnosticsDescription),
 		func(ctx context.Context, params DiagnosticsParams, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			lspManager := lspManager.ForRoot(GetWorkingDirFromContext(ctx, ""))
 			if lspManager.Clients().Len() == 0 {
 				return fantasy.NewTextErrorResponse("no LSP clients available"), nil
 			}
@@ -108,6 +109,33 @@ func notifyLSPs(
 	}
 }
 
+// formatContent formats the new content of a file with its formatter, if
+// any, so the formatted content is what's shown in the diff, written and
+// stored in the history. The content is kept as is if formatting fails, so a
+// broken formatter doesn't prevent edits. It reports whether the formatter
+// changed the content.
+func formatContent(ctx context.Context, manager *lsp.Manager, filePath, content string) (string, bool) {
+	formatted, err := manager.Format(ctx, filePath, content)
+	if err != nil {
+		slog.Warn("Failed to format file", "path", filePath, "error", err)
+		return content, false
+	}
+	return formatted, formatted != content
+}
+
+// reformattedNote is added to the result of a tool when the formatter changed
+// the content, so the agent doesn't assume the file holds what it wrote.
+const reformattedNote = "\nThe content was reformatted by the file's formatter. View the file before editing it again."
+
+// noteFormatting adds reformattedNote to the result of a tool when the
+// formatter changed the content.
+func noteFormatting(result string, reformatted bool) string {
+	if reformatted {
+		return result + reformattedNote
+	}
+	return result
+}
+
 func getDiagnostics(filePath string, manager *lsp.Manager) string {
 	if manager == nil {
 		return ""
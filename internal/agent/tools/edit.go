@@ -32,6 +32,9 @@ type EditPermissionsParams struct {
 	FilePath   string `json:"file_path"`
 	OldContent string `json:"old_content,omitempty"`
 	NewContent string `json:"new_content,omitempty"`
+	// Reformatted is whether the formatter of the file changed the new
+	// content, so the diff includes its changes.
+	Reformatted bool `json:"reformatted,omitempty"`
 }
 
 type EditResponseMetadata struct {
@@ -57,6 +60,7 @@ type editContext struct {
 	files       history.Service
 	filetracker filetracker.Service
 	workingDir  string
+	lspManager  *lsp.Manager
 }
 
 func NewEditTool(
@@ -70,6 +74,8 @@ func NewEditTool(
 		EditToolName,
 		string(editDescription),
 		func(ctx context.Context, params EditParams, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			workingDir := GetWorkingDirFromContext(ctx, workingDir)
+			lspManager := lspManager.ForRoot(workingDir)
 			if params.FilePath == "" {
 				return fantasy.NewTextErrorResponse("file_path is required"), nil
 			}
@@ -79,7 +85,7 @@ func NewEditTool(
 			var response fantasy.ToolResponse
 			var err error
 
-			editCtx := editContext{ctx, permissions, files, filetracker, workingDir}
+			editCtx := editContext{ctx, permissions, files, filetracker, workingDir, lspManager}
 
 			if params.OldString == "" {
 				response, err = createNewFile(editCtx, params.FilePath, params.NewString, call)
@@ -128,6 +134,8 @@ func createNewFile(edit editContext, filePath, content string, call fantasy.Tool
 		return fantasy.ToolResponse{}, fmt.Errorf("session ID is required for creating a new file")
 	}
 
+	content, reformatted := formatContent(edit.ctx, edit.lspManager, filePath, content)
+
 	_, additions, removals := diff.GenerateDiff(
 		"",
 		content,
@@ -142,9 +150,10 @@ func createNewFile(edit editContext, filePath, content string, call fantasy.Tool
 			Action:      "write",
 			Description: fmt.Sprintf("Create file %s", filePath),
 			Params: EditPermissionsParams{
-				FilePath:   filePath,
-				OldContent: "",
-				NewContent: content,
+				FilePath:    filePath,
+				OldContent:  "",
+				NewContent:  content,
+				Reformatted: reformatted,
 			},
 		},
 	)
@@ -161,7 +170,7 @@ func createNewFile(edit editContext, filePath, content string, call fantasy.Tool
 	}
 
 	// File can't be in the history so we create a new file history
-	_, err = edit.files.Create(edit.ctx, sessionID, filePath, "")
+	_, err = edit.files.CreateNew(edit.ctx, sessionID, filePath)
 	if err != nil {
 		// Log error but don't fail the operation
 		return fantasy.ToolResponse{}, fmt.Errorf("error creating file history: %w", err)
@@ -177,7 +186,7 @@ func createNewFile(edit editContext, filePath, content string, call fantasy.Tool
 	edit.filetracker.RecordRead(edit.ctx, sessionID, filePath)
 
 	return fantasy.WithResponseMetadata(
-		fantasy.NewTextResponse("File created: "+filePath),
+		fantasy.NewTextResponse(noteFormatting("File created: "+filePath, reformatted)),
 		EditResponseMetadata{
 			OldContent: "",
 			NewContent: content,
@@ -246,6 +255,8 @@ func deleteContent(edit editContext, filePath, oldString string, replaceAll bool
 		newContent = oldContent[:index] + oldContent[index+len(oldString):]
 	}
 
+	newContent, reformatted := formatContent(edit.ctx, edit.lspManager, filePath, newContent)
+
 	_, additions, removals := diff.GenerateDiff(
 		oldContent,
 		newContent,
@@ -261,9 +272,10 @@ func deleteContent(edit editContext, filePath, oldString string, replaceAll bool
 			Action:      "write",
 			Description: fmt.Sprintf("Delete content from file %s", filePath),
 			Params: EditPermissionsParams{
-				FilePath:   filePath,
-				OldContent: oldContent,
-				NewContent: newContent,
+				FilePath:    filePath,
+				OldContent:  oldContent,
+				NewContent:  newContent,
+				Reformatted: reformatted,
 			},
 		},
 	)
@@ -308,7 +320,7 @@ func deleteContent(edit editContext, filePath, oldString string, replaceAll bool
 	edit.filetracker.RecordRead(edit.ctx, sessionID, filePath)
 
 	return fantasy.WithResponseMetadata(
-		fantasy.NewTextResponse("Content deleted from file: "+filePath),
+		fantasy.NewTextResponse(noteFormatting("Content deleted from file: "+filePath, reformatted)),
 		EditResponseMetadata{
 			OldContent: oldContent,
 			NewContent: newContent,
@@ -374,6 +386,8 @@ func replaceContent(edit editContext, filePath, oldString, newString string, rep
 		newContent = oldContent[:index] + newString + oldContent[index+len(oldString):]
 	}
 
+	newContent, reformatted := formatContent(edit.ctx, edit.lspManager, filePath, newContent)
+
 	if oldContent == newContent {
 		return fantasy.NewTextErrorResponse("new content is the same as old content. No changes made."), nil
 	}
@@ -392,9 +406,10 @@ func replaceContent(edit editContext, filePath, oldString, newString string, rep
 			Action:      "write",
 			Description: fmt.Sprintf("Replace content in file %s", filePath),
 			Params: EditPermissionsParams{
-				FilePath:   filePath,
-				OldContent: oldContent,
-				NewContent: newContent,
+				FilePath:    filePath,
+				OldContent:  oldContent,
+				NewContent:  newContent,
+				Reformatted: reformatted,
 			},
 		},
 	)
@@ -439,7 +454,7 @@ func replaceContent(edit editContext, filePath, oldString, newString string, rep
 	edit.filetracker.RecordRead(edit.ctx, sessionID, filePath)
 
 	return fantasy.WithResponseMetadata(
-		fantasy.NewTextResponse("Content replaced in file: "+filePath),
+		fantasy.NewTextResponse(noteFormatting("Content replaced in file: "+filePath, reformatted)),
 		EditResponseMetadata{
 			OldContent: oldContent,
 			NewContent: newContent,
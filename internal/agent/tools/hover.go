@@ -0,0 +1,66 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"strings"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/lsp"
+)
+
+type HoverParams struct {
+	Symbol string `json:"symbol" description:"The symbol name to get information about (e.g., function name, variable name, type name)"`
+	Path   string `json:"path,omitempty" description:"The directory or file where the symbol is used. Use a directory/file to narrow down the symbol search. Defaults to the current working directory."`
+}
+
+const HoverToolName = "lsp_hover"
+
+//go:embed hover.md
+var hoverDescription []byte
+
+func NewHoverTool(lspManager *lsp.Manager) fantasy.AgentTool {
+	return fantasy.NewAgentTool(
+		HoverToolName,
+		string(hoverDescription),
+		func(ctx context.Context, params HoverParams, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			lspManager := lspManager.ForRoot(GetWorkingDirFromContext(ctx, ""))
+			if params.Symbol == "" {
+				return fantasy.NewTextErrorResponse("symbol is required"), nil
+			}
+
+			if lspManager.Clients().Len() == 0 {
+				return fantasy.NewTextErrorResponse("no LSP clients available"), nil
+			}
+
+			type hoverResult struct {
+				path      string
+				line      int
+				character int
+				content   string
+			}
+			path := cmp.Or(params.Path, GetWorkingDirFromContext(ctx, "."))
+			result, err := lookupSymbol(ctx, lspManager, params.Symbol, path, func(client *lsp.Client, path string, line, character int) (hoverResult, bool, error) {
+				hover, err := client.Hover(ctx, path, line, character)
+				if err != nil {
+					return hoverResult{}, false, err
+				}
+				content := strings.TrimSpace(hover.Contents.Value)
+				return hoverResult{path, line, character, content}, content != "", nil
+			})
+			if errors.Is(err, errSymbolNotFound) {
+				return fantasy.NewTextResponse(fmt.Sprintf("Symbol '%s' not found", params.Symbol)), nil
+			}
+			if err != nil {
+				return fantasy.NewTextErrorResponse(err.Error()), nil
+			}
+			if result.content == "" {
+				return fantasy.NewTextResponse(fmt.Sprintf("No information found for symbol '%s'", params.Symbol)), nil
+			}
+
+			return fantasy.NewTextResponse(fmt.Sprintf("%s:%d:%d\n%s\n", result.path, result.line, result.character, result.content)), nil
+		})
+}
@@ -0,0 +1,36 @@
+package tools
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/lsp/lsptest"
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHoverTool(t *testing.T) {
+	t.Parallel()
+
+	dir, file := writeHelperSource(t)
+	manager := newFakeLSPManager(t, dir, map[string]lsptest.Handler{
+		"textDocument/hover": func(params json.RawMessage) (any, error) {
+			var p protocol.HoverParams
+			if err := json.Unmarshal(params, &p); err != nil {
+				return nil, err
+			}
+			if p.Position != (protocol.Position{Line: 2, Character: 5}) {
+				return nil, nil
+			}
+			return protocol.Hover{Contents: protocol.MarkupContent{Kind: protocol.Markdown, Value: "func helper()\n\nhelper helps."}}, nil
+		},
+	})
+
+	resp := runLSPTool(t, NewHoverTool(manager), dir, HoverParams{Symbol: "helper"})
+	require.False(t, resp.IsError, resp.Content)
+	require.Equal(t, file+":3:6\nfunc helper()\n\nhelper helps.\n", resp.Content)
+
+	resp = runLSPTool(t, NewHoverTool(manager), dir, HoverParams{Symbol: "main"})
+	require.False(t, resp.IsError, resp.Content)
+	require.Equal(t, "No information found for symbol 'main'", resp.Content)
+}
@@ -0,0 +1,76 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"strings"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/lsp"
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+)
+
+type ImplementationParams struct {
+	Symbol string `json:"symbol" description:"The interface or abstract method to find the implementations of (e.g., interface name, method name)"`
+	Path   string `json:"path,omitempty" description:"The directory or file where the symbol is used. Use a directory/file to narrow down the symbol search. Defaults to the current working directory."`
+}
+
+const ImplementationToolName = "lsp_implementation"
+
+//go:embed implementation.md
+var implementationDescription []byte
+
+func NewImplementationTool(lspManager *lsp.Manager) fantasy.AgentTool {
+	return fantasy.NewAgentTool(
+		ImplementationToolName,
+		string(implementationDescription),
+		func(ctx context.Context, params ImplementationParams, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			lspManager := lspManager.ForRoot(GetWorkingDirFromContext(ctx, ""))
+			if params.Symbol == "" {
+				return fantasy.NewTextErrorResponse("symbol is required"), nil
+			}
+
+			if lspManager.Clients().Len() == 0 {
+				return fantasy.NewTextErrorResponse("no LSP clients available"), nil
+			}
+
+			path := cmp.Or(params.Path, GetWorkingDirFromContext(ctx, "."))
+			locations, err := lookupSymbol(ctx, lspManager, params.Symbol, path, func(client *lsp.Client, path string, line, character int) ([]protocol.Location, bool, error) {
+				locations, err := client.FindImplementations(ctx, path, line, character)
+				return locations, len(locations) > 0, err
+			})
+			if errors.Is(err, errSymbolNotFound) {
+				return fantasy.NewTextResponse(fmt.Sprintf("Symbol '%s' not found", params.Symbol)), nil
+			}
+			if err != nil {
+				return fantasy.NewTextErrorResponse(err.Error()), nil
+			}
+			if len(locations) == 0 {
+				return fantasy.NewTextResponse(fmt.Sprintf("No implementation found for symbol '%s'", params.Symbol)), nil
+			}
+
+			return fantasy.NewTextResponse(formatImplementations(cleanupLocations(locations))), nil
+		})
+}
+
+func formatImplementations(locations []protocol.Location) string {
+	var output strings.Builder
+	fmt.Fprintf(&output, "Found %d implementation(s):\n", len(locations))
+
+	for _, loc := range locations {
+		path, err := loc.URI.Path()
+		if err != nil {
+			continue
+		}
+		line := int(loc.Range.Start.Line) + 1
+		fmt.Fprintf(&output, "\n%s:%d:%d\n", path, line, int(loc.Range.Start.Character)+1)
+		if text, ok := sourceLine(path, line); ok {
+			fmt.Fprintf(&output, "%s\n", text)
+		}
+	}
+
+	return output.String()
+}
@@ -0,0 +1,51 @@
+package tools
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/lsp/lsptest"
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImplementationTool(t *testing.T) {
+	t.Parallel()
+
+	const source = "package main\n\ntype Runner interface {\n\tRun()\n}\n\ntype job struct{}\n\nfunc (job) Run() {}\n"
+	newDir := func(t *testing.T) (string, string) {
+		dir := t.TempDir()
+		file := filepath.Join(dir, "main.go")
+		require.NoError(t, os.WriteFile(file, []byte(source), 0o644))
+		return dir, file
+	}
+
+	t.Run("implementations", func(t *testing.T) {
+		t.Parallel()
+
+		dir, file := newDir(t)
+		manager := newFakeLSPManager(t, dir, map[string]lsptest.Handler{
+			"initialize": withCapabilities(map[string]any{"implementationProvider": true}),
+			"textDocument/implementation": func(json.RawMessage) (any, error) {
+				return []protocol.Location{location(file, 6, 5)}, nil
+			},
+		})
+
+		resp := runLSPTool(t, NewImplementationTool(manager), dir, ImplementationParams{Symbol: "Runner"})
+		require.False(t, resp.IsError, resp.Content)
+		require.Equal(t, "Found 1 implementation(s):\n\n"+file+":7:6\ntype job struct{}\n", resp.Content)
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		t.Parallel()
+
+		dir, _ := newDir(t)
+		manager := newFakeLSPManager(t, dir, nil)
+
+		resp := runLSPTool(t, NewImplementationTool(manager), dir, ImplementationParams{Symbol: "Runner"})
+		require.True(t, resp.IsError)
+		require.Contains(t, resp.Content, "doesn't support finding implementations")
+	})
+}
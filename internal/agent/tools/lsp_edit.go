@@ -0,0 +1,106 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/diff"
+	"github.com/charmbracelet/crush/internal/filetracker"
+	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/lsp"
+	"github.com/charmbracelet/crush/internal/lsp/util"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+)
+
+// lspEditor applies the workspace edits of LSP servers for tools like
+// lsp_rename, the way the edit tool changes files.
+type lspEditor struct {
+	lspManager  *lsp.Manager
+	permissions permission.Service
+	files       history.Service
+	filetracker filetracker.Service
+}
+
+// lspEditResult is what applying a workspace edit changed.
+type lspEditResult struct {
+	paths     []string
+	additions int
+	removals  int
+}
+
+// apply applies a workspace edit of client. Every file it changes must have
+// been read, and not modified since, and needs its own permission, asked
+// with the request made by newRequest from the file and its diff. Changes
+// are recorded in the history of the session. Problems the model can fix,
+// like files it didn't read, are returned as a refusal rather than an error.
+func (e lspEditor) apply(
+	ctx context.Context,
+	client *lsp.Client,
+	edit protocol.WorkspaceEdit,
+	sessionID, workingDir string,
+	newRequest func(path, diff string) permission.CreatePermissionRequest,
+) (result lspEditResult, refusal string, err error) {
+	encoding := client.OffsetEncoding()
+	newContents, err := util.PreviewWorkspaceEdit(edit, encoding)
+	if err != nil {
+		return result, fmt.Sprintf("failed to compute the changes: %s", err), nil
+	}
+
+	result.paths = slices.Sorted(maps.Keys(newContents))
+	oldContents := make(map[string]string, len(result.paths))
+	diffs := make(map[string]string, len(result.paths))
+	for _, path := range result.paths {
+		fileInfo, err := os.Stat(path)
+		if err != nil {
+			return result, "", fmt.Errorf("failed to access file: %w", err)
+		}
+		lastRead := e.filetracker.LastReadTime(ctx, sessionID, path)
+		if lastRead.IsZero() {
+			return result, fmt.Sprintf("you must read %s before changing it. Use the View tool first", path), nil
+		}
+		if modTime := fileInfo.ModTime().Truncate(time.Second); modTime.After(lastRead) {
+			return result, fmt.Sprintf("file %s has been modified since it was last read (mod time: %s, last read: %s)",
+				path, modTime.Format(time.RFC3339), lastRead.Format(time.RFC3339),
+			), nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return result, "", fmt.Errorf("failed to read file: %w", err)
+		}
+		oldContents[path] = string(content)
+		d, a, r := diff.GenerateDiff(oldContents[path], newContents[path], strings.TrimPrefix(path, workingDir))
+		diffs[path] = d
+		result.additions += a
+		result.removals += r
+	}
+
+	// Ask for each file, so that rules about files, including the ones
+	// of the edit and write tools, apply to every file the edit changes.
+	for _, path := range result.paths {
+		p, err := e.permissions.Request(ctx, newRequest(path, diffs[path]))
+		if err != nil {
+			return result, "", err
+		}
+		if !p {
+			return result, "", permission.ErrorPermissionDenied
+		}
+	}
+
+	if err := util.ApplyWorkspaceEdit(edit, encoding); err != nil {
+		return result, "", fmt.Errorf("failed to apply the changes: %w", err)
+	}
+
+	for _, path := range result.paths {
+		recordFileVersions(ctx, e.files, sessionID, path, oldContents[path], newContents[path])
+		e.filetracker.RecordRead(ctx, sessionID, path)
+		notifyLSPs(ctx, e.lspManager, path)
+	}
+	return result, "", nil
+}
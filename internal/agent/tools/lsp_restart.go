@@ -29,6 +29,7 @@ func NewLSPRestartTool(lspManager *lsp.Manager) fantasy.AgentTool {
 		LSPRestartToolName,
 		string(lspRestartDescription),
 		func(ctx context.Context, params LSPRestartParams, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			lspManager := lspManager.ForRoot(GetWorkingDirFromContext(ctx, ""))
 			if lspManager.Clients().Len() == 0 {
 				return fantasy.NewTextErrorResponse("no LSP clients available to restart"), nil
 			}
@@ -0,0 +1,64 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/lsp"
+	"github.com/charmbracelet/crush/internal/lsp/lsptest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	lsptest.Main(m)
+}
+
+// newFakeLSPManager returns a manager with a single client, of a fake server
+// handling the Go files in dir.
+func newFakeLSPManager(t *testing.T, dir string, handlers map[string]lsptest.Handler) *lsp.Manager {
+	t.Helper()
+
+	cfg, err := config.Init(dir, t.TempDir(), false)
+	require.NoError(t, err)
+	manager := lsp.NewManager(cfg)
+	manager.Clients().Set("fake", lsptest.NewClient(t, dir, []string{"go"}, handlers))
+	return manager
+}
+
+func runLSPTool(t *testing.T, tool fantasy.AgentTool, dir string, params any) fantasy.ToolResponse {
+	t.Helper()
+
+	input, err := json.Marshal(params)
+	require.NoError(t, err)
+	ctx := context.WithValue(t.Context(), WorkingDirContextKey, dir)
+	resp, err := tool.Run(ctx, fantasy.ToolCall{
+		ID:    "test-call",
+		Name:  tool.Info().Name,
+		Input: string(input),
+	})
+	require.NoError(t, err)
+	return resp
+}
+
+// fakeFileTracker tracks the files read in memory.
+type fakeFileTracker struct {
+	reads *csync.Map[string, time.Time]
+}
+
+func (f fakeFileTracker) RecordRead(ctx context.Context, sessionID, path string) {
+	f.reads.Set(sessionID+":"+path, time.Now())
+}
+
+func (f fakeFileTracker) LastReadTime(ctx context.Context, sessionID, path string) time.Time {
+	read, _ := f.reads.Get(sessionID + ":" + path)
+	return read
+}
+
+func (f fakeFileTracker) ListReadFiles(ctx context.Context, sessionID string) ([]string, error) {
+	return nil, nil
+}
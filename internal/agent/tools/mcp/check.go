@@ -0,0 +1,56 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// CheckResult is what an MCP server offers, as found by Check.
+type CheckResult struct {
+	Server    *mcp.Implementation
+	Tools     []*Tool
+	Prompts   []*Prompt
+	Resources []*Resource
+	// Elapsed is the time it took to connect and list everything.
+	Elapsed time.Duration
+}
+
+// Check connects to an MCP server and lists what it offers, without making
+// it available to the agent. It's meant to try out its configuration, so
+// servers are checked even when disabled.
+func Check(ctx context.Context, cfg *config.ConfigStore, name string) (CheckResult, error) {
+	m, ok := cfg.Config().MCP[name]
+	if !ok {
+		return CheckResult{}, fmt.Errorf("mcp '%s' not configured", name)
+	}
+
+	start := time.Now()
+	if creds, ok := cfg.Config().MCPCredentials(name); ok && creds.Expired() {
+		_ = refreshCredentials(ctx, cfg, name, creds)
+	}
+	session, err := connect(ctx, name, m, cfg)
+	if err != nil {
+		if unauthorized(name) {
+			return CheckResult{}, ErrUnauthorized
+		}
+		return CheckResult{}, err
+	}
+	defer session.Close()
+
+	result := CheckResult{Server: session.InitializeResult().ServerInfo}
+	if result.Tools, err = getTools(ctx, session); err != nil {
+		return CheckResult{}, fmt.Errorf("listing tools: %w", err)
+	}
+	if result.Prompts, err = getPrompts(ctx, session); err != nil {
+		return CheckResult{}, fmt.Errorf("listing prompts: %w", err)
+	}
+	if result.Resources, err = getResources(ctx, session); err != nil {
+		return CheckResult{}, fmt.Errorf("listing resources: %w", err)
+	}
+	result.Elapsed = time.Since(start)
+	return result, nil
+}
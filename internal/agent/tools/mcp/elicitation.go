@@ -0,0 +1,279 @@
+package mcp
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/google/uuid"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ErrElicitationNotPending is returned when answering an elicitation request
+// that was already answered, or given up on by its server.
+var ErrElicitationNotPending = errors.New("elicitation request is no longer pending")
+
+// ElicitationRequest is a request of an MCP server for input from the user.
+type ElicitationRequest struct {
+	ID string
+	// Name is the name of the MCP server.
+	Name    string
+	Message string
+	Fields  []ElicitationField
+}
+
+// ElicitationField is a field of the form requested by an MCP server.
+type ElicitationField struct {
+	Name        string
+	Title       string
+	Description string
+	// Type is the JSON type of the value: string, number, integer, boolean
+	// or array.
+	Type     string
+	Required bool
+	// Options are the values a field accepts, if limited. Arrays accept
+	// several of them, separated by commas.
+	Options []string
+	Default string
+}
+
+// Value converts the text entered for a field to its value. Empty optional
+// fields have no value.
+func (f ElicitationField) Value(text string) (any, bool, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		if f.Required {
+			return nil, false, fmt.Errorf("%s is required", f.label())
+		}
+		return nil, false, nil
+	}
+
+	switch f.Type {
+	case "boolean":
+		switch strings.ToLower(text) {
+		case "true", "yes", "y":
+			return true, true, nil
+		case "false", "no", "n":
+			return false, true, nil
+		}
+		return nil, false, fmt.Errorf("%s must be yes or no", f.label())
+	case "integer":
+		v, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return nil, false, fmt.Errorf("%s must be an integer", f.label())
+		}
+		return v, true, nil
+	case "number":
+		v, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, false, fmt.Errorf("%s must be a number", f.label())
+		}
+		return v, true, nil
+	case "array":
+		var values []string
+		for v := range strings.SplitSeq(text, ",") {
+			if v = strings.TrimSpace(v); v == "" {
+				continue
+			}
+			if len(f.Options) > 0 && !slices.Contains(f.Options, v) {
+				return nil, false, fmt.Errorf("%s must be among %s", f.label(), strings.Join(f.Options, ", "))
+			}
+			values = append(values, v)
+		}
+		return values, true, nil
+	default:
+		if len(f.Options) > 0 && !slices.Contains(f.Options, text) {
+			return nil, false, fmt.Errorf("%s must be one of %s", f.label(), strings.Join(f.Options, ", "))
+		}
+		return text, true, nil
+	}
+}
+
+func (f ElicitationField) label() string {
+	return cmp.Or(f.Title, f.Name)
+}
+
+// elicitationSchema is the restricted JSON schema of elicitation forms, which
+// only have top-level properties of primitive types.
+type elicitationSchema struct {
+	Properties map[string]elicitationProperty `json:"properties"`
+	Required   []string                       `json:"required"`
+}
+
+type elicitationProperty struct {
+	Type        string   `json:"type"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Enum        []string `json:"enum"`
+	OneOf       []struct {
+		Const string `json:"const"`
+	} `json:"oneOf"`
+	Items   *elicitationProperty `json:"items"`
+	Default any                  `json:"default"`
+}
+
+func (p elicitationProperty) options() []string {
+	if len(p.Enum) > 0 {
+		return p.Enum
+	}
+	var options []string
+	for _, o := range p.OneOf {
+		options = append(options, o.Const)
+	}
+	return options
+}
+
+// elicitationFields returns the fields of a requested schema. Required fields
+// come first, as the order of the properties is lost in transit.
+func elicitationFields(requestedSchema any) ([]ElicitationField, error) {
+	if requestedSchema == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(requestedSchema)
+	if err != nil {
+		return nil, err
+	}
+	var schema elicitationSchema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("invalid elicitation schema: %w", err)
+	}
+
+	fields := make([]ElicitationField, 0, len(schema.Properties))
+	for name, prop := range schema.Properties {
+		field := ElicitationField{
+			Name:        name,
+			Title:       prop.Title,
+			Description: prop.Description,
+			Type:        cmp.Or(prop.Type, "string"),
+			Required:    slices.Contains(schema.Required, name),
+			Options:     prop.options(),
+		}
+		if prop.Items != nil {
+			field.Options = prop.Items.options()
+		}
+		switch v := prop.Default.(type) {
+		case nil:
+		case bool:
+			field.Default = map[bool]string{true: "yes", false: "no"}[v]
+		case []any:
+			var values []string
+			for _, item := range v {
+				values = append(values, fmt.Sprint(item))
+			}
+			field.Default = strings.Join(values, ", ")
+		default:
+			field.Default = fmt.Sprint(v)
+		}
+		fields = append(fields, field)
+	}
+	slices.SortFunc(fields, func(a, b ElicitationField) int {
+		if a.Required != b.Required {
+			if a.Required {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return fields, nil
+}
+
+type pendingElicitation struct {
+	fields []ElicitationField
+	result chan *mcp.ElicitResult
+}
+
+var (
+	elicitations        = pubsub.NewBroker[ElicitationRequest]()
+	pendingElicitations = csync.NewMap[string, pendingElicitation]()
+)
+
+// SubscribeElicitations returns a channel for the elicitation requests of MCP
+// servers. Requests are only forwarded to the user while someone is
+// subscribed, and canceled otherwise, like in non-interactive runs.
+func SubscribeElicitations(ctx context.Context) <-chan pubsub.Event[ElicitationRequest] {
+	return elicitations.Subscribe(ctx)
+}
+
+// interactive reports whether someone is around to answer the requests of MCP
+// servers.
+func interactive() bool {
+	return elicitations.GetSubscriberCount() > 0
+}
+
+// AcceptElicitation answers an elicitation request with the text entered for
+// each field. Nothing is sent if a value is invalid, so it can be corrected.
+func AcceptElicitation(id string, values map[string]string) error {
+	pending, ok := pendingElicitations.Get(id)
+	if !ok {
+		return ErrElicitationNotPending
+	}
+	content := make(map[string]any)
+	for _, field := range pending.fields {
+		v, ok, err := field.Value(values[field.Name])
+		if err != nil {
+			return err
+		}
+		if ok {
+			content[field.Name] = v
+		}
+	}
+	return respondElicitation(id, &mcp.ElicitResult{Action: "accept", Content: content})
+}
+
+// CancelElicitation answers an elicitation request the user dismissed.
+func CancelElicitation(id string) error {
+	return respondElicitation(id, &mcp.ElicitResult{Action: "cancel"})
+}
+
+func respondElicitation(id string, result *mcp.ElicitResult) error {
+	pending, ok := pendingElicitations.Take(id)
+	if !ok {
+		return ErrElicitationNotPending
+	}
+	pending.result <- result
+	return nil
+}
+
+// elicit returns the handler of the elicitation requests of an MCP server,
+// which waits for the user to fill the requested form.
+func elicit(name string) func(context.Context, *mcp.ElicitRequest) (*mcp.ElicitResult, error) {
+	return func(ctx context.Context, req *mcp.ElicitRequest) (*mcp.ElicitResult, error) {
+		if mode := cmp.Or(req.Params.Mode, "form"); mode != "form" {
+			return nil, fmt.Errorf("unsupported elicitation mode: %q", mode)
+		}
+		if !interactive() {
+			return &mcp.ElicitResult{Action: "cancel"}, nil
+		}
+		fields, err := elicitationFields(req.Params.RequestedSchema)
+		if err != nil {
+			return nil, err
+		}
+
+		request := ElicitationRequest{
+			ID:      uuid.NewString(),
+			Name:    name,
+			Message: req.Params.Message,
+			Fields:  fields,
+		}
+		result := make(chan *mcp.ElicitResult, 1)
+		pendingElicitations.Set(request.ID, pendingElicitation{fields: fields, result: result})
+		defer pendingElicitations.Del(request.ID)
+
+		elicitations.Publish(pubsub.CreatedEvent, request)
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case res := <-result:
+			return res, nil
+		}
+	}
+}
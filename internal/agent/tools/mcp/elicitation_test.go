@@ -0,0 +1,116 @@
+package mcp
+
+import (
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testElicitationSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"name": map[string]any{"type": "string", "title": "Name"},
+		"age":  map[string]any{"type": "integer"},
+		"size": map[string]any{"type": "string", "enum": []any{"s", "m", "l"}, "default": "m"},
+		"ok":   map[string]any{"type": "boolean", "default": true},
+	},
+	"required": []any{"name"},
+}
+
+func TestElicitationFields(t *testing.T) {
+	t.Parallel()
+	fields, err := elicitationFields(testElicitationSchema)
+	require.NoError(t, err)
+	require.Len(t, fields, 4)
+
+	// Required fields come first.
+	require.Equal(t, "name", fields[0].Name)
+	require.True(t, fields[0].Required)
+	require.Equal(t, []string{"age", "ok", "size"}, []string{fields[1].Name, fields[2].Name, fields[3].Name})
+	require.Equal(t, "yes", fields[2].Default)
+	require.Equal(t, []string{"s", "m", "l"}, fields[3].Options)
+	require.Equal(t, "m", fields[3].Default)
+}
+
+func TestElicitationFieldValue(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		field ElicitationField
+		text  string
+		want  any
+		err   bool
+	}{
+		{field: ElicitationField{Type: "string"}, text: " hi ", want: "hi"},
+		{field: ElicitationField{Type: "string"}, text: ""},
+		{field: ElicitationField{Type: "string", Required: true}, text: "", err: true},
+		{field: ElicitationField{Type: "string", Options: []string{"a"}}, text: "b", err: true},
+		{field: ElicitationField{Type: "integer"}, text: "42", want: int64(42)},
+		{field: ElicitationField{Type: "integer"}, text: "4.2", err: true},
+		{field: ElicitationField{Type: "number"}, text: "4.2", want: 4.2},
+		{field: ElicitationField{Type: "boolean"}, text: "Yes", want: true},
+		{field: ElicitationField{Type: "boolean"}, text: "maybe", err: true},
+		{field: ElicitationField{Type: "array", Options: []string{"a", "b"}}, text: "a, b", want: []string{"a", "b"}},
+		{field: ElicitationField{Type: "array", Options: []string{"a", "b"}}, text: "c", err: true},
+	} {
+		got, ok, err := tt.field.Value(tt.text)
+		if tt.err {
+			require.Error(t, err, tt.text)
+			continue
+		}
+		require.NoError(t, err, tt.text)
+		require.Equal(t, tt.want != nil, ok, tt.text)
+		require.Equal(t, tt.want, got, tt.text)
+	}
+}
+
+func TestElicitation(t *testing.T) {
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	server := mcp.NewServer(&mcp.Implementation{Name: "asker"}, nil)
+	serverSession, err := server.Connect(t.Context(), serverTransport, nil)
+	require.NoError(t, err)
+	client := mcp.NewClient(&mcp.Implementation{Name: "crush"}, &mcp.ClientOptions{
+		ElicitationHandler: elicit("asker"),
+	})
+	clientSession, err := client.Connect(t.Context(), clientTransport, nil)
+	require.NoError(t, err)
+	defer func() {
+		_ = clientSession.Close()
+		_ = serverSession.Close()
+	}()
+
+	// Without anyone to answer, requests are canceled.
+	result, err := serverSession.Elicit(t.Context(), &mcp.ElicitParams{
+		Message:         "Who are you?",
+		RequestedSchema: testElicitationSchema,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "cancel", result.Action)
+
+	events := SubscribeElicitations(t.Context())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		event := <-events
+		assert.Equal(t, "asker", event.Payload.Name)
+		assert.Equal(t, "Who are you?", event.Payload.Message)
+		// Invalid values aren't sent, so they can be corrected.
+		assert.Error(t, AcceptElicitation(event.Payload.ID, map[string]string{"age": "old"}))
+		assert.NoError(t, AcceptElicitation(event.Payload.ID, map[string]string{"name": "Charm", "age": "3", "ok": "no"}))
+		assert.ErrorIs(t, CancelElicitation(event.Payload.ID), ErrElicitationNotPending)
+	}()
+
+	result, err = serverSession.Elicit(t.Context(), &mcp.ElicitParams{
+		Message:         "Who are you?",
+		RequestedSchema: testElicitationSchema,
+	})
+	require.NoError(t, err)
+	<-done
+	require.Equal(t, "accept", result.Action)
+	require.Equal(t, "Charm", result.Content["name"])
+	require.EqualValues(t, 3, result.Content["age"])
+	require.Equal(t, false, result.Content["ok"])
+	// Defaults are applied to the fields left empty.
+	require.Equal(t, "m", result.Content["size"])
+}
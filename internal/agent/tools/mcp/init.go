@@ -10,8 +10,10 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -163,8 +165,9 @@ func Close(ctx context.Context) error {
 }
 
 // Initialize initializes MCP clients based on the provided configuration.
-func Initialize(ctx context.Context, permissions permission.Service, cfg *config.ConfigStore) {
+func Initialize(ctx context.Context, perms permission.Service, cfg *config.ConfigStore) {
 	slog.Info("Initializing MCP clients")
+	permissions.Set(perms)
 	var wg sync.WaitGroup
 	// Initialize states for all configured MCPs
 	for name, m := range cfg.Config().MCP {
@@ -196,50 +199,73 @@ func Initialize(ctx context.Context, permissions permission.Service, cfg *config
 				}
 			}()
 
-			// createSession handles its own timeout internally.
-			session, err := createSession(ctx, name, m, cfg.Resolver())
-			if err != nil {
-				return
-			}
+			_ = startClient(ctx, cfg, name, m)
+		}(name, m)
+	}
+	wg.Wait()
+	initOnce.Do(func() { close(initDone) })
+}
 
-			tools, err := getTools(ctx, session)
-			if err != nil {
-				slog.Error("Error listing tools", "error", err)
-				updateState(name, StateError, err, nil, Counts{})
-				session.Close()
-				return
-			}
+// startClient connects to an MCP server and loads its tools, prompts and
+// resources.
+func startClient(ctx context.Context, cfg *config.ConfigStore, name string, m config.MCPConfig) error {
+	// createSession handles its own timeout internally.
+	session, err := createSession(ctx, name, m, cfg)
+	if err != nil {
+		return err
+	}
 
-			prompts, err := getPrompts(ctx, session)
-			if err != nil {
-				slog.Error("Error listing prompts", "error", err)
-				updateState(name, StateError, err, nil, Counts{})
-				session.Close()
-				return
-			}
+	tools, err := getTools(ctx, session)
+	if err != nil {
+		slog.Error("Error listing tools", "error", err)
+		updateState(name, StateError, err, nil, Counts{})
+		session.Close()
+		return err
+	}
 
-			resources, err := getResources(ctx, session)
-			if err != nil {
-				slog.Error("Error listing resources", "error", err)
-				updateState(name, StateError, err, nil, Counts{})
-				session.Close()
-				return
-			}
+	prompts, err := getPrompts(ctx, session)
+	if err != nil {
+		slog.Error("Error listing prompts", "error", err)
+		updateState(name, StateError, err, nil, Counts{})
+		session.Close()
+		return err
+	}
 
-			toolCount := updateTools(cfg, name, tools)
-			updatePrompts(name, prompts)
-			resourceCount := updateResources(name, resources)
-			sessions.Set(name, session)
+	resources, err := getResources(ctx, session)
+	if err != nil {
+		slog.Error("Error listing resources", "error", err)
+		updateState(name, StateError, err, nil, Counts{})
+		session.Close()
+		return err
+	}
 
-			updateState(name, StateConnected, nil, session, Counts{
-				Tools:     toolCount,
-				Prompts:   len(prompts),
-				Resources: resourceCount,
-			})
-		}(name, m)
+	toolCount := updateTools(cfg, name, tools)
+	updatePrompts(name, prompts)
+	resourceCount := updateResources(name, resources)
+	sessions.Set(name, session)
+
+	updateState(name, StateConnected, nil, session, Counts{
+		Tools:     toolCount,
+		Prompts:   len(prompts),
+		Resources: resourceCount,
+	})
+	return nil
+}
+
+// Restart closes the connection to an MCP server, if any, and connects to it
+// again. The connection lasts as long as ctx.
+func Restart(ctx context.Context, cfg *config.ConfigStore, name string) error {
+	m, ok := cfg.Config().MCP[name]
+	if !ok {
+		return fmt.Errorf("mcp '%s' not configured", name)
 	}
-	wg.Wait()
-	initOnce.Do(func() { close(initDone) })
+	if sess, ok := sessions.Take(name); ok {
+		if err := sess.Close(); err != nil {
+			slog.Debug("Failed to close MCP client", "name", name, "error", err)
+		}
+	}
+	updateState(name, StateStarting, nil, nil, Counts{})
+	return startClient(ctx, cfg, name, m)
 }
 
 // WaitForInit blocks until MCP initialization is complete.
@@ -271,7 +297,7 @@ func getOrRenewClient(ctx context.Context, cfg *config.ConfigStore, name string)
 	}
 	updateState(name, StateError, maybeTimeoutErr(err, timeout), nil, state.Counts)
 
-	sess, err = createSession(ctx, name, m, cfg.Resolver())
+	sess, err = createSession(ctx, name, m, cfg)
 	if err != nil {
 		return nil, err
 	}
@@ -296,6 +322,9 @@ func updateState(name string, state State, err error, client *ClientSession, cou
 	case StateError:
 		sessions.Del(name)
 	}
+	if prev, ok := states.Get(name); !ok || prev.State != state || err != nil {
+		logState(name, state, err)
+	}
 	states.Set(name, info)
 
 	// Publish state change event
@@ -308,20 +337,72 @@ func updateState(name string, state State, err error, client *ClientSession, cou
 	})
 }
 
-func createSession(ctx context.Context, name string, m config.MCPConfig, resolver config.VariableResolver) (*ClientSession, error) {
+// logState records a change of the state of an MCP client in its logs.
+func logState(name string, state State, err error) {
+	switch {
+	case err != nil:
+		appendLog(name, slog.LevelError, fmt.Sprintf("%s: %v", state, err))
+	default:
+		appendLog(name, slog.LevelInfo, state.String())
+	}
+}
+
+func createSession(ctx context.Context, name string, m config.MCPConfig, cfg *config.ConfigStore) (*ClientSession, error) {
+	creds, authorized := cfg.Config().MCPCredentials(name)
+	if authorized && creds.Expired() {
+		_ = refreshCredentials(ctx, cfg, name, creds)
+	}
+
+	session, err := connect(ctx, name, m, cfg)
+	if err != nil && unauthorized(name) && authorized {
+		// The access token was rejected before it expired, or couldn't be
+		// refreshed earlier.
+		if refreshCredentials(ctx, cfg, name, creds) == nil {
+			session, err = connect(ctx, name, m, cfg)
+		}
+	}
+	if err != nil {
+		if unauthorized(name) {
+			err = ErrUnauthorized
+		}
+		updateState(name, StateError, err, nil, Counts{})
+		slog.Error("MCP client failed to initialize", "error", err, "name", name)
+		return nil, err
+	}
+	slog.Debug("MCP client initialized", "name", name)
+	return session, nil
+}
+
+func connect(ctx context.Context, name string, m config.MCPConfig, cfg *config.ConfigStore) (*ClientSession, error) {
 	timeout := mcpTimeout(m)
 	mcpCtx, cancel := context.WithCancel(ctx)
 	cancelTimer := time.AfterFunc(timeout, cancel)
 
-	transport, err := createTransport(mcpCtx, m, resolver)
+	challenges.Del(name)
+	transport, err := createTransport(mcpCtx, name, m, cfg)
 	if err != nil {
-		updateState(name, StateError, err, nil, Counts{})
-		slog.Error("Error creating MCP client", "error", err, "name", name)
 		cancel()
 		cancelTimer.Stop()
 		return nil, err
 	}
 
+	client := newClient(name, cfg)
+
+	session, err := client.Connect(mcpCtx, transport, nil)
+	if err != nil {
+		err = maybeStdioErr(err, transport)
+		cancel()
+		cancelTimer.Stop()
+		return nil, maybeTimeoutErr(err, timeout)
+	}
+
+	cancelTimer.Stop()
+	return &ClientSession{session, cancel}, nil
+}
+
+// newClient creates the client of an MCP server, handling the requests and
+// notifications the server sends.
+func newClient(name string, cfg *config.ConfigStore) *mcp.Client {
 	client := mcp.NewClient(
 		&mcp.Implementation{
 			Name:    "crush",
@@ -350,23 +431,26 @@ func createSession(ctx context.Context, name string, m config.MCPConfig, resolve
 			LoggingMessageHandler: func(ctx context.Context, req *mcp.LoggingMessageRequest) {
 				level := parseLevel(req.Params.Level)
 				slog.Log(ctx, level, "MCP log", "name", name, "logger", req.Params.Logger, "data", req.Params.Data)
+				appendLog(name, level, logMessage(req.Params.Logger, req.Params.Data))
 			},
+			CreateMessageHandler: createMessage(name, cfg),
+			ElicitationHandler:   elicit(name),
 		},
 	)
+	client.AddRoots(workspaceRoot(cfg.WorkingDir()))
+	return client
+}
 
-	session, err := client.Connect(mcpCtx, transport, nil)
-	if err != nil {
-		err = maybeStdioErr(err, transport)
-		updateState(name, StateError, maybeTimeoutErr(err, timeout), nil, Counts{})
-		slog.Error("MCP client failed to initialize", "error", err, "name", name)
-		cancel()
-		cancelTimer.Stop()
-		return nil, err
+// workspaceRoot returns the root MCP servers may operate in.
+func workspaceRoot(dir string) *mcp.Root {
+	path := filepath.ToSlash(dir)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return &mcp.Root{
+		URI:  (&url.URL{Scheme: "file", Path: path}).String(),
+		Name: filepath.Base(dir),
 	}
-
-	cancelTimer.Stop()
-	slog.Debug("MCP client initialized", "name", name)
-	return &ClientSession{session, cancel}, nil
 }
 
 // maybeStdioErr if a stdio mcp prints an error in non-json format, it'll fail
@@ -397,10 +481,10 @@ func maybeTimeoutErr(err error, timeout time.Duration) error {
 	return err
 }
 
-func createTransport(ctx context.Context, m config.MCPConfig, resolver config.VariableResolver) (mcp.Transport, error) {
+func createTransport(ctx context.Context, name string, m config.MCPConfig, cfg *config.ConfigStore) (mcp.Transport, error) {
 	switch m.Type {
 	case config.MCPStdio:
-		command, err := resolver.ResolveValue(m.Command)
+		command, err := cfg.Resolver().ResolveValue(m.Command)
 		if err != nil {
 			return nil, fmt.Errorf("invalid mcp command: %w", err)
 		}
@@ -409,6 +493,7 @@ func createTransport(ctx context.Context, m config.MCPConfig, resolver config.Va
 		}
 		cmd := exec.CommandContext(ctx, home.Long(command), m.Args...)
 		cmd.Env = append(os.Environ(), m.ResolvedEnv()...)
+		cmd.Stderr = &stderrLog{name: name}
 		return &mcp.CommandTransport{
 			Command: cmd,
 		}, nil
@@ -418,7 +503,9 @@ func createTransport(ctx context.Context, m config.MCPConfig, resolver config.Va
 		}
 		client := &http.Client{
 			Transport: &headerRoundTripper{
+				name:    name,
 				headers: m.ResolvedHeaders(),
+				cfg:     cfg,
 			},
 		}
 		return &mcp.StreamableClientTransport{
@@ -431,7 +518,9 @@ func createTransport(ctx context.Context, m config.MCPConfig, resolver config.Va
 		}
 		client := &http.Client{
 			Transport: &headerRoundTripper{
+				name:    name,
 				headers: m.ResolvedHeaders(),
+				cfg:     cfg,
 			},
 		}
 		return &mcp.SSEClientTransport{
@@ -443,15 +532,30 @@ func createTransport(ctx context.Context, m config.MCPConfig, resolver config.Va
 	}
 }
 
+// headerRoundTripper adds the configured headers to the requests of remote
+// MCP servers, along with the OAuth access token when the server was
+// authorized and no Authorization header is configured. The challenge of
+// unauthorized responses is kept to authorize later.
 type headerRoundTripper struct {
+	name    string
 	headers map[string]string
+	cfg     *config.ConfigStore
 }
 
 func (rt headerRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
 	for k, v := range rt.headers {
 		req.Header.Set(k, v)
 	}
-	return http.DefaultTransport.RoundTrip(req)
+	if req.Header.Get("Authorization") == "" && rt.cfg != nil {
+		if creds, ok := rt.cfg.Config().MCPCredentials(rt.name); ok {
+			req.Header.Set("Authorization", "Bearer "+creds.Token.AccessToken)
+		}
+	}
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err == nil && resp.StatusCode == http.StatusUnauthorized {
+		challenges.Set(rt.name, resp.Header.Get("WWW-Authenticate"))
+	}
+	return resp, err
 }
 
 func mcpTimeout(m config.MCPConfig) time.Duration {
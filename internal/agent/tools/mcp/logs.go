@@ -0,0 +1,98 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/csync"
+)
+
+// maxLogEntries is the number of log entries kept for each MCP server.
+const maxLogEntries = 500
+
+// LogEntry is a line logged by an MCP server, or about it.
+type LogEntry struct {
+	Time    time.Time
+	Level   slog.Level
+	Message string
+}
+
+type serverLog struct {
+	mu      sync.Mutex
+	entries []LogEntry
+}
+
+var serverLogs = csync.NewMap[string, *serverLog]()
+
+// Logs returns the latest log entries of an MCP server, oldest first. They
+// include the messages it sent, what stdio servers wrote to stderr, and the
+// changes of its state.
+func Logs(name string) []LogEntry {
+	l, ok := serverLogs.Get(name)
+	if !ok {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]LogEntry(nil), l.entries...)
+}
+
+func appendLog(name string, level slog.Level, message string) {
+	l := serverLogs.GetOrSet(name, func() *serverLog { return &serverLog{} })
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, LogEntry{
+		Time:    time.Now(),
+		Level:   level,
+		Message: message,
+	})
+	if n := len(l.entries) - maxLogEntries; n > 0 {
+		l.entries = append(l.entries[:0], l.entries[n:]...)
+	}
+}
+
+// logMessage formats the data of a log message sent by an MCP server.
+func logMessage(logger string, data any) string {
+	var message string
+	switch v := data.(type) {
+	case string:
+		message = v
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			message = fmt.Sprint(v)
+		} else {
+			message = string(b)
+		}
+	}
+	if logger != "" {
+		message = logger + ": " + message
+	}
+	return message
+}
+
+// stderrLog records the lines a stdio MCP server writes to stderr.
+type stderrLog struct {
+	name string
+	buf  []byte
+}
+
+func (w *stderrLog) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		if line := strings.TrimRight(string(w.buf[:i]), "\r"); line != "" {
+			appendLog(w.name, slog.LevelInfo, line)
+		}
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
@@ -0,0 +1,50 @@
+package mcp
+
+import (
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStderrLog(t *testing.T) {
+	w := &stderrLog{name: "stderr-test"}
+	_, _ = w.Write([]byte("starting\r\nlisten"))
+	_, _ = w.Write([]byte("ing on stdio\n\n"))
+
+	logs := Logs("stderr-test")
+	require.Len(t, logs, 2)
+	require.Equal(t, "starting", logs[0].Message)
+	require.Equal(t, "listening on stdio", logs[1].Message)
+}
+
+func TestLogsAreCapped(t *testing.T) {
+	for i := range maxLogEntries + 10 {
+		appendLog("capped-test", slog.LevelInfo, fmt.Sprint(i))
+	}
+	logs := Logs("capped-test")
+	require.Len(t, logs, maxLogEntries)
+	require.Equal(t, "10", logs[0].Message)
+}
+
+func TestLogState(t *testing.T) {
+	updateState("state-test", StateStarting, nil, nil, Counts{})
+	updateState("state-test", StateConnected, nil, nil, Counts{})
+	// Unchanged states aren't logged again.
+	updateState("state-test", StateConnected, nil, nil, Counts{Tools: 1})
+	updateState("state-test", StateError, fmt.Errorf("boom"), nil, Counts{})
+
+	logs := Logs("state-test")
+	require.Len(t, logs, 3)
+	require.Equal(t, "starting", logs[0].Message)
+	require.Equal(t, "connected", logs[1].Message)
+	require.Equal(t, "error: boom", logs[2].Message)
+	require.Equal(t, slog.LevelError, logs[2].Level)
+}
+
+func TestLogMessage(t *testing.T) {
+	t.Parallel()
+	require.Equal(t, "hello", logMessage("", "hello"))
+	require.Equal(t, `db: {"rows":3}`, logMessage("db", map[string]any{"rows": 3}))
+}
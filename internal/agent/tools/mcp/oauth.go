@@ -0,0 +1,101 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/oauth/mcpauth"
+)
+
+// ErrUnauthorized is the error of MCP servers that require authorization.
+var ErrUnauthorized = errors.New("authorization required")
+
+var (
+	// challenges holds the WWW-Authenticate header of the last unauthorized
+	// response of each server.
+	challenges = csync.NewMap[string, string]()
+	refreshMu  sync.Mutex
+)
+
+// NeedsAuthorization reports whether an MCP server requires authorization.
+func NeedsAuthorization(name string) bool {
+	state, ok := states.Get(name)
+	return ok && state.State == StateError && errors.Is(state.Error, ErrUnauthorized)
+}
+
+// unauthorized reports whether the last request to the server was rejected
+// for a lack of authorization.
+func unauthorized(name string) bool {
+	_, ok := challenges.Get(name)
+	return ok
+}
+
+// refreshCredentials refreshes the access token of an MCP server. The stale
+// credentials are the ones that stopped working, so the token isn't
+// refreshed twice when several requests fail at once.
+func refreshCredentials(ctx context.Context, cfg *config.ConfigStore, name string, stale mcpauth.Credentials) error {
+	refreshMu.Lock()
+	defer refreshMu.Unlock()
+
+	creds, ok := cfg.Config().MCPCredentials(name)
+	if !ok {
+		return mcpauth.ErrNoRefreshToken
+	}
+	if creds.Token.AccessToken != stale.Token.AccessToken {
+		return nil
+	}
+	refreshed, err := mcpauth.Refresh(ctx, nil, creds)
+	if err != nil {
+		slog.Warn("Failed to refresh MCP token", "name", name, "error", err)
+		return err
+	}
+	slog.Info("Refreshed MCP token", "name", name)
+	return cfg.SetMCPCredentials(name, refreshed)
+}
+
+// StartAuthorization starts authorizing Crush with a remote MCP server. The
+// user completes it in the browser, and [CompleteAuthorization] waits for it.
+func StartAuthorization(ctx context.Context, cfg *config.ConfigStore, name string) (*mcpauth.Authorization, error) {
+	m, ok := cfg.Config().MCP[name]
+	if !ok {
+		return nil, fmt.Errorf("mcp '%s' not configured", name)
+	}
+	if m.Type != config.MCPHttp && m.Type != config.MCPSSE {
+		return nil, fmt.Errorf("mcp '%s' is not an http or sse server", name)
+	}
+
+	challenge, _ := challenges.Get(name)
+	opts := mcpauth.Options{
+		ServerURL: m.URL,
+		Challenge: challenge,
+		// The browser of a remote session can't reach the local callback.
+		Device: os.Getenv("SSH_CONNECTION") != "" || os.Getenv("SSH_TTY") != "",
+	}
+	if m.OAuth != nil {
+		secret, err := cfg.Resolver().ResolveValue(m.OAuth.ClientSecret)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client secret: %w", err)
+		}
+		opts.ClientID = m.OAuth.ClientID
+		opts.ClientSecret = secret
+		opts.Scopes = m.OAuth.Scopes
+		opts.CallbackPort = m.OAuth.CallbackPort
+	}
+	return mcpauth.Start(ctx, opts)
+}
+
+// CompleteAuthorization waits for the user to complete an authorization, and
+// stores the credentials obtained. [Restart] connects with them.
+func CompleteAuthorization(ctx context.Context, cfg *config.ConfigStore, name string, auth *mcpauth.Authorization) error {
+	creds, err := auth.Wait(ctx)
+	if err != nil {
+		return err
+	}
+	return cfg.SetMCPCredentials(name, creds)
+}
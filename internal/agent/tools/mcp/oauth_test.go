@@ -0,0 +1,100 @@
+package mcp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/oauth"
+	"github.com/charmbracelet/crush/internal/oauth/mcpauth"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/require"
+)
+
+// newProtectedServer starts an MCP server only accepting the given access
+// token, along with a token endpoint refreshing refresh-1 into it.
+func newProtectedServer(t *testing.T, accessToken string) *httptest.Server {
+	t.Helper()
+	server := mcp.NewServer(&mcp.Implementation{Name: "protected"}, nil)
+	handler := mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return server }, nil)
+
+	var srv *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mcp", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+accessToken {
+			w.Header().Set("WWW-Authenticate", `Bearer resource_metadata="`+srv.URL+`/.well-known/oauth-protected-resource/mcp"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("grant_type") != "refresh_token" || r.FormValue("refresh_token") != "refresh-1" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"` + accessToken + `","expires_in":3600}`))
+	})
+	srv = httptest.NewServer(mux)
+	t.Cleanup(func() {
+		srv.CloseClientConnections()
+		srv.Close()
+		http.DefaultTransport.(*http.Transport).CloseIdleConnections()
+	})
+	return srv
+}
+
+func newAuthTestConfig(t *testing.T, name, url string) *config.ConfigStore {
+	t.Helper()
+	t.Setenv("CRUSH_GLOBAL_CONFIG", t.TempDir())
+	t.Setenv("CRUSH_GLOBAL_DATA", t.TempDir())
+	cfg, err := config.Init(t.TempDir(), "", false)
+	require.NoError(t, err)
+	cfg.Config().MCP[name] = config.MCPConfig{Type: config.MCPHttp, URL: url}
+	return cfg
+}
+
+func TestCreateSessionRefreshesRejectedToken(t *testing.T) {
+	srv := newProtectedServer(t, "access-2")
+	cfg := newAuthTestConfig(t, "protected-refresh", srv.URL+"/mcp")
+
+	// The token hasn't expired yet, but the server rejects it.
+	require.NoError(t, cfg.SetMCPCredentials("protected-refresh", mcpauth.Credentials{
+		ServerURL: srv.URL + "/mcp",
+		ClientID:  "client-1",
+		TokenURL:  srv.URL + "/token",
+		Token: &oauth.Token{
+			AccessToken:  "access-1",
+			RefreshToken: "refresh-1",
+			ExpiresIn:    3600,
+			ExpiresAt:    time.Now().Add(time.Hour).Unix(),
+		},
+	}))
+
+	m := cfg.Config().MCP["protected-refresh"]
+	session, err := createSession(t.Context(), "protected-refresh", m, cfg)
+	require.NoError(t, err)
+	require.NoError(t, session.Close())
+
+	creds, ok := cfg.Config().MCPCredentials("protected-refresh")
+	require.True(t, ok)
+	require.Equal(t, "access-2", creds.Token.AccessToken)
+	require.Equal(t, "refresh-1", creds.Token.RefreshToken)
+	require.True(t, cfg.HasConfigField(config.ScopeGlobal, "mcp_oauth.protected-refresh.token.access_token"))
+}
+
+func TestCreateSessionRequiresAuthorization(t *testing.T) {
+	srv := newProtectedServer(t, "access-1")
+	cfg := newAuthTestConfig(t, "protected-unauthorized", srv.URL+"/mcp")
+
+	m := cfg.Config().MCP["protected-unauthorized"]
+	_, err := createSession(t.Context(), "protected-unauthorized", m, cfg)
+	require.ErrorIs(t, err, ErrUnauthorized)
+	require.True(t, NeedsAuthorization("protected-unauthorized"))
+
+	challenge, _ := challenges.Get("protected-unauthorized")
+	require.Contains(t, challenge, "resource_metadata=")
+}
@@ -19,6 +19,12 @@ func Prompts() iter.Seq2[string, []*Prompt] {
 	return allPrompts.Seq2()
 }
 
+// ServerPrompts returns the prompts of an MCP server.
+func ServerPrompts(name string) []*Prompt {
+	prompts, _ := allPrompts.Get(name)
+	return prompts
+}
+
 // GetPromptMessages retrieves the content of an MCP prompt with the given arguments.
 func GetPromptMessages(ctx context.Context, cfg *config.ConfigStore, clientName, promptName string, args map[string]string) ([]string, error) {
 	c, err := getOrRenewClient(ctx, cfg, clientName)
@@ -23,6 +23,12 @@ func Resources() iter.Seq2[string, []*Resource] {
 	return allResources.Seq2()
 }
 
+// ServerResources returns the resources of an MCP server.
+func ServerResources(name string) []*Resource {
+	resources, _ := allResources.Get(name)
+	return resources
+}
+
 // ListResources returns the current resources for an MCP server.
 func ListResources(ctx context.Context, cfg *config.ConfigStore, name string) ([]*Resource, error) {
 	session, err := getOrRenewClient(ctx, cfg, name)
@@ -0,0 +1,172 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Sampler completes the messages MCP servers send in sampling requests.
+type Sampler func(ctx context.Context, call fantasy.Call) (*fantasy.Response, error)
+
+var (
+	errSamplingUnavailable = errors.New("sampling is not available")
+
+	sampler     = csync.NewValue[Sampler](nil)
+	permissions = csync.NewValue[permission.Service](nil)
+)
+
+// SetSampler sets the sampler completing the sampling requests of MCP
+// servers. Sampling requests are rejected until it's set.
+func SetSampler(s Sampler) {
+	sampler.Set(s)
+}
+
+// samplingParams is how sampling requests are shown when asking for
+// permission.
+type samplingParams struct {
+	SystemPrompt string            `json:"system_prompt,omitempty"`
+	Messages     []samplingMessage `json:"messages"`
+	MaxTokens    int64             `json:"max_tokens"`
+}
+
+type samplingMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// createMessage returns the handler of the sampling requests of an MCP
+// server, which completes the messages with the small model once the user
+// allows it.
+func createMessage(name string, cfg *config.ConfigStore) func(context.Context, *mcp.CreateMessageRequest) (*mcp.CreateMessageResult, error) {
+	return func(ctx context.Context, req *mcp.CreateMessageRequest) (*mcp.CreateMessageResult, error) {
+		sample := sampler.Get()
+		if sample == nil {
+			return nil, errSamplingUnavailable
+		}
+		call, err := samplingCall(req.Params)
+		if err != nil {
+			return nil, err
+		}
+		if err := allowSampling(ctx, name, cfg, req.Params); err != nil {
+			return nil, err
+		}
+
+		resp, err := sample(ctx, call)
+		if err != nil {
+			return nil, err
+		}
+		return &mcp.CreateMessageResult{
+			Content:    &mcp.TextContent{Text: resp.Content.Text()},
+			Model:      cfg.Config().Models[config.SelectedModelTypeSmall].Model,
+			Role:       "assistant",
+			StopReason: stopReason(resp.FinishReason),
+		}, nil
+	}
+}
+
+// allowSampling asks the user whether an MCP server may use the small model.
+func allowSampling(ctx context.Context, name string, cfg *config.ConfigStore, params *mcp.CreateMessageParams) error {
+	perms := permissions.Get()
+	if perms == nil {
+		return errSamplingUnavailable
+	}
+	if !perms.SkipRequests() && !interactive() {
+		return permission.ErrorPermissionDenied
+	}
+
+	shown := samplingParams{
+		SystemPrompt: params.SystemPrompt,
+		MaxTokens:    params.MaxTokens,
+	}
+	for _, msg := range params.Messages {
+		var content string
+		switch c := msg.Content.(type) {
+		case *mcp.TextContent:
+			content = c.Text
+		case *mcp.ImageContent:
+			content = "[image]"
+		case *mcp.AudioContent:
+			content = "[audio]"
+		}
+		shown.Messages = append(shown.Messages, samplingMessage{Role: string(msg.Role), Content: content})
+	}
+	data, err := json.Marshal(shown)
+	if err != nil {
+		return err
+	}
+
+	// Sampling requests aren't made from a session, so they can't be
+	// allowed for one.
+	granted, err := perms.Request(ctx, permission.CreatePermissionRequest{
+		ToolName:    fmt.Sprintf("mcp_%s_sampling", name),
+		Action:      "sample",
+		Description: fmt.Sprintf("let %s complete messages with the small model", name),
+		Params:      string(data),
+		Path:        cfg.WorkingDir(),
+		OneTime:     true,
+	})
+	if err != nil {
+		return err
+	}
+	if !granted {
+		return permission.ErrorPermissionDenied
+	}
+	return nil
+}
+
+// samplingCall converts a sampling request to a call to a language model.
+func samplingCall(params *mcp.CreateMessageParams) (fantasy.Call, error) {
+	var call fantasy.Call
+	if params.SystemPrompt != "" {
+		call.Prompt = append(call.Prompt, fantasy.NewSystemMessage(params.SystemPrompt))
+	}
+	for _, msg := range params.Messages {
+		var part fantasy.MessagePart
+		switch content := msg.Content.(type) {
+		case *mcp.TextContent:
+			part = fantasy.TextPart{Text: content.Text}
+		case *mcp.ImageContent:
+			part = fantasy.FilePart{Data: content.Data, MediaType: content.MIMEType}
+		case *mcp.AudioContent:
+			part = fantasy.FilePart{Data: content.Data, MediaType: content.MIMEType}
+		default:
+			return fantasy.Call{}, fmt.Errorf("unsupported sampling content: %T", msg.Content)
+		}
+		role := fantasy.MessageRoleUser
+		if msg.Role == "assistant" {
+			role = fantasy.MessageRoleAssistant
+		}
+		call.Prompt = append(call.Prompt, fantasy.Message{
+			Role:    role,
+			Content: []fantasy.MessagePart{part},
+		})
+	}
+	if params.MaxTokens > 0 {
+		call.MaxOutputTokens = &params.MaxTokens
+	}
+	if params.Temperature != 0 {
+		call.Temperature = &params.Temperature
+	}
+	return call, nil
+}
+
+func stopReason(reason fantasy.FinishReason) string {
+	switch reason {
+	case fantasy.FinishReasonStop:
+		return "endTurn"
+	case fantasy.FinishReasonLength:
+		return "maxTokens"
+	case fantasy.FinishReasonToolCalls:
+		return "toolUse"
+	default:
+		return string(reason)
+	}
+}
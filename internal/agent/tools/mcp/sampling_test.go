@@ -0,0 +1,154 @@
+package mcp
+
+import (
+	"context"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/require"
+)
+
+// connectTestClient connects the client of the given MCP server to a server
+// in memory, returning the server side of the session.
+func connectTestClient(t *testing.T, name string, cfg *config.ConfigStore) *mcp.ServerSession {
+	t.Helper()
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+
+	server := mcp.NewServer(&mcp.Implementation{Name: name}, nil)
+	serverSession, err := server.Connect(t.Context(), serverTransport, nil)
+	require.NoError(t, err)
+
+	clientSession, err := newClient(name, cfg).Connect(t.Context(), clientTransport, nil)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = clientSession.Close()
+		_ = serverSession.Close()
+	})
+	return serverSession
+}
+
+func newSamplingTestConfig(t *testing.T, skipRequests bool) *config.ConfigStore {
+	t.Helper()
+	t.Setenv("CRUSH_GLOBAL_CONFIG", t.TempDir())
+	t.Setenv("CRUSH_GLOBAL_DATA", t.TempDir())
+	cfg, err := config.Init(t.TempDir(), "", false)
+	require.NoError(t, err)
+	cfg.Config().Models[config.SelectedModelTypeSmall] = config.SelectedModel{Model: "small-1"}
+
+	permissions.Set(permission.NewPermissionService(cfg.WorkingDir(), skipRequests, nil, nil, nil))
+	t.Cleanup(func() { permissions.Set(nil) })
+	return cfg
+}
+
+func setTestSampler(t *testing.T, s Sampler) {
+	t.Helper()
+	SetSampler(s)
+	t.Cleanup(func() { SetSampler(nil) })
+}
+
+func TestSampling(t *testing.T) {
+	cfg := newSamplingTestConfig(t, true)
+
+	var got fantasy.Call
+	setTestSampler(t, func(_ context.Context, call fantasy.Call) (*fantasy.Response, error) {
+		got = call
+		return &fantasy.Response{
+			Content:      fantasy.ResponseContent{fantasy.TextContent{Text: "Hello!"}},
+			FinishReason: fantasy.FinishReasonStop,
+		}, nil
+	})
+
+	session := connectTestClient(t, "sampler", cfg)
+	result, err := session.CreateMessage(t.Context(), &mcp.CreateMessageParams{
+		SystemPrompt: "Be nice.",
+		MaxTokens:    100,
+		Messages: []*mcp.SamplingMessage{
+			{Role: "user", Content: &mcp.TextContent{Text: "Hi"}},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "Hello!", result.Content.(*mcp.TextContent).Text)
+	require.Equal(t, "small-1", result.Model)
+	require.Equal(t, "endTurn", result.StopReason)
+
+	require.Len(t, got.Prompt, 2)
+	require.Equal(t, fantasy.MessageRoleSystem, got.Prompt[0].Role)
+	require.Equal(t, fantasy.MessageRoleUser, got.Prompt[1].Role)
+	require.Equal(t, int64(100), *got.MaxOutputTokens)
+}
+
+func TestSamplingWithoutUser(t *testing.T) {
+	cfg := newSamplingTestConfig(t, false)
+
+	called := false
+	setTestSampler(t, func(context.Context, fantasy.Call) (*fantasy.Response, error) {
+		called = true
+		return &fantasy.Response{}, nil
+	})
+
+	// Nobody is around to allow the request, so it's denied without
+	// blocking.
+	session := connectTestClient(t, "sampler", cfg)
+	_, err := session.CreateMessage(t.Context(), &mcp.CreateMessageParams{
+		MaxTokens: 100,
+		Messages: []*mcp.SamplingMessage{
+			{Role: "user", Content: &mcp.TextContent{Text: "Hi"}},
+		},
+	})
+	require.Error(t, err)
+	require.False(t, called)
+}
+
+func TestSamplingAllowedOnce(t *testing.T) {
+	cfg := newSamplingTestConfig(t, false)
+	setTestSampler(t, func(context.Context, fantasy.Call) (*fantasy.Response, error) {
+		return &fantasy.Response{Content: fantasy.ResponseContent{fantasy.TextContent{Text: "Hello!"}}}, nil
+	})
+	// Someone is around to answer.
+	_ = SubscribeElicitations(t.Context())
+	perms := permissions.Get()
+	requests := perms.Subscribe(t.Context())
+
+	session := connectTestClient(t, "sampler", cfg)
+	sample := func() <-chan error {
+		result := make(chan error, 1)
+		go func() {
+			_, err := session.CreateMessage(t.Context(), &mcp.CreateMessageParams{
+				MaxTokens: 100,
+				Messages: []*mcp.SamplingMessage{
+					{Role: "user", Content: &mcp.TextContent{Text: "Hi"}},
+				},
+			})
+			result <- err
+		}()
+		return result
+	}
+
+	// Allowing it for the session only allows it once, as sampling
+	// requests aren't made from a session.
+	result := sample()
+	event := <-requests
+	require.True(t, event.Payload.OneTime)
+	perms.GrantPersistent(event.Payload)
+	require.NoError(t, <-result)
+
+	result = sample()
+	event = <-requests
+	perms.Deny(event.Payload)
+	require.Error(t, <-result)
+}
+
+func TestListRoots(t *testing.T) {
+	cfg := newSamplingTestConfig(t, true)
+
+	session := connectTestClient(t, "roots", cfg)
+	result, err := session.ListRoots(t.Context(), nil)
+	require.NoError(t, err)
+	require.Len(t, result.Roots, 1)
+	require.Equal(t, workspaceRoot(cfg.WorkingDir()).URI, result.Roots[0].URI)
+	require.Contains(t, result.Roots[0].URI, "file://")
+}
@@ -24,13 +24,38 @@ type ToolResult struct {
 	MediaType string
 }
 
-var allTools = csync.NewMap[string, []*Tool]()
+var (
+	allTools = csync.NewMap[string, []*Tool]()
+	// serverTools are the tools of each MCP server, including the disabled
+	// ones.
+	serverTools = csync.NewMap[string, []*Tool]()
+)
 
 // Tools returns all available MCP tools.
 func Tools() iter.Seq2[string, []*Tool] {
 	return allTools.Seq2()
 }
 
+// ServerTools returns all the tools of an MCP server, including the ones
+// disabled in the config.
+func ServerTools(name string) []*Tool {
+	tools, _ := serverTools.Get(name)
+	return tools
+}
+
+// ApplyDisabledTools filters the tools of an MCP server again, after its
+// disabled tools changed in the config.
+func ApplyDisabledTools(cfg *config.ConfigStore, name string) {
+	toolCount := updateTools(cfg, name, ServerTools(name))
+
+	prev, ok := states.Get(name)
+	if !ok || prev.State != StateConnected {
+		return
+	}
+	prev.Counts.Tools = toolCount
+	updateState(name, StateConnected, nil, prev.Client, prev.Counts)
+}
+
 // RunTool runs an MCP tool with the given input parameters.
 func RunTool(ctx context.Context, cfg *config.ConfigStore, name, toolName string, input string) (ToolResult, error) {
 	var args map[string]any
@@ -140,6 +165,7 @@ func getTools(ctx context.Context, session *ClientSession) ([]*Tool, error) {
 }
 
 func updateTools(cfg *config.ConfigStore, name string, tools []*Tool) int {
+	serverTools.Set(name, tools)
 	tools = filterDisabledTools(cfg, name, tools)
 	if len(tools) == 0 {
 		allTools.Del(name)
@@ -0,0 +1,58 @@
+package mcp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyDisabledTools(t *testing.T) {
+	cfg := newAuthTestConfig(t, "toolbox", "http://localhost/mcp")
+	tools := []*Tool{{Name: "read"}, {Name: "write"}}
+	updateState("toolbox", StateConnected, nil, nil, Counts{Tools: updateTools(cfg, "toolbox", tools)})
+
+	require.NoError(t, cfg.SetMCPToolDisabled(config.ScopeGlobal, "toolbox", "write", true))
+	ApplyDisabledTools(cfg, "toolbox")
+
+	available, _ := allTools.Get("toolbox")
+	require.Len(t, available, 1)
+	require.Equal(t, "read", available[0].Name)
+	// Disabled tools are still listed, so they can be enabled again.
+	require.Len(t, ServerTools("toolbox"), 2)
+	state, _ := GetState("toolbox")
+	require.Equal(t, 1, state.Counts.Tools)
+}
+
+func TestCheck(t *testing.T) {
+	server := mcp.NewServer(&mcp.Implementation{Name: "checked", Version: "1.2.3"}, nil)
+	mcp.AddTool(server, &mcp.Tool{Name: "echo"}, func(context.Context, *mcp.CallToolRequest, struct{}) (*mcp.CallToolResult, any, error) {
+		return &mcp.CallToolResult{}, nil, nil
+	})
+	srv := httptest.NewServer(mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return server }, nil))
+	t.Cleanup(func() {
+		srv.CloseClientConnections()
+		srv.Close()
+		http.DefaultTransport.(*http.Transport).CloseIdleConnections()
+	})
+	cfg := newAuthTestConfig(t, "checked", srv.URL)
+
+	result, err := Check(t.Context(), cfg, "checked")
+	require.NoError(t, err)
+	require.Equal(t, "checked", result.Server.Name)
+	require.Equal(t, "1.2.3", result.Server.Version)
+	require.Len(t, result.Tools, 1)
+	require.Equal(t, "echo", result.Tools[0].Name)
+	require.Empty(t, result.Prompts)
+
+	// Checking a server doesn't make it available to the agent.
+	_, ok := GetState("checked")
+	require.False(t, ok)
+
+	_, err = Check(t.Context(), cfg, "missing")
+	require.Error(t, err)
+}
@@ -35,6 +35,9 @@ type MultiEditPermissionsParams struct {
 	FilePath   string `json:"file_path"`
 	OldContent string `json:"old_content,omitempty"`
 	NewContent string `json:"new_content,omitempty"`
+	// Reformatted is whether the formatter of the file changed the new
+	// content, so the diff includes its changes.
+	Reformatted bool `json:"reformatted,omitempty"`
 }
 
 type FailedEdit struct {
@@ -68,6 +71,8 @@ func NewMultiEditTool(
 		MultiEditToolName,
 		string(multieditDescription),
 		func(ctx context.Context, params MultiEditParams, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			workingDir := GetWorkingDirFromContext(ctx, workingDir)
+			lspManager := lspManager.ForRoot(workingDir)
 			if params.FilePath == "" {
 				return fantasy.NewTextErrorResponse("file_path is required"), nil
 			}
@@ -86,7 +91,7 @@ func NewMultiEditTool(
 			var response fantasy.ToolResponse
 			var err error
 
-			editCtx := editContext{ctx, permissions, files, filetracker, workingDir}
+			editCtx := editContext{ctx, permissions, files, filetracker, workingDir, lspManager}
 			// Handle file creation case (first edit has empty old_string)
 			if len(params.Edits) > 0 && params.Edits[0].OldString == "" {
 				response, err = processMultiEditWithCreation(editCtx, params, call)
@@ -168,6 +173,8 @@ func processMultiEditWithCreation(edit editContext, params MultiEditParams, call
 		return fantasy.ToolResponse{}, fmt.Errorf("session ID is required for creating a new file")
 	}
 
+	currentContent, reformatted := formatContent(edit.ctx, edit.lspManager, params.FilePath, currentContent)
+
 	// Check permissions
 	_, additions, removals := diff.GenerateDiff("", currentContent, strings.TrimPrefix(params.FilePath, edit.workingDir))
 
@@ -186,9 +193,10 @@ func processMultiEditWithCreation(edit editContext, params MultiEditParams, call
 		Action:      "write",
 		Description: description,
 		Params: MultiEditPermissionsParams{
-			FilePath:   params.FilePath,
-			OldContent: "",
-			NewContent: currentContent,
+			FilePath:    params.FilePath,
+			OldContent:  "",
+			NewContent:  currentContent,
+			Reformatted: reformatted,
 		},
 	})
 	if err != nil {
@@ -205,7 +213,7 @@ func processMultiEditWithCreation(edit editContext, params MultiEditParams, call
 	}
 
 	// Update file history
-	_, err = edit.files.Create(edit.ctx, sessionID, params.FilePath, "")
+	_, err = edit.files.CreateNew(edit.ctx, sessionID, params.FilePath)
 	if err != nil {
 		return fantasy.ToolResponse{}, fmt.Errorf("error creating file history: %w", err)
 	}
@@ -225,7 +233,7 @@ func processMultiEditWithCreation(edit editContext, params MultiEditParams, call
 	}
 
 	return fantasy.WithResponseMetadata(
-		fantasy.NewTextResponse(message),
+		fantasy.NewTextResponse(noteFormatting(message, reformatted)),
 		MultiEditResponseMetadata{
 			OldContent:   "",
 			NewContent:   currentContent,
@@ -295,6 +303,8 @@ func processMultiEditExistingFile(edit editContext, params MultiEditParams, call
 		currentContent = newContent
 	}
 
+	currentContent, reformatted := formatContent(edit.ctx, edit.lspManager, params.FilePath, currentContent)
+
 	// Check if content actually changed
 	if oldContent == currentContent {
 		// If we have failed edits, report them
@@ -328,9 +338,10 @@ func processMultiEditExistingFile(edit editContext, params MultiEditParams, call
 		Action:      "write",
 		Description: description,
 		Params: MultiEditPermissionsParams{
-			FilePath:   params.FilePath,
-			OldContent: oldContent,
-			NewContent: currentContent,
+			FilePath:    params.FilePath,
+			OldContent:  oldContent,
+			NewContent:  currentContent,
+			Reformatted: reformatted,
 		},
 	})
 	if err != nil {
@@ -382,7 +393,7 @@ func processMultiEditExistingFile(edit editContext, params MultiEditParams, call
 	}
 
 	return fantasy.WithResponseMetadata(
-		fantasy.NewTextResponse(message),
+		fantasy.NewTextResponse(noteFormatting(message, reformatted)),
 		MultiEditResponseMetadata{
 			OldContent:   oldContent,
 			NewContent:   currentContent,
@@ -20,6 +20,10 @@ func (m *mockPermissionService) Request(ctx context.Context, req permission.Crea
 	return true, nil
 }
 
+func (m *mockPermissionService) Approve(ctx context.Context, req permission.CreatePermissionRequest) (bool, error) {
+	return true, nil
+}
+
 func (m *mockPermissionService) Grant(req permission.PermissionRequest) {}
 
 func (m *mockPermissionService) Deny(req permission.PermissionRequest) {}
@@ -38,6 +42,14 @@ func (m *mockPermissionService) SubscribeNotifications(ctx context.Context) <-ch
 	return make(<-chan pubsub.Event[permission.PermissionNotification])
 }
 
+func (m *mockPermissionService) SessionGrants(ctx context.Context, sessionID string) ([]permission.Grant, error) {
+	return nil, nil
+}
+
+func (m *mockPermissionService) RevokeGrant(ctx context.Context, id string) error {
+	return nil
+}
+
 type mockHistoryService struct {
 	*pubsub.Broker[history.File]
 }
@@ -46,10 +58,18 @@ func (m *mockHistoryService) Create(ctx context.Context, sessionID, path, conten
 	return history.File{Path: path, Content: content}, nil
 }
 
+func (m *mockHistoryService) CreateNew(ctx context.Context, sessionID, path string) (history.File, error) {
+	return history.File{Path: path, IsNew: true}, nil
+}
+
 func (m *mockHistoryService) CreateVersion(ctx context.Context, sessionID, path, content string) (history.File, error) {
 	return history.File{}, nil
 }
 
+func (m *mockHistoryService) Import(ctx context.Context, sessionID string, file history.File) (history.File, error) {
+	return file, nil
+}
+
 func (m *mockHistoryService) GetByPathAndSession(ctx context.Context, path, sessionID string) (history.File, error) {
 	return history.File{Path: path, Content: ""}, nil
 }
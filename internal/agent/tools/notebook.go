@@ -0,0 +1,172 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+func isNotebookFile(filePath string) bool {
+	return strings.EqualFold(filepath.Ext(filePath), ".ipynb")
+}
+
+// notebookText is a multiline string in a notebook, stored either as a
+// string or as a list of lines.
+type notebookText string
+
+func (t *notebookText) UnmarshalJSON(data []byte) error {
+	var lines []string
+	if err := json.Unmarshal(data, &lines); err == nil {
+		*t = notebookText(strings.Join(lines, ""))
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*t = notebookText(s)
+	return nil
+}
+
+type notebookOutput struct {
+	OutputType string                     `json:"output_type"`
+	Text       notebookText               `json:"text"`
+	Data       map[string]json.RawMessage `json:"data"`
+	EName      string                     `json:"ename"`
+	EValue     string                     `json:"evalue"`
+	Traceback  []string                   `json:"traceback"`
+}
+
+type notebookCell struct {
+	CellType string           `json:"cell_type"`
+	Source   notebookText     `json:"source"`
+	Outputs  []notebookOutput `json:"outputs"`
+}
+
+type notebookFile struct {
+	Cells    []notebookCell `json:"cells"`
+	Metadata struct {
+		KernelSpec struct {
+			Language string `json:"language"`
+		} `json:"kernelspec"`
+		LanguageInfo struct {
+			Name string `json:"name"`
+		} `json:"language_info"`
+	} `json:"metadata"`
+}
+
+// renderNotebook renders the cells of a Jupyter notebook, along with the
+// outputs of code cells, as text.
+func renderNotebook(data []byte) (string, error) {
+	var nb notebookFile
+	if err := json.Unmarshal(data, &nb); err != nil {
+		return "", fmt.Errorf("invalid notebook: %w", err)
+	}
+	language := nb.Metadata.KernelSpec.Language
+	if language == "" {
+		language = nb.Metadata.LanguageInfo.Name
+	}
+
+	var sb strings.Builder
+	for i, cell := range nb.Cells {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		if cell.CellType == "code" && language != "" {
+			fmt.Fprintf(&sb, "<cell index=\"%d\" type=\"code\" language=\"%s\">\n", i, language)
+		} else {
+			fmt.Fprintf(&sb, "<cell index=\"%d\" type=\"%s\">\n", i, cell.CellType)
+		}
+		writeNotebookText(&sb, string(cell.Source))
+		for _, output := range cell.Outputs {
+			sb.WriteString("<output>\n")
+			writeNotebookText(&sb, renderNotebookOutput(output))
+			sb.WriteString("</output>\n")
+		}
+		sb.WriteString("</cell>")
+	}
+	return sb.String(), nil
+}
+
+func renderNotebookOutput(output notebookOutput) string {
+	switch output.OutputType {
+	case "stream":
+		return string(output.Text)
+	case "error":
+		if len(output.Traceback) > 0 {
+			return ansi.Strip(strings.Join(output.Traceback, "\n"))
+		}
+		return output.EName + ": " + output.EValue
+	case "execute_result", "display_data":
+		// Outputs are mime bundles whose values may be JSON objects, like
+		// the ones of widgets, so only the text one is rendered.
+		var text notebookText
+		if data, ok := output.Data["text/plain"]; ok && json.Unmarshal(data, &text) == nil {
+			return string(text)
+		}
+		mimeTypes := make([]string, 0, len(output.Data))
+		for mimeType := range output.Data {
+			mimeTypes = append(mimeTypes, mimeType)
+		}
+		slices.Sort(mimeTypes)
+		return fmt.Sprintf("[%s output]", strings.Join(mimeTypes, ", "))
+	default:
+		return ""
+	}
+}
+
+func writeNotebookText(sb *strings.Builder, text string) {
+	if text == "" {
+		return
+	}
+	sb.WriteString(text)
+	if !strings.HasSuffix(text, "\n") {
+		sb.WriteString("\n")
+	}
+}
+
+// notebookSourceLines splits source into the list of lines notebooks store,
+// each line but the last keeping its newline.
+func notebookSourceLines(source string) []any {
+	lines := []any{}
+	for line := range strings.SplitAfterSeq(source, "\n") {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// decodeNotebook decodes a notebook, keeping every field, so it can be
+// edited and encoded again without losing anything.
+func decodeNotebook(data []byte) (map[string]any, []any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var nb map[string]any
+	if err := dec.Decode(&nb); err != nil {
+		return nil, nil, fmt.Errorf("invalid notebook: %w", err)
+	}
+	cells, ok := nb["cells"].([]any)
+	if !ok && nb["cells"] != nil {
+		return nil, nil, fmt.Errorf("invalid notebook: cells is not a list")
+	}
+	return nb, cells, nil
+}
+
+// encodeNotebook encodes a notebook the way Jupyter does: sorted keys, one
+// space indentation and no HTML escaping.
+func encodeNotebook(nb map[string]any) (string, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", " ")
+	if err := enc.Encode(nb); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
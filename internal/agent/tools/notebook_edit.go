@@ -0,0 +1,293 @@
+package tools
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/diff"
+	"github.com/charmbracelet/crush/internal/filepathext"
+	"github.com/charmbracelet/crush/internal/filetracker"
+	"github.com/charmbracelet/crush/internal/fsext"
+	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/google/uuid"
+)
+
+//go:embed notebook_edit.md
+var notebookEditDescription []byte
+
+type NotebookEditParams struct {
+	FilePath  string `json:"file_path" description:"The path to the Jupyter notebook (.ipynb) to edit"`
+	CellIndex int    `json:"cell_index" description:"The 0-based index of the cell to replace or delete, or where to insert the new cell"`
+	NewSource string `json:"new_source,omitempty" description:"The new source of the cell"`
+	CellType  string `json:"cell_type,omitempty" description:"The type of the cell: code or markdown. Required when inserting, defaults to the current type when replacing"`
+	EditMode  string `json:"edit_mode,omitempty" description:"The kind of edit: replace (default), insert or delete"`
+}
+
+type NotebookEditPermissionsParams struct {
+	FilePath  string `json:"file_path"`
+	CellIndex int    `json:"cell_index"`
+	EditMode  string `json:"edit_mode"`
+	OldSource string `json:"old_source,omitempty"`
+	NewSource string `json:"new_source,omitempty"`
+}
+
+type NotebookEditResponseMetadata struct {
+	CellIndex int    `json:"cell_index"`
+	EditMode  string `json:"edit_mode"`
+	OldSource string `json:"old_source,omitempty"`
+	NewSource string `json:"new_source,omitempty"`
+	Additions int    `json:"additions"`
+	Removals  int    `json:"removals"`
+}
+
+const (
+	NotebookEditToolName = "notebook_edit"
+
+	notebookEditReplace = "replace"
+	notebookEditInsert  = "insert"
+	notebookEditDelete  = "delete"
+)
+
+func NewNotebookEditTool(
+	permissions permission.Service,
+	files history.Service,
+	filetracker filetracker.Service,
+	workingDir string,
+) fantasy.AgentTool {
+	return fantasy.NewAgentTool(
+		NotebookEditToolName,
+		string(notebookEditDescription),
+		func(ctx context.Context, params NotebookEditParams, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			workingDir := GetWorkingDirFromContext(ctx, workingDir)
+			if params.FilePath == "" {
+				return fantasy.NewTextErrorResponse("file_path is required"), nil
+			}
+			if !isNotebookFile(params.FilePath) {
+				return fantasy.NewTextErrorResponse("file_path must be a Jupyter notebook (.ipynb). Use the edit tool for other files"), nil
+			}
+			if params.EditMode == "" {
+				params.EditMode = notebookEditReplace
+			}
+			switch params.EditMode {
+			case notebookEditReplace, notebookEditInsert, notebookEditDelete:
+			default:
+				return fantasy.NewTextErrorResponse("edit_mode must be replace, insert or delete"), nil
+			}
+			switch params.CellType {
+			case "", "code", "markdown":
+			default:
+				return fantasy.NewTextErrorResponse("cell_type must be code or markdown"), nil
+			}
+			if params.EditMode == notebookEditInsert && params.CellType == "" {
+				return fantasy.NewTextErrorResponse("cell_type is required when inserting a cell"), nil
+			}
+
+			sessionID := GetSessionFromContext(ctx)
+			if sessionID == "" {
+				return fantasy.ToolResponse{}, fmt.Errorf("session ID is required for editing notebooks")
+			}
+
+			filePath := filepathext.SmartJoin(workingDir, params.FilePath)
+			fileInfo, err := os.Stat(filePath)
+			if err != nil {
+				if os.IsNotExist(err) {
+					return fantasy.NewTextErrorResponse(fmt.Sprintf("file not found: %s", filePath)), nil
+				}
+				return fantasy.ToolResponse{}, fmt.Errorf("failed to access file: %w", err)
+			}
+			if fileInfo.IsDir() {
+				return fantasy.NewTextErrorResponse(fmt.Sprintf("path is a directory, not a file: %s", filePath)), nil
+			}
+
+			lastRead := filetracker.LastReadTime(ctx, sessionID, filePath)
+			if lastRead.IsZero() {
+				return fantasy.NewTextErrorResponse("you must read the notebook before editing it. Use the View tool first"), nil
+			}
+			modTime := fileInfo.ModTime().Truncate(time.Second)
+			if modTime.After(lastRead) {
+				return fantasy.NewTextErrorResponse(
+					fmt.Sprintf("file %s has been modified since it was last read (mod time: %s, last read: %s)",
+						filePath, modTime.Format(time.RFC3339), lastRead.Format(time.RFC3339),
+					)), nil
+			}
+
+			content, err := os.ReadFile(filePath)
+			if err != nil {
+				return fantasy.ToolResponse{}, fmt.Errorf("failed to read file: %w", err)
+			}
+			oldContent := string(content)
+
+			nb, cells, err := decodeNotebook(content)
+			if err != nil {
+				return fantasy.NewTextErrorResponse(err.Error()), nil
+			}
+			cells, oldSource, err := editNotebookCells(nb, cells, params)
+			if err != nil {
+				return fantasy.NewTextErrorResponse(err.Error()), nil
+			}
+			nb["cells"] = cells
+			newContent, err := encodeNotebook(nb)
+			if err != nil {
+				return fantasy.ToolResponse{}, fmt.Errorf("failed to encode notebook: %w", err)
+			}
+
+			newSource := params.NewSource
+			if params.EditMode == notebookEditDelete {
+				newSource = ""
+			}
+			_, additions, removals := diff.GenerateDiff(
+				oldSource,
+				newSource,
+				strings.TrimPrefix(filePath, workingDir),
+			)
+
+			p, err := permissions.Request(ctx,
+				permission.CreatePermissionRequest{
+					SessionID:   sessionID,
+					Path:        fsext.PathOrPrefix(filePath, workingDir),
+					ToolCallID:  call.ID,
+					ToolName:    NotebookEditToolName,
+					Action:      "write",
+					Description: fmt.Sprintf("%s cell %d of notebook %s", notebookEditVerb(params.EditMode), params.CellIndex, filePath),
+					Params: NotebookEditPermissionsParams{
+						FilePath:  filePath,
+						CellIndex: params.CellIndex,
+						EditMode:  params.EditMode,
+						OldSource: oldSource,
+						NewSource: newSource,
+					},
+				},
+			)
+			if err != nil {
+				return fantasy.ToolResponse{}, err
+			}
+			if !p {
+				return fantasy.ToolResponse{}, permission.ErrorPermissionDenied
+			}
+
+			if err := os.WriteFile(filePath, []byte(newContent), 0o644); err != nil {
+				return fantasy.ToolResponse{}, fmt.Errorf("failed to write file: %w", err)
+			}
+			recordFileVersions(ctx, files, sessionID, filePath, oldContent, newContent)
+			filetracker.RecordRead(ctx, sessionID, filePath)
+
+			return fantasy.WithResponseMetadata(
+				fantasy.NewTextResponse(fmt.Sprintf("<result>\n%s cell %d of notebook: %s\n</result>\n", notebookEditPastVerb(params.EditMode), params.CellIndex, filePath)),
+				NotebookEditResponseMetadata{
+					CellIndex: params.CellIndex,
+					EditMode:  params.EditMode,
+					OldSource: oldSource,
+					NewSource: newSource,
+					Additions: additions,
+					Removals:  removals,
+				},
+			), nil
+		})
+}
+
+// editNotebookCells applies the edit to the cells of a notebook, returning
+// the new cells and the source of the replaced or deleted cell.
+func editNotebookCells(nb map[string]any, cells []any, params NotebookEditParams) ([]any, string, error) {
+	maxIndex := len(cells) - 1
+	if params.EditMode == notebookEditInsert {
+		maxIndex = len(cells)
+	}
+	if params.CellIndex < 0 || params.CellIndex > maxIndex {
+		return nil, "", fmt.Errorf("cell_index %d is out of range: the notebook has %d cells", params.CellIndex, len(cells))
+	}
+
+	if params.EditMode == notebookEditInsert {
+		cell := map[string]any{
+			"cell_type": params.CellType,
+			"metadata":  map[string]any{},
+			"source":    notebookSourceLines(params.NewSource),
+		}
+		if params.CellType == "code" {
+			cell["execution_count"] = nil
+			cell["outputs"] = []any{}
+		}
+		// Cell IDs are only allowed, and then required, from nbformat 4.5.
+		if notebookHasCellIDs(nb) {
+			cell["id"] = uuid.NewString()[:8]
+		}
+		return slices.Insert(cells, params.CellIndex, any(cell)), "", nil
+	}
+
+	cell, ok := cells[params.CellIndex].(map[string]any)
+	if !ok {
+		return nil, "", fmt.Errorf("cell %d is not a valid cell", params.CellIndex)
+	}
+	var oldSource notebookText
+	if source, err := json.Marshal(cell["source"]); err == nil {
+		_ = oldSource.UnmarshalJSON(source)
+	}
+
+	if params.EditMode == notebookEditDelete {
+		return slices.Delete(cells, params.CellIndex, params.CellIndex+1), string(oldSource), nil
+	}
+
+	cell["source"] = notebookSourceLines(params.NewSource)
+	if params.CellType != "" {
+		cell["cell_type"] = params.CellType
+	}
+	// Outputs are stale once the source changes, and only code cells have
+	// them.
+	if cell["cell_type"] == "code" {
+		cell["execution_count"] = nil
+		cell["outputs"] = []any{}
+	} else {
+		delete(cell, "execution_count")
+		delete(cell, "outputs")
+	}
+	return cells, string(oldSource), nil
+}
+
+// notebookHasCellIDs reports whether the format of the notebook, 4.5 or
+// later, has cell IDs.
+func notebookHasCellIDs(nb map[string]any) bool {
+	major := notebookFormatNumber(nb["nbformat"])
+	minor := notebookFormatNumber(nb["nbformat_minor"])
+	return major > 4 || major == 4 && minor >= 5
+}
+
+func notebookFormatNumber(v any) int64 {
+	switch v := v.(type) {
+	case json.Number:
+		n, _ := v.Int64()
+		return n
+	case float64:
+		return int64(v)
+	default:
+		return 0
+	}
+}
+
+func notebookEditVerb(mode string) string {
+	switch mode {
+	case notebookEditInsert:
+		return "Insert"
+	case notebookEditDelete:
+		return "Delete"
+	default:
+		return "Replace"
+	}
+}
+
+func notebookEditPastVerb(mode string) string {
+	switch mode {
+	case notebookEditInsert:
+		return "Inserted"
+	case notebookEditDelete:
+		return "Deleted"
+	default:
+		return "Replaced"
+	}
+}
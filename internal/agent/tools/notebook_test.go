@@ -0,0 +1,166 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testNotebook = `{
+ "cells": [
+  {
+   "cell_type": "markdown",
+   "id": "a1",
+   "metadata": {},
+   "source": ["# Title\n", "Some <b>text</b>"]
+  },
+  {
+   "cell_type": "code",
+   "execution_count": 1,
+   "id": "b2",
+   "metadata": {},
+   "outputs": [
+    {"name": "stdout", "output_type": "stream", "text": ["hello\n"]},
+    {"data": {"image/png": "iVBORw0KGgo="}, "metadata": {}, "output_type": "display_data"},
+    {"data": {"application/vnd.jupyter.widget-view+json": {"model_id": "w1", "version_major": 2}, "text/plain": ["FloatSlider(value=0.0)"]}, "metadata": {}, "output_type": "display_data"},
+    {"data": {"application/json": {"rows": [1, 2]}}, "execution_count": 1, "metadata": {}, "output_type": "execute_result"},
+    {"ename": "ValueError", "evalue": "bad", "output_type": "error", "traceback": ["\u001b[31mValueError\u001b[0m: bad"]}
+   ],
+   "source": "print('hello')"
+  }
+ ],
+ "metadata": {"kernelspec": {"language": "python", "name": "python3"}},
+ "nbformat": 4,
+ "nbformat_minor": 5
+}`
+
+func TestRenderNotebook(t *testing.T) {
+	t.Parallel()
+
+	rendered, err := renderNotebook([]byte(testNotebook))
+	require.NoError(t, err)
+	require.Equal(t, `<cell index="0" type="markdown">
+# Title
+Some <b>text</b>
+</cell>
+<cell index="1" type="code" language="python">
+print('hello')
+<output>
+hello
+</output>
+<output>
+[image/png output]
+</output>
+<output>
+FloatSlider(value=0.0)
+</output>
+<output>
+[application/json output]
+</output>
+<output>
+ValueError: bad
+</output>
+</cell>`, rendered)
+
+	_, err = renderNotebook([]byte("not json"))
+	require.Error(t, err)
+}
+
+func TestEditNotebookCells(t *testing.T) {
+	t.Parallel()
+
+	edit := func(t *testing.T, params NotebookEditParams) (string, string) {
+		t.Helper()
+		nb, cells, err := decodeNotebook([]byte(testNotebook))
+		require.NoError(t, err)
+		cells, oldSource, err := editNotebookCells(nb, cells, params)
+		require.NoError(t, err)
+		nb["cells"] = cells
+		content, err := encodeNotebook(nb)
+		require.NoError(t, err)
+		rendered, err := renderNotebook([]byte(content))
+		require.NoError(t, err)
+		return rendered, oldSource
+	}
+
+	t.Run("replace clears outputs", func(t *testing.T) {
+		t.Parallel()
+		rendered, oldSource := edit(t, NotebookEditParams{CellIndex: 1, NewSource: "x = 1\nprint(x)", EditMode: notebookEditReplace})
+		require.Equal(t, "print('hello')", oldSource)
+		require.Equal(t, `<cell index="0" type="markdown">
+# Title
+Some <b>text</b>
+</cell>
+<cell index="1" type="code" language="python">
+x = 1
+print(x)
+</cell>`, rendered)
+	})
+
+	t.Run("insert", func(t *testing.T) {
+		t.Parallel()
+		rendered, oldSource := edit(t, NotebookEditParams{CellIndex: 2, NewSource: "## End", CellType: "markdown", EditMode: notebookEditInsert})
+		require.Empty(t, oldSource)
+		require.Contains(t, rendered, "<cell index=\"2\" type=\"markdown\">\n## End\n</cell>")
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		t.Parallel()
+		rendered, oldSource := edit(t, NotebookEditParams{CellIndex: 0, EditMode: notebookEditDelete})
+		require.Equal(t, "# Title\nSome <b>text</b>", oldSource)
+		require.NotContains(t, rendered, "Title")
+		require.Contains(t, rendered, "<cell index=\"0\" type=\"code\" language=\"python\">")
+	})
+
+	t.Run("out of range", func(t *testing.T) {
+		t.Parallel()
+		nb, cells, err := decodeNotebook([]byte(testNotebook))
+		require.NoError(t, err)
+		_, _, err = editNotebookCells(nb, cells, NotebookEditParams{CellIndex: 2, EditMode: notebookEditReplace})
+		require.Error(t, err)
+	})
+}
+
+func TestEncodeNotebookKeepsFields(t *testing.T) {
+	t.Parallel()
+
+	nb, cells, err := decodeNotebook([]byte(testNotebook))
+	require.NoError(t, err)
+	cells, _, err = editNotebookCells(nb, cells, NotebookEditParams{CellIndex: 0, CellType: "code", EditMode: notebookEditInsert})
+	require.NoError(t, err)
+	nb["cells"] = cells
+	content, err := encodeNotebook(nb)
+	require.NoError(t, err)
+
+	require.Contains(t, content, `"nbformat_minor": 5`)
+	require.Contains(t, content, `"name": "python3"`)
+	require.Contains(t, content, "Some <b>text</b>")
+	require.Contains(t, content, `"execution_count": null`)
+
+	_, cells, err = decodeNotebook([]byte(content))
+	require.NoError(t, err)
+	require.Len(t, cells, 3)
+	require.Len(t, cells[0].(map[string]any)["id"], 8)
+}
+
+func TestEditNotebookCellIDs(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		minor string
+		id    bool
+	}{
+		{"4", false},
+		{"5", true},
+	} {
+		t.Run("4."+tt.minor, func(t *testing.T) {
+			t.Parallel()
+			nb, cells, err := decodeNotebook([]byte(`{"cells": [], "metadata": {}, "nbformat": 4, "nbformat_minor": ` + tt.minor + `}`))
+			require.NoError(t, err)
+			cells, _, err = editNotebookCells(nb, cells, NotebookEditParams{CellIndex: 0, CellType: "markdown", EditMode: notebookEditInsert})
+			require.NoError(t, err)
+			_, ok := cells[0].(map[string]any)["id"]
+			require.Equal(t, tt.id, ok)
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package tools
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// DefaultPDFPageLimit is the number of PDF pages read when no limit is given.
+const DefaultPDFPageLimit = 20
+
+func isPDFFile(filePath string) bool {
+	return strings.EqualFold(filepath.Ext(filePath), ".pdf")
+}
+
+// readPDFPages extracts the text of the PDF pages in [offset, offset+limit),
+// with offset being 0-based. It returns the text of each page, headed by its
+// number, along with the total number of pages.
+func readPDFPages(filePath string, offset, limit int) (text string, total int, err error) {
+	// The PDF reader panics on some malformed files.
+	defer func() {
+		if r := recover(); r != nil {
+			text, total, err = "", 0, fmt.Errorf("invalid PDF: %v", r)
+		}
+	}()
+
+	f, r, err := pdf.Open(filePath)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	total = r.NumPage()
+	start := min(max(offset, 0), total)
+	end := min(start+limit, total)
+
+	var sb strings.Builder
+	for num := start + 1; num <= end; num++ {
+		page := r.Page(num)
+		if page.V.IsNull() {
+			continue
+		}
+		pageText, err := page.GetPlainText(nil)
+		if err != nil {
+			return "", 0, fmt.Errorf("page %d: %w", num, err)
+		}
+		if sb.Len() > 0 {
+			sb.WriteString("\n\n")
+		}
+		fmt.Fprintf(&sb, "--- Page %d ---\n", num)
+		sb.WriteString(strings.TrimSpace(pageText))
+	}
+	return sb.String(), total, nil
+}
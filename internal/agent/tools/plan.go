@@ -0,0 +1,114 @@
+package tools
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"strings"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/session"
+)
+
+//go:embed plan.md
+var planDescription []byte
+
+const PlanToolName = "plan"
+
+type PlanParams struct {
+	Plan  string     `json:"plan" description:"The implementation plan, in Markdown"`
+	Todos []PlanTodo `json:"todos" description:"The steps of the plan, used to seed the todo list once approved"`
+}
+
+type PlanTodo struct {
+	Content    string `json:"content" description:"What needs to be done (imperative form)"`
+	ActiveForm string `json:"active_form" description:"Present continuous form (e.g., 'Running tests')"`
+}
+
+type PlanPermissionsParams struct {
+	Plan  string   `json:"plan"`
+	Todos []string `json:"todos"`
+}
+
+type PlanResponseMetadata struct {
+	Plan  string         `json:"plan"`
+	Todos []session.Todo `json:"todos"`
+}
+
+// PlanApprovedFunc is called once the user approves the plan of a session.
+type PlanApprovedFunc func(ctx context.Context, sessionID string)
+
+func NewPlanTool(sessions session.Service, permissions permission.Service, workingDir string, onApproved PlanApprovedFunc) fantasy.AgentTool {
+	return fantasy.NewAgentTool(
+		PlanToolName,
+		string(planDescription),
+		func(ctx context.Context, params PlanParams, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			if strings.TrimSpace(params.Plan) == "" {
+				return fantasy.NewTextErrorResponse("plan is required"), nil
+			}
+
+			sessionID := GetSessionFromContext(ctx)
+			if sessionID == "" {
+				return fantasy.ToolResponse{}, fmt.Errorf("session ID is required for submitting a plan")
+			}
+
+			steps := make([]string, len(params.Todos))
+			for i, todo := range params.Todos {
+				steps[i] = todo.Content
+			}
+			// The plan is always reviewed, even in yolo mode, so it's
+			// approved rather than requested like a tool call.
+			granted, err := permissions.Approve(ctx,
+				permission.CreatePermissionRequest{
+					SessionID:   sessionID,
+					Path:        GetWorkingDirFromContext(ctx, workingDir),
+					ToolCallID:  call.ID,
+					ToolName:    PlanToolName,
+					Action:      "approve",
+					Description: "Approve the plan and start implementing it",
+					Params: PlanPermissionsParams{
+						Plan:  params.Plan,
+						Todos: steps,
+					},
+				},
+			)
+			if err != nil {
+				return fantasy.ToolResponse{}, err
+			}
+			if !granted {
+				return fantasy.ToolResponse{}, permission.ErrorPermissionDenied
+			}
+
+			todos := make([]session.Todo, len(params.Todos))
+			for i, todo := range params.Todos {
+				todos[i] = session.Todo{
+					Content:    todo.Content,
+					Status:     session.TodoStatusPending,
+					ActiveForm: todo.ActiveForm,
+				}
+			}
+			if len(todos) > 0 {
+				currentSession, err := sessions.Get(ctx, sessionID)
+				if err != nil {
+					return fantasy.ToolResponse{}, fmt.Errorf("failed to get session: %w", err)
+				}
+				currentSession.Todos = todos
+				if _, err := sessions.Save(ctx, currentSession); err != nil {
+					return fantasy.ToolResponse{}, fmt.Errorf("failed to save todos: %w", err)
+				}
+			}
+
+			if onApproved != nil {
+				onApproved(ctx, sessionID)
+			}
+
+			return fantasy.WithResponseMetadata(
+				fantasy.NewTextResponse("The user approved the plan and plan mode is now off. Stop here: the implementation starts in the next turn, with the full toolset."),
+				PlanResponseMetadata{
+					Plan:  params.Plan,
+					Todos: todos,
+				},
+			), nil
+		})
+}
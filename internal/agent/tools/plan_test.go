@@ -0,0 +1,54 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanToolAlwaysAsks(t *testing.T) {
+	t.Parallel()
+
+	// Skip requests like with --yolo, with the plan tool allowed and the
+	// session auto-approved: the plan must still be reviewed.
+	permissions := permission.NewPermissionService(t.TempDir(), true, []string{PlanToolName}, nil, nil)
+	permissions.AutoApproveSession("session")
+	requests := permissions.Subscribe(t.Context())
+
+	approved := make(chan string, 1)
+	tool := NewPlanTool(nil, permissions, t.TempDir(), func(ctx context.Context, sessionID string) {
+		approved <- sessionID
+	})
+	input, err := json.Marshal(PlanParams{Plan: "1. Add retries"})
+	require.NoError(t, err)
+	run := func() <-chan error {
+		result := make(chan error, 1)
+		go func() {
+			ctx := context.WithValue(t.Context(), SessionIDContextKey, "session")
+			_, err := tool.Run(ctx, fantasy.ToolCall{ID: "test-call", Name: PlanToolName, Input: string(input)})
+			result <- err
+		}()
+		return result
+	}
+
+	result := run()
+	event := <-requests
+	require.True(t, event.Payload.OneTime)
+	// Allowing a one-time request for the session only allows it once.
+	permissions.GrantPersistent(event.Payload)
+	require.NoError(t, <-result)
+	require.Equal(t, "session", <-approved)
+	grants, err := permissions.SessionGrants(t.Context(), "session")
+	require.NoError(t, err)
+	require.Empty(t, grants)
+
+	result = run()
+	event = <-requests
+	permissions.Deny(event.Payload)
+	require.ErrorIs(t, <-result, permission.ErrorPermissionDenied)
+	require.Empty(t, approved)
+}
@@ -38,6 +38,7 @@ func NewReferencesTool(lspManager *lsp.Manager) fantasy.AgentTool {
 		ReferencesToolName,
 		string(referencesDescription),
 		func(ctx context.Context, params ReferencesParams, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			lspManager := lspManager.ForRoot(GetWorkingDirFromContext(ctx, ""))
 			if params.Symbol == "" {
 				return fantasy.NewTextErrorResponse("symbol is required"), nil
 			}
@@ -46,7 +47,7 @@ func NewReferencesTool(lspManager *lsp.Manager) fantasy.AgentTool {
 				return fantasy.NewTextErrorResponse("no LSP clients available"), nil
 			}
 
-			workingDir := cmp.Or(params.Path, ".")
+			workingDir := cmp.Or(params.Path, GetWorkingDirFromContext(ctx, "."))
 
 			matches, _, err := searchFiles(ctx, regexp.QuoteMeta(params.Symbol), workingDir, "", 100)
 			if err != nil {
@@ -100,14 +101,7 @@ func find(ctx context.Context, lspManager *lsp.Manager, symbol string, match gre
 		return nil, fmt.Errorf("failed to get absolute path: %s", err)
 	}
 
-	var client *lsp.Client
-	for c := range lspManager.Clients().Seq() {
-		if c.HandlesFile(absPath) {
-			client = c
-			break
-		}
-	}
-
+	client := clientForFile(lspManager, absPath)
 	if client == nil {
 		slog.Warn("No LSP clients to handle", "path", match.path)
 		return nil, nil
@@ -122,6 +116,60 @@ func find(ctx context.Context, lspManager *lsp.Manager, symbol string, match gre
 	)
 }
 
+// clientForFile returns the LSP client handling the given file, or nil if
+// there's none.
+func clientForFile(lspManager *lsp.Manager, path string) *lsp.Client {
+	for c := range lspManager.Clients().Seq() {
+		if c.HandlesFile(path) {
+			return c
+		}
+	}
+	return nil
+}
+
+var errSymbolNotFound = errors.New("symbol not found")
+
+// lookupSymbol searches for the symbol in path and runs lookup at each
+// occurrence, until one gives a result. Like for references, grep matches in
+// comments and strings are skipped. It returns [errSymbolNotFound] if the
+// symbol doesn't appear in path.
+func lookupSymbol[T any](ctx context.Context, lspManager *lsp.Manager, symbol, path string, lookup func(client *lsp.Client, path string, line, character int) (T, bool, error)) (T, error) {
+	var zero T
+	matches, _, err := searchFiles(ctx, regexp.QuoteMeta(symbol), path, "", 100)
+	if err != nil {
+		return zero, fmt.Errorf("failed to search for symbol: %w", err)
+	}
+	if len(matches) == 0 {
+		return zero, errSymbolNotFound
+	}
+
+	var allErrs error
+	for _, match := range matches {
+		absPath, err := filepath.Abs(match.path)
+		if err != nil {
+			return zero, fmt.Errorf("failed to get absolute path: %w", err)
+		}
+		client := clientForFile(lspManager, absPath)
+		if client == nil {
+			slog.Warn("No LSP clients to handle", "path", match.path)
+			continue
+		}
+		result, ok, err := lookup(client, absPath, match.lineNum, match.charNum+getSymbolOffset(symbol))
+		if err != nil {
+			if strings.Contains(err.Error(), "no identifier found") {
+				continue
+			}
+			slog.Error("Failed to look up symbol", "error", err, "symbol", symbol, "path", match.path, "line", match.lineNum, "char", match.charNum)
+			allErrs = errors.Join(allErrs, err)
+			continue
+		}
+		if ok {
+			return result, nil
+		}
+	}
+	return zero, allErrs
+}
+
 // getSymbolOffset returns the character offset to the actual symbol name
 // in a qualified symbol (e.g., "Bar" in "foo.Bar" or "method" in "Class::method").
 func getSymbolOffset(symbol string) int {
@@ -0,0 +1,156 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/filetracker"
+	"github.com/charmbracelet/crush/internal/fsext"
+	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/lsp"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+)
+
+type RenameParams struct {
+	Symbol  string `json:"symbol" description:"The symbol name to rename (e.g., function name, variable name, type name)"`
+	NewName string `json:"new_name" description:"The new name of the symbol"`
+	Path    string `json:"path,omitempty" description:"The directory or file where the symbol is used. Use a directory/file to narrow down the symbol search. Defaults to the current working directory."`
+}
+
+type RenamePermissionsParams struct {
+	Symbol   string `json:"symbol"`
+	NewName  string `json:"new_name"`
+	FilePath string `json:"file_path"`
+	Diff     string `json:"diff"`
+}
+
+type RenameResponseMetadata struct {
+	Files     []string `json:"files"`
+	Additions int      `json:"additions"`
+	Removals  int      `json:"removals"`
+}
+
+const RenameToolName = "lsp_rename"
+
+//go:embed rename.md
+var renameDescription []byte
+
+func NewRenameTool(
+	lspManager *lsp.Manager,
+	permissions permission.Service,
+	files history.Service,
+	filetracker filetracker.Service,
+	workingDir string,
+) fantasy.AgentTool {
+	return fantasy.NewAgentTool(
+		RenameToolName,
+		string(renameDescription),
+		func(ctx context.Context, params RenameParams, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			workingDir := GetWorkingDirFromContext(ctx, workingDir)
+			lspManager := lspManager.ForRoot(workingDir)
+			if params.Symbol == "" {
+				return fantasy.NewTextErrorResponse("symbol is required"), nil
+			}
+			if params.NewName == "" {
+				return fantasy.NewTextErrorResponse("new_name is required"), nil
+			}
+
+			if lspManager.Clients().Len() == 0 {
+				return fantasy.NewTextErrorResponse("no LSP clients available"), nil
+			}
+
+			sessionID := GetSessionFromContext(ctx)
+			if sessionID == "" {
+				return fantasy.ToolResponse{}, fmt.Errorf("session ID is required for renaming a symbol")
+			}
+
+			type renameEdit struct {
+				client *lsp.Client
+				edit   protocol.WorkspaceEdit
+			}
+			path := cmp.Or(params.Path, workingDir)
+			rename, err := lookupSymbol(ctx, lspManager, params.Symbol, path, func(client *lsp.Client, path string, line, character int) (renameEdit, bool, error) {
+				edit, err := client.Rename(ctx, path, line, character, params.NewName)
+				return renameEdit{client, edit}, len(edit.Changes) > 0 || len(edit.DocumentChanges) > 0, err
+			})
+			if errors.Is(err, errSymbolNotFound) {
+				return fantasy.NewTextResponse(fmt.Sprintf("Symbol '%s' not found", params.Symbol)), nil
+			}
+			if err != nil {
+				return fantasy.NewTextErrorResponse(err.Error()), nil
+			}
+			if rename.client == nil {
+				return fantasy.NewTextResponse(fmt.Sprintf("No references found for symbol '%s'", params.Symbol)), nil
+			}
+
+			editor := lspEditor{lspManager, permissions, files, filetracker}
+			result, refusal, err := editor.apply(ctx, rename.client, rename.edit, sessionID, workingDir, func(path, diff string) permission.CreatePermissionRequest {
+				return permission.CreatePermissionRequest{
+					SessionID:   sessionID,
+					Path:        fsext.PathOrPrefix(path, workingDir),
+					ToolCallID:  call.ID,
+					ToolName:    RenameToolName,
+					Action:      "write",
+					Description: fmt.Sprintf("Rename %s to %s in %s", params.Symbol, params.NewName, path),
+					Params: RenamePermissionsParams{
+						Symbol:   params.Symbol,
+						NewName:  params.NewName,
+						FilePath: path,
+						Diff:     diff,
+					},
+				}
+			})
+			if err != nil {
+				return fantasy.ToolResponse{}, err
+			}
+			if refusal != "" {
+				return fantasy.NewTextErrorResponse(refusal), nil
+			}
+
+			var output strings.Builder
+			fmt.Fprintf(&output, "Renamed %s to %s in %d file(s):\n", params.Symbol, params.NewName, len(result.paths))
+			for _, path := range result.paths {
+				fmt.Fprintf(&output, "  %s\n", path)
+			}
+			for _, path := range result.paths {
+				output.WriteString(getDiagnostics(path, lspManager))
+			}
+
+			return fantasy.WithResponseMetadata(
+				fantasy.NewTextResponse(output.String()),
+				RenameResponseMetadata{
+					Files:     result.paths,
+					Additions: result.additions,
+					Removals:  result.removals,
+				},
+			), nil
+		})
+}
+
+// recordFileVersions records a file changed by a tool in the history of the
+// session, along with its previous content if it's not in the history yet or
+// was changed outside of the session.
+func recordFileVersions(ctx context.Context, files history.Service, sessionID, path, oldContent, newContent string) {
+	file, err := files.GetByPathAndSession(ctx, path, sessionID)
+	if err != nil {
+		if file, err = files.Create(ctx, sessionID, path, oldContent); err != nil {
+			slog.Error("Error creating file history", "error", err)
+			return
+		}
+	}
+	if file.Content != oldContent {
+		if _, err := files.CreateVersion(ctx, sessionID, path, oldContent); err != nil {
+			slog.Debug("Error creating file history version", "error", err)
+		}
+	}
+	if _, err := files.CreateVersion(ctx, sessionID, path, newContent); err != nil {
+		slog.Error("Error creating file history version", "error", err)
+	}
+}
@@ -0,0 +1,81 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/lsp/lsptest"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenameTool(t *testing.T) {
+	t.Parallel()
+
+	setup := func(t *testing.T, rules ...permission.Rule) (fantasy.AgentTool, string) {
+		dir, file := writeHelperSource(t)
+		edit := func(line, character uint32) map[string]any {
+			start := protocol.Position{Line: line, Character: character}
+			end := protocol.Position{Line: line, Character: character + uint32(len("helper"))}
+			return map[string]any{"range": protocol.Range{Start: start, End: end}, "newText": "assist"}
+		}
+		manager := newFakeLSPManager(t, dir, map[string]lsptest.Handler{
+			"initialize": withCapabilities(map[string]any{"renameProvider": true}),
+			"textDocument/rename": func(json.RawMessage) (any, error) {
+				return map[string]any{"documentChanges": []any{map[string]any{
+					"textDocument": map[string]any{"uri": protocol.URIFromPath(file), "version": 1},
+					"edits":        []any{edit(2, 5), edit(5, 1)},
+				}}}, nil
+			},
+		})
+		tracker := fakeFileTracker{csync.NewMap[string, time.Time]()}
+		tracker.RecordRead(t.Context(), "session", file)
+		// Requests are skipped like with --yolo, rules still apply.
+		permissions := permission.NewPermissionService(dir, true, nil, rules, nil)
+		files := &mockHistoryService{Broker: pubsub.NewBroker[history.File]()}
+		return NewRenameTool(manager, permissions, files, tracker, dir), dir
+	}
+	run := func(t *testing.T, tool fantasy.AgentTool, dir string) (fantasy.ToolResponse, error) {
+		input, err := json.Marshal(RenameParams{Symbol: "helper", NewName: "assist"})
+		require.NoError(t, err)
+		ctx := context.WithValue(t.Context(), WorkingDirContextKey, dir)
+		ctx = context.WithValue(ctx, SessionIDContextKey, "session")
+		return tool.Run(ctx, fantasy.ToolCall{ID: "test-call", Name: RenameToolName, Input: string(input)})
+	}
+
+	t.Run("rename", func(t *testing.T) {
+		t.Parallel()
+
+		tool, dir := setup(t)
+		resp, err := run(t, tool, dir)
+		require.NoError(t, err)
+		require.False(t, resp.IsError, resp.Content)
+
+		content, err := os.ReadFile(filepath.Join(dir, "main.go"))
+		require.NoError(t, err)
+		require.Equal(t, "package main\n\nfunc assist() {}\n\nfunc main() {\n\tassist()\n}\n", string(content))
+	})
+
+	t.Run("write rules apply", func(t *testing.T) {
+		t.Parallel()
+
+		deny, err := permission.ParseRule(permission.DecisionDeny, "write(*.go)")
+		require.NoError(t, err)
+		tool, dir := setup(t, deny)
+		_, err = run(t, tool, dir)
+		require.ErrorIs(t, err, permission.ErrorPermissionDenied)
+
+		content, err := os.ReadFile(filepath.Join(dir, "main.go"))
+		require.NoError(t, err)
+		require.Equal(t, helperSource, string(content))
+	})
+}
@@ -15,7 +15,7 @@ import (
 	"golang.org/x/net/html"
 )
 
-// SearchResult represents a single search result from DuckDuckGo.
+// SearchResult represents a single search result.
 type SearchResult struct {
 	Title    string
 	Link     string
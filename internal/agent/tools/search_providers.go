@@ -0,0 +1,346 @@
+package tools
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/config"
+)
+
+// SearchProvider is a web search backend.
+type SearchProvider interface {
+	// Search returns up to maxResults results for the query.
+	Search(ctx context.Context, query string, maxResults int) ([]SearchResult, error)
+}
+
+const (
+	braveSearchURL  = "https://api.search.brave.com/res/v1/web/search"
+	kagiSearchURL   = "https://kagi.com/api/v0/search"
+	tavilySearchURL = "https://api.tavily.com/search"
+)
+
+// NewSearchProvider creates the search backend selected in the
+// configuration, DuckDuckGo by default.
+func NewSearchProvider(cfg config.ToolWebSearch, client *http.Client) (SearchProvider, error) {
+	apiKey, err := cfg.ResolvedAPIKey()
+	if err != nil {
+		return nil, fmt.Errorf("web search: failed to resolve API key: %w", err)
+	}
+	headers, err := cfg.ResolvedHeaders()
+	if err != nil {
+		return nil, fmt.Errorf("web search: failed to resolve headers: %w", err)
+	}
+	api := searchAPI{client: client, headers: headers}
+
+	switch cmp.Or(cfg.Provider, config.WebSearchDuckDuckGo) {
+	case config.WebSearchDuckDuckGo:
+		return &duckDuckGoProvider{client: client}, nil
+	case config.WebSearchSearXNG:
+		if cfg.BaseURL == "" {
+			return nil, fmt.Errorf("web search: base_url is required for searxng")
+		}
+		return &searXNGProvider{api: api, baseURL: strings.TrimSuffix(cfg.BaseURL, "/")}, nil
+	case config.WebSearchBrave:
+		if apiKey == "" {
+			return nil, fmt.Errorf("web search: api_key is required for brave")
+		}
+		return &braveProvider{api: api, url: cmp.Or(cfg.BaseURL, braveSearchURL), apiKey: apiKey}, nil
+	case config.WebSearchKagi:
+		if apiKey == "" {
+			return nil, fmt.Errorf("web search: api_key is required for kagi")
+		}
+		return &kagiProvider{api: api, url: cmp.Or(cfg.BaseURL, kagiSearchURL), apiKey: apiKey}, nil
+	case config.WebSearchTavily:
+		if apiKey == "" {
+			return nil, fmt.Errorf("web search: api_key is required for tavily")
+		}
+		return &tavilyProvider{api: api, url: cmp.Or(cfg.BaseURL, tavilySearchURL), apiKey: apiKey}, nil
+	case config.WebSearchJSON:
+		if cfg.BaseURL == "" {
+			return nil, fmt.Errorf("web search: base_url is required for json")
+		}
+		return &jsonProvider{api: api, url: cfg.BaseURL, apiKey: apiKey, mapping: cfg.Mapping}, nil
+	default:
+		return nil, fmt.Errorf("web search: unknown provider %q", cfg.Provider)
+	}
+}
+
+type duckDuckGoProvider struct {
+	client *http.Client
+}
+
+func (p *duckDuckGoProvider) Search(ctx context.Context, query string, maxResults int) ([]SearchResult, error) {
+	// DuckDuckGo is scraped, so searches are spaced out to avoid being rate
+	// limited.
+	maybeDelaySearch()
+	return searchDuckDuckGo(ctx, p.client, query, maxResults)
+}
+
+type searXNGProvider struct {
+	api     searchAPI
+	baseURL string
+}
+
+func (p *searXNGProvider) Search(ctx context.Context, query string, maxResults int) ([]SearchResult, error) {
+	params := url.Values{"q": {query}, "format": {"json"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+"/search?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	var resp struct {
+		Results []struct {
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Content string `json:"content"`
+		} `json:"results"`
+	}
+	if err := p.api.do(req, &resp); err != nil {
+		return nil, err
+	}
+	var results []SearchResult
+	for _, r := range resp.Results {
+		results = appendSearchResult(results, r.Title, r.URL, r.Content)
+	}
+	return limitSearchResults(results, maxResults), nil
+}
+
+type braveProvider struct {
+	api    searchAPI
+	url    string
+	apiKey string
+}
+
+func (p *braveProvider) Search(ctx context.Context, query string, maxResults int) ([]SearchResult, error) {
+	params := url.Values{"q": {query}, "count": {strconv.Itoa(maxResults)}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("X-Subscription-Token", p.apiKey)
+	var resp struct {
+		Web struct {
+			Results []struct {
+				Title       string `json:"title"`
+				URL         string `json:"url"`
+				Description string `json:"description"`
+			} `json:"results"`
+		} `json:"web"`
+	}
+	if err := p.api.do(req, &resp); err != nil {
+		return nil, err
+	}
+	var results []SearchResult
+	for _, r := range resp.Web.Results {
+		results = appendSearchResult(results, r.Title, r.URL, r.Description)
+	}
+	return limitSearchResults(results, maxResults), nil
+}
+
+type kagiProvider struct {
+	api    searchAPI
+	url    string
+	apiKey string
+}
+
+func (p *kagiProvider) Search(ctx context.Context, query string, maxResults int) ([]SearchResult, error) {
+	params := url.Values{"q": {query}, "limit": {strconv.Itoa(maxResults)}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bot "+p.apiKey)
+	var resp struct {
+		Data []struct {
+			// T is the type of the item: 0 for search results, 1 for related
+			// searches.
+			T       int    `json:"t"`
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Snippet string `json:"snippet"`
+		} `json:"data"`
+	}
+	if err := p.api.do(req, &resp); err != nil {
+		return nil, err
+	}
+	var results []SearchResult
+	for _, r := range resp.Data {
+		if r.T == 0 {
+			results = appendSearchResult(results, r.Title, r.URL, r.Snippet)
+		}
+	}
+	return limitSearchResults(results, maxResults), nil
+}
+
+type tavilyProvider struct {
+	api    searchAPI
+	url    string
+	apiKey string
+}
+
+func (p *tavilyProvider) Search(ctx context.Context, query string, maxResults int) ([]SearchResult, error) {
+	body, err := json.Marshal(map[string]any{
+		"query":       query,
+		"max_results": maxResults,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	var resp struct {
+		Results []struct {
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Content string `json:"content"`
+		} `json:"results"`
+	}
+	if err := p.api.do(req, &resp); err != nil {
+		return nil, err
+	}
+	var results []SearchResult
+	for _, r := range resp.Results {
+		results = appendSearchResult(results, r.Title, r.URL, r.Content)
+	}
+	return limitSearchResults(results, maxResults), nil
+}
+
+// jsonProvider searches with a generic JSON endpoint, finding the results in
+// its responses with a mapping.
+type jsonProvider struct {
+	api     searchAPI
+	url     string
+	apiKey  string
+	mapping config.WebSearchMapping
+}
+
+func (p *jsonProvider) Search(ctx context.Context, query string, maxResults int) ([]SearchResult, error) {
+	searchURL := strings.NewReplacer(
+		"{query}", url.QueryEscape(query),
+		"{max_results}", strconv.Itoa(maxResults),
+	).Replace(p.url)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, searchURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+	var resp any
+	if err := p.api.do(req, &resp); err != nil {
+		return nil, err
+	}
+
+	items, ok := lookupJSONPath(resp, p.mapping.Results).([]any)
+	if !ok {
+		return nil, fmt.Errorf("no list of results at %q in the response", p.mapping.Results)
+	}
+	var results []SearchResult
+	for _, item := range items {
+		results = appendSearchResult(
+			results,
+			jsonString(lookupJSONPath(item, cmp.Or(p.mapping.Title, "title"))),
+			jsonString(lookupJSONPath(item, cmp.Or(p.mapping.URL, "url"))),
+			jsonString(lookupJSONPath(item, cmp.Or(p.mapping.Snippet, "snippet"))),
+		)
+	}
+	return limitSearchResults(results, maxResults), nil
+}
+
+// lookupJSONPath returns the value at the dot-separated path in a decoded
+// JSON value, or nil if there's none. Numbers index into lists, and an empty
+// path returns the value itself.
+func lookupJSONPath(v any, path string) any {
+	if path == "" {
+		return v
+	}
+	for key := range strings.SplitSeq(path, ".") {
+		switch node := v.(type) {
+		case map[string]any:
+			v = node[key]
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil
+			}
+			v = node[i]
+		default:
+			return nil
+		}
+	}
+	return v
+}
+
+func jsonString(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// searchAPI makes requests to the JSON API of a search backend.
+type searchAPI struct {
+	client  *http.Client
+	headers map[string]string
+}
+
+func (a searchAPI) do(req *http.Request, out any) error {
+	req.Header.Set("Accept", "application/json")
+	for k, v := range a.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute search: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		if msg := strings.TrimSpace(string(body)); msg != "" {
+			return fmt.Errorf("search failed with status code: %d: %s", resp.StatusCode, msg)
+		}
+		return fmt.Errorf("search failed with status code: %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// appendSearchResult appends a result, numbered after the ones before it.
+// Results without a URL are skipped.
+func appendSearchResult(results []SearchResult, title, link, snippet string) []SearchResult {
+	if link == "" {
+		return results
+	}
+	return append(results, SearchResult{
+		Title:    strings.TrimSpace(title),
+		Link:     link,
+		Snippet:  strings.TrimSpace(snippet),
+		Position: len(results) + 1,
+	})
+}
+
+func limitSearchResults(results []SearchResult, maxResults int) []SearchResult {
+	if maxResults > 0 && len(results) > maxResults {
+		return results[:maxResults]
+	}
+	return results
+}
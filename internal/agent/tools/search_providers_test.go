@@ -0,0 +1,201 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchProviders(t *testing.T) {
+	t.Parallel()
+
+	want := []SearchResult{
+		{Title: "Crush", Link: "https://github.com/charmbracelet/crush", Snippet: "Glamourous agentic coding", Position: 1},
+		{Title: "Charm", Link: "https://charm.land", Snippet: "We make the command line glamorous", Position: 2},
+	}
+
+	tests := []struct {
+		name string
+		cfg  config.ToolWebSearch
+		// handler checks the request and replies like the backend would.
+		handler func(t *testing.T, w http.ResponseWriter, r *http.Request)
+	}{
+		{
+			name: "searxng",
+			cfg:  config.ToolWebSearch{Provider: config.WebSearchSearXNG},
+			handler: func(t *testing.T, w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, "/search", r.URL.Path)
+				require.Equal(t, "crush", r.URL.Query().Get("q"))
+				require.Equal(t, "json", r.URL.Query().Get("format"))
+				writeJSON(t, w, map[string]any{"results": []map[string]any{
+					{"title": "Crush", "url": "https://github.com/charmbracelet/crush", "content": "Glamourous agentic coding"},
+					{"title": "Charm", "url": "https://charm.land", "content": "We make the command line glamorous"},
+					{"title": "Extra", "url": "https://example.com", "content": "Over the limit"},
+				}})
+			},
+		},
+		{
+			name: "brave",
+			cfg:  config.ToolWebSearch{Provider: config.WebSearchBrave, APIKey: "brave-key"},
+			handler: func(t *testing.T, w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, "brave-key", r.Header.Get("X-Subscription-Token"))
+				require.Equal(t, "crush", r.URL.Query().Get("q"))
+				require.Equal(t, "2", r.URL.Query().Get("count"))
+				writeJSON(t, w, map[string]any{"web": map[string]any{"results": []map[string]any{
+					{"title": "Crush", "url": "https://github.com/charmbracelet/crush", "description": "Glamourous agentic coding"},
+					{"title": "Charm", "url": "https://charm.land", "description": "We make the command line glamorous"},
+				}}})
+			},
+		},
+		{
+			name: "kagi",
+			cfg:  config.ToolWebSearch{Provider: config.WebSearchKagi, APIKey: "kagi-key"},
+			handler: func(t *testing.T, w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, "Bot kagi-key", r.Header.Get("Authorization"))
+				require.Equal(t, "2", r.URL.Query().Get("limit"))
+				writeJSON(t, w, map[string]any{"data": []map[string]any{
+					{"t": 0, "title": "Crush", "url": "https://github.com/charmbracelet/crush", "snippet": "Glamourous agentic coding"},
+					{"t": 1, "list": []string{"crush cli"}},
+					{"t": 0, "title": "Charm", "url": "https://charm.land", "snippet": "We make the command line glamorous"},
+				}})
+			},
+		},
+		{
+			name: "tavily",
+			cfg:  config.ToolWebSearch{Provider: config.WebSearchTavily, APIKey: "tavily-key"},
+			handler: func(t *testing.T, w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, http.MethodPost, r.Method)
+				require.Equal(t, "Bearer tavily-key", r.Header.Get("Authorization"))
+				body, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+				require.JSONEq(t, `{"query": "crush", "max_results": 2}`, string(body))
+				writeJSON(t, w, map[string]any{"results": []map[string]any{
+					{"title": "Crush", "url": "https://github.com/charmbracelet/crush", "content": "Glamourous agentic coding"},
+					{"title": "Charm", "url": "https://charm.land", "content": "We make the command line glamorous"},
+				}})
+			},
+		},
+		{
+			name: "json",
+			cfg: config.ToolWebSearch{
+				Provider: config.WebSearchJSON,
+				Headers:  map[string]string{"X-Team": "charm"},
+				Mapping: config.WebSearchMapping{
+					Results: "response.items",
+					Title:   "name",
+					URL:     "links.0",
+					Snippet: "summary",
+				},
+			},
+			handler: func(t *testing.T, w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, "charm", r.Header.Get("X-Team"))
+				require.Equal(t, "crush", r.URL.Query().Get("query"))
+				require.Equal(t, "2", r.URL.Query().Get("n"))
+				writeJSON(t, w, map[string]any{"response": map[string]any{"items": []map[string]any{
+					{"name": "Crush", "links": []string{"https://github.com/charmbracelet/crush"}, "summary": "Glamourous agentic coding"},
+					{"name": "No link"},
+					{"name": "Charm", "links": []string{"https://charm.land"}, "summary": "We make the command line glamorous"},
+				}}})
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tt.handler(t, w, r)
+			}))
+			t.Cleanup(srv.Close)
+
+			cfg := tt.cfg
+			cfg.BaseURL = srv.URL
+			if cfg.Provider == config.WebSearchJSON {
+				cfg.BaseURL = srv.URL + "/api?query={query}&n={max_results}"
+			}
+			provider, err := NewSearchProvider(cfg, srv.Client())
+			require.NoError(t, err)
+
+			results, err := provider.Search(t.Context(), "crush", 2)
+			require.NoError(t, err)
+			require.Equal(t, want, results)
+		})
+	}
+}
+
+func TestSearchProviderErrors(t *testing.T) {
+	t.Parallel()
+
+	t.Run("missing settings", func(t *testing.T) {
+		t.Parallel()
+		for _, cfg := range []config.ToolWebSearch{
+			{Provider: config.WebSearchSearXNG},
+			{Provider: config.WebSearchBrave},
+			{Provider: config.WebSearchKagi},
+			{Provider: config.WebSearchTavily},
+			{Provider: config.WebSearchJSON},
+			{Provider: "altavista"},
+		} {
+			_, err := NewSearchProvider(cfg, http.DefaultClient)
+			require.Error(t, err, cfg.Provider)
+		}
+	})
+
+	t.Run("duckduckgo by default", func(t *testing.T) {
+		t.Parallel()
+		provider, err := NewSearchProvider(config.ToolWebSearch{}, http.DefaultClient)
+		require.NoError(t, err)
+		require.IsType(t, &duckDuckGoProvider{}, provider)
+	})
+
+	t.Run("error status", func(t *testing.T) {
+		t.Parallel()
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "rate limited", http.StatusTooManyRequests)
+		}))
+		t.Cleanup(srv.Close)
+
+		provider, err := NewSearchProvider(config.ToolWebSearch{Provider: config.WebSearchSearXNG, BaseURL: srv.URL}, srv.Client())
+		require.NoError(t, err)
+		_, err = provider.Search(t.Context(), "crush", 5)
+		require.ErrorContains(t, err, "429: rate limited")
+	})
+}
+
+// stubSearchProvider is a [SearchProvider] returning canned results.
+type stubSearchProvider struct {
+	results []SearchResult
+}
+
+func (p stubSearchProvider) Search(_ context.Context, _ string, maxResults int) ([]SearchResult, error) {
+	return limitSearchResults(p.results, maxResults), nil
+}
+
+func TestWebSearchTool(t *testing.T) {
+	t.Parallel()
+
+	tool := NewWebSearchTool(stubSearchProvider{results: []SearchResult{
+		{Title: "Crush", Link: "https://github.com/charmbracelet/crush", Snippet: "Glamourous agentic coding", Position: 1},
+	}})
+	resp, err := tool.Run(t.Context(), fantasy.ToolCall{
+		ID:    "call_1",
+		Name:  WebSearchToolName,
+		Input: `{"query": "crush"}`,
+	})
+	require.NoError(t, err)
+	require.False(t, resp.IsError)
+	require.Contains(t, resp.Content, "1. Crush\n   URL: https://github.com/charmbracelet/crush")
+}
+
+func writeJSON(t *testing.T, w http.ResponseWriter, v any) {
+	t.Helper()
+	w.Header().Set("Content-Type", "application/json")
+	require.NoError(t, json.NewEncoder(w).Encode(v))
+}
@@ -0,0 +1,152 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/filepathext"
+	"github.com/charmbracelet/crush/internal/fsext"
+	"github.com/charmbracelet/crush/internal/lsp"
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+)
+
+type SymbolsParams struct {
+	Path  string `json:"path,omitempty" description:"The file to outline, or the directory to search symbols in. Defaults to the current working directory."`
+	Query string `json:"query,omitempty" description:"The symbol name to search for across the project, matched fuzzily. Required unless path is a file."`
+}
+
+const SymbolsToolName = "lsp_symbols"
+
+// maxSymbols is the number of workspace symbols listed.
+const maxSymbols = 100
+
+//go:embed symbols.md
+var symbolsDescription []byte
+
+func NewSymbolsTool(lspManager *lsp.Manager) fantasy.AgentTool {
+	return fantasy.NewAgentTool(
+		SymbolsToolName,
+		string(symbolsDescription),
+		func(ctx context.Context, params SymbolsParams, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			workingDir := GetWorkingDirFromContext(ctx, "")
+			lspManager := lspManager.ForRoot(workingDir)
+			if lspManager.Clients().Len() == 0 {
+				return fantasy.NewTextErrorResponse("no LSP clients available"), nil
+			}
+
+			path := filepathext.SmartJoin(workingDir, params.Path)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return outlineFile(ctx, lspManager, path), nil
+			}
+			if params.Query == "" {
+				return fantasy.NewTextErrorResponse("query is required unless path is a file"), nil
+			}
+			return searchSymbols(ctx, lspManager, params.Query, path), nil
+		})
+}
+
+func outlineFile(ctx context.Context, lspManager *lsp.Manager, path string) fantasy.ToolResponse {
+	client := clientForFile(lspManager, path)
+	if client == nil {
+		return fantasy.NewTextResponse(fmt.Sprintf("No LSP server handles %s", path))
+	}
+	symbols, err := client.DocumentSymbols(ctx, path)
+	if err != nil {
+		return fantasy.NewTextErrorResponse(err.Error())
+	}
+	if len(symbols) == 0 {
+		return fantasy.NewTextResponse(fmt.Sprintf("No symbols found in %s", path))
+	}
+
+	var output strings.Builder
+	fmt.Fprintf(&output, "%s:\n", path)
+	writeOutline(&output, symbols, 1)
+	return fantasy.NewTextResponse(output.String())
+}
+
+func writeOutline(output *strings.Builder, symbols []lsp.Symbol, depth int) {
+	for _, s := range symbols {
+		fmt.Fprintf(output, "%s%s %s", strings.Repeat("  ", depth), symbolKind(s.Kind), s.Name)
+		if s.Detail != "" {
+			fmt.Fprintf(output, " %s", s.Detail)
+		}
+		start, end := s.Location.Range.Start.Line+1, s.Location.Range.End.Line+1
+		if start == end {
+			fmt.Fprintf(output, " (line %d)\n", start)
+		} else {
+			fmt.Fprintf(output, " (lines %d-%d)\n", start, end)
+		}
+		writeOutline(output, s.Children, depth+1)
+	}
+}
+
+// searchSymbols lists the symbols matching query in dir, from every client.
+func searchSymbols(ctx context.Context, lspManager *lsp.Manager, query, dir string) fantasy.ToolResponse {
+	var symbols []lsp.Symbol
+	var allErrs error
+	for client := range lspManager.Clients().Seq() {
+		found, err := client.WorkspaceSymbols(ctx, query)
+		if err != nil {
+			slog.Error("Failed to search symbols", "error", err, "query", query)
+			allErrs = errors.Join(allErrs, err)
+			continue
+		}
+		for _, s := range found {
+			path, err := s.Location.URI.Path()
+			if err != nil || !fsext.HasPrefix(path, dir) {
+				continue
+			}
+			symbols = append(symbols, s)
+		}
+	}
+	if len(symbols) == 0 {
+		if allErrs != nil {
+			return fantasy.NewTextErrorResponse(allErrs.Error())
+		}
+		return fantasy.NewTextResponse(fmt.Sprintf("No symbols found matching '%s'", query))
+	}
+
+	// Several servers may index the same files.
+	slices.SortStableFunc(symbols, func(a, b lsp.Symbol) int {
+		return cmp.Or(
+			strings.Compare(string(a.Location.URI), string(b.Location.URI)),
+			cmp.Compare(a.Location.Range.Start.Line, b.Location.Range.Start.Line),
+			cmp.Compare(a.Location.Range.Start.Character, b.Location.Range.Start.Character),
+		)
+	})
+	symbols = slices.CompactFunc(symbols, func(a, b lsp.Symbol) bool {
+		return a.Name == b.Name && a.Location.URI == b.Location.URI && a.Location.Range.Start == b.Location.Range.Start
+	})
+
+	var output strings.Builder
+	fmt.Fprintf(&output, "Found %d symbol(s) matching '%s':\n\n", len(symbols), query)
+	for i, s := range symbols {
+		if i == maxSymbols {
+			fmt.Fprintf(&output, "\n(%d more symbols not shown, use a more specific query)\n", len(symbols)-maxSymbols)
+			break
+		}
+		path, _ := s.Location.URI.Path()
+		name := s.Name
+		if s.Container != "" {
+			name = s.Container + "." + s.Name
+		}
+		pos := s.Location.Range.Start
+		fmt.Fprintf(&output, "%s:%d:%d %s %s\n", path, pos.Line+1, pos.Character+1, symbolKind(s.Kind), name)
+	}
+	return fantasy.NewTextResponse(output.String())
+}
+
+func symbolKind(kind protocol.SymbolKind) string {
+	if name, ok := protocol.TableKindMap[kind]; ok {
+		return strings.ToLower(name)
+	}
+	return "symbol"
+}
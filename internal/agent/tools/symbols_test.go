@@ -0,0 +1,94 @@
+package tools
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/lsp/lsptest"
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSymbolsTool(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.go")
+	require.NoError(t, os.WriteFile(file, []byte("package main\n\ntype Server struct{}\n\nfunc (s *Server) Run() {}\n"), 0o644))
+	other := filepath.Join(t.TempDir(), "other.go")
+
+	symbolRange := func(start, end uint32) protocol.Range {
+		return protocol.Range{Start: protocol.Position{Line: start}, End: protocol.Position{Line: end}}
+	}
+	manager := newFakeLSPManager(t, dir, map[string]lsptest.Handler{
+		"textDocument/documentSymbol": func(json.RawMessage) (any, error) {
+			return []map[string]any{{
+				"name":  "Server",
+				"kind":  protocol.Struct,
+				"range": symbolRange(2, 2),
+				"children": []map[string]any{{
+					"name":   "Run",
+					"kind":   protocol.Method,
+					"detail": "func()",
+					"range":  symbolRange(4, 6),
+				}},
+			}}, nil
+		},
+		"workspace/symbol": func(params json.RawMessage) (any, error) {
+			var p protocol.WorkspaceSymbolParams
+			if err := json.Unmarshal(params, &p); err != nil {
+				return nil, err
+			}
+			if p.Query != "Run" {
+				return nil, nil
+			}
+			return []map[string]any{
+				{
+					"name":          "Run",
+					"kind":          protocol.Method,
+					"containerName": "Server",
+					"location":      protocol.Location{URI: protocol.URIFromPath(file), Range: symbolRange(4, 4)},
+				},
+				{
+					"name":     "Run",
+					"kind":     protocol.Function,
+					"location": protocol.Location{URI: protocol.URIFromPath(other), Range: symbolRange(0, 0)},
+				},
+			}, nil
+		},
+	})
+	tool := NewSymbolsTool(manager)
+
+	t.Run("outline", func(t *testing.T) {
+		resp := runLSPTool(t, tool, dir, SymbolsParams{Path: "main.go"})
+		require.False(t, resp.IsError, resp.Content)
+		require.Equal(t, file+":\n  struct Server (line 3)\n    method Run func() (lines 5-7)\n", resp.Content)
+	})
+
+	t.Run("search", func(t *testing.T) {
+		resp := runLSPTool(t, tool, dir, SymbolsParams{Query: "Run"})
+		require.False(t, resp.IsError, resp.Content)
+		// Symbols outside the searched directory are left out.
+		require.Equal(t, "Found 1 symbol(s) matching 'Run':\n\n"+file+":5:1 method Server.Run\n", resp.Content)
+	})
+
+	t.Run("no match", func(t *testing.T) {
+		resp := runLSPTool(t, tool, dir, SymbolsParams{Query: "Missing"})
+		require.False(t, resp.IsError, resp.Content)
+		require.Equal(t, "No symbols found matching 'Missing'", resp.Content)
+	})
+
+	t.Run("unhandled file", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("notes"), 0o644))
+		resp := runLSPTool(t, tool, dir, SymbolsParams{Path: "notes.txt"})
+		require.False(t, resp.IsError, resp.Content)
+		require.Contains(t, resp.Content, "No LSP server handles")
+	})
+
+	t.Run("query required", func(t *testing.T) {
+		resp := runLSPTool(t, tool, dir, SymbolsParams{})
+		require.True(t, resp.IsError)
+	})
+}
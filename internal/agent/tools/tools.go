@@ -8,7 +8,9 @@ type (
 	sessionIDContextKey string
 	messageIDContextKey string
 	supportsImagesKey   string
+	supportsPDFKey      string
 	modelNameKey        string
+	workingDirKey       string
 )
 
 const (
@@ -18,8 +20,14 @@ const (
 	MessageIDContextKey messageIDContextKey = "message_id"
 	// SupportsImagesContextKey is the key for the model's image support capability.
 	SupportsImagesContextKey supportsImagesKey = "supports_images"
+	// SupportsPDFContextKey is the key for whether whole PDFs can be sent to
+	// the model.
+	SupportsPDFContextKey supportsPDFKey = "supports_pdf"
 	// ModelNameContextKey is the key for the model name in the context.
 	ModelNameContextKey modelNameKey = "model_name"
+	// WorkingDirContextKey is the key for the working directory of the
+	// session, when it differs from the project's, like in git worktrees.
+	WorkingDirContextKey workingDirKey = "working_dir"
 )
 
 // getContextValue is a generic helper that retrieves a typed value from context.
@@ -50,7 +58,19 @@ func GetSupportsImagesFromContext(ctx context.Context) bool {
 	return getContextValue(ctx, SupportsImagesContextKey, false)
 }
 
+// GetSupportsPDFFromContext retrieves whether whole PDFs can be sent to the
+// model from the context.
+func GetSupportsPDFFromContext(ctx context.Context) bool {
+	return getContextValue(ctx, SupportsPDFContextKey, false)
+}
+
 // GetModelNameFromContext retrieves the model name from the context.
 func GetModelNameFromContext(ctx context.Context) string {
 	return getContextValue(ctx, ModelNameContextKey, "")
 }
+
+// GetWorkingDirFromContext retrieves the working directory of the session
+// from the context, falling back to the given directory.
+func GetWorkingDirFromContext(ctx context.Context, fallback string) string {
+	return getContextValue(ctx, WorkingDirContextKey, fallback)
+}
@@ -69,6 +69,8 @@ func NewViewTool(
 		ViewToolName,
 		string(viewDescription),
 		func(ctx context.Context, params ViewParams, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			workingDir := GetWorkingDirFromContext(ctx, workingDir)
+			lspManager := lspManager.ForRoot(workingDir)
 			if params.FilePath == "" {
 				return fantasy.NewTextErrorResponse("file_path is required"), nil
 			}
@@ -160,6 +162,45 @@ func NewViewTool(
 					fileInfo.Size(), MaxReadSize)), nil
 			}
 
+			if isPDFFile(filePath) {
+				// Models that support it get the whole PDF, unless specific
+				// pages are asked for.
+				if GetSupportsPDFFromContext(ctx) && params.Offset == 0 && params.Limit <= 0 {
+					pdfData, readErr := os.ReadFile(filePath)
+					if readErr != nil {
+						return fantasy.ToolResponse{}, fmt.Errorf("error reading PDF file: %w", readErr)
+					}
+					filetracker.RecordRead(ctx, sessionID, filePath)
+
+					encoded := base64.StdEncoding.EncodeToString(pdfData)
+					return fantasy.NewMediaResponse([]byte(encoded), "application/pdf"), nil
+				}
+
+				if params.Limit <= 0 {
+					params.Limit = DefaultPDFPageLimit
+				}
+				content, pages, readErr := readPDFPages(filePath, params.Offset, params.Limit)
+				if readErr != nil {
+					return fantasy.NewTextErrorResponse(fmt.Sprintf("Failed to read PDF: %s", readErr)), nil
+				}
+
+				output := "<file>\n" + content
+				if params.Offset+params.Limit < pages {
+					output += fmt.Sprintf("\n\n(PDF has %d pages. Use 'offset' parameter to read beyond page %d)",
+						pages, params.Offset+params.Limit)
+				}
+				output += "\n</file>\n"
+				filetracker.RecordRead(ctx, sessionID, filePath)
+
+				return fantasy.WithResponseMetadata(
+					fantasy.NewTextResponse(output),
+					ViewResponseMetadata{
+						FilePath: filePath,
+						Content:  content,
+					},
+				), nil
+			}
+
 			// Set default limit if not provided (no limit for SKILL.md files)
 			if params.Limit <= 0 {
 				if isSkillFile {
@@ -185,8 +226,24 @@ func NewViewTool(
 				return fantasy.NewImageResponse([]byte(encoded), mimeType), nil
 			}
 
-			// Read the file content
-			content, hasMore, err := readTextFile(filePath, params.Offset, params.Limit)
+			// Read the file content, rendering notebooks as cells first.
+			var (
+				content string
+				hasMore bool
+			)
+			if isNotebookFile(filePath) {
+				data, readErr := os.ReadFile(filePath)
+				if readErr != nil {
+					return fantasy.ToolResponse{}, fmt.Errorf("error reading file: %w", readErr)
+				}
+				rendered, renderErr := renderNotebook(data)
+				if renderErr != nil {
+					return fantasy.NewTextErrorResponse(renderErr.Error()), nil
+				}
+				content, hasMore, err = readLines(strings.NewReader(rendered), params.Offset, params.Limit)
+			} else {
+				content, hasMore, err = readTextFile(filePath, params.Offset, params.Limit)
+			}
 			if err != nil {
 				return fantasy.ToolResponse{}, fmt.Errorf("error reading file: %w", err)
 			}
@@ -258,13 +315,19 @@ func readTextFile(filePath string, offset, limit int) (string, bool, error) {
 	}
 	defer file.Close()
 
-	scanner := NewLineScanner(file)
+	return readLines(file, offset, limit)
+}
+
+// readLines reads up to limit lines from r, skipping the first offset lines.
+// It also reports whether there are more lines to read.
+func readLines(r io.Reader, offset, limit int) (string, bool, error) {
+	scanner := NewLineScanner(r)
 	if offset > 0 {
 		skipped := 0
 		for skipped < offset && scanner.Scan() {
 			skipped++
 		}
-		if err = scanner.Err(); err != nil {
+		if err := scanner.Err(); err != nil {
 			return "", false, err
 		}
 	}
@@ -1,6 +1,7 @@
 package tools
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -85,3 +86,66 @@ func TestReadTextFileTruncatesLongLines(t *testing.T) {
 	require.False(t, hasMore)
 	require.Equal(t, strings.Repeat("a", MaxLineLength)+"...", content)
 }
+
+// writeTestPDF writes a minimal PDF with one line of text on each page.
+func writeTestPDF(t *testing.T, filePath string, pages []string) {
+	t.Helper()
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"", // The page tree, once the page objects are known.
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	}
+	var kids []string
+	for _, text := range pages {
+		stream := fmt.Sprintf("BT /F1 12 Tf 72 720 Td (%s) Tj ET", text)
+		objects = append(objects, fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(stream), stream))
+		objects = append(objects, fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", len(objects)))
+		kids = append(kids, fmt.Sprintf("%d 0 R", len(objects)))
+	}
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages))
+
+	var sb strings.Builder
+	sb.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = sb.Len()
+		fmt.Fprintf(&sb, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := sb.Len()
+	fmt.Fprintf(&sb, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&sb, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&sb, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	require.NoError(t, os.WriteFile(filePath, []byte(sb.String()), 0o644))
+}
+
+func TestReadPDFPages(t *testing.T) {
+	t.Parallel()
+
+	filePath := filepath.Join(t.TempDir(), "sample.pdf")
+	writeTestPDF(t, filePath, []string{"first page", "second page", "third page"})
+
+	content, pages, err := readPDFPages(filePath, 0, DefaultPDFPageLimit)
+	require.NoError(t, err)
+	require.Equal(t, 3, pages)
+	require.Equal(t, "--- Page 1 ---\nfirst page\n\n--- Page 2 ---\nsecond page\n\n--- Page 3 ---\nthird page", content)
+
+	content, pages, err = readPDFPages(filePath, 1, 1)
+	require.NoError(t, err)
+	require.Equal(t, 3, pages)
+	require.Equal(t, "--- Page 2 ---\nsecond page", content)
+
+	content, _, err = readPDFPages(filePath, 5, 1)
+	require.NoError(t, err)
+	require.Empty(t, content)
+
+	// The PDF reader panics on malformed objects.
+	data, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filePath, bytes.Replace(data, []byte("/Kids ["), []byte("/Kids <"), 1), 0o644))
+	_, _, err = readPDFPages(filePath, 0, DefaultPDFPageLimit)
+	require.ErrorContains(t, err, "invalid PDF")
+}
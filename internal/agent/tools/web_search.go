@@ -4,8 +4,6 @@ import (
 	"context"
 	_ "embed"
 	"log/slog"
-	"net/http"
-	"time"
 
 	"charm.land/fantasy"
 )
@@ -13,20 +11,9 @@ import (
 //go:embed web_search.md
 var webSearchToolDescription []byte
 
-// NewWebSearchTool creates a web search tool for sub-agents (no permissions needed).
-func NewWebSearchTool(client *http.Client) fantasy.AgentTool {
-	if client == nil {
-		transport := http.DefaultTransport.(*http.Transport).Clone()
-		transport.MaxIdleConns = 100
-		transport.MaxIdleConnsPerHost = 10
-		transport.IdleConnTimeout = 90 * time.Second
-
-		client = &http.Client{
-			Timeout:   30 * time.Second,
-			Transport: transport,
-		}
-	}
-
+// NewWebSearchTool creates a web search tool for sub-agents (no permissions
+// needed), searching with the given backend.
+func NewWebSearchTool(provider SearchProvider) fantasy.AgentTool {
 	return fantasy.NewParallelAgentTool(
 		WebSearchToolName,
 		string(webSearchToolDescription),
@@ -43,8 +30,7 @@ func NewWebSearchTool(client *http.Client) fantasy.AgentTool {
 				maxResults = 20
 			}
 
-			maybeDelaySearch()
-			results, err := searchDuckDuckGo(ctx, client, params.Query, maxResults)
+			results, err := provider.Search(ctx, params.Query, maxResults)
 			slog.Debug("Web search completed", "query", params.Query, "results", len(results), "err", err)
 			if err != nil {
 				return fantasy.NewTextErrorResponse("Failed to search: " + err.Error()), nil
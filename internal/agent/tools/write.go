@@ -33,6 +33,9 @@ type WritePermissionsParams struct {
 	FilePath   string `json:"file_path"`
 	OldContent string `json:"old_content,omitempty"`
 	NewContent string `json:"new_content,omitempty"`
+	// Reformatted is whether the formatter of the file changed the new
+	// content, so the diff includes its changes.
+	Reformatted bool `json:"reformatted,omitempty"`
 }
 
 type WriteResponseMetadata struct {
@@ -54,6 +57,8 @@ func NewWriteTool(
 		WriteToolName,
 		string(writeDescription),
 		func(ctx context.Context, params WriteParams, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			workingDir := GetWorkingDirFromContext(ctx, workingDir)
+			lspManager := lspManager.ForRoot(workingDir)
 			if params.FilePath == "" {
 				return fantasy.NewTextErrorResponse("file_path is required"), nil
 			}
@@ -68,6 +73,8 @@ func NewWriteTool(
 			}
 
 			filePath := filepathext.SmartJoin(workingDir, params.FilePath)
+			var reformatted bool
+			params.Content, reformatted = formatContent(ctx, lspManager, filePath, params.Content)
 
 			fileInfo, err := os.Stat(filePath)
 			if err == nil {
@@ -118,9 +125,10 @@ func NewWriteTool(
 					Action:      "write",
 					Description: fmt.Sprintf("Create file %s", filePath),
 					Params: WritePermissionsParams{
-						FilePath:   filePath,
-						OldContent: oldContent,
-						NewContent: params.Content,
+						FilePath:    filePath,
+						OldContent:  oldContent,
+						NewContent:  params.Content,
+						Reformatted: reformatted,
 					},
 				},
 			)
@@ -139,7 +147,11 @@ func NewWriteTool(
 			// Check if file exists in history
 			file, err := files.GetByPathAndSession(ctx, filePath, sessionID)
 			if err != nil {
-				_, err = files.Create(ctx, sessionID, filePath, oldContent)
+				if fileInfo == nil {
+					_, err = files.CreateNew(ctx, sessionID, filePath)
+				} else {
+					_, err = files.Create(ctx, sessionID, filePath, oldContent)
+				}
 				if err != nil {
 					// Log error but don't fail the operation
 					return fantasy.ToolResponse{}, fmt.Errorf("error creating file history: %w", err)
@@ -162,7 +174,7 @@ func NewWriteTool(
 
 			notifyLSPs(ctx, lspManager, params.FilePath)
 
-			result := fmt.Sprintf("File successfully written: %s", filePath)
+			result := noteFormatting(fmt.Sprintf("File successfully written: %s", filePath), reformatted)
 			result = fmt.Sprintf("<result>\n%s\n</result>", result)
 			result += getDiagnostics(filePath, lspManager)
 			return fantasy.WithResponseMetadata(fantasy.NewTextResponse(result),
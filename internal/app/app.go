@@ -20,7 +20,9 @@ import (
 	"charm.land/lipgloss/v2"
 	"github.com/charmbracelet/crush/internal/agent"
 	"github.com/charmbracelet/crush/internal/agent/notify"
+	"github.com/charmbracelet/crush/internal/agent/tools"
 	"github.com/charmbracelet/crush/internal/agent/tools/mcp"
+	"github.com/charmbracelet/crush/internal/checkpoint"
 	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/db"
 	"github.com/charmbracelet/crush/internal/event"
@@ -38,6 +40,7 @@ import (
 	"github.com/charmbracelet/crush/internal/ui/styles"
 	"github.com/charmbracelet/crush/internal/update"
 	"github.com/charmbracelet/crush/internal/version"
+	"github.com/charmbracelet/crush/internal/worktree"
 	"github.com/charmbracelet/x/ansi"
 	"github.com/charmbracelet/x/exp/charmtone"
 	"github.com/charmbracelet/x/term"
@@ -86,12 +89,23 @@ func New(ctx context.Context, conn *sql.DB, store *config.ConfigStore) (*App, er
 	if cfg.Permissions != nil && cfg.Permissions.AllowedTools != nil {
 		allowedTools = cfg.Permissions.AllowedTools
 	}
+	var permissionRules []permission.Rule
+	if cfg.Permissions != nil {
+		for _, r := range cfg.Permissions.Rules {
+			rule, err := permission.ParseRule(permission.Decision(r.Decision), r.Rule)
+			if err != nil {
+				slog.Warn("Ignoring invalid permission rule", "error", err)
+				continue
+			}
+			permissionRules = append(permissionRules, rule)
+		}
+	}
 
 	app := &App{
 		Sessions:    sessions,
 		Messages:    messages,
 		History:     files,
-		Permissions: permission.NewPermissionService(store.WorkingDir(), skipPermissionsRequests, allowedTools),
+		Permissions: permission.NewPermissionService(store.WorkingDir(), skipPermissionsRequests, allowedTools, permissionRules, permission.NewGrantStore(q)),
 		FileTracker: filetracker.NewService(q),
 		LSPManager:  lsp.NewManager(store),
 
@@ -157,20 +171,48 @@ func (app *App) AgentNotifications() *pubsub.Broker[notify.Notification] {
 	return app.agentNotifications
 }
 
+// RunOptions configures a non-interactive run.
+type RunOptions struct {
+	Prompt string
+	// SessionID is the session to continue. A new session is created if
+	// empty.
+	SessionID  string
+	LargeModel string
+	SmallModel string
+	AgentID    string
+	// OutputFormat is the format of the output. Defaults to text.
+	OutputFormat OutputFormat
+	HideSpinner  bool
+	// Worktree runs the session in its own git worktree, so its changes are
+	// isolated from the working tree until merged.
+	Worktree bool
+	// Plan starts the session in plan mode. Since permission requests are
+	// approved automatically, the plan is implemented as soon as it's made.
+	Plan bool
+	// Budget limits the run, overriding the budget of the agent where set.
+	Budget config.Budget
+}
+
 // RunNonInteractive runs the application in non-interactive mode with the
-// given prompt, printing to stdout.
-func (app *App) RunNonInteractive(ctx context.Context, output io.Writer, prompt, largeModel, smallModel string, hideSpinner bool) error {
+// given prompt, printing to output.
+func (app *App) RunNonInteractive(ctx context.Context, output io.Writer, opts RunOptions) error {
 	slog.Info("Running in non-interactive mode")
 
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	if largeModel != "" || smallModel != "" {
-		if err := app.overrideModelsForNonInteractive(ctx, largeModel, smallModel); err != nil {
+	if opts.LargeModel != "" || opts.SmallModel != "" {
+		if err := app.overrideModelsForNonInteractive(ctx, opts.LargeModel, opts.SmallModel); err != nil {
 			return fmt.Errorf("failed to override models: %w", err)
 		}
 	}
 
+	if opts.AgentID != "" {
+		if err := app.AgentCoordinator.SetMainAgent(opts.AgentID); err != nil {
+			return fmt.Errorf("failed to select agent: %w", err)
+		}
+	}
+
 	var (
 		spinner   *format.Spinner
 		stdoutTTY bool
@@ -186,7 +228,7 @@ func (app *App) RunNonInteractive(ctx context.Context, output io.Writer, prompt,
 	stdinTTY = term.IsTerminal(os.Stdin.Fd())
 	progress = app.config.Config().Options.Progress == nil || *app.config.Config().Options.Progress
 
-	if !hideSpinner && stderrTTY {
+	if !opts.HideSpinner && stderrTTY {
 		t := styles.DefaultStyles()
 
 		// Detect background color to set the appropriate color for the
@@ -211,7 +253,7 @@ func (app *App) RunNonInteractive(ctx context.Context, output io.Writer, prompt,
 
 	// Helper function to stop spinner once.
 	stopSpinner := func() {
-		if !hideSpinner && spinner != nil {
+		if !opts.HideSpinner && spinner != nil {
 			spinner.Stop()
 			spinner = nil
 		}
@@ -227,16 +269,77 @@ func (app *App) RunNonInteractive(ctx context.Context, output io.Writer, prompt,
 
 	defer stopSpinner()
 
-	sess, err := app.Sessions.Create(ctx, agent.DefaultSessionName)
-	if err != nil {
-		return fmt.Errorf("failed to create session for non-interactive mode: %w", err)
+	var (
+		sess session.Session
+		err  error
+	)
+	if opts.SessionID != "" {
+		sess, err = app.Sessions.Get(ctx, opts.SessionID)
+		if err != nil {
+			return fmt.Errorf("failed to get session for non-interactive mode: %w", err)
+		}
+		slog.Info("Continuing session for non-interactive run", "session_id", sess.ID)
+	} else {
+		sess, err = app.Sessions.Create(ctx, agent.DefaultSessionName)
+		if err != nil {
+			return fmt.Errorf("failed to create session for non-interactive mode: %w", err)
+		}
+		slog.Info("Created session for non-interactive run", "session_id", sess.ID)
+	}
+
+	if opts.Worktree {
+		wt, err := worktree.Find(ctx, app.config.WorkingDir(), sess.ID)
+		if errors.Is(err, worktree.ErrNotFound) {
+			wt, err = worktree.Create(ctx, app.config.WorkingDir(), sess.ID)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to set up worktree for non-interactive mode: %w", err)
+		}
+		slog.Info("Running in worktree", "session_id", sess.ID, "path", wt.Path, "branch", wt.Branch)
+		defer func() {
+			_, _ = fmt.Fprintf(os.Stderr, "Changes were made in the worktree %s on branch %s.\nRun `crush worktree merge %s` to merge them into %s, or `crush worktree discard %s` to throw them away.\n", wt.Path, wt.Branch, sess.ID, wt.BaseBranch, sess.ID)
+		}()
 	}
-	slog.Info("Created session for non-interactive run", "session_id", sess.ID)
+
+	if opts.Plan {
+		app.AgentCoordinator.SetPlanMode(sess.ID, true)
+	}
+	app.AgentCoordinator.SetBudget(sess.ID, opts.Budget)
 
 	// Automatically approve all permission requests for this non-interactive
 	// session.
 	app.Permissions.AutoApproveSession(sess.ID)
 
+	structured := opts.OutputFormat == OutputFormatJSON || opts.OutputFormat == OutputFormatStreamJSON
+
+	// Subscribe before starting the agent so as few events as possible are
+	// missed. Missed ones are caught up on once the run is done.
+	messageEvents := app.Messages.Subscribe(ctx)
+	// Requests of ask rules and plan approvals are still prompted for.
+	permissionRequests := app.Permissions.Subscribe(ctx)
+
+	var (
+		emitter          *runEmitter
+		sessionEvents    <-chan pubsub.Event[session.Session]
+		permissionEvents <-chan pubsub.Event[permission.PermissionNotification]
+	)
+	if structured {
+		emitter = newRunEmitter(output, opts.OutputFormat, sess.ID)
+		sessionEvents = app.Sessions.Subscribe(ctx)
+		permissionEvents = app.Permissions.SubscribeNotifications(ctx)
+
+		existing, err := app.Messages.List(ctx, sess.ID)
+		if err != nil {
+			return fmt.Errorf("failed to list messages: %w", err)
+		}
+		emitter.ignore(existing)
+
+		model := app.AgentCoordinator.Model()
+		if err := emitter.init(model.CatwalkCfg.ID, model.ModelCfg.Provider); err != nil {
+			return err
+		}
+	}
+
 	type response struct {
 		result *fantasy.AgentResult
 		err    error
@@ -254,9 +357,8 @@ func (app *App) RunNonInteractive(ctx context.Context, output io.Writer, prompt,
 		done <- response{
 			result: result,
 		}
-	}(ctx, sess.ID, prompt)
+	}(ctx, sess.ID, opts.Prompt)
 
-	messageEvents := app.Messages.Subscribe(ctx)
 	messageReadBytes := make(map[string]int)
 	var printed bool
 
@@ -267,7 +369,9 @@ func (app *App) RunNonInteractive(ctx context.Context, output io.Writer, prompt,
 
 		// Always print a newline at the end. If output is a TTY this will
 		// prevent the prompt from overwriting the last line of output.
-		_, _ = fmt.Fprintln(output)
+		if !structured {
+			_, _ = fmt.Fprintln(output)
+		}
 	}()
 
 	for {
@@ -280,17 +384,59 @@ func (app *App) RunNonInteractive(ctx context.Context, output io.Writer, prompt,
 		select {
 		case result := <-done:
 			stopSpinner()
-			if result.err != nil {
-				if errors.Is(result.err, context.Canceled) || errors.Is(result.err, agent.ErrRequestCancelled) {
-					slog.Debug("Non-interactive: agent processing cancelled", "session_id", sess.ID)
-					return nil
+			err := result.err
+			// Cancelled runs are still reported as errors in the result.
+			runErr := err
+			if errors.Is(err, context.Canceled) || errors.Is(err, agent.ErrRequestCancelled) {
+				slog.Debug("Non-interactive: agent processing cancelled", "session_id", sess.ID)
+				runErr = agent.ErrRequestCancelled
+				err = nil
+			} else if err != nil {
+				err = fmt.Errorf("agent processing failed: %w", err)
+				runErr = err
+			}
+			if emitter != nil {
+				if resultErr := app.finishStructuredOutput(ctx, emitter, messageEvents, runErr); resultErr != nil {
+					return resultErr
 				}
-				return fmt.Errorf("agent processing failed: %w", result.err)
 			}
-			return nil
+			return err
+
+		case event := <-sessionEvents:
+			if event.Payload.ID == sess.ID {
+				if err := emitter.handleSession(event.Payload); err != nil {
+					return err
+				}
+			}
+
+		case event := <-permissionEvents:
+			if err := emitter.handlePermission(event.Payload); err != nil {
+				return err
+			}
+
+		case event := <-permissionRequests:
+			// Nobody can answer them, so they're denied. Plans are printed
+			// so they can still be reviewed.
+			req := event.Payload
+			if plan, ok := req.Params.(tools.PlanPermissionsParams); ok && emitter == nil {
+				stopSpinner()
+				fmt.Fprintf(output, "%s\n", plan.Plan)
+				printed = true
+			}
+			slog.Info("Non-interactive: denying permission request", "tool", req.ToolName, "action", req.Action)
+			app.Permissions.Deny(req)
 
 		case event := <-messageEvents:
 			msg := event.Payload
+			if emitter != nil {
+				if msg.SessionID == sess.ID {
+					stopSpinner()
+					if err := emitter.handleMessage(msg); err != nil {
+						return err
+					}
+				}
+				break
+			}
 			if msg.SessionID == sess.ID && msg.Role == message.Assistant && len(msg.Parts) > 0 {
 				stopSpinner()
 
@@ -318,11 +464,57 @@ func (app *App) RunNonInteractive(ctx context.Context, output io.Writer, prompt,
 
 		case <-ctx.Done():
 			stopSpinner()
+			if emitter != nil {
+				if resultErr := app.finishStructuredOutput(ctx, emitter, messageEvents, agent.ErrRequestCancelled); resultErr != nil {
+					return resultErr
+				}
+			}
 			return ctx.Err()
 		}
 	}
 }
 
+// finishStructuredOutput handles the message events still buffered once the
+// agent is done, catches up on the ones that were dropped, and writes the
+// result of the run.
+func (app *App) finishStructuredOutput(ctx context.Context, emitter *runEmitter, messageEvents <-chan pubsub.Event[message.Message], runErr error) error {
+	// The run may have been cancelled, but the result is still written.
+	ctx = context.WithoutCancel(ctx)
+
+	for drained := false; !drained; {
+		select {
+		case event := <-messageEvents:
+			if event.Payload.SessionID == emitter.sessionID {
+				if err := emitter.handleMessage(event.Payload); err != nil {
+					return err
+				}
+			}
+		default:
+			drained = true
+		}
+	}
+
+	// Events may have been dropped when subscribers lagged behind, so go
+	// through the stored messages. Only what wasn't emitted yet is.
+	msgs, err := app.Messages.List(ctx, emitter.sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to list messages: %w", err)
+	}
+	for _, msg := range msgs {
+		if err := emitter.handleMessage(msg); err != nil {
+			return err
+		}
+	}
+
+	// Usage events may have been dropped, so read the final totals.
+	if sess, err := app.Sessions.Get(ctx, emitter.sessionID); err == nil {
+		if err := emitter.handleSession(sess); err != nil {
+			return err
+		}
+	}
+	return emitter.result(runErr)
+}
+
 func (app *App) UpdateAgentModel(ctx context.Context) error {
 	if app.AgentCoordinator == nil {
 		return fmt.Errorf("agent configuration is missing")
@@ -431,6 +623,7 @@ func (app *App) setupEvents() {
 	setupSubscriber(ctx, app.serviceEventsWG, "agent-notifications", app.agentNotifications.Subscribe, app.events)
 	setupSubscriber(ctx, app.serviceEventsWG, "mcp", mcp.SubscribeEvents, app.events)
 	setupSubscriber(ctx, app.serviceEventsWG, "lsp", SubscribeLSPEvents, app.events)
+	app.serviceEventsWG.Go(func() { app.deleteCheckpoints(ctx) })
 	cleanupFunc := func(context.Context) error {
 		cancel()
 		app.serviceEventsWG.Wait()
@@ -439,6 +632,20 @@ func (app *App) setupEvents() {
 	app.cleanupFuncs = append(app.cleanupFuncs, cleanupFunc)
 }
 
+// deleteCheckpoints deletes the checkpoints of the sessions deleted until ctx
+// is done, since they're kept outside the database.
+func (app *App) deleteCheckpoints(ctx context.Context) {
+	checkpoints := checkpoint.NewService(app.config.Config().Options.DataDirectory)
+	for event := range app.Sessions.Subscribe(ctx) {
+		if event.Type != pubsub.DeletedEvent {
+			continue
+		}
+		if err := checkpoints.Delete(ctx, app.config.WorkingDir(), event.Payload.ID); err != nil {
+			slog.Warn("Failed to delete checkpoints", "session_id", event.Payload.ID, "error", err)
+		}
+	}
+}
+
 const subscriberSendTimeout = 2 * time.Second
 
 func setupSubscriber[T any](
@@ -507,6 +714,7 @@ func (app *App) InitCoderAgent(ctx context.Context) error {
 		slog.Error("Failed to create coder agent", "err", err)
 		return err
 	}
+	mcp.SetSampler(app.AgentCoordinator.Sample)
 	return nil
 }
 
@@ -527,6 +735,9 @@ func (app *App) Subscribe(program *tea.Program) {
 	})
 	defer app.tuiWG.Done()
 
+	// MCP servers only ask for input when someone can answer.
+	setupSubscriber(tuiCtx, app.tuiWG, "mcp-elicitations", mcp.SubscribeElicitations, app.events)
+
 	for {
 		select {
 		case <-tuiCtx.Done():
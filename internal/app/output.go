@@ -0,0 +1,312 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/session"
+)
+
+// OutputFormat is the format non-interactive runs write their output in.
+type OutputFormat string
+
+const (
+	// OutputFormatText writes the assistant's text as it's generated.
+	OutputFormatText OutputFormat = "text"
+	// OutputFormatJSON writes a single [RunResult] once the run is done.
+	OutputFormatJSON OutputFormat = "json"
+	// OutputFormatStreamJSON writes newline-delimited [RunEvent]s as they
+	// happen, followed by a [RunResult].
+	OutputFormatStreamJSON OutputFormat = "stream-json"
+)
+
+// ParseOutputFormat parses an output format name. An empty name is the text
+// format.
+func ParseOutputFormat(name string) (OutputFormat, error) {
+	switch format := OutputFormat(strings.ToLower(name)); format {
+	case "", OutputFormatText:
+		return OutputFormatText, nil
+	case OutputFormatJSON, OutputFormatStreamJSON:
+		return format, nil
+	default:
+		return "", fmt.Errorf("invalid output format %q: must be one of text, json, stream-json", name)
+	}
+}
+
+// RunEventType is the type of a [RunEvent].
+type RunEventType string
+
+const (
+	RunEventInit       RunEventType = "init"
+	RunEventText       RunEventType = "text"
+	RunEventReasoning  RunEventType = "reasoning"
+	RunEventToolCall   RunEventType = "tool_call"
+	RunEventToolResult RunEventType = "tool_result"
+	RunEventPermission RunEventType = "permission"
+	RunEventFinish     RunEventType = "finish"
+	RunEventUsage      RunEventType = "usage"
+	RunEventResult     RunEventType = "result"
+)
+
+// RunEvent is an event of a non-interactive run, as written by the
+// stream-json output format. Only the fields relevant to the event type are
+// set.
+type RunEvent struct {
+	Type      RunEventType `json:"type"`
+	SessionID string       `json:"session_id"`
+	MessageID string       `json:"message_id,omitempty"`
+
+	// Model and Provider are set on init events.
+	Model    string `json:"model,omitempty"`
+	Provider string `json:"provider,omitempty"`
+
+	// Text is the delta of text and reasoning events.
+	Text string `json:"text,omitempty"`
+
+	ToolCall     *RunToolCall         `json:"tool_call,omitempty"`
+	ToolResult   *RunToolResult       `json:"tool_result,omitempty"`
+	Permission   *RunPermission       `json:"permission,omitempty"`
+	FinishReason message.FinishReason `json:"finish_reason,omitempty"`
+	Usage        *RunUsage            `json:"usage,omitempty"`
+}
+
+// RunToolCall is a tool call made by the agent.
+type RunToolCall struct {
+	ID    string          `json:"id"`
+	Name  string          `json:"name"`
+	Input json.RawMessage `json:"input,omitempty"`
+}
+
+// RunToolResult is the result of a tool call.
+type RunToolResult struct {
+	ToolCallID string `json:"tool_call_id"`
+	Name       string `json:"name"`
+	Content    string `json:"content"`
+	IsError    bool   `json:"is_error"`
+}
+
+// RunPermission is the decision made on a permission request of a tool call.
+type RunPermission struct {
+	ToolCallID string `json:"tool_call_id"`
+	ToolName   string `json:"tool_name,omitempty"`
+	Granted    bool   `json:"granted"`
+}
+
+// RunUsage is the accumulated token usage and cost of the session.
+type RunUsage struct {
+	InputTokens  int64   `json:"input_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	Cost         float64 `json:"cost"`
+}
+
+// RunResult is the summary written once a non-interactive run is done.
+type RunResult struct {
+	Type         RunEventType         `json:"type"`
+	SessionID    string               `json:"session_id"`
+	Result       string               `json:"result"`
+	FinishReason message.FinishReason `json:"finish_reason,omitempty"`
+	IsError      bool                 `json:"is_error"`
+	Error        string               `json:"error,omitempty"`
+	NumSteps     int                  `json:"num_steps"`
+	DurationMS   int64                `json:"duration_ms"`
+	Usage        RunUsage             `json:"usage"`
+}
+
+// runEmitter turns the events of a non-interactive run into the JSON output
+// formats.
+type runEmitter struct {
+	enc       *json.Encoder
+	stream    bool
+	sessionID string
+	startedAt time.Time
+
+	textBytes      map[string]int
+	reasoningBytes map[string]int
+	toolNames      map[string]string // tool call ID -> tool name
+	toolCalls      map[string]bool
+	toolResults    map[string]bool
+	finished       map[string]bool
+	ignored        map[string]bool // messages from before the run
+	usage          RunUsage
+
+	// Details of the latest assistant message, for the result.
+	lastText     string
+	finishReason message.FinishReason
+}
+
+func newRunEmitter(w io.Writer, format OutputFormat, sessionID string) *runEmitter {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return &runEmitter{
+		enc:            enc,
+		stream:         format == OutputFormatStreamJSON,
+		sessionID:      sessionID,
+		startedAt:      time.Now(),
+		textBytes:      make(map[string]int),
+		reasoningBytes: make(map[string]int),
+		toolNames:      make(map[string]string),
+		toolCalls:      make(map[string]bool),
+		toolResults:    make(map[string]bool),
+		finished:       make(map[string]bool),
+		ignored:        make(map[string]bool),
+	}
+}
+
+func (e *runEmitter) emit(event RunEvent) error {
+	if !e.stream {
+		return nil
+	}
+	event.SessionID = e.sessionID
+	return e.enc.Encode(event)
+}
+
+func (e *runEmitter) init(model, provider string) error {
+	return e.emit(RunEvent{
+		Type:     RunEventInit,
+		Model:    model,
+		Provider: provider,
+	})
+}
+
+// ignore makes the emitter skip the given messages, which were in the session
+// before the run.
+func (e *runEmitter) ignore(msgs []message.Message) {
+	for _, msg := range msgs {
+		e.ignored[msg.ID] = true
+	}
+}
+
+// handleMessage emits the events of a message that weren't emitted yet, so
+// it can be called with every update of a message, in any number.
+func (e *runEmitter) handleMessage(msg message.Message) error {
+	if e.ignored[msg.ID] {
+		return nil
+	}
+	switch msg.Role {
+	case message.Assistant:
+		return e.handleAssistantMessage(msg)
+	case message.Tool:
+		for _, result := range msg.ToolResults() {
+			if e.toolResults[result.ToolCallID] {
+				continue
+			}
+			e.toolResults[result.ToolCallID] = true
+			if err := e.emit(RunEvent{
+				Type:      RunEventToolResult,
+				MessageID: msg.ID,
+				ToolResult: &RunToolResult{
+					ToolCallID: result.ToolCallID,
+					Name:       result.Name,
+					Content:    result.Content,
+					IsError:    result.IsError,
+				},
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (e *runEmitter) handleAssistantMessage(msg message.Message) error {
+	if thinking := msg.ReasoningContent().Thinking; len(thinking) > e.reasoningBytes[msg.ID] {
+		delta := thinking[e.reasoningBytes[msg.ID]:]
+		e.reasoningBytes[msg.ID] = len(thinking)
+		if err := e.emit(RunEvent{Type: RunEventReasoning, MessageID: msg.ID, Text: delta}); err != nil {
+			return err
+		}
+	}
+
+	text := msg.Content().Text
+	if text != "" {
+		e.lastText = text
+	}
+	if len(text) > e.textBytes[msg.ID] {
+		delta := text[e.textBytes[msg.ID]:]
+		e.textBytes[msg.ID] = len(text)
+		if err := e.emit(RunEvent{Type: RunEventText, MessageID: msg.ID, Text: delta}); err != nil {
+			return err
+		}
+	}
+
+	for _, call := range msg.ToolCalls() {
+		// Remember the call as soon as it starts streaming, so its permission
+		// request can be told apart from the ones of other sessions.
+		e.toolNames[call.ID] = call.Name
+		if !call.Finished || e.toolCalls[call.ID] {
+			continue
+		}
+		e.toolCalls[call.ID] = true
+		toolCall := &RunToolCall{ID: call.ID, Name: call.Name}
+		if json.Valid([]byte(call.Input)) {
+			toolCall.Input = json.RawMessage(call.Input)
+		}
+		if err := e.emit(RunEvent{Type: RunEventToolCall, MessageID: msg.ID, ToolCall: toolCall}); err != nil {
+			return err
+		}
+	}
+
+	finish := msg.FinishPart()
+	if finish != nil {
+		e.finishReason = finish.Reason
+	}
+	if finish != nil && !e.finished[msg.ID] {
+		e.finished[msg.ID] = true
+		if err := e.emit(RunEvent{Type: RunEventFinish, MessageID: msg.ID, FinishReason: finish.Reason}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *runEmitter) handlePermission(notification permission.PermissionNotification) error {
+	toolName, ok := e.toolNames[notification.ToolCallID]
+	if !ok || (!notification.Granted && !notification.Denied) {
+		// Either another session's tool call, or a pending request.
+		return nil
+	}
+	return e.emit(RunEvent{
+		Type: RunEventPermission,
+		Permission: &RunPermission{
+			ToolCallID: notification.ToolCallID,
+			ToolName:   toolName,
+			Granted:    notification.Granted,
+		},
+	})
+}
+
+func (e *runEmitter) handleSession(sess session.Session) error {
+	usage := RunUsage{
+		InputTokens:  sess.PromptTokens,
+		OutputTokens: sess.CompletionTokens,
+		Cost:         sess.Cost,
+	}
+	if usage == e.usage {
+		return nil
+	}
+	e.usage = usage
+	return e.emit(RunEvent{Type: RunEventUsage, Usage: &usage})
+}
+
+// result writes the final summary of the run.
+func (e *runEmitter) result(runErr error) error {
+	result := RunResult{
+		Type:         RunEventResult,
+		SessionID:    e.sessionID,
+		Result:       e.lastText,
+		FinishReason: e.finishReason,
+		NumSteps:     len(e.finished),
+		DurationMS:   time.Since(e.startedAt).Milliseconds(),
+		Usage:        e.usage,
+	}
+	if runErr != nil {
+		result.IsError = true
+		result.Error = runErr.Error()
+	}
+	return e.enc.Encode(result)
+}
@@ -0,0 +1,189 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOutputFormat(t *testing.T) {
+	for name, want := range map[string]OutputFormat{
+		"":            OutputFormatText,
+		"text":        OutputFormatText,
+		"JSON":        OutputFormatJSON,
+		"stream-json": OutputFormatStreamJSON,
+	} {
+		got, err := ParseOutputFormat(name)
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+	}
+
+	_, err := ParseOutputFormat("yaml")
+	require.Error(t, err)
+}
+
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var lines []map[string]any
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var line map[string]any
+		require.NoError(t, dec.Decode(&line))
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func emitRun(t *testing.T, e *runEmitter) {
+	t.Helper()
+	assistant := message.Message{ID: "m1", SessionID: "s1", Role: message.Assistant}
+	assistant.AppendContent("Let me ")
+	require.NoError(t, e.handleMessage(assistant))
+	assistant.AppendContent("check.")
+	assistant.AddToolCall(message.ToolCall{ID: "call-1", Name: "bash", Input: `{"command":"ls"}`})
+	require.NoError(t, e.handleMessage(assistant))
+
+	// Permission requests of unknown tool calls belong to other sessions.
+	require.NoError(t, e.handlePermission(permission.PermissionNotification{ToolCallID: "other", Granted: true}))
+
+	assistant.FinishToolCall("call-1")
+	assistant.AddFinish(message.FinishReasonToolUse, "", "")
+	require.NoError(t, e.handleMessage(assistant))
+	require.NoError(t, e.handlePermission(permission.PermissionNotification{ToolCallID: "call-1"}))
+	require.NoError(t, e.handlePermission(permission.PermissionNotification{ToolCallID: "call-1", Granted: true}))
+
+	tool := message.Message{ID: "m2", SessionID: "s1", Role: message.Tool}
+	tool.AddToolResult(message.ToolResult{ToolCallID: "call-1", Name: "bash", Content: "main.go"})
+	require.NoError(t, e.handleMessage(tool))
+
+	require.NoError(t, e.handleSession(session.Session{ID: "s1", PromptTokens: 100, CompletionTokens: 20, Cost: 0.5}))
+	// Unchanged usage isn't emitted again.
+	require.NoError(t, e.handleSession(session.Session{ID: "s1", PromptTokens: 100, CompletionTokens: 20, Cost: 0.5}))
+
+	final := message.Message{ID: "m3", SessionID: "s1", Role: message.Assistant}
+	final.AppendContent("Done.")
+	final.AddFinish(message.FinishReasonEndTurn, "", "")
+	require.NoError(t, e.handleMessage(final))
+}
+
+func TestRunEmitterStreamJSON(t *testing.T) {
+	var buf bytes.Buffer
+	e := newRunEmitter(&buf, OutputFormatStreamJSON, "s1")
+	require.NoError(t, e.init("gpt-5", "openai"))
+	emitRun(t, e)
+	require.NoError(t, e.result(nil))
+
+	lines := decodeLines(t, &buf)
+	var types []string
+	for _, line := range lines {
+		require.Equal(t, "s1", line["session_id"])
+		types = append(types, line["type"].(string))
+	}
+	require.Equal(t, []string{
+		"init",
+		"text",
+		"text",
+		"tool_call",
+		"finish",
+		"permission",
+		"tool_result",
+		"usage",
+		"text",
+		"finish",
+		"result",
+	}, types)
+
+	require.Equal(t, "check.", lines[2]["text"])
+	require.Equal(t, map[string]any{
+		"id":    "call-1",
+		"name":  "bash",
+		"input": map[string]any{"command": "ls"},
+	}, lines[3]["tool_call"])
+	require.Equal(t, map[string]any{
+		"tool_call_id": "call-1",
+		"tool_name":    "bash",
+		"granted":      true,
+	}, lines[5]["permission"])
+
+	result := lines[len(lines)-1]
+	require.Equal(t, "Done.", result["result"])
+	require.Equal(t, "end_turn", result["finish_reason"])
+	require.Equal(t, false, result["is_error"])
+	require.Equal(t, float64(2), result["num_steps"])
+	require.Equal(t, map[string]any{
+		"input_tokens":  float64(100),
+		"output_tokens": float64(20),
+		"cost":          0.5,
+	}, result["usage"])
+}
+
+func TestRunEmitterJSON(t *testing.T) {
+	var buf bytes.Buffer
+	e := newRunEmitter(&buf, OutputFormatJSON, "s1")
+	require.NoError(t, e.init("gpt-5", "openai"))
+	emitRun(t, e)
+	require.NoError(t, e.result(errors.New("boom")))
+
+	lines := decodeLines(t, &buf)
+	require.Len(t, lines, 1)
+	require.Equal(t, "result", lines[0]["type"])
+	require.Equal(t, "Done.", lines[0]["result"])
+	require.Equal(t, true, lines[0]["is_error"])
+	require.Equal(t, "boom", lines[0]["error"])
+}
+
+func TestRunEmitterCatchUp(t *testing.T) {
+	var buf bytes.Buffer
+	e := newRunEmitter(&buf, OutputFormatStreamJSON, "s1")
+
+	old := message.Message{ID: "m0", SessionID: "s1", Role: message.Assistant}
+	old.AppendContent("Earlier answer.")
+	old.AddFinish(message.FinishReasonEndTurn, "", "")
+	e.ignore([]message.Message{old})
+
+	assistant := message.Message{ID: "m1", SessionID: "s1", Role: message.Assistant}
+	assistant.AppendContent("Let me check.")
+	require.NoError(t, e.handleMessage(assistant))
+
+	// The updates adding the tool call and the finish were dropped.
+	assistant.AddToolCall(message.ToolCall{ID: "call-1", Name: "bash", Input: `{"command":"ls"}`, Finished: true})
+	assistant.AddFinish(message.FinishReasonToolUse, "", "")
+	tool := message.Message{ID: "m2", SessionID: "s1", Role: message.Tool}
+	tool.AddToolResult(message.ToolResult{ToolCallID: "call-1", Name: "bash", Content: "main.go"})
+	require.NoError(t, e.handleMessage(tool))
+	final := message.Message{ID: "m3", SessionID: "s1", Role: message.Assistant}
+	final.AppendContent("Done.")
+	final.AddFinish(message.FinishReasonEndTurn, "", "")
+
+	for _, msg := range []message.Message{old, assistant, tool, final} {
+		require.NoError(t, e.handleMessage(msg))
+	}
+	require.NoError(t, e.result(errors.New("request canceled by user")))
+
+	var types []string
+	lines := decodeLines(t, &buf)
+	for _, line := range lines {
+		types = append(types, line["type"].(string))
+	}
+	require.Equal(t, []string{
+		"text",
+		"tool_result",
+		"tool_call",
+		"finish",
+		"text",
+		"finish",
+		"result",
+	}, types)
+
+	result := lines[len(lines)-1]
+	require.Equal(t, "Done.", result["result"])
+	require.Equal(t, "end_turn", result["finish_reason"])
+	require.Equal(t, float64(2), result["num_steps"])
+	require.Equal(t, true, result["is_error"])
+}
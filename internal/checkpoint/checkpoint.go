@@ -0,0 +1,246 @@
+// Package checkpoint snapshots the working tree at the start of each agent
+// turn, so every change made during a turn can be reviewed and undone,
+// whichever tool made it.
+//
+// In git repositories, snapshots are commits on a shadow ref per session,
+// made without touching the index, the branches or the stash. Elsewhere,
+// files are copied into a content-addressed store in the data directory.
+package checkpoint
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned when a session has no checkpoint with the
+	// given ID.
+	ErrNotFound = errors.New("checkpoint not found")
+	// ErrTooManyFiles is returned when a directory outside git has too many
+	// files to be copied.
+	ErrTooManyFiles = errors.New("too many files to checkpoint")
+)
+
+const (
+	// maxFiles is the maximum number of files copied outside git.
+	maxFiles = 10_000
+	// maxFileSize is the size above which files are left out of copies
+	// outside git.
+	maxFileSize = 5 * 1024 * 1024
+	// maxCheckpoints is the number of checkpoints kept per session.
+	maxCheckpoints = 100
+	// pruneBatch is how many checkpoints past maxCheckpoints a session
+	// takes before the oldest ones are pruned, since pruning rewrites the
+	// shadow ref of git stores.
+	pruneBatch = 20
+)
+
+// Checkpoint is a snapshot of the working tree taken at the start of a turn.
+type Checkpoint struct {
+	ID        string `json:"id"`
+	SessionID string `json:"session_id"`
+	// MessageID is the user message that started the turn. It's empty for
+	// checkpoints taken before a restore.
+	MessageID string `json:"message_id,omitempty"`
+	// Title is the first line of the prompt of the turn.
+	Title     string `json:"title"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+// ChangeKind is how a file changed since a checkpoint.
+type ChangeKind string
+
+const (
+	Added    ChangeKind = "added"
+	Modified ChangeKind = "modified"
+	Deleted  ChangeKind = "deleted"
+)
+
+// Change is a file that changed since a checkpoint.
+type Change struct {
+	// Path is relative to the working tree, with forward slashes.
+	Path string     `json:"path"`
+	Kind ChangeKind `json:"kind"`
+	// Before and After are the contents of the file at the checkpoint and
+	// now. They're empty for binary files.
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+	Binary bool   `json:"binary,omitempty"`
+}
+
+// store keeps the checkpoints of a working tree.
+type store interface {
+	// save snapshots the working tree, returning the ID of the checkpoint.
+	save(ctx context.Context, cp Checkpoint) (string, error)
+	list(ctx context.Context, sessionID string) ([]Checkpoint, error)
+	// changes lists the files that changed since the checkpoint.
+	changes(ctx context.Context, cp Checkpoint) ([]Change, error)
+	// restore brings the changed files back to their checkpointed state.
+	restore(ctx context.Context, cp Checkpoint, changes []Change) error
+	// prune deletes all but the keep newest checkpoints of a session.
+	prune(ctx context.Context, sessionID string, keep int) error
+}
+
+// Service takes, lists and restores checkpoints.
+type Service struct {
+	dataDir string
+}
+
+// NewService creates a new checkpoint service. Checkpoints of directories
+// outside git are kept in dataDir.
+func NewService(dataDir string) *Service {
+	return &Service{dataDir: dataDir}
+}
+
+// Create snapshots dir at the start of the turn started by the given user
+// message.
+func (s *Service) Create(ctx context.Context, dir, sessionID, messageID, prompt string) (Checkpoint, error) {
+	st, err := s.store(ctx, dir)
+	if err != nil {
+		return Checkpoint{}, err
+	}
+	title, _, _ := strings.Cut(strings.TrimSpace(prompt), "\n")
+	cp := Checkpoint{
+		SessionID: sessionID,
+		MessageID: messageID,
+		Title:     cmp.Or(title, "Checkpoint"),
+		CreatedAt: time.Now().Unix(),
+	}
+	cp.ID, err = st.save(ctx, cp)
+	if err != nil {
+		return Checkpoint{}, fmt.Errorf("failed to create checkpoint: %w", err)
+	}
+	checkpoints, err := st.list(ctx, sessionID)
+	if err == nil && len(checkpoints) > maxCheckpoints+pruneBatch {
+		err = st.prune(ctx, sessionID, maxCheckpoints)
+	}
+	if err != nil {
+		return cp, fmt.Errorf("failed to prune checkpoints: %w", err)
+	}
+	return cp, nil
+}
+
+// List returns the checkpoints of a session, newest first.
+func (s *Service) List(ctx context.Context, dir, sessionID string) ([]Checkpoint, error) {
+	st, err := s.store(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	checkpoints, err := st.list(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list checkpoints: %w", err)
+	}
+	slices.SortStableFunc(checkpoints, func(a, b Checkpoint) int {
+		return cmp.Compare(b.CreatedAt, a.CreatedAt)
+	})
+	return checkpoints, nil
+}
+
+// Changes lists the files that changed since the given checkpoint.
+func (s *Service) Changes(ctx context.Context, dir, sessionID, id string) ([]Change, error) {
+	st, cp, err := s.find(ctx, dir, sessionID, id)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := st.changes(ctx, cp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff checkpoint: %w", err)
+	}
+	return changes, nil
+}
+
+// Restore brings dir back to the state it had at the given checkpoint, and
+// returns the files it changed. The current state is checkpointed first, so
+// the restore can itself be undone.
+func (s *Service) Restore(ctx context.Context, dir, sessionID, id string) ([]Change, error) {
+	st, cp, err := s.find(ctx, dir, sessionID, id)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := st.changes(ctx, cp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff checkpoint: %w", err)
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	if _, err := s.Create(ctx, dir, sessionID, "", "Before restoring "+cp.Title); err != nil {
+		return nil, err
+	}
+	if err := st.restore(ctx, cp, changes); err != nil {
+		return nil, fmt.Errorf("failed to restore checkpoint: %w", err)
+	}
+	return changes, nil
+}
+
+// Delete deletes the checkpoints of a session in dir.
+func (s *Service) Delete(ctx context.Context, dir, sessionID string) error {
+	st, err := s.store(ctx, dir)
+	if err != nil {
+		return err
+	}
+	if err := st.prune(ctx, sessionID, 0); err != nil {
+		return fmt.Errorf("failed to delete checkpoints: %w", err)
+	}
+	return nil
+}
+
+func (s *Service) find(ctx context.Context, dir, sessionID, id string) (store, Checkpoint, error) {
+	st, err := s.store(ctx, dir)
+	if err != nil {
+		return nil, Checkpoint{}, err
+	}
+	checkpoints, err := st.list(ctx, sessionID)
+	if err != nil {
+		return nil, Checkpoint{}, fmt.Errorf("failed to list checkpoints: %w", err)
+	}
+	idx := slices.IndexFunc(checkpoints, func(cp Checkpoint) bool {
+		return cp.ID == id
+	})
+	if idx == -1 {
+		return nil, Checkpoint{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return st, checkpoints[idx], nil
+}
+
+// store returns the git store of the repository dir is in, or the file store
+// of dir outside git.
+func (s *Service) store(ctx context.Context, dir string) (store, error) {
+	if root, err := git(ctx, dir, nil, "", "rev-parse", "--show-toplevel"); err == nil {
+		return &gitStore{root: root}, nil
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	return newFileStore(s.dataDir, dir), nil
+}
+
+// isBinary reports whether content looks binary, the way git guesses it.
+func isBinary(content []byte) bool {
+	return slices.Contains(content[:min(len(content), 8000)], 0)
+}
+
+func sortChanges(changes []Change) {
+	slices.SortFunc(changes, func(a, b Change) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+}
+
+func writeFile(path string, content []byte, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create parent directories for %s: %w", path, err)
+	}
+	if err := os.WriteFile(path, content, mode); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", path, err)
+	}
+	// WriteFile only applies the mode to new files.
+	return os.Chmod(path, mode)
+}
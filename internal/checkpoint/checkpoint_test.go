@@ -0,0 +1,231 @@
+package checkpoint
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func setupRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	t.Setenv("GIT_AUTHOR_NAME", "Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	runGit(t, dir, "init", "--initial-branch", "main")
+	writeTestFile(t, dir, ".gitignore", "*.log\n")
+	writeTestFile(t, dir, "README.md", "hello\n")
+	writeTestFile(t, dir, "main.go", "package main\n")
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "--message", "initial")
+	return dir
+}
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := git(t.Context(), dir, nil, "", args...)
+	require.NoError(t, err)
+	return out
+}
+
+func writeTestFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+func readTestFile(t *testing.T, dir, name string) string {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join(dir, name))
+	require.NoError(t, err)
+	return string(content)
+}
+
+// changeFiles changes files the way a turn of the agent could: editing,
+// creating and deleting them.
+func changeFiles(t *testing.T, dir string) {
+	t.Helper()
+	writeTestFile(t, dir, "README.md", "hello world\n")
+	writeTestFile(t, dir, "gen/types.go", "package gen\n")
+	require.NoError(t, os.Remove(filepath.Join(dir, "main.go")))
+}
+
+func requireRestored(t *testing.T, dir string, changes []Change) {
+	t.Helper()
+	require.Equal(t, []Change{
+		{Path: "README.md", Kind: Modified, Before: "hello\n", After: "hello world\n"},
+		{Path: "gen/types.go", Kind: Added, After: "package gen\n"},
+		{Path: "main.go", Kind: Deleted, Before: "package main\n"},
+	}, changes)
+	require.Equal(t, "hello\n", readTestFile(t, dir, "README.md"))
+	require.Equal(t, "package main\n", readTestFile(t, dir, "main.go"))
+	require.NoFileExists(t, filepath.Join(dir, "gen", "types.go"))
+}
+
+func TestGitCheckpoints(t *testing.T) {
+	repo := setupRepo(t)
+	svc := NewService(t.TempDir())
+	ctx := t.Context()
+
+	// Untracked and staged changes are part of the checkpoint, but ignored
+	// files aren't.
+	writeTestFile(t, repo, "notes.txt", "todo\n")
+	writeTestFile(t, repo, "debug.log", "noise\n")
+	writeTestFile(t, repo, "README.md", "hello\n")
+	runGit(t, repo, "add", "notes.txt")
+	status := runGit(t, repo, "status", "--porcelain")
+
+	cp, err := svc.Create(ctx, repo, "session-1", "message-1", "Generate the types\nand more")
+	require.NoError(t, err)
+	require.Equal(t, "Generate the types", cp.Title)
+	require.Equal(t, status, runGit(t, repo, "status", "--porcelain"), "the index must be left alone")
+	require.Empty(t, runGit(t, repo, "stash", "list"))
+	require.Equal(t, "main", runGit(t, repo, "branch", "--show-current"))
+
+	checkpoints, err := svc.List(ctx, repo, "session-1")
+	require.NoError(t, err)
+	require.Len(t, checkpoints, 1)
+	require.Equal(t, cp.ID, checkpoints[0].ID)
+	require.Equal(t, "message-1", checkpoints[0].MessageID)
+	require.NotZero(t, checkpoints[0].CreatedAt)
+
+	other, err := svc.List(ctx, repo, "session-2")
+	require.NoError(t, err)
+	require.Empty(t, other)
+
+	changes, err := svc.Changes(ctx, repo, "session-1", cp.ID)
+	require.NoError(t, err)
+	require.Empty(t, changes)
+
+	changeFiles(t, repo)
+	writeTestFile(t, repo, "debug.log", "more noise\n")
+
+	changes, err = svc.Restore(ctx, repo, "session-1", cp.ID)
+	require.NoError(t, err)
+	requireRestored(t, repo, changes)
+	require.Equal(t, "todo\n", readTestFile(t, repo, "notes.txt"))
+	require.Equal(t, "more noise\n", readTestFile(t, repo, "debug.log"))
+
+	// The state before the restore was checkpointed, so it can be undone.
+	checkpoints, err = svc.List(ctx, repo, "session-1")
+	require.NoError(t, err)
+	require.Len(t, checkpoints, 2)
+	require.Equal(t, "Before restoring Generate the types", checkpoints[0].Title)
+	require.Empty(t, checkpoints[0].MessageID)
+
+	_, err = svc.Restore(ctx, repo, "session-1", checkpoints[0].ID)
+	require.NoError(t, err)
+	require.Equal(t, "hello world\n", readTestFile(t, repo, "README.md"))
+	require.NoFileExists(t, filepath.Join(repo, "main.go"))
+
+	_, err = svc.Changes(ctx, repo, "session-1", "nope")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestFileCheckpoints(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	if _, err := git(t.Context(), dir, nil, "", "rev-parse", "--show-toplevel"); err == nil {
+		t.Skip("temporary directory is in a git repository")
+	}
+	writeTestFile(t, dir, "README.md", "hello\n")
+	writeTestFile(t, dir, "main.go", "package main\n")
+	require.NoError(t, os.Chmod(filepath.Join(dir, "main.go"), 0o755))
+
+	dataDir := t.TempDir()
+	svc := NewService(dataDir)
+	ctx := t.Context()
+
+	cp, err := svc.Create(ctx, dir, "session-1", "message-1", "Generate the types")
+	require.NoError(t, err)
+
+	checkpoints, err := svc.List(ctx, dir, "session-1")
+	require.NoError(t, err)
+	require.Equal(t, []Checkpoint{cp}, checkpoints)
+
+	changeFiles(t, dir)
+	changes, err := svc.Changes(ctx, dir, "session-1", cp.ID)
+	require.NoError(t, err)
+	require.Len(t, changes, 3)
+
+	changes, err = svc.Restore(ctx, dir, "session-1", cp.ID)
+	require.NoError(t, err)
+	requireRestored(t, dir, changes)
+	info, err := os.Stat(filepath.Join(dir, "main.go"))
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o755), info.Mode().Perm())
+
+	checkpoints, err = svc.List(ctx, dir, "session-1")
+	require.NoError(t, err)
+	require.Len(t, checkpoints, 2)
+	require.Equal(t, cp.ID, checkpoints[1].ID)
+}
+
+func TestPruneCheckpoints(t *testing.T) {
+	t.Run("git", func(t *testing.T) {
+		testPruneCheckpoints(t, setupRepo(t))
+	})
+	t.Run("files", func(t *testing.T) {
+		dir, err := filepath.EvalSymlinks(t.TempDir())
+		require.NoError(t, err)
+		if _, err := git(t.Context(), dir, nil, "", "rev-parse", "--show-toplevel"); err == nil {
+			t.Skip("temporary directory is in a git repository")
+		}
+		testPruneCheckpoints(t, dir)
+	})
+}
+
+func testPruneCheckpoints(t *testing.T, dir string) {
+	svc := NewService(t.TempDir())
+	ctx := t.Context()
+
+	for _, content := range []string{"one\n", "two\n", "three\n", "four\n"} {
+		writeTestFile(t, dir, "notes.txt", content)
+		_, err := svc.Create(ctx, dir, "session-1", "", content)
+		require.NoError(t, err)
+	}
+	_, err := svc.Create(ctx, dir, "session-2", "", "other")
+	require.NoError(t, err)
+
+	st, err := svc.store(ctx, dir)
+	require.NoError(t, err)
+	require.NoError(t, st.prune(ctx, "session-1", 2))
+
+	checkpoints, err := svc.List(ctx, dir, "session-1")
+	require.NoError(t, err)
+	require.Len(t, checkpoints, 2)
+	require.Equal(t, "four", checkpoints[0].Title)
+	require.Equal(t, "three", checkpoints[1].Title)
+
+	writeTestFile(t, dir, "notes.txt", "five\n")
+	changes, err := svc.Changes(ctx, dir, "session-1", checkpoints[1].ID)
+	require.NoError(t, err)
+	require.Equal(t, []Change{{Path: "notes.txt", Kind: Modified, Before: "three\n", After: "five\n"}}, changes)
+
+	require.NoError(t, svc.Delete(ctx, dir, "session-1"))
+	checkpoints, err = svc.List(ctx, dir, "session-1")
+	require.NoError(t, err)
+	require.Empty(t, checkpoints)
+
+	other, err := svc.List(ctx, dir, "session-2")
+	require.NoError(t, err)
+	require.Len(t, other, 1)
+}
+
+func TestIsBinary(t *testing.T) {
+	t.Parallel()
+	require.False(t, isBinary(nil))
+	require.False(t, isBinary([]byte("package main\n")))
+	require.True(t, isBinary([]byte("\x89PNG\x00\x00")))
+}
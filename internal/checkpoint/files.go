@@ -0,0 +1,311 @@
+package checkpoint
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/fsext"
+)
+
+// fileStore keeps checkpoints of a directory outside git. File contents are
+// stored once in a content-addressed object directory, and each checkpoint
+// is a manifest mapping paths to contents.
+type fileStore struct {
+	root string
+	dir  string
+}
+
+type fileEntry struct {
+	Hash string      `json:"hash"`
+	Mode os.FileMode `json:"mode"`
+}
+
+type manifest struct {
+	Checkpoint
+	Files map[string]fileEntry `json:"files"`
+}
+
+func newFileStore(dataDir, root string) *fileStore {
+	sum := sha256.Sum256([]byte(root))
+	name := filepath.Base(root) + "-" + hex.EncodeToString(sum[:])[:8]
+	return &fileStore{
+		root: root,
+		dir:  filepath.Join(dataDir, "checkpoints", name),
+	}
+}
+
+func (f *fileStore) sessionDir(sessionID string) string {
+	return filepath.Join(f.dir, "sessions", sessionID)
+}
+
+func (f *fileStore) objectPath(hash string) string {
+	return filepath.Join(f.dir, "objects", hash[:2], hash[2:])
+}
+
+func (f *fileStore) save(_ context.Context, cp Checkpoint) (string, error) {
+	files, err := f.scan(true)
+	if err != nil {
+		return "", err
+	}
+	// IDs sort in creation order.
+	cp.ID = fmt.Sprintf("%016x", time.Now().UnixNano())
+
+	data, err := json.Marshal(manifest{Checkpoint: cp, Files: files})
+	if err != nil {
+		return "", err
+	}
+	dir := f.sessionDir(cp.SessionID)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(dir, cp.ID+".json"), data, 0o600); err != nil {
+		return "", err
+	}
+	return cp.ID, nil
+}
+
+// scan hashes the files of the directory, leaving out ignored and big ones.
+// When store is set, their contents are copied to the object directory.
+func (f *fileStore) scan(store bool) (map[string]fileEntry, error) {
+	paths, truncated, err := fsext.ListDirectory(f.root, nil, 0, maxFiles+1)
+	if err != nil {
+		return nil, err
+	}
+	if truncated || len(paths) > maxFiles {
+		return nil, fmt.Errorf("%w: %s has more than %d files", ErrTooManyFiles, f.root, maxFiles)
+	}
+
+	files := make(map[string]fileEntry, len(paths))
+	for _, path := range paths {
+		if strings.HasSuffix(path, string(filepath.Separator)) || strings.HasSuffix(path, "/") {
+			continue
+		}
+		path = filepath.FromSlash(path)
+		if fsext.HasPrefix(path, f.dir) {
+			continue
+		}
+		info, err := os.Lstat(path)
+		if err != nil || !info.Mode().IsRegular() || info.Size() > maxFileSize {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		sum := sha256.Sum256(content)
+		entry := fileEntry{Hash: hex.EncodeToString(sum[:]), Mode: info.Mode().Perm()}
+		if store {
+			if err := f.writeObject(entry.Hash, content); err != nil {
+				return nil, err
+			}
+		}
+		rel, err := filepath.Rel(f.root, path)
+		if err != nil {
+			continue
+		}
+		files[filepath.ToSlash(rel)] = entry
+	}
+	return files, nil
+}
+
+func (f *fileStore) writeObject(hash string, content []byte) error {
+	path := f.objectPath(hash)
+	if _, err := os.Stat(path); err == nil {
+		// Objects used recently are kept by prune, even when no manifest
+		// refers to them yet.
+		now := time.Now()
+		return os.Chtimes(path, now, now)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	// Write to a temporary file first, so a partial object is never taken
+	// for a complete one.
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, content, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (f *fileStore) readManifest(sessionID, id string) (manifest, error) {
+	var m manifest
+	data, err := os.ReadFile(filepath.Join(f.sessionDir(sessionID), id+".json"))
+	if err != nil {
+		return m, err
+	}
+	err = json.Unmarshal(data, &m)
+	return m, err
+}
+
+func (f *fileStore) list(_ context.Context, sessionID string) ([]Checkpoint, error) {
+	entries, err := os.ReadDir(f.sessionDir(sessionID))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var checkpoints []Checkpoint
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok {
+			continue
+		}
+		m, err := f.readManifest(sessionID, id)
+		if err != nil {
+			continue
+		}
+		checkpoints = append(checkpoints, m.Checkpoint)
+	}
+	// Newest first.
+	slices.SortFunc(checkpoints, func(a, b Checkpoint) int {
+		return strings.Compare(b.ID, a.ID)
+	})
+	return checkpoints, nil
+}
+
+func (f *fileStore) changes(_ context.Context, cp Checkpoint) ([]Change, error) {
+	m, err := f.readManifest(cp.SessionID, cp.ID)
+	if err != nil {
+		return nil, err
+	}
+	current, err := f.scan(false)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	for path, entry := range m.Files {
+		cur, ok := current[path]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Path: path, Kind: Deleted})
+		case cur.Hash != entry.Hash:
+			changes = append(changes, Change{Path: path, Kind: Modified})
+		}
+	}
+	for path := range current {
+		if _, ok := m.Files[path]; !ok {
+			changes = append(changes, Change{Path: path, Kind: Added})
+		}
+	}
+
+	for i, change := range changes {
+		var before, after []byte
+		if change.Kind != Added {
+			if before, err = os.ReadFile(f.objectPath(m.Files[change.Path].Hash)); err != nil {
+				return nil, fmt.Errorf("checkpoint content of %s is missing: %w", change.Path, err)
+			}
+		}
+		if change.Kind != Deleted {
+			if after, err = os.ReadFile(filepath.Join(f.root, filepath.FromSlash(change.Path))); err != nil {
+				return nil, err
+			}
+		}
+		if isBinary(before) || isBinary(after) {
+			changes[i].Binary = true
+		} else {
+			changes[i].Before, changes[i].After = string(before), string(after)
+		}
+	}
+	sortChanges(changes)
+	return changes, nil
+}
+
+func (f *fileStore) restore(_ context.Context, cp Checkpoint, changes []Change) error {
+	m, err := f.readManifest(cp.SessionID, cp.ID)
+	if err != nil {
+		return err
+	}
+	for _, change := range changes {
+		path := filepath.Join(f.root, filepath.FromSlash(change.Path))
+		if change.Kind == Added {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		}
+		entry := m.Files[change.Path]
+		content, err := os.ReadFile(f.objectPath(entry.Hash))
+		if err != nil {
+			return fmt.Errorf("checkpoint content of %s is missing: %w", change.Path, err)
+		}
+		if err := writeFile(path, content, entry.Mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fileStore) prune(ctx context.Context, sessionID string, keep int) error {
+	checkpoints, err := f.list(ctx, sessionID)
+	if err != nil || len(checkpoints) <= keep {
+		return err
+	}
+	if keep == 0 {
+		err = os.RemoveAll(f.sessionDir(sessionID))
+	} else {
+		for _, cp := range checkpoints[keep:] {
+			if err = os.Remove(filepath.Join(f.sessionDir(sessionID), cp.ID+".json")); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return f.removeUnusedObjects()
+}
+
+// removeUnusedObjects deletes the objects no manifest refers to. Objects
+// used in the last hour are kept, since a checkpoint being saved may not
+// have written its manifest yet.
+func (f *fileStore) removeUnusedObjects() error {
+	used := make(map[string]bool)
+	manifests, err := filepath.Glob(filepath.Join(f.dir, "sessions", "*", "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, path := range manifests {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var m manifest
+		if err := json.Unmarshal(data, &m); err != nil {
+			continue
+		}
+		for _, entry := range m.Files {
+			used[entry.Hash] = true
+		}
+	}
+
+	objects, err := filepath.Glob(filepath.Join(f.dir, "objects", "*", "*"))
+	if err != nil {
+		return err
+	}
+	cutoff := time.Now().Add(-time.Hour)
+	for _, path := range objects {
+		hash := filepath.Base(filepath.Dir(path)) + filepath.Base(path)
+		if used[hash] {
+			continue
+		}
+		if info, err := os.Stat(path); err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+package checkpoint
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// refPrefix is the prefix of the shadow refs holding the checkpoints of each
+// session. They're outside refs/heads, so they don't show up as branches.
+const refPrefix = "refs/crush/checkpoints/"
+
+// identityEnv makes checkpoint commits work without a configured identity,
+// and keeps them apart from the user's own commits.
+var identityEnv = []string{
+	"GIT_AUTHOR_NAME=Crush",
+	"GIT_AUTHOR_EMAIL=crush@charm.land",
+	"GIT_COMMITTER_NAME=Crush",
+	"GIT_COMMITTER_EMAIL=crush@charm.land",
+}
+
+const (
+	sessionTrailer = "Crush-Session: "
+	messageTrailer = "Crush-Message: "
+)
+
+// gitStore keeps checkpoints as commits on a shadow ref per session.
+type gitStore struct {
+	root string
+}
+
+func (g *gitStore) ref(sessionID string) string {
+	return refPrefix + sessionID
+}
+
+func (g *gitStore) save(ctx context.Context, cp Checkpoint) (string, error) {
+	tree, err := g.snapshot(ctx)
+	if err != nil {
+		return "", err
+	}
+	parent, err := git(ctx, g.root, nil, "", "rev-parse", "--verify", "--quiet", g.ref(cp.SessionID)+"^{commit}")
+	if err != nil {
+		parent = ""
+	}
+	commit, err := g.commit(ctx, tree, parent, cp)
+	if err != nil {
+		return "", err
+	}
+	if _, err := git(ctx, g.root, nil, "", "update-ref", g.ref(cp.SessionID), commit); err != nil {
+		return "", err
+	}
+	return commit, nil
+}
+
+// commit commits the tree of a checkpoint on top of parent, if any.
+func (g *gitStore) commit(ctx context.Context, tree, parent string, cp Checkpoint) (string, error) {
+	msg := cp.Title + "\n\n" + sessionTrailer + cp.SessionID
+	if cp.MessageID != "" {
+		msg += "\n" + messageTrailer + cp.MessageID
+	}
+	args := []string{"commit-tree", tree, "-m", msg}
+	if parent != "" {
+		args = append(args, "-p", parent)
+	}
+	date := fmt.Sprintf("@%d +0000", cp.CreatedAt)
+	env := append(slices.Clone(identityEnv), "GIT_AUTHOR_DATE="+date, "GIT_COMMITTER_DATE="+date)
+	return git(ctx, g.root, env, "", args...)
+}
+
+// snapshot writes the tree of the working tree, with tracked and untracked
+// files but not ignored ones. It works on a copy of the index, so the index
+// of the user is left alone.
+func (g *gitStore) snapshot(ctx context.Context) (string, error) {
+	indexPath, err := git(ctx, g.root, nil, "", "rev-parse", "--path-format=absolute", "--git-path", "index")
+	if err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp("", "crush-checkpoint-index-*")
+	if err != nil {
+		return "", err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	// Starting from the index of the user lets git skip hashing the files
+	// that didn't change. Without one, git needs a missing file rather than
+	// an empty one.
+	if data, err := os.ReadFile(indexPath); err == nil {
+		if err := os.WriteFile(tmp.Name(), data, 0o600); err != nil {
+			return "", err
+		}
+	} else {
+		os.Remove(tmp.Name())
+	}
+
+	env := []string{"GIT_INDEX_FILE=" + tmp.Name()}
+	if _, err := git(ctx, g.root, env, "", "add", "--all", "--", "."); err != nil {
+		return "", err
+	}
+	return git(ctx, g.root, env, "", "write-tree")
+}
+
+func (g *gitStore) list(ctx context.Context, sessionID string) ([]Checkpoint, error) {
+	ref := g.ref(sessionID)
+	if _, err := git(ctx, g.root, nil, "", "rev-parse", "--verify", "--quiet", ref); err != nil {
+		// No checkpoint yet.
+		return nil, nil
+	}
+	// Commits are separated by record separators, and their fields by NULs.
+	out, err := git(ctx, g.root, nil, "", "log", "--format=%H%x00%ct%x00%B%x1e", ref)
+	if err != nil {
+		return nil, err
+	}
+
+	var checkpoints []Checkpoint
+	for record := range strings.SplitSeq(out, "\x1e") {
+		fields := strings.SplitN(strings.TrimSpace(record), "\x00", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		createdAt, _ := strconv.ParseInt(fields[1], 10, 64)
+		cp := Checkpoint{
+			ID:        fields[0],
+			SessionID: sessionID,
+			CreatedAt: createdAt,
+		}
+		title, body, _ := strings.Cut(fields[2], "\n")
+		cp.Title = title
+		for line := range strings.SplitSeq(body, "\n") {
+			if id, ok := strings.CutPrefix(line, messageTrailer); ok {
+				cp.MessageID = id
+			}
+		}
+		checkpoints = append(checkpoints, cp)
+	}
+	return checkpoints, nil
+}
+
+func (g *gitStore) changes(ctx context.Context, cp Checkpoint) ([]Change, error) {
+	current, err := g.snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out, err := gitOutput(ctx, g.root, nil, "", "diff-tree", "-r", "-z", "--no-renames", "--ignore-submodules", "--name-status", cp.ID, current)
+	if err != nil {
+		return nil, err
+	}
+
+	// The output alternates statuses and paths, each terminated by a NUL.
+	fields := strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+	var changes []Change
+	for i := 0; i+1 < len(fields); i += 2 {
+		change := Change{Path: fields[i+1]}
+		switch fields[i] {
+		case "A":
+			change.Kind = Added
+		case "D":
+			change.Kind = Deleted
+		default:
+			change.Kind = Modified
+		}
+		var before, after []byte
+		if change.Kind != Added {
+			if before, err = gitOutput(ctx, g.root, nil, "", "cat-file", "blob", cp.ID+":"+change.Path); err != nil {
+				return nil, err
+			}
+		}
+		if change.Kind != Deleted {
+			if after, err = gitOutput(ctx, g.root, nil, "", "cat-file", "blob", current+":"+change.Path); err != nil {
+				return nil, err
+			}
+		}
+		if isBinary(before) || isBinary(after) {
+			change.Binary = true
+		} else {
+			change.Before, change.After = string(before), string(after)
+		}
+		changes = append(changes, change)
+	}
+	sortChanges(changes)
+	return changes, nil
+}
+
+func (g *gitStore) restore(ctx context.Context, cp Checkpoint, changes []Change) error {
+	var paths []string
+	for _, change := range changes {
+		if change.Kind == Added {
+			if err := os.Remove(filepath.Join(g.root, filepath.FromSlash(change.Path))); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		}
+		paths = append(paths, change.Path)
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+	// Only the working tree is restored, leaving the index as it is. Paths
+	// are passed on stdin, and literally, so none is taken for a pattern.
+	_, err := git(
+		ctx, g.root, []string{"GIT_LITERAL_PATHSPECS=1"}, strings.Join(paths, "\x00"),
+		"restore", "--source="+cp.ID, "--worktree", "--pathspec-from-file=-", "--pathspec-file-nul",
+	)
+	return err
+}
+
+func (g *gitStore) prune(ctx context.Context, sessionID string, keep int) error {
+	checkpoints, err := g.list(ctx, sessionID)
+	if err != nil || len(checkpoints) <= keep {
+		return err
+	}
+	ref := g.ref(sessionID)
+	if keep == 0 {
+		_, err := git(ctx, g.root, nil, "", "update-ref", "-d", ref)
+		return err
+	}
+	// The oldest kept checkpoint loses its parent, so the kept ones are
+	// committed again, oldest first. The others are left to git's garbage
+	// collection.
+	var parent string
+	for _, cp := range slices.Backward(checkpoints[:keep]) {
+		if parent, err = g.commit(ctx, cp.ID+"^{tree}", parent, cp); err != nil {
+			return err
+		}
+	}
+	_, err = git(ctx, g.root, nil, "", "update-ref", ref, parent, checkpoints[0].ID)
+	return err
+}
+
+func git(ctx context.Context, dir string, env []string, stdin string, args ...string) (string, error) {
+	out, err := gitOutput(ctx, dir, env, stdin, args...)
+	return strings.TrimSpace(string(out)), err
+}
+
+func gitOutput(ctx context.Context, dir string, env []string, stdin string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return nil, fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return out, nil
+}
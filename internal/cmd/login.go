@@ -10,6 +10,7 @@ import (
 	"charm.land/lipgloss/v2"
 	"github.com/atotto/clipboard"
 	hyperp "github.com/charmbracelet/crush/internal/agent/hyper"
+	"github.com/charmbracelet/crush/internal/agent/tools/mcp"
 	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/oauth"
 	"github.com/charmbracelet/crush/internal/oauth/copilot"
@@ -24,21 +25,25 @@ var loginCmd = &cobra.Command{
 	Short:   "Login Crush to a platform",
 	Long: `Login Crush to a specified platform.
 The platform should be provided as an argument.
-Available platforms are: hyper, copilot.`,
+Available platforms are: hyper, copilot, mcp.`,
 	Example: `
 # Authenticate with Charm Hyper
 crush login
 
 # Authenticate with GitHub Copilot
 crush login copilot
+
+# Authorize a remote MCP server configured as "linear"
+crush login mcp linear
   `,
 	ValidArgs: []cobra.Completion{
 		"hyper",
 		"copilot",
 		"github",
 		"github-copilot",
+		"mcp",
 	},
-	Args: cobra.MaximumNArgs(1),
+	Args: cobra.MaximumNArgs(2),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		app, err := setupAppWithProgressBar(cmd)
 		if err != nil {
@@ -55,6 +60,11 @@ crush login copilot
 			return loginHyper(app.Store())
 		case "copilot", "github", "github-copilot":
 			return loginCopilot(app.Store())
+		case "mcp":
+			if len(args) < 2 {
+				return fmt.Errorf("missing MCP server name")
+			}
+			return loginMCP(app.Store(), args[1])
 		default:
 			return fmt.Errorf("unknown platform: %s", args[0])
 		}
@@ -188,6 +198,47 @@ func loginCopilot(cfg *config.ConfigStore) error {
 	return nil
 }
 
+func loginMCP(cfg *config.ConfigStore, name string) error {
+	ctx := getLoginContext()
+
+	fmt.Printf("Discovering the authorization server of %s...\n", name)
+	auth, err := mcp.StartAuthorization(ctx, cfg, name)
+	if err != nil {
+		return err
+	}
+	defer auth.Close()
+
+	fmt.Println()
+	if auth.UserCode != "" {
+		if clipboard.WriteAll(auth.UserCode) == nil {
+			fmt.Println("The following code should be on clipboard already:")
+		} else {
+			fmt.Println("Copy the following code:")
+		}
+		fmt.Println()
+		fmt.Println(lipgloss.NewStyle().Bold(true).Render(auth.UserCode))
+		fmt.Println()
+		fmt.Println("Open this URL, and then paste it there:")
+	} else {
+		fmt.Println("Open this URL to authorize Crush:")
+	}
+	fmt.Println()
+	fmt.Println(lipgloss.NewStyle().Hyperlink(auth.URL, "id=mcp").Render(auth.URL))
+	fmt.Println()
+	if err := browser.OpenURL(auth.URL); err != nil {
+		fmt.Println("Could not open the URL. You'll need to manually open the URL in your browser.")
+	}
+
+	fmt.Println("Waiting for authorization...")
+	if err := mcp.CompleteAuthorization(ctx, cfg, name, auth); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	fmt.Printf("You're now authorized with %s!\n", name)
+	return nil
+}
+
 func getLoginContext() context.Context {
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
 	go func() {
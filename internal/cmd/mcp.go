@@ -0,0 +1,421 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"charm.land/lipgloss/v2"
+	"charm.land/lipgloss/v2/table"
+	"github.com/charmbracelet/crush/internal/agent/tools/mcp"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/x/exp/charmtone"
+	"github.com/charmbracelet/x/term"
+	"github.com/spf13/cobra"
+)
+
+var mcpCmd = &cobra.Command{
+	Use:     "mcp",
+	Aliases: []string{"mcps"},
+	Short:   "Manage MCP servers",
+	Long: `Manage the MCP servers Crush connects to.
+Changes are saved to the global data config, or to the workspace config with --workspace.
+Servers defined in other config files are listed, but can only be removed from those files.`,
+}
+
+var (
+	mcpAddType    string
+	mcpAddEnv     []string
+	mcpAddHeaders []string
+	mcpAddTimeout int
+	mcpWorkspace  bool
+	mcpListJSON   bool
+	mcpTestJSON   bool
+)
+
+var mcpAddCmd = &cobra.Command{
+	Use:   "add <name> <command|url> [args...]",
+	Short: "Add an MCP server",
+	Long: `Add an MCP server, started with a command or reached at a URL.
+The type is http for URLs and stdio for commands, unless set with --type.`,
+	Example: `
+# Add a server started with a command
+crush mcp add filesystem -- npx -y @modelcontextprotocol/server-filesystem .
+
+# Add a remote server with an API key taken from the environment
+crush mcp add github https://api.githubcopilot.com/mcp/ --header 'Authorization=Bearer $GH_PAT'
+
+# Add a server to this project only
+crush mcp add --workspace db -e DATABASE_URL=postgres://localhost/app -- mcp-postgres
+  `,
+	Args: cobra.MinimumNArgs(2),
+	RunE: runMCPAdd,
+}
+
+var mcpRemoveCmd = &cobra.Command{
+	Use:     "remove <name>",
+	Aliases: []string{"rm"},
+	Short:   "Remove an MCP server",
+	Long:    "Remove an MCP server from the config files Crush manages, along with its saved authorization.",
+	Args:    cobra.ExactArgs(1),
+	RunE:    runMCPRemove,
+}
+
+var mcpListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List MCP servers",
+	Long:    "List the MCP servers configured for the current project. Use --json for machine-readable output.",
+	RunE:    runMCPList,
+}
+
+var mcpEnableCmd = &cobra.Command{
+	Use:   "enable <name>",
+	Short: "Enable an MCP server",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setMCPDisabled(cmd, args[0], false)
+	},
+}
+
+var mcpDisableCmd = &cobra.Command{
+	Use:   "disable <name>",
+	Short: "Disable an MCP server",
+	Long:  "Disable an MCP server, so Crush doesn't connect to it, without removing its configuration.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setMCPDisabled(cmd, args[0], true)
+	},
+}
+
+var mcpTestCmd = &cobra.Command{
+	Use:   "test <name>",
+	Short: "Test the connection to an MCP server",
+	Long:  "Connect to an MCP server and list its tools, prompts and resources. Disabled servers are tested too. Use --json for machine-readable output.",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runMCPTest,
+}
+
+func init() {
+	mcpAddCmd.Flags().StringVarP(&mcpAddType, "type", "t", "", "Type of the server: stdio, http or sse")
+	mcpAddCmd.Flags().StringArrayVarP(&mcpAddEnv, "env", "e", nil, "Environment variable of stdio servers, as KEY=VALUE")
+	mcpAddCmd.Flags().StringArrayVarP(&mcpAddHeaders, "header", "H", nil, "HTTP header of remote servers, as KEY=VALUE")
+	mcpAddCmd.Flags().IntVar(&mcpAddTimeout, "timeout", 0, "Timeout in seconds of the connection")
+	mcpAddCmd.Flags().BoolVarP(&mcpWorkspace, "workspace", "w", false, "Save to the workspace config")
+	mcpRemoveCmd.Flags().BoolVarP(&mcpWorkspace, "workspace", "w", false, "Only remove from the workspace config")
+	mcpEnableCmd.Flags().BoolVarP(&mcpWorkspace, "workspace", "w", false, "Save to the workspace config instead of the one defining the server")
+	mcpDisableCmd.Flags().BoolVarP(&mcpWorkspace, "workspace", "w", false, "Save to the workspace config instead of the one defining the server")
+	mcpListCmd.Flags().BoolVar(&mcpListJSON, "json", false, "output in JSON format")
+	mcpTestCmd.Flags().BoolVar(&mcpTestJSON, "json", false, "output in JSON format")
+	mcpCmd.AddCommand(mcpAddCmd)
+	mcpCmd.AddCommand(mcpRemoveCmd)
+	mcpCmd.AddCommand(mcpListCmd)
+	mcpCmd.AddCommand(mcpEnableCmd)
+	mcpCmd.AddCommand(mcpDisableCmd)
+	mcpCmd.AddCommand(mcpTestCmd)
+}
+
+// mcpSetup loads the config of the current project.
+func mcpSetup(cmd *cobra.Command) (*config.ConfigStore, error) {
+	cwd, err := ResolveCwd(cmd)
+	if err != nil {
+		return nil, err
+	}
+	dataDir, _ := cmd.Flags().GetString("data-dir")
+	debug, _ := cmd.Flags().GetBool("debug")
+	return config.Init(cwd, dataDir, debug)
+}
+
+func mcpScope(workspace bool) config.Scope {
+	if workspace {
+		return config.ScopeWorkspace
+	}
+	return config.ScopeGlobal
+}
+
+// newMCPConfig builds the config of an MCP server from the arguments of the
+// add command.
+func newMCPConfig(typ string, target string, args, env, headers []string, timeout int) (config.MCPConfig, error) {
+	m := config.MCPConfig{
+		Type:    config.MCPType(typ),
+		Timeout: timeout,
+	}
+	if m.Type == "" {
+		m.Type = config.MCPStdio
+		if u, err := url.Parse(target); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+			m.Type = config.MCPHttp
+		}
+	}
+
+	var err error
+	switch m.Type {
+	case config.MCPStdio:
+		if len(headers) > 0 {
+			return m, errors.New("headers are only sent to http and sse servers")
+		}
+		m.Command = target
+		m.Args = args
+		if m.Env, err = parseKeyValues(env); err != nil {
+			return m, fmt.Errorf("invalid env: %w", err)
+		}
+	case config.MCPHttp, config.MCPSSE:
+		if len(args) > 0 || len(env) > 0 {
+			return m, errors.New("arguments and env are only passed to stdio servers")
+		}
+		m.URL = target
+		if m.Headers, err = parseKeyValues(headers); err != nil {
+			return m, fmt.Errorf("invalid header: %w", err)
+		}
+	default:
+		return m, fmt.Errorf("unknown mcp type %q, expected stdio, http or sse", typ)
+	}
+	return m, nil
+}
+
+func parseKeyValues(values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	m := make(map[string]string, len(values))
+	for _, v := range values {
+		key, value, ok := strings.Cut(v, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("%q is not KEY=VALUE", v)
+		}
+		m[key] = value
+	}
+	return m, nil
+}
+
+func runMCPAdd(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	m, err := newMCPConfig(mcpAddType, args[1], args[2:], mcpAddEnv, mcpAddHeaders, mcpAddTimeout)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := mcpSetup(cmd)
+	if err != nil {
+		return err
+	}
+	if _, ok := cfg.Config().MCP[name]; ok {
+		return fmt.Errorf("mcp %q already exists, remove it first to replace it", name)
+	}
+	if err := cfg.SetMCP(mcpScope(mcpWorkspace), name, m); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Added %s MCP, test it with: crush mcp test %s\n", name, name)
+	return nil
+}
+
+func runMCPRemove(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	cfg, err := mcpSetup(cmd)
+	if err != nil {
+		return err
+	}
+	if _, ok := cfg.Config().MCP[name]; !ok {
+		return fmt.Errorf("mcp %q not configured", name)
+	}
+
+	scopes := []config.Scope{config.ScopeWorkspace}
+	if !mcpWorkspace {
+		scopes = append(scopes, config.ScopeGlobal)
+	}
+	removed := false
+	for _, scope := range scopes {
+		if !cfg.HasConfigField(scope, "mcp."+name) {
+			continue
+		}
+		if err := cfg.RemoveMCP(scope, name); err != nil {
+			return err
+		}
+		removed = true
+	}
+	if !removed {
+		return fmt.Errorf("mcp %q is defined in another config file, remove it from there", name)
+	}
+	if err := cfg.RemoveMCPCredentials(name); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Removed %s MCP\n", name)
+	return nil
+}
+
+func setMCPDisabled(cmd *cobra.Command, name string, disabled bool) error {
+	cfg, err := mcpSetup(cmd)
+	if err != nil {
+		return err
+	}
+	scope := config.ScopeWorkspace
+	if !mcpWorkspace {
+		if scope, err = cfg.MCPScope(name); err != nil {
+			return err
+		}
+	}
+	if err := cfg.SetMCPDisabled(scope, name, disabled); err != nil {
+		return err
+	}
+	if disabled {
+		fmt.Fprintf(cmd.OutOrStdout(), "Disabled %s MCP\n", name)
+	} else {
+		fmt.Fprintf(cmd.OutOrStdout(), "Enabled %s MCP\n", name)
+	}
+	return nil
+}
+
+type mcpJSON struct {
+	Name          string   `json:"name"`
+	Type          string   `json:"type"`
+	Command       string   `json:"command,omitempty"`
+	Args          []string `json:"args,omitempty"`
+	URL           string   `json:"url,omitempty"`
+	Disabled      bool     `json:"disabled,omitempty"`
+	DisabledTools []string `json:"disabled_tools,omitempty"`
+}
+
+// mcpTarget returns the command or URL of an MCP server.
+func mcpTarget(m config.MCPConfig) string {
+	if m.Type == config.MCPStdio {
+		return strings.Join(append([]string{m.Command}, m.Args...), " ")
+	}
+	return m.URL
+}
+
+func runMCPList(cmd *cobra.Command, _ []string) error {
+	cfg, err := mcpSetup(cmd)
+	if err != nil {
+		return err
+	}
+	mcps := cfg.Config().MCP.Sorted()
+
+	out := cmd.OutOrStdout()
+	if mcpListJSON {
+		output := make([]mcpJSON, len(mcps))
+		for i, m := range mcps {
+			output[i] = mcpJSON{
+				Name:          m.Name,
+				Type:          string(m.MCP.Type),
+				Command:       m.MCP.Command,
+				Args:          m.MCP.Args,
+				URL:           m.MCP.URL,
+				Disabled:      m.MCP.Disabled,
+				DisabledTools: m.MCP.DisabledTools,
+			}
+		}
+		enc := json.NewEncoder(out)
+		enc.SetEscapeHTML(false)
+		return enc.Encode(output)
+	}
+
+	if len(mcps) == 0 {
+		fmt.Fprintln(out, "No MCP servers configured. Add one with: crush mcp add")
+		return nil
+	}
+
+	status := func(m config.MCPConfig) string {
+		if m.Disabled {
+			return "disabled"
+		}
+		return "enabled"
+	}
+	if term.IsTerminal(os.Stdout.Fd()) {
+		t := table.New().
+			Border(lipgloss.RoundedBorder()).
+			StyleFunc(func(row, col int) lipgloss.Style {
+				return lipgloss.NewStyle().Padding(0, 2)
+			}).
+			Headers("Name", "Type", "Command or URL", "Status")
+		for _, m := range mcps {
+			t.Row(m.Name, string(m.MCP.Type), mcpTarget(m.MCP), status(m.MCP))
+		}
+		lipgloss.Fprintln(out, t)
+		return nil
+	}
+
+	for _, m := range mcps {
+		fmt.Fprintf(out, "%s\t%s\t%s\t%s\n", m.Name, m.MCP.Type, mcpTarget(m.MCP), status(m.MCP))
+	}
+	return nil
+}
+
+type mcpCheckJSON struct {
+	Name      string   `json:"name"`
+	Server    string   `json:"server,omitempty"`
+	Version   string   `json:"version,omitempty"`
+	Tools     []string `json:"tools"`
+	Prompts   []string `json:"prompts"`
+	Resources []string `json:"resources"`
+	ElapsedMS int64    `json:"elapsed_ms"`
+}
+
+func runMCPTest(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	cfg, err := mcpSetup(cmd)
+	if err != nil {
+		return err
+	}
+
+	result, err := mcp.Check(cmd.Context(), cfg, name)
+	if errors.Is(err, mcp.ErrUnauthorized) {
+		return fmt.Errorf("%w, authorize it with: crush login mcp %s", err, name)
+	}
+	if err != nil {
+		return err
+	}
+
+	output := mcpCheckJSON{
+		Name:      name,
+		Tools:     []string{},
+		Prompts:   []string{},
+		Resources: []string{},
+		ElapsedMS: result.Elapsed.Milliseconds(),
+	}
+	if result.Server != nil {
+		output.Server = result.Server.Name
+		output.Version = result.Server.Version
+	}
+	for _, t := range result.Tools {
+		output.Tools = append(output.Tools, t.Name)
+	}
+	for _, p := range result.Prompts {
+		output.Prompts = append(output.Prompts, p.Name)
+	}
+	for _, r := range result.Resources {
+		output.Resources = append(output.Resources, r.URI)
+	}
+
+	out := cmd.OutOrStdout()
+	if mcpTestJSON {
+		enc := json.NewEncoder(out)
+		enc.SetEscapeHTML(false)
+		return enc.Encode(output)
+	}
+
+	nameStyle := lipgloss.NewStyle().Foreground(charmtone.Malibu)
+	disabledStyle := lipgloss.NewStyle().Foreground(charmtone.Squid)
+	disabledTools := cfg.Config().MCP[name].DisabledTools
+	fmt.Fprintf(out, "Connected to %s %s in %s\n", output.Server, output.Version, result.Elapsed.Round(time.Millisecond))
+	fmt.Fprintf(out, "\nTools (%d)\n", len(output.Tools))
+	for _, tool := range output.Tools {
+		if slices.Contains(disabledTools, tool) {
+			fmt.Fprintln(out, "  "+nameStyle.Render(tool), disabledStyle.Render("disabled"))
+			continue
+		}
+		fmt.Fprintln(out, "  "+nameStyle.Render(tool))
+	}
+	fmt.Fprintf(out, "\nPrompts (%d)\n", len(output.Prompts))
+	for _, prompt := range output.Prompts {
+		fmt.Fprintln(out, "  "+nameStyle.Render(prompt))
+	}
+	fmt.Fprintf(out, "\nResources (%d)\n", len(output.Resources))
+	for _, resource := range output.Resources {
+		fmt.Fprintln(out, "  "+nameStyle.Render(resource))
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/agent/tools"
+	"github.com/charmbracelet/crush/internal/app"
+	"github.com/charmbracelet/crush/internal/event"
+	"github.com/charmbracelet/crush/internal/mcpserver"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/server"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/spf13/cobra"
+)
+
+var (
+	mcpServeHTTP        string
+	mcpServeAsk         bool
+	mcpServePermissions string
+	mcpServeToken       string
+)
+
+var mcpServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run Crush as an MCP server",
+	Long: `Run Crush as an MCP server, so other MCP clients can use its tools.
+Edits are recorded in the file history and report LSP diagnostics, just like
+in Crush. With --ask, an ask_crush tool runs prompts with the coder agent.
+
+Permission requests are asked to the client through elicitation, and denied
+if it doesn't support it, unless another policy is set with --permissions.
+
+The server speaks stdio, or streamable HTTP with --http. HTTP clients must
+send a bearer token, generated and printed unless set with --token or
+$CRUSH_SERVER_TOKEN, and requests from other hosts than the one served on
+are rejected.`,
+	Example: `
+# Serve over stdio
+crush mcp serve
+
+# Also let clients run prompts with the agent
+crush mcp serve --ask
+
+# Serve over HTTP, allowing every permission request
+crush mcp serve --http 127.0.0.1:4142 --permissions allow
+  `,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		policy, err := mcpserver.ParsePolicy(mcpServePermissions)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := signal.NotifyContext(cmd.Context(), os.Interrupt, os.Kill)
+		defer cancel()
+
+		appInstance, err := setupApp(cmd)
+		if err != nil {
+			return err
+		}
+		defer appInstance.Shutdown()
+
+		event.SetNonInteractive(true)
+		event.AppInitialized()
+
+		opts := mcpserver.Options{
+			Tools:       mcpServeTools(appInstance),
+			Sessions:    appInstance.Sessions,
+			Permissions: appInstance.Permissions,
+			Policy:      policy,
+		}
+		if mcpServeAsk {
+			if appInstance.AgentCoordinator == nil {
+				return errors.New("no providers configured, can't serve ask_crush")
+			}
+			opts.Agent = appInstance.AgentCoordinator
+		}
+		mcpServer := mcpserver.New(ctx, opts)
+
+		if mcpServeHTTP == "" {
+			return mcpServer.Run(ctx, &mcp.StdioTransport{})
+		}
+
+		host, _, err := net.SplitHostPort(mcpServeHTTP)
+		if err != nil {
+			return fmt.Errorf("invalid address %q: %w", mcpServeHTTP, err)
+		}
+		token := cmp.Or(mcpServeToken, os.Getenv("CRUSH_SERVER_TOKEN"))
+		generated := token == ""
+		if generated {
+			token = rand.Text()
+		}
+		srv := &http.Server{
+			Addr:              mcpServeHTTP,
+			Handler:           server.Guard(token, []string{host}, mcpServer.Handler()),
+			ReadHeaderTimeout: 10 * time.Second,
+			BaseContext:       func(net.Listener) context.Context { return ctx },
+		}
+
+		errc := make(chan error, 1)
+		go func() {
+			errc <- srv.ListenAndServe()
+		}()
+		fmt.Fprintf(cmd.ErrOrStderr(), "Serving MCP on http://%s\n", srv.Addr)
+		if generated {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Token: %s\n", token)
+		}
+
+		select {
+		case err := <-errc:
+			if !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+		case <-ctx.Done():
+		}
+
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer shutdownCancel()
+		return srv.Shutdown(shutdownCtx)
+	},
+}
+
+func init() {
+	mcpServeCmd.Flags().StringVar(&mcpServeHTTP, "http", "", "Serve streamable HTTP on this address instead of stdio")
+	mcpServeCmd.Flags().BoolVar(&mcpServeAsk, "ask", false, "Expose the ask_crush tool, running prompts with the coder agent")
+	mcpServeCmd.Flags().StringVar(&mcpServePermissions, "permissions", "ask", "How to answer permission requests: ask, allow or deny")
+	mcpServeCmd.Flags().StringVar(&mcpServeToken, "token", "", "Token HTTP clients must send as a bearer token. Defaults to $CRUSH_SERVER_TOKEN, or a generated one")
+	mcpCmd.AddCommand(mcpServeCmd)
+}
+
+// mcpServeTools returns the tools exposed by crush mcp serve. Tools running
+// commands or reaching the network are left out, clients have their own.
+func mcpServeTools(app *app.App) []fantasy.AgentTool {
+	cfg := app.Config()
+	wd := app.Store().WorkingDir()
+	list := []fantasy.AgentTool{
+		tools.NewEditTool(app.LSPManager, app.Permissions, app.History, app.FileTracker, wd),
+		tools.NewMultiEditTool(app.LSPManager, app.Permissions, app.History, app.FileTracker, wd),
+		tools.NewWriteTool(app.LSPManager, app.Permissions, app.History, app.FileTracker, wd),
+		tools.NewViewTool(app.LSPManager, app.Permissions, app.FileTracker, wd, cfg.Options.SkillsPaths...),
+		tools.NewGlobTool(wd),
+		tools.NewGrepTool(wd, cfg.Tools.Grep),
+		tools.NewLsTool(app.Permissions, wd, cfg.Tools.Ls),
+	}
+	if len(cfg.LSP) > 0 || cfg.Options.AutoLSP == nil || *cfg.Options.AutoLSP {
+		list = append(list,
+			tools.NewDiagnosticsTool(app.LSPManager),
+			tools.NewReferencesTool(app.LSPManager),
+			tools.NewDefinitionTool(app.LSPManager),
+			tools.NewImplementationTool(app.LSPManager),
+			tools.NewHoverTool(app.LSPManager),
+			tools.NewSymbolsTool(app.LSPManager),
+		)
+	}
+	for i, tool := range list {
+		list[i] = permission.WrapTool(tool)
+	}
+	return list
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMCPConfig(t *testing.T) {
+	t.Parallel()
+
+	m, err := newMCPConfig("", "npx", []string{"-y", "server"}, []string{"TOKEN=a=b"}, nil, 30)
+	require.NoError(t, err)
+	require.Equal(t, config.MCPStdio, m.Type)
+	require.Equal(t, "npx", m.Command)
+	require.Equal(t, []string{"-y", "server"}, m.Args)
+	require.Equal(t, map[string]string{"TOKEN": "a=b"}, m.Env)
+	require.Equal(t, 30, m.Timeout)
+
+	m, err = newMCPConfig("", "https://example.com/mcp", nil, nil, []string{"Authorization=Bearer $TOKEN"}, 0)
+	require.NoError(t, err)
+	require.Equal(t, config.MCPHttp, m.Type)
+	require.Equal(t, "https://example.com/mcp", m.URL)
+	require.Equal(t, map[string]string{"Authorization": "Bearer $TOKEN"}, m.Headers)
+
+	m, err = newMCPConfig("sse", "https://example.com/sse", nil, nil, nil, 0)
+	require.NoError(t, err)
+	require.Equal(t, config.MCPSSE, m.Type)
+
+	_, err = newMCPConfig("", "npx", nil, nil, []string{"Authorization=x"}, 0)
+	require.Error(t, err)
+	_, err = newMCPConfig("", "https://example.com/mcp", []string{"arg"}, nil, nil, 0)
+	require.Error(t, err)
+	_, err = newMCPConfig("", "npx", nil, []string{"TOKEN"}, nil, 0)
+	require.Error(t, err)
+	_, err = newMCPConfig("ws", "npx", nil, nil, nil, 0)
+	require.Error(t, err)
+}
@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/charmbracelet/x/exp/charmtone"
+	"github.com/spf13/cobra"
+)
+
+var permissionsCmd = &cobra.Command{
+	Use:     "permissions",
+	Aliases: []string{"permission"},
+	Short:   "Manage saved permission grants",
+	Long: `Manage the permissions granted with "Allow for session".
+These grants are saved and reloaded when a session is reopened. Use --json for machine-readable output.`,
+}
+
+var (
+	permissionsListJSON    bool
+	permissionsListSession string
+	permissionsListAll     bool
+	permissionsRevokeJSON  bool
+)
+
+var permissionsListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List saved permission grants",
+	Long:    "List the permission grants made in the current project, or in a given session. Use --json for machine-readable output.",
+	Example: `
+# List the grants made in the current project
+crush permissions list
+
+# List the grants of a session
+crush permissions list --session 3f2a
+  `,
+	RunE: runPermissionsList,
+}
+
+var permissionsRevokeCmd = &cobra.Command{
+	Use:   "revoke <id>",
+	Short: "Revoke a saved permission grant",
+	Long:  "Revoke a permission grant, so it's asked for again. Use --json for machine-readable output. ID can be a prefix.",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runPermissionsRevoke,
+}
+
+func init() {
+	permissionsListCmd.Flags().BoolVar(&permissionsListJSON, "json", false, "output in JSON format")
+	permissionsListCmd.Flags().StringVarP(&permissionsListSession, "session", "s", "", "List the grants of a session. Accepts a UUID, full hash, or hash prefix")
+	permissionsListCmd.Flags().BoolVarP(&permissionsListAll, "all", "a", false, "List the grants of all projects")
+	permissionsListCmd.MarkFlagsMutuallyExclusive("session", "all")
+	permissionsRevokeCmd.Flags().BoolVar(&permissionsRevokeJSON, "json", false, "output in JSON format")
+	permissionsCmd.AddCommand(permissionsListCmd)
+	permissionsCmd.AddCommand(permissionsRevokeCmd)
+}
+
+type permissionGrantJSON struct {
+	ID        string `json:"id"`
+	SessionID string `json:"session_id"`
+	Project   string `json:"project"`
+	ToolName  string `json:"tool_name"`
+	Action    string `json:"action"`
+	Path      string `json:"path"`
+	Created   string `json:"created"`
+	Revoked   bool   `json:"revoked,omitempty"`
+}
+
+func toPermissionGrantJSON(g permission.Grant) permissionGrantJSON {
+	return permissionGrantJSON{
+		ID:        g.ID,
+		SessionID: g.SessionID,
+		Project:   g.Project,
+		ToolName:  g.ToolName,
+		Action:    g.Action,
+		Path:      g.Path,
+		Created:   g.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+func runPermissionsList(cmd *cobra.Command, _ []string) error {
+	cwd, err := ResolveCwd(cmd)
+	if err != nil {
+		return err
+	}
+	ctx, svc, cleanup, err := sessionSetup(cmd)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	var grants []permission.Grant
+	switch {
+	case permissionsListSession != "":
+		sess, err := resolveSessionID(ctx, svc.sessions, permissionsListSession)
+		if err != nil {
+			return err
+		}
+		grants, err = svc.grants.ListBySession(ctx, sess.ID)
+		if err != nil {
+			return err
+		}
+	case permissionsListAll:
+		grants, err = svc.grants.List(ctx)
+		if err != nil {
+			return err
+		}
+	default:
+		grants, err = svc.grants.ListByProject(ctx, cwd)
+		if err != nil {
+			return err
+		}
+	}
+
+	out := cmd.OutOrStdout()
+	if permissionsListJSON {
+		output := make([]permissionGrantJSON, len(grants))
+		for i, g := range grants {
+			output[i] = toPermissionGrantJSON(g)
+		}
+		enc := json.NewEncoder(out)
+		enc.SetEscapeHTML(false)
+		return enc.Encode(output)
+	}
+
+	if len(grants) == 0 {
+		fmt.Fprintln(out, "No saved permission grants")
+		return nil
+	}
+
+	idStyle := lipgloss.NewStyle().Foreground(charmtone.Malibu)
+	sessionStyle := lipgloss.NewStyle().Foreground(charmtone.Damson)
+	for _, g := range grants {
+		fmt.Fprintln(
+			out,
+			idStyle.Render(g.ID[:8]),
+			sessionStyle.Render(session.HashID(g.SessionID)[:7]),
+			g.ToolName+":"+g.Action,
+			g.Path,
+		)
+	}
+	return nil
+}
+
+// resolveGrantID resolves a grant ID that can be a full ID or a prefix.
+func resolveGrantID(ctx context.Context, grants permission.GrantStore, id string) (permission.Grant, error) {
+	if g, err := grants.Get(ctx, id); err == nil {
+		return g, nil
+	}
+
+	all, err := grants.List(ctx)
+	if err != nil {
+		return permission.Grant{}, err
+	}
+
+	var matches []permission.Grant
+	for _, g := range all {
+		if strings.HasPrefix(g.ID, id) {
+			matches = append(matches, g)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return permission.Grant{}, fmt.Errorf("permission grant not found: %s", id)
+	case 1:
+		return matches[0], nil
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "permission grant ID '%s' is ambiguous. Matches:\n\n", id)
+	for _, m := range matches {
+		fmt.Fprintf(&sb, "  %s... %s:%s %s\n", m.ID[:12], m.ToolName, m.Action, m.Path)
+	}
+	sb.WriteString("\nUse more characters or the full ID")
+	return permission.Grant{}, errors.New(sb.String())
+}
+
+func runPermissionsRevoke(cmd *cobra.Command, args []string) error {
+	ctx, svc, cleanup, err := sessionSetup(cmd)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	grant, err := resolveGrantID(ctx, svc.grants, args[0])
+	if err != nil {
+		return err
+	}
+
+	if err := svc.grants.Delete(ctx, grant.ID); err != nil {
+		return fmt.Errorf("failed to revoke permission grant: %w", err)
+	}
+
+	out := cmd.OutOrStdout()
+	if permissionsRevokeJSON {
+		result := toPermissionGrantJSON(grant)
+		result.Revoked = true
+		enc := json.NewEncoder(out)
+		enc.SetEscapeHTML(false)
+		return enc.Encode(result)
+	}
+
+	fmt.Fprintf(out, "Revoked %s:%s on %s\n", grant.ToolName, grant.Action, grant.Path)
+	return nil
+}
@@ -16,6 +16,7 @@ import (
 	"charm.land/fang/v2"
 	"charm.land/lipgloss/v2"
 	"github.com/charmbracelet/colorprofile"
+	"github.com/charmbracelet/crush/internal/agent"
 	"github.com/charmbracelet/crush/internal/app"
 	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/db"
@@ -37,6 +38,9 @@ func init() {
 	rootCmd.PersistentFlags().BoolP("debug", "d", false, "Debug")
 	rootCmd.Flags().BoolP("help", "h", false, "Help")
 	rootCmd.Flags().BoolP("yolo", "y", false, "Automatically accept all permissions (dangerous mode)")
+	rootCmd.Flags().StringP("session", "s", "", "Open an existing session by ID. Accepts a UUID, full hash, or hash prefix")
+	rootCmd.Flags().BoolP("continue", "C", false, "Continue the most recent session")
+	rootCmd.MarkFlagsMutuallyExclusive("session", "continue")
 
 	rootCmd.AddCommand(
 		runCmd,
@@ -46,8 +50,12 @@ func init() {
 		logsCmd,
 		schemaCmd,
 		loginCmd,
+		mcpCmd,
 		statsCmd,
 		sessionCmd,
+		permissionsCmd,
+		worktreeCmd,
+		serveCmd,
 	)
 }
 
@@ -71,6 +79,12 @@ crush --debug --cwd /path/to/project
 # Run in yolo mode (auto-accept all permissions; use with care)
 crush --yolo
 
+# Pick up where you left off
+crush --continue
+
+# Open a specific session
+crush --session 3f2a
+
 # Run with custom data directory
 crush --data-dir /path/to/custom/.crush
   `,
@@ -81,13 +95,18 @@ crush --data-dir /path/to/custom/.crush
 		}
 		defer app.Shutdown()
 
+		sessionID, err := sessionFromFlags(cmd, app.Sessions)
+		if err != nil {
+			return err
+		}
+
 		event.AppInitialized()
 
 		// Set up the TUI.
 		var env uv.Environ = os.Environ()
 
 		com := common.DefaultCommon(app)
-		model := ui.New(com)
+		model := ui.New(com, sessionID)
 
 		program := tea.NewProgram(
 			model,
@@ -124,6 +143,9 @@ var heartbit = lipgloss.NewStyle().Foreground(charmtone.Dolly).SetString(`
 const defaultVersionTemplate = `{{with .DisplayName}}{{printf "%s " .}}{{end}}{{printf "version %s" .Version}}
 `
 
+// exitBudgetExceeded is the exit status of runs stopped by their budget.
+const exitBudgetExceeded = 3
+
 func Execute() {
 	// NOTE: very hacky: we create a colorprofile writer with STDOUT, then make
 	// it forward to a bytes.Buffer, write the colored heartbit to it, and then
@@ -145,6 +167,11 @@ func Execute() {
 		fang.WithVersion(version.Version),
 		fang.WithNotifySignal(os.Interrupt),
 	); err != nil {
+		// Runs stopped by their budget exit with a distinct status, so
+		// scripts can tell them apart from failures.
+		if errors.Is(err, agent.ErrBudgetExceeded) {
+			os.Exit(exitBudgetExceeded)
+		}
 		os.Exit(1)
 	}
 }
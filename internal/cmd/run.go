@@ -9,6 +9,8 @@ import (
 	"strings"
 
 	"charm.land/log/v2"
+	"github.com/charmbracelet/crush/internal/app"
+	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/event"
 	"github.com/spf13/cobra"
 )
@@ -36,27 +38,69 @@ crush run --quiet "Generate a README for this project"
 
 # Run in verbose mode (show logs)
 crush run --verbose "Generate a README for this project"
+
+# Continue the most recent session
+crush run --continue "Now add tests for it"
+
+# Continue a specific session
+crush run --session 3f2a "Now add tests for it"
+
+# Run with a user-defined agent
+crush run --agent reviewer "Review the latest changes"
+
+# Work in an isolated git worktree, to be merged or discarded later
+crush run --worktree "Refactor the config loader"
+
+# Explore and plan before changing anything
+crush run --plan "Add retries to the HTTP client"
+
+# Stop after 50 steps or $2, exiting with status 3 if either is hit
+crush run --max-steps 50 --max-cost 2 "Fix the failing tests"
+
+# Print a JSON summary with the session ID, usage and cost
+crush run --output-format json "Fix the failing tests"
+
+# Stream tool calls, results and usage as newline-delimited JSON
+crush run --output-format stream-json "Fix the failing tests" | jq -c 'select(.type == "tool_call")'
   `,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		quiet, _ := cmd.Flags().GetBool("quiet")
 		verbose, _ := cmd.Flags().GetBool("verbose")
 		largeModel, _ := cmd.Flags().GetString("model")
 		smallModel, _ := cmd.Flags().GetString("small-model")
+		agentID, _ := cmd.Flags().GetString("agent")
+		outputFormatName, _ := cmd.Flags().GetString("output-format")
+		useWorktree, _ := cmd.Flags().GetBool("worktree")
+		plan, _ := cmd.Flags().GetBool("plan")
+		maxSteps, _ := cmd.Flags().GetInt("max-steps")
+		maxTokens, _ := cmd.Flags().GetInt64("max-tokens")
+		maxCost, _ := cmd.Flags().GetFloat64("max-cost")
+		maxDuration, _ := cmd.Flags().GetDuration("max-duration")
+
+		outputFormat, err := app.ParseOutputFormat(outputFormatName)
+		if err != nil {
+			return err
+		}
 
 		// Cancel on SIGINT or SIGTERM.
 		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
 		defer cancel()
 
-		app, err := setupApp(cmd)
+		appInstance, err := setupApp(cmd)
 		if err != nil {
 			return err
 		}
-		defer app.Shutdown()
+		defer appInstance.Shutdown()
 
-		if !app.Config().IsConfigured() {
+		if !appInstance.Config().IsConfigured() {
 			return fmt.Errorf("no providers configured - please run 'crush' to set up a provider interactively")
 		}
 
+		sessionID, err := sessionFromFlags(cmd, appInstance.Sessions)
+		if err != nil {
+			return err
+		}
+
 		if verbose {
 			slog.SetDefault(slog.New(log.New(os.Stderr)))
 		}
@@ -76,7 +120,23 @@ crush run --verbose "Generate a README for this project"
 		event.SetNonInteractive(true)
 		event.AppInitialized()
 
-		return app.RunNonInteractive(ctx, os.Stdout, prompt, largeModel, smallModel, quiet || verbose)
+		return appInstance.RunNonInteractive(ctx, os.Stdout, app.RunOptions{
+			Prompt:       prompt,
+			SessionID:    sessionID,
+			LargeModel:   largeModel,
+			SmallModel:   smallModel,
+			AgentID:      agentID,
+			OutputFormat: outputFormat,
+			HideSpinner:  quiet || verbose,
+			Worktree:     useWorktree,
+			Plan:         plan,
+			Budget: config.Budget{
+				MaxSteps:    maxSteps,
+				MaxTokens:   maxTokens,
+				MaxCost:     maxCost,
+				MaxDuration: int(maxDuration.Seconds()),
+			},
+		})
 	},
 }
 
@@ -85,4 +145,15 @@ func init() {
 	runCmd.Flags().BoolP("verbose", "v", false, "Show logs")
 	runCmd.Flags().StringP("model", "m", "", "Model to use. Accepts 'model' or 'provider/model' to disambiguate models with the same name across providers")
 	runCmd.Flags().String("small-model", "", "Small model to use. If not provided, uses the default small model for the provider")
+	runCmd.Flags().StringP("agent", "a", "", "Agent to use, as defined in the agents section of the config")
+	runCmd.Flags().StringP("session", "s", "", "Continue an existing session by ID. Accepts a UUID, full hash, or hash prefix")
+	runCmd.Flags().BoolP("continue", "C", false, "Continue the most recent session")
+	runCmd.MarkFlagsMutuallyExclusive("session", "continue")
+	runCmd.Flags().String("output-format", "text", "Output format: text, json or stream-json")
+	runCmd.Flags().BoolP("worktree", "w", false, "Run the session in its own git worktree, isolated from the working tree")
+	runCmd.Flags().Bool("plan", false, "Start in plan mode: explore with read-only tools and make a plan before changing anything")
+	runCmd.Flags().Int("max-steps", 0, "Stop after this many steps, overriding the budget of the agent. Exits with status 3 when hit")
+	runCmd.Flags().Int64("max-tokens", 0, "Stop after using this many tokens, overriding the budget of the agent. Exits with status 3 when hit")
+	runCmd.Flags().Float64("max-cost", 0, "Stop after spending this many dollars, overriding the budget of the agent. Exits with status 3 when hit")
+	runCmd.Flags().Duration("max-duration", 0, "Stop after running for this long, like 10m, overriding the budget of the agent. Exits with status 3 when hit")
 }
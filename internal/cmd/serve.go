@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/event"
+	"github.com/charmbracelet/crush/internal/server"
+	"github.com/spf13/cobra"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve sessions over a local HTTP API",
+	Long: `Start a headless server exposing sessions over a local HTTP API.
+Clients can create and list sessions, send prompts, answer permission requests
+and follow along with server-sent events from /v1/events.
+
+Clients must send the token as a bearer token. Unless set with --token or
+$CRUSH_SERVER_TOKEN, a token is generated and printed on start. Request bodies
+must be sent as application/json.
+
+Endpoints:
+  GET    /v1/sessions                 list sessions
+  POST   /v1/sessions                 create a session
+  GET    /v1/sessions/{id}            get a session
+  DELETE /v1/sessions/{id}            delete a session
+  GET    /v1/sessions/{id}/messages   list the messages of a session
+  POST   /v1/sessions/{id}/prompt     send a prompt
+  POST   /v1/sessions/{id}/cancel     cancel the running prompt
+  GET    /v1/permissions              list pending permission requests
+  POST   /v1/permissions/{id}         allow or deny a permission request
+  GET    /v1/events                   stream events`,
+	Example: `
+# Serve on the default address
+crush serve
+
+# Use a token of your own
+CRUSH_SERVER_TOKEN=secret crush serve --port 8080
+
+# Send a prompt and follow its events
+curl -N -H "Authorization: Bearer $TOKEN" localhost:4141/v1/events &
+curl -X POST -H "Authorization: Bearer $TOKEN" -H "Content-Type: application/json" \
+  localhost:4141/v1/sessions/3f2a.../prompt -d '{"prompt": "Fix the failing tests"}'
+  `,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		host, _ := cmd.Flags().GetString("host")
+		port, _ := cmd.Flags().GetInt("port")
+		token, _ := cmd.Flags().GetString("token")
+		token = cmp.Or(token, os.Getenv("CRUSH_SERVER_TOKEN"))
+		generated := token == ""
+		if generated {
+			token = rand.Text()
+		}
+
+		ctx, cancel := signal.NotifyContext(cmd.Context(), os.Interrupt, os.Kill)
+		defer cancel()
+
+		appInstance, err := setupApp(cmd)
+		if err != nil {
+			return err
+		}
+		defer appInstance.Shutdown()
+
+		if !appInstance.Config().IsConfigured() {
+			slog.Warn("No providers configured, prompts will be rejected")
+		}
+
+		event.SetNonInteractive(true)
+		event.AppInitialized()
+
+		srv := &http.Server{
+			Addr: net.JoinHostPort(host, strconv.Itoa(port)),
+			Handler: server.New(ctx, server.Options{
+				Sessions:    appInstance.Sessions,
+				Messages:    appInstance.Messages,
+				Permissions: appInstance.Permissions,
+				Agent:       appInstance.AgentCoordinator,
+				Token:       token,
+				Hosts:       []string{host},
+			}),
+			ReadHeaderTimeout: 10 * time.Second,
+			// Derive requests from ctx, so event streams end on shutdown.
+			BaseContext: func(net.Listener) context.Context { return ctx },
+		}
+
+		errc := make(chan error, 1)
+		go func() {
+			errc <- srv.ListenAndServe()
+		}()
+		fmt.Fprintf(cmd.ErrOrStderr(), "Serving on http://%s\n", srv.Addr)
+		if generated {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Token: %s\n", token)
+		}
+
+		select {
+		case err := <-errc:
+			if !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+		case <-ctx.Done():
+		}
+
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer shutdownCancel()
+		return srv.Shutdown(shutdownCtx)
+	},
+}
+
+func init() {
+	serveCmd.Flags().String("host", "127.0.0.1", "Host to listen on")
+	serveCmd.Flags().IntP("port", "p", 4141, "Port to listen on")
+	serveCmd.Flags().String("token", "", "Token clients must send as a bearer token. Defaults to $CRUSH_SERVER_TOKEN, or a generated one")
+}
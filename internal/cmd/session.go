@@ -15,9 +15,14 @@ import (
 
 	"charm.land/lipgloss/v2"
 	"github.com/charmbracelet/colorprofile"
+	"github.com/charmbracelet/crush/internal/checkpoint"
 	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/fork"
+	"github.com/charmbracelet/crush/internal/history"
 	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/rewind"
 	"github.com/charmbracelet/crush/internal/session"
 	"github.com/charmbracelet/crush/internal/ui/chat"
 	"github.com/charmbracelet/crush/internal/ui/styles"
@@ -40,6 +45,9 @@ var (
 	sessionLastJSON   bool
 	sessionDeleteJSON bool
 	sessionRenameJSON bool
+	sessionRewindJSON bool
+	sessionForkJSON   bool
+	sessionForkAt     string
 )
 
 var sessionListCmd = &cobra.Command{
@@ -82,22 +90,68 @@ var sessionRenameCmd = &cobra.Command{
 	RunE:  runSessionRename,
 }
 
+var sessionRewindCmd = &cobra.Command{
+	Use:   "rewind <id> <message-id>",
+	Short: "Rewind a session to an earlier message",
+	Long: `Rewind a session to the point right before one of its user messages was sent.
+The message and everything after it are deleted, and files changed by the agent since then are restored.
+Use --json for machine-readable output. Session and message IDs can be prefixes.`,
+	Example: `
+# Find the message to rewind to
+crush session show 3f2a --json
+
+# Rewind to it
+crush session rewind 3f2a 9c1e7b
+  `,
+	Args: cobra.ExactArgs(2),
+	RunE: runSessionRewind,
+}
+
+var sessionForkCmd = &cobra.Command{
+	Use:   "fork <id>",
+	Short: "Fork a session",
+	Long: `Copy a session into a new session, to try another approach without losing the original.
+The messages, todos and file history are copied, up to the message given with --at if any.
+Sessions the agent is working on can't be forked.
+Use --json for machine-readable output. Session and message IDs can be prefixes.`,
+	Example: `
+# Fork the whole session
+crush session fork 3f2a
+
+# Fork it right after one of its messages
+crush session fork 3f2a --at 9c1e7b
+
+# Continue the fork
+crush run --session <fork-id> "Try it with a state machine instead"
+  `,
+	Args: cobra.ExactArgs(1),
+	RunE: runSessionFork,
+}
+
 func init() {
 	sessionListCmd.Flags().BoolVar(&sessionListJSON, "json", false, "output in JSON format")
 	sessionShowCmd.Flags().BoolVar(&sessionShowJSON, "json", false, "output in JSON format")
 	sessionLastCmd.Flags().BoolVar(&sessionLastJSON, "json", false, "output in JSON format")
 	sessionDeleteCmd.Flags().BoolVar(&sessionDeleteJSON, "json", false, "output in JSON format")
 	sessionRenameCmd.Flags().BoolVar(&sessionRenameJSON, "json", false, "output in JSON format")
+	sessionRewindCmd.Flags().BoolVar(&sessionRewindJSON, "json", false, "output in JSON format")
+	sessionForkCmd.Flags().BoolVar(&sessionForkJSON, "json", false, "output in JSON format")
+	sessionForkCmd.Flags().StringVar(&sessionForkAt, "at", "", "fork right after this message instead of the end of the session")
 	sessionCmd.AddCommand(sessionListCmd)
 	sessionCmd.AddCommand(sessionShowCmd)
 	sessionCmd.AddCommand(sessionLastCmd)
 	sessionCmd.AddCommand(sessionDeleteCmd)
 	sessionCmd.AddCommand(sessionRenameCmd)
+	sessionCmd.AddCommand(sessionRewindCmd)
+	sessionCmd.AddCommand(sessionForkCmd)
 }
 
 type sessionServices struct {
-	sessions session.Service
-	messages message.Service
+	sessions    session.Service
+	messages    message.Service
+	history     history.Service
+	grants      permission.GrantStore
+	checkpoints *checkpoint.Service
 }
 
 func sessionSetup(cmd *cobra.Command) (context.Context, *sessionServices, func(), error) {
@@ -121,6 +175,11 @@ func sessionSetup(cmd *cobra.Command) (context.Context, *sessionServices, func()
 	svc := &sessionServices{
 		sessions: session.NewService(queries, conn),
 		messages: message.NewService(queries),
+		history:  history.NewService(queries, conn),
+		grants:   permission.NewGrantStore(queries),
+		// Checkpoints are kept in git or the data directory, not in the
+		// database.
+		checkpoints: checkpoint.NewService(dataDir),
 	}
 	return ctx, svc, func() { conn.Close() }, nil
 }
@@ -148,6 +207,9 @@ func runSessionList(cmd *cobra.Command, _ []string) error {
 				Created:  time.Unix(s.CreatedAt, 0).Format(time.RFC3339),
 				Modified: time.Unix(s.UpdatedAt, 0).Format(time.RFC3339),
 			}
+			if s.IsFork {
+				output[i].ForkOf = session.HashID(s.ParentSessionID)
+			}
 		}
 		enc := json.NewEncoder(out)
 		enc.SetEscapeHTML(false)
@@ -159,6 +221,7 @@ func runSessionList(cmd *cobra.Command, _ []string) error {
 
 	hashStyle := lipgloss.NewStyle().Foreground(charmtone.Malibu)
 	dateStyle := lipgloss.NewStyle().Foreground(charmtone.Damson)
+	forkStyle := lipgloss.NewStyle().Foreground(charmtone.Squid)
 
 	width := sessionOutputWidth
 	if tw, _, err := term.GetSize(os.Stdout.Fd()); err == nil && tw > 0 {
@@ -176,6 +239,9 @@ func runSessionList(cmd *cobra.Command, _ []string) error {
 		date := time.Unix(s.CreatedAt, 0).Format(time.RFC3339)
 		title := strings.ReplaceAll(s.Title, "\n", " ")
 		title = ansi.Truncate(title, titleWidth, "…")
+		if s.IsFork {
+			title += forkStyle.Render(" (fork of " + session.HashID(s.ParentSessionID)[:7] + ")")
+		}
 		_, writeErr = fmt.Fprintln(w, hashStyle.Render(hash), dateStyle.Render(date), title)
 		if writeErr != nil {
 			break
@@ -191,10 +257,20 @@ type sessionJSON struct {
 	ID       string `json:"id"`
 	UUID     string `json:"uuid"`
 	Title    string `json:"title"`
+	ForkOf   string `json:"fork_of,omitempty"`
 	Created  string `json:"created"`
 	Modified string `json:"modified"`
 }
 
+type sessionForkResult struct {
+	ID       string `json:"id"`
+	UUID     string `json:"uuid"`
+	Title    string `json:"title"`
+	ForkOf   string `json:"fork_of"`
+	Messages int    `json:"messages"`
+	Files    int    `json:"files"`
+}
+
 type sessionMutationResult struct {
 	ID      string `json:"id"`
 	UUID    string `json:"uuid"`
@@ -203,6 +279,17 @@ type sessionMutationResult struct {
 	Renamed bool   `json:"renamed,omitempty"`
 }
 
+type sessionRewindResult struct {
+	ID              string   `json:"id"`
+	UUID            string   `json:"uuid"`
+	Title           string   `json:"title"`
+	MessageID       string   `json:"message_id"`
+	Prompt          string   `json:"prompt"`
+	DeletedMessages int      `json:"deleted_messages"`
+	RestoredFiles   []string `json:"restored_files,omitempty"`
+	RemovedFiles    []string `json:"removed_files,omitempty"`
+}
+
 // resolveSessionID resolves a session ID that can be a UUID, full hash, or hash prefix.
 // Returns an error if the prefix is ambiguous (matches multiple sessions).
 func resolveSessionID(ctx context.Context, svc session.Service, id string) (session.Session, error) {
@@ -287,6 +374,13 @@ func runSessionDelete(cmd *cobra.Command, args []string) error {
 	if err := svc.sessions.Delete(ctx, sess.ID); err != nil {
 		return fmt.Errorf("failed to delete session: %w", err)
 	}
+	cwd, err := ResolveCwd(cmd)
+	if err != nil {
+		return err
+	}
+	if err := svc.checkpoints.Delete(ctx, cwd, sess.ID); err != nil {
+		return err
+	}
 
 	out := cmd.OutOrStdout()
 	if sessionDeleteJSON {
@@ -337,23 +431,164 @@ func runSessionRename(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func runSessionLast(cmd *cobra.Command, _ []string) error {
+func runSessionRewind(cmd *cobra.Command, args []string) error {
 	ctx, svc, cleanup, err := sessionSetup(cmd)
 	if err != nil {
 		return err
 	}
 	defer cleanup()
 
-	list, err := svc.sessions.List(ctx)
+	sess, err := resolveSessionID(ctx, svc.sessions, args[0])
 	if err != nil {
-		return fmt.Errorf("failed to list sessions: %w", err)
+		return err
+	}
+
+	rewinder := rewind.NewService(svc.sessions, svc.messages, svc.history)
+	msg, err := rewinder.ResolveMessage(ctx, sess.ID, args[1])
+	if err != nil {
+		return err
+	}
+	result, err := rewinder.Rewind(ctx, sess.ID, msg.ID)
+	if err != nil {
+		return fmt.Errorf("failed to rewind session: %w", err)
+	}
+
+	out := cmd.OutOrStdout()
+	if sessionRewindJSON {
+		enc := json.NewEncoder(out)
+		enc.SetEscapeHTML(false)
+		return enc.Encode(sessionRewindResult{
+			ID:              session.HashID(sess.ID),
+			UUID:            sess.ID,
+			Title:           sess.Title,
+			MessageID:       msg.ID,
+			Prompt:          msg.Content().Text,
+			DeletedMessages: result.DeletedMessages,
+			RestoredFiles:   result.RestoredFiles,
+			RemovedFiles:    result.RemovedFiles,
+		})
+	}
+
+	fmt.Fprintf(
+		out,
+		"Rewound session %s: deleted %d messages, restored %d files, removed %d files\n",
+		session.HashID(sess.ID)[:12],
+		result.DeletedMessages,
+		len(result.RestoredFiles),
+		len(result.RemovedFiles),
+	)
+	return nil
+}
+
+func runSessionFork(cmd *cobra.Command, args []string) error {
+	ctx, svc, cleanup, err := sessionSetup(cmd)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	sess, err := resolveSessionID(ctx, svc.sessions, args[0])
+	if err != nil {
+		return err
+	}
+
+	forks := fork.NewService(svc.sessions, svc.messages, svc.history)
+	busy, err := forks.Busy(ctx, sess.ID)
+	if err != nil {
+		return err
+	}
+	if busy {
+		return fmt.Errorf("session %s is busy: wait for the agent to finish before forking it", session.HashID(sess.ID)[:12])
+	}
+
+	var messageID string
+	if sessionForkAt != "" {
+		msg, err := rewind.NewService(svc.sessions, svc.messages, svc.history).ResolveMessage(ctx, sess.ID, sessionForkAt)
+		if err != nil {
+			return err
+		}
+		messageID = msg.ID
+	}
+
+	result, err := forks.Fork(ctx, sess.ID, messageID)
+	if err != nil {
+		return fmt.Errorf("failed to fork session: %w", err)
+	}
+
+	out := cmd.OutOrStdout()
+	if sessionForkJSON {
+		enc := json.NewEncoder(out)
+		enc.SetEscapeHTML(false)
+		return enc.Encode(sessionForkResult{
+			ID:       session.HashID(result.Session.ID),
+			UUID:     result.Session.ID,
+			Title:    result.Session.Title,
+			ForkOf:   session.HashID(sess.ID),
+			Messages: result.Messages,
+			Files:    result.Files,
+		})
+	}
+
+	fmt.Fprintf(
+		out,
+		"Forked session %s into %s: copied %d messages and %d file versions\n",
+		session.HashID(sess.ID)[:12],
+		session.HashID(result.Session.ID)[:12],
+		result.Messages,
+		result.Files,
+	)
+	return nil
+}
+
+// lastSession returns the most recently updated session.
+func lastSession(ctx context.Context, svc session.Service) (session.Session, error) {
+	list, err := svc.List(ctx)
+	if err != nil {
+		return session.Session{}, fmt.Errorf("failed to list sessions: %w", err)
 	}
 
 	if len(list) == 0 {
-		return fmt.Errorf("no sessions found")
+		return session.Session{}, fmt.Errorf("no sessions found")
 	}
 
-	sess := list[0]
+	return list[0], nil
+}
+
+// sessionFromFlags resolves the session selected with the --session or
+// --continue flags. It returns an empty ID if neither is set.
+func sessionFromFlags(cmd *cobra.Command, svc session.Service) (string, error) {
+	id, _ := cmd.Flags().GetString("session")
+	cont, _ := cmd.Flags().GetBool("continue")
+
+	switch {
+	case id != "":
+		sess, err := resolveSessionID(cmd.Context(), svc, id)
+		if err != nil {
+			return "", err
+		}
+		return sess.ID, nil
+	case cont:
+		sess, err := lastSession(cmd.Context(), svc)
+		if err != nil {
+			return "", err
+		}
+		return sess.ID, nil
+	default:
+		return "", nil
+	}
+}
+
+func runSessionLast(cmd *cobra.Command, _ []string) error {
+	ctx, svc, cleanup, err := sessionSetup(cmd)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	sess, err := lastSession(ctx, svc.sessions)
+	if err != nil {
+		return err
+	}
 
 	msgs, err := svc.messages.List(ctx, sess.ID)
 	if err != nil {
@@ -0,0 +1,492 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/charmbracelet/crush/internal/version"
+	"github.com/spf13/cobra"
+)
+
+//go:embed transcript/index.html
+var transcriptTemplate string
+
+//go:embed transcript/index.css
+var transcriptCSS string
+
+// sessionExportVersion is the version of the JSON export format. It's bumped
+// when a change would keep older versions of Crush from importing exports.
+const sessionExportVersion = 1
+
+// Export formats.
+const (
+	exportFormatMarkdown = "md"
+	exportFormatJSON     = "json"
+	exportFormatHTML     = "html"
+)
+
+var exportFormats = []string{exportFormatMarkdown, exportFormatJSON, exportFormatHTML}
+
+var (
+	sessionExportFormat string
+	sessionExportOutput string
+	sessionImportJSON   bool
+)
+
+var sessionExportCmd = &cobra.Command{
+	Use:   "export <id>",
+	Short: "Export a session",
+	Long: `Export a session as a Markdown, JSON or HTML transcript. ID can be a UUID, full hash, or hash prefix.
+JSON exports keep every part of the messages, and can be imported back with "crush session import".`,
+	Example: `
+# Export the last session as Markdown
+crush session export 3f2a > session.md
+
+# Share a session as a web page
+crush session export 3f2a --format html --output session.html
+
+# Move a session to another machine
+crush session export 3f2a --format json --output session.json
+crush session import session.json
+  `,
+	Args: cobra.ExactArgs(1),
+	RunE: runSessionExport,
+}
+
+var sessionImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import a session",
+	Long:  `Import a session exported with "crush session export --format json". Use "-" to read from stdin. Use --json for machine-readable output.`,
+	Args:  cobra.ExactArgs(1),
+	RunE:  runSessionImport,
+}
+
+func init() {
+	sessionExportCmd.Flags().StringVarP(&sessionExportFormat, "format", "f", exportFormatMarkdown, "export format: "+strings.Join(exportFormats, ", "))
+	sessionExportCmd.Flags().StringVarP(&sessionExportOutput, "output", "o", "", "write to a file instead of stdout")
+	sessionImportCmd.Flags().BoolVar(&sessionImportJSON, "json", false, "output in JSON format")
+	sessionCmd.AddCommand(sessionExportCmd)
+	sessionCmd.AddCommand(sessionImportCmd)
+}
+
+// sessionExport is a session in the JSON export format.
+type sessionExport struct {
+	Version  int                    `json:"version"`
+	Exported string                 `json:"exported"`
+	Crush    string                 `json:"crush"`
+	Session  sessionExportMeta      `json:"session"`
+	Messages []sessionExportMessage `json:"messages"`
+}
+
+type sessionExportMeta struct {
+	ID               string         `json:"id"`
+	Title            string         `json:"title"`
+	PromptTokens     int64          `json:"prompt_tokens"`
+	CompletionTokens int64          `json:"completion_tokens"`
+	Cost             float64        `json:"cost"`
+	SummaryMessageID string         `json:"summary_message_id,omitempty"`
+	Todos            []session.Todo `json:"todos,omitempty"`
+	CreatedAt        int64          `json:"created_at"`
+	UpdatedAt        int64          `json:"updated_at"`
+}
+
+type sessionExportMessage struct {
+	ID               string `json:"id"`
+	Role             string `json:"role"`
+	Model            string `json:"model,omitempty"`
+	Provider         string `json:"provider,omitempty"`
+	IsSummaryMessage bool   `json:"is_summary_message,omitempty"`
+	CreatedAt        int64  `json:"created_at"`
+	UpdatedAt        int64  `json:"updated_at"`
+	// Parts are encoded the way they're stored, so every kind of part,
+	// attachments included, survives a round trip.
+	Parts json.RawMessage `json:"parts"`
+}
+
+func runSessionExport(cmd *cobra.Command, args []string) error {
+	if !slices.Contains(exportFormats, sessionExportFormat) {
+		return fmt.Errorf("unknown export format %q, use one of: %s", sessionExportFormat, strings.Join(exportFormats, ", "))
+	}
+
+	ctx, svc, cleanup, err := sessionSetup(cmd)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	sess, err := resolveSessionID(ctx, svc.sessions, args[0])
+	if err != nil {
+		return err
+	}
+	msgs, err := svc.messages.List(ctx, sess.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list messages: %w", err)
+	}
+
+	var buf bytes.Buffer
+	switch sessionExportFormat {
+	case exportFormatJSON:
+		err = exportSessionJSON(&buf, sess, msgs)
+	case exportFormatHTML:
+		err = exportSessionHTML(&buf, sess, msgs)
+	default:
+		err = exportSessionMarkdown(&buf, sess, msgs)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to export session: %w", err)
+	}
+
+	if sessionExportOutput == "" {
+		_, err = cmd.OutOrStdout().Write(buf.Bytes())
+		return err
+	}
+	if err := os.WriteFile(sessionExportOutput, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "Exported session %s to %s\n", session.HashID(sess.ID)[:12], sessionExportOutput)
+	return nil
+}
+
+func runSessionImport(cmd *cobra.Command, args []string) error {
+	var (
+		data []byte
+		err  error
+	)
+	if args[0] == "-" {
+		data, err = io.ReadAll(cmd.InOrStdin())
+	} else {
+		data, err = os.ReadFile(args[0])
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read export: %w", err)
+	}
+
+	var export sessionExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return fmt.Errorf("failed to parse export: %w", err)
+	}
+	if export.Version == 0 || export.Version > sessionExportVersion {
+		return fmt.Errorf("unsupported export version %d, this version of Crush imports up to version %d", export.Version, sessionExportVersion)
+	}
+
+	ctx, svc, cleanup, err := sessionSetup(cmd)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	sess, err := importSession(ctx, svc, export)
+	if err != nil {
+		return fmt.Errorf("failed to import session: %w", err)
+	}
+
+	out := cmd.OutOrStdout()
+	if sessionImportJSON {
+		enc := json.NewEncoder(out)
+		enc.SetEscapeHTML(false)
+		return enc.Encode(sessionMutationResult{
+			ID:    session.HashID(sess.ID),
+			UUID:  sess.ID,
+			Title: sess.Title,
+		})
+	}
+
+	fmt.Fprintf(out, "Imported session %s %q with %d messages\n", session.HashID(sess.ID)[:12], sess.Title, len(export.Messages))
+	return nil
+}
+
+func exportSessionJSON(w io.Writer, sess session.Session, msgs []message.Message) error {
+	export := sessionExport{
+		Version:  sessionExportVersion,
+		Exported: time.Now().Format(time.RFC3339),
+		Crush:    version.Version,
+		Session: sessionExportMeta{
+			ID:               sess.ID,
+			Title:            sess.Title,
+			PromptTokens:     sess.PromptTokens,
+			CompletionTokens: sess.CompletionTokens,
+			Cost:             sess.Cost,
+			SummaryMessageID: sess.SummaryMessageID,
+			Todos:            sess.Todos,
+			CreatedAt:        sess.CreatedAt,
+			UpdatedAt:        sess.UpdatedAt,
+		},
+		Messages: make([]sessionExportMessage, 0, len(msgs)),
+	}
+	for _, msg := range msgs {
+		parts, err := message.MarshalParts(msg.Parts)
+		if err != nil {
+			return err
+		}
+		export.Messages = append(export.Messages, sessionExportMessage{
+			ID:               msg.ID,
+			Role:             string(msg.Role),
+			Model:            msg.Model,
+			Provider:         msg.Provider,
+			IsSummaryMessage: msg.IsSummaryMessage,
+			CreatedAt:        msg.CreatedAt,
+			UpdatedAt:        msg.UpdatedAt,
+			Parts:            parts,
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(export)
+}
+
+// importSession adds an exported session as a new session. Sessions and
+// messages get new IDs, so a session can be imported next to the one it was
+// exported from.
+func importSession(ctx context.Context, svc *sessionServices, export sessionExport) (session.Session, error) {
+	msgs := make([]message.Message, 0, len(export.Messages))
+	for _, m := range export.Messages {
+		parts, err := message.UnmarshalParts(m.Parts)
+		if err != nil {
+			return session.Session{}, fmt.Errorf("message %s: %w", m.ID, err)
+		}
+		msgs = append(msgs, message.Message{
+			ID:               m.ID,
+			Role:             message.MessageRole(m.Role),
+			Parts:            parts,
+			Model:            m.Model,
+			Provider:         m.Provider,
+			IsSummaryMessage: m.IsSummaryMessage,
+			CreatedAt:        m.CreatedAt,
+			UpdatedAt:        m.UpdatedAt,
+		})
+	}
+
+	sess, err := svc.sessions.Create(ctx, export.Session.Title)
+	if err != nil {
+		return session.Session{}, err
+	}
+	ids := make(map[string]string, len(msgs))
+	for _, msg := range msgs {
+		imported, err := svc.messages.Import(ctx, sess.ID, msg)
+		if err != nil {
+			// Don't leave a half imported session behind.
+			_ = svc.sessions.Delete(ctx, sess.ID)
+			return session.Session{}, err
+		}
+		ids[msg.ID] = imported.ID
+	}
+
+	sess.PromptTokens = export.Session.PromptTokens
+	sess.CompletionTokens = export.Session.CompletionTokens
+	sess.Cost = export.Session.Cost
+	sess.SummaryMessageID = ids[export.Session.SummaryMessageID]
+	sess.Todos = export.Session.Todos
+	return svc.sessions.Save(ctx, sess)
+}
+
+func exportSessionMarkdown(w io.Writer, sess session.Session, msgs []message.Message) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", sess.Title)
+	fmt.Fprintf(&b, "- **Session:** `%s`\n", session.HashID(sess.ID)[:12])
+	fmt.Fprintf(&b, "- **Date:** %s\n", time.Unix(sess.CreatedAt, 0).Format(time.RFC1123))
+	fmt.Fprintf(&b, "- **Tokens:** %d prompt, %d completion\n", sess.PromptTokens, sess.CompletionTokens)
+	fmt.Fprintf(&b, "- **Cost:** $%.4f\n", sess.Cost)
+
+	for _, msg := range transcriptMessages(msgs) {
+		fmt.Fprintf(&b, "\n## %s\n\n", msg.Heading)
+		if msg.Model != "" {
+			fmt.Fprintf(&b, "_%s, %s_\n\n", msg.Model, msg.Time)
+		} else {
+			fmt.Fprintf(&b, "_%s_\n\n", msg.Time)
+		}
+		for _, entry := range msg.Entries {
+			switch entry.Kind {
+			case "text":
+				fmt.Fprintf(&b, "%s\n\n", strings.TrimSpace(entry.Body))
+			case "reasoning":
+				fmt.Fprintf(&b, "<details>\n<summary>Thinking</summary>\n\n%s\n\n</details>\n\n", strings.TrimSpace(entry.Body))
+			case "tool_call":
+				fmt.Fprintf(&b, "**Tool call:** `%s`\n\n%s\n\n", entry.Title, fenced(entry.Body, "json"))
+			case "tool_result":
+				label := "Result"
+				if entry.Error {
+					label = "Error"
+				}
+				fmt.Fprintf(&b, "**%s of** `%s`\n\n%s\n\n", label, entry.Title, fenced(entry.Body, ""))
+			case "attachment":
+				fmt.Fprintf(&b, "**Attachment:** `%s` (%s)\n\n", entry.Title, entry.Body)
+			case "image":
+				fmt.Fprintf(&b, "![image](%s)\n\n", entry.Body)
+			case "finish":
+				fmt.Fprintf(&b, "> **%s**", entry.Title)
+				if entry.Body != "" {
+					fmt.Fprintf(&b, ": %s", entry.Body)
+				}
+				b.WriteString("\n\n")
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, strings.TrimRight(b.String(), "\n")+"\n")
+	return err
+}
+
+// fenced wraps content in a code fence longer than any run of backticks in
+// it.
+func fenced(content, lang string) string {
+	longest, run := 0, 0
+	for _, r := range content {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+			continue
+		}
+		run = 0
+	}
+	fence := strings.Repeat("`", max(3, longest+1))
+	return fence + lang + "\n" + strings.TrimRight(content, "\n") + "\n" + fence
+}
+
+func exportSessionHTML(w io.Writer, sess session.Session, msgs []message.Message) error {
+	tmpl, err := template.New("transcript").Parse(transcriptTemplate)
+	if err != nil {
+		return fmt.Errorf("parse template: %w", err)
+	}
+
+	data := struct {
+		CSS              template.CSS
+		Header           template.HTML
+		Heartbit         template.HTML
+		Footer           template.HTML
+		Title            string
+		ID               string
+		Date             string
+		PromptTokens     int64
+		CompletionTokens int64
+		Cost             string
+		Messages         []transcriptMessage
+	}{
+		CSS:              template.CSS(transcriptCSS),
+		Header:           template.HTML(headerSVG),
+		Heartbit:         template.HTML(heartbitSVG),
+		Footer:           template.HTML(footerSVG),
+		Title:            sess.Title,
+		ID:               session.HashID(sess.ID)[:12],
+		Date:             time.Unix(sess.CreatedAt, 0).Format(time.RFC1123),
+		PromptTokens:     sess.PromptTokens,
+		CompletionTokens: sess.CompletionTokens,
+		Cost:             fmt.Sprintf("$%.4f", sess.Cost),
+		Messages:         transcriptMessages(msgs),
+	}
+
+	if err := tmpl.Execute(w, data); err != nil {
+		return fmt.Errorf("execute template: %w", err)
+	}
+	return nil
+}
+
+// transcriptMessage is a message as shown in Markdown and HTML transcripts.
+type transcriptMessage struct {
+	Role    string
+	Heading string
+	Model   string
+	Time    string
+	Entries []transcriptEntry
+}
+
+// transcriptEntry is a part of a message as shown in transcripts.
+type transcriptEntry struct {
+	// Kind is one of text, reasoning, tool_call, tool_result, attachment,
+	// image and finish.
+	Kind  string
+	Title string
+	Body  string
+	Error bool
+}
+
+func transcriptMessages(msgs []message.Message) []transcriptMessage {
+	result := make([]transcriptMessage, 0, len(msgs))
+	for _, msg := range msgs {
+		tm := transcriptMessage{
+			Role:  string(msg.Role),
+			Model: msg.Model,
+			Time:  time.Unix(msg.CreatedAt, 0).Format(time.DateTime),
+		}
+		switch msg.Role {
+		case message.User:
+			tm.Heading = "User"
+			tm.Model = ""
+		case message.Tool:
+			tm.Heading = "Tool"
+			tm.Model = ""
+		default:
+			tm.Heading = "Assistant"
+			if msg.IsSummaryMessage {
+				tm.Heading = "Summary"
+			}
+		}
+
+		for _, part := range msg.Parts {
+			switch p := part.(type) {
+			case message.TextContent:
+				if strings.TrimSpace(p.Text) != "" {
+					tm.Entries = append(tm.Entries, transcriptEntry{Kind: "text", Body: p.Text})
+				}
+			case message.ReasoningContent:
+				if strings.TrimSpace(p.Thinking) != "" {
+					tm.Entries = append(tm.Entries, transcriptEntry{Kind: "reasoning", Body: p.Thinking})
+				}
+			case message.ToolCall:
+				tm.Entries = append(tm.Entries, transcriptEntry{Kind: "tool_call", Title: p.Name, Body: prettyJSON(p.Input)})
+			case message.ToolResult:
+				body := p.Content
+				if p.Data != "" && body == "" {
+					body = fmt.Sprintf("[%s data]", p.MIMEType)
+				}
+				tm.Entries = append(tm.Entries, transcriptEntry{Kind: "tool_result", Title: p.Name, Body: body, Error: p.IsError})
+			case message.BinaryContent:
+				tm.Entries = append(tm.Entries, transcriptEntry{
+					Kind:  "attachment",
+					Title: p.Path,
+					Body:  fmt.Sprintf("%s, %d bytes", p.MIMEType, len(p.Data)),
+				})
+			case message.ImageURLContent:
+				tm.Entries = append(tm.Entries, transcriptEntry{Kind: "image", Body: p.URL})
+			case message.Finish:
+				// Only unusual endings are worth a mention.
+				switch p.Reason {
+				case message.FinishReasonCanceled:
+					tm.Entries = append(tm.Entries, transcriptEntry{Kind: "finish", Title: "Canceled", Body: p.Message, Error: true})
+				case message.FinishReasonError:
+					tm.Entries = append(tm.Entries, transcriptEntry{Kind: "finish", Title: "Error", Body: strings.TrimSpace(p.Message + " " + p.Details), Error: true})
+				case message.FinishReasonPermissionDenied:
+					tm.Entries = append(tm.Entries, transcriptEntry{Kind: "finish", Title: "Permission denied", Error: true})
+				case message.FinishReasonBudgetExceeded:
+					tm.Entries = append(tm.Entries, transcriptEntry{Kind: "finish", Title: "Budget exceeded", Body: p.Message, Error: true})
+				}
+			}
+		}
+		if len(tm.Entries) == 0 {
+			continue
+		}
+		result = append(result, tm)
+	}
+	return result
+}
+
+// prettyJSON indents JSON tool inputs, leaving anything else as it is.
+func prettyJSON(input string) string {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(input), "", "  "); err != nil {
+		return input
+	}
+	return buf.String()
+}
@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSessionServices(t *testing.T) *sessionServices {
+	t.Helper()
+	conn, err := db.Connect(t.Context(), t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	queries := db.New(conn)
+	return &sessionServices{
+		sessions: session.NewService(queries, conn),
+		messages: message.NewService(queries),
+		history:  history.NewService(queries, conn),
+		grants:   permission.NewGrantStore(queries),
+	}
+}
+
+func TestSessionExportImport(t *testing.T) {
+	t.Parallel()
+	ctx := t.Context()
+	svc := newTestSessionServices(t)
+
+	sess, err := svc.sessions.Create(ctx, "Fix the parser")
+	require.NoError(t, err)
+
+	exported := []message.Message{
+		{
+			Role: message.User,
+			Parts: []message.ContentPart{
+				message.TextContent{Text: "Why does this ```fail```?"},
+				message.BinaryContent{Path: "screenshot.png", MIMEType: "image/png", Data: []byte{0x89, 'P', 'N', 'G', 0}},
+				message.ImageURLContent{URL: "https://example.com/a.png", Detail: "high"},
+				message.Finish{Reason: message.FinishReasonEndTurn, Time: 100},
+			},
+			CreatedAt: 100,
+			UpdatedAt: 100,
+		},
+		{
+			Role:     message.Assistant,
+			Model:    "claude-sonnet",
+			Provider: "anthropic",
+			Parts: []message.ContentPart{
+				message.ReasoningContent{Thinking: "Let me look.", Signature: "sig", StartedAt: 101, FinishedAt: 102},
+				message.ToolCall{ID: "call-1", Name: "view", Input: `{"file_path":"parser.go"}`, Finished: true},
+				message.Finish{Reason: message.FinishReasonToolUse, Time: 103},
+			},
+			CreatedAt: 101,
+			UpdatedAt: 103,
+		},
+		{
+			Role: message.Tool,
+			Parts: []message.ContentPart{
+				message.ToolResult{ToolCallID: "call-1", Name: "view", Content: "package parser", Metadata: `{"lines":1}`},
+			},
+			CreatedAt: 103,
+			UpdatedAt: 103,
+		},
+		{
+			Role:             message.Assistant,
+			Model:            "claude-sonnet",
+			Provider:         "anthropic",
+			IsSummaryMessage: true,
+			Parts: []message.ContentPart{
+				message.TextContent{Text: "The parser was fixed."},
+				message.Finish{Reason: message.FinishReasonError, Time: 105, Message: "Overloaded", Details: "try again"},
+			},
+			CreatedAt: 104,
+			UpdatedAt: 105,
+		},
+	}
+	for i, msg := range exported {
+		exported[i], err = svc.messages.Import(ctx, sess.ID, msg)
+		require.NoError(t, err)
+	}
+	sess.PromptTokens = 1200
+	sess.CompletionTokens = 300
+	sess.Cost = 0.25
+	sess.SummaryMessageID = exported[3].ID
+	sess.Todos = []session.Todo{{Content: "Fix the parser", Status: session.TodoStatusCompleted}}
+	sess, err = svc.sessions.Save(ctx, sess)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, exportSessionJSON(&buf, sess, exported))
+	var export sessionExport
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &export))
+	require.Equal(t, sessionExportVersion, export.Version)
+
+	imported, err := importSession(ctx, svc, export)
+	require.NoError(t, err)
+	require.NotEqual(t, sess.ID, imported.ID)
+	require.Equal(t, "Fix the parser", imported.Title)
+	require.Equal(t, int64(1200), imported.PromptTokens)
+	require.Equal(t, int64(300), imported.CompletionTokens)
+	require.Equal(t, 0.25, imported.Cost)
+	require.Equal(t, sess.Todos, imported.Todos)
+
+	msgs, err := svc.messages.List(ctx, imported.ID)
+	require.NoError(t, err)
+	require.Len(t, msgs, len(exported))
+	for i, msg := range msgs {
+		require.NotEqual(t, exported[i].ID, msg.ID)
+		require.Equal(t, exported[i].Role, msg.Role)
+		require.Equal(t, exported[i].Model, msg.Model)
+		require.Equal(t, exported[i].Provider, msg.Provider)
+		require.Equal(t, exported[i].IsSummaryMessage, msg.IsSummaryMessage)
+		require.Equal(t, exported[i].CreatedAt, msg.CreatedAt)
+		require.Equal(t, exported[i].UpdatedAt, msg.UpdatedAt)
+		require.Equal(t, exported[i].Parts, msg.Parts)
+	}
+	require.Equal(t, msgs[3].ID, imported.SummaryMessageID)
+}
+
+func TestSessionExportMarkdown(t *testing.T) {
+	t.Parallel()
+	sess := session.Session{ID: "session-1", Title: "Fix the parser", CreatedAt: 100}
+	msgs := []message.Message{
+		{
+			Role:  message.User,
+			Parts: []message.ContentPart{message.TextContent{Text: "Fix it"}},
+		},
+		{
+			Role:  message.Assistant,
+			Model: "claude-sonnet",
+			Parts: []message.ContentPart{
+				message.ToolCall{ID: "call-1", Name: "bash", Input: `{"command":"go test"}`},
+			},
+		},
+		{
+			Role: message.Tool,
+			Parts: []message.ContentPart{
+				message.ToolResult{ToolCallID: "call-1", Name: "bash", Content: "```\nFAIL\n```", IsError: true},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, exportSessionMarkdown(&buf, sess, msgs))
+	out := buf.String()
+	require.Contains(t, out, "# Fix the parser\n")
+	require.Contains(t, out, "## User\n")
+	require.Contains(t, out, "Fix it\n")
+	require.Contains(t, out, "**Tool call:** `bash`\n\n```json\n{\n  \"command\": \"go test\"\n}\n```")
+	// Fences are longer than the backticks they wrap.
+	require.Contains(t, out, "**Error of** `bash`\n\n````\n```\nFAIL\n```\n````")
+}
+
+func TestSessionExportHTML(t *testing.T) {
+	t.Parallel()
+	sess := session.Session{ID: "session-1", Title: "<Fix> the parser", CreatedAt: 100}
+	msgs := []message.Message{
+		{
+			Role:  message.User,
+			Parts: []message.ContentPart{message.TextContent{Text: "<script>alert(1)</script>"}},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, exportSessionHTML(&buf, sess, msgs))
+	out := buf.String()
+	require.Contains(t, out, "&lt;Fix&gt; the parser")
+	require.Contains(t, out, "&lt;script&gt;alert(1)&lt;/script&gt;")
+	require.NotContains(t, out, "<script>alert(1)</script>")
+}
@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/crush/internal/home"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/charmbracelet/crush/internal/worktree"
+	"github.com/charmbracelet/x/exp/charmtone"
+	"github.com/spf13/cobra"
+)
+
+var worktreeCmd = &cobra.Command{
+	Use:     "worktree",
+	Aliases: []string{"worktrees", "wt"},
+	Short:   "Manage the git worktrees of sessions",
+	Long: `Manage the git worktrees sessions run in when started with --worktree.
+Changes made in a worktree stay there until merged into the branch it was created from. Use --json for machine-readable output.`,
+}
+
+var (
+	worktreeListJSON    bool
+	worktreeMergeJSON   bool
+	worktreeDiscardJSON bool
+)
+
+var worktreeListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List session worktrees",
+	Long:    "List the session worktrees of the current repository. Use --json for machine-readable output.",
+	RunE:    runWorktreeList,
+}
+
+var worktreeMergeCmd = &cobra.Command{
+	Use:   "merge <session-id>",
+	Short: "Merge a session worktree",
+	Long: `Commit the pending changes of a session worktree and merge its branch into the branch it was created from.
+That branch must be checked out. The worktree is removed once merged. Use --json for machine-readable output.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runWorktreeMerge,
+}
+
+var worktreeDiscardCmd = &cobra.Command{
+	Use:   "discard <session-id>",
+	Short: "Discard a session worktree",
+	Long:  "Remove a session worktree and its branch, throwing away its changes. Use --json for machine-readable output.",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runWorktreeDiscard,
+}
+
+var worktreeOpenCmd = &cobra.Command{
+	Use:   "open <session-id>",
+	Short: "Open a shell in a session worktree",
+	Long:  "Start $SHELL in a session worktree, to review or change its files by hand.",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runWorktreeOpen,
+}
+
+func init() {
+	worktreeListCmd.Flags().BoolVar(&worktreeListJSON, "json", false, "output in JSON format")
+	worktreeMergeCmd.Flags().BoolVar(&worktreeMergeJSON, "json", false, "output in JSON format")
+	worktreeDiscardCmd.Flags().BoolVar(&worktreeDiscardJSON, "json", false, "output in JSON format")
+	worktreeCmd.AddCommand(worktreeListCmd)
+	worktreeCmd.AddCommand(worktreeMergeCmd)
+	worktreeCmd.AddCommand(worktreeDiscardCmd)
+	worktreeCmd.AddCommand(worktreeOpenCmd)
+}
+
+type worktreeJSON struct {
+	worktree.Worktree
+	Changed   bool `json:"changed"`
+	Merged    bool `json:"merged,omitempty"`
+	Discarded bool `json:"discarded,omitempty"`
+}
+
+func runWorktreeList(cmd *cobra.Command, _ []string) error {
+	cwd, err := ResolveCwd(cmd)
+	if err != nil {
+		return err
+	}
+	worktrees, err := worktree.List(cmd.Context(), cwd)
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	if worktreeListJSON {
+		output := make([]worktreeJSON, len(worktrees))
+		for i, wt := range worktrees {
+			changed, _ := wt.HasChanges(cmd.Context())
+			output[i] = worktreeJSON{Worktree: wt, Changed: changed}
+		}
+		enc := json.NewEncoder(out)
+		enc.SetEscapeHTML(false)
+		return enc.Encode(output)
+	}
+
+	if len(worktrees) == 0 {
+		fmt.Fprintln(out, "No session worktrees")
+		return nil
+	}
+
+	sessionStyle := lipgloss.NewStyle().Foreground(charmtone.Damson)
+	branchStyle := lipgloss.NewStyle().Foreground(charmtone.Malibu)
+	for _, wt := range worktrees {
+		status := "clean"
+		if changed, _ := wt.HasChanges(cmd.Context()); changed {
+			status = "changed"
+		}
+		fmt.Fprintln(
+			out,
+			sessionStyle.Render(session.HashID(wt.SessionID)[:7]),
+			branchStyle.Render(wt.Branch),
+			status,
+			home.Short(wt.Path),
+		)
+	}
+	return nil
+}
+
+// resolveWorktree finds the worktree of a session given its ID, hash, or a
+// prefix of either.
+func resolveWorktree(cmd *cobra.Command, id string) (worktree.Worktree, error) {
+	cwd, err := ResolveCwd(cmd)
+	if err != nil {
+		return worktree.Worktree{}, err
+	}
+	worktrees, err := worktree.List(cmd.Context(), cwd)
+	if err != nil {
+		return worktree.Worktree{}, err
+	}
+
+	var matches []worktree.Worktree
+	for _, wt := range worktrees {
+		if wt.SessionID == id {
+			return wt, nil
+		}
+		if strings.HasPrefix(wt.SessionID, id) || strings.HasPrefix(session.HashID(wt.SessionID), id) {
+			matches = append(matches, wt)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return worktree.Worktree{}, fmt.Errorf("no worktree found for session: %s", id)
+	case 1:
+		return matches[0], nil
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "session ID '%s' is ambiguous. Matches:\n\n", id)
+	for _, m := range matches {
+		fmt.Fprintf(&sb, "  %s %s\n", session.HashID(m.SessionID)[:12], m.Branch)
+	}
+	sb.WriteString("\nUse more characters or the full ID")
+	return worktree.Worktree{}, errors.New(sb.String())
+}
+
+func runWorktreeMerge(cmd *cobra.Command, args []string) error {
+	wt, err := resolveWorktree(cmd, args[0])
+	if err != nil {
+		return err
+	}
+	if err := wt.Merge(cmd.Context()); err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	if worktreeMergeJSON {
+		enc := json.NewEncoder(out)
+		enc.SetEscapeHTML(false)
+		return enc.Encode(worktreeJSON{Worktree: wt, Merged: true})
+	}
+
+	fmt.Fprintf(out, "Merged %s into %s\n", wt.Branch, wt.BaseBranch)
+	return nil
+}
+
+func runWorktreeDiscard(cmd *cobra.Command, args []string) error {
+	wt, err := resolveWorktree(cmd, args[0])
+	if err != nil {
+		return err
+	}
+	if err := wt.Discard(cmd.Context()); err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	if worktreeDiscardJSON {
+		enc := json.NewEncoder(out)
+		enc.SetEscapeHTML(false)
+		return enc.Encode(worktreeJSON{Worktree: wt, Discarded: true})
+	}
+
+	fmt.Fprintf(out, "Discarded %s\n", wt.Branch)
+	return nil
+}
+
+func runWorktreeOpen(cmd *cobra.Command, args []string) error {
+	wt, err := resolveWorktree(cmd, args[0])
+	if err != nil {
+		return err
+	}
+
+	shell := cmp.Or(os.Getenv("SHELL"), "sh")
+	c := exec.CommandContext(cmd.Context(), shell)
+	c.Dir = wt.Path
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	fmt.Fprintf(cmd.ErrOrStderr(), "Opening %s in %s, exit the shell to return\n", wt.Branch, home.Short(wt.Path))
+	return c.Run()
+}
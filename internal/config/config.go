@@ -17,6 +17,7 @@ import (
 	"github.com/charmbracelet/crush/internal/env"
 	"github.com/charmbracelet/crush/internal/oauth"
 	"github.com/charmbracelet/crush/internal/oauth/copilot"
+	"github.com/charmbracelet/crush/internal/oauth/mcpauth"
 	"github.com/invopop/jsonschema"
 )
 
@@ -86,6 +87,37 @@ type SelectedModel struct {
 
 	// Override provider specific options.
 	ProviderOptions map[string]any `json:"provider_options,omitempty" jsonschema:"description=Additional provider-specific options for the model"`
+
+	// Models to fail over to, in order, when this one keeps failing.
+	Fallbacks []SelectedModel `json:"fallbacks,omitempty" jsonschema:"description=Models to fail over to in order when this one keeps failing"`
+	// Errors failing over right away, without retrying the model first.
+	FailoverOn []FailoverClass `json:"failover_on,omitempty" jsonschema:"description=Errors failing over to the next fallback right away instead of after retries,enum=overloaded,enum=rate_limit,enum=quota,enum=server,enum=auth,default=overloaded,default=rate_limit,default=quota"`
+}
+
+// FailoverClass is a class of provider errors.
+type FailoverClass string
+
+const (
+	// FailoverOverloaded is the provider being overloaded, like Anthropic's
+	// 529 errors.
+	FailoverOverloaded FailoverClass = "overloaded"
+	// FailoverRateLimit is the provider rate limiting requests.
+	FailoverRateLimit FailoverClass = "rate_limit"
+	// FailoverQuota is running out of credits or quota.
+	FailoverQuota FailoverClass = "quota"
+	// FailoverServer is any other server error.
+	FailoverServer FailoverClass = "server"
+	// FailoverAuth is the provider rejecting the credentials.
+	FailoverAuth FailoverClass = "auth"
+)
+
+// FailoverClasses returns the errors failing over right away, defaulting to
+// the provider being overloaded, rate limited or out of quota.
+func (m SelectedModel) FailoverClasses() []FailoverClass {
+	if len(m.FailoverOn) > 0 {
+		return m.FailoverOn
+	}
+	return []FailoverClass{FailoverOverloaded, FailoverRateLimit, FailoverQuota}
 }
 
 type ProviderConfig struct {
@@ -177,6 +209,18 @@ type MCPConfig struct {
 
 	// TODO: maybe make it possible to get the value from the env
 	Headers map[string]string `json:"headers,omitempty" jsonschema:"description=HTTP headers for HTTP/SSE MCP servers"`
+
+	OAuth *MCPOAuthConfig `json:"oauth,omitempty" jsonschema:"description=OAuth client settings for HTTP/SSE MCP servers requiring authorization"`
+}
+
+// MCPOAuthConfig configures how Crush authorizes with a remote MCP server.
+// Servers are authorized when they require it, with a client registered
+// dynamically unless a client ID is set.
+type MCPOAuthConfig struct {
+	ClientID     string   `json:"client_id,omitempty" jsonschema:"description=ID of a client registered with the authorization server beforehand"`
+	ClientSecret string   `json:"client_secret,omitempty" jsonschema:"description=Secret of the registered client (supports $ENV_VAR)"`
+	Scopes       []string `json:"scopes,omitempty" jsonschema:"description=Scopes to request instead of the ones the server asks for,example=read"`
+	CallbackPort int      `json:"callback_port,omitempty" jsonschema:"description=Port of the local callback receiving the authorization code. The registered redirect URI is http://127.0.0.1:<port>/callback,example=8765"`
 }
 
 type LSPConfig struct {
@@ -189,6 +233,7 @@ type LSPConfig struct {
 	InitOptions map[string]any    `json:"init_options,omitempty" jsonschema:"description=Initialization options passed to the LSP server during initialize request"`
 	Options     map[string]any    `json:"options,omitempty" jsonschema:"description=LSP server-specific settings passed during initialization"`
 	Timeout     int               `json:"timeout,omitempty" jsonschema:"description=Timeout in seconds for LSP server initialization,default=30,example=60,example=120"`
+	Formatter   string            `json:"formatter,omitempty" jsonschema:"description=Shell command formatting the files of this LSP server after the agent changes them. It reads the content on stdin and writes the formatted content on stdout. The file path is in $CRUSH_FILE_PATH,example=gofmt,example=goimports"`
 }
 
 type TUIOptions struct {
@@ -212,8 +257,16 @@ func (c Completions) Limits() (depth, items int) {
 }
 
 type Permissions struct {
-	AllowedTools []string `json:"allowed_tools,omitempty" jsonschema:"description=List of tools that don't require permission prompts,example=bash,example=view"` // Tools that don't require permission prompts
-	SkipRequests bool     `json:"-"`                                                                                                                              // Automatically accept all permissions (YOLO mode)
+	AllowedTools []string         `json:"allowed_tools,omitempty" jsonschema:"description=List of tools that don't require permission prompts,example=bash,example=view"` // Tools that don't require permission prompts
+	Rules        []PermissionRule `json:"rules,omitempty" jsonschema:"description=Ordered permission rules. The last rule matching a request decides it"`                 // Allow/deny/ask rules evaluated before prompting
+	SkipRequests bool             `json:"-"`                                                                                                                              // Automatically accept all permissions (YOLO mode)
+}
+
+// PermissionRule decides permission requests matching a tool and an optional
+// pattern.
+type PermissionRule struct {
+	Rule     string `json:"rule" jsonschema:"required,description=Tool name with an optional action and pattern,example=bash(go test *),example=edit(internal/**),example=write(.env*)"`
+	Decision string `json:"decision" jsonschema:"required,description=What to do with matching requests,enum=allow,enum=deny,enum=ask"`
 }
 
 type TrailerStyle string
@@ -240,22 +293,32 @@ func (Attribution) JSONSchemaExtend(schema *jsonschema.Schema) {
 }
 
 type Options struct {
-	ContextPaths              []string     `json:"context_paths,omitempty" jsonschema:"description=Paths to files containing context information for the AI,example=.cursorrules,example=CRUSH.md"`
-	SkillsPaths               []string     `json:"skills_paths,omitempty" jsonschema:"description=Paths to directories containing Agent Skills (folders with SKILL.md files),example=~/.config/crush/skills,example=./skills"`
-	TUI                       *TUIOptions  `json:"tui,omitempty" jsonschema:"description=Terminal user interface options"`
-	Debug                     bool         `json:"debug,omitempty" jsonschema:"description=Enable debug logging,default=false"`
-	DebugLSP                  bool         `json:"debug_lsp,omitempty" jsonschema:"description=Enable debug logging for LSP servers,default=false"`
-	DisableAutoSummarize      bool         `json:"disable_auto_summarize,omitempty" jsonschema:"description=Disable automatic conversation summarization,default=false"`
-	DataDirectory             string       `json:"data_directory,omitempty" jsonschema:"description=Directory for storing application data (relative to working directory),default=.crush,example=.crush"` // Relative to the cwd
-	DisabledTools             []string     `json:"disabled_tools,omitempty" jsonschema:"description=List of built-in tools to disable and hide from the agent,example=bash,example=sourcegraph"`
-	DisableProviderAutoUpdate bool         `json:"disable_provider_auto_update,omitempty" jsonschema:"description=Disable providers auto-update,default=false"`
-	DisableDefaultProviders   bool         `json:"disable_default_providers,omitempty" jsonschema:"description=Ignore all default/embedded providers. When enabled, providers must be fully specified in the config file with base_url, models, and api_key - no merging with defaults occurs,default=false"`
-	Attribution               *Attribution `json:"attribution,omitempty" jsonschema:"description=Attribution settings for generated content"`
-	DisableMetrics            bool         `json:"disable_metrics,omitempty" jsonschema:"description=Disable sending metrics,default=false"`
-	InitializeAs              string       `json:"initialize_as,omitempty" jsonschema:"description=Name of the context file to create/update during project initialization,default=AGENTS.md,example=AGENTS.md,example=CRUSH.md,example=CLAUDE.md,example=docs/LLMs.md"`
-	AutoLSP                   *bool        `json:"auto_lsp,omitempty" jsonschema:"description=Automatically setup LSPs based on root markers,default=true"`
-	Progress                  *bool        `json:"progress,omitempty" jsonschema:"description=Show indeterminate progress updates during long operations,default=true"`
-	DisableNotifications      bool         `json:"disable_notifications,omitempty" jsonschema:"description=Disable desktop notifications,default=false"`
+	ContextPaths              []string      `json:"context_paths,omitempty" jsonschema:"description=Paths to files containing context information for the AI,example=.cursorrules,example=CRUSH.md"`
+	SkillsPaths               []string      `json:"skills_paths,omitempty" jsonschema:"description=Paths to directories containing Agent Skills (folders with SKILL.md files),example=~/.config/crush/skills,example=./skills"`
+	TUI                       *TUIOptions   `json:"tui,omitempty" jsonschema:"description=Terminal user interface options"`
+	Debug                     bool          `json:"debug,omitempty" jsonschema:"description=Enable debug logging,default=false"`
+	DebugLSP                  bool          `json:"debug_lsp,omitempty" jsonschema:"description=Enable debug logging for LSP servers,default=false"`
+	DisableAutoSummarize      bool          `json:"disable_auto_summarize,omitempty" jsonschema:"description=Disable automatic conversation summarization,default=false"`
+	DataDirectory             string        `json:"data_directory,omitempty" jsonschema:"description=Directory for storing application data (relative to working directory),default=.crush,example=.crush"` // Relative to the cwd
+	DisabledTools             []string      `json:"disabled_tools,omitempty" jsonschema:"description=List of built-in tools to disable and hide from the agent,example=bash,example=sourcegraph"`
+	DisableProviderAutoUpdate bool          `json:"disable_provider_auto_update,omitempty" jsonschema:"description=Disable providers auto-update,default=false"`
+	DisableDefaultProviders   bool          `json:"disable_default_providers,omitempty" jsonschema:"description=Ignore all default/embedded providers. When enabled, providers must be fully specified in the config file with base_url, models, and api_key - no merging with defaults occurs,default=false"`
+	Attribution               *Attribution  `json:"attribution,omitempty" jsonschema:"description=Attribution settings for generated content"`
+	DisableMetrics            bool          `json:"disable_metrics,omitempty" jsonschema:"description=Disable sending metrics,default=false"`
+	InitializeAs              string        `json:"initialize_as,omitempty" jsonschema:"description=Name of the context file to create/update during project initialization,default=AGENTS.md,example=AGENTS.md,example=CRUSH.md,example=CLAUDE.md,example=docs/LLMs.md"`
+	AutoLSP                   *bool         `json:"auto_lsp,omitempty" jsonschema:"description=Automatically setup LSPs based on root markers,default=true"`
+	Progress                  *bool         `json:"progress,omitempty" jsonschema:"description=Show indeterminate progress updates during long operations,default=true"`
+	DisableNotifications      bool          `json:"disable_notifications,omitempty" jsonschema:"description=Disable desktop notifications,default=false"`
+	LoopDetection             LoopDetection `json:"loop_detection,omitzero" jsonschema:"description=Detection of agents stuck repeating the same tool calls"`
+	DisableCheckpoints        bool          `json:"disable_checkpoints,omitempty" jsonschema:"description=Disable the snapshots of the working tree taken at the start of each agent turn,default=false"`
+}
+
+// LoopDetection configures how agents stuck repeating the same tool calls
+// are detected and stopped.
+type LoopDetection struct {
+	Disabled   bool `json:"disabled,omitempty" jsonschema:"description=Disable loop detection,default=false"`
+	WindowSize int  `json:"window_size,omitempty" jsonschema:"description=Number of recent steps checked for repeated tool calls,default=10,example=20"`
+	MaxRepeats int  `json:"max_repeats,omitempty" jsonschema:"description=Number of times the same tool call and result can repeat within the window before the agent is stopped,default=5,example=8"`
 }
 
 type MCPs map[string]MCPConfig
@@ -322,31 +385,58 @@ func (m MCPConfig) ResolvedHeaders() map[string]string {
 }
 
 type Agent struct {
-	ID          string `json:"id,omitempty"`
-	Name        string `json:"name,omitempty"`
-	Description string `json:"description,omitempty"`
-	// This is the id of the system prompt used by the agent
-	Disabled bool `json:"disabled,omitempty"`
-
-	Model SelectedModelType `json:"model" jsonschema:"required,description=The model type to use for this agent,enum=large,enum=small,default=large"`
+	ID          string `json:"id,omitempty" jsonschema:"-"`
+	Name        string `json:"name,omitempty" jsonschema:"description=Display name of the agent,example=Reviewer"`
+	Description string `json:"description,omitempty" jsonschema:"description=Short description of what the agent does,example=Reviews changes without modifying files"`
+	// This is the system prompt template used by the agent, if empty the
+	// default coder prompt is used.
+	SystemPrompt string `json:"system_prompt,omitempty" jsonschema:"description=Go template used as the system prompt of the agent (defaults to the coder prompt),example=You are a careful code reviewer working in {{.WorkingDir}}."`
+	// Path to a file containing the system prompt template, relative paths
+	// are resolved against the working directory.
+	SystemPromptFile string `json:"system_prompt_file,omitempty" jsonschema:"description=Path to a file containing the system prompt template of the agent,example=.crush/agents/reviewer.md"`
+	Disabled         bool   `json:"disabled,omitempty" jsonschema:"description=Disable this agent,default=false"`
+
+	Model SelectedModelType `json:"model,omitempty" jsonschema:"description=The model type to use for this agent,enum=large,enum=small,default=large"`
 
 	// The available tools for the agent
 	//  if this is nil, all tools are available
-	AllowedTools []string `json:"allowed_tools,omitempty"`
+	AllowedTools []string `json:"allowed_tools,omitempty" jsonschema:"description=Built-in tools available to this agent (defaults to all enabled tools),example=view,example=grep"`
 
 	// this tells us which MCPs are available for this agent
 	//  if this is empty all mcps are available
 	//  the string array is the list of tools from the AllowedMCP the agent has available
 	//  if the string array is nil, all tools from the AllowedMCP are available
-	AllowedMCP map[string][]string `json:"allowed_mcp,omitempty"`
+	AllowedMCP map[string][]string `json:"allowed_mcp,omitempty" jsonschema:"description=MCP servers and their tools available to this agent (an empty list allows all tools of a server)"`
 
 	// Overrides the context paths for this agent
-	ContextPaths []string `json:"context_paths,omitempty"`
+	ContextPaths []string `json:"context_paths,omitempty" jsonschema:"description=Context files for this agent (defaults to options.context_paths),example=REVIEW.md"`
+
+	Budget Budget `json:"budget,omitzero" jsonschema:"description=Limits for a single run of this agent"`
+}
+
+// Budget limits a single run of an agent, from a prompt until the agent ends
+// its turn. Zero values mean no limit.
+type Budget struct {
+	MaxSteps    int     `json:"max_steps,omitempty" jsonschema:"description=Maximum number of steps (model requests) per run,example=50"`
+	MaxTokens   int64   `json:"max_tokens,omitempty" jsonschema:"description=Maximum number of tokens used per run,example=2000000"`
+	MaxCost     float64 `json:"max_cost,omitempty" jsonschema:"description=Maximum cost in dollars per run,example=5"`
+	MaxDuration int     `json:"max_duration,omitempty" jsonschema:"description=Maximum wall-clock time in seconds per run,example=1800"`
+}
+
+// Override returns the budget with its limits replaced by the ones set in
+// other.
+func (b Budget) Override(other Budget) Budget {
+	b.MaxSteps = cmp.Or(other.MaxSteps, b.MaxSteps)
+	b.MaxTokens = cmp.Or(other.MaxTokens, b.MaxTokens)
+	b.MaxCost = cmp.Or(other.MaxCost, b.MaxCost)
+	b.MaxDuration = cmp.Or(other.MaxDuration, b.MaxDuration)
+	return b
 }
 
 type Tools struct {
-	Ls   ToolLs   `json:"ls,omitzero"`
-	Grep ToolGrep `json:"grep,omitzero"`
+	Ls        ToolLs        `json:"ls,omitzero"`
+	Grep      ToolGrep      `json:"grep,omitzero"`
+	WebSearch ToolWebSearch `json:"web_search,omitzero"`
 }
 
 type ToolLs struct {
@@ -368,6 +458,86 @@ func (t ToolGrep) GetTimeout() time.Duration {
 	return ptrValOr(t.Timeout, 5*time.Second)
 }
 
+type WebSearchProvider string
+
+const (
+	WebSearchDuckDuckGo WebSearchProvider = "duckduckgo"
+	WebSearchSearXNG    WebSearchProvider = "searxng"
+	WebSearchBrave      WebSearchProvider = "brave"
+	WebSearchKagi       WebSearchProvider = "kagi"
+	WebSearchTavily     WebSearchProvider = "tavily"
+	WebSearchJSON       WebSearchProvider = "json"
+)
+
+type ToolWebSearch struct {
+	Provider WebSearchProvider `json:"provider,omitempty" jsonschema:"description=Search backend used by the web_search tool,enum=duckduckgo,enum=searxng,enum=brave,enum=kagi,enum=tavily,enum=json,default=duckduckgo"`
+	// BaseURL is the URL of the search backend. It's required for SearXNG,
+	// the URL of the instance, and for generic JSON backends, where {query}
+	// and {max_results} are replaced by the query and the number of results
+	// wanted. Other backends default to their public API.
+	BaseURL string            `json:"base_url,omitempty" jsonschema:"description=URL of the search backend. Required for searxng and json backends. For json backends {query} and {max_results} are replaced,example=https://searx.example.com,example=https://search.example.com/api?q={query}&n={max_results}"`
+	APIKey  string            `json:"api_key,omitempty" jsonschema:"description=API key of the search backend. Supports environment variables,example=$BRAVE_API_KEY"`
+	Headers map[string]string `json:"headers,omitempty" jsonschema:"description=HTTP headers sent to the search backend. Supports environment variables"`
+	// Mapping tells where the results are in the responses of a generic JSON
+	// backend.
+	Mapping WebSearchMapping `json:"mapping,omitzero" jsonschema:"description=Where to find the results in the responses of a JSON backend"`
+}
+
+// WebSearchMapping maps the responses of a generic JSON search backend to
+// search results. Fields are dot-separated paths, like "data.items".
+type WebSearchMapping struct {
+	Results string `json:"results,omitempty" jsonschema:"description=Path to the list of results in the response,example=data.items"`
+	Title   string `json:"title,omitempty" jsonschema:"description=Path to the title in a result,default=title"`
+	URL     string `json:"url,omitempty" jsonschema:"description=Path to the URL in a result,default=url"`
+	Snippet string `json:"snippet,omitempty" jsonschema:"description=Path to the snippet in a result,default=snippet"`
+}
+
+// ResolvedAPIKey returns the API key with its variables resolved.
+func (t ToolWebSearch) ResolvedAPIKey() (string, error) {
+	if t.APIKey == "" {
+		return "", nil
+	}
+	return NewShellVariableResolver(env.New()).ResolveValue(t.APIKey)
+}
+
+// ResolvedHeaders returns the headers with their variables resolved.
+func (t ToolWebSearch) ResolvedHeaders() (map[string]string, error) {
+	resolver := NewShellVariableResolver(env.New())
+	headers := make(map[string]string, len(t.Headers))
+	for k, v := range t.Headers {
+		resolved, err := resolver.ResolveValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("header %s: %w", k, err)
+		}
+		headers[k] = resolved
+	}
+	return headers, nil
+}
+
+// Hook is a shell command run on a lifecycle event. The hook receives a JSON
+// payload describing the event on stdin.
+type Hook struct {
+	Matcher string `json:"matcher,omitempty" jsonschema:"description=Regular expression matched against the tool name (tool events only). Matches every tool when empty,example=^(edit|write|multiedit)$"`
+	Command string `json:"command" jsonschema:"required,description=Shell command to run,example=gofmt -l ."`
+	Timeout int    `json:"timeout,omitempty" jsonschema:"description=Timeout in seconds for the hook command,default=60,example=30"`
+}
+
+// GetTimeout returns the user-defined timeout or the default.
+func (h Hook) GetTimeout() time.Duration {
+	if h.Timeout <= 0 {
+		return 60 * time.Second
+	}
+	return time.Duration(h.Timeout) * time.Second
+}
+
+type Hooks struct {
+	PreToolUse       []Hook `json:"pre_tool_use,omitempty" jsonschema:"description=Hooks run before a tool is called. They can deny the call"`
+	PostToolUse      []Hook `json:"post_tool_use,omitempty" jsonschema:"description=Hooks run after a tool is called. They can append to the tool result"`
+	UserPromptSubmit []Hook `json:"user_prompt_submit,omitempty" jsonschema:"description=Hooks run when a prompt is submitted. They can deny the prompt or add context to it"`
+	Stop             []Hook `json:"stop,omitempty" jsonschema:"description=Hooks run when the agent finishes responding"`
+	SessionStart     []Hook `json:"session_start,omitempty" jsonschema:"description=Hooks run on the first prompt of a session. They can add context to it"`
+}
+
 // Config holds the configuration for crush.
 type Config struct {
 	Schema string `json:"$schema,omitempty"`
@@ -391,7 +561,34 @@ type Config struct {
 
 	Tools Tools `json:"tools,omitzero" jsonschema:"description=Tool configurations"`
 
-	Agents map[string]Agent `json:"-"`
+	Hooks Hooks `json:"hooks,omitempty" jsonschema:"description=Shell commands run on lifecycle events"`
+
+	Agents map[string]Agent `json:"agents,omitempty" jsonschema:"description=Agent definitions keyed by agent ID (built-in agents such as coder and task can be overridden)"`
+
+	// OAuth credentials of the MCP servers, stored in the data directory
+	// config.
+	MCPOAuth *csync.Map[string, mcpauth.Credentials] `json:"mcp_oauth,omitempty" jsonschema:"-"`
+
+	// userAgents are the agents as defined in the config, before
+	// SetupAgents resolves them into Agents.
+	userAgents map[string]Agent
+}
+
+// MCPCredentials returns the OAuth credentials of an MCP server, if it was
+// authorized.
+func (c *Config) MCPCredentials(name string) (mcpauth.Credentials, bool) {
+	if c.MCPOAuth == nil {
+		return mcpauth.Credentials{}, false
+	}
+	creds, ok := c.MCPOAuth.Get(name)
+	if !ok || creds.Token == nil {
+		return mcpauth.Credentials{}, false
+	}
+	// Credentials are only valid for the server they were issued for.
+	if m, ok := c.MCP[name]; ok && !mcpauth.SameServer(m.URL, creds.ServerURL) {
+		return mcpauth.Credentials{}, false
+	}
+	return creds, true
 }
 
 func (c *Config) EnabledProviders() []ProviderConfig {
@@ -466,8 +663,15 @@ func allToolNames() []string {
 		"download",
 		"edit",
 		"multiedit",
+		"notebook_edit",
 		"lsp_diagnostics",
 		"lsp_references",
+		"lsp_definition",
+		"lsp_implementation",
+		"lsp_hover",
+		"lsp_symbols",
+		"lsp_rename",
+		"lsp_code_action",
 		"lsp_restart",
 		"fetch",
 		"agentic_fetch",
@@ -480,6 +684,7 @@ func allToolNames() []string {
 		"write",
 		"list_mcp_resources",
 		"read_mcp_resource",
+		"plan",
 	}
 }
 
@@ -491,12 +696,20 @@ func resolveAllowedTools(allTools []string, disabledTools []string) []string {
 	return filterSlice(allTools, disabledTools, false)
 }
 
+var readOnlyTools = []string{"glob", "grep", "ls", "sourcegraph", "view"}
+
 func resolveReadOnlyTools(tools []string) []string {
-	readOnlyTools := []string{"glob", "grep", "ls", "sourcegraph", "view"}
 	// filter to only include tools that are in allowedtools (include mode)
 	return filterSlice(tools, readOnlyTools, true)
 }
 
+// PlanModeTools filters tools down to the ones available in plan mode: the
+// read-only tools, the agent tool, whose task agent is read-only too, and the
+// plan tool.
+func PlanModeTools(tools []string) []string {
+	return filterSlice(tools, append([]string{"agent", "plan"}, readOnlyTools...), true)
+}
+
 func filterSlice(data []string, mask []string, include bool) []string {
 	var filtered []string
 	for _, s := range data {
@@ -509,7 +722,17 @@ func filterSlice(data []string, mask []string, include bool) []string {
 	return filtered
 }
 
+// SetupAgents resolves the agents: the built-in ones, and the user-defined
+// ones, filled in from them. Agents are always resolved from the config as
+// loaded, so it can be called again, like once onboarding configured the
+// providers.
 func (c *Config) SetupAgents() {
+	if c.userAgents == nil {
+		c.userAgents = maps.Clone(c.Agents)
+		if c.userAgents == nil {
+			c.userAgents = map[string]Agent{}
+		}
+	}
 	allowedTools := resolveAllowedTools(allToolNames(), c.Options.DisabledTools)
 
 	agents := map[string]Agent{
@@ -533,9 +756,80 @@ func (c *Config) SetupAgents() {
 			AllowedMCP: map[string][]string{},
 		},
 	}
+
+	// User-defined agents either override one of the built-in agents or add
+	// a new one.
+	for id, agent := range c.userAgents {
+		agents[id] = resolveAgent(id, agent, agents[id], allowedTools, c.Options.ContextPaths)
+	}
+	// The coder agent is the fallback for every session, so it can't be
+	// disabled.
+	coder := agents[AgentCoder]
+	coder.Disabled = false
+	agents[AgentCoder] = coder
+
 	c.Agents = agents
 }
 
+// resolveAgent fills in the missing fields of a user-defined agent, taking
+// them from base (the built-in agent with the same ID, if any) or from the
+// global defaults.
+func resolveAgent(id string, agent, base Agent, allowedTools, contextPaths []string) Agent {
+	agent.ID = id
+	agent.Name = cmp.Or(agent.Name, base.Name, id)
+	agent.Description = cmp.Or(agent.Description, base.Description)
+	agent.SystemPrompt = cmp.Or(agent.SystemPrompt, base.SystemPrompt)
+	agent.SystemPromptFile = cmp.Or(agent.SystemPromptFile, base.SystemPromptFile)
+	agent.Model = cmp.Or(agent.Model, base.Model, SelectedModelTypeLarge)
+
+	switch {
+	case agent.AllowedTools != nil:
+		// Only keep tools that exist and are not globally disabled.
+		agent.AllowedTools = filterSlice(agent.AllowedTools, allowedTools, true)
+	case base.AllowedTools != nil:
+		agent.AllowedTools = base.AllowedTools
+	default:
+		agent.AllowedTools = allowedTools
+	}
+	if agent.AllowedMCP == nil {
+		agent.AllowedMCP = base.AllowedMCP
+	}
+	if agent.ContextPaths == nil {
+		agent.ContextPaths = base.ContextPaths
+	}
+	if agent.ContextPaths == nil {
+		agent.ContextPaths = contextPaths
+	}
+	agent.Budget = base.Budget.Override(agent.Budget)
+	return agent
+}
+
+// PrimaryAgents returns the enabled agents that can drive a session, sorted
+// by name. The task agent is excluded since it's only used as a sub-agent.
+func (c *Config) PrimaryAgents() []Agent {
+	var agents []Agent
+	for _, agent := range c.Agents {
+		if agent.Disabled || agent.ID == AgentTask {
+			continue
+		}
+		agents = append(agents, agent)
+	}
+	slices.SortFunc(agents, func(a, b Agent) int {
+		// Keep the coder agent first.
+		switch {
+		case a.ID == AgentCoder:
+			return -1
+		case b.ID == AgentCoder:
+			return 1
+		}
+		return cmp.Or(
+			cmp.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name)),
+			cmp.Compare(a.ID, b.ID),
+		)
+	})
+	return agents
+}
+
 func (c *ProviderConfig) TestConnection(resolver VariableResolver) error {
 	var (
 		providerID = catwalk.InferenceProvider(c.ID)
@@ -23,6 +23,7 @@ import (
 	"github.com/charmbracelet/crush/internal/fsext"
 	"github.com/charmbracelet/crush/internal/home"
 	"github.com/charmbracelet/crush/internal/log"
+	"github.com/charmbracelet/crush/internal/oauth/mcpauth"
 	powernapConfig "github.com/charmbracelet/x/powernap/pkg/config"
 	"github.com/qjebbs/go-jsons"
 )
@@ -395,6 +396,9 @@ func (c *Config) setDefaults(workingDir, dataDir string) {
 	if c.MCP == nil {
 		c.MCP = make(map[string]MCPConfig)
 	}
+	if c.MCPOAuth == nil {
+		c.MCPOAuth = csync.NewMap[string, mcpauth.Credentials]()
+	}
 	if c.LSP == nil {
 		c.LSP = make(map[string]LSPConfig)
 	}
@@ -3,6 +3,7 @@ package config
 import (
 	"io"
 	"log/slog"
+	"maps"
 	"os"
 	"path/filepath"
 	"testing"
@@ -490,7 +491,7 @@ func TestConfig_setupAgentsWithDisabledTools(t *testing.T) {
 	coderAgent, ok := cfg.Agents[AgentCoder]
 	require.True(t, ok)
 
-	assert.Equal(t, []string{"agent", "bash", "job_output", "job_kill", "multiedit", "lsp_diagnostics", "lsp_references", "lsp_restart", "fetch", "agentic_fetch", "glob", "ls", "sourcegraph", "todos", "view", "write", "list_mcp_resources", "read_mcp_resource"}, coderAgent.AllowedTools)
+	assert.Equal(t, []string{"agent", "bash", "job_output", "job_kill", "multiedit", "notebook_edit", "lsp_diagnostics", "lsp_references", "lsp_definition", "lsp_implementation", "lsp_hover", "lsp_symbols", "lsp_rename", "lsp_code_action", "lsp_restart", "fetch", "agentic_fetch", "glob", "ls", "sourcegraph", "todos", "view", "write", "list_mcp_resources", "read_mcp_resource", "plan"}, coderAgent.AllowedTools)
 
 	taskAgent, ok := cfg.Agents[AgentTask]
 	require.True(t, ok)
@@ -513,13 +514,98 @@ func TestConfig_setupAgentsWithEveryReadOnlyToolDisabled(t *testing.T) {
 	cfg.SetupAgents()
 	coderAgent, ok := cfg.Agents[AgentCoder]
 	require.True(t, ok)
-	assert.Equal(t, []string{"agent", "bash", "job_output", "job_kill", "download", "edit", "multiedit", "lsp_diagnostics", "lsp_references", "lsp_restart", "fetch", "agentic_fetch", "todos", "write", "list_mcp_resources", "read_mcp_resource"}, coderAgent.AllowedTools)
+	assert.Equal(t, []string{"agent", "bash", "job_output", "job_kill", "download", "edit", "multiedit", "notebook_edit", "lsp_diagnostics", "lsp_references", "lsp_definition", "lsp_implementation", "lsp_hover", "lsp_symbols", "lsp_rename", "lsp_code_action", "lsp_restart", "fetch", "agentic_fetch", "todos", "write", "list_mcp_resources", "read_mcp_resource", "plan"}, coderAgent.AllowedTools)
 
 	taskAgent, ok := cfg.Agents[AgentTask]
 	require.True(t, ok)
 	assert.Len(t, taskAgent.AllowedTools, 0)
 }
 
+func TestConfig_setupAgentsWithUserDefinedAgents(t *testing.T) {
+	cfg := &Config{
+		Options: &Options{
+			ContextPaths:  []string{"CRUSH.md"},
+			DisabledTools: []string{"bash"},
+		},
+		Agents: map[string]Agent{
+			"reviewer": {
+				Description:  "Reviews changes",
+				SystemPrompt: "You review code.",
+				Model:        SelectedModelTypeSmall,
+				AllowedTools: []string{"view", "grep", "bash", "unknown"},
+				ContextPaths: []string{"REVIEW.md"},
+				Budget:       Budget{MaxSteps: 50, MaxCost: 2},
+			},
+			"minimal": {},
+			AgentTask: {
+				AllowedTools: []string{"view"},
+			},
+			AgentCoder: {
+				Disabled: true,
+			},
+		},
+	}
+
+	cfg.SetupAgents()
+
+	reviewer, ok := cfg.Agents["reviewer"]
+	require.True(t, ok)
+	assert.Equal(t, "reviewer", reviewer.ID)
+	assert.Equal(t, "reviewer", reviewer.Name)
+	assert.Equal(t, "You review code.", reviewer.SystemPrompt)
+	assert.Equal(t, SelectedModelTypeSmall, reviewer.Model)
+	assert.Equal(t, []string{"view", "grep"}, reviewer.AllowedTools)
+	assert.Equal(t, []string{"REVIEW.md"}, reviewer.ContextPaths)
+	assert.Equal(t, Budget{MaxSteps: 50, MaxCost: 2}, reviewer.Budget)
+	assert.Nil(t, reviewer.AllowedMCP)
+
+	minimal, ok := cfg.Agents["minimal"]
+	require.True(t, ok)
+	assert.Equal(t, SelectedModelTypeLarge, minimal.Model)
+	assert.Equal(t, resolveAllowedTools(allToolNames(), []string{"bash"}), minimal.AllowedTools)
+	assert.Equal(t, []string{"CRUSH.md"}, minimal.ContextPaths)
+
+	taskAgent, ok := cfg.Agents[AgentTask]
+	require.True(t, ok)
+	assert.Equal(t, "Task", taskAgent.Name)
+	assert.Equal(t, []string{"view"}, taskAgent.AllowedTools)
+	assert.Equal(t, map[string][]string{}, taskAgent.AllowedMCP)
+
+	coderAgent, ok := cfg.Agents[AgentCoder]
+	require.True(t, ok)
+	assert.False(t, coderAgent.Disabled)
+	assert.Equal(t, "Coder", coderAgent.Name)
+
+	// Calling it again must not change the result.
+	agents := maps.Clone(cfg.Agents)
+	cfg.SetupAgents()
+	assert.Equal(t, agents, cfg.Agents)
+
+	// Agents are resolved again from the config as loaded, not from the
+	// resolved ones.
+	cfg.Options.DisabledTools = nil
+	cfg.SetupAgents()
+	assert.Equal(t, []string{"view", "grep", "bash"}, cfg.Agents["reviewer"].AllowedTools)
+	cfg.Options.DisabledTools = []string{"bash"}
+	cfg.SetupAgents()
+	assert.Equal(t, agents, cfg.Agents)
+
+	var ids []string
+	for _, agent := range cfg.PrimaryAgents() {
+		ids = append(ids, agent.ID)
+	}
+	assert.Equal(t, []string{AgentCoder, "minimal", "reviewer"}, ids)
+}
+
+func TestBudget_Override(t *testing.T) {
+	t.Parallel()
+
+	base := Budget{MaxSteps: 50, MaxCost: 2, MaxDuration: 600}
+	got := base.Override(Budget{MaxCost: 5, MaxTokens: 100_000})
+	assert.Equal(t, Budget{MaxSteps: 50, MaxTokens: 100_000, MaxCost: 5, MaxDuration: 600}, got)
+	assert.Equal(t, base, base.Override(Budget{}))
+}
+
 func TestConfig_configureProvidersWithDisabledProvider(t *testing.T) {
 	knownProviders := []catwalk.Provider{
 		{
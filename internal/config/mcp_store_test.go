@@ -0,0 +1,63 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMCPStore(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	cfg := &Config{}
+	cfg.setDefaults(dir, "")
+	store := testStoreWithPath(cfg, dir)
+
+	require.NoError(t, store.SetMCP(ScopeGlobal, "fs", MCPConfig{
+		Type:    MCPStdio,
+		Command: "mcp-fs",
+		Args:    []string{"."},
+	}))
+	require.Equal(t, "mcp-fs", cfg.MCP["fs"].Command)
+	fs := readConfigJSON(t, store.globalDataPath)["mcp"].(map[string]any)["fs"].(map[string]any)
+	require.Equal(t, "stdio", fs["type"])
+	require.Equal(t, "mcp-fs", fs["command"])
+
+	require.NoError(t, store.SetMCPDisabled(ScopeGlobal, "fs", true))
+	require.True(t, cfg.MCP["fs"].Disabled)
+	fs = readConfigJSON(t, store.globalDataPath)["mcp"].(map[string]any)["fs"].(map[string]any)
+	require.Equal(t, true, fs["disabled"])
+
+	// Only the list of the file is saved, not the merged one, which has the
+	// tools of the other config files too.
+	m := cfg.MCP["fs"]
+	m.DisabledTools = []string{"write"}
+	cfg.MCP["fs"] = m
+	require.NoError(t, store.SetMCPToolDisabled(ScopeGlobal, "fs", "read", true))
+	require.NoError(t, store.SetMCPToolDisabled(ScopeGlobal, "fs", "read", true))
+	require.Equal(t, []string{"write", "read"}, cfg.MCP["fs"].DisabledTools)
+	fs = readConfigJSON(t, store.globalDataPath)["mcp"].(map[string]any)["fs"].(map[string]any)
+	require.Equal(t, []any{"read"}, fs["disabled_tools"])
+
+	// Tools disabled in another file can't be enabled from this one.
+	require.ErrorContains(t, store.SetMCPToolDisabled(ScopeGlobal, "fs", "write", false), "another config file")
+	require.Equal(t, []string{"write", "read"}, cfg.MCP["fs"].DisabledTools)
+	require.NoError(t, store.SetMCPToolDisabled(ScopeGlobal, "fs", "read", false))
+	require.Equal(t, []string{"write"}, cfg.MCP["fs"].DisabledTools)
+	fs = readConfigJSON(t, store.globalDataPath)["mcp"].(map[string]any)["fs"].(map[string]any)
+	require.Equal(t, []any{}, fs["disabled_tools"])
+
+	scope, err := store.MCPScope("fs")
+	require.NoError(t, err)
+	require.Equal(t, ScopeGlobal, scope)
+	cfg.MCP["project"] = MCPConfig{Type: MCPStdio, Command: "mcp-project"}
+	_, err = store.MCPScope("project")
+	require.ErrorContains(t, err, "doesn't manage")
+
+	require.Error(t, store.SetMCPDisabled(ScopeGlobal, "missing", true))
+
+	require.NoError(t, store.RemoveMCP(ScopeGlobal, "fs"))
+	require.NotContains(t, cfg.MCP, "fs")
+	require.NotContains(t, readConfigJSON(t, store.globalDataPath)["mcp"], "fs")
+}
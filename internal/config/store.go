@@ -11,9 +11,11 @@ import (
 
 	"charm.land/catwalk/pkg/catwalk"
 	hyperp "github.com/charmbracelet/crush/internal/agent/hyper"
+	"github.com/charmbracelet/crush/internal/csync"
 	"github.com/charmbracelet/crush/internal/oauth"
 	"github.com/charmbracelet/crush/internal/oauth/copilot"
 	"github.com/charmbracelet/crush/internal/oauth/hyper"
+	"github.com/charmbracelet/crush/internal/oauth/mcpauth"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 )
@@ -76,11 +78,17 @@ func (s *ConfigStore) configPath(scope Scope) string {
 // HasConfigField checks whether a key exists in the config file for the given
 // scope.
 func (s *ConfigStore) HasConfigField(scope Scope, key string) bool {
+	return s.configField(scope, key).Exists()
+}
+
+// configField returns the value of a key in the config file for the given
+// scope.
+func (s *ConfigStore) configField(scope Scope, key string) gjson.Result {
 	data, err := os.ReadFile(s.configPath(scope))
 	if err != nil {
-		return false
+		return gjson.Result{}
 	}
-	return gjson.Get(string(data), key).Exists()
+	return gjson.Get(string(data), key)
 }
 
 // SetConfigField sets a key/value pair in the config file for the given scope.
@@ -132,6 +140,12 @@ func (s *ConfigStore) RemoveConfigField(scope Scope, key string) error {
 // UpdatePreferredModel updates the preferred model for the given type and
 // persists it to the config file at the given scope.
 func (s *ConfigStore) UpdatePreferredModel(scope Scope, modelType SelectedModelType, model SelectedModel) error {
+	// Switching models keeps the fallbacks, which are set for the model type
+	// rather than for a model.
+	if prev, ok := s.config.Models[modelType]; ok && model.Fallbacks == nil {
+		model.Fallbacks = prev.Fallbacks
+		model.FailoverOn = prev.FailoverOn
+	}
 	s.config.Models[modelType] = model
 	if err := s.SetConfigField(scope, fmt.Sprintf("models.%s", modelType), model); err != nil {
 		return fmt.Errorf("failed to update preferred model: %w", err)
@@ -260,6 +274,137 @@ func (s *ConfigStore) RefreshOAuthToken(ctx context.Context, scope Scope, provid
 	return nil
 }
 
+// SetMCPCredentials stores the OAuth credentials of an MCP server in the
+// global data config.
+func (s *ConfigStore) SetMCPCredentials(name string, creds mcpauth.Credentials) error {
+	if s.config.MCPOAuth == nil {
+		s.config.MCPOAuth = csync.NewMap[string, mcpauth.Credentials]()
+	}
+	s.config.MCPOAuth.Set(name, creds)
+	if err := s.SetConfigField(ScopeGlobal, fmt.Sprintf("mcp_oauth.%s", name), creds); err != nil {
+		return fmt.Errorf("failed to persist mcp credentials: %w", err)
+	}
+	return nil
+}
+
+// RemoveMCPCredentials forgets the OAuth credentials of an MCP server.
+func (s *ConfigStore) RemoveMCPCredentials(name string) error {
+	if s.config.MCPOAuth != nil {
+		s.config.MCPOAuth.Del(name)
+	}
+	if !s.HasConfigField(ScopeGlobal, fmt.Sprintf("mcp_oauth.%s", name)) {
+		return nil
+	}
+	return s.RemoveConfigField(ScopeGlobal, fmt.Sprintf("mcp_oauth.%s", name))
+}
+
+// SetMCP adds an MCP server to the config file for the given scope, or
+// replaces it.
+func (s *ConfigStore) SetMCP(scope Scope, name string, m MCPConfig) error {
+	if s.config.MCP == nil {
+		s.config.MCP = make(MCPs)
+	}
+	s.config.MCP[name] = m
+	if err := s.SetConfigField(scope, fmt.Sprintf("mcp.%s", name), m); err != nil {
+		return fmt.Errorf("failed to save mcp: %w", err)
+	}
+	return nil
+}
+
+// RemoveMCP removes an MCP server from the config file for the given scope.
+func (s *ConfigStore) RemoveMCP(scope Scope, name string) error {
+	delete(s.config.MCP, name)
+	if err := s.RemoveConfigField(scope, fmt.Sprintf("mcp.%s", name)); err != nil {
+		return fmt.Errorf("failed to remove mcp: %w", err)
+	}
+	return nil
+}
+
+// MCPScope returns the scope of the config file defining an MCP server, so
+// changes to it are saved next to its definition. It fails when the server
+// is only defined in files Crush doesn't manage, such as crush.json.
+func (s *ConfigStore) MCPScope(name string) (Scope, error) {
+	if _, ok := s.config.MCP[name]; !ok {
+		return 0, fmt.Errorf("mcp %q not configured", name)
+	}
+	key := "mcp." + name
+	switch {
+	case s.HasConfigField(ScopeWorkspace, key):
+		return ScopeWorkspace, nil
+	case s.HasConfigField(ScopeGlobal, key):
+		return ScopeGlobal, nil
+	default:
+		return 0, fmt.Errorf("mcp %q is defined in a config file Crush doesn't manage, change it there", name)
+	}
+}
+
+// SetMCPDisabled enables or disables an MCP server in the config file for
+// the given scope.
+func (s *ConfigStore) SetMCPDisabled(scope Scope, name string, disabled bool) error {
+	m, ok := s.config.MCP[name]
+	if !ok {
+		return fmt.Errorf("mcp %q not configured", name)
+	}
+	m.Disabled = disabled
+	s.config.MCP[name] = m
+	if err := s.SetConfigField(scope, fmt.Sprintf("mcp.%s.disabled", name), disabled); err != nil {
+		return fmt.Errorf("failed to update mcp: %w", err)
+	}
+	return nil
+}
+
+// SetMCPToolDisabled enables or disables a tool of an MCP server in the
+// config file for the given scope. A tool disabled in another config file
+// can't be enabled from this one.
+func (s *ConfigStore) SetMCPToolDisabled(scope Scope, name, tool string, disabled bool) error {
+	m, ok := s.config.MCP[name]
+	if !ok {
+		return fmt.Errorf("mcp %q not configured", name)
+	}
+	key := fmt.Sprintf("mcp.%s.disabled_tools", name)
+	listed := s.configField(scope, key).Array()
+	tools := make([]string, 0, len(listed)+1)
+	for _, t := range listed {
+		if t.String() != tool {
+			tools = append(tools, t.String())
+		}
+	}
+	// Lists of several config files are concatenated when loaded, so the
+	// tool is listed in another file if the merged list has it more often
+	// than this file's list.
+	inFile := len(listed) - len(tools)
+	if !disabled && countOf(m.DisabledTools, tool) > inFile {
+		return fmt.Errorf("tool %q of mcp %q is disabled in another config file, enable it there", tool, name)
+	}
+	if disabled {
+		tools = append(tools, tool)
+	}
+	if err := s.SetConfigField(scope, key, tools); err != nil {
+		return fmt.Errorf("failed to update mcp: %w", err)
+	}
+
+	switch {
+	case !disabled:
+		m.DisabledTools = slices.DeleteFunc(slices.Clone(m.DisabledTools), func(t string) bool {
+			return t == tool
+		})
+	case inFile == 0:
+		m.DisabledTools = append(slices.Clone(m.DisabledTools), tool)
+	}
+	s.config.MCP[name] = m
+	return nil
+}
+
+func countOf(values []string, value string) int {
+	n := 0
+	for _, v := range values {
+		if v == value {
+			n++
+		}
+	}
+	return n
+}
+
 // recordRecentModel records a model in the recent models list.
 func (s *ConfigStore) recordRecentModel(scope Scope, modelType SelectedModelType, model SelectedModel) error {
 	if model.Provider == "" || model.Model == "" {
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE sessions ADD COLUMN is_fork INTEGER DEFAULT 0 NOT NULL;
+
+-- +goose Down
+ALTER TABLE sessions DROP COLUMN is_fork;
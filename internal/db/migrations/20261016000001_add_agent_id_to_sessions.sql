@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE sessions ADD COLUMN agent_id TEXT;
+
+-- +goose Down
+ALTER TABLE sessions DROP COLUMN agent_id;
@@ -25,10 +25,29 @@ INSERT INTO messages (
 )
 RETURNING *;
 
+-- name: ImportMessage :one
+INSERT INTO messages (
+    id,
+    session_id,
+    role,
+    parts,
+    model,
+    provider,
+    is_summary_message,
+    created_at,
+    updated_at,
+    finished_at
+) VALUES (
+    ?, ?, ?, ?, ?, ?, ?, ?, ?, ?
+)
+RETURNING *;
+
 -- name: UpdateMessage :exec
 UPDATE messages
 SET
     parts = ?,
+    model = ?,
+    provider = ?,
     finished_at = ?,
     updated_at = strftime('%s', 'now')
 WHERE id = ?;
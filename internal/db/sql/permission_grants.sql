@@ -0,0 +1,42 @@
+-- name: CreatePermissionGrant :one
+INSERT INTO permission_grants (
+    id,
+    session_id,
+    project,
+    tool_name,
+    action,
+    path,
+    created_at
+) VALUES (
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    strftime('%s', 'now')
+) ON CONFLICT(session_id, tool_name, action, path) DO UPDATE SET
+    project = excluded.project
+RETURNING *;
+
+-- name: GetPermissionGrant :one
+SELECT * FROM permission_grants
+WHERE id = ? LIMIT 1;
+
+-- name: ListSessionPermissionGrants :many
+SELECT * FROM permission_grants
+WHERE session_id = ?
+ORDER BY created_at ASC;
+
+-- name: ListProjectPermissionGrants :many
+SELECT * FROM permission_grants
+WHERE project = ?
+ORDER BY created_at ASC;
+
+-- name: ListPermissionGrants :many
+SELECT * FROM permission_grants
+ORDER BY created_at ASC;
+
+-- name: DeletePermissionGrant :exec
+DELETE FROM permission_grants
+WHERE id = ?;
@@ -8,6 +8,7 @@ INSERT INTO sessions (
     completion_tokens,
     cost,
     summary_message_id,
+    is_fork,
     updated_at,
     created_at
 ) VALUES (
@@ -19,6 +20,7 @@ INSERT INTO sessions (
     ?,
     ?,
     null,
+    ?,
     strftime('%s', 'now'),
     strftime('%s', 'now')
 ) RETURNING *;
@@ -31,7 +33,7 @@ WHERE id = ? LIMIT 1;
 -- name: ListSessions :many
 SELECT *
 FROM sessions
-WHERE parent_session_id is NULL
+WHERE parent_session_id is NULL OR is_fork = 1
 ORDER BY updated_at DESC;
 
 -- name: UpdateSession :one
@@ -46,6 +48,12 @@ SET
 WHERE id = ?
 RETURNING *;
 
+-- name: UpdateSessionAgent :exec
+UPDATE sessions
+SET
+    agent_id = ?
+WHERE id = ?;
+
 -- name: UpdateSessionTitleAndUsage :exec
 UPDATE sessions
 SET
@@ -32,7 +32,7 @@ SELECT
     CAST(strftime('%H', created_at, 'unixepoch') AS INTEGER) as hour,
     COUNT(*) as session_count
 FROM sessions
-WHERE parent_session_id IS NULL
+WHERE (parent_session_id IS NULL OR is_fork = 1)
 GROUP BY day_of_week, hour
 ORDER BY day_of_week, hour
 `
@@ -73,7 +73,7 @@ SELECT
     SUM(prompt_tokens + completion_tokens) as total_tokens,
     SUM(cost) as cost
 FROM sessions
-WHERE parent_session_id IS NULL
+WHERE (parent_session_id IS NULL OR is_fork = 1)
   AND created_at >= strftime('%s', 'now', '-30 days')
 GROUP BY date(created_at, 'unixepoch')
 ORDER BY day ASC
@@ -163,7 +163,7 @@ SELECT
     COALESCE(AVG(prompt_tokens + completion_tokens), 0) as avg_tokens_per_session,
     COALESCE(AVG(message_count), 0) as avg_messages_per_session
 FROM sessions
-WHERE parent_session_id IS NULL
+WHERE (parent_session_id IS NULL OR is_fork = 1)
 `
 
 type GetTotalStatsRow struct {
@@ -199,7 +199,7 @@ SELECT
     SUM(cost) as cost,
     COUNT(*) as session_count
 FROM sessions
-WHERE parent_session_id IS NULL
+WHERE (parent_session_id IS NULL OR is_fork = 1)
 GROUP BY date(created_at, 'unixepoch')
 ORDER BY day DESC
 `
@@ -248,7 +248,7 @@ SELECT
     SUM(prompt_tokens) as prompt_tokens,
     SUM(completion_tokens) as completion_tokens
 FROM sessions
-WHERE parent_session_id IS NULL
+WHERE (parent_session_id IS NULL OR is_fork = 1)
 GROUP BY day_of_week
 ORDER BY day_of_week
 `
@@ -293,7 +293,7 @@ SELECT
     CAST(strftime('%H', created_at, 'unixepoch') AS INTEGER) as hour,
     COUNT(*) as session_count
 FROM sessions
-WHERE parent_session_id IS NULL
+WHERE (parent_session_id IS NULL OR is_fork = 1)
 GROUP BY hour
 ORDER BY hour
 `
@@ -0,0 +1,200 @@
+// Package fork copies a session, up to one of its messages, into a new
+// session, so an alternative approach can be tried without losing the
+// original conversation.
+package fork
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/charmbracelet/crush/internal/agent/tools"
+	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+)
+
+var (
+	ErrMessageNotFound = errors.New("message not found in session")
+	ErrNotUserMessage  = errors.New("only user messages can be forked before")
+)
+
+// Result describes a fork.
+type Result struct {
+	Session session.Session `json:"session"`
+	// Messages is the number of messages copied to the fork.
+	Messages int `json:"messages"`
+	// Files is the number of file versions copied to the fork.
+	Files int `json:"files"`
+}
+
+// Service forks sessions.
+type Service struct {
+	sessions session.Service
+	messages message.Service
+	history  history.Service
+}
+
+// NewService creates a new fork service.
+func NewService(sessions session.Service, messages message.Service, history history.Service) *Service {
+	return &Service{
+		sessions: sessions,
+		messages: messages,
+		history:  history,
+	}
+}
+
+// Busy reports whether the agent looks to be working on the session, from
+// another process: its last message is a tool result waiting for the next
+// step, or an answer still being written.
+func (s *Service) Busy(ctx context.Context, sessionID string) (bool, error) {
+	msgs, err := s.messages.List(ctx, sessionID)
+	if err != nil {
+		return false, fmt.Errorf("failed to list messages: %w", err)
+	}
+	if len(msgs) == 0 {
+		return false, nil
+	}
+	last := msgs[len(msgs)-1]
+	return last.Role == message.Tool || last.Role == message.Assistant && !last.IsFinished(), nil
+}
+
+// Fork copies the session up to and including the given message into a new
+// session, along with its todos and the file history as they were then.
+// When messageID is empty, the whole session is copied. Tool results are
+// never separated from the tool calls they answer.
+func (s *Service) Fork(ctx context.Context, sessionID, messageID string) (Result, error) {
+	msgs, err := s.messages.List(ctx, sessionID)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to list messages: %w", err)
+	}
+	end := len(msgs)
+	if messageID != "" {
+		idx := slices.IndexFunc(msgs, func(msg message.Message) bool {
+			return msg.ID == messageID
+		})
+		if idx == -1 {
+			return Result{}, fmt.Errorf("%w: %s", ErrMessageNotFound, messageID)
+		}
+		end = idx + 1
+		for end < len(msgs) && msgs[end].Role == message.Tool {
+			end++
+		}
+	}
+	return s.fork(ctx, sessionID, msgs, end)
+}
+
+// ForkBefore copies the session up to, but not including, the given user
+// message, so it can be edited and sent again in the fork.
+func (s *Service) ForkBefore(ctx context.Context, sessionID, messageID string) (Result, error) {
+	msgs, err := s.messages.List(ctx, sessionID)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to list messages: %w", err)
+	}
+	idx := slices.IndexFunc(msgs, func(msg message.Message) bool {
+		return msg.ID == messageID
+	})
+	if idx == -1 {
+		return Result{}, fmt.Errorf("%w: %s", ErrMessageNotFound, messageID)
+	}
+	if msgs[idx].Role != message.User {
+		return Result{}, ErrNotUserMessage
+	}
+	return s.fork(ctx, sessionID, msgs, idx)
+}
+
+// fork copies the first end messages of the session into a new session.
+func (s *Service) fork(ctx context.Context, sessionID string, msgs []message.Message, end int) (Result, error) {
+	sess, err := s.sessions.Get(ctx, sessionID)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	forked, err := s.sessions.CreateFork(ctx, sess.ID, sess.Title)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to create fork: %w", err)
+	}
+	result, err := s.copy(ctx, sess, forked, msgs, end)
+	if err != nil {
+		// Don't leave a half copied fork behind.
+		_ = s.sessions.Delete(ctx, forked.ID)
+		return Result{}, err
+	}
+	return result, nil
+}
+
+func (s *Service) copy(ctx context.Context, sess, forked session.Session, msgs []message.Message, end int) (Result, error) {
+	result := Result{}
+	ids := make(map[string]string, end)
+	for _, msg := range msgs[:end] {
+		copied, err := s.messages.Import(ctx, forked.ID, msg)
+		if err != nil {
+			return Result{}, fmt.Errorf("failed to copy message: %w", err)
+		}
+		ids[msg.ID] = copied.ID
+		result.Messages++
+	}
+
+	// Copy the file versions recorded before the first message left out.
+	files, err := s.history.ListBySession(ctx, sess.ID)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to list file history: %w", err)
+	}
+	for _, file := range files {
+		if file.RecordedDuring(msgs[end:]) {
+			continue
+		}
+		file.MessageID = ids[file.MessageID]
+		if _, err := s.history.Import(ctx, forked.ID, file); err != nil {
+			return Result{}, fmt.Errorf("failed to copy file history: %w", err)
+		}
+		result.Files++
+	}
+
+	// Usage stays with the original session, so it isn't counted twice.
+	forked.Todos = sess.Todos
+	if end < len(msgs) {
+		forked.Todos = todosAfter(msgs[:end])
+	}
+	forked.SummaryMessageID = ids[sess.SummaryMessageID]
+	forked, err = s.sessions.Save(ctx, forked)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to save fork: %w", err)
+	}
+	if sess.AgentID != "" {
+		if err := s.sessions.SetAgent(ctx, forked.ID, sess.AgentID); err != nil {
+			return Result{}, fmt.Errorf("failed to save fork: %w", err)
+		}
+		forked.AgentID = sess.AgentID
+	}
+	result.Session = forked
+	return result, nil
+}
+
+// todosAfter returns the todos of a session as they were after msgs, from
+// the results of the last tool that set them.
+func todosAfter(msgs []message.Message) []session.Todo {
+	for _, msg := range slices.Backward(msgs) {
+		for _, result := range slices.Backward(msg.ToolResults()) {
+			if result.IsError {
+				continue
+			}
+			switch result.Name {
+			case tools.TodosToolName:
+				var metadata tools.TodosResponseMetadata
+				if err := json.Unmarshal([]byte(result.Metadata), &metadata); err == nil {
+					return metadata.Todos
+				}
+			case tools.PlanToolName:
+				// Plans without steps leave the todos alone.
+				var metadata tools.PlanResponseMetadata
+				if err := json.Unmarshal([]byte(result.Metadata), &metadata); err == nil && len(metadata.Todos) > 0 {
+					return metadata.Todos
+				}
+			}
+		}
+	}
+	return nil
+}
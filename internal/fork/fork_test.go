@@ -0,0 +1,215 @@
+package fork
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/stretchr/testify/require"
+)
+
+var pendingTodos = []session.Todo{{Content: "Refactor", Status: session.TodoStatusPending}}
+
+type testEnv struct {
+	conn     *sql.DB
+	sessions session.Service
+	messages message.Service
+	history  history.Service
+	svc      *Service
+}
+
+func setupTest(t *testing.T) *testEnv {
+	t.Helper()
+
+	conn, err := db.Connect(t.Context(), t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	q := db.New(conn)
+	env := &testEnv{
+		conn:     conn,
+		sessions: session.NewService(q, conn),
+		messages: message.NewService(q),
+		history:  history.NewService(q, conn),
+	}
+	env.svc = NewService(env.sessions, env.messages, env.history)
+	return env
+}
+
+func (e *testEnv) createMessage(t *testing.T, sessionID string, role message.MessageRole, at int64, parts ...message.ContentPart) message.Message {
+	t.Helper()
+	msg, err := e.messages.Create(t.Context(), sessionID, message.CreateMessageParams{
+		Role:  role,
+		Parts: parts,
+	})
+	require.NoError(t, err)
+	_, err = e.conn.ExecContext(t.Context(), "UPDATE messages SET created_at = ? WHERE id = ?", at, msg.ID)
+	require.NoError(t, err)
+	msg.CreatedAt = at
+	return msg
+}
+
+func (e *testEnv) createVersion(t *testing.T, sessionID, path, content string, at int64) {
+	t.Helper()
+	file, err := e.history.CreateVersion(t.Context(), sessionID, path, content)
+	require.NoError(t, err)
+	_, err = e.conn.ExecContext(t.Context(), "UPDATE files SET created_at = ? WHERE id = ?", at, file.ID)
+	require.NoError(t, err)
+}
+
+// setupSession creates a session with two turns. The first one calls tools
+// that edit a file and add a todo, and the second one edits the file again
+// and starts the todo.
+func (e *testEnv) setupSession(t *testing.T) (session.Session, []message.Message) {
+	t.Helper()
+	sess, err := e.sessions.Create(t.Context(), "Refactor the parser")
+	require.NoError(t, err)
+
+	msgs := []message.Message{
+		e.createMessage(t, sess.ID, message.User, 100, message.TextContent{Text: "first"}),
+		e.createMessage(t, sess.ID, message.Assistant, 101,
+			message.ToolCall{ID: "call-1", Name: "edit", Input: "{}", Finished: true},
+			message.ToolCall{ID: "call-2", Name: "todos", Input: "{}", Finished: true},
+			message.Finish{Reason: message.FinishReasonToolUse},
+		),
+		e.createMessage(t, sess.ID, message.Tool, 102,
+			message.ToolResult{ToolCallID: "call-1", Name: "edit", Content: "ok"},
+			message.ToolResult{ToolCallID: "call-2", Name: "todos", Content: "ok", Metadata: `{"todos":[{"content":"Refactor","status":"pending","active_form":""}]}`},
+		),
+		e.createMessage(t, sess.ID, message.Assistant, 103, message.TextContent{Text: "done"}, message.Finish{Reason: message.FinishReasonEndTurn}),
+		e.createMessage(t, sess.ID, message.User, 200, message.TextContent{Text: "second"}),
+		e.createMessage(t, sess.ID, message.Assistant, 201, message.TextContent{Text: "done again"}, message.Finish{Reason: message.FinishReasonEndTurn}),
+	}
+	e.createVersion(t, sess.ID, "/tmp/parser.go", "original", 101)
+	e.createVersion(t, sess.ID, "/tmp/parser.go", "first edit", 101)
+	e.createVersion(t, sess.ID, "/tmp/parser.go", "second edit", 200)
+
+	sess.Todos = []session.Todo{{Content: "Refactor", Status: session.TodoStatusInProgress}}
+	sess.PromptTokens = 1000
+	sess.Cost = 0.5
+	sess, err = e.sessions.Save(t.Context(), sess)
+	require.NoError(t, err)
+	return sess, msgs
+}
+
+func (e *testEnv) requireFork(t *testing.T, original session.Session, result Result, want []message.Message, wantFiles []string, wantTodos []session.Todo) {
+	t.Helper()
+	ctx := t.Context()
+
+	forked, err := e.sessions.Get(ctx, result.Session.ID)
+	require.NoError(t, err)
+	require.True(t, forked.IsFork)
+	require.Equal(t, original.ID, forked.ParentSessionID)
+	require.Equal(t, original.Title, forked.Title)
+	require.Equal(t, wantTodos, forked.Todos)
+	require.Zero(t, forked.PromptTokens)
+	require.Zero(t, forked.Cost)
+
+	msgs, err := e.messages.List(ctx, forked.ID)
+	require.NoError(t, err)
+	require.Len(t, msgs, len(want))
+	require.Equal(t, len(want), result.Messages)
+	for i, msg := range msgs {
+		require.NotEqual(t, want[i].ID, msg.ID)
+		require.Equal(t, want[i].Role, msg.Role)
+		require.Equal(t, want[i].CreatedAt, msg.CreatedAt)
+		require.Equal(t, want[i].Parts, msg.Parts)
+	}
+
+	files, err := e.history.ListBySession(ctx, forked.ID)
+	require.NoError(t, err)
+	var contents []string
+	for _, file := range files {
+		contents = append(contents, file.Content)
+	}
+	require.Equal(t, wantFiles, contents)
+	require.Equal(t, len(wantFiles), result.Files)
+
+	// The original session is left alone.
+	msgs, err = e.messages.List(ctx, original.ID)
+	require.NoError(t, err)
+	require.Len(t, msgs, 6)
+}
+
+func TestFork(t *testing.T) {
+	t.Parallel()
+	env := setupTest(t)
+	sess, msgs := env.setupSession(t)
+
+	result, err := env.svc.Fork(t.Context(), sess.ID, "")
+	require.NoError(t, err)
+	env.requireFork(t, sess, result, msgs, []string{"original", "first edit", "second edit"}, sess.Todos)
+
+	// Forks are listed with top-level sessions.
+	sessions, err := env.sessions.List(t.Context())
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+}
+
+func TestForkAtMessage(t *testing.T) {
+	t.Parallel()
+	env := setupTest(t)
+	sess, msgs := env.setupSession(t)
+
+	// Forking at a tool call keeps its result, and the todos it set.
+	result, err := env.svc.Fork(t.Context(), sess.ID, msgs[1].ID)
+	require.NoError(t, err)
+	env.requireFork(t, sess, result, msgs[:3], []string{"original", "first edit"}, pendingTodos)
+
+	// Todos and file versions recorded later are left out.
+	result, err = env.svc.Fork(t.Context(), sess.ID, msgs[0].ID)
+	require.NoError(t, err)
+	env.requireFork(t, sess, result, msgs[:1], nil, []session.Todo{})
+}
+
+func TestForkBefore(t *testing.T) {
+	t.Parallel()
+	env := setupTest(t)
+	sess, msgs := env.setupSession(t)
+
+	result, err := env.svc.ForkBefore(t.Context(), sess.ID, msgs[4].ID)
+	require.NoError(t, err)
+	env.requireFork(t, sess, result, msgs[:4], []string{"original", "first edit"}, pendingTodos)
+
+	_, err = env.svc.ForkBefore(t.Context(), sess.ID, msgs[3].ID)
+	require.ErrorIs(t, err, ErrNotUserMessage)
+}
+
+func TestBusy(t *testing.T) {
+	t.Parallel()
+	env := setupTest(t)
+	sess, _ := env.setupSession(t)
+
+	busy, err := env.svc.Busy(t.Context(), sess.ID)
+	require.NoError(t, err)
+	require.False(t, busy)
+
+	// A tool call waiting for the next step.
+	env.createMessage(t, sess.ID, message.Assistant, 300, message.ToolCall{ID: "call-3", Name: "edit", Input: "{}", Finished: true}, message.Finish{Reason: message.FinishReasonToolUse})
+	env.createMessage(t, sess.ID, message.Tool, 301, message.ToolResult{ToolCallID: "call-3", Name: "edit", Content: "ok"})
+	busy, err = env.svc.Busy(t.Context(), sess.ID)
+	require.NoError(t, err)
+	require.True(t, busy)
+
+	// An answer being written.
+	env.createMessage(t, sess.ID, message.Assistant, 302, message.TextContent{Text: "almost"})
+	busy, err = env.svc.Busy(t.Context(), sess.ID)
+	require.NoError(t, err)
+	require.True(t, busy)
+}
+
+func TestForkMessageNotFound(t *testing.T) {
+	t.Parallel()
+	env := setupTest(t)
+	sess, _ := env.setupSession(t)
+
+	_, err := env.svc.Fork(t.Context(), sess.ID, "nope")
+	require.ErrorIs(t, err, ErrMessageNotFound)
+
+	sessions, err := env.sessions.List(t.Context())
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+}
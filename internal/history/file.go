@@ -4,9 +4,11 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/message"
 	"github.com/charmbracelet/crush/internal/pubsub"
 	"github.com/google/uuid"
 )
@@ -23,6 +25,44 @@ type File struct {
 	Version   int64
 	CreatedAt int64
 	UpdatedAt int64
+	// MessageID is the assistant message the version was recorded for, if
+	// any.
+	MessageID string
+	// IsNew is set on the initial version of files that didn't exist before
+	// the session created them.
+	IsNew bool
+}
+
+// RecordedDuring reports whether the version was recorded while the agent
+// answered one of the given messages, which must be the tail of the session.
+// Versions recorded without a message are compared against the time of the
+// first one instead.
+func (f File) RecordedDuring(msgs []message.Message) bool {
+	if len(msgs) == 0 {
+		return false
+	}
+	if f.MessageID == "" {
+		// Versions recorded in the same second as the message are
+		// considered newer, since the agent may have run tools right after
+		// it was sent.
+		return f.CreatedAt >= msgs[0].CreatedAt
+	}
+	return slices.ContainsFunc(msgs, func(msg message.Message) bool {
+		return msg.ID == f.MessageID
+	})
+}
+
+type messageIDKey struct{}
+
+// WithMessageID returns a context recording file versions for the given
+// assistant message.
+func WithMessageID(ctx context.Context, messageID string) context.Context {
+	return context.WithValue(ctx, messageIDKey{}, messageID)
+}
+
+func messageIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(messageIDKey{}).(string)
+	return id
 }
 
 // Service manages file versions and history for sessions.
@@ -30,9 +70,17 @@ type Service interface {
 	pubsub.Subscriber[File]
 	Create(ctx context.Context, sessionID, path, content string) (File, error)
 
+	// CreateNew creates the initial, empty version of a file the session is
+	// about to create.
+	CreateNew(ctx context.Context, sessionID, path string) (File, error)
+
 	// CreateVersion creates a new version of a file.
 	CreateVersion(ctx context.Context, sessionID, path, content string) (File, error)
 
+	// Import adds a version of a file from another session, keeping its
+	// version number and timestamps.
+	Import(ctx context.Context, sessionID string, file File) (File, error)
+
 	Get(ctx context.Context, id string) (File, error)
 	GetByPathAndSession(ctx context.Context, path, sessionID string) (File, error)
 	ListBySession(ctx context.Context, sessionID string) ([]File, error)
@@ -56,7 +104,11 @@ func NewService(q *db.Queries, db *sql.DB) Service {
 }
 
 func (s *service) Create(ctx context.Context, sessionID, path, content string) (File, error) {
-	return s.createWithVersion(ctx, sessionID, path, content, InitialVersion)
+	return s.createWithVersion(ctx, sessionID, path, content, InitialVersion, false)
+}
+
+func (s *service) CreateNew(ctx context.Context, sessionID, path string) (File, error) {
+	return s.createWithVersion(ctx, sessionID, path, "", InitialVersion, true)
 }
 
 // CreateVersion creates a new version of a file with auto-incremented version
@@ -78,10 +130,10 @@ func (s *service) CreateVersion(ctx context.Context, sessionID, path, content st
 	latestFile := files[0] // Files are ordered by version DESC, created_at DESC
 	nextVersion := latestFile.Version + 1
 
-	return s.createWithVersion(ctx, sessionID, path, content, nextVersion)
+	return s.createWithVersion(ctx, sessionID, path, content, nextVersion, false)
 }
 
-func (s *service) createWithVersion(ctx context.Context, sessionID, path, content string, version int64) (File, error) {
+func (s *service) createWithVersion(ctx context.Context, sessionID, path, content string, version int64, isNew bool) (File, error) {
 	// Maximum number of retries for transaction conflicts
 	const maxRetries = 3
 	var file File
@@ -105,6 +157,8 @@ func (s *service) createWithVersion(ctx context.Context, sessionID, path, conten
 			Path:      path,
 			Content:   content,
 			Version:   version,
+			MessageID: toNullString(messageIDFromContext(ctx)),
+			IsNew:     boolToInt(isNew),
 		})
 		if txErr != nil {
 			// Rollback the transaction
@@ -134,6 +188,26 @@ func (s *service) createWithVersion(ctx context.Context, sessionID, path, conten
 	return file, err
 }
 
+func (s *service) Import(ctx context.Context, sessionID string, file File) (File, error) {
+	dbFile, err := s.q.ImportFile(ctx, db.ImportFileParams{
+		ID:        uuid.New().String(),
+		SessionID: sessionID,
+		Path:      file.Path,
+		Content:   file.Content,
+		Version:   file.Version,
+		MessageID: toNullString(file.MessageID),
+		IsNew:     boolToInt(file.IsNew),
+		CreatedAt: file.CreatedAt,
+		UpdatedAt: file.UpdatedAt,
+	})
+	if err != nil {
+		return File{}, err
+	}
+	imported := s.fromDBItem(dbFile)
+	s.Publish(pubsub.CreatedEvent, imported)
+	return imported, nil
+}
+
 func (s *service) Get(ctx context.Context, id string) (File, error) {
 	dbFile, err := s.q.GetFile(ctx, id)
 	if err != nil {
@@ -213,5 +287,18 @@ func (s *service) fromDBItem(item db.File) File {
 		Version:   item.Version,
 		CreatedAt: item.CreatedAt,
 		UpdatedAt: item.UpdatedAt,
+		MessageID: item.MessageID.String,
+		IsNew:     item.IsNew != 0,
+	}
+}
+
+func toNullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func boolToInt(b bool) int64 {
+	if b {
+		return 1
 	}
+	return 0
 }
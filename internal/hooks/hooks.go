@@ -0,0 +1,230 @@
+// Package hooks runs the user-defined shell commands configured for the
+// agent lifecycle events.
+//
+// Every hook receives a JSON [Payload] on stdin. A hook allows the event by
+// exiting with status 0, and denies it by exiting with status 2, in which case
+// stderr (or stdout) is used as the reason. Any other exit status is logged
+// and otherwise ignored. On success, a hook can print a JSON [Output] to stdout
+// to make the decision explicit; any other output is used as extra context.
+package hooks
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/shell"
+)
+
+// Event is a lifecycle event hooks can run on.
+type Event string
+
+const (
+	PreToolUse       Event = "PreToolUse"
+	PostToolUse      Event = "PostToolUse"
+	UserPromptSubmit Event = "UserPromptSubmit"
+	Stop             Event = "Stop"
+	SessionStart     Event = "SessionStart"
+)
+
+// denyExitCode is the exit code hooks use to deny an event.
+const denyExitCode = 2
+
+// Decision is the outcome of running the hooks of an event.
+type Decision string
+
+const (
+	DecisionAllow Decision = "allow"
+	DecisionDeny  Decision = "deny"
+)
+
+// Payload is the JSON document passed to hooks on stdin.
+type Payload struct {
+	Event        Event           `json:"event"`
+	SessionID    string          `json:"session_id,omitempty"`
+	WorkingDir   string          `json:"cwd"`
+	ToolName     string          `json:"tool_name,omitempty"`
+	ToolCallID   string          `json:"tool_call_id,omitempty"`
+	ToolInput    json.RawMessage `json:"tool_input,omitempty"`
+	ToolResponse *ToolResponse   `json:"tool_response,omitempty"`
+	Prompt       string          `json:"prompt,omitempty"`
+	StopReason   string          `json:"stop_reason,omitempty"`
+}
+
+// ToolResponse is the result of a tool call, as passed to PostToolUse hooks.
+type ToolResponse struct {
+	Content string `json:"content"`
+	IsError bool   `json:"is_error"`
+}
+
+// Output is the optional JSON document hooks can print to stdout.
+type Output struct {
+	Decision Decision `json:"decision,omitempty"`
+	Reason   string   `json:"reason,omitempty"`
+	Context  string   `json:"context,omitempty"`
+}
+
+// Result is the combined outcome of all the hooks run for an event.
+type Result struct {
+	Decision Decision
+	// Reason explains why the event was denied.
+	Reason string
+	// Context is extra text to append to the prompt or tool result.
+	Context string
+}
+
+// Denied returns whether any of the hooks denied the event.
+func (r Result) Denied() bool {
+	return r.Decision == DecisionDeny
+}
+
+// Runner runs the hooks configured in the config store.
+type Runner struct {
+	cfg *config.ConfigStore
+}
+
+// NewRunner creates a new hook runner.
+func NewRunner(cfg *config.ConfigStore) *Runner {
+	return &Runner{cfg: cfg}
+}
+
+// Has returns whether any hooks are configured for the given event.
+func (r *Runner) Has(event Event) bool {
+	return len(r.hooks(event)) > 0
+}
+
+func (r *Runner) hooks(event Event) []config.Hook {
+	hooks := r.cfg.Config().Hooks
+	switch event {
+	case PreToolUse:
+		return hooks.PreToolUse
+	case PostToolUse:
+		return hooks.PostToolUse
+	case UserPromptSubmit:
+		return hooks.UserPromptSubmit
+	case Stop:
+		return hooks.Stop
+	case SessionStart:
+		return hooks.SessionStart
+	default:
+		return nil
+	}
+}
+
+// Run runs every hook matching the payload, in order. It stops at the first
+// hook that denies the event.
+func (r *Runner) Run(ctx context.Context, payload Payload) Result {
+	result := Result{Decision: DecisionAllow}
+	hooks := r.hooks(payload.Event)
+	if len(hooks) == 0 {
+		return result
+	}
+
+	payload.WorkingDir = cmp.Or(payload.WorkingDir, r.cfg.WorkingDir())
+	input, err := json.Marshal(payload)
+	if err != nil {
+		slog.Error("Failed to marshal hook payload", "event", payload.Event, "error", err)
+		return result
+	}
+
+	var contexts []string
+	for _, hook := range hooks {
+		if !matches(hook, payload) {
+			continue
+		}
+		output, ok := r.runHook(ctx, hook, payload, input)
+		if !ok {
+			continue
+		}
+		if output.Context != "" {
+			contexts = append(contexts, output.Context)
+		}
+		if output.Decision == DecisionDeny {
+			result.Decision = DecisionDeny
+			result.Reason = cmp.Or(output.Reason, "denied by hook: "+hook.Command)
+			break
+		}
+	}
+	result.Context = strings.Join(contexts, "\n")
+	return result
+}
+
+// runHook runs a single hook. It returns false if the hook failed, in which
+// case its output must be ignored.
+func (r *Runner) runHook(ctx context.Context, hook config.Hook, payload Payload, input []byte) (Output, bool) {
+	ctx, cancel := context.WithTimeout(ctx, hook.GetTimeout())
+	defer cancel()
+
+	env := append(
+		os.Environ(),
+		"CRUSH_HOOK_EVENT="+string(payload.Event),
+		"CRUSH_SESSION_ID="+payload.SessionID,
+		"CRUSH_TOOL_NAME="+payload.ToolName,
+	)
+	sh := shell.NewShell(&shell.Options{
+		WorkingDir: payload.WorkingDir,
+		Env:        env,
+	})
+	stdout, stderr, err := sh.ExecWithInput(ctx, hook.Command, bytes.NewReader(input))
+	stdout = strings.TrimSpace(stdout)
+	stderr = strings.TrimSpace(stderr)
+
+	switch code := shell.ExitCode(err); {
+	case err == nil:
+		return parseOutput(stdout), true
+	case code == denyExitCode && !shell.IsInterrupt(err):
+		return Output{
+			Decision: DecisionDeny,
+			Reason:   cmp.Or(stderr, stdout),
+		}, true
+	default:
+		slog.Warn(
+			"Hook failed",
+			"event", payload.Event,
+			"command", hook.Command,
+			"exit_code", code,
+			"stderr", stderr,
+			"error", err,
+		)
+		return Output{}, false
+	}
+}
+
+// parseOutput parses the stdout of a successful hook. Only a JSON object with
+// a decision is taken as an [Output] document, so hooks printing other JSON
+// have it used as context, like any other output.
+func parseOutput(stdout string) Output {
+	var output Output
+	if err := json.Unmarshal([]byte(stdout), &output); err == nil {
+		switch output.Decision {
+		case DecisionAllow, DecisionDeny:
+			return output
+		}
+	}
+	return Output{Context: stdout}
+}
+
+func matches(hook config.Hook, payload Payload) bool {
+	if hook.Matcher == "" || payload.ToolName == "" {
+		return true
+	}
+	re, err := regexp.Compile(hook.Matcher)
+	if err != nil {
+		slog.Warn("Invalid hook matcher", "matcher", hook.Matcher, "error", err)
+		return false
+	}
+	return re.MatchString(payload.ToolName)
+}
+
+// FormatContext wraps hook context so the model can tell it apart from the
+// rest of the content.
+func FormatContext(event Event, text string) string {
+	return fmt.Sprintf("<hook event=%q>\n%s\n</hook>", event, text)
+}
@@ -0,0 +1,148 @@
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/agent/tools"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRunner(t *testing.T, hooks config.Hooks) *Runner {
+	t.Helper()
+	cfg, err := config.Init(t.TempDir(), "", false)
+	require.NoError(t, err)
+	cfg.Config().Hooks = hooks
+	return NewRunner(cfg)
+}
+
+func TestRunner(t *testing.T) {
+	t.Run("no hooks", func(t *testing.T) {
+		r := newTestRunner(t, config.Hooks{})
+		result := r.Run(t.Context(), Payload{Event: PreToolUse, ToolName: "bash"})
+		require.False(t, result.Denied())
+		require.Empty(t, result.Context)
+	})
+
+	t.Run("payload on stdin", func(t *testing.T) {
+		r := newTestRunner(t, config.Hooks{
+			UserPromptSubmit: []config.Hook{{Command: `read -r line; echo "$line"`}},
+		})
+		result := r.Run(t.Context(), Payload{Event: UserPromptSubmit, SessionID: "s1", Prompt: "hello"})
+		require.False(t, result.Denied())
+
+		// The payload has no decision, so it's used as context.
+		var payload Payload
+		require.NoError(t, json.Unmarshal([]byte(result.Context), &payload))
+		require.Equal(t, UserPromptSubmit, payload.Event)
+		require.Equal(t, "s1", payload.SessionID)
+		require.Equal(t, "hello", payload.Prompt)
+		require.NotEmpty(t, payload.WorkingDir)
+	})
+
+	t.Run("runs in the working dir of the payload", func(t *testing.T) {
+		dir := t.TempDir()
+		r := newTestRunner(t, config.Hooks{
+			Stop: []config.Hook{{Command: `pwd`}},
+		})
+		result := r.Run(t.Context(), Payload{Event: Stop, WorkingDir: dir})
+		require.Equal(t, dir, result.Context)
+	})
+
+	t.Run("exit code 2 denies", func(t *testing.T) {
+		r := newTestRunner(t, config.Hooks{
+			PreToolUse: []config.Hook{
+				{Command: `echo "not allowed" >&2; exit 2`},
+				{Command: `echo "never run"`},
+			},
+		})
+		result := r.Run(t.Context(), Payload{Event: PreToolUse, ToolName: "bash"})
+		require.True(t, result.Denied())
+		require.Equal(t, "not allowed", result.Reason)
+		require.Empty(t, result.Context)
+	})
+
+	t.Run("other exit codes are ignored", func(t *testing.T) {
+		r := newTestRunner(t, config.Hooks{
+			PreToolUse: []config.Hook{{Command: `echo "oops"; exit 1`}},
+		})
+		result := r.Run(t.Context(), Payload{Event: PreToolUse, ToolName: "bash"})
+		require.False(t, result.Denied())
+		require.Empty(t, result.Context)
+	})
+
+	t.Run("json output", func(t *testing.T) {
+		r := newTestRunner(t, config.Hooks{
+			PreToolUse: []config.Hook{{Command: `echo '{"decision":"deny","reason":"use go test","context":"see docs"}'`}},
+		})
+		result := r.Run(t.Context(), Payload{Event: PreToolUse, ToolName: "bash"})
+		require.True(t, result.Denied())
+		require.Equal(t, "use go test", result.Reason)
+		require.Equal(t, "see docs", result.Context)
+	})
+
+	t.Run("json output without decision", func(t *testing.T) {
+		r := newTestRunner(t, config.Hooks{
+			PostToolUse: []config.Hook{
+				{Command: `echo '{"files":["a.go"]}'`},
+				{Command: `echo '{"decision":"maybe"}'`},
+			},
+		})
+		result := r.Run(t.Context(), Payload{Event: PostToolUse, ToolName: "bash"})
+		require.False(t, result.Denied())
+		require.Equal(t, "{\"files\":[\"a.go\"]}\n{\"decision\":\"maybe\"}", result.Context)
+	})
+
+	t.Run("matcher", func(t *testing.T) {
+		r := newTestRunner(t, config.Hooks{
+			PostToolUse: []config.Hook{
+				{Matcher: "^(edit|write)$", Command: "echo formatted"},
+				{Matcher: "^bash$", Command: "echo linted"},
+			},
+		})
+		result := r.Run(t.Context(), Payload{Event: PostToolUse, ToolName: "write"})
+		require.Equal(t, "formatted", result.Context)
+	})
+}
+
+func TestWrapTool(t *testing.T) {
+	newTool := func(ran *bool) fantasy.AgentTool {
+		return fantasy.NewAgentTool("bash", "Runs commands", func(ctx context.Context, input struct {
+			Command string `json:"command"`
+		}, call fantasy.ToolCall,
+		) (fantasy.ToolResponse, error) {
+			*ran = true
+			return fantasy.NewTextResponse("ran " + input.Command), nil
+		})
+	}
+	ctx := context.WithValue(t.Context(), tools.SessionIDContextKey, "s1")
+	call := fantasy.ToolCall{ID: "call-1", Name: "bash", Input: `{"command":"rm -rf /"}`}
+
+	t.Run("pre tool use denies", func(t *testing.T) {
+		r := newTestRunner(t, config.Hooks{
+			PreToolUse: []config.Hook{{Matcher: "bash", Command: `read -r line; case "$line" in *"rm -rf"*) echo "dangerous" >&2; exit 2;; esac`}},
+		})
+		var ran bool
+		resp, err := r.WrapTool(newTool(&ran)).Run(ctx, call)
+		require.NoError(t, err)
+		require.False(t, ran)
+		require.True(t, resp.IsError)
+		require.Contains(t, resp.Content, "dangerous")
+	})
+
+	t.Run("post tool use appends", func(t *testing.T) {
+		r := newTestRunner(t, config.Hooks{
+			PostToolUse: []config.Hook{{Command: "echo checked"}},
+		})
+		var ran bool
+		resp, err := r.WrapTool(newTool(&ran)).Run(ctx, call)
+		require.NoError(t, err)
+		require.True(t, ran)
+		require.False(t, resp.IsError)
+		require.Contains(t, resp.Content, "ran rm -rf /")
+		require.Contains(t, resp.Content, "checked")
+	})
+}
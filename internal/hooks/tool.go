@@ -0,0 +1,61 @@
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/agent/tools"
+)
+
+// hookedTool runs the PreToolUse and PostToolUse hooks around a tool.
+type hookedTool struct {
+	fantasy.AgentTool
+	runner *Runner
+}
+
+// WrapTool returns a tool that runs the tool hooks around the given tool.
+func (r *Runner) WrapTool(tool fantasy.AgentTool) fantasy.AgentTool {
+	return &hookedTool{AgentTool: tool, runner: r}
+}
+
+// Run implements [fantasy.AgentTool].
+func (t *hookedTool) Run(ctx context.Context, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+	payload := Payload{
+		SessionID:  tools.GetSessionFromContext(ctx),
+		WorkingDir: tools.GetWorkingDirFromContext(ctx, ""),
+		ToolName:   t.Info().Name,
+		ToolCallID: call.ID,
+	}
+	if json.Valid([]byte(call.Input)) {
+		payload.ToolInput = json.RawMessage(call.Input)
+	}
+
+	if t.runner.Has(PreToolUse) {
+		payload.Event = PreToolUse
+		result := t.runner.Run(ctx, payload)
+		if result.Denied() {
+			return fantasy.NewTextErrorResponse("Tool call blocked by hook: " + result.Reason), nil
+		}
+	}
+
+	response, err := t.AgentTool.Run(ctx, call)
+	if err != nil || !t.runner.Has(PostToolUse) {
+		return response, err
+	}
+
+	payload.Event = PostToolUse
+	payload.ToolResponse = &ToolResponse{
+		Content: response.Content,
+		IsError: response.IsError,
+	}
+	result := t.runner.Run(ctx, payload)
+	if result.Context != "" {
+		response.Content += "\n\n" + FormatContext(PostToolUse, result.Context)
+	}
+	if result.Denied() {
+		response.IsError = true
+		response.Content += "\n\n" + FormatContext(PostToolUse, result.Reason)
+	}
+	return response, nil
+}
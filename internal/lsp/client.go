@@ -29,6 +29,10 @@ type DiagnosticCounts struct {
 	Hint        int
 }
 
+// Client is a connection to a language server. Requests go through the
+// powernap client, which only sends completion, hover and references requests.
+// Others, like document and workspace symbols, are sent through its
+// connection with [Client.call].
 type Client struct {
 	client *powernap.Client
 	name   string
@@ -561,3 +565,145 @@ func (c *Client) FindReferences(ctx context.Context, filepath string, line, char
 	// See: https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#position
 	return c.client.FindReferences(ctx, filepath, line-1, character-1, includeDeclaration)
 }
+
+// FindDefinitions finds where the symbol at the given position is declared.
+// For servers not supporting definition requests, declarations are worked
+// out from the references: they're the ones only returned when declarations
+// are included. This finds nothing for symbols declared outside the
+// workspace, and mixes up overloads.
+func (c *Client) FindDefinitions(ctx context.Context, filepath string, line, character int) ([]protocol.Location, error) {
+	if provider := c.client.GetCapabilities().DefinitionProvider; provider != nil && provider.Value != false {
+		return c.findLocations(ctx, "textDocument/definition", filepath, line, character)
+	}
+
+	withDeclaration, err := c.FindReferences(ctx, filepath, line, character, true)
+	if err != nil {
+		return nil, err
+	}
+	withoutDeclaration, err := c.FindReferences(ctx, filepath, line, character, false)
+	if err != nil {
+		return nil, err
+	}
+
+	type key struct {
+		uri   protocol.DocumentURI
+		start protocol.Position
+	}
+	references := make(map[key]bool, len(withoutDeclaration))
+	for _, loc := range withoutDeclaration {
+		references[key{loc.URI, loc.Range.Start}] = true
+	}
+	var definitions []protocol.Location
+	for _, loc := range withDeclaration {
+		if !references[key{loc.URI, loc.Range.Start}] {
+			definitions = append(definitions, loc)
+		}
+	}
+	return definitions, nil
+}
+
+// FindImplementations finds the implementations of the interface, or of the
+// abstract method, at the given position.
+func (c *Client) FindImplementations(ctx context.Context, filepath string, line, character int) ([]protocol.Location, error) {
+	if provider := c.client.GetCapabilities().ImplementationProvider; provider == nil || provider.Value == false {
+		return nil, fmt.Errorf("%s doesn't support finding implementations", c.name)
+	}
+	return c.findLocations(ctx, "textDocument/implementation", filepath, line, character)
+}
+
+// findLocations sends a request for the locations related to the symbol at
+// the given position, like textDocument/definition.
+func (c *Client) findLocations(ctx context.Context, method, filepath string, line, character int) ([]protocol.Location, error) {
+	if err := c.OpenFileOnDemand(ctx, filepath); err != nil {
+		return nil, err
+	}
+
+	// Add timeout to prevent hanging on slow LSP servers.
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// NOTE: line and character should be 0-based.
+	params := protocol.TextDocumentPositionParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: protocol.URIFromPath(filepath)},
+		Position: protocol.Position{
+			Line:      uint32(line - 1),      //nolint:gosec
+			Character: uint32(character - 1), //nolint:gosec
+		},
+	}
+	var result json.RawMessage
+	if err := c.call(ctx, method, params, &result); err != nil {
+		return nil, err
+	}
+	return decodeLocations(result)
+}
+
+// Hover returns the hover information of the symbol at the given position,
+// usually its signature and documentation.
+func (c *Client) Hover(ctx context.Context, filepath string, line, character int) (*protocol.Hover, error) {
+	if err := c.OpenFileOnDemand(ctx, filepath); err != nil {
+		return nil, err
+	}
+
+	// Add timeout to prevent hanging on slow LSP servers.
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// NOTE: line and character should be 0-based.
+	return c.client.RequestHover(ctx, string(protocol.URIFromPath(filepath)), protocol.Position{
+		Line:      uint32(line - 1),      //nolint:gosec
+		Character: uint32(character - 1), //nolint:gosec
+	})
+}
+
+// Rename returns the edit renaming the symbol at the given position, and all
+// its references, to newName. For servers not supporting rename requests,
+// the edit replaces every reference to the symbol, including its
+// declaration: symbols the server only ties to it through a rename, such as
+// implementations of an interface method, aren't changed.
+func (c *Client) Rename(ctx context.Context, filepath string, line, character int, newName string) (protocol.WorkspaceEdit, error) {
+	if provider := c.client.GetCapabilities().RenameProvider; provider != nil && provider != false {
+		if err := c.OpenFileOnDemand(ctx, filepath); err != nil {
+			return protocol.WorkspaceEdit{}, err
+		}
+
+		// Add timeout to prevent hanging on slow LSP servers.
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+
+		// NOTE: line and character should be 0-based.
+		var edit protocol.WorkspaceEdit
+		err := c.call(ctx, "textDocument/rename", protocol.RenameParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: protocol.URIFromPath(filepath)},
+			Position: protocol.Position{
+				Line:      uint32(line - 1),      //nolint:gosec
+				Character: uint32(character - 1), //nolint:gosec
+			},
+			NewName: newName,
+		}, &edit)
+		return edit, err
+	}
+
+	references, err := c.FindReferences(ctx, filepath, line, character, true)
+	if err != nil {
+		return protocol.WorkspaceEdit{}, err
+	}
+
+	changes := make(map[protocol.DocumentURI][]protocol.TextEdit)
+	seen := make(map[protocol.Location]bool, len(references))
+	for _, loc := range references {
+		if seen[loc] {
+			continue
+		}
+		seen[loc] = true
+		changes[loc.URI] = append(changes[loc.URI], protocol.TextEdit{
+			Range:   loc.Range,
+			NewText: newName,
+		})
+	}
+	return protocol.WorkspaceEdit{Changes: changes}, nil
+}
+
+// OffsetEncoding returns the position encoding negotiated with the server.
+func (c *Client) OffsetEncoding() powernap.OffsetEncoding {
+	return c.client.GetOffsetEncoding()
+}
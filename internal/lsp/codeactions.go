@@ -0,0 +1,85 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+)
+
+// CodeActions returns the code actions available for the given lines of a
+// file, 1-based and inclusive, like the quick fixes of their diagnostics or
+// organizing imports.
+func (c *Client) CodeActions(ctx context.Context, filepath string, startLine, endLine int) ([]protocol.CodeAction, error) {
+	if err := c.OpenFileOnDemand(ctx, filepath); err != nil {
+		return nil, err
+	}
+
+	// Add timeout to prevent hanging on slow LSP servers.
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// NOTE: lines should be 0-based, the range ending at the start of the
+	// line after the last one.
+	uri := protocol.URIFromPath(filepath)
+	rng := protocol.Range{
+		Start: protocol.Position{Line: uint32(startLine - 1)}, //nolint:gosec
+		End:   protocol.Position{Line: uint32(endLine)},       //nolint:gosec
+	}
+	// Quick fixes are only offered for the diagnostics sent along.
+	diagnostics := []protocol.Diagnostic{}
+	for _, d := range c.GetFileDiagnostics(uri) {
+		if d.Range.Start.Line < rng.End.Line && d.Range.End.Line >= rng.Start.Line {
+			diagnostics = append(diagnostics, d)
+		}
+	}
+
+	var raw []json.RawMessage
+	err := c.call(ctx, "textDocument/codeAction", protocol.CodeActionParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+		Range:        rng,
+		Context:      protocol.CodeActionContext{Diagnostics: diagnostics},
+	}, &raw)
+	if err != nil {
+		return nil, err
+	}
+
+	actions := make([]protocol.CodeAction, 0, len(raw))
+	for _, r := range raw {
+		// Servers may return bare commands, whose command is a string
+		// rather than an object.
+		var kind struct {
+			Command json.RawMessage `json:"command"`
+		}
+		if err := json.Unmarshal(r, &kind); err == nil && len(kind.Command) > 0 && kind.Command[0] == '"' {
+			var command protocol.Command
+			if err := json.Unmarshal(r, &command); err != nil {
+				return nil, fmt.Errorf("invalid command: %w", err)
+			}
+			actions = append(actions, protocol.CodeAction{Title: command.Title, Command: &command})
+			continue
+		}
+		var action protocol.CodeAction
+		if err := json.Unmarshal(r, &action); err != nil {
+			return nil, fmt.Errorf("invalid code action: %w", err)
+		}
+		actions = append(actions, action)
+	}
+	return actions, nil
+}
+
+// ResolveCodeAction returns the code action with its edit, which servers may
+// only compute once the action is picked.
+func (c *Client) ResolveCodeAction(ctx context.Context, action protocol.CodeAction) (protocol.CodeAction, error) {
+	// Add timeout to prevent hanging on slow LSP servers.
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var resolved protocol.CodeAction
+	if err := c.call(ctx, "codeAction/resolve", action, &resolved); err != nil {
+		return protocol.CodeAction{}, err
+	}
+	return resolved, nil
+}
@@ -0,0 +1,63 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/shell"
+)
+
+// formatTimeout is how long a formatter has to format a file.
+const formatTimeout = 10 * time.Second
+
+// Format formats content, the new content of the file at path, with the
+// formatter of the LSP server handling the file. The content is returned
+// unchanged if no formatter is configured for the file.
+func (s *Manager) Format(ctx context.Context, path, content string) (string, error) {
+	if s == nil {
+		return content, nil
+	}
+	name, formatter := s.formatterFor(path)
+	if formatter == "" {
+		return content, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, formatTimeout)
+	defer cancel()
+
+	sh := shell.NewShell(&shell.Options{
+		WorkingDir: s.workingDir,
+		Env:        append(os.Environ(), "CRUSH_FILE_PATH="+path),
+	})
+	stdout, stderr, err := sh.ExecWithInput(ctx, formatter, strings.NewReader(content))
+	if err != nil {
+		return content, fmt.Errorf("formatter of %s failed: %w: %s", name, err, strings.TrimSpace(stderr))
+	}
+	if strings.TrimSpace(stdout) == "" && strings.TrimSpace(content) != "" {
+		return content, fmt.Errorf("formatter of %s returned no content", name)
+	}
+	return stdout, nil
+}
+
+// formatterFor returns the name and formatter of the first LSP server, by
+// name, that has a formatter and whose file types include the given file.
+func (s *Manager) formatterFor(path string) (string, string) {
+	lsps := s.cfg.Config().LSP
+	for _, name := range slices.Sorted(maps.Keys(lsps)) {
+		lspCfg := lsps[name]
+		if lspCfg.Disabled || lspCfg.Formatter == "" {
+			continue
+		}
+		// Unlike servers, formatters without file types don't handle every
+		// file.
+		if len(lspCfg.FileTypes) > 0 && handlesFiletype(name, lspCfg.FileTypes, path) {
+			return name, lspCfg.Formatter
+		}
+	}
+	return "", ""
+}
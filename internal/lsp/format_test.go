@@ -0,0 +1,74 @@
+package lsp
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager_Format(t *testing.T) {
+	cfg, err := config.Init(t.TempDir(), "", false)
+	require.NoError(t, err)
+	cfg.Config().LSP = config.LSPs{
+		"upper": {
+			Command:   "upper-lsp",
+			FileTypes: []string{"txt"},
+			Formatter: `tr a-z A-Z`,
+		},
+		"broken": {
+			Command:   "broken-lsp",
+			FileTypes: []string{"md"},
+			Formatter: `echo "syntax error" >&2; exit 1`,
+		},
+		"path": {
+			Command:   "path-lsp",
+			FileTypes: []string{"log"},
+			Formatter: `echo "$CRUSH_FILE_PATH"`,
+		},
+		"pwd": {
+			Command:   "pwd-lsp",
+			FileTypes: []string{"pwd"},
+			Formatter: `pwd`,
+		},
+		"disabled": {
+			Disabled:  true,
+			FileTypes: []string{"go"},
+			Formatter: `tr a-z A-Z`,
+		},
+	}
+	manager := NewManager(cfg)
+
+	t.Run("formats content on stdin", func(t *testing.T) {
+		formatted, err := manager.Format(t.Context(), "notes.txt", "hello\n")
+		require.NoError(t, err)
+		require.Equal(t, "HELLO\n", formatted)
+	})
+
+	t.Run("passes the file path", func(t *testing.T) {
+		formatted, err := manager.Format(t.Context(), "/tmp/app.log", "hello\n")
+		require.NoError(t, err)
+		require.Equal(t, "/tmp/app.log\n", formatted)
+	})
+
+	t.Run("runs in the root of the manager", func(t *testing.T) {
+		root := t.TempDir()
+		formatted, err := manager.ForRoot(root).Format(t.Context(), "dir.pwd", "hello\n")
+		require.NoError(t, err)
+		require.Equal(t, root+"\n", formatted)
+	})
+
+	t.Run("keeps content when the formatter fails", func(t *testing.T) {
+		formatted, err := manager.Format(t.Context(), "README.md", "hello\n")
+		require.ErrorContains(t, err, "syntax error")
+		require.Equal(t, "hello\n", formatted)
+	})
+
+	t.Run("no formatter", func(t *testing.T) {
+		for _, path := range []string{"main.go", "main.rs"} {
+			formatted, err := manager.Format(t.Context(), path, "hello\n")
+			require.NoError(t, err)
+			require.Equal(t, "hello\n", formatted)
+		}
+	})
+}
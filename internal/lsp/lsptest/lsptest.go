@@ -0,0 +1,129 @@
+// Package lsptest runs fake language servers, so code using LSP clients can
+// be tested without real ones.
+//
+// LSP clients start their server as a process, so the test binary is run as
+// one, relaying its stdio to a fake server answering in the test. Packages
+// using fake servers must call [Main] from their TestMain.
+package lsptest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/env"
+	"github.com/charmbracelet/crush/internal/lsp"
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/stretchr/testify/require"
+)
+
+// relayEnv is set to the address of the fake server when the test binary
+// is run as a language server.
+const relayEnv = "CRUSH_LSPTEST_RELAY"
+
+// Main runs the tests, or relays stdio to a fake server when the test binary
+// is run as a language server.
+func Main(m *testing.M) {
+	if addr := os.Getenv(relayEnv); addr != "" {
+		if err := relay(addr); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func relay(addr string) error {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return err
+	}
+	go func() {
+		_, _ = io.Copy(conn, os.Stdin)
+		_ = conn.Close()
+	}()
+	_, err = io.Copy(os.Stdout, conn)
+	return err
+}
+
+// Handler answers a request with its result. Notifications are handled too,
+// their result being ignored.
+type Handler func(params json.RawMessage) (any, error)
+
+// NewClient returns an initialized client of a fake server answering
+// requests with handlers, keyed by method. Requests without a handler get a
+// null result, and the server has no capabilities unless an initialize
+// handler says otherwise. Like real servers, it publishes the diagnostics of
+// documents when they change, none by default. The client handles the files
+// of fileTypes in dir.
+func NewClient(t *testing.T, dir string, fileTypes []string, handlers map[string]Handler) *lsp.Client {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+	go serve(ln, handlers)
+
+	exe, err := os.Executable()
+	require.NoError(t, err)
+	cfg := config.LSPConfig{
+		Command:   exe,
+		FileTypes: fileTypes,
+		Env:       map[string]string{relayEnv: ln.Addr().String()},
+	}
+	client, err := lsp.New(t.Context(), "fake", cfg, config.NewEnvironmentVariableResolver(env.New()), dir, false)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close(context.Background()) })
+
+	_, err = client.Initialize(t.Context(), dir)
+	require.NoError(t, err)
+	client.SetServerState(lsp.StateReady)
+	return client
+}
+
+func serve(ln net.Listener, handlers map[string]Handler) {
+	conn, err := ln.Accept()
+	if err != nil {
+		return
+	}
+	var rpc *jsonrpc2.Conn
+	rpc = jsonrpc2.NewConn(
+		context.Background(),
+		jsonrpc2.NewBufferedStream(conn, jsonrpc2.VSCodeObjectCodec{}),
+		jsonrpc2.HandlerWithError(func(ctx context.Context, _ *jsonrpc2.Conn, req *jsonrpc2.Request) (any, error) {
+			var params json.RawMessage
+			if req.Params != nil {
+				params = *req.Params
+			}
+			if handler, ok := handlers[req.Method]; ok {
+				return handler(params)
+			}
+			switch req.Method {
+			case "initialize":
+				return map[string]any{"capabilities": map[string]any{}}, nil
+			case "textDocument/didChange":
+				var change struct {
+					TextDocument struct {
+						URI string `json:"uri"`
+					} `json:"textDocument"`
+				}
+				if err := json.Unmarshal(params, &change); err == nil {
+					_ = rpc.Notify(ctx, "textDocument/publishDiagnostics", map[string]any{
+						"uri":         change.TextDocument.URI,
+						"diagnostics": []any{},
+					})
+				}
+			case "exit":
+				go rpc.Close()
+			}
+			return nil, nil
+		}),
+	)
+	<-rpc.DisconnectNotify()
+}
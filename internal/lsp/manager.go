@@ -25,10 +25,15 @@ var unavailable = csync.NewMap[string, struct{}]()
 
 // Manager handles lazy initialization of LSP clients based on file types.
 type Manager struct {
-	clients  *csync.Map[string, *Client]
-	cfg      *config.ConfigStore
-	manager  *powernapconfig.Manager
-	callback func(name string, client *Client)
+	clients    *csync.Map[string, *Client]
+	cfg        *config.ConfigStore
+	manager    *powernapconfig.Manager
+	callback   func(name string, client *Client)
+	workingDir string
+
+	// roots holds the managers of other workspace roots, like the git
+	// worktrees of sessions, keyed by root directory.
+	roots *csync.Map[string, *Manager]
 }
 
 // NewManager creates a new LSP manager service.
@@ -59,10 +64,39 @@ func NewManager(cfg *config.ConfigStore) *Manager {
 	}
 
 	return &Manager{
-		clients:  csync.NewMap[string, *Client](),
-		cfg:      cfg,
-		manager:  manager,
-		callback: func(string, *Client) {}, // default no-op callback
+		clients:    csync.NewMap[string, *Client](),
+		cfg:        cfg,
+		manager:    manager,
+		callback:   func(string, *Client) {}, // default no-op callback
+		workingDir: cfg.WorkingDir(),
+		roots:      csync.NewMap[string, *Manager](),
+	}
+}
+
+// ForRoot returns the manager of the LSP clients of another workspace root,
+// such as a git worktree, creating it if needed. It returns the manager
+// itself if root is empty or its own working directory.
+func (s *Manager) ForRoot(root string) *Manager {
+	if s == nil || root == "" || root == s.workingDir {
+		return s
+	}
+	return s.roots.GetOrSet(root, func() *Manager {
+		return &Manager{
+			clients:    csync.NewMap[string, *Client](),
+			cfg:        s.cfg,
+			manager:    s.manager,
+			callback:   func(string, *Client) {},
+			workingDir: root,
+			roots:      csync.NewMap[string, *Manager](),
+		}
+	})
+}
+
+// StopRoot stops the LSP clients of another workspace root and forgets
+// about it.
+func (s *Manager) StopRoot(ctx context.Context, root string) {
+	if m, ok := s.roots.Take(root); ok {
+		m.StopAll(ctx)
 	}
 }
 
@@ -95,7 +129,7 @@ func (s *Manager) TrackConfigured() {
 // Start starts an LSP server that can handle the given file path.
 // If an appropriate LSP is already running, this is a no-op.
 func (s *Manager) Start(ctx context.Context, path string) {
-	if !fsext.HasPrefix(path, s.cfg.WorkingDir()) {
+	if !fsext.HasPrefix(path, s.workingDir) {
 		return
 	}
 
@@ -174,7 +208,7 @@ func (s *Manager) startServer(ctx context.Context, name, filepath string, server
 	}
 
 	// this is the slowest bit, so we do it last.
-	if !handles(server, filepath, s.cfg.WorkingDir()) {
+	if !handles(server, filepath, s.workingDir) {
 		// nothing to do
 		return
 	}
@@ -193,7 +227,7 @@ func (s *Manager) startServer(ctx context.Context, name, filepath string, server
 		name,
 		cfg,
 		s.cfg.Resolver(),
-		s.cfg.WorkingDir(),
+		s.workingDir,
 		s.cfg.Config().Options.DebugLSP,
 	)
 	if err != nil {
@@ -226,7 +260,7 @@ func (s *Manager) startServer(ctx context.Context, name, filepath string, server
 	initCtx, cancel := context.WithTimeout(ctx, time.Duration(cmp.Or(cfg.Timeout, 30))*time.Second)
 	defer cancel()
 
-	if _, err := client.Initialize(initCtx, s.cfg.WorkingDir()); err != nil {
+	if _, err := client.Initialize(initCtx, s.workingDir); err != nil {
 		slog.Error("LSP client initialization failed", "name", name, "error", err)
 		_ = client.Close(ctx)
 		s.clients.Del(name)
@@ -325,8 +359,11 @@ func handles(server *powernapconfig.ServerConfig, filePath, workDir string) bool
 // the server to exit gracefully, but it can lead to data loss if the server is
 // in the middle of writing something.
 // Generally it doesn't matter when shutting down Crush, though.
-func (s *Manager) KillAll(context.Context) {
+func (s *Manager) KillAll(ctx context.Context) {
 	var wg sync.WaitGroup
+	for m := range s.roots.Seq() {
+		wg.Go(func() { m.KillAll(ctx) })
+	}
 	for name, client := range s.clients.Seq2() {
 		wg.Go(func() {
 			defer func() { s.callback(name, client) }()
@@ -342,6 +379,9 @@ func (s *Manager) KillAll(context.Context) {
 // StopAll stops all running LSP clients and clears the client map.
 func (s *Manager) StopAll(ctx context.Context) {
 	var wg sync.WaitGroup
+	for m := range s.roots.Seq() {
+		wg.Go(func() { m.StopAll(ctx) })
+	}
 	for name, client := range s.clients.Seq2() {
 		wg.Go(func() {
 			defer func() { s.callback(name, client) }()
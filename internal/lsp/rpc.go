@@ -0,0 +1,82 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"unsafe"
+
+	powernap "github.com/charmbracelet/x/powernap/pkg/lsp"
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+	"github.com/charmbracelet/x/powernap/pkg/transport"
+)
+
+// call sends a request the powernap client has no method for, like
+// textDocument/documentSymbol, and decodes its result into result.
+func (c *Client) call(ctx context.Context, method string, params, result any) error {
+	if !c.client.IsInitialized() {
+		return fmt.Errorf("%s request failed: client not initialized", method)
+	}
+	conn := rpcConn(c.client)
+	if conn == nil {
+		return fmt.Errorf("%s request failed: no connection to the server", method)
+	}
+	if err := conn.Call(ctx, method, params, result); err != nil {
+		return fmt.Errorf("%s request failed: %w", method, err)
+	}
+	return nil
+}
+
+// rpcConn returns the JSON-RPC connection of a powernap client. powernap
+// keeps it unexported, so it's read through reflection; TestRPCConn fails if
+// the field changes. It returns nil if the field can't be found.
+func rpcConn(client *powernap.Client) *transport.Connection {
+	if client == nil {
+		return nil
+	}
+	field := reflect.ValueOf(client).Elem().FieldByName("conn")
+	if !field.IsValid() || field.Type() != reflect.TypeFor[*transport.Connection]() {
+		return nil
+	}
+	return *(**transport.Connection)(unsafe.Pointer(field.UnsafeAddr()))
+}
+
+// rawLocation is a Location or a LocationLink, which servers may return
+// instead.
+type rawLocation struct {
+	URI                  protocol.DocumentURI `json:"uri"`
+	Range                protocol.Range       `json:"range"`
+	TargetURI            protocol.DocumentURI `json:"targetUri"`
+	TargetSelectionRange protocol.Range       `json:"targetSelectionRange"`
+}
+
+// decodeLocations decodes the result of requests like textDocument/definition,
+// which may be null, a location, or a list of locations or location links.
+func decodeLocations(result json.RawMessage) ([]protocol.Location, error) {
+	var raw []rawLocation
+	switch {
+	case len(result) == 0 || string(result) == "null":
+		return nil, nil
+	case result[0] == '[':
+		if err := json.Unmarshal(result, &raw); err != nil {
+			return nil, fmt.Errorf("invalid locations: %w", err)
+		}
+	default:
+		var loc rawLocation
+		if err := json.Unmarshal(result, &loc); err != nil {
+			return nil, fmt.Errorf("invalid location: %w", err)
+		}
+		raw = append(raw, loc)
+	}
+
+	locations := make([]protocol.Location, 0, len(raw))
+	for _, r := range raw {
+		if r.TargetURI != "" {
+			locations = append(locations, protocol.Location{URI: r.TargetURI, Range: r.TargetSelectionRange})
+			continue
+		}
+		locations = append(locations, protocol.Location{URI: r.URI, Range: r.Range})
+	}
+	return locations, nil
+}
@@ -0,0 +1,56 @@
+package lsp
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	powernap "github.com/charmbracelet/x/powernap/pkg/lsp"
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+	"github.com/charmbracelet/x/powernap/pkg/transport"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRPCConn(t *testing.T) {
+	t.Parallel()
+
+	// Requests powernap has no method for are sent through this field.
+	field, ok := reflect.TypeFor[powernap.Client]().FieldByName("conn")
+	require.True(t, ok, "powernap.Client has no conn field anymore")
+	require.Equal(t, reflect.TypeFor[*transport.Connection](), field.Type)
+
+	require.Nil(t, rpcConn(nil))
+	require.Nil(t, rpcConn(&powernap.Client{}))
+}
+
+func TestDecodeLocations(t *testing.T) {
+	t.Parallel()
+
+	loc := protocol.Location{
+		URI:   "file:///a.go",
+		Range: protocol.Range{Start: protocol.Position{Line: 1, Character: 2}, End: protocol.Position{Line: 1, Character: 5}},
+	}
+	tests := []struct {
+		name   string
+		result string
+		want   []protocol.Location
+	}{
+		{"null", `null`, nil},
+		{"location", `{"uri":"file:///a.go","range":{"start":{"line":1,"character":2},"end":{"line":1,"character":5}}}`, []protocol.Location{loc}},
+		{"locations", `[{"uri":"file:///a.go","range":{"start":{"line":1,"character":2},"end":{"line":1,"character":5}}}]`, []protocol.Location{loc}},
+		{
+			"location links",
+			`[{"targetUri":"file:///a.go","targetRange":{"start":{"line":0,"character":0},"end":{"line":3,"character":1}},"targetSelectionRange":{"start":{"line":1,"character":2},"end":{"line":1,"character":5}}}]`,
+			[]protocol.Location{loc},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := decodeLocations(json.RawMessage(tt.result))
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
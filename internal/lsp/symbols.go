@@ -0,0 +1,97 @@
+package lsp
+
+import (
+	"context"
+	"time"
+
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+)
+
+// Symbol is a symbol of a document or of the workspace.
+type Symbol struct {
+	Name string
+	Kind protocol.SymbolKind
+	// Detail is more information about the symbol, like its signature.
+	Detail string
+	// Container is the name of the symbol containing it, if known.
+	Container string
+	// Location spans the whole definition of the symbol, when the server
+	// knows it.
+	Location protocol.Location
+	// Children are the symbols it contains, for servers nesting document
+	// symbols.
+	Children []Symbol
+}
+
+// rawSymbol is a DocumentSymbol, SymbolInformation or WorkspaceSymbol, which
+// servers return depending on their version.
+type rawSymbol struct {
+	Name          string              `json:"name"`
+	Kind          protocol.SymbolKind `json:"kind"`
+	Detail        string              `json:"detail"`
+	ContainerName string              `json:"containerName"`
+	Range         protocol.Range      `json:"range"`
+	Location      *protocol.Location  `json:"location"`
+	Children      []rawSymbol         `json:"children"`
+}
+
+// DocumentSymbols returns the outline of a file: its symbols, along with the
+// symbols they contain if the server nests them.
+func (c *Client) DocumentSymbols(ctx context.Context, filepath string) ([]Symbol, error) {
+	if err := c.OpenFileOnDemand(ctx, filepath); err != nil {
+		return nil, err
+	}
+
+	// Add timeout to prevent hanging on slow LSP servers.
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	uri := protocol.URIFromPath(filepath)
+	var raw []rawSymbol
+	err := c.call(ctx, "textDocument/documentSymbol", protocol.DocumentSymbolParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+	}, &raw)
+	if err != nil {
+		return nil, err
+	}
+	return toSymbols(uri, "", raw), nil
+}
+
+// WorkspaceSymbols returns the symbols of the workspace matching query.
+// Servers match it loosely, usually fuzzily.
+func (c *Client) WorkspaceSymbols(ctx context.Context, query string) ([]Symbol, error) {
+	// Add timeout to prevent hanging on slow LSP servers.
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var raw []rawSymbol
+	if err := c.call(ctx, "workspace/symbol", protocol.WorkspaceSymbolParams{Query: query}, &raw); err != nil {
+		return nil, err
+	}
+	return toSymbols("", "", raw), nil
+}
+
+func toSymbols(uri protocol.DocumentURI, container string, raw []rawSymbol) []Symbol {
+	if len(raw) == 0 {
+		return nil
+	}
+	symbols := make([]Symbol, 0, len(raw))
+	for _, r := range raw {
+		s := Symbol{
+			Name:      r.Name,
+			Kind:      r.Kind,
+			Detail:    r.Detail,
+			Container: r.ContainerName,
+			Location:  protocol.Location{URI: uri, Range: r.Range},
+		}
+		if r.Location != nil {
+			s.Location = *r.Location
+		}
+		if s.Container == "" {
+			s.Container = container
+		}
+		s.Children = toSymbols(s.Location.URI, s.Name, r.Children)
+		symbols = append(symbols, s)
+	}
+	return symbols
+}
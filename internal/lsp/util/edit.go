@@ -23,6 +23,19 @@ func applyTextEdits(uri protocol.DocumentURI, edits []protocol.TextEdit, encodin
 		return fmt.Errorf("failed to read file: %w", err)
 	}
 
+	newContent, err := applyTextEditsToContent(content, edits, encoding)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path, []byte(newContent), 0o644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	return nil
+}
+
+func applyTextEditsToContent(content []byte, edits []protocol.TextEdit, encoding powernap.OffsetEncoding) (string, error) {
 	// Detect line ending style
 	var lineEnding string
 	if bytes.Contains(content, []byte("\r\n")) {
@@ -41,7 +54,7 @@ func applyTextEdits(uri protocol.DocumentURI, edits []protocol.TextEdit, encodin
 	for i, edit1 := range edits {
 		for j := i + 1; j < len(edits); j++ {
 			if rangesOverlap(edit1.Range, edits[j].Range) {
-				return fmt.Errorf("overlapping edits detected between edit %d and %d", i, j)
+				return "", fmt.Errorf("overlapping edits detected between edit %d and %d", i, j)
 			}
 		}
 	}
@@ -60,7 +73,7 @@ func applyTextEdits(uri protocol.DocumentURI, edits []protocol.TextEdit, encodin
 	for _, edit := range sortedEdits {
 		newLines, err := applyTextEdit(lines, edit, encoding)
 		if err != nil {
-			return fmt.Errorf("failed to apply edit: %w", err)
+			return "", fmt.Errorf("failed to apply edit: %w", err)
 		}
 		lines = newLines
 	}
@@ -79,11 +92,7 @@ func applyTextEdits(uri protocol.DocumentURI, edits []protocol.TextEdit, encodin
 		newContent.WriteString(lineEnding)
 	}
 
-	if err := os.WriteFile(path, []byte(newContent.String()), 0o644); err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
-	}
-
-	return nil
+	return newContent.String(), nil
 }
 
 func applyTextEdit(lines []string, edit protocol.TextEdit, encoding powernap.OffsetEncoding) ([]string, error) {
@@ -284,6 +293,48 @@ func ApplyWorkspaceEdit(edit protocol.WorkspaceEdit, encoding powernap.OffsetEnc
 	return nil
 }
 
+// PreviewWorkspaceEdit returns the content the files changed by the given
+// WorkspaceEdit would have once it's applied, keyed by path, without writing
+// anything. Only text edits are supported: it fails if the edit creates,
+// renames or deletes files.
+func PreviewWorkspaceEdit(edit protocol.WorkspaceEdit, encoding powernap.OffsetEncoding) (map[string]string, error) {
+	edits := make(map[protocol.DocumentURI][]protocol.TextEdit)
+	for uri, textEdits := range edit.Changes {
+		edits[uri] = append(edits[uri], textEdits...)
+	}
+	for _, change := range edit.DocumentChanges {
+		if change.TextDocumentEdit == nil {
+			return nil, fmt.Errorf("file operations are not supported")
+		}
+		uri := change.TextDocumentEdit.TextDocument.URI
+		for _, e := range change.TextDocumentEdit.Edits {
+			textEdit, err := e.AsTextEdit()
+			if err != nil {
+				return nil, fmt.Errorf("invalid edit type: %w", err)
+			}
+			edits[uri] = append(edits[uri], textEdit)
+		}
+	}
+
+	contents := make(map[string]string, len(edits))
+	for uri, textEdits := range edits {
+		path, err := uri.Path()
+		if err != nil {
+			return nil, fmt.Errorf("invalid URI: %w", err)
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file: %w", err)
+		}
+		newContent, err := applyTextEditsToContent(content, textEdits, encoding)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply text edits: %w", err)
+		}
+		contents[path] = newContent
+	}
+	return contents, nil
+}
+
 // rangesOverlap checks if two LSP ranges overlap.
 // Per the LSP specification, ranges are half-open intervals [start, end),
 // so adjacent ranges where one's end equals another's start do NOT overlap.
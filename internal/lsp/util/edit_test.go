@@ -1,6 +1,8 @@
 package util
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	powernap "github.com/charmbracelet/x/powernap/pkg/lsp"
@@ -374,3 +376,45 @@ func TestRangesOverlap(t *testing.T) {
 		})
 	}
 }
+
+func TestPreviewWorkspaceEdit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.go")
+	content := "package main\n\nfunc old() {}\n\nfunc main() { old() }\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+	rename := func(line, start uint32) protocol.TextEdit {
+		return protocol.TextEdit{
+			Range: protocol.Range{
+				Start: protocol.Position{Line: line, Character: start},
+				End:   protocol.Position{Line: line, Character: start + 3},
+			},
+			NewText: "renamed",
+		}
+	}
+	edit := protocol.WorkspaceEdit{
+		Changes: map[protocol.DocumentURI][]protocol.TextEdit{
+			protocol.URIFromPath(path): {rename(2, 5), rename(4, 14)},
+		},
+	}
+
+	contents, err := PreviewWorkspaceEdit(edit, powernap.UTF16)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		path: "package main\n\nfunc renamed() {}\n\nfunc main() { renamed() }\n",
+	}, contents)
+
+	// Nothing is written until the edit is applied.
+	got, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, content, string(got))
+
+	require.NoError(t, ApplyWorkspaceEdit(edit, powernap.UTF16))
+	got, err = os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, contents[path], string(got))
+
+	_, err = PreviewWorkspaceEdit(protocol.WorkspaceEdit{
+		DocumentChanges: []protocol.DocumentChange{{DeleteFile: &protocol.DeleteFile{URI: protocol.URIFromPath(path)}}},
+	}, powernap.UTF16)
+	require.Error(t, err)
+}
@@ -0,0 +1,365 @@
+// Package mcpserver exposes Crush's tools, and optionally its agent, to other
+// MCP clients.
+package mcpserver
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/agent/tools"
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/charmbracelet/crush/internal/version"
+	"github.com/google/uuid"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// AskToolName is the name of the tool running prompts with the agent.
+const AskToolName = "ask_crush"
+
+// Policy decides the permission requests of tool calls.
+type Policy string
+
+const (
+	// PolicyAsk asks the client through elicitation, denying the request if
+	// the client doesn't support it.
+	PolicyAsk Policy = "ask"
+	// PolicyAllow allows every request.
+	PolicyAllow Policy = "allow"
+	// PolicyDeny denies every request.
+	PolicyDeny Policy = "deny"
+)
+
+// ParsePolicy parses a permission policy, defaulting to [PolicyAsk].
+func ParsePolicy(s string) (Policy, error) {
+	switch p := Policy(cmp.Or(s, string(PolicyAsk))); p {
+	case PolicyAsk, PolicyAllow, PolicyDeny:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid permission policy %q: must be ask, allow or deny", s)
+	}
+}
+
+// Agent runs prompts in sessions. It's implemented by [agent.Coordinator].
+type Agent interface {
+	Run(ctx context.Context, sessionID, prompt string, attachments ...message.Attachment) (*fantasy.AgentResult, error)
+	IsSessionBusy(sessionID string) bool
+}
+
+// Options configures a [Server].
+type Options struct {
+	// Tools are exposed as they are.
+	Tools       []fantasy.AgentTool
+	Sessions    session.Service
+	Permissions permission.Service
+	// Agent, if set, is exposed as the ask_crush tool.
+	Agent  Agent
+	Policy Policy
+}
+
+// Server is an MCP server exposing Crush's tools.
+type Server struct {
+	ctx         context.Context
+	sessions    session.Service
+	permissions permission.Service
+	agent       Agent
+	policy      Policy
+	server      *mcp.Server
+
+	// clients maps MCP sessions to the session their tool calls run in.
+	clients   map[*mcp.ServerSession]string
+	clientsMu sync.Mutex
+	// calls holds the calls in progress, so their permission requests can be
+	// asked to the client making them. Tool calls are keyed by tool call ID,
+	// and prompts by session ID.
+	calls *csync.Map[string, call]
+}
+
+type call struct {
+	ctx     context.Context
+	session *mcp.ServerSession
+}
+
+// New creates a server. Permission requests are answered until ctx is done.
+func New(ctx context.Context, opts Options) *Server {
+	s := &Server{
+		ctx:         ctx,
+		sessions:    opts.Sessions,
+		permissions: opts.Permissions,
+		agent:       opts.Agent,
+		policy:      cmp.Or(opts.Policy, PolicyAsk),
+		server: mcp.NewServer(&mcp.Implementation{
+			Name:    "crush",
+			Title:   "Crush",
+			Version: version.Version,
+		}, nil),
+		clients: make(map[*mcp.ServerSession]string),
+		calls:   csync.NewMap[string, call](),
+	}
+	for _, tool := range opts.Tools {
+		s.addTool(tool)
+	}
+	if s.agent != nil {
+		mcp.AddTool(s.server, &mcp.Tool{
+			Name:        AskToolName,
+			Description: "Ask Crush, a coding agent working in this project, to do something. It can read, search and edit files and run commands. Pass the returned session_id to continue the conversation.",
+		}, s.ask)
+	}
+	s.answerPermissions()
+	return s
+}
+
+// Run serves a single client over the given transport, like stdio.
+func (s *Server) Run(ctx context.Context, t mcp.Transport) error {
+	return s.server.Run(ctx, t)
+}
+
+// Handler returns a streamable HTTP handler serving any number of clients.
+func (s *Server) Handler() http.Handler {
+	return mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server {
+		return s.server
+	}, &mcp.StreamableHTTPOptions{
+		// Close the sessions of clients that went away without saying so.
+		SessionTimeout: 30 * time.Minute,
+	})
+}
+
+func (s *Server) addTool(tool fantasy.AgentTool) {
+	info := tool.Info()
+	schema := map[string]any{
+		"type":       "object",
+		"properties": info.Parameters,
+	}
+	if len(info.Required) > 0 {
+		schema["required"] = info.Required
+	}
+	s.server.AddTool(&mcp.Tool{
+		Name:        info.Name,
+		Description: info.Description,
+		InputSchema: schema,
+	}, func(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		sessionID, err := s.clientSession(ctx, req.Session)
+		if err != nil {
+			return nil, err
+		}
+		toolCall := fantasy.ToolCall{
+			ID:    uuid.NewString(),
+			Name:  info.Name,
+			Input: cmp.Or(string(req.Params.Arguments), "{}"),
+		}
+		s.calls.Set(toolCall.ID, call{ctx: ctx, session: req.Session})
+		defer s.calls.Del(toolCall.ID)
+
+		ctx = context.WithValue(ctx, tools.SessionIDContextKey, sessionID)
+		resp, err := tool.Run(ctx, toolCall)
+		if err != nil {
+			return errorResult(err), nil
+		}
+		return toolResult(resp), nil
+	})
+}
+
+// clientSession returns the session the tool calls of an MCP session run in,
+// creating it on the first call.
+func (s *Server) clientSession(ctx context.Context, ss *mcp.ServerSession) (string, error) {
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
+	if id, ok := s.clients[ss]; ok {
+		return id, nil
+	}
+	title := "MCP client"
+	if params := ss.InitializeParams(); params != nil && params.ClientInfo != nil {
+		title = "MCP: " + cmp.Or(params.ClientInfo.Title, params.ClientInfo.Name)
+	}
+	sess, err := s.sessions.Create(ctx, title)
+	if err != nil {
+		return "", fmt.Errorf("creating session: %w", err)
+	}
+	s.allowSession(sess.ID)
+	s.clients[ss] = sess.ID
+	go func() {
+		_ = ss.Wait()
+		s.clientsMu.Lock()
+		defer s.clientsMu.Unlock()
+		delete(s.clients, ss)
+	}()
+	return sess.ID, nil
+}
+
+func (s *Server) allowSession(sessionID string) {
+	if s.policy == PolicyAllow {
+		s.permissions.AutoApproveSession(sessionID)
+	}
+}
+
+type askInput struct {
+	Prompt    string `json:"prompt" jsonschema:"What to ask Crush to do"`
+	SessionID string `json:"session_id,omitempty" jsonschema:"The session to continue. A new session is started if empty"`
+}
+
+type askOutput struct {
+	SessionID string `json:"session_id"`
+	Response  string `json:"response"`
+}
+
+func (s *Server) ask(ctx context.Context, req *mcp.CallToolRequest, in askInput) (*mcp.CallToolResult, askOutput, error) {
+	if strings.TrimSpace(in.Prompt) == "" {
+		return nil, askOutput{}, errors.New("prompt is required")
+	}
+
+	sessionID := in.SessionID
+	if sessionID == "" {
+		sess, err := s.sessions.Create(ctx, "New Session")
+		if err != nil {
+			return nil, askOutput{}, fmt.Errorf("creating session: %w", err)
+		}
+		sessionID = sess.ID
+		s.allowSession(sessionID)
+	} else if _, err := s.sessions.Get(ctx, sessionID); err != nil {
+		return nil, askOutput{}, fmt.Errorf("session %s not found", sessionID)
+	}
+	if s.agent.IsSessionBusy(sessionID) {
+		return nil, askOutput{}, fmt.Errorf("session %s is busy", sessionID)
+	}
+
+	s.calls.Set(sessionID, call{ctx: ctx, session: req.Session})
+	defer s.calls.Del(sessionID)
+
+	result, err := s.agent.Run(ctx, sessionID, in.Prompt)
+	if err != nil {
+		return nil, askOutput{}, err
+	}
+	out := askOutput{SessionID: sessionID}
+	if result != nil {
+		out.Response = result.Response.Content.Text()
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{&mcp.TextContent{Text: out.Response}},
+	}, out, nil
+}
+
+// answerPermissions answers the permission requests of tool calls, asking
+// the clients making them when the policy says so.
+func (s *Server) answerPermissions() {
+	requests := s.permissions.Subscribe(s.ctx)
+	go func() {
+		for event := range requests {
+			go s.answer(event.Payload)
+		}
+	}()
+}
+
+func (s *Server) answer(req permission.PermissionRequest) {
+	switch s.policy {
+	case PolicyAllow:
+		s.permissions.Grant(req)
+		return
+	case PolicyDeny:
+		s.permissions.Deny(req)
+		return
+	}
+
+	c, ok := s.findCall(req)
+	if !ok || !supportsElicitation(c.session) {
+		slog.Warn("Denying permission request, the client can't be asked", "tool", req.ToolName, "action", req.Action)
+		s.permissions.Deny(req)
+		return
+	}
+
+	res, err := c.session.Elicit(c.ctx, &mcp.ElicitParams{
+		Message:         permissionMessage(req),
+		RequestedSchema: permissionSchema,
+	})
+	switch {
+	case err != nil:
+		slog.Warn("Denying permission request, asking the client failed", "tool", req.ToolName, "error", err)
+		s.permissions.Deny(req)
+	case res.Action != "accept":
+		s.permissions.Deny(req)
+	case res.Content["remember"] == true:
+		s.permissions.GrantPersistent(req)
+	default:
+		s.permissions.Grant(req)
+	}
+}
+
+// findCall finds the call a permission request comes from: a tool call, a
+// prompt, or a prompt of a parent session for sub-agents.
+func (s *Server) findCall(req permission.PermissionRequest) (call, bool) {
+	if c, ok := s.calls.Get(req.ToolCallID); ok {
+		return c, true
+	}
+	sessionID := req.SessionID
+	for sessionID != "" {
+		if c, ok := s.calls.Get(sessionID); ok {
+			return c, true
+		}
+		sess, err := s.sessions.Get(s.ctx, sessionID)
+		if err != nil {
+			break
+		}
+		sessionID = sess.ParentSessionID
+	}
+	return call{}, false
+}
+
+var permissionSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"remember": map[string]any{
+			"type":        "boolean",
+			"title":       "Don't ask again",
+			"description": "Allow this for the rest of the session",
+			"default":     false,
+		},
+	},
+}
+
+func permissionMessage(req permission.PermissionRequest) string {
+	msg := fmt.Sprintf("Allow %s to %s %s?", req.ToolName, req.Action, req.Path)
+	if req.Description != "" {
+		msg += "\n\n" + req.Description
+	}
+	return msg
+}
+
+func supportsElicitation(ss *mcp.ServerSession) bool {
+	params := ss.InitializeParams()
+	return params != nil && params.Capabilities != nil && params.Capabilities.Elicitation != nil
+}
+
+func toolResult(resp fantasy.ToolResponse) *mcp.CallToolResult {
+	result := &mcp.CallToolResult{IsError: resp.IsError}
+	if resp.Content != "" {
+		result.Content = append(result.Content, &mcp.TextContent{Text: resp.Content})
+	}
+	if len(resp.Data) > 0 {
+		switch {
+		case strings.HasPrefix(resp.MediaType, "image/"):
+			result.Content = append(result.Content, &mcp.ImageContent{Data: resp.Data, MIMEType: resp.MediaType})
+		case strings.HasPrefix(resp.MediaType, "audio/"):
+			result.Content = append(result.Content, &mcp.AudioContent{Data: resp.Data, MIMEType: resp.MediaType})
+		}
+	}
+	if len(result.Content) == 0 {
+		result.Content = []mcp.Content{&mcp.TextContent{}}
+	}
+	return result
+}
+
+func errorResult(err error) *mcp.CallToolResult {
+	return &mcp.CallToolResult{
+		IsError: true,
+		Content: []mcp.Content{&mcp.TextContent{Text: err.Error()}},
+	}
+}
@@ -0,0 +1,212 @@
+package mcpserver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/agent/tools"
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/require"
+)
+
+type touchParams struct {
+	Path string `json:"path" description:"The file to touch"`
+}
+
+// touchTool asks for permission to write the given path and returns the
+// outcome.
+func touchTool(permissions permission.Service) fantasy.AgentTool {
+	return fantasy.NewAgentTool("touch", "Touches a file", func(ctx context.Context, params touchParams, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+		granted, err := permissions.Request(ctx, permission.CreatePermissionRequest{
+			SessionID:  tools.GetSessionFromContext(ctx),
+			ToolCallID: call.ID,
+			ToolName:   "touch",
+			Action:     "write",
+			Path:       params.Path,
+		})
+		if err != nil {
+			return fantasy.ToolResponse{}, err
+		}
+		if !granted {
+			return fantasy.ToolResponse{}, permission.ErrorPermissionDenied
+		}
+		return fantasy.NewTextResponse("touched " + params.Path), nil
+	})
+}
+
+// fakeAgent answers prompts with the prompt itself.
+type fakeAgent struct{}
+
+func (fakeAgent) Run(_ context.Context, _, prompt string, _ ...message.Attachment) (*fantasy.AgentResult, error) {
+	return &fantasy.AgentResult{
+		Response: fantasy.Response{
+			Content: fantasy.ResponseContent{fantasy.TextContent{Text: "you said: " + prompt}},
+		},
+	}, nil
+}
+
+func (fakeAgent) IsSessionBusy(string) bool { return false }
+
+func setupServer(t *testing.T, policy Policy, agent Agent, clientOpts *mcp.ClientOptions) (*Server, *mcp.ClientSession, session.Service) {
+	t.Helper()
+
+	conn, err := db.Connect(t.Context(), t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	q := db.New(conn)
+	sessions := session.NewService(q, conn)
+	permissions := permission.NewPermissionService(t.TempDir(), false, nil, nil, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	server := New(ctx, Options{
+		Tools:       []fantasy.AgentTool{touchTool(permissions)},
+		Sessions:    sessions,
+		Permissions: permissions,
+		Agent:       agent,
+		Policy:      policy,
+	})
+
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	go func() { _ = server.Run(ctx, serverTransport) }()
+
+	client := mcp.NewClient(&mcp.Implementation{Name: "test"}, clientOpts)
+	cs, err := client.Connect(t.Context(), clientTransport, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { cs.Close() })
+	return server, cs, sessions
+}
+
+func callTool(t *testing.T, cs *mcp.ClientSession, name string, args map[string]any) *mcp.CallToolResult {
+	t.Helper()
+	result, err := cs.CallTool(t.Context(), &mcp.CallToolParams{Name: name, Arguments: args})
+	require.NoError(t, err)
+	return result
+}
+
+func resultText(t *testing.T, result *mcp.CallToolResult) string {
+	t.Helper()
+	require.Len(t, result.Content, 1)
+	return result.Content[0].(*mcp.TextContent).Text
+}
+
+func TestTools(t *testing.T) {
+	t.Parallel()
+
+	server, cs, sessions := setupServer(t, PolicyAllow, nil, nil)
+	tools, err := cs.ListTools(t.Context(), nil)
+	require.NoError(t, err)
+	require.Len(t, tools.Tools, 1)
+	require.Equal(t, "touch", tools.Tools[0].Name)
+
+	result := callTool(t, cs, "touch", map[string]any{"path": "a.txt"})
+	require.False(t, result.IsError)
+	require.Equal(t, "touched a.txt", resultText(t, result))
+
+	// Tool calls of a client share a session.
+	callTool(t, cs, "touch", map[string]any{"path": "b.txt"})
+	list, err := sessions.List(t.Context())
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.Equal(t, "MCP: test", list[0].Title)
+
+	// Clients are forgotten once they disconnect.
+	require.NoError(t, cs.Close())
+	require.Eventually(t, func() bool {
+		server.clientsMu.Lock()
+		defer server.clientsMu.Unlock()
+		return len(server.clients) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestPermissions(t *testing.T) {
+	t.Parallel()
+
+	t.Run("deny policy", func(t *testing.T) {
+		t.Parallel()
+		_, cs, _ := setupServer(t, PolicyDeny, nil, nil)
+		result := callTool(t, cs, "touch", map[string]any{"path": "a.txt"})
+		require.True(t, result.IsError)
+		require.Equal(t, permission.ErrorPermissionDenied.Error(), resultText(t, result))
+	})
+
+	t.Run("no elicitation", func(t *testing.T) {
+		t.Parallel()
+		_, cs, _ := setupServer(t, PolicyAsk, nil, nil)
+		result := callTool(t, cs, "touch", map[string]any{"path": "a.txt"})
+		require.True(t, result.IsError)
+	})
+
+	t.Run("elicitation", func(t *testing.T) {
+		t.Parallel()
+		var asked []string
+		_, cs, _ := setupServer(t, PolicyAsk, nil, &mcp.ClientOptions{
+			ElicitationHandler: func(_ context.Context, req *mcp.ElicitRequest) (*mcp.ElicitResult, error) {
+				asked = append(asked, req.Params.Message)
+				if len(asked) == 1 {
+					return &mcp.ElicitResult{Action: "decline"}, nil
+				}
+				return &mcp.ElicitResult{Action: "accept", Content: map[string]any{"remember": true}}, nil
+			},
+		})
+
+		result := callTool(t, cs, "touch", map[string]any{"path": "a.txt"})
+		require.True(t, result.IsError)
+		result = callTool(t, cs, "touch", map[string]any{"path": "a.txt"})
+		require.False(t, result.IsError)
+		require.Equal(t, []string{"Allow touch to write a.txt?", "Allow touch to write a.txt?"}, asked)
+
+		// Remembered for the rest of the session.
+		result = callTool(t, cs, "touch", map[string]any{"path": "a.txt"})
+		require.False(t, result.IsError)
+		require.Len(t, asked, 2)
+	})
+}
+
+func TestAsk(t *testing.T) {
+	t.Parallel()
+
+	_, cs, _ := setupServer(t, PolicyAsk, nil, nil)
+	tools, err := cs.ListTools(t.Context(), nil)
+	require.NoError(t, err)
+	require.Len(t, tools.Tools, 1, "ask_crush is only exposed with an agent")
+
+	_, cs, sessions := setupServer(t, PolicyAsk, fakeAgent{}, nil)
+	result := callTool(t, cs, AskToolName, map[string]any{"prompt": "hi"})
+	require.False(t, result.IsError)
+	require.Equal(t, "you said: hi", resultText(t, result))
+	out := result.StructuredContent.(map[string]any)
+	require.Equal(t, "you said: hi", out["response"])
+	sessionID := out["session_id"].(string)
+	_, err = sessions.Get(t.Context(), sessionID)
+	require.NoError(t, err)
+
+	result = callTool(t, cs, AskToolName, map[string]any{"prompt": "again", "session_id": sessionID})
+	require.False(t, result.IsError)
+	require.Equal(t, sessionID, result.StructuredContent.(map[string]any)["session_id"])
+
+	result = callTool(t, cs, AskToolName, map[string]any{"prompt": "hi", "session_id": "missing"})
+	require.True(t, result.IsError)
+	result = callTool(t, cs, AskToolName, map[string]any{"prompt": " "})
+	require.True(t, result.IsError)
+}
+
+func TestParsePolicy(t *testing.T) {
+	t.Parallel()
+
+	p, err := ParsePolicy("")
+	require.NoError(t, err)
+	require.Equal(t, PolicyAsk, p)
+	p, err = ParsePolicy("allow")
+	require.NoError(t, err)
+	require.Equal(t, PolicyAllow, p)
+	_, err = ParsePolicy("maybe")
+	require.Error(t, err)
+}
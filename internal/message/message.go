@@ -23,6 +23,9 @@ type CreateMessageParams struct {
 type Service interface {
 	pubsub.Subscriber[Message]
 	Create(ctx context.Context, sessionID string, params CreateMessageParams) (Message, error)
+	// Import adds a message from another session, keeping its parts and
+	// timestamps as they are, under a new ID.
+	Import(ctx context.Context, sessionID string, message Message) (Message, error)
 	Update(ctx context.Context, message Message) error
 	Get(ctx context.Context, id string) (Message, error)
 	List(ctx context.Context, sessionID string) ([]Message, error)
@@ -65,7 +68,7 @@ func (s *service) Create(ctx context.Context, sessionID string, params CreateMes
 			Reason: "stop",
 		})
 	}
-	partsJSON, err := marshalParts(params.Parts)
+	partsJSON, err := MarshalParts(params.Parts)
 	if err != nil {
 		return Message{}, err
 	}
@@ -95,6 +98,43 @@ func (s *service) Create(ctx context.Context, sessionID string, params CreateMes
 	return message, nil
 }
 
+func (s *service) Import(ctx context.Context, sessionID string, message Message) (Message, error) {
+	partsJSON, err := MarshalParts(message.Parts)
+	if err != nil {
+		return Message{}, err
+	}
+	finishedAt := sql.NullInt64{}
+	if f := message.FinishPart(); f != nil {
+		finishedAt.Int64 = f.Time
+		finishedAt.Valid = true
+	}
+	isSummary := int64(0)
+	if message.IsSummaryMessage {
+		isSummary = 1
+	}
+	dbMessage, err := s.q.ImportMessage(ctx, db.ImportMessageParams{
+		ID:               uuid.New().String(),
+		SessionID:        sessionID,
+		Role:             string(message.Role),
+		Parts:            string(partsJSON),
+		Model:            sql.NullString{String: message.Model, Valid: true},
+		Provider:         sql.NullString{String: message.Provider, Valid: message.Provider != ""},
+		IsSummaryMessage: isSummary,
+		CreatedAt:        message.CreatedAt,
+		UpdatedAt:        message.UpdatedAt,
+		FinishedAt:       finishedAt,
+	})
+	if err != nil {
+		return Message{}, err
+	}
+	imported, err := s.fromDBItem(dbMessage)
+	if err != nil {
+		return Message{}, err
+	}
+	s.Publish(pubsub.CreatedEvent, imported.Clone())
+	return imported, nil
+}
+
 func (s *service) DeleteSessionMessages(ctx context.Context, sessionID string) error {
 	messages, err := s.List(ctx, sessionID)
 	if err != nil {
@@ -112,7 +152,7 @@ func (s *service) DeleteSessionMessages(ctx context.Context, sessionID string) e
 }
 
 func (s *service) Update(ctx context.Context, message Message) error {
-	parts, err := marshalParts(message.Parts)
+	parts, err := MarshalParts(message.Parts)
 	if err != nil {
 		return err
 	}
@@ -124,6 +164,8 @@ func (s *service) Update(ctx context.Context, message Message) error {
 	err = s.q.UpdateMessage(ctx, db.UpdateMessageParams{
 		ID:         message.ID,
 		Parts:      string(parts),
+		Model:      sql.NullString{String: message.Model, Valid: true},
+		Provider:   sql.NullString{String: message.Provider, Valid: message.Provider != ""},
 		FinishedAt: finishedAt,
 	})
 	if err != nil {
@@ -190,7 +232,7 @@ func (s *service) ListAllUserMessages(ctx context.Context) ([]Message, error) {
 }
 
 func (s *service) fromDBItem(item db.Message) (Message, error) {
-	parts, err := unmarshalParts([]byte(item.Parts))
+	parts, err := UnmarshalParts([]byte(item.Parts))
 	if err != nil {
 		return Message{}, err
 	}
@@ -224,7 +266,9 @@ type partWrapper struct {
 	Data ContentPart `json:"data"`
 }
 
-func marshalParts(parts []ContentPart) ([]byte, error) {
+// MarshalParts encodes content parts as JSON, tagging each part with its type
+// so they can be decoded with [UnmarshalParts].
+func MarshalParts(parts []ContentPart) ([]byte, error) {
 	wrappedParts := make([]partWrapper, len(parts))
 
 	for i, part := range parts {
@@ -257,7 +301,8 @@ func marshalParts(parts []ContentPart) ([]byte, error) {
 	return json.Marshal(wrappedParts)
 }
 
-func unmarshalParts(data []byte) ([]ContentPart, error) {
+// UnmarshalParts decodes content parts encoded with [MarshalParts].
+func UnmarshalParts(data []byte) ([]ContentPart, error) {
 	temp := []json.RawMessage{}
 
 	if err := json.Unmarshal(data, &temp); err != nil {
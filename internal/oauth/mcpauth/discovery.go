@@ -0,0 +1,263 @@
+package mcpauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// metadata describes how to authorize with an MCP server.
+type metadata struct {
+	// resource is the canonical URL of the MCP server. It's sent along the
+	// authorization and token requests, so tokens are only valid for it.
+	resource string
+	// scopes are the scopes to request.
+	scopes []string
+	server serverMetadata
+}
+
+// resourceMetadata is the OAuth protected resource metadata of an MCP
+// server (RFC 9728).
+type resourceMetadata struct {
+	Resource             string   `json:"resource"`
+	AuthorizationServers []string `json:"authorization_servers"`
+	ScopesSupported      []string `json:"scopes_supported,omitempty"`
+}
+
+// serverMetadata is the metadata of an authorization server (RFC 8414).
+type serverMetadata struct {
+	Issuer                        string   `json:"issuer"`
+	AuthorizationEndpoint         string   `json:"authorization_endpoint"`
+	TokenEndpoint                 string   `json:"token_endpoint"`
+	RegistrationEndpoint          string   `json:"registration_endpoint,omitempty"`
+	DeviceAuthorizationEndpoint   string   `json:"device_authorization_endpoint,omitempty"`
+	ScopesSupported               []string `json:"scopes_supported,omitempty"`
+	CodeChallengeMethodsSupported []string `json:"code_challenge_methods_supported,omitempty"`
+}
+
+var challengeParam = regexp.MustCompile(`([a-zA-Z_]+)=(?:"((?:[^"\\]|\\.)*)"|([^\s,]+))`)
+
+// parseChallenge returns the parameters of a WWW-Authenticate header.
+func parseChallenge(header string) map[string]string {
+	params := make(map[string]string)
+	for _, match := range challengeParam.FindAllStringSubmatch(header, -1) {
+		value := match[3]
+		if value == "" {
+			value = strings.ReplaceAll(match[2], `\"`, `"`)
+		}
+		params[strings.ToLower(match[1])] = value
+	}
+	return params
+}
+
+// discover finds the authorization server of an MCP server, following the
+// MCP authorization spec. Servers without protected resource metadata are
+// expected to be their own authorization server.
+func discover(ctx context.Context, client *http.Client, serverURL, challenge string) (*metadata, error) {
+	resource, err := canonicalURL(serverURL)
+	if err != nil {
+		return nil, err
+	}
+	md := &metadata{resource: resource}
+
+	params := parseChallenge(challenge)
+	if scope := params["scope"]; scope != "" {
+		md.scopes = strings.Fields(scope)
+	}
+
+	var candidates []string
+	if u := params["resource_metadata"]; u != "" {
+		candidates = append(candidates, u)
+	}
+	candidates = append(candidates, wellKnownURLs(resource, "oauth-protected-resource")...)
+
+	var prm resourceMetadata
+	found, err := fetchFirst(ctx, client, candidates, &prm)
+	if err != nil {
+		return nil, err
+	}
+
+	issuer := origin(resource)
+	if found {
+		if len(prm.AuthorizationServers) == 0 {
+			return nil, fmt.Errorf("protected resource metadata of %s lists no authorization servers", resource)
+		}
+		issuer = prm.AuthorizationServers[0]
+		if len(md.scopes) == 0 {
+			md.scopes = prm.ScopesSupported
+		}
+	}
+
+	ok, err := fetchFirst(ctx, client, serverMetadataURLs(issuer), &md.server)
+	switch {
+	case err != nil:
+		return nil, err
+	case !ok && found:
+		return nil, fmt.Errorf("no authorization server metadata found for %s", issuer)
+	case !ok:
+		// Servers predating protected resource metadata may not publish
+		// their metadata either, in which case the default endpoints are
+		// used.
+		md.server = serverMetadata{
+			Issuer:                issuer,
+			AuthorizationEndpoint: issuer + "/authorize",
+			TokenEndpoint:         issuer + "/token",
+			RegistrationEndpoint:  issuer + "/register",
+		}
+	}
+
+	if methods := md.server.CodeChallengeMethodsSupported; len(methods) > 0 && !slices.Contains(methods, "S256") {
+		return nil, fmt.Errorf("authorization server %s doesn't support PKCE with S256", issuer)
+	}
+	for _, endpoint := range []string{
+		md.server.AuthorizationEndpoint,
+		md.server.TokenEndpoint,
+		md.server.RegistrationEndpoint,
+		md.server.DeviceAuthorizationEndpoint,
+	} {
+		if err := checkEndpoint(endpoint); err != nil {
+			return nil, err
+		}
+	}
+	return md, nil
+}
+
+// canonicalURL returns the URL identifying an MCP server as a resource.
+func canonicalURL(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid server url: %w", err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("invalid server url: %q", rawURL)
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.Fragment = ""
+	u.RawQuery = ""
+	return u.String(), nil
+}
+
+// SameServer reports whether two URLs point to the same MCP server.
+func SameServer(a, b string) bool {
+	ca, err := canonicalURL(a)
+	if err != nil {
+		return false
+	}
+	cb, err := canonicalURL(b)
+	return err == nil && ca == cb
+}
+
+func origin(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// wellKnownURLs returns the well-known URLs of a resource, with the path of
+// the resource inserted after the well-known suffix first, and at the root
+// second.
+func wellKnownURLs(rawURL, suffix string) []string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil
+	}
+	root := u.Scheme + "://" + u.Host + "/.well-known/" + suffix
+	path := strings.TrimSuffix(u.Path, "/")
+	if path == "" {
+		return []string{root}
+	}
+	return []string{root + path, root}
+}
+
+// serverMetadataURLs returns the URLs the metadata of an authorization
+// server may be published at, for both OAuth and OpenID Connect.
+func serverMetadataURLs(issuer string) []string {
+	u, err := url.Parse(issuer)
+	if err != nil {
+		return nil
+	}
+	root := u.Scheme + "://" + u.Host
+	path := strings.TrimSuffix(u.Path, "/")
+	if path == "" {
+		return []string{
+			root + "/.well-known/oauth-authorization-server",
+			root + "/.well-known/openid-configuration",
+		}
+	}
+	return []string{
+		root + "/.well-known/oauth-authorization-server" + path,
+		root + "/.well-known/openid-configuration" + path,
+		root + path + "/.well-known/openid-configuration",
+	}
+}
+
+// fetchFirst decodes the first of the given URLs found into v. Only network
+// errors are returned, as missing or invalid documents are expected while
+// looking for metadata.
+func fetchFirst(ctx context.Context, client *http.Client, urls []string, v any) (bool, error) {
+	for _, u := range urls {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			continue
+		}
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("User-Agent", "crush")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return false, ctx.Err()
+			}
+			return false, fmt.Errorf("fetch %s: %w", u, err)
+		}
+		body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		resp.Body.Close()
+		if err != nil || resp.StatusCode != http.StatusOK {
+			continue
+		}
+		if err := json.Unmarshal(body, v); err != nil {
+			continue
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// checkEndpoint makes sure credentials are only sent over HTTPS, or to the
+// local machine.
+func checkEndpoint(endpoint string) error {
+	if endpoint == "" {
+		return nil
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid endpoint %q: %w", endpoint, err)
+	}
+	switch u.Scheme {
+	case "https":
+		return nil
+	case "http":
+		if isLoopback(u.Hostname()) {
+			return nil
+		}
+	}
+	return fmt.Errorf("endpoint %q must use https", endpoint)
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
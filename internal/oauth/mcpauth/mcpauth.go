@@ -0,0 +1,505 @@
+// Package mcpauth implements the OAuth 2.1 authorization of remote MCP
+// servers: discovery of their authorization server, dynamic client
+// registration, the authorization code flow with PKCE and a local callback,
+// the device authorization flow, and token refreshes.
+package mcpauth
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/oauth"
+)
+
+// ErrNoRefreshToken is returned when refreshing credentials that can't be
+// refreshed.
+var ErrNoRefreshToken = errors.New("no refresh token")
+
+const deviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+// Credentials are kept after authorizing with an MCP server, to
+// authenticate with it and refresh the access token.
+type Credentials struct {
+	// ServerURL is the URL of the MCP server the credentials were issued
+	// for.
+	ServerURL    string       `json:"server_url"`
+	ClientID     string       `json:"client_id"`
+	ClientSecret string       `json:"client_secret,omitempty"`
+	TokenURL     string       `json:"token_url"`
+	Token        *oauth.Token `json:"token"`
+}
+
+// Expired reports whether the access token expired. Tokens without an
+// expiration are used until the server rejects them.
+func (c Credentials) Expired() bool {
+	return c.Token == nil || (c.Token.ExpiresIn > 0 && c.Token.IsExpired())
+}
+
+// Options configures an authorization.
+type Options struct {
+	// ServerURL is the URL of the MCP server.
+	ServerURL string
+	// Challenge is the WWW-Authenticate header of the response the server
+	// rejected, if any. It points to the metadata of the server.
+	Challenge string
+	// ClientID and ClientSecret identify a client registered with the
+	// authorization server beforehand. When empty, a client is registered
+	// dynamically.
+	ClientID     string
+	ClientSecret string
+	// Scopes are the scopes to request, instead of the ones the server
+	// asks for.
+	Scopes []string
+	// CallbackPort is the port of the local callback receiving the
+	// authorization code. A random port is used when zero.
+	CallbackPort int
+	// Device prefers the device authorization flow, when the authorization
+	// server supports it. It's useful when the browser can't reach the
+	// local callback, like over SSH.
+	Device bool
+	// HTTPClient makes the requests to the servers.
+	HTTPClient *http.Client
+}
+
+// Authorization is an authorization waiting for the user.
+type Authorization struct {
+	// URL is where the user authorizes Crush.
+	URL string
+	// UserCode is the code to enter at URL, in the device flow.
+	UserCode string
+
+	md           *metadata
+	client       *http.Client
+	clientID     string
+	clientSecret string
+
+	// Authorization code flow.
+	redirectURI string
+	verifier    string
+	server      *http.Server
+	codes       chan callbackResult
+
+	// Device authorization flow.
+	deviceCode string
+	interval   time.Duration
+	expiresAt  time.Time
+}
+
+type callbackResult struct {
+	code string
+	err  error
+}
+
+// Start discovers the authorization server of an MCP server, registers a
+// client if needed, and starts an authorization. The user completes it at
+// the returned URL, which [Authorization.Wait] waits for.
+func Start(ctx context.Context, opts Options) (*Authorization, error) {
+	client := cmp.Or(opts.HTTPClient, &http.Client{Timeout: 30 * time.Second})
+	md, err := discover(ctx, client, opts.ServerURL, opts.Challenge)
+	if err != nil {
+		return nil, err
+	}
+	if len(opts.Scopes) > 0 {
+		md.scopes = opts.Scopes
+	}
+
+	a := &Authorization{
+		md:           md,
+		client:       client,
+		clientID:     opts.ClientID,
+		clientSecret: opts.ClientSecret,
+	}
+	if opts.Device && md.server.DeviceAuthorizationEndpoint != "" {
+		if err := a.startDevice(ctx); err != nil {
+			return nil, err
+		}
+		return a, nil
+	}
+	if err := a.startCode(ctx, opts.CallbackPort); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// startCode starts the authorization code flow, with a local callback.
+func (a *Authorization) startCode(ctx context.Context, port int) error {
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		return fmt.Errorf("listen for the authorization callback: %w", err)
+	}
+	a.redirectURI = fmt.Sprintf("http://127.0.0.1:%d/callback", listener.Addr().(*net.TCPAddr).Port)
+
+	if a.clientID == "" {
+		if err := a.register(ctx, registrationRequest{
+			RedirectURIs:  []string{a.redirectURI},
+			GrantTypes:    []string{"authorization_code", "refresh_token"},
+			ResponseTypes: []string{"code"},
+		}); err != nil {
+			listener.Close()
+			return err
+		}
+	}
+
+	a.verifier = randomString()
+	state := randomString()
+	sum := sha256.Sum256([]byte(a.verifier))
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {a.clientID},
+		"redirect_uri":          {a.redirectURI},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(sum[:])},
+		"code_challenge_method": {"S256"},
+		"state":                 {state},
+		"resource":              {a.md.resource},
+	}
+	if len(a.md.scopes) > 0 {
+		query.Set("scope", strings.Join(a.md.scopes, " "))
+	}
+	authURL, err := url.Parse(a.md.server.AuthorizationEndpoint)
+	if err != nil {
+		listener.Close()
+		return fmt.Errorf("invalid authorization endpoint: %w", err)
+	}
+	for k, v := range authURL.Query() {
+		if _, ok := query[k]; !ok {
+			query[k] = v
+		}
+	}
+	authURL.RawQuery = query.Encode()
+	a.URL = authURL.String()
+
+	a.codes = make(chan callbackResult, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		var result callbackResult
+		switch {
+		case q.Get("state") != state:
+			http.Error(w, "Invalid state.", http.StatusBadRequest)
+			return
+		case q.Get("error") != "":
+			result.err = fmt.Errorf("authorization denied: %s", cmp.Or(q.Get("error_description"), q.Get("error")))
+		case q.Get("code") == "":
+			result.err = errors.New("authorization callback without a code")
+		default:
+			result.code = q.Get("code")
+		}
+		if result.err != nil {
+			http.Error(w, "Authorization failed. You can close this window and return to Crush.", http.StatusBadRequest)
+		} else {
+			fmt.Fprintln(w, "Authorization complete. You can close this window and return to Crush.")
+		}
+		select {
+		case a.codes <- result:
+		default:
+		}
+	})
+	a.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go a.server.Serve(listener) //nolint:errcheck
+	return nil
+}
+
+// startDevice starts the device authorization flow (RFC 8628).
+func (a *Authorization) startDevice(ctx context.Context) error {
+	if a.clientID == "" {
+		if err := a.register(ctx, registrationRequest{
+			GrantTypes: []string{deviceCodeGrantType, "refresh_token"},
+		}); err != nil {
+			return err
+		}
+	}
+
+	form := url.Values{
+		"client_id": {a.clientID},
+		"resource":  {a.md.resource},
+	}
+	if len(a.md.scopes) > 0 {
+		form.Set("scope", strings.Join(a.md.scopes, " "))
+	}
+	var resp struct {
+		DeviceCode              string `json:"device_code"`
+		UserCode                string `json:"user_code"`
+		VerificationURI         string `json:"verification_uri"`
+		VerificationURIComplete string `json:"verification_uri_complete"`
+		ExpiresIn               int    `json:"expires_in"`
+		Interval                int    `json:"interval"`
+	}
+	if err := postForm(ctx, a.client, a.md.server.DeviceAuthorizationEndpoint, form, &resp); err != nil {
+		return fmt.Errorf("device authorization failed: %w", err)
+	}
+
+	a.URL = cmp.Or(resp.VerificationURIComplete, resp.VerificationURI)
+	a.UserCode = resp.UserCode
+	a.deviceCode = resp.DeviceCode
+	a.interval = time.Duration(cmp.Or(resp.Interval, 5)) * time.Second
+	a.expiresAt = time.Now().Add(time.Duration(cmp.Or(resp.ExpiresIn, 600)) * time.Second)
+	return nil
+}
+
+// Wait waits for the user to complete the authorization, and returns the
+// credentials obtained.
+func (a *Authorization) Wait(ctx context.Context) (Credentials, error) {
+	defer a.Close()
+
+	creds := Credentials{
+		ServerURL:    a.md.resource,
+		ClientID:     a.clientID,
+		ClientSecret: a.clientSecret,
+		TokenURL:     a.md.server.TokenEndpoint,
+	}
+	var (
+		token *oauth.Token
+		err   error
+	)
+	if a.deviceCode != "" {
+		token, err = a.poll(ctx, creds)
+	} else {
+		select {
+		case <-ctx.Done():
+			return Credentials{}, ctx.Err()
+		case result := <-a.codes:
+			if result.err != nil {
+				return Credentials{}, result.err
+			}
+			token, err = requestToken(ctx, a.client, creds, url.Values{
+				"grant_type":    {"authorization_code"},
+				"code":          {result.code},
+				"redirect_uri":  {a.redirectURI},
+				"code_verifier": {a.verifier},
+				"client_id":     {a.clientID},
+			})
+		}
+	}
+	if err != nil {
+		return Credentials{}, err
+	}
+	creds.Token = token
+	return creds, nil
+}
+
+// poll polls the token endpoint until the user completes the device
+// authorization.
+func (a *Authorization) poll(ctx context.Context, creds Credentials) (*oauth.Token, error) {
+	interval := a.interval
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+		if time.Now().After(a.expiresAt) {
+			return nil, errors.New("the device code expired")
+		}
+
+		token, err := requestToken(ctx, a.client, creds, url.Values{
+			"grant_type":  {deviceCodeGrantType},
+			"device_code": {a.deviceCode},
+			"client_id":   {a.clientID},
+		})
+		var tokenErr *tokenError
+		switch {
+		case errors.As(err, &tokenErr) && tokenErr.Code == "authorization_pending":
+			continue
+		case errors.As(err, &tokenErr) && tokenErr.Code == "slow_down":
+			interval += 5 * time.Second
+			continue
+		case err != nil:
+			return nil, err
+		}
+		return token, nil
+	}
+}
+
+// Close stops waiting for the authorization.
+func (a *Authorization) Close() {
+	if a.server != nil {
+		_ = a.server.Close()
+	}
+}
+
+// Refresh exchanges the refresh token of the credentials for a new access
+// token.
+func Refresh(ctx context.Context, client *http.Client, creds Credentials) (Credentials, error) {
+	if creds.Token == nil || creds.Token.RefreshToken == "" {
+		return Credentials{}, ErrNoRefreshToken
+	}
+	client = cmp.Or(client, &http.Client{Timeout: 30 * time.Second})
+	token, err := requestToken(ctx, client, creds, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {creds.Token.RefreshToken},
+		"client_id":     {creds.ClientID},
+	})
+	if err != nil {
+		return Credentials{}, fmt.Errorf("failed to refresh token: %w", err)
+	}
+	// Servers may keep the refresh token the same without returning it.
+	token.RefreshToken = cmp.Or(token.RefreshToken, creds.Token.RefreshToken)
+	creds.Token = token
+	return creds, nil
+}
+
+type registrationRequest struct {
+	ClientName              string   `json:"client_name"`
+	ClientURI               string   `json:"client_uri,omitempty"`
+	RedirectURIs            []string `json:"redirect_uris,omitempty"`
+	GrantTypes              []string `json:"grant_types"`
+	ResponseTypes           []string `json:"response_types,omitempty"`
+	TokenEndpointAuthMethod string   `json:"token_endpoint_auth_method"`
+	Scope                   string   `json:"scope,omitempty"`
+}
+
+// register registers Crush as a public client with the authorization server
+// (RFC 7591).
+func (a *Authorization) register(ctx context.Context, reg registrationRequest) error {
+	if a.md.server.RegistrationEndpoint == "" {
+		return errors.New("the authorization server doesn't support dynamic client registration, set a client_id in the oauth options of the server")
+	}
+	reg.ClientName = "Crush"
+	reg.ClientURI = "https://github.com/charmbracelet/crush"
+	reg.TokenEndpointAuthMethod = "none"
+	reg.Scope = strings.Join(a.md.scopes, " ")
+
+	data, err := json.Marshal(reg)
+	if err != nil {
+		return fmt.Errorf("marshal registration: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.md.server.RegistrationEndpoint, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "crush")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("client registration failed: status %d, body %q", resp.StatusCode, string(body))
+	}
+
+	var client struct {
+		ClientID     string `json:"client_id"`
+		ClientSecret string `json:"client_secret"`
+	}
+	if err := json.Unmarshal(body, &client); err != nil {
+		return fmt.Errorf("unmarshal response: %w", err)
+	}
+	if client.ClientID == "" {
+		return errors.New("client registration returned no client_id")
+	}
+	a.clientID = client.ClientID
+	a.clientSecret = client.ClientSecret
+	return nil
+}
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// tokenError is an error response of an OAuth endpoint.
+type tokenError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+func (e *tokenError) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("%s: %s", e.Code, e.Description)
+	}
+	return e.Code
+}
+
+// requestToken requests a token for the credentials, with the given grant.
+func requestToken(ctx context.Context, client *http.Client, creds Credentials, form url.Values) (*oauth.Token, error) {
+	form.Set("resource", creds.ServerURL)
+	var resp tokenResponse
+	if err := postForm(ctx, client, creds.TokenURL, withSecret(form, creds.ClientSecret), &resp); err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	if resp.AccessToken == "" {
+		return nil, errors.New("token response has no access_token")
+	}
+	token := &oauth.Token{
+		AccessToken:  resp.AccessToken,
+		RefreshToken: resp.RefreshToken,
+		ExpiresIn:    resp.ExpiresIn,
+	}
+	if token.ExpiresIn > 0 {
+		token.SetExpiresAt()
+	}
+	return token, nil
+}
+
+func withSecret(form url.Values, secret string) url.Values {
+	if secret == "" {
+		return form
+	}
+	form.Set("client_secret", secret)
+	return form
+}
+
+// postForm posts a form to an OAuth endpoint and decodes the response into
+// v. OAuth error responses are returned as a *tokenError.
+func postForm(ctx context.Context, client *http.Client, endpoint string, form url.Values, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "crush")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var tokenErr tokenError
+		if json.Unmarshal(body, &tokenErr) == nil && tokenErr.Code != "" {
+			return &tokenErr
+		}
+		return fmt.Errorf("status %d, body %q", resp.StatusCode, string(body))
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("unmarshal response: %w", err)
+	}
+	return nil
+}
+
+// randomString returns a random URL safe string, for PKCE verifiers and
+// states.
+func randomString() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,235 @@
+package mcpauth
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// authServer is a stand-in for an MCP server protected by an authorization
+// server, both served by the same test server.
+type authServer struct {
+	*httptest.Server
+
+	mu            sync.Mutex
+	redirectURI   string
+	codeChallenge string
+	devicePending bool
+	resources     []string
+}
+
+func newAuthServer(t *testing.T) *authServer {
+	t.Helper()
+	s := &authServer{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mcp", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "Bearer access-1" {
+			return
+		}
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer resource_metadata="%s/.well-known/oauth-protected-resource/mcp", scope="read write"`, s.URL))
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	mux.HandleFunc("/.well-known/oauth-protected-resource/mcp", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, resourceMetadata{
+			Resource:             s.URL + "/mcp",
+			AuthorizationServers: []string{s.URL + "/auth"},
+		})
+	})
+	mux.HandleFunc("/.well-known/oauth-authorization-server/auth", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, serverMetadata{
+			Issuer:                        s.URL + "/auth",
+			AuthorizationEndpoint:         s.URL + "/auth/authorize",
+			TokenEndpoint:                 s.URL + "/auth/token",
+			RegistrationEndpoint:          s.URL + "/auth/register",
+			DeviceAuthorizationEndpoint:   s.URL + "/auth/device",
+			CodeChallengeMethodsSupported: []string{"S256"},
+		})
+	})
+	mux.HandleFunc("/auth/register", func(w http.ResponseWriter, r *http.Request) {
+		var reg registrationRequest
+		if err := json.NewDecoder(r.Body).Decode(&reg); err != nil || reg.TokenEndpointAuthMethod != "none" {
+			writeJSON(w, http.StatusBadRequest, tokenError{Code: "invalid_client_metadata"})
+			return
+		}
+		s.mu.Lock()
+		if len(reg.RedirectURIs) > 0 {
+			s.redirectURI = reg.RedirectURIs[0]
+		}
+		s.mu.Unlock()
+		writeJSON(w, http.StatusCreated, map[string]string{"client_id": "client-1"})
+	})
+	mux.HandleFunc("/auth/authorize", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if q.Get("client_id") != "client-1" || q.Get("redirect_uri") != s.redirectURI || q.Get("code_challenge_method") != "S256" || q.Get("scope") != "read write" {
+			http.Error(w, "invalid request", http.StatusBadRequest)
+			return
+		}
+		s.codeChallenge = q.Get("code_challenge")
+		s.resources = append(s.resources, q.Get("resource"))
+		http.Redirect(w, r, q.Get("redirect_uri")+"?code=code-1&state="+url.QueryEscape(q.Get("state")), http.StatusFound)
+	})
+	mux.HandleFunc("/auth/device", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{
+			"device_code":      "device-1",
+			"user_code":        "ABCD-EFGH",
+			"verification_uri": s.URL + "/auth/verify",
+			"expires_in":       60,
+			"interval":         1,
+		})
+	})
+	mux.HandleFunc("/auth/token", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.resources = append(s.resources, r.FormValue("resource"))
+		if r.FormValue("client_id") != "client-1" {
+			writeJSON(w, http.StatusUnauthorized, tokenError{Code: "invalid_client"})
+			return
+		}
+		switch r.FormValue("grant_type") {
+		case "authorization_code":
+			sum := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+			if r.FormValue("code") != "code-1" || base64.RawURLEncoding.EncodeToString(sum[:]) != s.codeChallenge || r.FormValue("redirect_uri") != s.redirectURI {
+				writeJSON(w, http.StatusBadRequest, tokenError{Code: "invalid_grant"})
+				return
+			}
+			writeJSON(w, http.StatusOK, tokenResponse{AccessToken: "access-1", RefreshToken: "refresh-1", ExpiresIn: 3600})
+		case deviceCodeGrantType:
+			if !s.devicePending {
+				s.devicePending = true
+				writeJSON(w, http.StatusBadRequest, tokenError{Code: "authorization_pending"})
+				return
+			}
+			writeJSON(w, http.StatusOK, tokenResponse{AccessToken: "access-1", RefreshToken: "refresh-1"})
+		case "refresh_token":
+			if r.FormValue("refresh_token") != "refresh-1" {
+				writeJSON(w, http.StatusBadRequest, tokenError{Code: "invalid_grant"})
+				return
+			}
+			writeJSON(w, http.StatusOK, tokenResponse{AccessToken: "access-2", ExpiresIn: 3600})
+		default:
+			writeJSON(w, http.StatusBadRequest, tokenError{Code: "unsupported_grant_type"})
+		}
+	})
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// challenge returns the WWW-Authenticate header of an unauthorized request
+// to the MCP server.
+func (s *authServer) challenge(t *testing.T) string {
+	t.Helper()
+	resp, err := http.Get(s.URL + "/mcp")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	return resp.Header.Get("WWW-Authenticate")
+}
+
+func TestAuthorizationCode(t *testing.T) {
+	t.Parallel()
+	srv := newAuthServer(t)
+
+	auth, err := Start(t.Context(), Options{
+		ServerURL: srv.URL + "/mcp",
+		Challenge: srv.challenge(t),
+	})
+	require.NoError(t, err)
+	require.Empty(t, auth.UserCode)
+
+	// Stand in for the browser, which follows the redirect to the local
+	// callback.
+	resp, err := http.Get(auth.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	creds, err := auth.Wait(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, srv.URL+"/mcp", creds.ServerURL)
+	require.Equal(t, "client-1", creds.ClientID)
+	require.Equal(t, srv.URL+"/auth/token", creds.TokenURL)
+	require.Equal(t, "access-1", creds.Token.AccessToken)
+	require.Equal(t, "refresh-1", creds.Token.RefreshToken)
+	require.False(t, creds.Expired())
+
+	// The token is bound to the MCP server.
+	require.Equal(t, []string{srv.URL + "/mcp", srv.URL + "/mcp"}, srv.resources)
+
+	refreshed, err := Refresh(t.Context(), nil, creds)
+	require.NoError(t, err)
+	require.Equal(t, "access-2", refreshed.Token.AccessToken)
+	require.Equal(t, "refresh-1", refreshed.Token.RefreshToken)
+}
+
+func TestDeviceAuthorization(t *testing.T) {
+	t.Parallel()
+	srv := newAuthServer(t)
+
+	auth, err := Start(t.Context(), Options{
+		ServerURL: srv.URL + "/mcp",
+		Challenge: srv.challenge(t),
+		Device:    true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "ABCD-EFGH", auth.UserCode)
+	require.Equal(t, srv.URL+"/auth/verify", auth.URL)
+
+	creds, err := auth.Wait(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, "access-1", creds.Token.AccessToken)
+	// Tokens without an expiration are used until they're rejected.
+	require.False(t, creds.Expired())
+}
+
+func TestRefreshWithoutRefreshToken(t *testing.T) {
+	t.Parallel()
+	_, err := Refresh(t.Context(), nil, Credentials{})
+	require.ErrorIs(t, err, ErrNoRefreshToken)
+}
+
+func TestDiscoverDefaultEndpoints(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(srv.Close)
+
+	// Servers without metadata are their own authorization server.
+	md, err := discover(t.Context(), http.DefaultClient, srv.URL+"/mcp#fragment", "")
+	require.NoError(t, err)
+	require.Equal(t, srv.URL+"/mcp", md.resource)
+	require.Equal(t, srv.URL+"/authorize", md.server.AuthorizationEndpoint)
+	require.Equal(t, srv.URL+"/token", md.server.TokenEndpoint)
+	require.Equal(t, srv.URL+"/register", md.server.RegistrationEndpoint)
+}
+
+func TestParseChallenge(t *testing.T) {
+	t.Parallel()
+	params := parseChallenge(`Bearer error="invalid_token", resource_metadata="https://example.com/.well-known/oauth-protected-resource", scope="files:read files:write"`)
+	require.Equal(t, "invalid_token", params["error"])
+	require.Equal(t, "https://example.com/.well-known/oauth-protected-resource", params["resource_metadata"])
+	require.Equal(t, "files:read files:write", params["scope"])
+}
+
+func TestCheckEndpoint(t *testing.T) {
+	t.Parallel()
+	require.NoError(t, checkEndpoint("https://auth.example.com/token"))
+	require.NoError(t, checkEndpoint("http://127.0.0.1:8080/token"))
+	require.NoError(t, checkEndpoint("http://localhost/token"))
+	require.Error(t, checkEndpoint("http://auth.example.com/token"))
+}
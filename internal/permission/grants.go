@@ -0,0 +1,137 @@
+package permission
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/google/uuid"
+)
+
+// Grant is a permission granted for the rest of a session. Grants are
+// persisted so they survive restarts, and are reloaded when the session is
+// reopened.
+type Grant struct {
+	ID        string    `json:"id"`
+	SessionID string    `json:"session_id"`
+	Project   string    `json:"project"`
+	ToolName  string    `json:"tool_name"`
+	Action    string    `json:"action"`
+	Path      string    `json:"path"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// matches returns whether the grant covers the given request, made in the
+// given project directory. Grants only apply to the project they were made
+// in, like the session they belong to.
+func (g Grant) matches(req PermissionRequest, project string) bool {
+	return g.SessionID == req.SessionID &&
+		g.ToolName == req.ToolName &&
+		g.Action == req.Action &&
+		g.Path == req.Path &&
+		(g.Project == "" || g.Project == project)
+}
+
+// sameKey returns whether both grants are for the same permission, which the
+// store keeps a single grant for.
+func (g Grant) sameKey(other Grant) bool {
+	return g.SessionID == other.SessionID &&
+		g.ToolName == other.ToolName &&
+		g.Action == other.Action &&
+		g.Path == other.Path
+}
+
+// GrantStore persists session permission grants.
+type GrantStore interface {
+	// Create stores a grant, returning the existing one if the session
+	// already has an identical grant.
+	Create(ctx context.Context, grant Grant) (Grant, error)
+	// Get returns a grant by ID.
+	Get(ctx context.Context, id string) (Grant, error)
+	// List returns all grants.
+	List(ctx context.Context) ([]Grant, error)
+	// ListBySession returns the grants of a session.
+	ListBySession(ctx context.Context, sessionID string) ([]Grant, error)
+	// ListByProject returns the grants made in a project directory.
+	ListByProject(ctx context.Context, project string) ([]Grant, error)
+	// Delete deletes a grant by ID.
+	Delete(ctx context.Context, id string) error
+}
+
+type grantStore struct {
+	q db.Querier
+}
+
+// NewGrantStore creates a new grant store backed by the database.
+func NewGrantStore(q db.Querier) GrantStore {
+	return &grantStore{q: q}
+}
+
+func (s *grantStore) Create(ctx context.Context, grant Grant) (Grant, error) {
+	if grant.ID == "" {
+		grant.ID = uuid.New().String()
+	}
+	dbGrant, err := s.q.CreatePermissionGrant(ctx, db.CreatePermissionGrantParams{
+		ID:        grant.ID,
+		SessionID: grant.SessionID,
+		Project:   grant.Project,
+		ToolName:  grant.ToolName,
+		Action:    grant.Action,
+		Path:      grant.Path,
+	})
+	if err != nil {
+		return Grant{}, fmt.Errorf("creating permission grant: %w", err)
+	}
+	return fromDBGrant(dbGrant), nil
+}
+
+func (s *grantStore) Get(ctx context.Context, id string) (Grant, error) {
+	dbGrant, err := s.q.GetPermissionGrant(ctx, id)
+	if err != nil {
+		return Grant{}, err
+	}
+	return fromDBGrant(dbGrant), nil
+}
+
+func (s *grantStore) List(ctx context.Context) ([]Grant, error) {
+	return fromDBGrants(s.q.ListPermissionGrants(ctx))
+}
+
+func (s *grantStore) ListBySession(ctx context.Context, sessionID string) ([]Grant, error) {
+	return fromDBGrants(s.q.ListSessionPermissionGrants(ctx, sessionID))
+}
+
+func (s *grantStore) ListByProject(ctx context.Context, project string) ([]Grant, error) {
+	return fromDBGrants(s.q.ListProjectPermissionGrants(ctx, project))
+}
+
+func (s *grantStore) Delete(ctx context.Context, id string) error {
+	if err := s.q.DeletePermissionGrant(ctx, id); err != nil {
+		return fmt.Errorf("deleting permission grant: %w", err)
+	}
+	return nil
+}
+
+func fromDBGrants(dbGrants []db.PermissionGrant, err error) ([]Grant, error) {
+	if err != nil {
+		return nil, fmt.Errorf("listing permission grants: %w", err)
+	}
+	grants := make([]Grant, len(dbGrants))
+	for i, g := range dbGrants {
+		grants[i] = fromDBGrant(g)
+	}
+	return grants, nil
+}
+
+func fromDBGrant(g db.PermissionGrant) Grant {
+	return Grant{
+		ID:        g.ID,
+		SessionID: g.SessionID,
+		Project:   g.Project,
+		ToolName:  g.ToolName,
+		Action:    g.Action,
+		Path:      g.Path,
+		CreatedAt: time.Unix(g.CreatedAt, 0),
+	}
+}
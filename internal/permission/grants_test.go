@@ -0,0 +1,123 @@
+package permission
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/stretchr/testify/require"
+)
+
+func setupGrantStore(t *testing.T, sessionIDs ...string) GrantStore {
+	t.Helper()
+
+	conn, err := db.Connect(t.Context(), t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	q := db.New(conn)
+	for _, id := range sessionIDs {
+		_, err := q.CreateSession(t.Context(), db.CreateSessionParams{ID: id, Title: id})
+		require.NoError(t, err)
+	}
+	return NewGrantStore(q)
+}
+
+func TestPermissionService_PersistentGrants(t *testing.T) {
+	store := setupGrantStore(t, "session-1", "session-2")
+	workingDir := t.TempDir()
+	req := CreatePermissionRequest{
+		SessionID: "session-1",
+		ToolName:  "bash",
+		Action:    "execute",
+		Path:      workingDir,
+	}
+
+	service := NewPermissionService(workingDir, false, nil, nil, store)
+	events := service.Subscribe(t.Context())
+
+	var granted bool
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		granted, _ = service.Request(t.Context(), req)
+	})
+	event := <-events
+	service.GrantPersistent(event.Payload)
+	wg.Wait()
+	require.True(t, granted)
+
+	stored, err := store.ListByProject(t.Context(), workingDir)
+	require.NoError(t, err)
+	require.Len(t, stored, 1)
+	require.Equal(t, "session-1", stored[0].SessionID)
+	require.Equal(t, "bash", stored[0].ToolName)
+
+	t.Run("reloaded after restart", func(t *testing.T) {
+		restarted := NewPermissionService(workingDir, false, nil, nil, store)
+		ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+		defer cancel()
+
+		granted, err := restarted.Request(ctx, req)
+		require.NoError(t, err)
+		require.True(t, granted)
+
+		grants, err := restarted.SessionGrants(t.Context(), "session-1")
+		require.NoError(t, err)
+		require.Len(t, grants, 1)
+		require.Equal(t, stored[0].ID, grants[0].ID)
+	})
+
+	t.Run("granted again", func(t *testing.T) {
+		restarted := NewPermissionService(workingDir, false, nil, nil, store)
+		for range 2 {
+			restarted.GrantPersistent(PermissionRequest{
+				SessionID: "session-1",
+				ToolName:  "bash",
+				Action:    "execute",
+				Path:      workingDir,
+			})
+		}
+
+		grants, err := restarted.SessionGrants(t.Context(), "session-1")
+		require.NoError(t, err)
+		require.Len(t, grants, 1)
+		require.Equal(t, stored[0].ID, grants[0].ID)
+	})
+
+	t.Run("not applied in other projects", func(t *testing.T) {
+		other := NewPermissionService(t.TempDir(), false, nil, nil, store)
+		events := other.Subscribe(t.Context())
+
+		var granted bool
+		var wg sync.WaitGroup
+		wg.Go(func() {
+			granted, _ = other.Request(t.Context(), req)
+		})
+		event := <-events
+		other.Deny(event.Payload)
+		wg.Wait()
+		require.False(t, granted)
+	})
+
+	t.Run("not shared with other sessions", func(t *testing.T) {
+		restarted := NewPermissionService(workingDir, false, nil, nil, store)
+		grants, err := restarted.SessionGrants(t.Context(), "session-2")
+		require.NoError(t, err)
+		require.Empty(t, grants)
+	})
+
+	t.Run("revoke", func(t *testing.T) {
+		restarted := NewPermissionService(workingDir, false, nil, nil, store)
+		require.NoError(t, restarted.RevokeGrant(t.Context(), stored[0].ID))
+
+		grants, err := restarted.SessionGrants(t.Context(), "session-1")
+		require.NoError(t, err)
+		require.Empty(t, grants)
+
+		grants, err = store.ListBySession(t.Context(), "session-1")
+		require.NoError(t, err)
+		require.Empty(t, grants)
+	})
+}
@@ -1,12 +1,15 @@
 package permission
 
 import (
+	"cmp"
 	"context"
 	"errors"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"slices"
 	"sync"
+	"time"
 
 	"github.com/charmbracelet/crush/internal/csync"
 	"github.com/charmbracelet/crush/internal/pubsub"
@@ -15,6 +18,15 @@ import (
 
 var ErrorPermissionDenied = errors.New("user denied permission")
 
+type workingDirKey struct{}
+
+// WithWorkingDir returns a context whose permission requests are made from
+// the given directory rather than the project's, like the git worktree of a
+// session. Rules with relative paths are resolved against it.
+func WithWorkingDir(ctx context.Context, workingDir string) context.Context {
+	return context.WithValue(ctx, workingDirKey{}, workingDir)
+}
+
 type CreatePermissionRequest struct {
 	SessionID   string `json:"session_id"`
 	ToolCallID  string `json:"tool_call_id"`
@@ -23,6 +35,9 @@ type CreatePermissionRequest struct {
 	Action      string `json:"action"`
 	Params      any    `json:"params"`
 	Path        string `json:"path"`
+	// OneTime requests can only be allowed once, not for the rest of the
+	// session. Requests made without a session always are.
+	OneTime bool `json:"one_time,omitempty"`
 }
 
 type PermissionNotification struct {
@@ -40,6 +55,10 @@ type PermissionRequest struct {
 	Action      string `json:"action"`
 	Params      any    `json:"params"`
 	Path        string `json:"path"`
+	// OneTime requests, like plan approvals and the ones that aren't made
+	// from a session, can only be allowed once, not for the rest of the
+	// session.
+	OneTime bool `json:"one_time,omitempty"`
 }
 
 type Service interface {
@@ -48,10 +67,13 @@ type Service interface {
 	Grant(permission PermissionRequest)
 	Deny(permission PermissionRequest)
 	Request(ctx context.Context, opts CreatePermissionRequest) (bool, error)
+	Approve(ctx context.Context, opts CreatePermissionRequest) (bool, error)
 	AutoApproveSession(sessionID string)
 	SetSkipRequests(skip bool)
 	SkipRequests() bool
 	SubscribeNotifications(ctx context.Context) <-chan pubsub.Event[PermissionNotification]
+	SessionGrants(ctx context.Context, sessionID string) ([]Grant, error)
+	RevokeGrant(ctx context.Context, id string) error
 }
 
 type permissionService struct {
@@ -59,13 +81,16 @@ type permissionService struct {
 
 	notificationBroker    *pubsub.Broker[PermissionNotification]
 	workingDir            string
-	sessionPermissions    []PermissionRequest
+	sessionPermissions    []Grant
 	sessionPermissionsMu  sync.RWMutex
+	loadedSessions        map[string]bool
+	grants                GrantStore
 	pendingRequests       *csync.Map[string, chan bool]
 	autoApproveSessions   map[string]bool
 	autoApproveSessionsMu sync.RWMutex
 	skip                  bool
 	allowedTools          []string
+	rules                 []Rule
 
 	// used to make sure we only process one request at a time
 	requestMu       sync.Mutex
@@ -74,6 +99,12 @@ type permissionService struct {
 }
 
 func (s *permissionService) GrantPersistent(permission PermissionRequest) {
+	// Grants need a session, requests made without one are only allowed
+	// once.
+	if permission.OneTime || permission.SessionID == "" {
+		s.Grant(permission)
+		return
+	}
 	s.notificationBroker.Publish(pubsub.CreatedEvent, PermissionNotification{
 		ToolCallID: permission.ToolCallID,
 		Granted:    true,
@@ -83,8 +114,29 @@ func (s *permissionService) GrantPersistent(permission PermissionRequest) {
 		respCh <- true
 	}
 
+	grant := Grant{
+		ID:        uuid.New().String(),
+		SessionID: permission.SessionID,
+		Project:   s.workingDir,
+		ToolName:  permission.ToolName,
+		Action:    permission.Action,
+		Path:      permission.Path,
+		CreatedAt: time.Now(),
+	}
+	if s.grants != nil {
+		stored, err := s.grants.Create(context.Background(), grant)
+		if err != nil {
+			slog.Error("Failed to persist permission grant", "error", err)
+		} else {
+			grant = stored
+		}
+	}
+
+	// The store returns the existing grant if there's one already, which
+	// replaces the one in memory.
 	s.sessionPermissionsMu.Lock()
-	s.sessionPermissions = append(s.sessionPermissions, permission)
+	s.sessionPermissions = slices.DeleteFunc(s.sessionPermissions, grant.sameKey)
+	s.sessionPermissions = append(s.sessionPermissions, grant)
 	s.sessionPermissionsMu.Unlock()
 
 	s.activeRequestMu.Lock()
@@ -130,13 +182,27 @@ func (s *permissionService) Deny(permission PermissionRequest) {
 }
 
 func (s *permissionService) Request(ctx context.Context, opts CreatePermissionRequest) (bool, error) {
-	if s.skip {
+	workingDir, _ := ctx.Value(workingDirKey{}).(string)
+	workingDir = cmp.Or(workingDir, s.workingDir)
+	decision, rule := evaluateRules(s.rules, opts, workingDir)
+	switch {
+	case decision == DecisionDeny:
+		// Deny rules apply even when skipping requests.
+		s.notificationBroker.Publish(pubsub.CreatedEvent, PermissionNotification{
+			ToolCallID: opts.ToolCallID,
+			Denied:     true,
+		})
+		return false, &RuleDeniedError{Rule: rule}
+	case decision == DecisionAsk:
+		// Ask rules always prompt, even when skipping requests.
+	case s.skip, decision == DecisionAllow:
 		return true, nil
 	}
+	ask := decision == DecisionAsk
 
 	// Check if the tool/action combination is in the allowlist
 	commandKey := opts.ToolName + ":" + opts.Action
-	if slices.Contains(s.allowedTools, commandKey) || slices.Contains(s.allowedTools, opts.ToolName) {
+	if !ask && (slices.Contains(s.allowedTools, commandKey) || slices.Contains(s.allowedTools, opts.ToolName)) {
 		return true, nil
 	}
 
@@ -151,7 +217,7 @@ func (s *permissionService) Request(ctx context.Context, opts CreatePermissionRe
 	autoApprove := s.autoApproveSessions[opts.SessionID]
 	s.autoApproveSessionsMu.RUnlock()
 
-	if autoApprove {
+	if autoApprove && !ask {
 		s.notificationBroker.Publish(pubsub.CreatedEvent, PermissionNotification{
 			ToolCallID: opts.ToolCallID,
 			Granted:    true,
@@ -159,6 +225,44 @@ func (s *permissionService) Request(ctx context.Context, opts CreatePermissionRe
 		return true, nil
 	}
 
+	permission := newPermissionRequest(opts, workingDir)
+	s.loadSessionGrants(ctx, permission.SessionID)
+	s.sessionPermissionsMu.RLock()
+	for _, g := range s.sessionPermissions {
+		if !ask && g.matches(permission, s.workingDir) {
+			s.sessionPermissionsMu.RUnlock()
+			s.notificationBroker.Publish(pubsub.CreatedEvent, PermissionNotification{
+				ToolCallID: opts.ToolCallID,
+				Granted:    true,
+			})
+			return true, nil
+		}
+	}
+	s.sessionPermissionsMu.RUnlock()
+
+	return s.publishRequest(ctx, permission)
+}
+
+// Approve asks the user to approve a request, like a plan. Unlike Request,
+// it always prompts: rules, the allowlist, auto-approved sessions, skip mode
+// and the permissions allowed for the session don't apply, and the request
+// can only be allowed once.
+func (s *permissionService) Approve(ctx context.Context, opts CreatePermissionRequest) (bool, error) {
+	workingDir, _ := ctx.Value(workingDirKey{}).(string)
+	workingDir = cmp.Or(workingDir, s.workingDir)
+
+	s.notificationBroker.Publish(pubsub.CreatedEvent, PermissionNotification{
+		ToolCallID: opts.ToolCallID,
+	})
+	s.requestMu.Lock()
+	defer s.requestMu.Unlock()
+
+	permission := newPermissionRequest(opts, workingDir)
+	permission.OneTime = true
+	return s.publishRequest(ctx, permission)
+}
+
+func newPermissionRequest(opts CreatePermissionRequest, workingDir string) PermissionRequest {
 	fileInfo, err := os.Stat(opts.Path)
 	dir := opts.Path
 	if err == nil {
@@ -170,9 +274,9 @@ func (s *permissionService) Request(ctx context.Context, opts CreatePermissionRe
 	}
 
 	if dir == "." {
-		dir = s.workingDir
+		dir = workingDir
 	}
-	permission := PermissionRequest{
+	return PermissionRequest{
 		ID:          uuid.New().String(),
 		Path:        dir,
 		SessionID:   opts.SessionID,
@@ -181,21 +285,12 @@ func (s *permissionService) Request(ctx context.Context, opts CreatePermissionRe
 		Description: opts.Description,
 		Action:      opts.Action,
 		Params:      opts.Params,
+		OneTime:     opts.OneTime || opts.SessionID == "",
 	}
+}
 
-	s.sessionPermissionsMu.RLock()
-	for _, p := range s.sessionPermissions {
-		if p.ToolName == permission.ToolName && p.Action == permission.Action && p.SessionID == permission.SessionID && p.Path == permission.Path {
-			s.sessionPermissionsMu.RUnlock()
-			s.notificationBroker.Publish(pubsub.CreatedEvent, PermissionNotification{
-				ToolCallID: opts.ToolCallID,
-				Granted:    true,
-			})
-			return true, nil
-		}
-	}
-	s.sessionPermissionsMu.RUnlock()
-
+// publishRequest publishes a permission request and waits for its answer.
+func (s *permissionService) publishRequest(ctx context.Context, permission PermissionRequest) (bool, error) {
 	s.activeRequestMu.Lock()
 	s.activeRequest = &permission
 	s.activeRequestMu.Unlock()
@@ -215,6 +310,58 @@ func (s *permissionService) Request(ctx context.Context, opts CreatePermissionRe
 	}
 }
 
+// loadSessionGrants loads the persisted grants of a session the first time
+// the session is seen, so grants survive restarts.
+func (s *permissionService) loadSessionGrants(ctx context.Context, sessionID string) {
+	if s.grants == nil || sessionID == "" {
+		return
+	}
+	s.sessionPermissionsMu.Lock()
+	defer s.sessionPermissionsMu.Unlock()
+	if s.loadedSessions[sessionID] {
+		return
+	}
+	grants, err := s.grants.ListBySession(ctx, sessionID)
+	if err != nil {
+		slog.Error("Failed to load permission grants", "session_id", sessionID, "error", err)
+		return
+	}
+	s.loadedSessions[sessionID] = true
+	for _, grant := range grants {
+		if !slices.ContainsFunc(s.sessionPermissions, func(g Grant) bool { return g.ID == grant.ID }) {
+			s.sessionPermissions = append(s.sessionPermissions, grant)
+		}
+	}
+}
+
+// SessionGrants returns the permissions granted for the rest of a session.
+func (s *permissionService) SessionGrants(ctx context.Context, sessionID string) ([]Grant, error) {
+	s.loadSessionGrants(ctx, sessionID)
+	s.sessionPermissionsMu.RLock()
+	defer s.sessionPermissionsMu.RUnlock()
+	var grants []Grant
+	for _, g := range s.sessionPermissions {
+		if g.SessionID == sessionID {
+			grants = append(grants, g)
+		}
+	}
+	return grants, nil
+}
+
+// RevokeGrant revokes a session grant, so the permission is asked for again.
+func (s *permissionService) RevokeGrant(ctx context.Context, id string) error {
+	s.sessionPermissionsMu.Lock()
+	s.sessionPermissions = slices.DeleteFunc(s.sessionPermissions, func(g Grant) bool {
+		return g.ID == id
+	})
+	s.sessionPermissionsMu.Unlock()
+
+	if s.grants == nil {
+		return nil
+	}
+	return s.grants.Delete(ctx, id)
+}
+
 func (s *permissionService) AutoApproveSession(sessionID string) {
 	s.autoApproveSessionsMu.Lock()
 	s.autoApproveSessions[sessionID] = true
@@ -233,15 +380,20 @@ func (s *permissionService) SkipRequests() bool {
 	return s.skip
 }
 
-func NewPermissionService(workingDir string, skip bool, allowedTools []string) Service {
+// NewPermissionService creates a new permission service. Session grants are
+// persisted in grants, which may be nil to keep them in memory only.
+func NewPermissionService(workingDir string, skip bool, allowedTools []string, rules []Rule, grants GrantStore) Service {
 	return &permissionService{
 		Broker:              pubsub.NewBroker[PermissionRequest](),
 		notificationBroker:  pubsub.NewBroker[PermissionNotification](),
 		workingDir:          workingDir,
-		sessionPermissions:  make([]PermissionRequest, 0),
+		sessionPermissions:  make([]Grant, 0),
+		loadedSessions:      make(map[string]bool),
+		grants:              grants,
 		autoApproveSessions: make(map[string]bool),
 		skip:                skip,
 		allowedTools:        allowedTools,
+		rules:               rules,
 		pendingRequests:     csync.NewMap[string, chan bool](),
 	}
 }
@@ -0,0 +1,270 @@
+package permission
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/charmbracelet/crush/internal/home"
+	"github.com/charmbracelet/crush/internal/shell"
+)
+
+// Decision is what a [Rule] does with the requests it matches.
+type Decision string
+
+const (
+	// DecisionAllow grants the request without prompting.
+	DecisionAllow Decision = "allow"
+	// DecisionDeny denies the request without prompting.
+	DecisionDeny Decision = "deny"
+	// DecisionAsk always prompts, even if the tool is otherwise allowed.
+	DecisionAsk Decision = "ask"
+)
+
+// Rule is a permission rule of the form "tool", "tool:action",
+// "tool(pattern)" or "tool:action(pattern)". The tool can be "*" to match
+// every tool.
+//
+// The pattern is matched against the command of bash requests, the URL of
+// fetch and download requests, and the file path of everything else. Commands
+// and URLs are matched with "*" wildcards, while paths are matched as globs
+// relative to the working directory, supporting "**". Patterns without a
+// slash also match the base name of the path. Commands are split into the
+// simple commands they run, which are matched one by one. Rules for the edit
+// and write tools also apply to the tools changing files through LSP servers.
+type Rule struct {
+	Decision Decision
+	Tool     string
+	Action   string
+	Pattern  string
+
+	raw string
+}
+
+// ParseRule parses a permission rule.
+func ParseRule(decision Decision, rule string) (Rule, error) {
+	switch decision {
+	case DecisionAllow, DecisionDeny, DecisionAsk:
+	default:
+		return Rule{}, fmt.Errorf("invalid decision %q for rule %q: must be one of allow, deny, ask", decision, rule)
+	}
+
+	r := Rule{Decision: decision, raw: rule}
+	spec := strings.TrimSpace(rule)
+	if i := strings.Index(spec, "("); i != -1 {
+		if !strings.HasSuffix(spec, ")") {
+			return Rule{}, fmt.Errorf("invalid rule %q: missing closing parenthesis", rule)
+		}
+		r.Pattern = spec[i+1 : len(spec)-1]
+		spec = spec[:i]
+	}
+	r.Tool, r.Action, _ = strings.Cut(spec, ":")
+	if r.Tool == "" {
+		return Rule{}, fmt.Errorf("invalid rule %q: missing tool name", rule)
+	}
+	if strings.HasPrefix(r.Pattern, "~") {
+		r.Pattern = home.Long(r.Pattern)
+	}
+	return r, nil
+}
+
+// String returns the rule as it was written.
+func (r Rule) String() string {
+	return r.raw
+}
+
+// Matches returns whether the rule applies to the given request. Rules apply
+// to bash requests if they match any of the commands the command line runs,
+// with or without their variable assignments and redirections.
+func (r Rule) Matches(req CreatePermissionRequest, workingDir string) bool {
+	if !r.matchesTool(req) {
+		return false
+	}
+	if r.Pattern == "" {
+		return true
+	}
+
+	params := requestParams(req)
+	if command, ok := params["command"].(string); ok {
+		return slices.ContainsFunc(splitCommand(command), func(command shell.Command) bool {
+			return r.matchCommand(req, command)
+		})
+	}
+	if url, ok := params["url"].(string); ok && matchWildcard(r.Pattern, url) {
+		return true
+	}
+	for _, key := range []string{"file_path", "path"} {
+		if path, ok := params[key].(string); ok && path != "" {
+			return r.matchPath(path, workingDir)
+		}
+	}
+	return req.Path != "" && r.matchPath(req.Path, workingDir)
+}
+
+// lspEditTools change files through the edits of LSP servers. They change
+// files like the edit and write tools do, so the rules of these apply to
+// them too.
+var lspEditTools = []string{"lsp_rename", "lsp_code_action"}
+
+func (r Rule) matchesTool(req CreatePermissionRequest) bool {
+	switch {
+	case r.Tool == "*", r.Tool == req.ToolName:
+	case (r.Tool == "edit" || r.Tool == "write") && slices.Contains(lspEditTools, req.ToolName):
+	default:
+		return false
+	}
+	return r.Action == "" || r.Action == req.Action
+}
+
+func (r Rule) matchCommand(req CreatePermissionRequest, command shell.Command) bool {
+	if !r.matchesTool(req) {
+		return false
+	}
+	return r.Pattern == "" || matchWildcard(r.Pattern, command.Args) || matchWildcard(r.Pattern, command.Text)
+}
+
+func (r Rule) matchPath(path, workingDir string) bool {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(workingDir, path)
+	}
+	path = filepath.Clean(path)
+
+	candidates := []string{filepath.ToSlash(path)}
+	if rel, err := filepath.Rel(workingDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		candidates = append(candidates, filepath.ToSlash(rel))
+	}
+	if !strings.Contains(r.Pattern, "/") {
+		candidates = append(candidates, filepath.Base(path))
+	}
+
+	for _, candidate := range candidates {
+		if ok, _ := doublestar.Match(r.Pattern, candidate); ok {
+			return true
+		}
+		// A pattern matching a directory also matches everything in it.
+		if ok, _ := doublestar.Match(strings.TrimSuffix(r.Pattern, "/")+"/**", candidate); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// requestParams returns the params of a request as a generic map, so rules
+// can look into the params of any tool.
+func requestParams(req CreatePermissionRequest) map[string]any {
+	if req.Params == nil {
+		return nil
+	}
+	data, err := json.Marshal(req.Params)
+	if err != nil {
+		return nil
+	}
+	var params map[string]any
+	if err := json.Unmarshal(data, &params); err != nil {
+		return nil
+	}
+	return params
+}
+
+// matchWildcard matches a string against a pattern where "*" matches any
+// sequence of characters.
+func matchWildcard(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	re, err := regexp.Compile("^" + strings.Join(parts, ".*") + "$")
+	if err != nil {
+		return false
+	}
+	return re.MatchString(strings.TrimSpace(s))
+}
+
+// splitCommand returns the simple commands a command line runs. Command lines
+// that can't be parsed won't run either, so they're matched as a whole.
+func splitCommand(command string) []shell.Command {
+	commands, err := shell.SplitCommands(command)
+	if err != nil || len(commands) == 0 {
+		return []shell.Command{{Args: command, Text: command}}
+	}
+	return commands
+}
+
+// evaluateRules returns the decision of the last rule matching the request,
+// so later rules, like the ones of the project config, override earlier ones.
+// It returns an empty decision if no rule matches.
+//
+// The commands of bash requests are decided one by one: the request is denied
+// if any of them is, prompted for if any of them is, and only allowed if all
+// of them are. Allow rules with a pattern don't allow commands assigning
+// variables or writing files with redirections, as "allow bash(echo *)" isn't
+// meant to allow "echo x > ~/.bashrc".
+func evaluateRules(rules []Rule, req CreatePermissionRequest, workingDir string) (Decision, Rule) {
+	command, ok := requestParams(req)["command"].(string)
+	if !ok {
+		return lastMatch(rules, func(r Rule) bool {
+			return r.Matches(req, workingDir)
+		})
+	}
+
+	var (
+		ask, allow Rule
+		asked      bool
+		allowed    = true
+	)
+	for _, command := range splitCommand(command) {
+		decision, rule := lastMatch(rules, func(r Rule) bool {
+			return r.matchCommand(req, command)
+		})
+		switch decision {
+		case DecisionDeny:
+			return decision, rule
+		case DecisionAsk:
+			if !asked {
+				ask, asked = rule, true
+			}
+		case DecisionAllow:
+			if rule.Pattern != "" && (command.Assigns || command.WritesFiles) {
+				allowed = false
+				break
+			}
+			allow = rule
+		default:
+			allowed = false
+		}
+	}
+	switch {
+	case asked:
+		return DecisionAsk, ask
+	case allowed:
+		return DecisionAllow, allow
+	}
+	return "", Rule{}
+}
+
+func lastMatch(rules []Rule, match func(Rule) bool) (Decision, Rule) {
+	for i := len(rules) - 1; i >= 0; i-- {
+		if match(rules[i]) {
+			return rules[i].Decision, rules[i]
+		}
+	}
+	return "", Rule{}
+}
+
+// RuleDeniedError is returned when a permission rule denies a request.
+type RuleDeniedError struct {
+	Rule Rule
+}
+
+func (e *RuleDeniedError) Error() string {
+	return fmt.Sprintf("permission denied by rule %q", e.Rule.String())
+}
+
+// Is makes rule denials match [ErrorPermissionDenied].
+func (e *RuleDeniedError) Is(target error) bool {
+	return target == ErrorPermissionDenied
+}
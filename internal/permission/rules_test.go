@@ -0,0 +1,290 @@
+package permission
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testParams struct {
+	Command  string `json:"command,omitempty"`
+	URL      string `json:"url,omitempty"`
+	FilePath string `json:"file_path,omitempty"`
+}
+
+func mustParseRule(t *testing.T, decision Decision, rule string) Rule {
+	t.Helper()
+	r, err := ParseRule(decision, rule)
+	require.NoError(t, err)
+	return r
+}
+
+func TestParseRule(t *testing.T) {
+	r := mustParseRule(t, DecisionAllow, "bash:execute(go test *)")
+	require.Equal(t, "bash", r.Tool)
+	require.Equal(t, "execute", r.Action)
+	require.Equal(t, "go test *", r.Pattern)
+	require.Equal(t, "bash:execute(go test *)", r.String())
+
+	r = mustParseRule(t, DecisionDeny, "view")
+	require.Equal(t, "view", r.Tool)
+	require.Empty(t, r.Action)
+	require.Empty(t, r.Pattern)
+
+	for _, tt := range []struct {
+		decision Decision
+		rule     string
+	}{
+		{DecisionAllow, "bash(go test *"},
+		{DecisionAllow, "(foo)"},
+		{"maybe", "bash"},
+	} {
+		_, err := ParseRule(tt.decision, tt.rule)
+		require.Error(t, err, tt.rule)
+	}
+}
+
+func TestRuleMatches(t *testing.T) {
+	const workingDir = "/project"
+
+	tests := []struct {
+		rule     string
+		req      CreatePermissionRequest
+		expected bool
+	}{
+		{
+			rule:     "bash(go test *)",
+			req:      CreatePermissionRequest{ToolName: "bash", Action: "execute", Params: testParams{Command: "go test ./..."}},
+			expected: true,
+		},
+		{
+			rule:     "bash(go test *)",
+			req:      CreatePermissionRequest{ToolName: "bash", Action: "execute", Params: testParams{Command: "go build ./..."}},
+			expected: false,
+		},
+		{
+			rule:     "bash(rm -rf *)",
+			req:      CreatePermissionRequest{ToolName: "bash", Action: "execute", Params: testParams{Command: "rm -rf /"}},
+			expected: true,
+		},
+		{
+			rule:     "bash",
+			req:      CreatePermissionRequest{ToolName: "bash", Action: "execute", Params: testParams{Command: "ls"}},
+			expected: true,
+		},
+		{
+			rule:     "bash:read",
+			req:      CreatePermissionRequest{ToolName: "bash", Action: "execute", Params: testParams{Command: "ls"}},
+			expected: false,
+		},
+		{
+			rule:     "edit(internal/**)",
+			req:      CreatePermissionRequest{ToolName: "edit", Action: "write", Params: testParams{FilePath: "/project/internal/app/app.go"}},
+			expected: true,
+		},
+		{
+			rule:     "edit(internal/**)",
+			req:      CreatePermissionRequest{ToolName: "edit", Action: "write", Params: testParams{FilePath: "/project/cmd/main.go"}},
+			expected: false,
+		},
+		{
+			rule:     "edit(internal)",
+			req:      CreatePermissionRequest{ToolName: "edit", Action: "write", Params: testParams{FilePath: "internal/app/app.go"}},
+			expected: true,
+		},
+		{
+			rule:     "write(.env*)",
+			req:      CreatePermissionRequest{ToolName: "write", Action: "write", Params: testParams{FilePath: "/project/config/.env.local"}},
+			expected: true,
+		},
+		{
+			rule:     "write(/etc/**)",
+			req:      CreatePermissionRequest{ToolName: "write", Action: "write", Params: testParams{FilePath: "/etc/hosts"}},
+			expected: true,
+		},
+		{
+			rule:     "write(.env*)",
+			req:      CreatePermissionRequest{ToolName: "lsp_rename", Action: "write", Params: testParams{FilePath: "/project/.env"}},
+			expected: true,
+		},
+		{
+			rule:     "edit(internal/**)",
+			req:      CreatePermissionRequest{ToolName: "lsp_code_action", Action: "write", Params: testParams{FilePath: "/project/internal/app/app.go"}},
+			expected: true,
+		},
+		{
+			rule:     "view(internal/**)",
+			req:      CreatePermissionRequest{ToolName: "lsp_rename", Action: "write", Params: testParams{FilePath: "/project/internal/app/app.go"}},
+			expected: false,
+		},
+		{
+			rule:     "*(secrets/**)",
+			req:      CreatePermissionRequest{ToolName: "view", Action: "read", Path: "/project/secrets/key.pem"},
+			expected: true,
+		},
+		{
+			rule:     "fetch(https://example.com/*)",
+			req:      CreatePermissionRequest{ToolName: "fetch", Action: "fetch", Params: testParams{URL: "https://example.com/docs"}},
+			expected: true,
+		},
+		{
+			rule:     "download(downloads/**)",
+			req:      CreatePermissionRequest{ToolName: "download", Action: "download", Params: testParams{URL: "https://example.com/a.zip", FilePath: "/project/downloads/a.zip"}},
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			r := mustParseRule(t, DecisionAllow, tt.rule)
+			require.Equal(t, tt.expected, r.Matches(tt.req, workingDir))
+		})
+	}
+}
+
+func TestEvaluateRulesCommands(t *testing.T) {
+	rules := []Rule{
+		mustParseRule(t, DecisionAllow, "bash(go test *)"),
+		mustParseRule(t, DecisionAllow, "bash(cd *)"),
+		mustParseRule(t, DecisionAllow, "bash(echo *)"),
+		mustParseRule(t, DecisionAsk, "bash(git push*)"),
+		mustParseRule(t, DecisionDeny, "bash(rm -rf *)"),
+	}
+
+	tests := []struct {
+		command  string
+		expected Decision
+	}{
+		{"go test ./...", DecisionAllow},
+		{" go test ./...", DecisionAllow},
+		{"cd internal && go test ./...", DecisionAllow},
+		{"go test ./... && make build", ""},
+		{"go test ./... && rm -rf ~", DecisionDeny},
+		{"cd x && rm -rf y", DecisionDeny},
+		{" rm -rf y", DecisionDeny},
+		{"cd x; rm -rf y", DecisionDeny},
+		{"echo hi | rm -rf y", DecisionDeny},
+		{"go test ./... | tee out.txt", ""},
+		{"(cd x && rm -rf y)", DecisionDeny},
+		{"(cd x && go test ./...)", DecisionAllow},
+		{"echo $(rm -rf y)", DecisionDeny},
+		{"echo $(make build)", ""},
+		{"go test ./... && git push", DecisionAsk},
+		{"git push && rm -rf y", DecisionDeny},
+		{"echo x > ~/.bashrc", ""},
+		{"echo x 2>&1 >/dev/null", DecisionAllow},
+		{"GOFLAGS=-toolexec=./x go test ./...", ""},
+		{"FOO=1 rm -rf y", DecisionDeny},
+		{"rm -rf y > /dev/null", DecisionDeny},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			req := CreatePermissionRequest{ToolName: "bash", Action: "execute", Params: testParams{Command: tt.command}}
+			decision, _ := evaluateRules(rules, req, "/project")
+			require.Equal(t, tt.expected, decision)
+		})
+	}
+}
+
+func TestPermissionService_Rules(t *testing.T) {
+	rules := []Rule{
+		mustParseRule(t, DecisionAsk, "bash"),
+		mustParseRule(t, DecisionAllow, "bash(go test *)"),
+		mustParseRule(t, DecisionDeny, "bash(rm -rf *)"),
+	}
+	bashRequest := func(command string) CreatePermissionRequest {
+		return CreatePermissionRequest{
+			SessionID: "session",
+			ToolName:  "bash",
+			Action:    "execute",
+			Path:      "/tmp",
+			Params:    testParams{Command: command},
+		}
+	}
+
+	t.Run("allow", func(t *testing.T) {
+		service := NewPermissionService("/tmp", false, nil, rules, nil)
+		granted, err := service.Request(t.Context(), bashRequest("go test ./..."))
+		require.NoError(t, err)
+		require.True(t, granted)
+	})
+
+	t.Run("deny applies in skip mode", func(t *testing.T) {
+		service := NewPermissionService("/tmp", true, nil, rules, nil)
+		granted, err := service.Request(t.Context(), bashRequest("rm -rf /"))
+		require.False(t, granted)
+		require.ErrorIs(t, err, ErrorPermissionDenied)
+
+		var ruleErr *RuleDeniedError
+		require.ErrorAs(t, err, &ruleErr)
+		require.Equal(t, "bash(rm -rf *)", ruleErr.Rule.String())
+	})
+
+	t.Run("ask prompts in skip mode and auto-approved sessions", func(t *testing.T) {
+		rules := []Rule{mustParseRule(t, DecisionAsk, "bash(git push*)")}
+		for name, service := range map[string]Service{
+			"skip":         NewPermissionService("/tmp", true, nil, rules, nil),
+			"auto-approve": NewPermissionService("/tmp", false, nil, rules, nil),
+		} {
+			t.Run(name, func(t *testing.T) {
+				service.AutoApproveSession("session")
+				requests := service.Subscribe(t.Context())
+				result := make(chan bool, 1)
+				go func() {
+					granted, _ := service.Request(t.Context(), bashRequest("git push"))
+					result <- granted
+				}()
+
+				event := <-requests
+				require.Equal(t, "bash", event.Payload.ToolName)
+				service.Deny(event.Payload)
+				require.False(t, <-result)
+
+				granted, err := service.Request(t.Context(), bashRequest("go test ./..."))
+				require.NoError(t, err)
+				require.True(t, granted)
+			})
+		}
+	})
+
+	t.Run("relative to the working dir of the request", func(t *testing.T) {
+		rules := []Rule{mustParseRule(t, DecisionDeny, "edit(secrets/**)")}
+		service := NewPermissionService("/project", true, nil, rules, nil)
+		ctx := WithWorkingDir(t.Context(), "/worktree")
+		granted, err := service.Request(ctx, CreatePermissionRequest{
+			SessionID: "session",
+			ToolName:  "edit",
+			Action:    "write",
+			Params:    testParams{FilePath: "secrets/key.pem"},
+		})
+		require.False(t, granted)
+		require.ErrorIs(t, err, ErrorPermissionDenied)
+
+		granted, err = service.Request(ctx, CreatePermissionRequest{
+			SessionID: "session",
+			ToolName:  "edit",
+			Action:    "write",
+			Params:    testParams{FilePath: "/worktree/secrets/key.pem"},
+		})
+		require.False(t, granted)
+		require.ErrorIs(t, err, ErrorPermissionDenied)
+	})
+
+	t.Run("ask overrides allowed tools", func(t *testing.T) {
+		service := NewPermissionService("/tmp", false, []string{"bash"}, rules, nil)
+		events := service.Subscribe(t.Context())
+
+		var granted bool
+		var wg sync.WaitGroup
+		wg.Go(func() {
+			granted, _ = service.Request(t.Context(), bashRequest("make build"))
+		})
+
+		event := <-events
+		require.Equal(t, "bash", event.Payload.ToolName)
+		service.Grant(event.Payload)
+		wg.Wait()
+		require.True(t, granted)
+	})
+}
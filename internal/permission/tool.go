@@ -0,0 +1,30 @@
+package permission
+
+import (
+	"context"
+	"errors"
+
+	"charm.land/fantasy"
+)
+
+// ruleCheckedTool reports tool calls denied by a permission rule to the
+// model, instead of ending the turn like a denial by the user does.
+type ruleCheckedTool struct {
+	fantasy.AgentTool
+}
+
+// WrapTool returns a tool that turns [RuleDeniedError]s into error results,
+// so the model can try something else.
+func WrapTool(tool fantasy.AgentTool) fantasy.AgentTool {
+	return &ruleCheckedTool{AgentTool: tool}
+}
+
+// Run implements [fantasy.AgentTool].
+func (t *ruleCheckedTool) Run(ctx context.Context, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+	response, err := t.AgentTool.Run(ctx, call)
+	var ruleErr *RuleDeniedError
+	if errors.As(err, &ruleErr) {
+		return fantasy.NewTextErrorResponse("Tool call blocked: " + ruleErr.Error()), nil
+	}
+	return response, err
+}
@@ -0,0 +1,192 @@
+// Package rewind brings a session back to the state it had right before one
+// of its user messages was sent, both in the conversation and on disk.
+package rewind
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+)
+
+var (
+	ErrMessageNotFound  = errors.New("message not found in session")
+	ErrAmbiguousMessage = errors.New("message id is ambiguous")
+	ErrNotUserMessage   = errors.New("only user messages can be rewound to")
+)
+
+// Result describes what a rewind changed.
+type Result struct {
+	// Message is the user message the session was rewound to. It's deleted
+	// along with everything after it, so callers can offer to resend it.
+	Message         message.Message `json:"message"`
+	DeletedMessages int             `json:"deleted_messages"`
+	RestoredFiles   []string        `json:"restored_files,omitempty"`
+	RemovedFiles    []string        `json:"removed_files,omitempty"`
+}
+
+// Service rewinds sessions.
+type Service struct {
+	sessions session.Service
+	messages message.Service
+	history  history.Service
+}
+
+// NewService creates a new rewind service.
+func NewService(sessions session.Service, messages message.Service, history history.Service) *Service {
+	return &Service{
+		sessions: sessions,
+		messages: messages,
+		history:  history,
+	}
+}
+
+// ResolveMessage finds a message of the session by its ID or a unique prefix
+// of it.
+func (s *Service) ResolveMessage(ctx context.Context, sessionID, id string) (message.Message, error) {
+	msgs, err := s.messages.List(ctx, sessionID)
+	if err != nil {
+		return message.Message{}, fmt.Errorf("failed to list messages: %w", err)
+	}
+	var matches []message.Message
+	for _, msg := range msgs {
+		if msg.ID == id {
+			return msg, nil
+		}
+		if strings.HasPrefix(msg.ID, id) {
+			matches = append(matches, msg)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return message.Message{}, fmt.Errorf("%w: %s", ErrMessageNotFound, id)
+	case 1:
+		return matches[0], nil
+	default:
+		return message.Message{}, fmt.Errorf("%w: %s matches %d messages", ErrAmbiguousMessage, id, len(matches))
+	}
+}
+
+// Rewind deletes the given user message and every message after it, and
+// restores the files the agent changed since then to the content they had
+// when the message was sent. Files the agent created since then are removed.
+func (s *Service) Rewind(ctx context.Context, sessionID, messageID string) (Result, error) {
+	msgs, err := s.messages.List(ctx, sessionID)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to list messages: %w", err)
+	}
+	idx := slices.IndexFunc(msgs, func(msg message.Message) bool {
+		return msg.ID == messageID
+	})
+	if idx == -1 {
+		return Result{}, fmt.Errorf("%w: %s", ErrMessageNotFound, messageID)
+	}
+	target := msgs[idx]
+	if target.Role != message.User {
+		return Result{}, ErrNotUserMessage
+	}
+
+	result := Result{Message: target}
+	later := msgs[idx:]
+	if err := s.restoreFiles(ctx, sessionID, later, &result); err != nil {
+		return result, err
+	}
+
+	// Delete from the newest message backwards so the conversation is always
+	// consistent if something fails midway.
+	for i := len(later) - 1; i >= 0; i-- {
+		if err := s.messages.Delete(ctx, later[i].ID); err != nil {
+			return result, fmt.Errorf("failed to delete message: %w", err)
+		}
+		result.DeletedMessages++
+	}
+
+	sess, err := s.sessions.Get(ctx, sessionID)
+	if err != nil {
+		return result, fmt.Errorf("failed to get session: %w", err)
+	}
+	if sess.SummaryMessageID != "" && slices.ContainsFunc(later, func(msg message.Message) bool {
+		return msg.ID == sess.SummaryMessageID
+	}) {
+		sess.SummaryMessageID = ""
+		if _, err := s.sessions.Save(ctx, sess); err != nil {
+			return result, fmt.Errorf("failed to save session: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// restoreFiles restores every file of the session to the latest version
+// recorded before the given messages, and deletes the newer versions from the
+// history.
+func (s *Service) restoreFiles(ctx context.Context, sessionID string, later []message.Message, result *Result) error {
+	files, err := s.history.ListBySession(ctx, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to list file history: %w", err)
+	}
+
+	// Versions are ordered by version, so the first one of each path is the
+	// content the file had before the agent touched it.
+	byPath := map[string][]history.File{}
+	var paths []string
+	for _, file := range files {
+		if _, ok := byPath[file.Path]; !ok {
+			paths = append(paths, file.Path)
+		}
+		byPath[file.Path] = append(byPath[file.Path], file)
+	}
+
+	for _, path := range paths {
+		versions := byPath[path]
+		keep := slices.IndexFunc(versions, func(file history.File) bool {
+			return file.RecordedDuring(later)
+		})
+		if keep == -1 {
+			// Untouched since the message was sent.
+			continue
+		}
+
+		switch {
+		case keep > 0:
+			if err := writeFile(path, versions[keep-1].Content); err != nil {
+				return err
+			}
+			result.RestoredFiles = append(result.RestoredFiles, path)
+		case versions[0].IsNew:
+			// The file didn't exist before the agent created it.
+			if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("failed to remove %s: %w", path, err)
+			}
+			result.RemovedFiles = append(result.RemovedFiles, path)
+		default:
+			if err := writeFile(path, versions[0].Content); err != nil {
+				return err
+			}
+			result.RestoredFiles = append(result.RestoredFiles, path)
+		}
+
+		for _, file := range versions[keep:] {
+			if err := s.history.Delete(ctx, file.ID); err != nil {
+				return fmt.Errorf("failed to delete file history: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+func writeFile(path, content string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create parent directories for %s: %w", path, err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package rewind
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/stretchr/testify/require"
+)
+
+type testEnv struct {
+	conn     *sql.DB
+	sessions session.Service
+	messages message.Service
+	history  history.Service
+	svc      *Service
+}
+
+func setupTest(t *testing.T) *testEnv {
+	t.Helper()
+
+	conn, err := db.Connect(t.Context(), t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	q := db.New(conn)
+	env := &testEnv{
+		conn:     conn,
+		sessions: session.NewService(q, conn),
+		messages: message.NewService(q),
+		history:  history.NewService(q, conn),
+	}
+	env.svc = NewService(env.sessions, env.messages, env.history)
+	return env
+}
+
+func (e *testEnv) createMessage(t *testing.T, sessionID string, role message.MessageRole, text string, at int64) message.Message {
+	t.Helper()
+	msg, err := e.messages.Create(t.Context(), sessionID, message.CreateMessageParams{
+		Role:  role,
+		Parts: []message.ContentPart{message.TextContent{Text: text}},
+	})
+	require.NoError(t, err)
+	_, err = e.conn.ExecContext(t.Context(), "UPDATE messages SET created_at = ? WHERE id = ?", at, msg.ID)
+	require.NoError(t, err)
+	msg.CreatedAt = at
+	return msg
+}
+
+func (e *testEnv) createVersion(t *testing.T, sessionID, messageID, path, content string, at int64) {
+	t.Helper()
+	file, err := e.history.CreateVersion(history.WithMessageID(t.Context(), messageID), sessionID, path, content)
+	require.NoError(t, err)
+	e.setCreatedAt(t, file.ID, at)
+}
+
+func (e *testEnv) createNew(t *testing.T, sessionID, messageID, path string, at int64) {
+	t.Helper()
+	file, err := e.history.CreateNew(history.WithMessageID(t.Context(), messageID), sessionID, path)
+	require.NoError(t, err)
+	e.setCreatedAt(t, file.ID, at)
+}
+
+func (e *testEnv) setCreatedAt(t *testing.T, fileID string, at int64) {
+	t.Helper()
+	_, err := e.conn.ExecContext(t.Context(), "UPDATE files SET created_at = ? WHERE id = ?", at, fileID)
+	require.NoError(t, err)
+}
+
+func TestRewind(t *testing.T) {
+	env := setupTest(t)
+	dir := t.TempDir()
+	edited := filepath.Join(dir, "edited.go")
+	created := filepath.Join(dir, "created.go")
+	empty := filepath.Join(dir, "empty.go")
+
+	sess, err := env.sessions.Create(t.Context(), "Test")
+	require.NoError(t, err)
+
+	// Everything happens within the same second, so only the messages tell
+	// the versions apart.
+	env.createMessage(t, sess.ID, message.User, "first", 100)
+	first := env.createMessage(t, sess.ID, message.Assistant, "done", 100)
+	env.createVersion(t, sess.ID, first.ID, edited, "original", 100)
+	env.createVersion(t, sess.ID, first.ID, edited, "first edit", 100)
+
+	second := env.createMessage(t, sess.ID, message.User, "second", 100)
+	reply := env.createMessage(t, sess.ID, message.Assistant, "done again", 100)
+	env.createVersion(t, sess.ID, reply.ID, edited, "second edit", 100)
+	env.createNew(t, sess.ID, reply.ID, created, 100)
+	env.createVersion(t, sess.ID, reply.ID, created, "new file", 100)
+	env.createVersion(t, sess.ID, reply.ID, empty, "", 100)
+	env.createVersion(t, sess.ID, reply.ID, empty, "filled", 100)
+
+	require.NoError(t, os.WriteFile(edited, []byte("second edit"), 0o644))
+	require.NoError(t, os.WriteFile(created, []byte("new file"), 0o644))
+	require.NoError(t, os.WriteFile(empty, []byte("filled"), 0o644))
+
+	result, err := env.svc.Rewind(t.Context(), sess.ID, second.ID)
+	require.NoError(t, err)
+	require.Equal(t, second.ID, result.Message.ID)
+	require.Equal(t, 2, result.DeletedMessages)
+	require.Equal(t, []string{edited, empty}, result.RestoredFiles)
+	require.Equal(t, []string{created}, result.RemovedFiles)
+
+	content, err := os.ReadFile(edited)
+	require.NoError(t, err)
+	require.Equal(t, "first edit", string(content))
+	require.NoFileExists(t, created)
+	content, err = os.ReadFile(empty)
+	require.NoError(t, err)
+	require.Empty(t, content)
+
+	msgs, err := env.messages.List(t.Context(), sess.ID)
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+	require.Equal(t, "done", msgs[1].Content().Text)
+
+	files, err := env.history.ListBySession(t.Context(), sess.ID)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	for _, file := range files {
+		require.Equal(t, edited, file.Path)
+	}
+}
+
+func TestRewindWithoutMessageIDs(t *testing.T) {
+	env := setupTest(t)
+	edited := filepath.Join(t.TempDir(), "edited.go")
+
+	sess, err := env.sessions.Create(t.Context(), "Test")
+	require.NoError(t, err)
+
+	env.createMessage(t, sess.ID, message.User, "first", 100)
+	env.createVersion(t, sess.ID, "", edited, "original", 101)
+	second := env.createMessage(t, sess.ID, message.User, "second", 200)
+	env.createVersion(t, sess.ID, "", edited, "edit", 200)
+	require.NoError(t, os.WriteFile(edited, []byte("edit"), 0o644))
+
+	result, err := env.svc.Rewind(t.Context(), sess.ID, second.ID)
+	require.NoError(t, err)
+	require.Equal(t, []string{edited}, result.RestoredFiles)
+
+	content, err := os.ReadFile(edited)
+	require.NoError(t, err)
+	require.Equal(t, "original", string(content))
+}
+
+func TestRewindErrors(t *testing.T) {
+	env := setupTest(t)
+
+	sess, err := env.sessions.Create(t.Context(), "Test")
+	require.NoError(t, err)
+	env.createMessage(t, sess.ID, message.User, "hello", 100)
+	reply := env.createMessage(t, sess.ID, message.Assistant, "hi", 101)
+
+	_, err = env.svc.Rewind(t.Context(), sess.ID, reply.ID)
+	require.ErrorIs(t, err, ErrNotUserMessage)
+
+	_, err = env.svc.Rewind(t.Context(), sess.ID, "missing")
+	require.ErrorIs(t, err, ErrMessageNotFound)
+
+	msg, err := env.svc.ResolveMessage(t.Context(), sess.ID, reply.ID[:8])
+	require.NoError(t, err)
+	require.Equal(t, reply.ID, msg.ID)
+}
@@ -0,0 +1,143 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/agent/tools/mcp"
+	"github.com/charmbracelet/crush/internal/pubsub"
+)
+
+// Names of the server-sent events.
+const (
+	EventSession           = "session"
+	EventMessage           = "message"
+	EventPermissionRequest = "permission_request"
+	EventPermission        = "permission"
+	EventMCP               = "mcp"
+	EventRun               = "run"
+)
+
+// keepAliveInterval is how often a comment is sent on idle event streams, so
+// proxies don't close them.
+const keepAliveInterval = 15 * time.Second
+
+// Event is the data of a server-sent event.
+type Event struct {
+	Type    pubsub.EventType `json:"type"`
+	Payload any              `json:"payload"`
+}
+
+// handleEvents streams events as server-sent events. With the session_id
+// query parameter, only the events of that session are sent, along with the
+// MCP and permission notification events, which aren't tied to a session.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("streaming is not supported"))
+		return
+	}
+
+	ctx := r.Context()
+	sessionID := r.URL.Query().Get("session_id")
+	sessions := s.sessions.Subscribe(ctx)
+	messages := s.messages.Subscribe(ctx)
+	requests := s.permissions.Subscribe(ctx)
+	notifications := s.permissions.SubscribeNotifications(ctx)
+	mcpEvents := mcp.SubscribeEvents(ctx)
+	runs := s.runs.Subscribe(ctx)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	matches := func(id string) bool {
+		return sessionID == "" || id == sessionID
+	}
+	for {
+		var (
+			name string
+			data Event
+		)
+		select {
+		case <-ctx.Done():
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+			continue
+		case event, ok := <-sessions:
+			if !ok {
+				return
+			}
+			if !matches(event.Payload.ID) && !matches(event.Payload.ParentSessionID) {
+				continue
+			}
+			name, data = EventSession, Event{event.Type, toSession(event.Payload)}
+		case event, ok := <-messages:
+			if !ok {
+				return
+			}
+			if !matches(event.Payload.SessionID) {
+				continue
+			}
+			msg, err := toMessage(event.Payload)
+			if err != nil {
+				slog.Error("Failed to encode message event", "error", err)
+				continue
+			}
+			name, data = EventMessage, Event{event.Type, msg}
+		case event, ok := <-requests:
+			if !ok {
+				return
+			}
+			if !matches(event.Payload.SessionID) {
+				continue
+			}
+			name, data = EventPermissionRequest, Event{event.Type, toPermissionRequest(event.Payload)}
+		case event, ok := <-notifications:
+			if !ok {
+				return
+			}
+			name, data = EventPermission, Event{event.Type, event.Payload}
+		case event, ok := <-mcpEvents:
+			if !ok {
+				return
+			}
+			name, data = EventMCP, Event{event.Type, toMCPEvent(event.Payload)}
+		case event, ok := <-runs:
+			if !ok {
+				return
+			}
+			if !matches(event.Payload.SessionID) {
+				continue
+			}
+			name, data = EventRun, Event{event.Type, event.Payload}
+		}
+
+		if err := writeEvent(w, name, data); err != nil {
+			slog.Debug("Event stream closed", "error", err)
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+func writeEvent(w http.ResponseWriter, name string, data Event) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, b)
+	return err
+}
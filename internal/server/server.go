@@ -0,0 +1,391 @@
+// Package server exposes sessions over a local HTTP API, so editor plugins,
+// web UIs and bots can drive Crush without a terminal. Events are streamed
+// to clients with server-sent events.
+package server
+
+import (
+	"cmp"
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/charmbracelet/crush/internal/session"
+)
+
+// Agent runs prompts in sessions. It's implemented by [agent.Coordinator].
+type Agent interface {
+	Run(ctx context.Context, sessionID, prompt string, attachments ...message.Attachment) (*fantasy.AgentResult, error)
+	Cancel(sessionID string)
+	IsSessionBusy(sessionID string) bool
+}
+
+// Options configures a [Server].
+type Options struct {
+	Sessions    session.Service
+	Messages    message.Service
+	Permissions permission.Service
+	// Agent runs prompts. Prompts are rejected if it's nil, which is the case
+	// when no provider is configured.
+	Agent Agent
+	// Token, if set, must be sent by clients as a bearer token.
+	Token string
+	// Hosts are the host names accepted in the Host and Origin headers of
+	// requests, besides loopback ones. Other hosts are rejected, so web pages
+	// can't reach the server through DNS rebinding.
+	Hosts []string
+}
+
+// Server is the HTTP API. It implements [http.Handler].
+type Server struct {
+	ctx         context.Context
+	sessions    session.Service
+	messages    message.Service
+	permissions permission.Service
+	agent       Agent
+	handler     http.Handler
+
+	// pending holds the permission requests waiting for an answer.
+	pending *csync.Map[string, permission.PermissionRequest]
+	runs    *pubsub.Broker[RunEvent]
+	mux     *http.ServeMux
+}
+
+// New creates a server. Prompts run, and permission requests are tracked,
+// until ctx is done.
+func New(ctx context.Context, opts Options) *Server {
+	s := &Server{
+		ctx:         ctx,
+		sessions:    opts.Sessions,
+		messages:    opts.Messages,
+		permissions: opts.Permissions,
+		agent:       opts.Agent,
+		pending:     csync.NewMap[string, permission.PermissionRequest](),
+		runs:        pubsub.NewBroker[RunEvent](),
+		mux:         http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("GET /v1/sessions", s.handleListSessions)
+	s.mux.HandleFunc("POST /v1/sessions", s.handleCreateSession)
+	s.mux.HandleFunc("GET /v1/sessions/{id}", s.handleGetSession)
+	s.mux.HandleFunc("DELETE /v1/sessions/{id}", s.handleDeleteSession)
+	s.mux.HandleFunc("GET /v1/sessions/{id}/messages", s.handleListMessages)
+	s.mux.HandleFunc("POST /v1/sessions/{id}/prompt", s.handlePrompt)
+	s.mux.HandleFunc("POST /v1/sessions/{id}/cancel", s.handleCancel)
+	s.mux.HandleFunc("GET /v1/permissions", s.handleListPermissions)
+	s.mux.HandleFunc("POST /v1/permissions/{id}", s.handleAnswerPermission)
+	s.mux.HandleFunc("GET /v1/events", s.handleEvents)
+	s.handler = Guard(opts.Token, opts.Hosts, s.mux)
+
+	s.trackPermissions()
+	go func() {
+		<-ctx.Done()
+		s.runs.Shutdown()
+	}()
+	return s
+}
+
+// ServeHTTP implements [http.Handler].
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.ServeHTTP(w, r)
+}
+
+// Guard rejects requests whose Host or Origin header isn't a loopback host or
+// one of hosts, so web pages can't reach next through DNS rebinding. If token
+// is set, requests must also send it as a bearer token.
+func Guard(token string, hosts []string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !allowedHost(hosts, r.Host) {
+			writeError(w, http.StatusForbidden, errors.New("host not allowed"))
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" {
+			u, err := url.Parse(origin)
+			if err != nil || !allowedHost(hosts, u.Host) {
+				writeError(w, http.StatusForbidden, errors.New("origin not allowed"))
+				return
+			}
+		}
+		if token != "" {
+			got, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				writeError(w, http.StatusUnauthorized, errors.New("invalid or missing token"))
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowedHost returns whether host, with an optional port, is a loopback
+// host or one of hosts.
+func allowedHost(hosts []string, host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return true
+	}
+	return slices.ContainsFunc(hosts, func(h string) bool {
+		return strings.EqualFold(h, host)
+	})
+}
+
+// trackPermissions keeps the list of pending permission requests up to date,
+// so clients connecting late can still answer them.
+func (s *Server) trackPermissions() {
+	requests := s.permissions.Subscribe(s.ctx)
+	notifications := s.permissions.SubscribeNotifications(s.ctx)
+	go func() {
+		for {
+			select {
+			case event, ok := <-requests:
+				if !ok {
+					return
+				}
+				s.pending.Set(event.Payload.ID, event.Payload)
+			case event, ok := <-notifications:
+				if !ok {
+					return
+				}
+				n := event.Payload
+				if !n.Granted && !n.Denied {
+					continue
+				}
+				for id, req := range s.pending.Seq2() {
+					if req.ToolCallID == n.ToolCallID {
+						s.pending.Del(id)
+					}
+				}
+			}
+		}
+	}()
+}
+
+func (s *Server) handleListSessions(w http.ResponseWriter, r *http.Request) {
+	sessions, err := s.sessions.List(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	out := make([]Session, len(sessions))
+	for i, sess := range sessions {
+		out[i] = toSession(sess)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) handleCreateSession(w http.ResponseWriter, r *http.Request) {
+	var req CreateSessionRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	sess, err := s.sessions.Create(r.Context(), cmp.Or(req.Title, "New Session"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, toSession(sess))
+}
+
+func (s *Server) handleGetSession(w http.ResponseWriter, r *http.Request) {
+	sess, ok := s.session(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, toSession(sess))
+}
+
+func (s *Server) handleDeleteSession(w http.ResponseWriter, r *http.Request) {
+	sess, ok := s.session(w, r)
+	if !ok {
+		return
+	}
+	if s.agent != nil && s.agent.IsSessionBusy(sess.ID) {
+		writeError(w, http.StatusConflict, errors.New("session is busy"))
+		return
+	}
+	if err := s.sessions.Delete(r.Context(), sess.ID); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleListMessages(w http.ResponseWriter, r *http.Request) {
+	sess, ok := s.session(w, r)
+	if !ok {
+		return
+	}
+	msgs, err := s.messages.List(r.Context(), sess.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	out := make([]Message, 0, len(msgs))
+	for _, msg := range msgs {
+		m, err := toMessage(msg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		out = append(out, m)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) handlePrompt(w http.ResponseWriter, r *http.Request) {
+	if s.agent == nil {
+		writeError(w, http.StatusServiceUnavailable, errors.New("no provider is configured"))
+		return
+	}
+	sess, ok := s.session(w, r)
+	if !ok {
+		return
+	}
+	var req PromptRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if strings.TrimSpace(req.Prompt) == "" {
+		writeError(w, http.StatusBadRequest, errors.New("prompt is required"))
+		return
+	}
+
+	// Prompts sent while the session is busy are queued by the agent.
+	queued := s.agent.IsSessionBusy(sess.ID)
+	go s.run(sess.ID, req.Prompt)
+	writeJSON(w, http.StatusAccepted, PromptResponse{
+		SessionID: sess.ID,
+		Queued:    queued,
+	})
+}
+
+// run runs a prompt in the background, publishing a [RunEvent] once done.
+func (s *Server) run(sessionID, prompt string) {
+	result, err := s.agent.Run(s.ctx, sessionID, prompt)
+	event := RunEvent{SessionID: sessionID}
+	switch {
+	case err != nil:
+		if !errors.Is(err, context.Canceled) {
+			slog.Error("Failed to run prompt", "session_id", sessionID, "error", err)
+		}
+		event.Error = err.Error()
+	case result != nil:
+		event.Result = result.Response.Content.Text()
+	}
+	s.runs.Publish(pubsub.UpdatedEvent, event)
+}
+
+func (s *Server) handleCancel(w http.ResponseWriter, r *http.Request) {
+	sess, ok := s.session(w, r)
+	if !ok {
+		return
+	}
+	if s.agent != nil {
+		s.agent.Cancel(sess.ID)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleListPermissions(w http.ResponseWriter, r *http.Request) {
+	sessionID := r.URL.Query().Get("session_id")
+	out := []PermissionRequest{}
+	for req := range s.pending.Seq() {
+		if sessionID != "" && req.SessionID != sessionID {
+			continue
+		}
+		out = append(out, toPermissionRequest(req))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) handleAnswerPermission(w http.ResponseWriter, r *http.Request) {
+	var req PermissionAnswer
+	if !readJSON(w, r, &req) {
+		return
+	}
+	var answer func(permission.PermissionRequest)
+	switch req.Decision {
+	case DecisionAllow:
+		answer = s.permissions.Grant
+	case DecisionAllowSession:
+		answer = s.permissions.GrantPersistent
+	case DecisionDeny:
+		answer = s.permissions.Deny
+	default:
+		writeError(w, http.StatusBadRequest, errors.New(`decision must be "allow", "allow_session" or "deny"`))
+		return
+	}
+
+	pending, ok := s.pending.Take(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, errors.New("permission request not found"))
+		return
+	}
+	answer(pending)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// session gets the session in the request path, writing an error if it
+// doesn't exist.
+func (s *Server) session(w http.ResponseWriter, r *http.Request) (session.Session, bool) {
+	sess, err := s.sessions.Get(r.Context(), r.PathValue("id"))
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		writeError(w, http.StatusNotFound, errors.New("session not found"))
+		return session.Session{}, false
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err)
+		return session.Session{}, false
+	}
+	return sess, true
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	if r.ContentLength == 0 {
+		return true
+	}
+	// Requiring JSON keeps browsers from sending requests without a CORS
+	// preflight, which plain text forms don't need.
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		writeError(w, http.StatusUnsupportedMediaType, errors.New("content type must be application/json"))
+		return false
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		slog.Error("Failed to write response", "error", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, Error{Error: err.Error()})
+}
@@ -0,0 +1,290 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAgent stores the prompt, asks for permission to run a tool and stores
+// the outcome as the answer.
+type fakeAgent struct {
+	messages    message.Service
+	permissions permission.Service
+}
+
+func (a *fakeAgent) Run(ctx context.Context, sessionID, prompt string, _ ...message.Attachment) (*fantasy.AgentResult, error) {
+	if _, err := a.messages.Create(ctx, sessionID, message.CreateMessageParams{
+		Role:  message.User,
+		Parts: []message.ContentPart{message.TextContent{Text: prompt}},
+	}); err != nil {
+		return nil, err
+	}
+	granted, err := a.permissions.Request(ctx, permission.CreatePermissionRequest{
+		SessionID:  sessionID,
+		ToolCallID: "call-1",
+		ToolName:   "bash",
+		Action:     "execute",
+		Path:       ".",
+	})
+	if err != nil {
+		return nil, err
+	}
+	answer := "denied"
+	if granted {
+		answer = "granted"
+	}
+	_, err = a.messages.Create(ctx, sessionID, message.CreateMessageParams{
+		Role:  message.Assistant,
+		Parts: []message.ContentPart{message.TextContent{Text: answer}},
+	})
+	return nil, err
+}
+
+func (a *fakeAgent) Cancel(string)             {}
+func (a *fakeAgent) IsSessionBusy(string) bool { return false }
+
+func setupServer(t *testing.T, token string) *httptest.Server {
+	t.Helper()
+
+	conn, err := db.Connect(t.Context(), t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	q := db.New(conn)
+	sessions := session.NewService(q, conn)
+	messages := message.NewService(q)
+	permissions := permission.NewPermissionService(t.TempDir(), false, nil, nil, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	srv := httptest.NewServer(New(ctx, Options{
+		Sessions:    sessions,
+		Messages:    messages,
+		Permissions: permissions,
+		Agent:       &fakeAgent{messages: messages, permissions: permissions},
+		Token:       token,
+	}))
+	t.Cleanup(func() {
+		cancel()
+		srv.Close()
+	})
+	return srv
+}
+
+func doJSON(t *testing.T, method, url string, body, out any) int {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		require.NoError(t, json.NewEncoder(&buf).Encode(body))
+	}
+	req, err := http.NewRequestWithContext(t.Context(), method, url, &buf)
+	require.NoError(t, err)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	if out != nil {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+	}
+	return resp.StatusCode
+}
+
+type sseEvent struct {
+	name string
+	data Event
+}
+
+// readEvent reads server-sent events from the stream until one named name
+// is found.
+func readEvent(t *testing.T, events <-chan sseEvent, name string) sseEvent {
+	t.Helper()
+	for {
+		select {
+		case event, ok := <-events:
+			require.True(t, ok, "event stream closed before %q", name)
+			if event.name == name {
+				return event
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %q event", name)
+		}
+	}
+}
+
+func subscribe(t *testing.T, url string) <-chan sseEvent {
+	t.Helper()
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, url, nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	events := make(chan sseEvent, 64)
+	go func() {
+		defer resp.Body.Close()
+		defer close(events)
+		var name string
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if n, ok := strings.CutPrefix(line, "event: "); ok {
+				name = n
+			}
+			if d, ok := strings.CutPrefix(line, "data: "); ok {
+				var data Event
+				if err := json.Unmarshal([]byte(d), &data); err == nil {
+					events <- sseEvent{name: name, data: data}
+				}
+			}
+		}
+	}()
+	return events
+}
+
+func TestServer_Sessions(t *testing.T) {
+	srv := setupServer(t, "")
+
+	var created Session
+	status := doJSON(t, http.MethodPost, srv.URL+"/v1/sessions", CreateSessionRequest{Title: "API session"}, &created)
+	require.Equal(t, http.StatusCreated, status)
+	require.Equal(t, "API session", created.Title)
+
+	var sessions []Session
+	status = doJSON(t, http.MethodGet, srv.URL+"/v1/sessions", nil, &sessions)
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, sessions, 1)
+	require.Equal(t, created.ID, sessions[0].ID)
+
+	var got Session
+	status = doJSON(t, http.MethodGet, srv.URL+"/v1/sessions/"+created.ID, nil, &got)
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, created, got)
+
+	var apiErr Error
+	status = doJSON(t, http.MethodGet, srv.URL+"/v1/sessions/missing", nil, &apiErr)
+	require.Equal(t, http.StatusNotFound, status)
+	require.Equal(t, "session not found", apiErr.Error)
+
+	status = doJSON(t, http.MethodDelete, srv.URL+"/v1/sessions/"+created.ID, nil, nil)
+	require.Equal(t, http.StatusNoContent, status)
+	status = doJSON(t, http.MethodGet, srv.URL+"/v1/sessions/"+created.ID, nil, nil)
+	require.Equal(t, http.StatusNotFound, status)
+}
+
+func TestServer_PromptAndPermissions(t *testing.T) {
+	srv := setupServer(t, "")
+
+	var sess Session
+	require.Equal(t, http.StatusCreated, doJSON(t, http.MethodPost, srv.URL+"/v1/sessions", nil, &sess))
+
+	events := subscribe(t, srv.URL+"/v1/events?session_id="+sess.ID)
+
+	var prompt PromptResponse
+	status := doJSON(t, http.MethodPost, srv.URL+"/v1/sessions/"+sess.ID+"/prompt", PromptRequest{Prompt: "list the files"}, &prompt)
+	require.Equal(t, http.StatusAccepted, status)
+	require.Equal(t, sess.ID, prompt.SessionID)
+
+	event := readEvent(t, events, EventMessage)
+	require.Equal(t, "created", string(event.data.Type))
+
+	readEvent(t, events, EventPermissionRequest)
+	var pending []PermissionRequest
+	require.Eventually(t, func() bool {
+		pending = nil
+		doJSON(t, http.MethodGet, srv.URL+"/v1/permissions", nil, &pending)
+		return len(pending) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, "bash", pending[0].ToolName)
+	require.Equal(t, sess.ID, pending[0].SessionID)
+
+	status = doJSON(t, http.MethodPost, srv.URL+"/v1/permissions/"+pending[0].ID, PermissionAnswer{Decision: "maybe"}, nil)
+	require.Equal(t, http.StatusBadRequest, status)
+	status = doJSON(t, http.MethodPost, srv.URL+"/v1/permissions/"+pending[0].ID, PermissionAnswer{Decision: DecisionAllow}, nil)
+	require.Equal(t, http.StatusNoContent, status)
+	status = doJSON(t, http.MethodPost, srv.URL+"/v1/permissions/"+pending[0].ID, PermissionAnswer{Decision: DecisionAllow}, nil)
+	require.Equal(t, http.StatusNotFound, status)
+
+	run := readEvent(t, events, EventRun)
+	payload, ok := run.data.Payload.(map[string]any)
+	require.True(t, ok)
+	require.Equal(t, sess.ID, payload["session_id"])
+	require.Empty(t, payload["error"])
+
+	var msgs []Message
+	require.Equal(t, http.StatusOK, doJSON(t, http.MethodGet, srv.URL+"/v1/sessions/"+sess.ID+"/messages", nil, &msgs))
+	require.Len(t, msgs, 2)
+	require.Equal(t, message.User, msgs[0].Role)
+	require.Equal(t, message.Assistant, msgs[1].Role)
+	parts, err := message.UnmarshalParts(msgs[1].Parts)
+	require.NoError(t, err)
+	require.Equal(t, []message.ContentPart{message.TextContent{Text: "granted"}}, parts)
+}
+
+func TestServer_Token(t *testing.T) {
+	srv := setupServer(t, "secret")
+
+	status := doJSON(t, http.MethodGet, srv.URL+"/v1/sessions", nil, nil)
+	require.Equal(t, http.StatusUnauthorized, status)
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, srv.URL+"/v1/sessions", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestServer_Hosts(t *testing.T) {
+	srv := setupServer(t, "")
+
+	do := func(header, value string) int {
+		req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, srv.URL+"/v1/sessions", nil)
+		require.NoError(t, err)
+		if header == "Host" {
+			req.Host = value
+		} else {
+			req.Header.Set(header, value)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	require.Equal(t, http.StatusOK, do("Host", "localhost:4141"))
+	require.Equal(t, http.StatusOK, do("Host", "[::1]:4141"))
+	require.Equal(t, http.StatusForbidden, do("Host", "attacker.example:4141"))
+	require.Equal(t, http.StatusOK, do("Origin", "http://localhost:3000"))
+	require.Equal(t, http.StatusForbidden, do("Origin", "http://attacker.example"))
+	require.Equal(t, http.StatusForbidden, do("Origin", "null"))
+}
+
+func TestServer_ContentType(t *testing.T) {
+	srv := setupServer(t, "")
+
+	resp, err := http.Post(srv.URL+"/v1/sessions", "text/plain", strings.NewReader(`{"title": "Form"}`))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+
+	resp, err = http.Post(srv.URL+"/v1/sessions", "application/json; charset=utf-8", strings.NewReader(`{"title": "JSON"}`))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+}
@@ -0,0 +1,159 @@
+package server
+
+import (
+	"encoding/json"
+
+	"github.com/charmbracelet/crush/internal/agent/tools/mcp"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/session"
+)
+
+// Error is the body of error responses.
+type Error struct {
+	Error string `json:"error"`
+}
+
+// Session is a session, as returned by the API.
+type Session struct {
+	ID               string  `json:"id"`
+	ParentSessionID  string  `json:"parent_session_id,omitempty"`
+	IsFork           bool    `json:"is_fork,omitempty"`
+	Title            string  `json:"title"`
+	MessageCount     int64   `json:"message_count"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`
+	CreatedAt        int64   `json:"created_at"`
+	UpdatedAt        int64   `json:"updated_at"`
+}
+
+func toSession(s session.Session) Session {
+	return Session{
+		ID:               s.ID,
+		ParentSessionID:  s.ParentSessionID,
+		IsFork:           s.IsFork,
+		Title:            s.Title,
+		MessageCount:     s.MessageCount,
+		PromptTokens:     s.PromptTokens,
+		CompletionTokens: s.CompletionTokens,
+		Cost:             s.Cost,
+		CreatedAt:        s.CreatedAt,
+		UpdatedAt:        s.UpdatedAt,
+	}
+}
+
+// Message is a message, as returned by the API. Parts are encoded the same
+// way they're stored, as a list of objects with a type and its data.
+type Message struct {
+	ID        string              `json:"id"`
+	SessionID string              `json:"session_id"`
+	Role      message.MessageRole `json:"role"`
+	Parts     json.RawMessage     `json:"parts"`
+	Model     string              `json:"model,omitempty"`
+	Provider  string              `json:"provider,omitempty"`
+	CreatedAt int64               `json:"created_at"`
+	UpdatedAt int64               `json:"updated_at"`
+}
+
+func toMessage(m message.Message) (Message, error) {
+	parts, err := message.MarshalParts(m.Parts)
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{
+		ID:        m.ID,
+		SessionID: m.SessionID,
+		Role:      m.Role,
+		Parts:     parts,
+		Model:     m.Model,
+		Provider:  m.Provider,
+		CreatedAt: m.CreatedAt,
+		UpdatedAt: m.UpdatedAt,
+	}, nil
+}
+
+// PermissionRequest is a permission request waiting for an answer.
+type PermissionRequest struct {
+	ID          string `json:"id"`
+	SessionID   string `json:"session_id"`
+	ToolCallID  string `json:"tool_call_id"`
+	ToolName    string `json:"tool_name"`
+	Description string `json:"description"`
+	Action      string `json:"action"`
+	Params      any    `json:"params"`
+	Path        string `json:"path"`
+	// OneTime requests can't be allowed for the session.
+	OneTime bool `json:"one_time,omitempty"`
+}
+
+func toPermissionRequest(p permission.PermissionRequest) PermissionRequest {
+	return PermissionRequest(p)
+}
+
+// PermissionDecision is the answer to a permission request.
+type PermissionDecision string
+
+const (
+	// DecisionAllow allows the request once.
+	DecisionAllow PermissionDecision = "allow"
+	// DecisionAllowSession allows the request for the rest of the session.
+	DecisionAllowSession PermissionDecision = "allow_session"
+	// DecisionDeny denies the request.
+	DecisionDeny PermissionDecision = "deny"
+)
+
+// PermissionAnswer is the body of a request answering a permission request.
+type PermissionAnswer struct {
+	Decision PermissionDecision `json:"decision"`
+}
+
+// CreateSessionRequest is the body of a request creating a session.
+type CreateSessionRequest struct {
+	Title string `json:"title"`
+}
+
+// PromptRequest is the body of a request sending a prompt to a session.
+type PromptRequest struct {
+	Prompt string `json:"prompt"`
+}
+
+// PromptResponse is returned once a prompt is accepted. The progress of the
+// prompt is streamed as events.
+type PromptResponse struct {
+	SessionID string `json:"session_id"`
+	// Queued is true if the session was busy, so the prompt runs after the
+	// current one.
+	Queued bool `json:"queued"`
+}
+
+// RunEvent is published when a prompt is done running.
+type RunEvent struct {
+	SessionID string `json:"session_id"`
+	Result    string `json:"result,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// MCPEvent is a change in the state of an MCP server.
+type MCPEvent struct {
+	Name      string `json:"name"`
+	State     string `json:"state"`
+	Error     string `json:"error,omitempty"`
+	Tools     int    `json:"tools"`
+	Prompts   int    `json:"prompts"`
+	Resources int    `json:"resources"`
+}
+
+func toMCPEvent(e mcp.Event) MCPEvent {
+	event := MCPEvent{
+		Name:      e.Name,
+		State:     e.State.String(),
+		Tools:     e.Counts.Tools,
+		Prompts:   e.Counts.Prompts,
+		Resources: e.Counts.Resources,
+	}
+	if e.Error != nil {
+		event.Error = e.Error.Error()
+	}
+	return event
+}
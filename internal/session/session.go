@@ -58,6 +58,11 @@ type Session struct {
 	Todos            []Todo
 	CreatedAt        int64
 	UpdatedAt        int64
+	// IsFork is set for sessions forked from their parent session, as
+	// opposed to the sessions of sub-agents and title generation.
+	IsFork bool
+	// AgentID is the agent picked for the session, if any.
+	AgentID string
 }
 
 type Service interface {
@@ -65,11 +70,13 @@ type Service interface {
 	Create(ctx context.Context, title string) (Session, error)
 	CreateTitleSession(ctx context.Context, parentSessionID string) (Session, error)
 	CreateTaskSession(ctx context.Context, toolCallID, parentSessionID, title string) (Session, error)
+	CreateFork(ctx context.Context, parentSessionID, title string) (Session, error)
 	Get(ctx context.Context, id string) (Session, error)
 	List(ctx context.Context) ([]Session, error)
 	Save(ctx context.Context, session Session) (Session, error)
 	UpdateTitleAndUsage(ctx context.Context, sessionID, title string, promptTokens, completionTokens int64, cost float64) error
 	Rename(ctx context.Context, id string, title string) error
+	SetAgent(ctx context.Context, id, agentID string) error
 	Delete(ctx context.Context, id string) error
 
 	// Agent tool session management
@@ -112,6 +119,24 @@ func (s *service) CreateTaskSession(ctx context.Context, toolCallID, parentSessi
 	return session, nil
 }
 
+// CreateFork creates an empty session forked from the given one. Forks are
+// listed along with top-level sessions.
+func (s *service) CreateFork(ctx context.Context, parentSessionID, title string) (Session, error) {
+	dbSession, err := s.q.CreateSession(ctx, db.CreateSessionParams{
+		ID:              uuid.New().String(),
+		ParentSessionID: sql.NullString{String: parentSessionID, Valid: true},
+		Title:           title,
+		IsFork:          1,
+	})
+	if err != nil {
+		return Session{}, err
+	}
+	session := s.fromDBItem(dbSession)
+	s.Publish(pubsub.CreatedEvent, session)
+	event.SessionCreated()
+	return session, nil
+}
+
 func (s *service) CreateTitleSession(ctx context.Context, parentSessionID string) (Session, error) {
 	dbSession, err := s.q.CreateSession(ctx, db.CreateSessionParams{
 		ID:              "title-" + parentSessionID,
@@ -216,6 +241,14 @@ func (s *service) Rename(ctx context.Context, id string, title string) error {
 	})
 }
 
+// SetAgent sets the agent the session runs with.
+func (s *service) SetAgent(ctx context.Context, id, agentID string) error {
+	return s.q.UpdateSessionAgent(ctx, db.UpdateSessionAgentParams{
+		ID:      id,
+		AgentID: sql.NullString{String: agentID, Valid: agentID != ""},
+	})
+}
+
 func (s *service) List(ctx context.Context) ([]Session, error) {
 	dbSessions, err := s.q.ListSessions(ctx)
 	if err != nil {
@@ -236,6 +269,8 @@ func (s service) fromDBItem(item db.Session) Session {
 	return Session{
 		ID:               item.ID,
 		ParentSessionID:  item.ParentSessionID.String,
+		IsFork:           item.IsFork != 0,
+		AgentID:          item.AgentID.String,
 		Title:            item.Title,
 		MessageCount:     item.MessageCount,
 		PromptTokens:     item.PromptTokens,
@@ -0,0 +1,136 @@
+package shell
+
+import (
+	"fmt"
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// Command is a simple command of a command line.
+type Command struct {
+	// Args is the name and the arguments of the command, like "go test".
+	Args string
+	// Text is the whole command, with its variable assignments and
+	// redirections, like "GOFLAGS=-v go test > out.txt".
+	Text string
+	// Assigns reports whether the command assigns variables, which can
+	// change what the command, or the ones run after it, do.
+	Assigns bool
+	// WritesFiles reports whether the output of the command is redirected
+	// to files other than /dev/null.
+	WritesFiles bool
+}
+
+// SplitCommands returns the simple commands a command line runs, including
+// the ones chained with operators like "&&", ";" and "|", and the ones run
+// in subshells and command substitutions. The redirections of compound
+// commands, like "(cd x && make) > out.txt", apply to every command they
+// run.
+func SplitCommands(command string) ([]Command, error) {
+	file, err := syntax.NewParser().Parse(strings.NewReader(command), "")
+	if err != nil {
+		return nil, fmt.Errorf("could not parse command: %w", err)
+	}
+
+	printer := syntax.NewPrinter()
+	var (
+		commands []Command
+		// The nodes enclosing the current one, walked down to it.
+		parents []syntax.Node
+	)
+	syntax.Walk(file, func(node syntax.Node) bool {
+		if node == nil {
+			parents = parents[:len(parents)-1]
+			return true
+		}
+		defer func() { parents = append(parents, node) }()
+
+		var (
+			args    []string
+			assigns []*syntax.Assign
+		)
+		switch node := node.(type) {
+		case *syntax.Stmt:
+			// Statements like "> out.txt" only have redirections.
+			if node.Cmd != nil || len(node.Redirs) == 0 {
+				return true
+			}
+		case *syntax.CallExpr:
+			for _, word := range node.Args {
+				args = append(args, printNode(printer, word))
+			}
+			assigns = node.Assigns
+		case *syntax.DeclClause:
+			// Declarations like export and local are commands too.
+			args = append(args, node.Variant.Value)
+			for _, assign := range node.Args {
+				args = append(args, printNode(printer, assign))
+			}
+		default:
+			return true
+		}
+
+		cmd := Command{
+			Args:    strings.Join(args, " "),
+			Assigns: len(assigns) > 0,
+		}
+		text := make([]string, 0, len(assigns)+len(args))
+		for _, assign := range assigns {
+			text = append(text, printNode(printer, assign))
+		}
+		text = append(text, args...)
+		for _, redir := range redirections(node, parents) {
+			target := printNode(printer, redir.Word)
+			var n string
+			if redir.N != nil {
+				n = redir.N.Value
+			}
+			text = append(text, n+redir.Op.String()+target)
+			cmd.WritesFiles = cmd.WritesFiles || writesFile(redir.Op, target)
+		}
+		cmd.Text = strings.Join(text, " ")
+		commands = append(commands, cmd)
+		return true
+	})
+	return commands, nil
+}
+
+// redirections returns the redirections applying to a command: the ones of
+// its own statement, and the ones of the compound commands it's part of, up
+// to the command substitution it's run in, if any, which captures its output.
+func redirections(node syntax.Node, parents []syntax.Node) []*syntax.Redirect {
+	var redirs []*syntax.Redirect
+	if stmt, ok := node.(*syntax.Stmt); ok {
+		redirs = append(redirs, stmt.Redirs...)
+	}
+	for i := len(parents) - 1; i >= 0; i-- {
+		switch parent := parents[i].(type) {
+		case *syntax.CmdSubst, *syntax.ProcSubst:
+			return redirs
+		case *syntax.Stmt:
+			redirs = append(redirs, parent.Redirs...)
+		}
+	}
+	return redirs
+}
+
+// writesFile reports whether a redirection writes to its target.
+func writesFile(op syntax.RedirOperator, target string) bool {
+	switch op {
+	case syntax.RdrOut, syntax.AppOut, syntax.RdrInOut, syntax.RdrClob, syntax.AppClob,
+		syntax.RdrAll, syntax.RdrAllClob, syntax.AppAll, syntax.AppAllClob:
+		return target != "/dev/null"
+	case syntax.DplOut:
+		// ">&file" redirects to a file, ">&2" and ">&-" don't.
+		return target != "-" && strings.Trim(target, "0123456789") != ""
+	}
+	return false
+}
+
+func printNode(printer *syntax.Printer, node syntax.Node) string {
+	var sb strings.Builder
+	// Nodes coming out of the parser always print.
+	_ = printer.Print(&sb, node)
+	return sb.String()
+}
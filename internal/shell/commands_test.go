@@ -0,0 +1,78 @@
+package shell
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitCommands(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		command  string
+		expected []string
+	}{
+		{"go test ./...", []string{"go test ./..."}},
+		{"  go test ./...", []string{"go test ./..."}},
+		{"go test ./... && rm -rf ~", []string{"go test ./...", "rm -rf ~"}},
+		{"cd x; rm -rf y", []string{"cd x", "rm -rf y"}},
+		{"cat go.mod | grep mvdan", []string{"cat go.mod", "grep mvdan"}},
+		{"(cd x && rm -rf y)", []string{"cd x", "rm -rf y"}},
+		{"echo $(rm -rf y)", []string{"echo $(rm -rf y)", "rm -rf y"}},
+		{"FOO=bar go test > out.txt", []string{"go test"}},
+		{"export FOO=`rm -rf y`", []string{"export FOO=$(rm -rf y)", "rm -rf y"}},
+		{"if true; then rm -rf y; fi", []string{"true", "rm -rf y"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			t.Parallel()
+			commands, err := SplitCommands(tt.command)
+			require.NoError(t, err)
+			var args []string
+			for _, command := range commands {
+				args = append(args, command.Args)
+			}
+			require.Equal(t, tt.expected, args)
+		})
+	}
+
+	_, err := SplitCommands("echo 'unterminated")
+	require.Error(t, err)
+}
+
+func TestSplitCommandsText(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		command  string
+		expected []Command
+	}{
+		{"go test ./...", []Command{{Args: "go test ./...", Text: "go test ./..."}}},
+		{"FOO=bar go test", []Command{{Args: "go test", Text: "FOO=bar go test", Assigns: true}}},
+		{"PATH=/tmp", []Command{{Text: "PATH=/tmp", Assigns: true}}},
+		{"echo x > ~/.bashrc", []Command{{Args: "echo x", Text: "echo x >~/.bashrc", WritesFiles: true}}},
+		{"echo x 2>>log", []Command{{Args: "echo x", Text: "echo x 2>>log", WritesFiles: true}}},
+		{"echo x &>log", []Command{{Args: "echo x", Text: "echo x &>log", WritesFiles: true}}},
+		{"echo x >&log", []Command{{Args: "echo x", Text: "echo x >&log", WritesFiles: true}}},
+		{"> out.txt", []Command{{Text: ">out.txt", WritesFiles: true}}},
+		{"go test 2>&1 >/dev/null", []Command{{Args: "go test", Text: "go test 2>&1 >/dev/null"}}},
+		{"grep x < in.txt", []Command{{Args: "grep x", Text: "grep x <in.txt"}}},
+		{"(cd x && make) > out.txt", []Command{
+			{Args: "cd x", Text: "cd x >out.txt", WritesFiles: true},
+			{Args: "make", Text: "make >out.txt", WritesFiles: true},
+		}},
+		{"echo $(date > now.txt) > /dev/null", []Command{
+			{Args: "echo $(date >now.txt)", Text: "echo $(date >now.txt) >/dev/null"},
+			{Args: "date", Text: "date >now.txt", WritesFiles: true},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			t.Parallel()
+			commands, err := SplitCommands(tt.command)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, commands)
+		})
+	}
+}
@@ -151,7 +151,9 @@ func (a *AssistantMessageItem) renderMessageContent(width int) string {
 		case message.FinishReasonCanceled:
 			messageParts = append(messageParts, a.sty.Base.Italic(true).Render("Canceled"))
 		case message.FinishReasonError:
-			messageParts = append(messageParts, a.renderError(width))
+			messageParts = append(messageParts, a.renderError("ERROR", width))
+		case message.FinishReasonBudgetExceeded:
+			messageParts = append(messageParts, a.renderError("BUDGET", width))
 		}
 	}
 
@@ -219,10 +221,10 @@ func (a *AssistantMessageItem) renderSpinning() string {
 	return a.anim.Render()
 }
 
-// renderError renders an error message.
-func (a *AssistantMessageItem) renderError(width int) string {
+// renderError renders an error message, headed by the given tag.
+func (a *AssistantMessageItem) renderError(tag string, width int) string {
 	finishPart := a.message.FinishPart()
-	errTag := a.sty.Chat.Message.ErrorTag.Render("ERROR")
+	errTag := a.sty.Chat.Message.ErrorTag.Render(tag)
 	truncated := ansi.Truncate(finishPart.Message, width-2-lipgloss.Width(errTag), "...")
 	title := fmt.Sprintf("%s %s", errTag, a.sty.Chat.Message.ErrorTitle.Render(truncated))
 	details := a.sty.Chat.Message.ErrorDetails.Width(width - 2).Render(finishPart.Details)
@@ -274,5 +276,10 @@ func (a *AssistantMessageItem) HandleKeyEvent(key tea.KeyMsg) (bool, tea.Cmd) {
 		text := a.message.Content().Text
 		return true, common.CopyToClipboard(text, "Message copied to clipboard")
 	}
+	if key.String() == "f" {
+		return true, func() tea.Msg {
+			return ForkMsg{Message: a.message}
+		}
+	}
 	return false, nil
 }
@@ -0,0 +1,67 @@
+package chat
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/charmbracelet/crush/internal/agent/tools"
+	"github.com/charmbracelet/crush/internal/fsext"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/ui/styles"
+)
+
+// CodeActionToolMessageItem is a message item that represents a code action tool call.
+type CodeActionToolMessageItem struct {
+	*baseToolMessageItem
+}
+
+var _ ToolMessageItem = (*CodeActionToolMessageItem)(nil)
+
+// NewCodeActionToolMessageItem creates a new [CodeActionToolMessageItem].
+func NewCodeActionToolMessageItem(
+	sty *styles.Styles,
+	toolCall message.ToolCall,
+	result *message.ToolResult,
+	canceled bool,
+) ToolMessageItem {
+	return newBaseToolMessageItem(sty, toolCall, result, &CodeActionToolRenderContext{}, canceled)
+}
+
+// CodeActionToolRenderContext renders code action tool messages.
+type CodeActionToolRenderContext struct{}
+
+// RenderTool implements the [ToolRenderer] interface.
+func (r *CodeActionToolRenderContext) RenderTool(sty *styles.Styles, width int, opts *ToolRenderOpts) string {
+	cappedWidth := cappedMessageWidth(width)
+	if opts.IsPending() {
+		return pendingTool(sty, "Code Action", opts.Anim)
+	}
+
+	var params tools.CodeActionParams
+	_ = json.Unmarshal([]byte(opts.ToolCall.Input), &params)
+
+	toolParams := []string{fsext.PrettyPath(params.FilePath)}
+	if params.Line > 0 {
+		toolParams = append(toolParams, "line", strconv.Itoa(params.Line))
+	}
+	if params.Apply != "" {
+		toolParams = append(toolParams, "apply", params.Apply)
+	}
+
+	header := toolHeader(sty, opts.Status, "Code Action", cappedWidth, opts.Compact, toolParams...)
+	if opts.Compact {
+		return header
+	}
+
+	if earlyState, ok := toolEarlyStateContent(sty, opts, cappedWidth); ok {
+		return joinToolParts(header, earlyState)
+	}
+
+	if opts.HasEmptyResult() {
+		return header
+	}
+
+	bodyWidth := cappedWidth - toolBodyLeftPaddingTotal
+	body := sty.Tool.Body.Render(toolOutputPlainContent(sty, opts.Result.Content, bodyWidth, opts.ExpandedContent))
+	return joinToolParts(header, body)
+}
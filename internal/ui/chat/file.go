@@ -3,6 +3,7 @@ package chat
 import (
 	"encoding/json"
 	"fmt"
+	"path/filepath"
 	"strings"
 
 	"github.com/charmbracelet/crush/internal/agent/tools"
@@ -92,6 +93,14 @@ func (v *ViewToolRenderContext) RenderTool(sty *styles.Styles, width int, opts *
 		return header
 	}
 
+	// PDFs are either passed to the model as is or read as text by pages,
+	// so there are no lines to number.
+	if strings.EqualFold(filepath.Ext(params.FilePath), ".pdf") {
+		bodyWidth := cappedWidth - toolBodyLeftPaddingTotal
+		body := sty.Tool.Body.Render(toolOutputPlainContent(sty, content, bodyWidth, opts.ExpandedContent))
+		return joinToolParts(header, body)
+	}
+
 	// Render code content with syntax highlighting.
 	body := toolOutputCodeContent(sty, params.FilePath, content, params.Offset, cappedWidth, opts.ExpandedContent)
 	return joinToolParts(header, body)
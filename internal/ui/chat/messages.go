@@ -74,6 +74,16 @@ type SendMsg struct {
 	Attachments []message.Attachment
 }
 
+// RewindMsg represents a request to rewind the session to a user message.
+type RewindMsg struct {
+	Message *message.Message
+}
+
+// ForkMsg represents a request to fork the session from a message.
+type ForkMsg struct {
+	Message *message.Message
+}
+
 type highlightableMessageItem struct {
 	startLine   int
 	startCol    int
@@ -302,7 +312,8 @@ func ExtractMessageItems(sty *styles.Styles, msg *message.Message, toolResults m
 func ShouldRenderAssistantMessage(msg *message.Message) bool {
 	content := strings.TrimSpace(msg.Content().Text)
 	thinking := strings.TrimSpace(msg.ReasoningContent().Thinking)
-	isError := msg.FinishReason() == message.FinishReasonError
+	isError := msg.FinishReason() == message.FinishReasonError ||
+		msg.FinishReason() == message.FinishReasonBudgetExceeded
 	isCancelled := msg.FinishReason() == message.FinishReasonCanceled
 	hasToolCalls := len(msg.ToolCalls()) > 0
 	return !hasToolCalls || content != "" || thinking != "" || msg.IsThinking() || isError || isCancelled
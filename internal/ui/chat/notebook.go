@@ -0,0 +1,74 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/charmbracelet/crush/internal/agent/tools"
+	"github.com/charmbracelet/crush/internal/fsext"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/ui/styles"
+)
+
+// NotebookEditToolMessageItem is a message item that represents a notebook
+// edit tool call.
+type NotebookEditToolMessageItem struct {
+	*baseToolMessageItem
+}
+
+var _ ToolMessageItem = (*NotebookEditToolMessageItem)(nil)
+
+// NewNotebookEditToolMessageItem creates a new [NotebookEditToolMessageItem].
+func NewNotebookEditToolMessageItem(
+	sty *styles.Styles,
+	toolCall message.ToolCall,
+	result *message.ToolResult,
+	canceled bool,
+) ToolMessageItem {
+	return newBaseToolMessageItem(sty, toolCall, result, &NotebookEditToolRenderContext{}, canceled)
+}
+
+// NotebookEditToolRenderContext renders notebook edit tool messages.
+type NotebookEditToolRenderContext struct{}
+
+// RenderTool implements the [ToolRenderer] interface.
+func (n *NotebookEditToolRenderContext) RenderTool(sty *styles.Styles, width int, opts *ToolRenderOpts) string {
+	// Notebook edit tool uses full width for diffs.
+	if opts.IsPending() {
+		return pendingTool(sty, "Notebook Edit", opts.Anim)
+	}
+
+	var params tools.NotebookEditParams
+	if err := json.Unmarshal([]byte(opts.ToolCall.Input), &params); err != nil {
+		return toolErrorContent(sty, &message.ToolResult{Content: "Invalid parameters"}, width)
+	}
+
+	file := fsext.PrettyPath(params.FilePath)
+	toolParams := []string{file, "cell", fmt.Sprintf("%d", params.CellIndex)}
+	if params.EditMode != "" {
+		toolParams = append(toolParams, "mode", params.EditMode)
+	}
+
+	header := toolHeader(sty, opts.Status, "Notebook Edit", width, opts.Compact, toolParams...)
+	if opts.Compact {
+		return header
+	}
+
+	if earlyState, ok := toolEarlyStateContent(sty, opts, width); ok {
+		return joinToolParts(header, earlyState)
+	}
+
+	if !opts.HasResult() {
+		return header
+	}
+
+	var meta tools.NotebookEditResponseMetadata
+	if err := json.Unmarshal([]byte(opts.Result.Metadata), &meta); err != nil {
+		bodyWidth := width - toolBodyLeftPaddingTotal
+		body := sty.Tool.Body.Render(toolOutputPlainContent(sty, opts.Result.Content, bodyWidth, opts.ExpandedContent))
+		return joinToolParts(header, body)
+	}
+
+	body := toolOutputDiffContent(sty, file, meta.OldSource, meta.NewSource, width, opts.ExpandedContent)
+	return joinToolParts(header, body)
+}
@@ -0,0 +1,63 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/charmbracelet/crush/internal/agent/tools"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/ui/styles"
+)
+
+// PlanToolMessageItem is a message item that represents a plan tool call.
+type PlanToolMessageItem struct {
+	*baseToolMessageItem
+}
+
+var _ ToolMessageItem = (*PlanToolMessageItem)(nil)
+
+// NewPlanToolMessageItem creates a new [PlanToolMessageItem].
+func NewPlanToolMessageItem(
+	sty *styles.Styles,
+	toolCall message.ToolCall,
+	result *message.ToolResult,
+	canceled bool,
+) ToolMessageItem {
+	return newBaseToolMessageItem(sty, toolCall, result, &PlanToolRenderContext{}, canceled)
+}
+
+// PlanToolRenderContext renders plan tool messages.
+type PlanToolRenderContext struct{}
+
+// RenderTool implements the [ToolRenderer] interface.
+func (r *PlanToolRenderContext) RenderTool(sty *styles.Styles, width int, opts *ToolRenderOpts) string {
+	cappedWidth := cappedMessageWidth(width)
+	if opts.IsPending() {
+		return pendingTool(sty, "Plan", opts.Anim)
+	}
+
+	var params tools.PlanParams
+	_ = json.Unmarshal([]byte(opts.ToolCall.Input), &params)
+
+	var toolParams []string
+	if len(params.Todos) > 0 {
+		toolParams = append(toolParams, fmt.Sprintf("%d steps", len(params.Todos)))
+	}
+
+	header := toolHeader(sty, opts.Status, "Plan", cappedWidth, opts.Compact, toolParams...)
+	if opts.Compact {
+		return header
+	}
+
+	if earlyState, ok := toolEarlyStateContent(sty, opts, cappedWidth); ok {
+		return joinToolParts(header, earlyState)
+	}
+
+	if params.Plan == "" {
+		return header
+	}
+
+	bodyWidth := cappedWidth - toolBodyLeftPaddingTotal
+	body := sty.Tool.Body.Render(toolOutputMarkdownContent(sty, params.Plan, bodyWidth, opts.ExpandedContent))
+	return joinToolParts(header, body)
+}
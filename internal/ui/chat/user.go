@@ -106,5 +106,15 @@ func (m *UserMessageItem) HandleKeyEvent(key tea.KeyMsg) (bool, tea.Cmd) {
 		text := m.message.Content().Text
 		return true, common.CopyToClipboard(text, "Message copied to clipboard")
 	}
+	if key.String() == "r" {
+		return true, func() tea.Msg {
+			return RewindMsg{Message: m.message}
+		}
+	}
+	if key.String() == "f" {
+		return true, func() tea.Msg {
+			return ForkMsg{Message: m.message}
+		}
+	}
 	return false, nil
 }
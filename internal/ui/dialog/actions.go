@@ -8,6 +8,7 @@ import (
 
 	tea "charm.land/bubbletea/v2"
 	"charm.land/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/checkpoint"
 	"github.com/charmbracelet/crush/internal/commands"
 	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/message"
@@ -16,6 +17,7 @@ import (
 	"github.com/charmbracelet/crush/internal/session"
 	"github.com/charmbracelet/crush/internal/ui/common"
 	"github.com/charmbracelet/crush/internal/ui/util"
+	"github.com/charmbracelet/crush/internal/worktree"
 )
 
 // ActionClose is a message to close the current dialog.
@@ -45,18 +47,53 @@ type ActionSelectModel struct {
 // Messages for commands
 type (
 	ActionNewSession          struct{}
+	ActionNewWorktreeSession  struct{}
 	ActionToggleHelp          struct{}
 	ActionToggleCompactMode   struct{}
 	ActionToggleThinking      struct{}
 	ActionTogglePills         struct{}
 	ActionExternalEditor      struct{}
 	ActionToggleYoloMode      struct{}
+	ActionTogglePlanMode      struct{}
 	ActionToggleNotifications struct{}
 	// ActionInitializeProject is a message to initialize a project.
 	ActionInitializeProject struct{}
 	ActionSummarize         struct {
 		SessionID string
 	}
+	// ActionRewind is a message to rewind a session to one of its user
+	// messages.
+	ActionRewind struct {
+		SessionID string
+		MessageID string
+	}
+	// ActionForkSession is a message to fork a whole session.
+	ActionForkSession struct {
+		SessionID string
+	}
+	// ActionExtendBudget is a message to continue a run that was stopped by
+	// its budget.
+	ActionExtendBudget struct {
+		SessionID string
+	}
+	// ActionRestoreCheckpoint is a message to bring the working tree of a
+	// session back to one of its checkpoints.
+	ActionRestoreCheckpoint struct {
+		SessionID  string
+		Dir        string
+		Checkpoint checkpoint.Checkpoint
+	}
+	// ActionFinishWorktree is a message to merge, discard or keep the
+	// worktree of a session.
+	ActionFinishWorktree struct {
+		Worktree worktree.Worktree
+		Decision WorktreeDecision
+		Quit     bool
+	}
+	// ActionSelectAgent is a message indicating an agent has been selected.
+	ActionSelectAgent struct {
+		Agent config.Agent
+	}
 	// ActionSelectReasoningEffort is a message indicating a reasoning effort has been selected.
 	ActionSelectReasoningEffort struct {
 		Effort string
@@ -112,6 +149,19 @@ type (
 	}
 )
 
+// Messages for MCP authorization dialog.
+type (
+	// ActionAuthorizeMCP is a message to authorize with an MCP server.
+	ActionAuthorizeMCP struct {
+		Name string
+	}
+
+	// ActionMCPAuthorized is sent once an MCP server has been authorized.
+	ActionMCPAuthorized struct {
+		Name string
+	}
+)
+
 // ActionCmd represents an action that carries a [tea.Cmd] to be passed to the
 // Bubble Tea program loop.
 type ActionCmd struct {
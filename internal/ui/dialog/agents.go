@@ -0,0 +1,273 @@
+package dialog
+
+import (
+	"charm.land/bubbles/v2/help"
+	"charm.land/bubbles/v2/key"
+	"charm.land/bubbles/v2/textinput"
+	tea "charm.land/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/ui/common"
+	"github.com/charmbracelet/crush/internal/ui/list"
+	"github.com/charmbracelet/crush/internal/ui/styles"
+	uv "github.com/charmbracelet/ultraviolet"
+	"github.com/sahilm/fuzzy"
+)
+
+const (
+	// AgentsID is the identifier for the agent selection dialog.
+	AgentsID              = "agents"
+	agentsDialogMaxWidth  = 60
+	agentsDialogMaxHeight = 12
+)
+
+// Agents represents a dialog for selecting the agent of a session.
+type Agents struct {
+	com   *common.Common
+	help  help.Model
+	list  *list.FilterableList
+	input textinput.Model
+
+	keyMap struct {
+		Select   key.Binding
+		Next     key.Binding
+		Previous key.Binding
+		UpDown   key.Binding
+		Close    key.Binding
+	}
+}
+
+// AgentItem represents an agent list item.
+type AgentItem struct {
+	agent     config.Agent
+	isCurrent bool
+	t         *styles.Styles
+	m         fuzzy.Match
+	cache     map[int]string
+	focused   bool
+}
+
+var (
+	_ Dialog   = (*Agents)(nil)
+	_ ListItem = (*AgentItem)(nil)
+)
+
+// NewAgents creates a new agent selection dialog. currentAgentID is the ID
+// of the agent currently in use.
+func NewAgents(com *common.Common, currentAgentID string) *Agents {
+	a := &Agents{com: com}
+
+	help := help.New()
+	help.Styles = com.Styles.DialogHelpStyles()
+	a.help = help
+
+	a.list = list.NewFilterableList()
+	a.list.Focus()
+
+	a.input = textinput.New()
+	a.input.SetVirtualCursor(false)
+	a.input.Placeholder = "Type to filter"
+	a.input.SetStyles(com.Styles.TextInput)
+	a.input.Focus()
+
+	a.keyMap.Select = key.NewBinding(
+		key.WithKeys("enter", "ctrl+y"),
+		key.WithHelp("enter", "confirm"),
+	)
+	a.keyMap.Next = key.NewBinding(
+		key.WithKeys("down", "ctrl+n"),
+		key.WithHelp("↓", "next item"),
+	)
+	a.keyMap.Previous = key.NewBinding(
+		key.WithKeys("up", "ctrl+p"),
+		key.WithHelp("↑", "previous item"),
+	)
+	a.keyMap.UpDown = key.NewBinding(
+		key.WithKeys("up", "down"),
+		key.WithHelp("↑/↓", "choose"),
+	)
+	a.keyMap.Close = CloseKey
+
+	a.setAgentItems(currentAgentID)
+
+	return a
+}
+
+// ID implements Dialog.
+func (a *Agents) ID() string {
+	return AgentsID
+}
+
+// HandleMsg implements [Dialog].
+func (a *Agents) HandleMsg(msg tea.Msg) Action {
+	switch msg := msg.(type) {
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, a.keyMap.Close):
+			return ActionClose{}
+		case key.Matches(msg, a.keyMap.Previous):
+			a.list.Focus()
+			if a.list.IsSelectedFirst() {
+				a.list.SelectLast()
+				a.list.ScrollToBottom()
+				break
+			}
+			a.list.SelectPrev()
+			a.list.ScrollToSelected()
+		case key.Matches(msg, a.keyMap.Next):
+			a.list.Focus()
+			if a.list.IsSelectedLast() {
+				a.list.SelectFirst()
+				a.list.ScrollToTop()
+				break
+			}
+			a.list.SelectNext()
+			a.list.ScrollToSelected()
+		case key.Matches(msg, a.keyMap.Select):
+			selectedItem := a.list.SelectedItem()
+			if selectedItem == nil {
+				break
+			}
+			agentItem, ok := selectedItem.(*AgentItem)
+			if !ok {
+				break
+			}
+			return ActionSelectAgent{Agent: agentItem.agent}
+		default:
+			var cmd tea.Cmd
+			a.input, cmd = a.input.Update(msg)
+			value := a.input.Value()
+			a.list.SetFilter(value)
+			a.list.ScrollToTop()
+			a.list.SetSelected(0)
+			return ActionCmd{cmd}
+		}
+	}
+	return nil
+}
+
+// Cursor returns the cursor position relative to the dialog.
+func (a *Agents) Cursor() *tea.Cursor {
+	return InputCursor(a.com.Styles, a.input.Cursor())
+}
+
+// Draw implements [Dialog].
+func (a *Agents) Draw(scr uv.Screen, area uv.Rectangle) *tea.Cursor {
+	t := a.com.Styles
+	width := max(0, min(agentsDialogMaxWidth, area.Dx()))
+	height := max(0, min(agentsDialogMaxHeight, area.Dy()))
+	innerWidth := width - t.Dialog.View.GetHorizontalFrameSize()
+	heightOffset := t.Dialog.Title.GetVerticalFrameSize() + titleContentHeight +
+		t.Dialog.InputPrompt.GetVerticalFrameSize() + inputContentHeight +
+		t.Dialog.HelpView.GetVerticalFrameSize() +
+		t.Dialog.View.GetVerticalFrameSize()
+
+	a.input.SetWidth(innerWidth - t.Dialog.InputPrompt.GetHorizontalFrameSize() - 1)
+	a.list.SetSize(innerWidth, height-heightOffset)
+	a.help.SetWidth(innerWidth)
+
+	rc := NewRenderContext(t, width)
+	rc.Title = "Switch Agent"
+	inputView := t.Dialog.InputPrompt.Render(a.input.View())
+	rc.AddPart(inputView)
+
+	visibleCount := len(a.list.FilteredItems())
+	if a.list.Height() >= visibleCount {
+		a.list.ScrollToTop()
+	} else {
+		a.list.ScrollToSelected()
+	}
+
+	listView := t.Dialog.List.Height(a.list.Height()).Render(a.list.Render())
+	rc.AddPart(listView)
+	rc.Help = a.help.View(a)
+
+	view := rc.Render()
+
+	cur := a.Cursor()
+	DrawCenterCursor(scr, area, view, cur)
+	return cur
+}
+
+// ShortHelp implements [help.KeyMap].
+func (a *Agents) ShortHelp() []key.Binding {
+	return []key.Binding{
+		a.keyMap.UpDown,
+		a.keyMap.Select,
+		a.keyMap.Close,
+	}
+}
+
+// FullHelp implements [help.KeyMap].
+func (a *Agents) FullHelp() [][]key.Binding {
+	m := [][]key.Binding{}
+	slice := []key.Binding{
+		a.keyMap.Select,
+		a.keyMap.Next,
+		a.keyMap.Previous,
+		a.keyMap.Close,
+	}
+	for i := 0; i < len(slice); i += 4 {
+		end := min(i+4, len(slice))
+		m = append(m, slice[i:end])
+	}
+	return m
+}
+
+func (a *Agents) setAgentItems(currentAgentID string) {
+	agents := a.com.Config().PrimaryAgents()
+	items := make([]list.FilterableItem, 0, len(agents))
+	selectedIndex := 0
+	for i, agent := range agents {
+		items = append(items, &AgentItem{
+			agent:     agent,
+			isCurrent: agent.ID == currentAgentID,
+			t:         a.com.Styles,
+		})
+		if agent.ID == currentAgentID {
+			selectedIndex = i
+		}
+	}
+
+	a.list.SetItems(items...)
+	a.list.SetSelected(selectedIndex)
+	a.list.ScrollToSelected()
+}
+
+// Filter returns the filter value for the agent item.
+func (i *AgentItem) Filter() string {
+	return i.agent.Name
+}
+
+// ID returns the unique identifier for the agent.
+func (i *AgentItem) ID() string {
+	return i.agent.ID
+}
+
+// SetFocused sets the focus state of the agent item.
+func (i *AgentItem) SetFocused(focused bool) {
+	if i.focused != focused {
+		i.cache = nil
+	}
+	i.focused = focused
+}
+
+// SetMatch sets the fuzzy match for the agent item.
+func (i *AgentItem) SetMatch(m fuzzy.Match) {
+	i.cache = nil
+	i.m = m
+}
+
+// Render returns the string representation of the agent item.
+func (i *AgentItem) Render(width int) string {
+	info := ""
+	if i.isCurrent {
+		info = "current"
+	}
+	styles := ListItemStyles{
+		ItemBlurred:     i.t.Dialog.NormalItem,
+		ItemFocused:     i.t.Dialog.SelectedItem,
+		InfoTextBlurred: i.t.Base,
+		InfoTextFocused: i.t.Base,
+	}
+	return renderItem(styles, i.agent.Name, info, i.focused, width, i.cache, &i.m)
+}
@@ -218,6 +218,9 @@ func (a *Arguments) HandleMsg(msg tea.Msg) Action {
 				case ActionRunMCPPrompt:
 					action.Args = args
 					return action
+				case submitElicitation:
+					action.Args = args
+					return action
 				}
 			}
 			a.focusInput(a.focused + 1)
@@ -247,6 +250,9 @@ func (a *Arguments) HandleMsg(msg tea.Msg) Action {
 // Cursor returns the cursor position relative to the dialog.
 // we pass the description height to offset the cursor correctly.
 func (a *Arguments) Cursor(descriptionHeight int) *tea.Cursor {
+	if len(a.inputs) == 0 {
+		return nil
+	}
 	cursor := InputCursor(a.com.Styles, a.inputs[a.focused].Cursor())
 	if cursor == nil {
 		return nil
@@ -308,6 +314,9 @@ func (a *Arguments) Draw(scr uv.Screen, area uv.Rectangle) *tea.Cursor {
 	const scrollbarWidth = 1
 	width := lipgloss.Width(renderedFields)
 	height := lipgloss.Height(renderedFields)
+	if len(a.inputs) == 0 {
+		width = minInputWidth
+	}
 
 	// Use standard header
 	titleStyle := s.Dialog.Title
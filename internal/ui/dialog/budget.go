@@ -0,0 +1,142 @@
+package dialog
+
+import (
+	"fmt"
+
+	"charm.land/bubbles/v2/key"
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/crush/internal/ui/common"
+	uv "github.com/charmbracelet/ultraviolet"
+)
+
+// BudgetID is the identifier for the budget dialog.
+const BudgetID = "budget"
+
+// Budget represents a confirmation dialog for extending the budget of a run
+// that hit it.
+type Budget struct {
+	com         *common.Common
+	sessionID   string
+	description string
+	selectedNo  bool // true if "No" button is selected
+	keyMap      struct {
+		LeftRight,
+		EnterSpace,
+		Yes,
+		No,
+		Tab,
+		Close key.Binding
+	}
+}
+
+var _ Dialog = (*Budget)(nil)
+
+// NewBudget creates a new budget confirmation dialog. description describes
+// the budget that was hit, like "50 steps".
+func NewBudget(com *common.Common, sessionID, description string) *Budget {
+	r := &Budget{
+		com:         com,
+		sessionID:   sessionID,
+		description: description,
+		selectedNo:  true,
+	}
+	r.keyMap.LeftRight = key.NewBinding(
+		key.WithKeys("left", "right"),
+		key.WithHelp("←/→", "switch options"),
+	)
+	r.keyMap.EnterSpace = key.NewBinding(
+		key.WithKeys("enter", " "),
+		key.WithHelp("enter/space", "confirm"),
+	)
+	r.keyMap.Yes = key.NewBinding(
+		key.WithKeys("y", "Y"),
+		key.WithHelp("y/Y", "yes"),
+	)
+	r.keyMap.No = key.NewBinding(
+		key.WithKeys("n", "N"),
+		key.WithHelp("n/N", "no"),
+	)
+	r.keyMap.Tab = key.NewBinding(
+		key.WithKeys("tab"),
+		key.WithHelp("tab", "switch options"),
+	)
+	r.keyMap.Close = CloseKey
+	return r
+}
+
+// ID implements [Model].
+func (*Budget) ID() string {
+	return BudgetID
+}
+
+// HandleMsg implements [Model].
+func (r *Budget) HandleMsg(msg tea.Msg) Action {
+	switch msg := msg.(type) {
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, r.keyMap.Close):
+			return ActionClose{}
+		case key.Matches(msg, r.keyMap.LeftRight, r.keyMap.Tab):
+			r.selectedNo = !r.selectedNo
+		case key.Matches(msg, r.keyMap.EnterSpace):
+			if !r.selectedNo {
+				return r.action()
+			}
+			return ActionClose{}
+		case key.Matches(msg, r.keyMap.Yes):
+			return r.action()
+		case key.Matches(msg, r.keyMap.No):
+			return ActionClose{}
+		}
+	}
+
+	return nil
+}
+
+func (r *Budget) action() Action {
+	return ActionExtendBudget{
+		SessionID: r.sessionID,
+	}
+}
+
+// Draw implements [Dialog].
+func (r *Budget) Draw(scr uv.Screen, area uv.Rectangle) *tea.Cursor {
+	const question = "Keep going?"
+	details := fmt.Sprintf("The agent stopped after reaching its budget of %s.", r.description)
+	baseStyle := r.com.Styles.Base
+	buttonOpts := []common.ButtonOpts{
+		{Text: "Yep!", Selected: !r.selectedNo, Padding: 3},
+		{Text: "Nope", Selected: r.selectedNo, Padding: 3},
+	}
+	buttons := common.ButtonGroup(r.com.Styles, buttonOpts, " ")
+	content := baseStyle.Render(
+		lipgloss.JoinVertical(
+			lipgloss.Center,
+			question,
+			r.com.Styles.Subtle.Render(details),
+			"",
+			buttons,
+		),
+	)
+
+	view := r.com.Styles.BorderFocus.Render(content)
+	DrawCenter(scr, area, view)
+	return nil
+}
+
+// ShortHelp implements [help.KeyMap].
+func (r *Budget) ShortHelp() []key.Binding {
+	return []key.Binding{
+		r.keyMap.LeftRight,
+		r.keyMap.EnterSpace,
+	}
+}
+
+// FullHelp implements [help.KeyMap].
+func (r *Budget) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{r.keyMap.LeftRight, r.keyMap.EnterSpace, r.keyMap.Yes, r.keyMap.No},
+		{r.keyMap.Tab, r.keyMap.Close},
+	}
+}
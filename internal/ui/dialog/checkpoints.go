@@ -0,0 +1,411 @@
+package dialog
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"charm.land/bubbles/v2/help"
+	"charm.land/bubbles/v2/key"
+	"charm.land/bubbles/v2/textinput"
+	"charm.land/bubbles/v2/viewport"
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/crush/internal/checkpoint"
+	"github.com/charmbracelet/crush/internal/ui/common"
+	"github.com/charmbracelet/crush/internal/ui/list"
+	"github.com/charmbracelet/crush/internal/ui/styles"
+	"github.com/charmbracelet/crush/internal/ui/util"
+	uv "github.com/charmbracelet/ultraviolet"
+	"github.com/dustin/go-humanize"
+	"github.com/sahilm/fuzzy"
+)
+
+const (
+	// CheckpointsID is the identifier for the checkpoints dialog.
+	CheckpointsID              = "checkpoints"
+	checkpointsDialogMaxWidth  = 80
+	checkpointsDialogMaxHeight = 16
+)
+
+// checkpointChangesMsg carries the changes made since a checkpoint, listed
+// in the background since it can take a while in large trees.
+type checkpointChangesMsg struct {
+	checkpoint checkpoint.Checkpoint
+	changes    []checkpoint.Change
+	err        error
+}
+
+// Checkpoints represents a dialog listing the checkpoints of a session,
+// taken at the start of each turn, to review the changes made since one of
+// them and restore it.
+type Checkpoints struct {
+	com         *common.Common
+	help        help.Model
+	list        *list.FilterableList
+	input       textinput.Model
+	viewport    viewport.Model
+	service     *checkpoint.Service
+	sessionID   string
+	dir         string
+	checkpoints []checkpoint.Checkpoint
+
+	// selected is the checkpoint whose changes are shown, if any.
+	selected *checkpoint.Checkpoint
+	changes  []checkpoint.Change
+	// diffWidth is the width the changes were rendered at.
+	diffWidth int
+
+	keyMap struct {
+		Diff     key.Binding
+		Restore  key.Binding
+		Next     key.Binding
+		Previous key.Binding
+		UpDown   key.Binding
+		Scroll   key.Binding
+		Back     key.Binding
+		Close    key.Binding
+	}
+}
+
+// CheckpointItem represents a checkpoint list item.
+type CheckpointItem struct {
+	checkpoint checkpoint.Checkpoint
+	t          *styles.Styles
+	m          fuzzy.Match
+	cache      map[int]string
+	focused    bool
+}
+
+var (
+	_ Dialog   = (*Checkpoints)(nil)
+	_ ListItem = (*CheckpointItem)(nil)
+)
+
+// NewCheckpoints creates a new dialog with the checkpoints of a session
+// working in dir.
+func NewCheckpoints(com *common.Common, sessionID, dir string) (*Checkpoints, error) {
+	service := checkpoint.NewService(com.Config().Options.DataDirectory)
+	checkpoints, err := service.List(context.TODO(), dir, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Checkpoints{
+		com:         com,
+		service:     service,
+		sessionID:   sessionID,
+		dir:         dir,
+		checkpoints: checkpoints,
+	}
+
+	help := help.New()
+	help.Styles = com.Styles.DialogHelpStyles()
+	c.help = help
+
+	c.list = list.NewFilterableList()
+	c.list.Focus()
+
+	c.input = textinput.New()
+	c.input.SetVirtualCursor(false)
+	c.input.Placeholder = "Type to filter"
+	c.input.SetStyles(com.Styles.TextInput)
+	c.input.Focus()
+
+	c.viewport = viewport.New()
+
+	c.keyMap.Diff = key.NewBinding(
+		key.WithKeys("enter"),
+		key.WithHelp("enter", "changes since"),
+	)
+	c.keyMap.Restore = key.NewBinding(
+		key.WithKeys("ctrl+r"),
+		key.WithHelp("ctrl+r", "restore"),
+	)
+	c.keyMap.Next = key.NewBinding(
+		key.WithKeys("down", "ctrl+n"),
+		key.WithHelp("↓", "next item"),
+	)
+	c.keyMap.Previous = key.NewBinding(
+		key.WithKeys("up", "ctrl+p"),
+		key.WithHelp("↑", "previous item"),
+	)
+	c.keyMap.UpDown = key.NewBinding(
+		key.WithKeys("up", "down"),
+		key.WithHelp("↑/↓", "choose"),
+	)
+	c.keyMap.Scroll = key.NewBinding(
+		key.WithKeys("up", "down", "pgup", "pgdown"),
+		key.WithHelp("↑/↓", "scroll"),
+	)
+	c.keyMap.Back = key.NewBinding(
+		key.WithKeys("esc", "alt+esc"),
+		key.WithHelp("esc", "back"),
+	)
+	c.keyMap.Close = CloseKey
+
+	items := make([]list.FilterableItem, 0, len(checkpoints))
+	for _, cp := range checkpoints {
+		items = append(items, &CheckpointItem{checkpoint: cp, t: com.Styles})
+	}
+	c.list.SetItems(items...)
+	c.list.SetSelected(0)
+	c.list.ScrollToTop()
+
+	return c, nil
+}
+
+// ID implements Dialog.
+func (c *Checkpoints) ID() string {
+	return CheckpointsID
+}
+
+// HandleMsg implements [Dialog].
+func (c *Checkpoints) HandleMsg(msg tea.Msg) Action {
+	if msg, ok := msg.(checkpointChangesMsg); ok {
+		if msg.err != nil {
+			return ActionCmd{util.ReportError(msg.err)}
+		}
+		c.selected = &msg.checkpoint
+		c.changes = msg.changes
+		c.diffWidth = 0
+		return nil
+	}
+	if c.selected != nil {
+		return c.handleDiffMsg(msg)
+	}
+
+	switch msg := msg.(type) {
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, c.keyMap.Close):
+			return ActionClose{}
+		case key.Matches(msg, c.keyMap.Previous):
+			c.list.Focus()
+			if c.list.IsSelectedFirst() {
+				c.list.SelectLast()
+				c.list.ScrollToBottom()
+				break
+			}
+			c.list.SelectPrev()
+			c.list.ScrollToSelected()
+		case key.Matches(msg, c.keyMap.Next):
+			c.list.Focus()
+			if c.list.IsSelectedLast() {
+				c.list.SelectFirst()
+				c.list.ScrollToTop()
+				break
+			}
+			c.list.SelectNext()
+			c.list.ScrollToSelected()
+		case key.Matches(msg, c.keyMap.Diff):
+			item, ok := c.list.SelectedItem().(*CheckpointItem)
+			if !ok {
+				break
+			}
+			return ActionCmd{c.loadChanges(item.checkpoint)}
+		case key.Matches(msg, c.keyMap.Restore):
+			item, ok := c.list.SelectedItem().(*CheckpointItem)
+			if !ok {
+				break
+			}
+			return c.restore(item.checkpoint)
+		default:
+			var cmd tea.Cmd
+			c.input, cmd = c.input.Update(msg)
+			c.list.SetFilter(c.input.Value())
+			c.list.ScrollToTop()
+			c.list.SetSelected(0)
+			return ActionCmd{cmd}
+		}
+	}
+	return nil
+}
+
+func (c *Checkpoints) handleDiffMsg(msg tea.Msg) Action {
+	switch msg := msg.(type) {
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, c.keyMap.Back):
+			c.selected = nil
+			c.changes = nil
+		case key.Matches(msg, c.keyMap.Restore):
+			return c.restore(*c.selected)
+		default:
+			c.viewport, _ = c.viewport.Update(msg)
+		}
+	case tea.MouseWheelMsg:
+		c.viewport, _ = c.viewport.Update(msg)
+	}
+	return nil
+}
+
+// loadChanges lists the changes made since the checkpoint in the background.
+func (c *Checkpoints) loadChanges(cp checkpoint.Checkpoint) tea.Cmd {
+	service, dir, sessionID := c.service, c.dir, c.sessionID
+	return func() tea.Msg {
+		changes, err := service.Changes(context.Background(), dir, sessionID, cp.ID)
+		return checkpointChangesMsg{checkpoint: cp, changes: changes, err: err}
+	}
+}
+
+func (c *Checkpoints) restore(cp checkpoint.Checkpoint) Action {
+	return ActionRestoreCheckpoint{
+		SessionID:  c.sessionID,
+		Dir:        c.dir,
+		Checkpoint: cp,
+	}
+}
+
+// Cursor returns the cursor position relative to the dialog.
+func (c *Checkpoints) Cursor() *tea.Cursor {
+	if c.selected != nil {
+		return nil
+	}
+	return InputCursor(c.com.Styles, c.input.Cursor())
+}
+
+// Draw implements [Dialog].
+func (c *Checkpoints) Draw(scr uv.Screen, area uv.Rectangle) *tea.Cursor {
+	if c.selected != nil {
+		return c.drawChanges(scr, area)
+	}
+
+	t := c.com.Styles
+	width := max(0, min(checkpointsDialogMaxWidth, area.Dx()))
+	height := max(0, min(checkpointsDialogMaxHeight, area.Dy()))
+	innerWidth := width - t.Dialog.View.GetHorizontalFrameSize()
+	heightOffset := t.Dialog.Title.GetVerticalFrameSize() + titleContentHeight +
+		t.Dialog.InputPrompt.GetVerticalFrameSize() + inputContentHeight +
+		t.Dialog.HelpView.GetVerticalFrameSize() +
+		t.Dialog.View.GetVerticalFrameSize()
+
+	c.input.SetWidth(innerWidth - t.Dialog.InputPrompt.GetHorizontalFrameSize() - 1)
+	c.list.SetSize(innerWidth, height-heightOffset)
+	c.help.SetWidth(innerWidth)
+
+	rc := NewRenderContext(t, width)
+	rc.Title = "Checkpoints"
+	rc.AddPart(t.Dialog.InputPrompt.Render(c.input.View()))
+
+	if c.list.Height() >= len(c.list.FilteredItems()) {
+		c.list.ScrollToTop()
+	} else {
+		c.list.ScrollToSelected()
+	}
+
+	if len(c.checkpoints) == 0 {
+		rc.AddPart(t.Subtle.Render("No checkpoints yet. One is taken at the start of each turn."))
+	} else {
+		rc.AddPart(t.Dialog.List.Height(c.list.Height()).Render(c.list.Render()))
+	}
+	rc.Help = c.help.View(c)
+
+	view := rc.Render()
+	cur := c.Cursor()
+	DrawCenterCursor(scr, area, view, cur)
+	return cur
+}
+
+func (c *Checkpoints) drawChanges(scr uv.Screen, area uv.Rectangle) *tea.Cursor {
+	t := c.com.Styles
+	width := max(0, min(int(float64(area.Dx())*diffSizeRatio), diffMaxWidth))
+	height := max(0, int(float64(area.Dy())*diffSizeRatio))
+	innerWidth := width - t.Dialog.View.GetHorizontalFrameSize()
+	heightOffset := t.Dialog.Title.GetVerticalFrameSize() + titleContentHeight + 2 +
+		t.Dialog.HelpView.GetVerticalFrameSize() +
+		t.Dialog.View.GetVerticalFrameSize()
+
+	// Leave room for the scrollbar.
+	contentWidth := innerWidth - 1
+	if c.diffWidth != contentWidth {
+		c.viewport.SetContent(c.renderChanges(contentWidth))
+		c.diffWidth = contentWidth
+	}
+	c.viewport.SetWidth(contentWidth)
+	c.viewport.SetHeight(max(1, height-heightOffset))
+	c.help.SetWidth(innerWidth)
+
+	rc := NewRenderContext(t, width)
+	rc.Title = "Changes Since Checkpoint"
+	rc.AddPart(t.Subtle.Render(fmt.Sprintf("%s, %s", c.selected.Title, humanize.Time(time.Unix(c.selected.CreatedAt, 0)))))
+	scrollbar := common.Scrollbar(t, c.viewport.Height(), c.viewport.TotalLineCount(), c.viewport.Height(), c.viewport.YOffset())
+	rc.AddPart(lipgloss.JoinHorizontal(lipgloss.Top, c.viewport.View(), scrollbar))
+	rc.Help = c.help.View(c)
+
+	DrawCenterCursor(scr, area, rc.Render(), nil)
+	return nil
+}
+
+func (c *Checkpoints) renderChanges(width int) string {
+	t := c.com.Styles
+	if len(c.changes) == 0 {
+		return t.Subtle.Render("Nothing changed since this checkpoint.")
+	}
+
+	parts := make([]string, 0, len(c.changes))
+	for _, change := range c.changes {
+		header := t.Base.Bold(true).Render(change.Path) + " " + t.Subtle.Render(string(change.Kind))
+		body := t.Subtle.Render("Binary file")
+		if !change.Binary {
+			body = common.DiffFormatter(t).
+				Before(change.Path, change.Before).
+				After(change.Path, change.After).
+				Width(width).
+				Unified().
+				String()
+		}
+		parts = append(parts, header+"\n"+body)
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// ShortHelp implements [help.KeyMap].
+func (c *Checkpoints) ShortHelp() []key.Binding {
+	if c.selected != nil {
+		return []key.Binding{c.keyMap.Scroll, c.keyMap.Restore, c.keyMap.Back}
+	}
+	return []key.Binding{c.keyMap.UpDown, c.keyMap.Diff, c.keyMap.Restore, c.keyMap.Close}
+}
+
+// FullHelp implements [help.KeyMap].
+func (c *Checkpoints) FullHelp() [][]key.Binding {
+	return [][]key.Binding{c.ShortHelp()}
+}
+
+// Filter returns the filter value for the checkpoint item.
+func (i *CheckpointItem) Filter() string {
+	return i.checkpoint.Title
+}
+
+// ID returns the unique identifier for the checkpoint.
+func (i *CheckpointItem) ID() string {
+	return i.checkpoint.ID
+}
+
+// SetFocused sets the focus state of the checkpoint item.
+func (i *CheckpointItem) SetFocused(focused bool) {
+	if i.focused != focused {
+		i.cache = nil
+	}
+	i.focused = focused
+}
+
+// SetMatch sets the fuzzy match for the checkpoint item.
+func (i *CheckpointItem) SetMatch(m fuzzy.Match) {
+	i.cache = nil
+	i.m = m
+}
+
+// Render returns the string representation of the checkpoint item.
+func (i *CheckpointItem) Render(width int) string {
+	styles := ListItemStyles{
+		ItemBlurred:     i.t.Dialog.NormalItem,
+		ItemFocused:     i.t.Dialog.SelectedItem,
+		InfoTextBlurred: i.t.Subtle,
+		InfoTextFocused: i.t.Base,
+	}
+	info := humanize.Time(time.Unix(i.checkpoint.CreatedAt, 0))
+	return renderItem(styles, i.checkpoint.Title, info, i.focused, width, i.cache, &i.m)
+}
@@ -9,6 +9,7 @@ import (
 	"charm.land/bubbles/v2/spinner"
 	"charm.land/bubbles/v2/textinput"
 	tea "charm.land/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/agent/tools/mcp"
 	"github.com/charmbracelet/crush/internal/commands"
 	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/ui/common"
@@ -49,11 +50,12 @@ type Commands struct {
 		Close key.Binding
 	}
 
-	sessionID  string
-	hasSession bool
-	hasTodos   bool
-	hasQueue   bool
-	selected   CommandType
+	sessionID   string
+	hasSession  bool
+	hasTodos    bool
+	hasQueue    bool
+	hasWorktree bool
+	selected    CommandType
 
 	spinner spinner.Model
 	loading bool
@@ -71,7 +73,7 @@ type Commands struct {
 var _ Dialog = (*Commands)(nil)
 
 // NewCommands creates a new commands dialog.
-func NewCommands(com *common.Common, sessionID string, hasSession, hasTodos, hasQueue bool, customCommands []commands.CustomCommand, mcpPrompts []commands.MCPPrompt) (*Commands, error) {
+func NewCommands(com *common.Common, sessionID string, hasSession, hasTodos, hasQueue, hasWorktree bool, customCommands []commands.CustomCommand, mcpPrompts []commands.MCPPrompt) (*Commands, error) {
 	c := &Commands{
 		com:            com,
 		selected:       SystemCommands,
@@ -79,6 +81,7 @@ func NewCommands(com *common.Common, sessionID string, hasSession, hasTodos, has
 		hasSession:     hasSession,
 		hasTodos:       hasTodos,
 		hasQueue:       hasQueue,
+		hasWorktree:    hasWorktree,
 		customCommands: customCommands,
 		mcpPrompts:     mcpPrompts,
 	}
@@ -388,13 +391,25 @@ func (c *Commands) setCommandItems(commandType CommandType) {
 func (c *Commands) defaultCommands() []*CommandItem {
 	commands := []*CommandItem{
 		NewCommandItem(c.com.Styles, "new_session", "New Session", "ctrl+n", ActionNewSession{}),
+		NewCommandItem(c.com.Styles, "new_worktree_session", "New Worktree Session", "", ActionNewWorktreeSession{}),
 		NewCommandItem(c.com.Styles, "switch_session", "Sessions", "ctrl+s", ActionOpenDialog{SessionsID}),
 		NewCommandItem(c.com.Styles, "switch_model", "Switch Model", "ctrl+l", ActionOpenDialog{ModelsID}),
 	}
 
+	// Only show the agent switcher if there's something to switch to.
+	if len(c.com.Config().PrimaryAgents()) > 1 {
+		commands = append(commands, NewCommandItem(c.com.Styles, "switch_agent", "Switch Agent", "", ActionOpenDialog{AgentsID}))
+	}
+
 	// Only show compact command if there's an active session
 	if c.hasSession {
 		commands = append(commands, NewCommandItem(c.com.Styles, "summarize", "Summarize Session", "", ActionSummarize{SessionID: c.sessionID}))
+		commands = append(commands, NewCommandItem(c.com.Styles, "fork_session", "Fork Session", "", ActionForkSession{SessionID: c.sessionID}))
+		commands = append(commands, NewCommandItem(c.com.Styles, "checkpoints", "Checkpoints", "", ActionOpenDialog{CheckpointsID}))
+		commands = append(commands, NewCommandItem(c.com.Styles, "session_permissions", "Session Permissions", "", ActionOpenDialog{GrantsID}))
+	}
+	if c.hasWorktree {
+		commands = append(commands, NewCommandItem(c.com.Styles, "finish_worktree", "Merge or Discard Worktree", "", ActionOpenDialog{WorktreeID}))
 	}
 
 	// Add reasoning toggle for models that support it
@@ -468,7 +483,19 @@ func (c *Commands) defaultCommands() []*CommandItem {
 	}
 	commands = append(commands, NewCommandItem(c.com.Styles, "toggle_notifications", notificationLabel, "", ActionToggleNotifications{}))
 
+	commands = append(commands, NewCommandItem(c.com.Styles, "mcp_servers", "MCP Servers", "", ActionOpenDialog{MCPsID}))
+
+	// Add commands for MCP servers waiting to be authorized.
+	if cfg != nil {
+		for _, m := range cfg.MCP.Sorted() {
+			if mcp.NeedsAuthorization(m.Name) {
+				commands = append(commands, NewCommandItem(c.com.Styles, "authorize_mcp_"+m.Name, "Authorize "+m.Name+" MCP", "", ActionAuthorizeMCP{Name: m.Name}))
+			}
+		}
+	}
+
 	commands = append(commands,
+		NewCommandItem(c.com.Styles, "toggle_plan", "Toggle Plan Mode", "", ActionTogglePlanMode{}),
 		NewCommandItem(c.com.Styles, "toggle_yolo", "Toggle Yolo Mode", "", ActionToggleYoloMode{}),
 		NewCommandItem(c.com.Styles, "toggle_help", "Toggle Help", "ctrl+g", ActionToggleHelp{}),
 		NewCommandItem(c.com.Styles, "init", "Initialize Project", "", ActionInitializeProject{}),
@@ -0,0 +1,124 @@
+package dialog
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"charm.land/bubbles/v2/key"
+	tea "charm.land/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/agent/tools/mcp"
+	"github.com/charmbracelet/crush/internal/commands"
+	"github.com/charmbracelet/crush/internal/ui/common"
+	"github.com/charmbracelet/crush/internal/ui/util"
+)
+
+// ElicitationID is the identifier for the MCP elicitation dialog.
+const ElicitationID = "elicitation"
+
+// submitElicitation is the result action of the form of an elicitation.
+type submitElicitation struct {
+	Args map[string]string
+}
+
+// Elicitation is a form an MCP server asks the user to fill.
+type Elicitation struct {
+	*Arguments
+	request mcp.ElicitationRequest
+}
+
+var _ Dialog = (*Elicitation)(nil)
+
+// NewElicitation creates a dialog with the form of an elicitation request.
+func NewElicitation(com *common.Common, request mcp.ElicitationRequest) *Elicitation {
+	args := make([]commands.Argument, len(request.Fields))
+	for i, field := range request.Fields {
+		args[i] = commands.Argument{
+			ID:          field.Name,
+			Title:       field.Name,
+			Description: fieldPlaceholder(field),
+			Required:    field.Required,
+		}
+		if field.Title != "" {
+			args[i].Title = field.Title
+		}
+	}
+
+	e := &Elicitation{
+		Arguments: NewArguments(com, request.Name+" MCP", request.Message, args, submitElicitation{}),
+		request:   request,
+	}
+	for i, field := range request.Fields {
+		e.inputs[i].SetValue(field.Default)
+	}
+	return e
+}
+
+// fieldPlaceholder describes what a field expects.
+func fieldPlaceholder(field mcp.ElicitationField) string {
+	var hint string
+	switch {
+	case field.Type == "boolean":
+		hint = "yes or no"
+	case field.Type == "array" && len(field.Options) > 0:
+		hint = "some of " + strings.Join(field.Options, ", ")
+	case len(field.Options) > 0:
+		hint = "one of " + strings.Join(field.Options, ", ")
+	case field.Type == "integer", field.Type == "number":
+		hint = "a " + field.Type
+	}
+	switch {
+	case field.Description == "":
+		return hint
+	case hint == "":
+		return field.Description
+	default:
+		return fmt.Sprintf("%s (%s)", field.Description, hint)
+	}
+}
+
+// ID implements Dialog.
+func (e *Elicitation) ID() string {
+	return ElicitationID
+}
+
+// HandleMsg implements Dialog.
+func (e *Elicitation) HandleMsg(msg tea.Msg) Action {
+	if len(e.inputs) == 0 {
+		// Forms without fields only ask for a confirmation.
+		if msg, ok := msg.(tea.KeyPressMsg); ok {
+			switch {
+			case key.Matches(msg, e.keyMap.Confirm):
+				return e.accept(nil)
+			case key.Matches(msg, e.keyMap.Close):
+				return e.cancel()
+			}
+		}
+		return nil
+	}
+
+	switch action := e.Arguments.HandleMsg(msg).(type) {
+	case ActionClose:
+		return e.cancel()
+	case submitElicitation:
+		return e.accept(action.Args)
+	default:
+		return action
+	}
+}
+
+func (e *Elicitation) accept(args map[string]string) Action {
+	err := mcp.AcceptElicitation(e.request.ID, args)
+	switch {
+	case errors.Is(err, mcp.ErrElicitationNotPending):
+		return ActionCmd{util.ReportWarn(e.request.Name + " MCP stopped waiting for an answer")}
+	case err != nil:
+		return ActionCmd{util.ReportWarn(err.Error())}
+	}
+	return ActionClose{}
+}
+
+func (e *Elicitation) cancel() Action {
+	_ = mcp.CancelElicitation(e.request.ID)
+	return ActionClose{}
+}
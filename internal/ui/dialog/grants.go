@@ -0,0 +1,290 @@
+package dialog
+
+import (
+	"context"
+
+	"charm.land/bubbles/v2/help"
+	"charm.land/bubbles/v2/key"
+	"charm.land/bubbles/v2/textinput"
+	tea "charm.land/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/home"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/ui/common"
+	"github.com/charmbracelet/crush/internal/ui/list"
+	"github.com/charmbracelet/crush/internal/ui/styles"
+	"github.com/charmbracelet/crush/internal/ui/util"
+	uv "github.com/charmbracelet/ultraviolet"
+	"github.com/sahilm/fuzzy"
+)
+
+const (
+	// GrantsID is the identifier for the session permission grants dialog.
+	GrantsID              = "grants"
+	grantsDialogMaxWidth  = 80
+	grantsDialogMaxHeight = 16
+)
+
+// Grants represents a dialog listing the permissions granted for the rest of
+// a session, allowing them to be revoked.
+type Grants struct {
+	com    *common.Common
+	help   help.Model
+	list   *list.FilterableList
+	input  textinput.Model
+	grants []permission.Grant
+
+	keyMap struct {
+		Revoke   key.Binding
+		Next     key.Binding
+		Previous key.Binding
+		UpDown   key.Binding
+		Close    key.Binding
+	}
+}
+
+// GrantItem represents a permission grant list item.
+type GrantItem struct {
+	grant   permission.Grant
+	t       *styles.Styles
+	m       fuzzy.Match
+	cache   map[int]string
+	focused bool
+}
+
+var (
+	_ Dialog   = (*Grants)(nil)
+	_ ListItem = (*GrantItem)(nil)
+)
+
+// NewGrants creates a new dialog with the permission grants of a session.
+func NewGrants(com *common.Common, sessionID string) (*Grants, error) {
+	grants, err := com.App.Permissions.SessionGrants(context.TODO(), sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &Grants{com: com, grants: grants}
+
+	help := help.New()
+	help.Styles = com.Styles.DialogHelpStyles()
+	g.help = help
+
+	g.list = list.NewFilterableList()
+	g.list.Focus()
+
+	g.input = textinput.New()
+	g.input.SetVirtualCursor(false)
+	g.input.Placeholder = "Type to filter"
+	g.input.SetStyles(com.Styles.TextInput)
+	g.input.Focus()
+
+	g.keyMap.Revoke = key.NewBinding(
+		key.WithKeys("enter", "ctrl+x"),
+		key.WithHelp("enter", "revoke"),
+	)
+	g.keyMap.Next = key.NewBinding(
+		key.WithKeys("down", "ctrl+n"),
+		key.WithHelp("↓", "next item"),
+	)
+	g.keyMap.Previous = key.NewBinding(
+		key.WithKeys("up", "ctrl+p"),
+		key.WithHelp("↑", "previous item"),
+	)
+	g.keyMap.UpDown = key.NewBinding(
+		key.WithKeys("up", "down"),
+		key.WithHelp("↑/↓", "choose"),
+	)
+	g.keyMap.Close = CloseKey
+
+	g.setGrantItems()
+
+	return g, nil
+}
+
+// ID implements Dialog.
+func (g *Grants) ID() string {
+	return GrantsID
+}
+
+// HandleMsg implements [Dialog].
+func (g *Grants) HandleMsg(msg tea.Msg) Action {
+	switch msg := msg.(type) {
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, g.keyMap.Close):
+			return ActionClose{}
+		case key.Matches(msg, g.keyMap.Previous):
+			g.list.Focus()
+			if g.list.IsSelectedFirst() {
+				g.list.SelectLast()
+				g.list.ScrollToBottom()
+				break
+			}
+			g.list.SelectPrev()
+			g.list.ScrollToSelected()
+		case key.Matches(msg, g.keyMap.Next):
+			g.list.Focus()
+			if g.list.IsSelectedLast() {
+				g.list.SelectFirst()
+				g.list.ScrollToTop()
+				break
+			}
+			g.list.SelectNext()
+			g.list.ScrollToSelected()
+		case key.Matches(msg, g.keyMap.Revoke):
+			selectedItem := g.list.SelectedItem()
+			if selectedItem == nil {
+				break
+			}
+			grantItem, ok := selectedItem.(*GrantItem)
+			if !ok {
+				break
+			}
+			g.removeGrant(grantItem.ID())
+			g.setGrantItems()
+			return ActionCmd{g.revokeGrantCmd(grantItem.grant)}
+		default:
+			var cmd tea.Cmd
+			g.input, cmd = g.input.Update(msg)
+			value := g.input.Value()
+			g.list.SetFilter(value)
+			g.list.ScrollToTop()
+			g.list.SetSelected(0)
+			return ActionCmd{cmd}
+		}
+	}
+	return nil
+}
+
+// Cursor returns the cursor position relative to the dialog.
+func (g *Grants) Cursor() *tea.Cursor {
+	return InputCursor(g.com.Styles, g.input.Cursor())
+}
+
+// Draw implements [Dialog].
+func (g *Grants) Draw(scr uv.Screen, area uv.Rectangle) *tea.Cursor {
+	t := g.com.Styles
+	width := max(0, min(grantsDialogMaxWidth, area.Dx()))
+	height := max(0, min(grantsDialogMaxHeight, area.Dy()))
+	innerWidth := width - t.Dialog.View.GetHorizontalFrameSize()
+	heightOffset := t.Dialog.Title.GetVerticalFrameSize() + titleContentHeight +
+		t.Dialog.InputPrompt.GetVerticalFrameSize() + inputContentHeight +
+		t.Dialog.HelpView.GetVerticalFrameSize() +
+		t.Dialog.View.GetVerticalFrameSize()
+
+	g.input.SetWidth(innerWidth - t.Dialog.InputPrompt.GetHorizontalFrameSize() - 1)
+	g.list.SetSize(innerWidth, height-heightOffset)
+	g.help.SetWidth(innerWidth)
+
+	rc := NewRenderContext(t, width)
+	rc.Title = "Session Permissions"
+	inputView := t.Dialog.InputPrompt.Render(g.input.View())
+	rc.AddPart(inputView)
+
+	visibleCount := len(g.list.FilteredItems())
+	if g.list.Height() >= visibleCount {
+		g.list.ScrollToTop()
+	} else {
+		g.list.ScrollToSelected()
+	}
+
+	if len(g.grants) == 0 {
+		rc.AddPart(t.Subtle.Render("No permissions were allowed for this session."))
+	} else {
+		listView := t.Dialog.List.Height(g.list.Height()).Render(g.list.Render())
+		rc.AddPart(listView)
+	}
+	rc.Help = g.help.View(g)
+
+	view := rc.Render()
+
+	cur := g.Cursor()
+	DrawCenterCursor(scr, area, view, cur)
+	return cur
+}
+
+// ShortHelp implements [help.KeyMap].
+func (g *Grants) ShortHelp() []key.Binding {
+	return []key.Binding{
+		g.keyMap.UpDown,
+		g.keyMap.Revoke,
+		g.keyMap.Close,
+	}
+}
+
+// FullHelp implements [help.KeyMap].
+func (g *Grants) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{g.keyMap.Revoke, g.keyMap.Next, g.keyMap.Previous, g.keyMap.Close},
+	}
+}
+
+func (g *Grants) setGrantItems() {
+	items := make([]list.FilterableItem, 0, len(g.grants))
+	for _, grant := range g.grants {
+		items = append(items, &GrantItem{grant: grant, t: g.com.Styles})
+	}
+	g.list.SetItems(items...)
+	g.list.SetFilter(g.input.Value())
+	g.list.SetSelected(0)
+	g.list.ScrollToTop()
+}
+
+func (g *Grants) removeGrant(id string) {
+	var grants []permission.Grant
+	for _, grant := range g.grants {
+		if grant.ID == id {
+			continue
+		}
+		grants = append(grants, grant)
+	}
+	g.grants = grants
+}
+
+func (g *Grants) revokeGrantCmd(grant permission.Grant) tea.Cmd {
+	return func() tea.Msg {
+		if err := g.com.App.Permissions.RevokeGrant(context.TODO(), grant.ID); err != nil {
+			return util.NewErrorMsg(err)
+		}
+		return util.NewInfoMsg("Revoked " + grant.ToolName + ":" + grant.Action + " permission")
+	}
+}
+
+func (i *GrantItem) title() string {
+	return i.grant.ToolName + ":" + i.grant.Action
+}
+
+// Filter returns the filter value for the grant item.
+func (i *GrantItem) Filter() string {
+	return i.title() + " " + i.grant.Path
+}
+
+// ID returns the unique identifier for the grant.
+func (i *GrantItem) ID() string {
+	return i.grant.ID
+}
+
+// SetFocused sets the focus state of the grant item.
+func (i *GrantItem) SetFocused(focused bool) {
+	if i.focused != focused {
+		i.cache = nil
+	}
+	i.focused = focused
+}
+
+// SetMatch sets the fuzzy match for the grant item.
+func (i *GrantItem) SetMatch(m fuzzy.Match) {
+	i.cache = nil
+	i.m = m
+}
+
+// Render returns the string representation of the grant item.
+func (i *GrantItem) Render(width int) string {
+	styles := ListItemStyles{
+		ItemBlurred:     i.t.Dialog.NormalItem,
+		ItemFocused:     i.t.Dialog.SelectedItem,
+		InfoTextBlurred: i.t.Subtle,
+		InfoTextFocused: i.t.Base,
+	}
+	return renderItem(styles, i.title(), home.Short(i.grant.Path), i.focused, width, i.cache, &i.m)
+}
@@ -0,0 +1,280 @@
+package dialog
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"charm.land/bubbles/v2/help"
+	"charm.land/bubbles/v2/key"
+	"charm.land/bubbles/v2/spinner"
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/crush/internal/agent/tools/mcp"
+	"github.com/charmbracelet/crush/internal/oauth/mcpauth"
+	"github.com/charmbracelet/crush/internal/ui/common"
+	"github.com/charmbracelet/crush/internal/ui/util"
+	uv "github.com/charmbracelet/ultraviolet"
+	"github.com/pkg/browser"
+)
+
+// MCPOAuthID is the identifier for the MCP authorization dialog.
+const MCPOAuthID = "mcp_oauth"
+
+type (
+	mcpOAuthStartedMsg struct {
+		auth *mcpauth.Authorization
+	}
+	mcpOAuthCompletedMsg struct{}
+	mcpOAuthErroredMsg   struct {
+		err error
+	}
+)
+
+// MCPOAuth authorizes Crush with a remote MCP server, in the browser or with
+// a device code.
+type MCPOAuth struct {
+	com  *common.Common
+	name string
+
+	state  OAuthState
+	auth   *mcpauth.Authorization
+	cancel context.CancelFunc
+
+	width   int
+	spinner spinner.Model
+	help    help.Model
+	keyMap  struct {
+		Copy   key.Binding
+		Submit key.Binding
+		Close  key.Binding
+	}
+}
+
+var _ Dialog = (*MCPOAuth)(nil)
+
+// NewMCPOAuth creates a dialog authorizing the given MCP server.
+func NewMCPOAuth(com *common.Common, name string) (*MCPOAuth, tea.Cmd) {
+	t := com.Styles
+
+	m := &MCPOAuth{
+		com:   com,
+		name:  name,
+		state: OAuthStateInitializing,
+		width: 60,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+
+	m.spinner = spinner.New(
+		spinner.WithSpinner(spinner.Dot),
+		spinner.WithStyle(t.Base.Foreground(t.GreenLight)),
+	)
+
+	m.help = help.New()
+	m.help.Styles = t.DialogHelpStyles()
+
+	m.keyMap.Copy = key.NewBinding(
+		key.WithKeys("c"),
+		key.WithHelp("c", "copy"),
+	)
+	m.keyMap.Submit = key.NewBinding(
+		key.WithKeys("enter", "ctrl+y"),
+		key.WithHelp("enter", "open browser"),
+	)
+	m.keyMap.Close = CloseKey
+
+	start := func() tea.Msg {
+		auth, err := mcp.StartAuthorization(ctx, com.Store(), name)
+		if err != nil {
+			return mcpOAuthErroredMsg{err}
+		}
+		return mcpOAuthStartedMsg{auth}
+	}
+	return m, tea.Batch(m.spinner.Tick, start)
+}
+
+// ID implements Dialog.
+func (m *MCPOAuth) ID() string {
+	return MCPOAuthID
+}
+
+// HandleMsg implements Dialog.
+func (m *MCPOAuth) HandleMsg(msg tea.Msg) Action {
+	switch msg := msg.(type) {
+	case spinner.TickMsg:
+		switch m.state {
+		case OAuthStateInitializing, OAuthStateDisplay:
+			var cmd tea.Cmd
+			m.spinner, cmd = m.spinner.Update(msg)
+			if cmd != nil {
+				return ActionCmd{cmd}
+			}
+		}
+
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, m.keyMap.Copy):
+			return ActionCmd{m.copy()}
+		case key.Matches(msg, m.keyMap.Submit):
+			return ActionCmd{tea.Sequence(m.copy(), m.openBrowser())}
+		case key.Matches(msg, m.keyMap.Close):
+			m.cancel()
+			if m.auth != nil {
+				m.auth.Close()
+			}
+			return ActionClose{}
+		}
+
+	case mcpOAuthStartedMsg:
+		m.auth = msg.auth
+		m.state = OAuthStateDisplay
+		ctx, store, name := context.Background(), m.com.Store(), m.name
+		return ActionCmd{tea.Batch(m.openBrowser(), func() tea.Msg {
+			if err := mcp.CompleteAuthorization(ctx, store, name, msg.auth); err != nil {
+				return mcpOAuthErroredMsg{err}
+			}
+			return mcpOAuthCompletedMsg{}
+		})}
+
+	case mcpOAuthCompletedMsg:
+		m.state = OAuthStateSuccess
+		return ActionMCPAuthorized{Name: m.name}
+
+	case mcpOAuthErroredMsg:
+		m.state = OAuthStateError
+		return ActionCmd{util.ReportError(fmt.Errorf("failed to authorize %s: %w", m.name, msg.err))}
+	}
+	return nil
+}
+
+// Draw implements Dialog.
+func (m *MCPOAuth) Draw(scr uv.Screen, area uv.Rectangle) *tea.Cursor {
+	t := m.com.Styles
+	view := t.Dialog.View.Width(m.width).Render(m.content())
+	DrawCenter(scr, area, view)
+	return nil
+}
+
+func (m *MCPOAuth) content() string {
+	var (
+		t            = m.com.Styles
+		titleStyle   = t.Dialog.Title
+		dialogStyle  = t.Dialog.View.Width(m.width)
+		headerOffset = titleStyle.GetHorizontalFrameSize() + dialogStyle.GetHorizontalFrameSize()
+		title        = fmt.Sprintf("Authorize %s", m.name)
+		header       = common.DialogTitle(t, titleStyle.Render(title), m.width-headerOffset, t.Primary, t.Secondary)
+	)
+	return strings.Join([]string{
+		header,
+		m.innerContent(),
+		t.Dialog.HelpView.Render(m.help.View(m)),
+	}, "\n")
+}
+
+func (m *MCPOAuth) innerContent() string {
+	var (
+		t            = m.com.Styles
+		whiteStyle   = lipgloss.NewStyle().Foreground(t.White)
+		primaryStyle = lipgloss.NewStyle().Foreground(t.Primary)
+		greenStyle   = lipgloss.NewStyle().Foreground(t.GreenLight)
+		linkStyle    = lipgloss.NewStyle().Foreground(t.GreenDark).Underline(true)
+		errorStyle   = lipgloss.NewStyle().Foreground(t.Error)
+		mutedStyle   = lipgloss.NewStyle().Foreground(t.FgMuted)
+		blockStyle   = lipgloss.NewStyle().Margin(0, 1).Width(m.width - 2)
+	)
+
+	switch m.state {
+	case OAuthStateInitializing:
+		return lipgloss.NewStyle().
+			Margin(1, 1).
+			Width(m.width - 2).
+			Align(lipgloss.Center).
+			Render(greenStyle.Render(m.spinner.View()) + mutedStyle.Render("Discovering the authorization server..."))
+
+	case OAuthStateDisplay:
+		elements := []string{""}
+		if m.auth.UserCode != "" {
+			elements = append(elements,
+				blockStyle.Render(
+					whiteStyle.Render("Press ")+
+						primaryStyle.Render("enter")+
+						whiteStyle.Render(" to copy the code below and open the browser."),
+				),
+				"",
+				lipgloss.NewStyle().
+					Width(m.width-2).
+					Height(5).
+					Align(lipgloss.Center, lipgloss.Center).
+					Background(t.BgBaseLighter).
+					Margin(0, 1).
+					Render(lipgloss.NewStyle().Bold(true).Foreground(t.White).Render(m.auth.UserCode)),
+			)
+		} else {
+			elements = append(elements,
+				blockStyle.Render(whiteStyle.Render("Authorize Crush in the browser to connect to the server.")),
+			)
+		}
+		link := linkStyle.Hyperlink(m.auth.URL, "id=mcp-oauth").Render(m.auth.URL)
+		elements = append(elements,
+			"",
+			mutedStyle.Margin(0, 1).Width(m.width-2).Render("Browser not opening? Refer to\n"+link),
+			"",
+			blockStyle.Render(greenStyle.Render(m.spinner.View())+mutedStyle.Render("Waiting for authorization...")),
+			"",
+		)
+		return lipgloss.JoinVertical(lipgloss.Left, elements...)
+
+	case OAuthStateSuccess:
+		return greenStyle.Margin(1).Width(m.width - 2).Render("Authorization successful!")
+
+	case OAuthStateError:
+		return lipgloss.NewStyle().Margin(1).Width(m.width - 2).Render(errorStyle.Render("Authorization failed."))
+
+	default:
+		return ""
+	}
+}
+
+// ShortHelp implements help.KeyMap.
+func (m *MCPOAuth) ShortHelp() []key.Binding {
+	if m.state != OAuthStateDisplay {
+		return []key.Binding{m.keyMap.Close}
+	}
+	return []key.Binding{m.keyMap.Copy, m.keyMap.Submit, m.keyMap.Close}
+}
+
+// FullHelp implements help.KeyMap.
+func (m *MCPOAuth) FullHelp() [][]key.Binding {
+	return [][]key.Binding{m.ShortHelp()}
+}
+
+// copy copies the user code in the device flow, or the URL otherwise.
+func (m *MCPOAuth) copy() tea.Cmd {
+	if m.state != OAuthStateDisplay {
+		return nil
+	}
+	if m.auth.UserCode != "" {
+		return tea.Sequence(
+			tea.SetClipboard(m.auth.UserCode),
+			util.ReportInfo("Code copied to clipboard"),
+		)
+	}
+	return tea.Sequence(
+		tea.SetClipboard(m.auth.URL),
+		util.ReportInfo("URL copied to clipboard"),
+	)
+}
+
+func (m *MCPOAuth) openBrowser() tea.Cmd {
+	if m.state != OAuthStateDisplay {
+		return nil
+	}
+	url := m.auth.URL
+	return func() tea.Msg {
+		if err := browser.OpenURL(url); err != nil {
+			return util.NewWarnMsg("Could not open the browser, open the URL manually")
+		}
+		return nil
+	}
+}
@@ -0,0 +1,675 @@
+package dialog
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"charm.land/bubbles/v2/help"
+	"charm.land/bubbles/v2/key"
+	"charm.land/bubbles/v2/textinput"
+	"charm.land/bubbles/v2/viewport"
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/crush/internal/agent/tools/mcp"
+	"github.com/charmbracelet/crush/internal/ui/common"
+	"github.com/charmbracelet/crush/internal/ui/list"
+	"github.com/charmbracelet/crush/internal/ui/styles"
+	"github.com/charmbracelet/crush/internal/ui/util"
+	uv "github.com/charmbracelet/ultraviolet"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/sahilm/fuzzy"
+)
+
+const (
+	// MCPsID is the identifier for the MCP servers dialog.
+	MCPsID              = "mcps"
+	mcpsDialogMaxWidth  = 80
+	mcpsDialogMaxHeight = 20
+)
+
+type mcpsView int
+
+const (
+	mcpsViewServers mcpsView = iota
+	mcpsViewServer
+	mcpsViewLogs
+)
+
+// MCPs represents a dialog listing the MCP servers and their state. A server
+// can be restarted, its tools enabled or disabled, and its logs read.
+type MCPs struct {
+	com      *common.Common
+	help     help.Model
+	input    textinput.Model
+	servers  *list.FilterableList
+	tools    *list.FilterableList
+	viewport viewport.Model
+
+	view mcpsView
+	// back is the view to go back to from the logs.
+	back mcpsView
+	// selected is the server shown, in the server and logs views.
+	selected string
+	// logsWidth is the width the logs were rendered at.
+	logsWidth int
+	// followLogs scrolls the logs to the bottom once rendered.
+	followLogs bool
+
+	keyMap struct {
+		Select   key.Binding
+		Toggle   key.Binding
+		Restart  key.Binding
+		Logs     key.Binding
+		Next     key.Binding
+		Previous key.Binding
+		UpDown   key.Binding
+		Scroll   key.Binding
+		Back     key.Binding
+		Close    key.Binding
+	}
+}
+
+// MCPItem represents an MCP server list item.
+type MCPItem struct {
+	info    mcp.ClientInfo
+	t       *styles.Styles
+	m       fuzzy.Match
+	cache   map[int]string
+	focused bool
+}
+
+// MCPToolItem represents a tool of an MCP server in a list.
+type MCPToolItem struct {
+	tool     *mcp.Tool
+	disabled bool
+	t        *styles.Styles
+	m        fuzzy.Match
+	cache    map[int]string
+	focused  bool
+}
+
+var (
+	_ Dialog   = (*MCPs)(nil)
+	_ ListItem = (*MCPItem)(nil)
+	_ ListItem = (*MCPToolItem)(nil)
+)
+
+// NewMCPs creates a new dialog with the MCP servers.
+func NewMCPs(com *common.Common) *MCPs {
+	d := &MCPs{com: com}
+
+	help := help.New()
+	help.Styles = com.Styles.DialogHelpStyles()
+	d.help = help
+
+	d.servers = list.NewFilterableList()
+	d.servers.Focus()
+	d.tools = list.NewFilterableList()
+	d.tools.Focus()
+
+	d.input = textinput.New()
+	d.input.SetVirtualCursor(false)
+	d.input.Placeholder = "Type to filter"
+	d.input.SetStyles(com.Styles.TextInput)
+	d.input.Focus()
+
+	d.viewport = viewport.New()
+
+	d.keyMap.Select = key.NewBinding(
+		key.WithKeys("enter"),
+		key.WithHelp("enter", "details"),
+	)
+	d.keyMap.Toggle = key.NewBinding(
+		key.WithKeys("enter", "ctrl+x"),
+		key.WithHelp("enter", "enable/disable"),
+	)
+	d.keyMap.Restart = key.NewBinding(
+		key.WithKeys("ctrl+r"),
+		key.WithHelp("ctrl+r", "restart"),
+	)
+	d.keyMap.Logs = key.NewBinding(
+		key.WithKeys("ctrl+l"),
+		key.WithHelp("ctrl+l", "logs"),
+	)
+	d.keyMap.Next = key.NewBinding(
+		key.WithKeys("down", "ctrl+n"),
+		key.WithHelp("↓", "next item"),
+	)
+	d.keyMap.Previous = key.NewBinding(
+		key.WithKeys("up", "ctrl+p"),
+		key.WithHelp("↑", "previous item"),
+	)
+	d.keyMap.UpDown = key.NewBinding(
+		key.WithKeys("up", "down"),
+		key.WithHelp("↑/↓", "choose"),
+	)
+	d.keyMap.Scroll = key.NewBinding(
+		key.WithKeys("up", "down", "pgup", "pgdown"),
+		key.WithHelp("↑/↓", "scroll"),
+	)
+	d.keyMap.Back = key.NewBinding(
+		key.WithKeys("esc", "alt+esc"),
+		key.WithHelp("esc", "back"),
+	)
+	d.keyMap.Close = CloseKey
+
+	d.setServerItems()
+	return d
+}
+
+// ID implements Dialog.
+func (d *MCPs) ID() string {
+	return MCPsID
+}
+
+// Refresh updates the dialog after the state of an MCP server changed.
+func (d *MCPs) Refresh() {
+	d.setServerItems()
+	if d.selected != "" {
+		d.setToolItems()
+	}
+	if d.view == mcpsViewLogs {
+		d.followLogs = d.viewport.AtBottom()
+		d.logsWidth = 0
+	}
+}
+
+// HandleMsg implements [Dialog].
+func (d *MCPs) HandleMsg(msg tea.Msg) Action {
+	switch d.view {
+	case mcpsViewServer:
+		return d.handleServerMsg(msg)
+	case mcpsViewLogs:
+		return d.handleLogsMsg(msg)
+	}
+
+	switch msg := msg.(type) {
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, d.keyMap.Close):
+			return ActionClose{}
+		case key.Matches(msg, d.keyMap.Previous):
+			selectPrev(d.servers)
+		case key.Matches(msg, d.keyMap.Next):
+			selectNext(d.servers)
+		case key.Matches(msg, d.keyMap.Select):
+			if item, ok := d.servers.SelectedItem().(*MCPItem); ok {
+				d.showServer(item.info.Name)
+			}
+		case key.Matches(msg, d.keyMap.Restart):
+			if item, ok := d.servers.SelectedItem().(*MCPItem); ok {
+				return ActionCmd{d.restartCmd(item.info.Name)}
+			}
+		case key.Matches(msg, d.keyMap.Logs):
+			if item, ok := d.servers.SelectedItem().(*MCPItem); ok {
+				d.showLogs(item.info.Name)
+			}
+		default:
+			return d.updateFilter(msg, d.servers)
+		}
+	}
+	return nil
+}
+
+func (d *MCPs) handleServerMsg(msg tea.Msg) Action {
+	switch msg := msg.(type) {
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, d.keyMap.Back):
+			d.view = mcpsViewServers
+			d.input.Reset()
+			d.servers.SetFilter("")
+		case key.Matches(msg, d.keyMap.Previous):
+			selectPrev(d.tools)
+		case key.Matches(msg, d.keyMap.Next):
+			selectNext(d.tools)
+		case key.Matches(msg, d.keyMap.Toggle):
+			if item, ok := d.tools.SelectedItem().(*MCPToolItem); ok {
+				return d.toggleTool(item)
+			}
+		case key.Matches(msg, d.keyMap.Restart):
+			return ActionCmd{d.restartCmd(d.selected)}
+		case key.Matches(msg, d.keyMap.Logs):
+			d.showLogs(d.selected)
+		default:
+			return d.updateFilter(msg, d.tools)
+		}
+	}
+	return nil
+}
+
+func (d *MCPs) handleLogsMsg(msg tea.Msg) Action {
+	switch msg := msg.(type) {
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, d.keyMap.Back):
+			d.view = d.back
+		case key.Matches(msg, d.keyMap.Restart):
+			return ActionCmd{d.restartCmd(d.selected)}
+		default:
+			d.viewport, _ = d.viewport.Update(msg)
+		}
+	case tea.MouseWheelMsg:
+		d.viewport, _ = d.viewport.Update(msg)
+	}
+	return nil
+}
+
+func (d *MCPs) updateFilter(msg tea.Msg, l *list.FilterableList) Action {
+	var cmd tea.Cmd
+	d.input, cmd = d.input.Update(msg)
+	l.SetFilter(d.input.Value())
+	l.ScrollToTop()
+	l.SetSelected(0)
+	return ActionCmd{cmd}
+}
+
+func selectPrev(l *list.FilterableList) {
+	l.Focus()
+	if l.IsSelectedFirst() {
+		l.SelectLast()
+		l.ScrollToBottom()
+		return
+	}
+	l.SelectPrev()
+	l.ScrollToSelected()
+}
+
+func selectNext(l *list.FilterableList) {
+	l.Focus()
+	if l.IsSelectedLast() {
+		l.SelectFirst()
+		l.ScrollToTop()
+		return
+	}
+	l.SelectNext()
+	l.ScrollToSelected()
+}
+
+func (d *MCPs) showServer(name string) {
+	d.view = mcpsViewServer
+	d.selected = name
+	d.input.Reset()
+	d.setToolItems()
+	d.tools.SetSelected(0)
+	d.tools.ScrollToTop()
+}
+
+func (d *MCPs) showLogs(name string) {
+	d.back = d.view
+	d.view = mcpsViewLogs
+	d.selected = name
+	d.logsWidth = 0
+	d.followLogs = true
+}
+
+func (d *MCPs) restartCmd(name string) tea.Cmd {
+	store := d.com.Store()
+	return func() tea.Msg {
+		if err := mcp.Restart(context.Background(), store, name); err != nil {
+			return util.NewErrorMsg(fmt.Errorf("failed to restart %s MCP: %w", name, err))
+		}
+		return util.NewInfoMsg(fmt.Sprintf("Restarted %s MCP", name))
+	}
+}
+
+func (d *MCPs) toggleTool(item *MCPToolItem) Action {
+	store, name, tool := d.com.Store(), d.selected, item.tool.Name
+	scope, err := store.MCPScope(name)
+	if err != nil {
+		return ActionCmd{util.ReportError(err)}
+	}
+	if err := store.SetMCPToolDisabled(scope, name, tool, !item.disabled); err != nil {
+		return ActionCmd{util.ReportError(err)}
+	}
+	item.disabled = !item.disabled
+	item.cache = nil
+
+	disabled := item.disabled
+	return ActionCmd{func() tea.Msg {
+		mcp.ApplyDisabledTools(store, name)
+		if disabled {
+			return util.NewInfoMsg(fmt.Sprintf("Disabled %s tool of %s MCP", tool, name))
+		}
+		return util.NewInfoMsg(fmt.Sprintf("Enabled %s tool of %s MCP", tool, name))
+	}}
+}
+
+func (d *MCPs) setServerItems() {
+	states := mcp.GetStates()
+	selected := d.selectedServer()
+
+	var items []list.FilterableItem
+	for _, m := range d.com.Config().MCP.Sorted() {
+		info, ok := states[m.Name]
+		if !ok {
+			info = mcp.ClientInfo{Name: m.Name}
+		}
+		items = append(items, &MCPItem{info: info, t: d.com.Styles})
+	}
+	d.servers.SetItems(items...)
+	d.servers.SetFilter(d.filter(mcpsViewServers))
+
+	d.servers.SetSelected(0)
+	for i, item := range d.servers.FilteredItems() {
+		if item.(*MCPItem).info.Name == selected {
+			d.servers.SetSelected(i)
+			break
+		}
+	}
+}
+
+func (d *MCPs) selectedServer() string {
+	if item, ok := d.servers.SelectedItem().(*MCPItem); ok {
+		return item.info.Name
+	}
+	return ""
+}
+
+// filter returns the current filter of the list of a view.
+func (d *MCPs) filter(view mcpsView) string {
+	if d.view == view || (d.view == mcpsViewLogs && d.back == view) {
+		return d.input.Value()
+	}
+	return ""
+}
+
+func (d *MCPs) setToolItems() {
+	var selected string
+	if item, ok := d.tools.SelectedItem().(*MCPToolItem); ok {
+		selected = item.tool.Name
+	}
+
+	disabledTools := d.com.Config().MCP[d.selected].DisabledTools
+	tools := mcp.ServerTools(d.selected)
+	items := make([]list.FilterableItem, 0, len(tools))
+	for _, tool := range tools {
+		items = append(items, &MCPToolItem{
+			tool:     tool,
+			disabled: slices.Contains(disabledTools, tool.Name),
+			t:        d.com.Styles,
+		})
+	}
+	d.tools.SetItems(items...)
+	d.tools.SetFilter(d.filter(mcpsViewServer))
+
+	d.tools.SetSelected(0)
+	for i, item := range d.tools.FilteredItems() {
+		if item.(*MCPToolItem).tool.Name == selected {
+			d.tools.SetSelected(i)
+			break
+		}
+	}
+}
+
+// Cursor returns the cursor position relative to the dialog.
+func (d *MCPs) Cursor() *tea.Cursor {
+	if d.view == mcpsViewLogs {
+		return nil
+	}
+	return InputCursor(d.com.Styles, d.input.Cursor())
+}
+
+// Draw implements [Dialog].
+func (d *MCPs) Draw(scr uv.Screen, area uv.Rectangle) *tea.Cursor {
+	if d.view == mcpsViewLogs {
+		return d.drawLogs(scr, area)
+	}
+
+	t := d.com.Styles
+	width := max(0, min(mcpsDialogMaxWidth, area.Dx()))
+	height := max(0, min(mcpsDialogMaxHeight, area.Dy()))
+	innerWidth := width - t.Dialog.View.GetHorizontalFrameSize()
+	heightOffset := t.Dialog.Title.GetVerticalFrameSize() + titleContentHeight +
+		t.Dialog.InputPrompt.GetVerticalFrameSize() + inputContentHeight +
+		t.Dialog.HelpView.GetVerticalFrameSize() +
+		t.Dialog.View.GetVerticalFrameSize()
+
+	d.input.SetWidth(innerWidth - t.Dialog.InputPrompt.GetHorizontalFrameSize() - 1)
+	d.help.SetWidth(innerWidth)
+
+	rc := NewRenderContext(t, width)
+	rc.AddPart(t.Dialog.InputPrompt.Render(d.input.View()))
+
+	l := d.servers
+	rc.Title = "MCP Servers"
+	empty := "No MCP servers configured. Add one with: crush mcp add"
+	if d.view == mcpsViewServer {
+		l = d.tools
+		rc.Title = d.selected + " MCP"
+		empty = "No tools."
+		details := d.renderDetails(innerWidth)
+		rc.AddPart(details)
+		heightOffset += lipgloss.Height(details)
+	}
+
+	l.SetSize(innerWidth, max(1, height-heightOffset))
+	if l.Height() >= len(l.FilteredItems()) {
+		l.ScrollToTop()
+	} else {
+		l.ScrollToSelected()
+	}
+	if l.Len() == 0 {
+		rc.AddPart(t.Subtle.Render(empty))
+	} else {
+		rc.AddPart(t.Dialog.List.Height(l.Height()).Render(l.Render()))
+	}
+	rc.Help = d.help.View(d)
+
+	view := rc.Render()
+	cur := d.Cursor()
+	DrawCenterCursor(scr, area, view, cur)
+	return cur
+}
+
+// renderDetails renders the state, prompts and resources of the selected
+// server.
+func (d *MCPs) renderDetails(width int) string {
+	t := d.com.Styles
+	info, _ := mcp.GetState(d.selected)
+
+	lines := []string{mcpStatus(t, info)}
+	if info.Error != nil {
+		lines = append(lines, lipgloss.NewStyle().Foreground(t.Error).Render(info.Error.Error()))
+	}
+	if prompts := mcp.ServerPrompts(d.selected); len(prompts) > 0 {
+		names := make([]string, 0, len(prompts))
+		for _, p := range prompts {
+			names = append(names, p.Name)
+		}
+		lines = append(lines, t.Subtle.Render("Prompts: ")+strings.Join(names, ", "))
+	}
+	if resources := mcp.ServerResources(d.selected); len(resources) > 0 {
+		names := make([]string, 0, len(resources))
+		for _, r := range resources {
+			names = append(names, cmp.Or(r.Name, r.URI))
+		}
+		lines = append(lines, t.Subtle.Render("Resources: ")+strings.Join(names, ", "))
+	}
+	for i, line := range lines {
+		lines[i] = ansi.Truncate(line, width, "…")
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func (d *MCPs) drawLogs(scr uv.Screen, area uv.Rectangle) *tea.Cursor {
+	t := d.com.Styles
+	width := max(0, min(int(float64(area.Dx())*diffSizeRatio), diffMaxWidth))
+	height := max(0, int(float64(area.Dy())*diffSizeRatio))
+	innerWidth := width - t.Dialog.View.GetHorizontalFrameSize()
+	heightOffset := t.Dialog.Title.GetVerticalFrameSize() + titleContentHeight +
+		t.Dialog.HelpView.GetVerticalFrameSize() +
+		t.Dialog.View.GetVerticalFrameSize()
+
+	// Leave room for the scrollbar.
+	contentWidth := innerWidth - 1
+	d.viewport.SetWidth(contentWidth)
+	d.viewport.SetHeight(max(1, height-heightOffset))
+	if d.logsWidth != contentWidth {
+		d.viewport.SetContent(d.renderLogs(contentWidth))
+		d.logsWidth = contentWidth
+	}
+	if d.followLogs {
+		d.viewport.GotoBottom()
+		d.followLogs = false
+	}
+	d.help.SetWidth(innerWidth)
+
+	rc := NewRenderContext(t, width)
+	rc.Title = d.selected + " MCP Logs"
+	scrollbar := common.Scrollbar(t, d.viewport.Height(), d.viewport.TotalLineCount(), d.viewport.Height(), d.viewport.YOffset())
+	rc.AddPart(lipgloss.JoinHorizontal(lipgloss.Top, d.viewport.View(), scrollbar))
+	rc.Help = d.help.View(d)
+
+	DrawCenterCursor(scr, area, rc.Render(), nil)
+	return nil
+}
+
+func (d *MCPs) renderLogs(width int) string {
+	t := d.com.Styles
+	logs := mcp.Logs(d.selected)
+	if len(logs) == 0 {
+		return t.Subtle.Render("Nothing logged yet.")
+	}
+
+	lines := make([]string, 0, len(logs))
+	for _, entry := range logs {
+		level := t.Subtle
+		switch {
+		case entry.Level >= slog.LevelError:
+			level = lipgloss.NewStyle().Foreground(t.Error)
+		case entry.Level >= slog.LevelWarn:
+			level = lipgloss.NewStyle().Foreground(t.Warning)
+		}
+		prefix := t.Subtle.Render(entry.Time.Format("15:04:05")) + " " + level.Render(fmt.Sprintf("%-5s", entry.Level)) + " "
+		lines = append(lines, prefix+entry.Message)
+	}
+	return lipgloss.NewStyle().Width(width).Render(strings.Join(lines, "\n"))
+}
+
+// ShortHelp implements [help.KeyMap].
+func (d *MCPs) ShortHelp() []key.Binding {
+	switch d.view {
+	case mcpsViewServer:
+		return []key.Binding{d.keyMap.UpDown, d.keyMap.Toggle, d.keyMap.Restart, d.keyMap.Logs, d.keyMap.Back}
+	case mcpsViewLogs:
+		return []key.Binding{d.keyMap.Scroll, d.keyMap.Restart, d.keyMap.Back}
+	}
+	return []key.Binding{d.keyMap.UpDown, d.keyMap.Select, d.keyMap.Restart, d.keyMap.Logs, d.keyMap.Close}
+}
+
+// FullHelp implements [help.KeyMap].
+func (d *MCPs) FullHelp() [][]key.Binding {
+	return [][]key.Binding{d.ShortHelp()}
+}
+
+// mcpStatus describes the state of an MCP server.
+func mcpStatus(t *styles.Styles, info mcp.ClientInfo) string {
+	var icon, status string
+	switch info.State {
+	case mcp.StateStarting:
+		icon, status = t.ResourceBusyIcon.String(), "starting"
+	case mcp.StateConnected:
+		icon, status = t.ResourceOnlineIcon.String(), "connected"
+		var counts []string
+		if info.Counts.Tools > 0 {
+			counts = append(counts, fmt.Sprintf("%d tools", info.Counts.Tools))
+		}
+		if info.Counts.Prompts > 0 {
+			counts = append(counts, fmt.Sprintf("%d prompts", info.Counts.Prompts))
+		}
+		if info.Counts.Resources > 0 {
+			counts = append(counts, fmt.Sprintf("%d resources", info.Counts.Resources))
+		}
+		if len(counts) > 0 {
+			status += ", " + strings.Join(counts, ", ")
+		}
+	case mcp.StateError:
+		icon, status = t.ResourceErrorIcon.String(), "error"
+	default:
+		icon, status = t.ResourceOfflineIcon.String(), "disabled"
+	}
+	return icon + " " + t.Subtle.Render(status)
+}
+
+// Filter returns the filter value for the MCP item.
+func (i *MCPItem) Filter() string {
+	return i.info.Name
+}
+
+// ID returns the unique identifier for the MCP.
+func (i *MCPItem) ID() string {
+	return i.info.Name
+}
+
+// SetFocused sets the focus state of the MCP item.
+func (i *MCPItem) SetFocused(focused bool) {
+	if i.focused != focused {
+		i.cache = nil
+	}
+	i.focused = focused
+}
+
+// SetMatch sets the fuzzy match for the MCP item.
+func (i *MCPItem) SetMatch(m fuzzy.Match) {
+	i.cache = nil
+	i.m = m
+}
+
+// Render returns the string representation of the MCP item.
+func (i *MCPItem) Render(width int) string {
+	styles := ListItemStyles{
+		ItemBlurred:     i.t.Dialog.NormalItem,
+		ItemFocused:     i.t.Dialog.SelectedItem,
+		InfoTextBlurred: i.t.Subtle,
+		InfoTextFocused: i.t.Base,
+	}
+	info := i.info.State.String()
+	if i.info.State == mcp.StateConnected {
+		info = fmt.Sprintf("%d tools", i.info.Counts.Tools)
+	}
+	return renderItem(styles, i.info.Name, info, i.focused, width, i.cache, &i.m)
+}
+
+// Filter returns the filter value for the tool item.
+func (i *MCPToolItem) Filter() string {
+	return i.tool.Name
+}
+
+// ID returns the unique identifier for the tool.
+func (i *MCPToolItem) ID() string {
+	return i.tool.Name
+}
+
+// SetFocused sets the focus state of the tool item.
+func (i *MCPToolItem) SetFocused(focused bool) {
+	if i.focused != focused {
+		i.cache = nil
+	}
+	i.focused = focused
+}
+
+// SetMatch sets the fuzzy match for the tool item.
+func (i *MCPToolItem) SetMatch(m fuzzy.Match) {
+	i.cache = nil
+	i.m = m
+}
+
+// Render returns the string representation of the tool item.
+func (i *MCPToolItem) Render(width int) string {
+	styles := ListItemStyles{
+		ItemBlurred:     i.t.Dialog.NormalItem,
+		ItemFocused:     i.t.Dialog.SelectedItem,
+		InfoTextBlurred: i.t.Subtle,
+		InfoTextFocused: i.t.Base,
+	}
+	info := "enabled"
+	if i.disabled {
+		info = "disabled"
+	}
+	return renderItem(styles, i.tool.Name, info, i.focused, width, i.cache, &i.m)
+}
@@ -61,7 +61,7 @@ type Permissions struct {
 	fullscreen   bool // true when dialog is fullscreen
 
 	permission     permission.PermissionRequest
-	selectedOption int // 0: Allow, 1: Allow for session, 2: Deny
+	selectedOption int // Index in options: Allow, Allow for session, Deny
 
 	viewport      viewport.Model
 	viewportDirty bool // true when viewport content needs to be re-rendered
@@ -233,15 +233,16 @@ func (p *Permissions) HandleMsg(msg tea.Msg) Action {
 			// Escape denies the permission request.
 			return p.respond(PermissionDeny)
 		case key.Matches(msg, p.keyMap.Right), key.Matches(msg, p.keyMap.Tab):
-			p.selectedOption = (p.selectedOption + 1) % 3
+			p.selectedOption = (p.selectedOption + 1) % len(p.options())
 		case key.Matches(msg, p.keyMap.Left):
-			// Add 2 instead of subtracting 1 to avoid negative modulo.
-			p.selectedOption = (p.selectedOption + 2) % 3
+			// Add len-1 instead of subtracting 1 to avoid negative modulo.
+			n := len(p.options())
+			p.selectedOption = (p.selectedOption + n - 1) % n
 		case key.Matches(msg, p.keyMap.Select):
 			return p.selectCurrentOption()
 		case key.Matches(msg, p.keyMap.Allow):
 			return p.respond(PermissionAllow)
-		case key.Matches(msg, p.keyMap.AllowSession):
+		case key.Matches(msg, p.keyMap.AllowSession) && !p.permission.OneTime:
 			return p.respond(PermissionAllowForSession)
 		case key.Matches(msg, p.keyMap.Deny):
 			return p.respond(PermissionDeny)
@@ -296,15 +297,17 @@ func (p *Permissions) HandleMsg(msg tea.Msg) Action {
 	return nil
 }
 
-func (p *Permissions) selectCurrentOption() tea.Msg {
-	switch p.selectedOption {
-	case 0:
-		return p.respond(PermissionAllow)
-	case 1:
-		return p.respond(PermissionAllowForSession)
-	default:
-		return p.respond(PermissionDeny)
+// options returns the answers to the request. One-time requests can't be
+// allowed for the session.
+func (p *Permissions) options() []PermissionAction {
+	if p.permission.OneTime {
+		return []PermissionAction{PermissionAllow, PermissionDeny}
 	}
+	return []PermissionAction{PermissionAllow, PermissionAllowForSession, PermissionDeny}
+}
+
+func (p *Permissions) selectCurrentOption() tea.Msg {
+	return p.respond(p.options()[p.selectedOption])
 }
 
 func (p *Permissions) respond(action PermissionAction) tea.Msg {
@@ -316,7 +319,7 @@ func (p *Permissions) respond(action PermissionAction) tea.Msg {
 
 func (p *Permissions) hasDiffView() bool {
 	switch p.permission.ToolName {
-	case tools.EditToolName, tools.WriteToolName, tools.MultiEditToolName:
+	case tools.EditToolName, tools.WriteToolName, tools.MultiEditToolName, tools.NotebookEditToolName:
 		return true
 	}
 	return false
@@ -463,14 +466,19 @@ func (p *Permissions) renderHeader(contentWidth int) string {
 			lines = append(lines, p.renderKeyValue("URL", params.URL, contentWidth))
 			lines = append(lines, p.renderKeyValue("File", fsext.PrettyPath(params.FilePath), contentWidth))
 		}
-	case tools.EditToolName, tools.WriteToolName, tools.MultiEditToolName, tools.ViewToolName:
-		var filePath string
+	case tools.EditToolName, tools.WriteToolName, tools.MultiEditToolName, tools.NotebookEditToolName, tools.ViewToolName:
+		var (
+			filePath    string
+			reformatted bool
+		)
 		switch params := p.permission.Params.(type) {
 		case tools.EditPermissionsParams:
-			filePath = params.FilePath
+			filePath, reformatted = params.FilePath, params.Reformatted
 		case tools.WritePermissionsParams:
-			filePath = params.FilePath
+			filePath, reformatted = params.FilePath, params.Reformatted
 		case tools.MultiEditPermissionsParams:
+			filePath, reformatted = params.FilePath, params.Reformatted
+		case tools.NotebookEditPermissionsParams:
 			filePath = params.FilePath
 		case tools.ViewPermissionsParams:
 			filePath = params.FilePath
@@ -478,6 +486,14 @@ func (p *Permissions) renderHeader(contentWidth int) string {
 		if filePath != "" {
 			lines = append(lines, p.renderKeyValue("File", fsext.PrettyPath(filePath), contentWidth))
 		}
+		if reformatted {
+			lines = append(lines, p.renderKeyValue("Note", "The diff includes the changes of the file's formatter", contentWidth))
+		}
+	case tools.CodeActionToolName:
+		if params, ok := p.permission.Params.(tools.CodeActionPermissionsParams); ok {
+			lines = append(lines, p.renderKeyValue("Action", params.Title, contentWidth))
+			lines = append(lines, p.renderKeyValue("File", fsext.PrettyPath(params.FilePath), contentWidth))
+		}
 	case tools.LSToolName:
 		if params, ok := p.permission.Params.(tools.LSPermissionsParams); ok {
 			lines = append(lines, p.renderKeyValue("Directory", fsext.PrettyPath(params.Path), contentWidth))
@@ -531,6 +547,8 @@ func (p *Permissions) renderContent(width int) string {
 		return p.renderWriteContent(width)
 	case tools.MultiEditToolName:
 		return p.renderMultiEditContent(width)
+	case tools.NotebookEditToolName:
+		return p.renderNotebookEditContent(width)
 	case tools.DownloadToolName:
 		return p.renderDownloadContent(width)
 	case tools.FetchToolName:
@@ -541,6 +559,12 @@ func (p *Permissions) renderContent(width int) string {
 		return p.renderViewContent(width)
 	case tools.LSToolName:
 		return p.renderLSContent(width)
+	case tools.PlanToolName:
+		return p.renderPlanContent(width)
+	case tools.RenameToolName:
+		return p.renderRenameContent(width)
+	case tools.CodeActionToolName:
+		return p.renderCodeActionContent(width)
 	default:
 		return p.renderDefaultContent(width)
 	}
@@ -579,6 +603,14 @@ func (p *Permissions) renderMultiEditContent(contentWidth int) string {
 	return p.renderDiff(params.FilePath, params.OldContent, params.NewContent, contentWidth)
 }
 
+func (p *Permissions) renderNotebookEditContent(contentWidth int) string {
+	params, ok := p.permission.Params.(tools.NotebookEditPermissionsParams)
+	if !ok {
+		return ""
+	}
+	return p.renderDiff(params.FilePath, params.OldSource, params.NewSource, contentWidth)
+}
+
 func (p *Permissions) renderDiff(filePath, oldContent, newContent string, contentWidth int) string {
 	if !p.viewportDirty {
 		if p.isSplitMode() {
@@ -678,6 +710,56 @@ func (p *Permissions) renderLSContent(width int) string {
 	return p.renderContentPanel(content, width)
 }
 
+func (p *Permissions) renderPlanContent(width int) string {
+	params, ok := p.permission.Params.(tools.PlanPermissionsParams)
+	if !ok {
+		return ""
+	}
+
+	content := params.Plan
+	if len(params.Todos) > 0 {
+		content += "\n\n**Steps**\n"
+		for i, todo := range params.Todos {
+			content += fmt.Sprintf("\n%d. %s", i+1, todo)
+		}
+	}
+
+	renderer := common.MarkdownRenderer(p.com.Styles, width)
+	if rendered, err := renderer.Render(content); err == nil {
+		content = strings.TrimSpace(rendered)
+	}
+
+	return p.renderContentPanel(content, width)
+}
+
+func (p *Permissions) renderRenameContent(width int) string {
+	params, ok := p.permission.Params.(tools.RenamePermissionsParams)
+	if !ok {
+		return ""
+	}
+	return p.renderUnifiedDiff(params.Diff, width)
+}
+
+func (p *Permissions) renderCodeActionContent(width int) string {
+	params, ok := p.permission.Params.(tools.CodeActionPermissionsParams)
+	if !ok {
+		return ""
+	}
+	return p.renderUnifiedDiff(params.Diff, width)
+}
+
+// renderUnifiedDiff renders a diff already in the unified format, like the
+// ones of the edits of LSP servers.
+func (p *Permissions) renderUnifiedDiff(diff string, width int) string {
+	t := p.com.Styles
+	content := strings.TrimSpace(diff)
+	if highlighted, err := common.SyntaxHighlight(t, content, "changes.diff", t.BgSubtle); err == nil {
+		content = highlighted
+	}
+
+	return p.renderContentPanel(content, width)
+}
+
 func (p *Permissions) renderDefaultContent(width int) string {
 	t := p.com.Styles
 	var content string
@@ -730,10 +812,18 @@ func (p *Permissions) renderContentPanel(content string, width int) string {
 }
 
 func (p *Permissions) renderButtons(contentWidth int) string {
-	buttons := []common.ButtonOpts{
-		{Text: "Allow", UnderlineIndex: 0, Selected: p.selectedOption == 0},
-		{Text: "Allow for Session", UnderlineIndex: 10, Selected: p.selectedOption == 1},
-		{Text: "Deny", UnderlineIndex: 0, Selected: p.selectedOption == 2},
+	var buttons []common.ButtonOpts
+	for i, option := range p.options() {
+		button := common.ButtonOpts{Selected: p.selectedOption == i}
+		switch option {
+		case PermissionAllow:
+			button.Text = "Allow"
+		case PermissionAllowForSession:
+			button.Text, button.UnderlineIndex = "Allow for Session", 10
+		case PermissionDeny:
+			button.Text = "Deny"
+		}
+		buttons = append(buttons, button)
 	}
 
 	content := common.ButtonGroup(p.com.Styles, buttons, "  ")
@@ -66,8 +66,8 @@ func NewSessions(com *common.Common, selectedSessionID string) (*Session, error)
 		return nil, err
 	}
 
-	s.sessions = sessions
-	for i, sess := range sessions {
+	s.sessions = sessionTree(sessions)
+	for i, sess := range s.sessions {
 		if sess.ID == selectedSessionID {
 			s.selectedSessionInx = i
 			break
@@ -78,7 +78,7 @@ func NewSessions(com *common.Common, selectedSessionID string) (*Session, error)
 	help.Styles = com.Styles.DialogHelpStyles()
 
 	s.help = help
-	s.list = list.NewFilterableList(sessionItems(com.Styles, sessionsModeNormal, sessions...)...)
+	s.list = list.NewFilterableList(sessionItems(com.Styles, sessionsModeNormal, s.sessions...)...)
 	s.list.Focus()
 	s.list.SetSelected(s.selectedSessionInx)
 
@@ -36,6 +36,9 @@ type SessionItem struct {
 	cache            map[int]string
 	updateTitleInput textinput.Model
 	focused          bool
+	// depth is how many forks deep the session is nested under the listed
+	// sessions.
+	depth int
 }
 
 var _ ListItem = &SessionItem{}
@@ -98,7 +101,19 @@ func (s *SessionItem) Render(width int) string {
 		}
 	}
 
-	return renderItem(styles, s.Title, info, s.focused, width, s.cache, &s.m)
+	if s.depth == 0 {
+		return renderItem(styles, s.Title, info, s.focused, width, s.cache, &s.m)
+	}
+
+	// Draw forks as a tree under the session they were forked from, and
+	// shift the matched indexes past the prefix.
+	prefix := strings.Repeat("  ", s.depth-1) + "└ "
+	m := s.m
+	m.MatchedIndexes = make([]int, len(s.m.MatchedIndexes))
+	for i, idx := range s.m.MatchedIndexes {
+		m.MatchedIndexes[i] = idx + len(prefix)
+	}
+	return renderItem(styles, prefix+s.Title, info, s.focused, width, s.cache, &m)
 }
 
 type ListItemStyles struct {
@@ -185,9 +200,22 @@ func (s *SessionItem) SetFocused(focused bool) {
 // sessionItems takes a slice of [session.Session]s and convert them to a slice
 // of [ListItem]s.
 func sessionItems(t *styles.Styles, mode sessionsMode, sessions ...session.Session) []list.FilterableItem {
+	listed := make(map[string]bool, len(sessions))
+	for _, s := range sessions {
+		listed[s.ID] = true
+	}
+	parents := make(map[string]string, len(sessions))
+	for _, s := range sessions {
+		if s.IsFork && listed[s.ParentSessionID] {
+			parents[s.ID] = s.ParentSessionID
+		}
+	}
 	items := make([]list.FilterableItem, len(sessions))
 	for i, s := range sessions {
 		item := &SessionItem{Session: s, t: t, sessionsMode: mode}
+		for id, ok := parents[s.ID]; ok; id, ok = parents[id] {
+			item.depth++
+		}
 		if mode == sessionsModeUpdating {
 			item.updateTitleInput = textinput.New()
 			item.updateTitleInput.SetVirtualCursor(false)
@@ -202,6 +230,38 @@ func sessionItems(t *styles.Styles, mode sessionsMode, sessions ...session.Sessi
 	return items
 }
 
+// sessionTree orders sessions so that forks follow the session they were
+// forked from. Sessions keep their relative order otherwise, and forks whose
+// original session isn't listed are kept at the top level.
+func sessionTree(sessions []session.Session) []session.Session {
+	listed := make(map[string]bool, len(sessions))
+	for _, s := range sessions {
+		listed[s.ID] = true
+	}
+	children := make(map[string][]session.Session)
+	var roots []session.Session
+	for _, s := range sessions {
+		if s.IsFork && listed[s.ParentSessionID] {
+			children[s.ParentSessionID] = append(children[s.ParentSessionID], s)
+			continue
+		}
+		roots = append(roots, s)
+	}
+
+	tree := make([]session.Session, 0, len(sessions))
+	var walk func(s session.Session)
+	walk = func(s session.Session) {
+		tree = append(tree, s)
+		for _, child := range children[s.ID] {
+			walk(child)
+		}
+	}
+	for _, s := range roots {
+		walk(s)
+	}
+	return tree
+}
+
 func matchedRanges(in []int) [][2]int {
 	if len(in) == 0 {
 		return [][2]int{}
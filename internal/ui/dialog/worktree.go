@@ -0,0 +1,167 @@
+package dialog
+
+import (
+	"charm.land/bubbles/v2/key"
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/crush/internal/home"
+	"github.com/charmbracelet/crush/internal/ui/common"
+	"github.com/charmbracelet/crush/internal/worktree"
+	uv "github.com/charmbracelet/ultraviolet"
+)
+
+// WorktreeID is the identifier for the worktree dialog.
+const WorktreeID = "worktree"
+
+// WorktreeDecision is what to do with the worktree of a session.
+type WorktreeDecision int
+
+const (
+	// WorktreeMerge merges the worktree into its base branch.
+	WorktreeMerge WorktreeDecision = iota
+	// WorktreeDiscard throws the worktree and its changes away.
+	WorktreeDiscard
+	// WorktreeKeep leaves the worktree in place, to be opened later.
+	WorktreeKeep
+)
+
+// Worktree represents a dialog offering to merge, discard or keep the git
+// worktree of a session once the user is done with it.
+type Worktree struct {
+	com      *common.Common
+	worktree worktree.Worktree
+	quit     bool
+	selected WorktreeDecision
+	keyMap   struct {
+		LeftRight,
+		Enter,
+		Merge,
+		Discard,
+		Keep,
+		Tab,
+		Close key.Binding
+	}
+}
+
+var _ Dialog = (*Worktree)(nil)
+
+// NewWorktree creates a new worktree dialog. If quit is true, Crush quits
+// once the decision is carried out.
+func NewWorktree(com *common.Common, wt worktree.Worktree, quit bool) *Worktree {
+	w := &Worktree{
+		com:      com,
+		worktree: wt,
+		quit:     quit,
+		selected: WorktreeKeep,
+	}
+	w.keyMap.LeftRight = key.NewBinding(
+		key.WithKeys("left", "right"),
+		key.WithHelp("←/→", "switch options"),
+	)
+	w.keyMap.Enter = key.NewBinding(
+		key.WithKeys("enter", " "),
+		key.WithHelp("enter/space", "confirm"),
+	)
+	w.keyMap.Merge = key.NewBinding(
+		key.WithKeys("m", "M"),
+		key.WithHelp("m", "merge"),
+	)
+	w.keyMap.Discard = key.NewBinding(
+		key.WithKeys("d", "D"),
+		key.WithHelp("d", "discard"),
+	)
+	w.keyMap.Keep = key.NewBinding(
+		key.WithKeys("k", "K"),
+		key.WithHelp("k", "keep"),
+	)
+	w.keyMap.Tab = key.NewBinding(
+		key.WithKeys("tab"),
+		key.WithHelp("tab", "switch options"),
+	)
+	w.keyMap.Close = CloseKey
+	return w
+}
+
+// ID implements [Model].
+func (*Worktree) ID() string {
+	return WorktreeID
+}
+
+// HandleMsg implements [Model].
+func (w *Worktree) HandleMsg(msg tea.Msg) Action {
+	switch msg := msg.(type) {
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, w.keyMap.Close):
+			return ActionClose{}
+		case key.Matches(msg, w.keyMap.Tab):
+			w.selected = (w.selected + 1) % 3
+		case key.Matches(msg, w.keyMap.LeftRight):
+			if msg.String() == "left" {
+				w.selected = (w.selected + 2) % 3
+			} else {
+				w.selected = (w.selected + 1) % 3
+			}
+		case key.Matches(msg, w.keyMap.Enter):
+			return w.action(w.selected)
+		case key.Matches(msg, w.keyMap.Merge):
+			return w.action(WorktreeMerge)
+		case key.Matches(msg, w.keyMap.Discard):
+			return w.action(WorktreeDiscard)
+		case key.Matches(msg, w.keyMap.Keep):
+			return w.action(WorktreeKeep)
+		}
+	}
+
+	return nil
+}
+
+func (w *Worktree) action(decision WorktreeDecision) Action {
+	return ActionFinishWorktree{
+		Worktree: w.worktree,
+		Decision: decision,
+		Quit:     w.quit,
+	}
+}
+
+// Draw implements [Dialog].
+func (w *Worktree) Draw(scr uv.Screen, area uv.Rectangle) *tea.Cursor {
+	question := "This session works in the worktree " + home.Short(w.worktree.Path) + "."
+	details := "Merge branch " + w.worktree.Branch + " into " + w.worktree.BaseBranch + ", discard it, or keep it for later?"
+	baseStyle := w.com.Styles.Base
+	buttonOpts := []common.ButtonOpts{
+		{Text: "Merge", Selected: w.selected == WorktreeMerge, Padding: 3},
+		{Text: "Discard", Selected: w.selected == WorktreeDiscard, Padding: 3},
+		{Text: "Keep", Selected: w.selected == WorktreeKeep, Padding: 3},
+	}
+	buttons := common.ButtonGroup(w.com.Styles, buttonOpts, " ")
+	content := baseStyle.Render(
+		lipgloss.JoinVertical(
+			lipgloss.Center,
+			question,
+			w.com.Styles.Subtle.Render(details),
+			"",
+			buttons,
+		),
+	)
+
+	view := w.com.Styles.BorderFocus.Render(content)
+	DrawCenter(scr, area, view)
+	return nil
+}
+
+// ShortHelp implements [help.KeyMap].
+func (w *Worktree) ShortHelp() []key.Binding {
+	return []key.Binding{
+		w.keyMap.LeftRight,
+		w.keyMap.Enter,
+	}
+}
+
+// FullHelp implements [help.KeyMap].
+func (w *Worktree) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{w.keyMap.LeftRight, w.keyMap.Enter, w.keyMap.Merge, w.keyMap.Discard, w.keyMap.Keep},
+		{w.keyMap.Tab, w.keyMap.Close},
+	}
+}
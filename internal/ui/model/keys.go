@@ -45,6 +45,8 @@ type KeyMap struct {
 		Home           key.Binding
 		End            key.Binding
 		Copy           key.Binding
+		Rewind         key.Binding
+		Fork           key.Binding
 		ClearHighlight key.Binding
 		Expand         key.Binding
 	}
@@ -237,6 +239,14 @@ func DefaultKeyMap() KeyMap {
 		key.WithKeys("c", "y", "C", "Y"),
 		key.WithHelp("c/y", "copy"),
 	)
+	km.Chat.Rewind = key.NewBinding(
+		key.WithKeys("r"),
+		key.WithHelp("r", "rewind"),
+	)
+	km.Chat.Fork = key.NewBinding(
+		key.WithKeys("f"),
+		key.WithHelp("f", "fork"),
+	)
 	km.Chat.ClearHighlight = key.NewBinding(
 		key.WithKeys("esc", "alt+esc"),
 		key.WithHelp("esc", "clear selection"),
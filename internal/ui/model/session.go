@@ -10,13 +10,20 @@ import (
 
 	tea "charm.land/bubbletea/v2"
 	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/crush/internal/agent"
+	"github.com/charmbracelet/crush/internal/checkpoint"
 	"github.com/charmbracelet/crush/internal/diff"
+	"github.com/charmbracelet/crush/internal/fork"
 	"github.com/charmbracelet/crush/internal/fsext"
 	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/rewind"
 	"github.com/charmbracelet/crush/internal/session"
 	"github.com/charmbracelet/crush/internal/ui/common"
+	"github.com/charmbracelet/crush/internal/ui/dialog"
 	"github.com/charmbracelet/crush/internal/ui/styles"
 	"github.com/charmbracelet/crush/internal/ui/util"
+	"github.com/charmbracelet/crush/internal/worktree"
 	"github.com/charmbracelet/x/ansi"
 )
 
@@ -26,6 +33,8 @@ type loadSessionMsg struct {
 	session   *session.Session
 	files     []SessionFile
 	readFiles []string
+	// worktree is the git worktree the session works in, if any.
+	worktree *worktree.Worktree
 }
 
 // lspFilePaths returns deduplicated file paths from both modified and read
@@ -81,11 +90,125 @@ func (m *UI) loadSession(sessionID string) tea.Cmd {
 			slog.Error("Failed to load read files for session", "error", err)
 		}
 
-		return loadSessionMsg{
+		msg := loadSessionMsg{
 			session:   &session,
 			files:     sessionFiles,
 			readFiles: readFiles,
 		}
+		if wt, err := worktree.Find(context.Background(), m.com.Store().WorkingDir(), sessionID); err == nil {
+			msg.worktree = &wt
+		}
+		return msg
+	}
+}
+
+// budgetExceededMsg is a message indicating that a run of a session was
+// stopped by its budget.
+type budgetExceededMsg struct {
+	sessionID string
+	err       *agent.BudgetExceededError
+}
+
+// worktreeFinishedMsg is a message indicating that the worktree of a session
+// has been merged, discarded or kept.
+type worktreeFinishedMsg struct {
+	worktree worktree.Worktree
+	decision dialog.WorktreeDecision
+	quit     bool
+	err      error
+}
+
+// finishWorktree merges or discards the worktree of a session, as decided by
+// the user in the worktree dialog.
+func (m *UI) finishWorktree(wt worktree.Worktree, decision dialog.WorktreeDecision, quit bool) tea.Cmd {
+	return func() tea.Msg {
+		ctx := context.Background()
+		var err error
+		switch decision {
+		case dialog.WorktreeMerge:
+			err = wt.Merge(ctx)
+		case dialog.WorktreeDiscard:
+			err = wt.Discard(ctx)
+		}
+		if err == nil && decision != dialog.WorktreeKeep {
+			m.com.App.LSPManager.StopRoot(ctx, wt.Path)
+		}
+		return worktreeFinishedMsg{
+			worktree: wt,
+			decision: decision,
+			quit:     quit,
+			err:      err,
+		}
+	}
+}
+
+// sessionRewoundMsg is a message indicating that the session has been rewound
+// to one of its user messages.
+type sessionRewoundMsg struct {
+	result rewind.Result
+}
+
+// rewindSession rewinds the session to the given user message, deleting it
+// along with every later message and reverting the file changes made since.
+func (m *UI) rewindSession(sessionID, messageID string) tea.Cmd {
+	return func() tea.Msg {
+		rewinder := rewind.NewService(m.com.App.Sessions, m.com.App.Messages, m.com.App.History)
+		result, err := rewinder.Rewind(context.Background(), sessionID, messageID)
+		if err != nil {
+			return util.NewErrorMsg(err)
+		}
+		return sessionRewoundMsg{result: result}
+	}
+}
+
+// sessionForkedMsg is a message indicating that a session was forked.
+type sessionForkedMsg struct {
+	result fork.Result
+	// prompt is the user message the fork was made before, if any.
+	prompt string
+}
+
+// forkSession forks a session. Forking from a user message copies the
+// conversation before it, so it can be edited and sent again in the fork.
+// Forking from any other message copies the conversation up to it. Without a
+// message, the whole session is copied.
+func (m *UI) forkSession(sessionID string, msg *message.Message) tea.Cmd {
+	return func() tea.Msg {
+		forker := fork.NewService(m.com.App.Sessions, m.com.App.Messages, m.com.App.History)
+		var (
+			result fork.Result
+			prompt string
+			err    error
+		)
+		switch {
+		case msg == nil:
+			result, err = forker.Fork(context.Background(), sessionID, "")
+		case msg.Role == message.User:
+			result, err = forker.ForkBefore(context.Background(), sessionID, msg.ID)
+			prompt = msg.Content().Text
+		default:
+			result, err = forker.Fork(context.Background(), sessionID, msg.ID)
+		}
+		if err != nil {
+			return util.NewErrorMsg(err)
+		}
+		return sessionForkedMsg{result: result, prompt: prompt}
+	}
+}
+
+// restoreCheckpoint brings the working tree of a session back to one of its
+// checkpoints.
+func (m *UI) restoreCheckpoint(sessionID, dir string, cp checkpoint.Checkpoint) tea.Cmd {
+	return func() tea.Msg {
+		service := checkpoint.NewService(m.com.Config().Options.DataDirectory)
+		changes, err := service.Restore(context.Background(), dir, sessionID, cp.ID)
+		if err != nil {
+			return util.NewErrorMsg(err)
+		}
+		if len(changes) == 0 {
+			return util.NewInfoMsg("Nothing changed since " + cp.Title)
+		}
+		return util.NewInfoMsg(fmt.Sprintf("Restored %d file(s) to %q", len(changes), cp.Title))
 	}
 }
 
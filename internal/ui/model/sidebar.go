@@ -112,7 +112,17 @@ func (m *UI) drawSidebar(scr uv.Screen, area uv.Rectangle) {
 	height := area.Dy()
 
 	title := t.Muted.Width(width).MaxHeight(2).Render(m.session.Title)
-	cwd := common.PrettyPath(t, m.com.Store().WorkingDir(), width)
+	workingDir := m.com.Store().WorkingDir()
+	if m.worktree != nil {
+		workingDir = m.worktree.Path
+	}
+	cwd := common.PrettyPath(t, workingDir, width)
+	if m.worktree != nil {
+		cwd += "\n" + t.Muted.Width(width).MaxHeight(1).Render("worktree "+m.worktree.Branch)
+	}
+	if m.isPlanMode() {
+		cwd += "\n" + t.Muted.Width(width).MaxHeight(1).Render("plan mode")
+	}
 	sidebarLogo := m.sidebarLogo
 	if height < logoHeightBreakpoint {
 		sidebarLogo = logo.SmallRender(m.com.Styles, width)
@@ -138,7 +148,7 @@ func (m *UI) drawSidebar(scr uv.Screen, area uv.Rectangle) {
 
 	lspSection := m.lspInfo(width, maxLSPs, true)
 	mcpSection := m.mcpInfo(width, maxMCPs, true)
-	filesSection := m.filesInfo(m.com.Store().WorkingDir(), width, maxFiles, true)
+	filesSection := m.filesInfo(workingDir, width, maxFiles, true)
 
 	uv.NewStyledString(
 		lipgloss.NewStyle().
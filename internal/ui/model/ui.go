@@ -25,6 +25,7 @@ import (
 	tea "charm.land/bubbletea/v2"
 	"charm.land/catwalk/pkg/catwalk"
 	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/crush/internal/agent"
 	"github.com/charmbracelet/crush/internal/agent/notify"
 	agenttools "github.com/charmbracelet/crush/internal/agent/tools"
 	"github.com/charmbracelet/crush/internal/agent/tools/mcp"
@@ -50,6 +51,7 @@ import (
 	"github.com/charmbracelet/crush/internal/ui/styles"
 	"github.com/charmbracelet/crush/internal/ui/util"
 	"github.com/charmbracelet/crush/internal/version"
+	"github.com/charmbracelet/crush/internal/worktree"
 	uv "github.com/charmbracelet/ultraviolet"
 	"github.com/charmbracelet/ultraviolet/layout"
 	"github.com/charmbracelet/ultraviolet/screen"
@@ -139,6 +141,13 @@ type UI struct {
 	// keeps track of read files while we don't have a session id
 	sessionFileReads []string
 
+	// worktree is the git worktree the current session works in, if any.
+	worktree *worktree.Worktree
+	// worktreeNext creates the next new session in its own worktree.
+	worktreeNext bool
+	// planModeNext starts the next new session in plan mode.
+	planModeNext bool
+
 	lastUserMessageTime int64
 
 	// The width and height of the terminal in cells.
@@ -239,10 +248,14 @@ type UI struct {
 		index    int
 		draft    string
 	}
+
+	// initialSessionID is the session to open on start, if any.
+	initialSessionID string
 }
 
-// New creates a new instance of the [UI] model.
-func New(com *common.Common) *UI {
+// New creates a new instance of the [UI] model. If initialSessionID is set,
+// that session is opened on start.
+func New(com *common.Common, initialSessionID string) *UI {
 	// Editor components
 	ta := textarea.New()
 	ta.SetStyles(com.Styles.TextArea)
@@ -298,6 +311,7 @@ func New(com *common.Common) *UI {
 		mcpStates:           make(map[string]mcp.ClientInfo),
 		notifyBackend:       notification.NoopBackend{},
 		notifyWindowFocused: true,
+		initialSessionID:    initialSessionID,
 	}
 
 	status := NewStatus(com, ui)
@@ -341,6 +355,8 @@ func (m *UI) Init() tea.Cmd {
 		if cmd := m.openModelsDialog(); cmd != nil {
 			cmds = append(cmds, cmd)
 		}
+	} else if m.initialSessionID != "" {
+		cmds = append(cmds, m.loadSession(m.initialSessionID))
 	}
 	// load the user commands async
 	cmds = append(cmds, m.loadCustomCommands())
@@ -442,6 +458,27 @@ func (m *UI) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if cmd := m.handleAgentNotification(msg.Payload); cmd != nil {
 			cmds = append(cmds, cmd)
 		}
+	case budgetExceededMsg:
+		if m.hasSession() && m.session.ID == msg.sessionID {
+			m.openBudgetDialog(msg.err.Description)
+		}
+	case worktreeFinishedMsg:
+		if msg.err != nil {
+			cmds = append(cmds, util.ReportError(msg.err))
+			break
+		}
+		if m.worktree != nil && m.worktree.SessionID == msg.worktree.SessionID {
+			m.worktree = nil
+		}
+		if msg.quit {
+			cmds = append(cmds, tea.Quit)
+			break
+		}
+		status := "discarded"
+		if msg.decision == dialog.WorktreeMerge {
+			status = "merged into " + msg.worktree.BaseBranch
+		}
+		cmds = append(cmds, util.CmdHandler(util.NewInfoMsg("Worktree "+msg.worktree.Branch+" "+status)))
 	case loadSessionMsg:
 		if m.forceCompactMode {
 			m.isCompact = true
@@ -449,6 +486,7 @@ func (m *UI) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.setState(uiChat, m.focus)
 		m.session = msg.session
 		m.sessionFiles = msg.files
+		m.worktree = msg.worktree
 		cmds = append(cmds, m.startLSPs(msg.lspFilePaths()))
 		msgs, err := m.com.App.Messages.List(context.Background(), m.session.ID)
 		if err != nil {
@@ -496,6 +534,9 @@ func (m *UI) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case mcpStateChangedMsg:
 		m.mcpStates = msg.states
+		if mcps, ok := m.dialog.Dialog(dialog.MCPsID).(*dialog.MCPs); ok {
+			mcps.Refresh()
+		}
 	case mcpPromptsLoadedMsg:
 		m.mcpPrompts = msg.Prompts
 		dia := m.dialog.Dialog(dialog.CommandsID)
@@ -572,9 +613,14 @@ func (m *UI) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case pubsub.Event[mcp.Event]:
 		switch msg.Payload.Type {
 		case mcp.EventStateChanged:
+			var warn tea.Cmd
+			if errors.Is(msg.Payload.Error, mcp.ErrUnauthorized) {
+				warn = util.ReportWarn(fmt.Sprintf("%s MCP requires authorization, authorize it from the commands", msg.Payload.Name))
+			}
 			return m, tea.Batch(
 				m.handleStateChanged(),
 				m.loadMCPrompts,
+				warn,
 			)
 		case mcp.EventPromptsListChanged:
 			return m, handleMCPPromptsEvent(msg.Payload.Name)
@@ -595,6 +641,14 @@ func (m *UI) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 	case pubsub.Event[permission.PermissionNotification]:
 		m.handlePermissionNotification(msg.Payload)
+	case pubsub.Event[mcp.ElicitationRequest]:
+		m.dialog.OpenDialog(dialog.NewElicitation(m.com, msg.Payload))
+		if cmd := m.sendNotification(notification.Notification{
+			Title:   "Crush is waiting...",
+			Message: fmt.Sprintf("%s MCP is asking for input", msg.Payload.Name),
+		}); cmd != nil {
+			cmds = append(cmds, cmd)
+		}
 	case cancelTimerExpiredMsg:
 		m.isCanceling = false
 	case tea.TerminalVersionMsg:
@@ -790,6 +844,54 @@ func (m *UI) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if cmd != nil {
 			cmds = append(cmds, cmd)
 		}
+	case chat.RewindMsg:
+		if !m.hasSession() || msg.Message.SessionID != m.session.ID {
+			break
+		}
+		if m.isAgentBusy() {
+			cmds = append(cmds, util.ReportWarn("Agent is busy, please wait before rewinding..."))
+			break
+		}
+		if cmd := m.openRewindDialog(msg.Message.ID); cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+	case chat.ForkMsg:
+		if !m.hasSession() || msg.Message.SessionID != m.session.ID {
+			break
+		}
+		if m.isAgentBusy() {
+			cmds = append(cmds, util.ReportWarn("Agent is busy, please wait before forking..."))
+			break
+		}
+		cmds = append(cmds, m.forkSession(msg.Message.SessionID, msg.Message))
+	case sessionForkedMsg:
+		cmds = append(cmds, m.loadSession(msg.result.Session.ID))
+		if msg.prompt != "" {
+			// Put the prompt the fork was made before back in the editor,
+			// so another take on it can be sent.
+			m.textarea.SetValue(msg.prompt)
+			m.textarea.MoveToEnd()
+			m.focus = uiFocusEditor
+			m.chat.Blur()
+			cmds = append(cmds, m.textarea.Focus())
+		}
+		cmds = append(cmds, util.ReportInfo(fmt.Sprintf(
+			"Forked session: copied %d messages",
+			msg.result.Messages,
+		)))
+	case sessionRewoundMsg:
+		// Put the rewound prompt back in the editor so it can be edited and
+		// resent.
+		m.textarea.SetValue(msg.result.Message.Content().Text)
+		m.textarea.MoveToEnd()
+		m.focus = uiFocusEditor
+		m.chat.Blur()
+		cmds = append(cmds, m.textarea.Focus())
+		cmds = append(cmds, util.ReportInfo(fmt.Sprintf(
+			"Rewound session: deleted %d messages, reverted %d files",
+			msg.result.DeletedMessages,
+			len(msg.result.RestoredFiles)+len(msg.result.RemovedFiles),
+		)))
 	case util.InfoMsg:
 		m.status.SetInfoMsg(msg)
 		ttl := msg.TTL
@@ -1247,6 +1349,23 @@ func (m *UI) handleDialogMsg(msg tea.Msg) tea.Cmd {
 		m.com.App.Permissions.SetSkipRequests(yolo)
 		m.setEditorPrompt(yolo)
 		m.dialog.CloseDialog(dialog.CommandsID)
+	case dialog.ActionTogglePlanMode:
+		m.dialog.CloseDialog(dialog.CommandsID)
+		if m.com.App.AgentCoordinator == nil {
+			cmds = append(cmds, util.ReportWarn("Agent is not initialized"))
+			break
+		}
+		enabled := !m.isPlanMode()
+		if m.hasSession() {
+			m.com.App.AgentCoordinator.SetPlanMode(m.session.ID, enabled)
+		} else {
+			m.planModeNext = enabled
+		}
+		status := "off"
+		if enabled {
+			status = "on, the agent will submit a plan for approval before changing anything"
+		}
+		cmds = append(cmds, util.CmdHandler(util.NewInfoMsg("Plan mode "+status)))
 	case dialog.ActionToggleNotifications:
 		cfg := m.com.Config()
 		if cfg != nil && cfg.Options != nil {
@@ -1272,6 +1391,30 @@ func (m *UI) handleDialogMsg(msg tea.Msg) tea.Cmd {
 			cmds = append(cmds, cmd)
 		}
 		m.dialog.CloseDialog(dialog.CommandsID)
+	case dialog.ActionNewWorktreeSession:
+		if m.isAgentBusy() {
+			cmds = append(cmds, util.ReportWarn("Agent is busy, please wait before starting a new session..."))
+			break
+		}
+		if cmd := m.newSession(); cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+		m.worktreeNext = true
+		cmds = append(cmds, util.CmdHandler(util.NewInfoMsg("The next session will work in its own git worktree")))
+		m.dialog.CloseDialog(dialog.CommandsID)
+	case dialog.ActionFinishWorktree:
+		m.dialog.CloseDialog(dialog.WorktreeID)
+		if msg.Decision == dialog.WorktreeKeep {
+			if msg.Quit {
+				cmds = append(cmds, tea.Quit)
+			}
+			break
+		}
+		if m.isAgentBusy() {
+			cmds = append(cmds, util.ReportWarn("Agent is busy, please wait before finishing the worktree..."))
+			break
+		}
+		cmds = append(cmds, m.finishWorktree(msg.Worktree, msg.Decision, msg.Quit))
 	case dialog.ActionSummarize:
 		if m.isAgentBusy() {
 			cmds = append(cmds, util.ReportWarn("Agent is busy, please wait before summarizing session..."))
@@ -1400,6 +1543,67 @@ func (m *UI) handleDialogMsg(msg tea.Msg) tea.Cmd {
 				cmds = append(cmds, util.ReportError(err))
 			}
 		}
+	case dialog.ActionRewind:
+		m.dialog.CloseDialog(dialog.RewindID)
+		if m.isAgentBusy() {
+			cmds = append(cmds, util.ReportWarn("Agent is busy, please wait before rewinding..."))
+			break
+		}
+		cmds = append(cmds, m.rewindSession(msg.SessionID, msg.MessageID))
+	case dialog.ActionRestoreCheckpoint:
+		m.dialog.CloseDialog(dialog.CheckpointsID)
+		if m.isAgentBusy() {
+			cmds = append(cmds, util.ReportWarn("Agent is busy, please wait before restoring..."))
+			break
+		}
+		cmds = append(cmds, m.restoreCheckpoint(msg.SessionID, msg.Dir, msg.Checkpoint))
+	case dialog.ActionForkSession:
+		m.dialog.CloseDialog(dialog.CommandsID)
+		if m.isAgentBusy() {
+			cmds = append(cmds, util.ReportWarn("Agent is busy, please wait before forking..."))
+			break
+		}
+		cmds = append(cmds, m.forkSession(msg.SessionID, nil))
+	case dialog.ActionAuthorizeMCP:
+		m.dialog.CloseDialog(dialog.CommandsID)
+		cmds = append(cmds, m.openMCPOAuthDialog(msg.Name))
+	case dialog.ActionMCPAuthorized:
+		m.dialog.CloseDialog(dialog.MCPOAuthID)
+		store, name := m.com.Store(), msg.Name
+		cmds = append(cmds, func() tea.Msg {
+			if err := mcp.Restart(context.Background(), store, name); err != nil {
+				return util.NewErrorMsg(fmt.Errorf("failed to restart %s MCP: %w", name, err))
+			}
+			return util.NewInfoMsg(fmt.Sprintf("Authorized %s MCP", name))
+		})
+	case dialog.ActionExtendBudget:
+		m.dialog.CloseDialog(dialog.BudgetID)
+		if m.isAgentBusy() {
+			cmds = append(cmds, util.ReportWarn("Agent is busy, please wait..."))
+			break
+		}
+		cmds = append(cmds, m.sendMessage(agent.BudgetContinuePrompt))
+	case dialog.ActionSelectAgent:
+		coordinator := m.com.App.AgentCoordinator
+		if coordinator == nil {
+			cmds = append(cmds, util.ReportError(errors.New("agent is not initialized")))
+			break
+		}
+
+		var err error
+		if m.hasSession() {
+			err = coordinator.SetSessionAgent(m.session.ID, msg.Agent.ID)
+		} else {
+			// No session yet, so make it the default for the next one.
+			err = coordinator.SetMainAgent(msg.Agent.ID)
+		}
+		if err != nil {
+			cmds = append(cmds, util.ReportError(err))
+			break
+		}
+
+		m.dialog.CloseDialog(dialog.AgentsID)
+		cmds = append(cmds, util.ReportInfo("Switched to agent "+msg.Agent.Name))
 	case dialog.ActionSelectReasoningEffort:
 		if m.isAgentBusy() {
 			cmds = append(cmds, util.ReportWarn("Agent is busy, please wait..."))
@@ -2216,6 +2420,8 @@ func (m *UI) FullHelp() [][]key.Binding {
 				},
 				[]key.Binding{
 					k.Chat.Copy,
+					k.Chat.Rewind,
+					k.Chat.Fork,
 					k.Chat.ClearHighlight,
 				},
 			)
@@ -2726,6 +2932,15 @@ func (m *UI) isAgentBusy() bool {
 		m.com.App.AgentCoordinator.IsBusy()
 }
 
+// isPlanMode reports whether the current session is in plan mode or, without
+// a session, whether the next one will be.
+func (m *UI) isPlanMode() bool {
+	if !m.hasSession() {
+		return m.planModeNext
+	}
+	return m.com.App.AgentCoordinator != nil && m.com.App.AgentCoordinator.PlanMode(m.session.ID)
+}
+
 // hasSession returns true if there is an active session with a valid ID.
 func (m *UI) hasSession() bool {
 	return m.session != nil && m.session.ID != ""
@@ -2790,6 +3005,19 @@ func (m *UI) sendMessage(content string, attachments ...message.Attachment) tea.
 		if err != nil {
 			return util.ReportError(err)
 		}
+		if m.worktreeNext {
+			m.worktreeNext = false
+			wt, err := worktree.Create(context.Background(), m.com.Store().WorkingDir(), newSession.ID)
+			if err != nil {
+				_ = m.com.App.Sessions.Delete(context.Background(), newSession.ID)
+				return util.ReportError(fmt.Errorf("could not create worktree: %w", err))
+			}
+			m.worktree = &wt
+		}
+		if m.planModeNext {
+			m.planModeNext = false
+			m.com.App.AgentCoordinator.SetPlanMode(newSession.ID, true)
+		}
 		if m.forceCompactMode {
 			m.isCompact = true
 		}
@@ -2819,6 +3047,10 @@ func (m *UI) sendMessage(content string, attachments ...message.Attachment) tea.
 			if isCancelErr || isPermissionErr {
 				return nil
 			}
+			var budgetErr *agent.BudgetExceededError
+			if errors.As(err, &budgetErr) {
+				return budgetExceededMsg{sessionID: sessionID, err: budgetErr}
+			}
 			return util.InfoMsg{
 				Type: util.InfoTypeError,
 				Msg:  err.Error(),
@@ -2892,6 +3124,26 @@ func (m *UI) openDialog(id string) tea.Cmd {
 		if cmd := m.openReasoningDialog(); cmd != nil {
 			cmds = append(cmds, cmd)
 		}
+	case dialog.AgentsID:
+		if cmd := m.openAgentsDialog(); cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+	case dialog.GrantsID:
+		if cmd := m.openGrantsDialog(); cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+	case dialog.CheckpointsID:
+		if cmd := m.openCheckpointsDialog(); cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+	case dialog.MCPsID:
+		if cmd := m.openMCPsDialog(); cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+	case dialog.WorktreeID:
+		if cmd := m.openWorktreeDialog(false); cmd != nil {
+			cmds = append(cmds, cmd)
+		}
 	case dialog.QuitID:
 		if cmd := m.openQuitDialog(); cmd != nil {
 			cmds = append(cmds, cmd)
@@ -2903,19 +3155,60 @@ func (m *UI) openDialog(id string) tea.Cmd {
 	return tea.Batch(cmds...)
 }
 
-// openQuitDialog opens the quit confirmation dialog.
+// openQuitDialog opens the quit confirmation dialog. When the session works
+// in a worktree, the worktree dialog is opened instead, so the user can decide
+// what to do with it before quitting.
 func (m *UI) openQuitDialog() tea.Cmd {
 	if m.dialog.ContainsDialog(dialog.QuitID) {
 		// Bring to front
 		m.dialog.BringToFront(dialog.QuitID)
 		return nil
 	}
+	if m.worktree != nil && !m.dialog.ContainsDialog(dialog.WorktreeID) {
+		return m.openWorktreeDialog(true)
+	}
 
 	quitDialog := dialog.NewQuit(m.com)
 	m.dialog.OpenDialog(quitDialog)
 	return nil
 }
 
+// openWorktreeDialog opens the dialog to merge, discard or keep the worktree
+// of the current session.
+func (m *UI) openWorktreeDialog(quit bool) tea.Cmd {
+	if m.dialog.ContainsDialog(dialog.WorktreeID) {
+		m.dialog.BringToFront(dialog.WorktreeID)
+		return nil
+	}
+	if m.worktree == nil {
+		return util.ReportWarn("This session doesn't work in a worktree")
+	}
+
+	m.dialog.OpenDialog(dialog.NewWorktree(m.com, *m.worktree, quit))
+	return nil
+}
+
+// openRewindDialog opens the rewind confirmation dialog for the given user
+// message.
+func (m *UI) openRewindDialog(messageID string) tea.Cmd {
+	if m.dialog.ContainsDialog(dialog.RewindID) {
+		m.dialog.CloseDialog(dialog.RewindID)
+	}
+
+	rewindDialog := dialog.NewRewind(m.com, m.session.ID, messageID)
+	m.dialog.OpenDialog(rewindDialog)
+	return nil
+}
+
+// openBudgetDialog opens the dialog offering to continue a run stopped by its
+// budget.
+func (m *UI) openBudgetDialog(description string) {
+	if m.dialog.ContainsDialog(dialog.BudgetID) {
+		m.dialog.CloseDialog(dialog.BudgetID)
+	}
+	m.dialog.OpenDialog(dialog.NewBudget(m.com, m.session.ID, description))
+}
+
 // openModelsDialog opens the models dialog.
 func (m *UI) openModelsDialog() tea.Cmd {
 	if m.dialog.ContainsDialog(dialog.ModelsID) {
@@ -2951,7 +3244,9 @@ func (m *UI) openCommandsDialog() tea.Cmd {
 	hasTodos := hasSession && hasIncompleteTodos(m.session.Todos)
 	hasQueue := m.promptQueue > 0
 
-	commands, err := dialog.NewCommands(m.com, sessionID, hasSession, hasTodos, hasQueue, m.customCommands, m.mcpPrompts)
+	hasWorktree := m.worktree != nil
+
+	commands, err := dialog.NewCommands(m.com, sessionID, hasSession, hasTodos, hasQueue, hasWorktree, m.customCommands, m.mcpPrompts)
 	if err != nil {
 		return util.ReportError(err)
 	}
@@ -2977,6 +3272,98 @@ func (m *UI) openReasoningDialog() tea.Cmd {
 	return nil
 }
 
+// openAgentsDialog opens the agent selection dialog.
+func (m *UI) openAgentsDialog() tea.Cmd {
+	if m.dialog.ContainsDialog(dialog.AgentsID) {
+		m.dialog.BringToFront(dialog.AgentsID)
+		return nil
+	}
+
+	currentAgentID := config.AgentCoder
+	if coordinator := m.com.App.AgentCoordinator; coordinator != nil {
+		sessionID := ""
+		if m.hasSession() {
+			sessionID = m.session.ID
+		}
+		currentAgentID = coordinator.SessionAgentID(sessionID)
+	}
+
+	m.dialog.OpenDialog(dialog.NewAgents(m.com, currentAgentID))
+	return nil
+}
+
+// openGrantsDialog opens the dialog with the permission grants of the
+// current session.
+func (m *UI) openGrantsDialog() tea.Cmd {
+	if m.dialog.ContainsDialog(dialog.GrantsID) {
+		m.dialog.BringToFront(dialog.GrantsID)
+		return nil
+	}
+	if !m.hasSession() {
+		return util.ReportWarn("No session selected")
+	}
+
+	grantsDialog, err := dialog.NewGrants(m.com, m.session.ID)
+	if err != nil {
+		return util.ReportError(err)
+	}
+
+	m.dialog.OpenDialog(grantsDialog)
+	return nil
+}
+
+// openCheckpointsDialog opens the dialog with the checkpoints of the current
+// session.
+func (m *UI) openCheckpointsDialog() tea.Cmd {
+	if m.dialog.ContainsDialog(dialog.CheckpointsID) {
+		m.dialog.BringToFront(dialog.CheckpointsID)
+		return nil
+	}
+	if !m.hasSession() {
+		return util.ReportWarn("No session selected")
+	}
+
+	checkpointsDialog, err := dialog.NewCheckpoints(m.com, m.session.ID, m.sessionWorkingDir())
+	if err != nil {
+		return util.ReportError(err)
+	}
+
+	m.dialog.OpenDialog(checkpointsDialog)
+	return nil
+}
+
+// openMCPsDialog opens the dialog with the MCP servers.
+func (m *UI) openMCPsDialog() tea.Cmd {
+	if m.dialog.ContainsDialog(dialog.MCPsID) {
+		m.dialog.BringToFront(dialog.MCPsID)
+		return nil
+	}
+
+	m.dialog.OpenDialog(dialog.NewMCPs(m.com))
+	return nil
+}
+
+// openMCPOAuthDialog opens the dialog authorizing with an MCP server.
+func (m *UI) openMCPOAuthDialog(name string) tea.Cmd {
+	if m.dialog.ContainsDialog(dialog.MCPOAuthID) {
+		m.dialog.BringToFront(dialog.MCPOAuthID)
+		return nil
+	}
+
+	mcpOAuthDialog, cmd := dialog.NewMCPOAuth(m.com, name)
+	m.dialog.OpenDialog(mcpOAuthDialog)
+	return cmd
+}
+
+// sessionWorkingDir returns the directory the agent works in for the current
+// session: its worktree if it has one, or the working directory.
+func (m *UI) sessionWorkingDir() string {
+	if m.worktree != nil && m.hasSession() && m.worktree.SessionID == m.session.ID {
+		return m.worktree.Path
+	}
+	return m.com.Store().WorkingDir()
+}
+
 // openSessionsDialog opens the sessions dialog. If the dialog is already open,
 // it brings it to the front. Otherwise, it will list all the sessions and open
 // the dialog.
@@ -3057,6 +3444,13 @@ func (m *UI) handleAgentNotification(n notify.Notification) tea.Cmd {
 			Title:   "Crush is waiting...",
 			Message: fmt.Sprintf("Agent's turn completed in \"%s\"", n.SessionTitle),
 		})
+	case notify.TypeBudgetExceeded:
+		return m.sendNotification(notification.Notification{
+			Title:   "Crush hit its budget",
+			Message: fmt.Sprintf("Agent stopped after %s in \"%s\"", n.Message, n.SessionTitle),
+		})
+	case notify.TypeFailover:
+		return util.ReportWarn(n.Message)
 	default:
 		return nil
 	}
@@ -3073,6 +3467,7 @@ func (m *UI) newSession() tea.Cmd {
 	m.session = nil
 	m.sessionFiles = nil
 	m.sessionFileReads = nil
+	m.worktree = nil
 	m.setState(uiLanding, uiFocusEditor)
 	m.textarea.Focus()
 	m.chat.Blur()
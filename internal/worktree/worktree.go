@@ -0,0 +1,247 @@
+// Package worktree isolates sessions in git worktrees, so several sessions
+// can change the same repository at once without stepping on each other.
+package worktree
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/config"
+)
+
+// BranchPrefix is the prefix of the branches of session worktrees.
+const BranchPrefix = "crush/"
+
+var (
+	// ErrNotFound is returned when a session has no worktree.
+	ErrNotFound = errors.New("session has no worktree")
+	// ErrNotRepository is returned when the directory isn't in a git
+	// repository.
+	ErrNotRepository = errors.New("not a git repository")
+)
+
+// Worktree is the git worktree of a session.
+type Worktree struct {
+	SessionID string `json:"session_id"`
+	// Path is the directory of the worktree.
+	Path string `json:"path"`
+	// Branch is the branch checked out in the worktree.
+	Branch string `json:"branch"`
+	// BaseBranch is the branch the worktree was created from, and the one
+	// it's merged into.
+	BaseBranch string `json:"base_branch"`
+	// RepoDir is the root of the main working tree of the repository.
+	RepoDir string `json:"repo_dir"`
+}
+
+// Dir returns the directory worktrees of the given repository are created
+// in. It lives in the global data directory, outside the repository, so
+// tools working on the repository don't pick up the worktrees.
+func Dir(repoDir string) string {
+	sum := sha256.Sum256([]byte(repoDir))
+	name := filepath.Base(repoDir) + "-" + hex.EncodeToString(sum[:])[:8]
+	return filepath.Join(filepath.Dir(config.GlobalConfigData()), "worktrees", name)
+}
+
+// BranchName returns the name of the branch of a session's worktree.
+func BranchName(sessionID string) string {
+	return BranchPrefix + shortID(sessionID)
+}
+
+func shortID(sessionID string) string {
+	if len(sessionID) > 8 {
+		return sessionID[:8]
+	}
+	return sessionID
+}
+
+// Create creates a worktree and branch for a session, based on the branch
+// currently checked out in dir.
+func Create(ctx context.Context, dir, sessionID string) (Worktree, error) {
+	repoDir, err := repoRoot(ctx, dir)
+	if err != nil {
+		return Worktree{}, err
+	}
+	base, err := git(ctx, repoDir, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return Worktree{}, fmt.Errorf("repository has no commits to branch from: %w", err)
+	}
+
+	wt := Worktree{
+		SessionID:  sessionID,
+		Path:       filepath.Join(Dir(repoDir), shortID(sessionID)),
+		Branch:     BranchName(sessionID),
+		BaseBranch: base,
+		RepoDir:    repoDir,
+	}
+	if err := os.MkdirAll(filepath.Dir(wt.Path), 0o700); err != nil {
+		return Worktree{}, fmt.Errorf("creating worktrees directory: %w", err)
+	}
+	if _, err := git(ctx, repoDir, "worktree", "add", "-b", wt.Branch, wt.Path, "HEAD"); err != nil {
+		return Worktree{}, fmt.Errorf("creating worktree: %w", err)
+	}
+	// Remember which session and branch the worktree belongs to, so it can be
+	// found and merged later.
+	if _, err := git(ctx, repoDir, "config", "branch."+wt.Branch+".crush-session", sessionID); err != nil {
+		return Worktree{}, err
+	}
+	if _, err := git(ctx, repoDir, "config", "branch."+wt.Branch+".crush-base", base); err != nil {
+		return Worktree{}, err
+	}
+	return wt, nil
+}
+
+// Find returns the worktree of a session, or [ErrNotFound] if it has none.
+func Find(ctx context.Context, dir, sessionID string) (Worktree, error) {
+	worktrees, err := List(ctx, dir)
+	if err != nil {
+		return Worktree{}, err
+	}
+	for _, wt := range worktrees {
+		if wt.SessionID == sessionID {
+			return wt, nil
+		}
+	}
+	return Worktree{}, ErrNotFound
+}
+
+// List returns the session worktrees of the repository dir is in.
+func List(ctx context.Context, dir string) ([]Worktree, error) {
+	repoDir, err := repoRoot(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	out, err := git(ctx, repoDir, "worktree", "list", "--porcelain")
+	if err != nil {
+		return nil, fmt.Errorf("listing worktrees: %w", err)
+	}
+
+	var worktrees []Worktree
+	// Entries are separated by blank lines, with a "worktree <path>" line
+	// and a "branch refs/heads/<name>" line for checked out branches.
+	for entry := range strings.SplitSeq(out, "\n\n") {
+		var path, branch string
+		for line := range strings.SplitSeq(entry, "\n") {
+			if p, ok := strings.CutPrefix(line, "worktree "); ok {
+				path = p
+			}
+			if b, ok := strings.CutPrefix(line, "branch refs/heads/"); ok {
+				branch = b
+			}
+		}
+		if path == "" || !strings.HasPrefix(branch, BranchPrefix) {
+			continue
+		}
+		sessionID, err := git(ctx, repoDir, "config", "--get", "branch."+branch+".crush-session")
+		if err != nil || sessionID == "" {
+			continue
+		}
+		base, _ := git(ctx, repoDir, "config", "--get", "branch."+branch+".crush-base")
+		worktrees = append(worktrees, Worktree{
+			SessionID:  sessionID,
+			Path:       path,
+			Branch:     branch,
+			BaseBranch: base,
+			RepoDir:    repoDir,
+		})
+	}
+	return worktrees, nil
+}
+
+// HasChanges returns whether the worktree has uncommitted changes, or commits
+// that aren't in its base branch.
+func (w Worktree) HasChanges(ctx context.Context) (bool, error) {
+	status, err := git(ctx, w.Path, "status", "--porcelain")
+	if err != nil {
+		return false, err
+	}
+	if status != "" {
+		return true, nil
+	}
+	ahead, err := git(ctx, w.RepoDir, "rev-list", "--count", w.BaseBranch+".."+w.Branch)
+	if err != nil {
+		return false, err
+	}
+	return ahead != "0", nil
+}
+
+// Merge commits any pending changes of the worktree and merges its branch
+// into the base branch, which must be checked out in the main working tree.
+// The worktree and its branch are removed once merged.
+func (w Worktree) Merge(ctx context.Context) error {
+	current, err := git(ctx, w.RepoDir, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return err
+	}
+	if current != w.BaseBranch {
+		return fmt.Errorf("cannot merge %s: %s must be checked out in %s, but %s is", w.Branch, w.BaseBranch, w.RepoDir, current)
+	}
+
+	if status, err := git(ctx, w.Path, "status", "--porcelain"); err != nil {
+		return err
+	} else if status != "" {
+		if _, err := git(ctx, w.Path, "add", "--all"); err != nil {
+			return err
+		}
+		if _, err := git(ctx, w.Path, "commit", "--message", "Changes from Crush session "+shortID(w.SessionID)); err != nil {
+			return fmt.Errorf("committing worktree changes: %w", err)
+		}
+	}
+
+	if _, err := git(ctx, w.RepoDir, "merge", "--no-edit", w.Branch); err != nil {
+		_, _ = git(ctx, w.RepoDir, "merge", "--abort")
+		return fmt.Errorf("merging %s into %s: %w", w.Branch, w.BaseBranch, err)
+	}
+	return w.Discard(ctx)
+}
+
+// Discard removes the worktree and deletes its branch, throwing away its
+// changes.
+func (w Worktree) Discard(ctx context.Context) error {
+	if _, err := git(ctx, w.RepoDir, "worktree", "remove", "--force", w.Path); err != nil {
+		return fmt.Errorf("removing worktree: %w", err)
+	}
+	if _, err := git(ctx, w.RepoDir, "branch", "--delete", "--force", w.Branch); err != nil {
+		return fmt.Errorf("deleting branch: %w", err)
+	}
+	// The branch config section goes away with the branch in recent git
+	// versions, but not in older ones.
+	_, _ = git(ctx, w.RepoDir, "config", "--remove-section", "branch."+w.Branch)
+	return nil
+}
+
+func repoRoot(ctx context.Context, dir string) (string, error) {
+	// The common dir is shared by all worktrees, so this resolves to the main
+	// working tree even when called from within a session worktree.
+	commonDir, err := git(ctx, dir, "rev-parse", "--path-format=absolute", "--git-common-dir")
+	if err != nil {
+		return "", ErrNotRepository
+	}
+	if filepath.Base(commonDir) == ".git" {
+		return filepath.Dir(commonDir), nil
+	}
+	return git(ctx, dir, "rev-parse", "--show-toplevel")
+}
+
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
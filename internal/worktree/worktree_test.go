@@ -0,0 +1,105 @@
+package worktree
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func setupRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	t.Setenv("CRUSH_GLOBAL_DATA", t.TempDir())
+	t.Setenv("GIT_AUTHOR_NAME", "Crush")
+	t.Setenv("GIT_AUTHOR_EMAIL", "crush@charm.land")
+	t.Setenv("GIT_COMMITTER_NAME", "Crush")
+	t.Setenv("GIT_COMMITTER_EMAIL", "crush@charm.land")
+
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	runGit(t, dir, "init", "--initial-branch", "main")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("hello\n"), 0o644))
+	runGit(t, dir, "add", "README.md")
+	runGit(t, dir, "commit", "--message", "initial")
+	return dir
+}
+
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	_, err := git(t.Context(), dir, args...)
+	require.NoError(t, err)
+}
+
+func TestCreateAndFind(t *testing.T) {
+	repo := setupRepo(t)
+
+	wt, err := Create(t.Context(), repo, "0123456789abcdef")
+	require.NoError(t, err)
+	require.Equal(t, "crush/01234567", wt.Branch)
+	require.Equal(t, "main", wt.BaseBranch)
+	require.Equal(t, repo, wt.RepoDir)
+	require.FileExists(t, filepath.Join(wt.Path, "README.md"))
+
+	found, err := Find(t.Context(), repo, "0123456789abcdef")
+	require.NoError(t, err)
+	require.Equal(t, wt.Branch, found.Branch)
+	require.Equal(t, wt.BaseBranch, found.BaseBranch)
+
+	// The worktree can be found from within the worktree itself.
+	found, err = Find(t.Context(), wt.Path, "0123456789abcdef")
+	require.NoError(t, err)
+	require.Equal(t, repo, found.RepoDir)
+
+	_, err = Find(t.Context(), repo, "other-session")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	_, err = Find(t.Context(), t.TempDir(), "0123456789abcdef")
+	require.ErrorIs(t, err, ErrNotRepository)
+}
+
+func TestMerge(t *testing.T) {
+	repo := setupRepo(t)
+
+	wt, err := Create(t.Context(), repo, "merge-session")
+	require.NoError(t, err)
+
+	changed, err := wt.HasChanges(t.Context())
+	require.NoError(t, err)
+	require.False(t, changed)
+
+	require.NoError(t, os.WriteFile(filepath.Join(wt.Path, "feature.txt"), []byte("feature\n"), 0o644))
+	changed, err = wt.HasChanges(t.Context())
+	require.NoError(t, err)
+	require.True(t, changed)
+
+	// Nothing changes in the main working tree until the merge.
+	require.NoFileExists(t, filepath.Join(repo, "feature.txt"))
+
+	require.NoError(t, wt.Merge(t.Context()))
+	require.FileExists(t, filepath.Join(repo, "feature.txt"))
+	require.NoDirExists(t, wt.Path)
+
+	_, err = Find(t.Context(), repo, "merge-session")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestDiscard(t *testing.T) {
+	repo := setupRepo(t)
+
+	wt, err := Create(t.Context(), repo, "discard-session")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(wt.Path, "feature.txt"), []byte("feature\n"), 0o644))
+
+	require.NoError(t, wt.Discard(t.Context()))
+	require.NoDirExists(t, wt.Path)
+	require.NoFileExists(t, filepath.Join(repo, "feature.txt"))
+
+	_, err = git(t.Context(), repo, "rev-parse", "--verify", wt.Branch)
+	require.Error(t, err)
+}